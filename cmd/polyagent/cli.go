@@ -0,0 +1,281 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/config"
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
+)
+
+// cliOptions 命令行选项，仅对本次运行生效，不写回配置文件
+type cliOptions struct {
+	configPath string
+	workdir    string
+	model      string
+	offline    bool
+	resume     resumeFlag
+	version    bool
+	pprof      string
+}
+
+// resumeFlag --resume 恢复最近的会话，--resume=N 恢复 /history 中的第 N 个会话
+type resumeFlag int
+
+func (r *resumeFlag) String() string {
+	if r == nil || *r == 0 {
+		return ""
+	}
+	return strconv.Itoa(int(*r))
+}
+
+func (r *resumeFlag) Set(value string) error {
+	switch value {
+	case "true":
+		*r = 1
+		return nil
+	case "false":
+		*r = 0
+		return nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return fmt.Errorf("应为正整数: %q", value)
+	}
+	*r = resumeFlag(n)
+	return nil
+}
+
+func (r *resumeFlag) IsBoolFlag() bool { return true }
+
+// cliCommand 命令行子命令
+type cliCommand struct {
+	name string
+	// usage 子命令之后的参数说明，用于帮助信息
+	usage       string
+	description string
+	subcommands []cliCommand
+	// args 子命令之后可补全的参数
+	args func() []string
+	// run 执行子命令并返回退出码，仅顶层子命令需要
+	run func(opts *cliOptions, args []string) int
+}
+
+// cliCommands 返回所有子命令，帮助信息和补全脚本都由此生成
+func cliCommands() []cliCommand {
+	return []cliCommand{
+		{
+			name:        "doctor",
+			description: "Check config, API keys, connectivity and terminal support",
+			run:         runDoctorCommand,
+		},
+		{
+			name:        "config",
+			usage:       "list|get|set|edit",
+			description: "Manage settings, e.g. config set file_engine.max_file_size 20971520",
+			subcommands: []cliCommand{
+				{name: "list", description: "List all settings"},
+				{name: "get", description: "Print a setting", args: config.Keys},
+				{name: "set", description: "Change a setting", args: config.Keys},
+				{name: "edit", description: "Edit the config file in $EDITOR"},
+			},
+			run: func(_ *cliOptions, args []string) int { return runConfigCommand(args) },
+		},
+		{
+			name:        "release-manifests",
+			usage:       "<tag> [checksums.txt]",
+			description: "Generate the Homebrew formula and Scoop manifest for a release",
+			run:         runReleaseManifests,
+		},
+		{
+			name:        "completion",
+			usage:       "bash|zsh|fish|powershell",
+			description: "Print a shell completion script",
+			subcommands: []cliCommand{
+				{name: "bash", description: "Completion script for bash"},
+				{name: "zsh", description: "Completion script for zsh"},
+				{name: "fish", description: "Completion script for fish"},
+				{name: "powershell", description: "Completion script for PowerShell"},
+			},
+			run: func(_ *cliOptions, args []string) int { return runCompletion(args) },
+		},
+	}
+}
+
+// tuiCommands 界面中可用的命令，列在帮助信息中
+var tuiCommands = [][2]string{
+	{"check update", "Check for updates"},
+	{"update", "Update PolyAgent to latest version"},
+	{"/init", "Initialize project documentation"},
+	{"/rename <title>", "Rename the current session"},
+	{"/history", "List recent sessions"},
+	{"/trust", "Trust the current directory and enable all tools"},
+	{"/audit", "Review the tool calls made in this session"},
+	{"/quota [MB] [files]", "Show or raise the session write quota"},
+	{"/trash [restore <id>|empty]", "List, restore or empty deleted files"},
+	{"/reload-config", "Reload API keys from the config file"},
+	{"/open [n]", "List file:line references or open one in $EDITOR (Ctrl+O: latest)"},
+	{"/show [n|list]", "Display a mermaid/PlantUML diagram or image from recent replies"},
+	{"/retry [t=0.2] [note]", "Regenerate the last reply, optionally with a temperature or extra instructions"},
+	{"/new [keep-context]", "Start a new session, optionally carrying over AGENT.md, pinned files and a summary"},
+	{"/pin [path]", "List pinned files, or pin/unpin a file for /new keep-context"},
+	{"/telemetry [status|on|off|export [file]]", "Show, enable, disable or export anonymous usage stats"},
+	{"/recover [apply|discard]", "Review, restore or discard unsaved edits left by a crash"},
+	{"/verbose [on|off]", "Switch between compact and verbose display of tool calls and reasoning"},
+	{"/debug [error|messages [file]|net]", "Show the raw response of the last failed API request, inspect and export the messages the next request will send, or show connection settings and stats"},
+	{"/add-root [dir]", "Allow the file tools to access another directory for this session (asks for confirmation)"},
+	{"/scope [dir|off]", "Restrict searches, symbol lookups and globs to a subdirectory of a monorepo"},
+	{"/diff [n]", "Show the files changed in the last turn, or the diff of file n"},
+	{"/revert <n|all>", "Restore file n (or all files) to its content at the start of the last turn"},
+	{"/stats", "Summarize this session: turns, tool calls, files, tokens, cost, retries and cache hit ratios"},
+	{"/plan <task>", "Draft a numbered plan with read-only tools, then approve, deselect or edit its steps before execution"},
+}
+
+// commonFlags 所有子命令都接受的选项，以当前值为默认值，保留子命令之前已解析的取值
+func (o *cliOptions) commonFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.configPath, "config", o.configPath, "Use the given config file instead of ~/.config/polyagent/config.yaml")
+	fs.StringVar(&o.workdir, "workdir", o.workdir, "Run in the given directory instead of the current one")
+}
+
+// newRootFlagSet 启动界面时的选项
+func newRootFlagSet(o *cliOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet("polyagent", flag.ContinueOnError)
+	o.commonFlags(fs)
+	fs.StringVar(&o.model, "model", "", "Model to use for this session, overrides the config file")
+	fs.BoolVar(&o.offline, "offline", false, "Disable network tools and update checks")
+	fs.Var(&o.resume, "resume", "Resume the latest saved session, or the Nth one listed by /history with --resume=N")
+	fs.StringVar(&o.pprof, "pprof", "", "Serve pprof profiles on the given address for debugging, e.g. localhost:6060")
+	fs.BoolVar(&o.version, "version", false, "Show version information")
+	fs.BoolVar(&o.version, "v", false, "Shorthand for --version")
+	fs.Usage = func() { printRootUsage(fs) }
+	return fs
+}
+
+// newCommandFlagSet 子命令的选项
+func newCommandFlagSet(o *cliOptions, cmd cliCommand) *flag.FlagSet {
+	fs := flag.NewFlagSet("polyagent "+cmd.name, flag.ContinueOnError)
+	o.commonFlags(fs)
+	fs.Usage = func() {
+		w := fs.Output()
+		fmt.Fprintf(w, "%s\n\nUsage:\n  polyagent %s [flags] %s\n", cmd.description, cmd.name, cmd.usage)
+		if len(cmd.subcommands) > 0 {
+			fmt.Fprintln(w, "\nCommands:")
+			for _, sub := range cmd.subcommands {
+				fmt.Fprintf(w, "  %-12s %s\n", sub.name, sub.description)
+			}
+		}
+		fmt.Fprintln(w, "\nFlags:")
+		printFlags(w, fs)
+	}
+	return fs
+}
+
+func printRootUsage(fs *flag.FlagSet) {
+	w := fs.Output()
+	fmt.Fprintln(w, "PolyAgent - Vibe Coding Tool")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Usage:")
+	fmt.Fprintln(w, "  polyagent [flags]              Start the interactive TUI")
+	fmt.Fprintln(w, "  polyagent <command> [flags]    Run a command, see polyagent <command> -h")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range cliCommands() {
+		fmt.Fprintf(w, "  %-12s %s\n", cmd.name, cmd.description)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Flags:")
+	printFlags(w, fs)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands in TUI:")
+	for _, cmd := range tuiCommands {
+		fmt.Fprintf(w, "  %-28s %s\n", cmd[0], cmd[1])
+	}
+}
+
+// printFlags 以 --name 形式列出选项（flag.PrintDefaults 使用单横线）
+func printFlags(w io.Writer, fs *flag.FlagSet) {
+	fs.VisitAll(func(f *flag.Flag) {
+		name := flagName(f.Name)
+		if _, isBool := f.Value.(interface{ IsBoolFlag() bool }); !isBool {
+			name += " <value>"
+		}
+		fmt.Fprintf(w, "  %-22s %s\n", name, f.Usage)
+	})
+	fmt.Fprintf(w, "  %-22s %s\n", "-h, --help", "Show help information")
+}
+
+// flagName 单字母选项使用单横线，其余使用双横线
+func flagName(name string) string {
+	if len(name) == 1 {
+		return "-" + name
+	}
+	return "--" + name
+}
+
+// parseCommandLine 解析命令行，返回选项、要执行的子命令（为 nil 时启动界面）及其参数
+// 选项既可以写在子命令之前，也可以写在子命令之后
+func parseCommandLine(args []string) (*cliOptions, *cliCommand, []string, error) {
+	opts := &cliOptions{}
+	fs := newRootFlagSet(opts)
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, nil, err
+	}
+	rest := fs.Args()
+	if len(rest) == 0 {
+		return opts, nil, nil, nil
+	}
+
+	for _, cmd := range cliCommands() {
+		if cmd.name != rest[0] {
+			continue
+		}
+		sub := newCommandFlagSet(opts, cmd)
+		if err := sub.Parse(rest[1:]); err != nil {
+			return nil, nil, nil, err
+		}
+		return opts, &cmd, sub.Args(), nil
+	}
+	fmt.Fprintln(os.Stderr, i18n.T("cli.unknown_command", rest[0]))
+	fs.Usage()
+	return nil, nil, nil, errors.New("unknown command")
+}
+
+// apply 应用对所有子命令生效的选项
+// 相对的 --config 路径相对于启动时的目录，而不是 --workdir
+func (o *cliOptions) apply() error {
+	if o.configPath != "" {
+		path, err := filepath.Abs(o.configPath)
+		if err != nil {
+			return fmt.Errorf("解析配置文件路径失败: %w", err)
+		}
+		config.SetConfigPath(path)
+	}
+	if o.workdir != "" {
+		if err := os.Chdir(o.workdir); err != nil {
+			return fmt.Errorf("切换工作目录失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// rootFlagNames 返回顶层选项的名称，用于生成补全脚本
+func rootFlagNames() []completionCandidate {
+	var candidates []completionCandidate
+	newRootFlagSet(&cliOptions{}).VisitAll(func(f *flag.Flag) {
+		candidates = append(candidates, completionCandidate{flagName(f.Name), f.Usage})
+	})
+	return append(candidates, completionCandidate{"--help", "Show help information"})
+}
+
+// usageError 报告命令行用法错误，flag 包已输出具体原因
+func usageError(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	return 2
+}
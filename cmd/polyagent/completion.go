@@ -0,0 +1,225 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
+)
+
+// completionCandidate 一个补全候选项
+type completionCandidate struct {
+	word        string
+	description string
+}
+
+// completionTable 按已输入的子命令路径（以空格连接，不含选项）列出补全候选项
+func completionTable() map[string][]completionCandidate {
+	table := make(map[string][]completionCandidate)
+	var walk func(path string, commands []cliCommand)
+	walk = func(path string, commands []cliCommand) {
+		for _, cmd := range commands {
+			table[path] = append(table[path], completionCandidate{cmd.name, cmd.description})
+			sub := strings.TrimSpace(path + " " + cmd.name)
+			walk(sub, cmd.subcommands)
+			if cmd.args != nil {
+				for _, arg := range cmd.args() {
+					table[sub] = append(table[sub], completionCandidate{word: arg})
+				}
+			}
+		}
+	}
+	walk("", cliCommands())
+	table[""] = append(table[""], rootFlagNames()...)
+	return table
+}
+
+// sortedPaths 返回补全表中的路径，保证生成的脚本稳定
+func sortedPaths(table map[string][]completionCandidate) []string {
+	paths := make([]string, 0, len(table))
+	for path := range table {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// runCompletion 处理 polyagent completion <shell>，将补全脚本输出到标准输出
+func runCompletion(args []string) int {
+	generators := map[string]func(io.Writer, map[string][]completionCandidate){
+		"bash":       writeBashCompletion,
+		"zsh":        writeZshCompletion,
+		"fish":       writeFishCompletion,
+		"powershell": writePowerShellCompletion,
+	}
+	if len(args) != 1 || generators[args[0]] == nil {
+		fmt.Fprintln(os.Stderr, i18n.T("completion.usage"))
+		return 2
+	}
+	generators[args[0]](os.Stdout, completionTable())
+	return 0
+}
+
+func candidateWords(candidates []completionCandidate) string {
+	words := make([]string, len(candidates))
+	for i, c := range candidates {
+		words[i] = c.word
+	}
+	return strings.Join(words, " ")
+}
+
+// shellQuote 将字符串转义为 bash/zsh 单引号字符串
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// writeBashCompletion 输出 bash 补全脚本
+// 用法：source <(polyagent completion bash)
+func writeBashCompletion(w io.Writer, table map[string][]completionCandidate) {
+	fmt.Fprint(w, `# bash completion for polyagent
+_polyagent() {
+    local cur="${COMP_WORDS[COMP_CWORD]}" path="" i
+    for ((i = 1; i < COMP_CWORD; i++)); do
+        [[ ${COMP_WORDS[i]} == -* ]] || path="${path:+$path }${COMP_WORDS[i]}"
+    done
+    case "$path" in
+`)
+	for _, path := range sortedPaths(table) {
+		fmt.Fprintf(w, "    %s) COMPREPLY=($(compgen -W %s -- \"$cur\")) ;;\n", shellQuote(path), shellQuote(candidateWords(table[path])))
+	}
+	fmt.Fprint(w, `    esac
+}
+complete -F _polyagent polyagent
+`)
+}
+
+// zshEscape 转义 zsh 单引号字符串中的内容，冒号需转义以免被 _describe 当作分隔符
+func zshEscape(s string) string {
+	return strings.NewReplacer("'", `'\''`, ":", `\:`).Replace(s)
+}
+
+// writeZshCompletion 输出 zsh 补全脚本
+// 用法：source <(polyagent completion zsh)，或保存为 $fpath 中的 _polyagent
+func writeZshCompletion(w io.Writer, table map[string][]completionCandidate) {
+	fmt.Fprint(w, `#compdef polyagent
+# zsh completion for polyagent
+_polyagent() {
+    local path_="" i
+    local -a candidates
+    for ((i = 2; i < CURRENT; i++)); do
+        [[ ${words[i]} == -* ]] || path_="${path_:+$path_ }${words[i]}"
+    done
+    case "$path_" in
+`)
+	for _, path := range sortedPaths(table) {
+		items := make([]string, len(table[path]))
+		for i, c := range table[path] {
+			item := zshEscape(c.word)
+			if c.description != "" {
+				item += ":" + zshEscape(c.description)
+			}
+			items[i] = "'" + item + "'"
+		}
+		fmt.Fprintf(w, "    %s) candidates=(%s) ;;\n", shellQuote(path), strings.Join(items, " "))
+	}
+	fmt.Fprint(w, `    *) return 1 ;;
+    esac
+    _describe 'polyagent' candidates
+}
+
+if [ "$funcstack[1]" = "_polyagent" ]; then
+    _polyagent "$@"
+else
+    compdef _polyagent polyagent
+fi
+`)
+}
+
+// fishQuote 将字符串转义为 fish 单引号字符串
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+// writeFishCompletion 输出 fish 补全脚本
+// 用法：polyagent completion fish > ~/.config/fish/completions/polyagent.fish
+func writeFishCompletion(w io.Writer, table map[string][]completionCandidate) {
+	fmt.Fprint(w, `# fish completion for polyagent
+function __polyagent_at
+    set -l path
+    for token in (commandline -opc)[2..-1]
+        string match -q -- '-*' $token; or set -a path $token
+    end
+    test "$path" = "$argv[1]"
+end
+
+complete -c polyagent -f
+`)
+	for _, path := range sortedPaths(table) {
+		condition := fishQuote("__polyagent_at " + fishQuote(path))
+		for _, c := range table[path] {
+			var option string
+			switch {
+			case strings.HasPrefix(c.word, "--"):
+				option = "-l " + fishQuote(strings.TrimPrefix(c.word, "--"))
+			case strings.HasPrefix(c.word, "-"):
+				option = "-s " + fishQuote(strings.TrimPrefix(c.word, "-"))
+			default:
+				option = "-a " + fishQuote(c.word)
+			}
+			if c.description != "" {
+				option += " -d " + fishQuote(c.description)
+			}
+			fmt.Fprintf(w, "complete -c polyagent -n %s %s\n", condition, option)
+		}
+	}
+}
+
+// powerShellQuote 将字符串转义为 PowerShell 单引号字符串
+func powerShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// writePowerShellCompletion 输出 PowerShell 补全脚本
+// 用法：polyagent completion powershell | Out-String | Invoke-Expression
+func writePowerShellCompletion(w io.Writer, table map[string][]completionCandidate) {
+	fmt.Fprint(w, `# PowerShell completion for polyagent
+Register-ArgumentCompleter -Native -CommandName 'polyagent' -ScriptBlock {
+    param($wordToComplete, $commandAst, $cursorPosition)
+    $completions = @{
+`)
+	for _, path := range sortedPaths(table) {
+		items := make([]string, len(table[path]))
+		for i, c := range table[path] {
+			description := c.description
+			if description == "" {
+				description = c.word
+			}
+			items[i] = fmt.Sprintf("@(%s, %s)", powerShellQuote(c.word), powerShellQuote(description))
+		}
+		list := strings.Join(items, ", ")
+		if len(items) == 1 {
+			// 单个元素需用一元逗号，否则内层数组会被展开
+			list = "," + list
+		}
+		fmt.Fprintf(w, "        %s = @(%s)\n", powerShellQuote(path), list)
+	}
+	fmt.Fprint(w, `    }
+    $words = @($commandAst.CommandElements | Select-Object -Skip 1 | ForEach-Object { $_.ToString() })
+    if ($wordToComplete) {
+        $words = @($words | Select-Object -SkipLast 1)
+    }
+    $path = (@($words | Where-Object { $_ -notlike '-*' })) -join ' '
+    if (-not $completions.ContainsKey($path)) {
+        return
+    }
+    foreach ($candidate in $completions[$path]) {
+        if ($candidate[0] -like "$wordToComplete*") {
+            [System.Management.Automation.CompletionResult]::new($candidate[0], $candidate[0], 'ParameterValue', $candidate[1])
+        }
+    }
+}
+`)
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/config"
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
+	"github.com/Zacy-Sokach/PolyAgent/internal/utils"
+)
+
+// runConfigCommand 处理 polyagent config list/get/set/edit，返回退出码
+func runConfigCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Println(i18n.T("config_cmd.usage"))
+		return 2
+	}
+
+	cfg, err := config.LoadConfig()
+	if err == nil {
+		i18n.SetLanguage(cfg.Language)
+	}
+	// edit 也用于修复无法解析的配置文件，不要求配置能够加载
+	if args[0] == "edit" {
+		return editConfig()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("startup.load_config_failed", err))
+		printConfigIssues()
+		return 1
+	}
+
+	switch args[0] {
+	case "list":
+		if path, err := config.ConfigPath(); err == nil {
+			fmt.Println(i18n.T("config_cmd.list_header", path))
+		}
+		for _, kv := range config.ListValues(cfg) {
+			fmt.Printf("%s = %s\n", kv.Key, kv.Value)
+		}
+		return 0
+	case "get":
+		if len(args) != 2 {
+			fmt.Println(i18n.T("config_cmd.usage"))
+			return 2
+		}
+		value, err := config.GetValue(cfg, args[1])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		fmt.Println(value)
+		return 0
+	case "set":
+		if len(args) < 3 {
+			fmt.Println(i18n.T("config_cmd.usage"))
+			return 2
+		}
+		return setConfigValue(cfg, args[1], strings.Join(args[2:], " "))
+	default:
+		fmt.Fprintln(os.Stderr, i18n.T("config_cmd.unknown", args[0]))
+		fmt.Println(i18n.T("config_cmd.usage"))
+		return 2
+	}
+}
+
+// setConfigValue 修改配置项并保存；修改引入新的校验错误时不保存
+func setConfigValue(cfg *config.Config, key, value string) int {
+	before := issueSet(config.ValidateConfig(cfg))
+	if err := config.SetValue(cfg, key, value); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	var introduced []config.ValidationIssue
+	for _, issue := range config.ValidateConfig(cfg) {
+		if !issue.Warning && !before[issue.Key+"\x00"+issue.Message] {
+			introduced = append(introduced, issue)
+		}
+	}
+	if len(introduced) > 0 {
+		fmt.Fprintln(os.Stderr, i18n.T("config_cmd.set_rejected"))
+		for _, issue := range introduced {
+			// 行号对应内存中序列化的配置而非配置文件，这里只显示配置项和原因
+			issue.Line, issue.Column = 0, 0
+			fmt.Fprintf(os.Stderr, "  %s\n", issue)
+		}
+		return 1
+	}
+
+	if err := config.SaveConfig(cfg); err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("startup.save_config_failed", err))
+		return 1
+	}
+	display, err := config.GetValue(cfg, key)
+	if err != nil {
+		display = value
+	}
+	if config.IsSecretKey(key) {
+		display = config.MaskSecret(display)
+	}
+	fmt.Println(i18n.T("config_cmd.set_done", key, display))
+	return 0
+}
+
+// issueSet 以配置项和原因标识校验问题，用于比较修改前后的差异
+func issueSet(issues []config.ValidationIssue) map[string]bool {
+	set := make(map[string]bool, len(issues))
+	for _, issue := range issues {
+		set[issue.Key+"\x00"+issue.Message] = true
+	}
+	return set
+}
+
+// editConfig 用 $VISUAL / $EDITOR 打开配置文件，退出编辑器后校验并应用（明文 API Key 迁移到密钥环）
+func editConfig() int {
+	path, err := config.ConfigPath()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		// 首次编辑时写入默认配置，方便在已有结构上修改
+		cfg, err := config.LoadConfig()
+		if err == nil {
+			err = config.SaveConfig(cfg)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, i18n.T("startup.save_config_failed", err))
+			return 1
+		}
+	}
+
+	editor := strings.Fields(utils.DefaultEditor())
+	cmd := exec.Command(editor[0], append(editor[1:], path)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("config_cmd.editor_failed", err))
+		return 1
+	}
+
+	_, issues, err := config.ValidateConfigFile()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if len(issues) > 0 {
+		printConfigIssues()
+	}
+	if _, err := config.LoadConfig(); err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("startup.load_config_failed", err))
+		return 1
+	}
+	if len(issues) == 0 {
+		fmt.Println(i18n.T("config_cmd.edit_valid", path))
+	}
+	return 0
+}
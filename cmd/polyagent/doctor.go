@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/api"
+	"github.com/Zacy-Sokach/PolyAgent/internal/config"
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
+	"github.com/Zacy-Sokach/PolyAgent/internal/mcp"
+	"github.com/Zacy-Sokach/PolyAgent/internal/utils"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// doctorTimeout 单项网络检查的超时
+const doctorTimeout = 10 * time.Second
+
+// doctorReport 汇总诊断结果
+type doctorReport struct {
+	problems int
+	warnings int
+}
+
+func (r *doctorReport) ok(name, detail string) {
+	fmt.Printf("%s %s: %s\n", lipgloss.NewStyle().Foreground(lipgloss.Color("10")).Render("✓"), name, detail)
+}
+
+func (r *doctorReport) warn(name, detail string) {
+	r.warnings++
+	fmt.Printf("%s %s: %s\n", lipgloss.NewStyle().Foreground(lipgloss.Color("11")).Render("!"), name, detail)
+}
+
+func (r *doctorReport) fail(name, detail string) {
+	r.problems++
+	fmt.Printf("%s %s: %s\n", lipgloss.NewStyle().Foreground(lipgloss.Color("9")).Render("✗"), name, detail)
+}
+
+// runDoctorCommand 处理 polyagent doctor：加载配置（失败时仍检查其余项目）后运行诊断
+func runDoctorCommand(_ *cliOptions, _ []string) int {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return runDoctor(nil)
+	}
+	i18n.SetLanguage(cfg.Language)
+	// 无效的代理和证书由配置校验报告
+	utils.ConfigureNetwork(cfg.Network.Proxy, cfg.Network.CAFiles)
+	return runDoctor(cfg)
+}
+
+// runDoctor 检查配置、API Key、网络连通性、git 和终端渲染，有问题时返回非零退出码
+// cfg 为 nil 表示配置文件无法加载
+func runDoctor(cfg *config.Config) int {
+	report := &doctorReport{}
+	fmt.Println(lipgloss.NewStyle().Bold(true).Render(i18n.T("doctor.title")))
+	fmt.Println()
+
+	checkConfigFile(report)
+	if cfg != nil {
+		checkKeys(report, cfg)
+		checkNetwork(report, cfg)
+	}
+	checkGit(report)
+	checkTerminal(report)
+
+	fmt.Println()
+	if report.problems == 0 && report.warnings == 0 {
+		fmt.Println(i18n.T("doctor.summary_ok"))
+	} else {
+		fmt.Println(i18n.T("doctor.summary_problems", report.problems, report.warnings))
+	}
+	if report.problems > 0 {
+		return 1
+	}
+	return 0
+}
+
+// checkConfigFile 校验配置文件，逐条列出问题及行号
+func checkConfigFile(report *doctorReport) {
+	path, issues, err := config.ValidateConfigFile()
+	if err != nil {
+		report.fail(i18n.T("doctor.config_file"), i18n.T("doctor.config_invalid", err))
+		return
+	}
+	if _, statErr := os.Stat(path); os.IsNotExist(statErr) {
+		report.ok(i18n.T("doctor.config_file"), i18n.T("doctor.config_missing", path))
+		return
+	}
+	report.ok(i18n.T("doctor.config_file"), path)
+	for _, issue := range issues {
+		if issue.Warning {
+			report.warn(i18n.T("doctor.config"), issue.String())
+		} else {
+			report.fail(i18n.T("doctor.config"), issue.String())
+		}
+	}
+}
+
+// checkKeys 检查 API Key 是否已配置及其保存位置
+func checkKeys(report *doctorReport, cfg *config.Config) {
+	storage := i18n.T("doctor.storage_keyring")
+	switch {
+	case cfg.SecretStorage == config.SecretStorageFile:
+		storage = i18n.T("doctor.storage_file")
+		report.ok(i18n.T("doctor.keyring"), i18n.T("doctor.keyring_disabled"))
+	default:
+		if err := config.KeyringStatus(); err != nil {
+			storage = i18n.T("doctor.storage_file")
+			report.warn(i18n.T("doctor.keyring"), i18n.T("doctor.keyring_unavailable", err))
+		} else {
+			report.ok(i18n.T("doctor.keyring"), i18n.T("doctor.keyring_ok"))
+		}
+	}
+
+	if cfg.APIKey == "" {
+		report.fail(i18n.T("doctor.api_key"), i18n.T("doctor.api_key_missing"))
+	} else {
+		report.ok(i18n.T("doctor.api_key"), i18n.T("doctor.key_configured", storage))
+	}
+	if cfg.TavilyAPIKey == "" {
+		report.warn(i18n.T("doctor.tavily_key"), i18n.T("doctor.tavily_key_missing"))
+	} else {
+		report.ok(i18n.T("doctor.tavily_key"), i18n.T("doctor.key_configured", storage))
+	}
+}
+
+// checkNetwork 检查模型 API 和 Tavily 是否可访问（使用配置的代理和证书）
+func checkNetwork(report *doctorReport, cfg *config.Config) {
+	if cfg.Offline {
+		report.ok(i18n.T("doctor.network"), i18n.T("doctor.network_offline"))
+		return
+	}
+	client := &http.Client{Timeout: doctorTimeout, Transport: utils.NewTransport()}
+	modelEndpoint := api.Endpoint
+	if cfg.BaseURL != "" {
+		modelEndpoint = cfg.BaseURL
+	}
+	for _, endpoint := range []string{modelEndpoint, mcp.TavilyEndpoint} {
+		host := endpoint
+		if u, err := url.Parse(endpoint); err == nil {
+			host = u.Host
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), doctorTimeout)
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, endpoint, nil)
+		if err != nil {
+			cancel()
+			report.fail(i18n.T("doctor.network"), i18n.T("doctor.network_failed", host, err))
+			continue
+		}
+		start := time.Now()
+		resp, err := client.Do(req)
+		cancel()
+		if err != nil {
+			report.fail(i18n.T("doctor.network"), i18n.T("doctor.network_failed", host, err))
+			continue
+		}
+		resp.Body.Close()
+		// 任何 HTTP 响应都说明网络、代理和 TLS 正常
+		report.ok(i18n.T("doctor.network"), i18n.T("doctor.network_ok", host, time.Since(start).Round(time.Millisecond)))
+	}
+}
+
+// checkGit 检查 git 是否可用
+func checkGit(report *doctorReport) {
+	path, err := exec.LookPath("git")
+	if err != nil {
+		report.warn(i18n.T("doctor.git"), i18n.T("doctor.git_missing"))
+		return
+	}
+	output, err := exec.Command(path, "--version").Output()
+	if err != nil {
+		report.warn(i18n.T("doctor.git"), fmt.Sprintf("%s: %v", path, err))
+		return
+	}
+	report.ok(i18n.T("doctor.git"), strings.TrimSpace(string(output)))
+}
+
+// checkTerminal 检查界面渲染所需的终端能力：是否为终端、颜色支持和 UTF-8 区域设置
+func checkTerminal(report *doctorReport) {
+	name := i18n.T("doctor.terminal")
+	if !isTerminal() {
+		report.warn(name, i18n.T("doctor.terminal_not_tty"))
+		return
+	}
+	report.ok(name, i18n.T("doctor.terminal_info", lipgloss.ColorProfile().Name(), os.Getenv("TERM")))
+	if runtime.GOOS != "windows" && !utf8Locale() {
+		report.warn(name, i18n.T("doctor.no_utf8"))
+	}
+}
+
+// utf8Locale 按 LC_ALL、LC_CTYPE、LANG 的优先级判断区域设置是否为 UTF-8
+func utf8Locale() bool {
+	for _, name := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if value := os.Getenv(name); value != "" {
+			value = strings.ToLower(value)
+			return strings.Contains(value, "utf-8") || strings.Contains(value, "utf8")
+		}
+	}
+	return false
+}
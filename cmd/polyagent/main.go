@@ -3,11 +3,18 @@ package main
 import (
 	"fmt"
 	"os"
+	"os/signal"
 	"runtime/debug"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/Zacy-Sokach/PolyAgent/internal/api"
 	"github.com/Zacy-Sokach/PolyAgent/internal/config"
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
 	"github.com/Zacy-Sokach/PolyAgent/internal/mcp"
 	"github.com/Zacy-Sokach/PolyAgent/internal/tui"
+	"github.com/Zacy-Sokach/PolyAgent/internal/utils"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
@@ -17,28 +24,22 @@ var (
 )
 
 func main() {
-	// 处理命令行参数
-	if len(os.Args) > 1 {
-		switch os.Args[1] {
-		case "-v", "--version":
-			fmt.Printf("PolyAgent %s\n", Version)
-			os.Exit(0)
-		case "-h", "--help":
-			fmt.Println("PolyAgent - Vibe Coding Tool")
-			fmt.Println()
-			fmt.Println("Usage:")
-			fmt.Println("  polyagent              Start the interactive TUI")
-			fmt.Println("  polyagent -v, --version  Show version information")
-			fmt.Println("  polyagent -h, --help     Show help information")
-			fmt.Println()
-			fmt.Println("Commands in TUI:")
-			fmt.Println("  check update           Check for updates")
-			fmt.Println("  update                 Update PolyAgent to latest version")
-			fmt.Println("  /init                  Initialize project documentation")
-			os.Exit(0)
-		}
+	opts, command, args, err := parseCommandLine(os.Args[1:])
+	if err != nil {
+		os.Exit(usageError(err))
+	}
+	if opts.version {
+		fmt.Printf("PolyAgent %s\n", Version)
+		os.Exit(0)
+	}
+	if err := opts.apply(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if command != nil {
+		os.Exit(command.run(opts, args))
 	}
-	
+
 	// 添加panic恢复
 	defer func() {
 		if r := recover(); r != nil {
@@ -51,36 +52,73 @@ func main() {
 
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		fmt.Printf("加载配置失败: %v\n", err)
+		fmt.Println(i18n.T("startup.load_config_failed", err))
+		printConfigIssues()
+		os.Exit(1)
+	}
+
+	// 设置界面语言（无法识别时回退到默认语言）
+	if err := i18n.SetLanguage(cfg.Language); err != nil {
+		fmt.Printf("warning: %v\n", err)
+	}
+
+	// 应用代理和 CA 设置，需在创建任何 HTTP 客户端之前完成
+	if err := utils.ConfigureNetwork(cfg.Network.Proxy, cfg.Network.CAFiles); err != nil {
+		fmt.Println(i18n.T("startup.network_config_failed", err))
 		os.Exit(1)
 	}
+	printConfigIssues()
 
 	if cfg.APIKey == "" {
-		fmt.Println(lipgloss.NewStyle().Foreground(lipgloss.Color("11")).Render("欢迎使用 PolyAgent!"))
-		fmt.Println("首次使用需要配置 GLM-4.5 API Key")
-		fmt.Print("请输入你的 GLM API Key: ")
+		fmt.Println(lipgloss.NewStyle().Foreground(lipgloss.Color("11")).Render(i18n.T("startup.welcome")))
+		fmt.Println(i18n.T("startup.need_api_key"))
+		fmt.Print(i18n.T("startup.enter_api_key"))
 
 		var apiKey string
 		fmt.Scanln(&apiKey)
 
 		cfg.APIKey = apiKey
 		if err := config.SaveConfig(cfg); err != nil {
-			fmt.Printf("保存配置失败: %v\n", err)
+			fmt.Println(i18n.T("startup.save_config_failed", err))
 			os.Exit(1)
 		}
 
-		fmt.Println(lipgloss.NewStyle().Foreground(lipgloss.Color("10")).Render("GLM API Key 已保存!"))
+		fmt.Println(lipgloss.NewStyle().Foreground(lipgloss.Color("10")).Render(i18n.T("startup.api_key_saved")))
 	}
 
-	// 检查 Tavily API Key（用于搜索功能）
-	if cfg.TavilyAPIKey == "" {
+	// 应用客户端限流配置
+	for provider, limit := range cfg.RateLimits {
+		api.SetRateLimit(provider, limit.RequestsPerMinute, limit.TokensPerMinute)
+	}
+	api.SetTimeouts(api.Timeouts{
+		Connect:        time.Duration(cfg.Timeouts.Connect) * time.Second,
+		ResponseHeader: time.Duration(cfg.Timeouts.ResponseHeader) * time.Second,
+		Request:        time.Duration(cfg.Timeouts.Request) * time.Second,
+	})
+	api.SetBaseURL(cfg.BaseURL)
+	api.SetTransportOptions(api.TransportOptions{
+		ForceHTTP1:          cfg.Network.ForceHTTP1,
+		MaxConnsPerHost:     cfg.Network.MaxConnsPerHost,
+		MaxIdleConnsPerHost: cfg.Network.MaxIdleConnsPerHost,
+		IdleConnTimeout:     time.Duration(cfg.Network.IdleConnTimeout) * time.Second,
+		KeepAlive:           time.Duration(cfg.Network.KeepAlive) * time.Second,
+	})
+
+	// 命令行参数仅对本次运行生效，不写回配置文件
+	if opts.offline {
+		cfg.Offline = true
+	}
+
+
+	// 检查 Tavily API Key（用于搜索功能，离线模式下不需要）
+	if cfg.TavilyAPIKey == "" && !cfg.Offline {
 		fmt.Println()
-		fmt.Println(lipgloss.NewStyle().Foreground(lipgloss.Color("11")).Render("💡 检测到未配置 Tavily API Key"))
-		fmt.Println("Tavily API Key 用于网页搜索和爬取功能 (web_search, web_crawl)")
-		fmt.Println("如果暂时不需要使用搜索功能，可以直接回车跳过")
+		fmt.Println(lipgloss.NewStyle().Foreground(lipgloss.Color("11")).Render(i18n.T("startup.tavily_missing")))
+		fmt.Println(i18n.T("startup.tavily_usage"))
+		fmt.Println(i18n.T("startup.tavily_skip_hint"))
 		fmt.Println()
-		fmt.Println("获取免费 API Key: https://tavily.com/")
-		fmt.Print("请输入 Tavily API Key（直接回车跳过）: ")
+		fmt.Println(i18n.T("startup.tavily_get_key"))
+		fmt.Print(i18n.T("startup.tavily_enter_key"))
 
 		var tavilyKey string
 		fmt.Scanln(&tavilyKey)
@@ -88,12 +126,20 @@ func main() {
 		if tavilyKey != "" {
 			cfg.TavilyAPIKey = tavilyKey
 			if err := config.SaveConfig(cfg); err != nil {
-				fmt.Printf("保存配置失败: %v\n", err)
+				fmt.Println(i18n.T("startup.save_config_failed", err))
 				os.Exit(1)
 			}
-			fmt.Println(lipgloss.NewStyle().Foreground(lipgloss.Color("10")).Render("✓ Tavily API Key 已保存!"))
+			fmt.Println(lipgloss.NewStyle().Foreground(lipgloss.Color("10")).Render(i18n.T("startup.tavily_saved")))
 		} else {
-			fmt.Println(lipgloss.NewStyle().Foreground(lipgloss.Color("8")).Render("跳过配置，搜索功能将在首次使用时提示配置"))
+			fmt.Println(lipgloss.NewStyle().Foreground(lipgloss.Color("8")).Render(i18n.T("startup.tavily_skipped")))
+		}
+	}
+
+	// 调试用的性能分析端点，仅在指定 --pprof 时开启
+	if opts.pprof != "" {
+		if err := startPprofServer(opts.pprof); err != nil {
+			fmt.Println(i18n.T("startup.pprof_failed", err))
+			os.Exit(1)
 		}
 	}
 
@@ -108,25 +154,106 @@ func main() {
 			BackupDir:       cfg.FileEngine.BackupDir,
 		}
 		toolRegistry := mcp.DefaultToolRegistry(&fileEngineConfig)
+		if cfg.Offline {
+			mcp.DisableNetworkTools(toolRegistry)
+		}
+		// 未信任的工作区只开放只读工具，可在会话中通过 /trust 信任；
+		// 也不提前建立项目索引，信任后第一次查找符号时再写入 .polyagent/index
+		if promptWorkspaceTrust(cfg) {
+			toolRegistry.StartProjectIndex()
+		} else {
+			toolRegistry.SetReadOnly(true)
+		}
+		// 每个会话的工具调用写入审计日志，失败时仅提示，不影响使用
+		if auditLog, err := mcp.NewAuditLog(mcp.SessionID()); err != nil {
+			fmt.Println(i18n.T("startup.audit_log_failed", err))
+		} else {
+			defer auditLog.Close()
+			toolRegistry.SetAuditLog(auditLog)
+		}
+		// 会话写入预算，防止失控的生成循环写满磁盘
+		toolRegistry.SetWriteQuota(mcp.NewWriteQuota(int64(cfg.WriteQuota.MaxMB)*1024*1024, cfg.WriteQuota.MaxFiles))
+		// 工具 panic 转换为失败的工具结果，同时发布系统错误事件
+		mcp.SetPanicHandler(tui.PublishToolPanic)
 		toolManager := tui.NewToolManagerWithRegistry(toolRegistry)
 		
 		// 暂时注释掉版本设置
 		// tui.Version = Version
 		
 		// 创建模型并使用指针
-		model := tui.InitialModel(cfg.APIKey, toolManager)
-		p := tea.NewProgram(&model, tea.WithAltScreen())
+		model := tui.InitialModelWithConfig(cfg, toolManager)
+		// --model 不修改 cfg，避免保存配置时写回配置文件
+		model.SetModelOverride(opts.model)
+		if opts.resume > 0 {
+			if err := model.ResumeHistory(int(opts.resume)); err != nil {
+				fmt.Println(i18n.T("startup.resume_failed", err))
+				os.Exit(1)
+			}
+		}
+		// 自行处理终止信号，保存会话后再退出
+		p := tea.NewProgram(&model, tea.WithAltScreen(), tea.WithReportFocus(), tea.WithoutSignalHandler())
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+		go func() {
+			sig := <-sigCh
+			p.Send(tui.ShutdownMsg{Signal: sig})
+		}()
+		// 将工具进度转发到界面
+		mcp.SetProgressHandler(func(progress mcp.Progress) {
+			p.Send(tui.ToolProgressMsg{Progress: progress})
+		})
 		if _, err := p.Run(); err != nil {
-			fmt.Printf("程序运行错误: %v\n", err)
+			fmt.Println(i18n.T("startup.run_failed", err))
 			os.Exit(1)
 		}
 	} else {
 		// 非交互式环境，使用简单模式
-		fmt.Println("PolyAgent 运行在非交互式模式")
-		fmt.Println("请确保在交互式终端中运行以获得完整TUI体验")
-		fmt.Printf("当前API Key: %s\n", maskAPIKey(cfg.APIKey))
-		fmt.Println("程序将在非交互式环境中退出")
+		fmt.Println(i18n.T("startup.non_interactive"))
+		fmt.Println(i18n.T("startup.non_interactive_hint"))
+		fmt.Println(i18n.T("startup.current_api_key", config.MaskSecret(cfg.APIKey)))
+		fmt.Println(i18n.T("startup.non_interactive_exit"))
+	}
+}
+
+// promptWorkspaceTrust 首次在目录中运行时询问是否信任该目录，返回是否已信任
+func promptWorkspaceTrust(cfg *config.Config) bool {
+	workspace, err := os.Getwd()
+	if err != nil || cfg.IsWorkspaceTrusted(workspace) {
+		return err == nil
+	}
+
+	fmt.Println()
+	fmt.Println(lipgloss.NewStyle().Foreground(lipgloss.Color("11")).Render(i18n.T("startup.trust_title", workspace)))
+	fmt.Println(i18n.T("startup.trust_capabilities"))
+	fmt.Println(i18n.T("startup.trust_untrusted_hint"))
+	fmt.Print(i18n.T("startup.trust_confirm"))
+
+	var answer string
+	fmt.Scanln(&answer)
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	if answer != "y" && answer != "yes" {
+		fmt.Println(lipgloss.NewStyle().Foreground(lipgloss.Color("8")).Render(i18n.T("startup.trust_declined")))
+		return false
+	}
+
+	cfg.TrustWorkspace(workspace)
+	if err := config.SaveConfig(cfg); err != nil {
+		fmt.Println(i18n.T("startup.save_config_failed", err))
 	}
+	return true
+}
+
+// printConfigIssues 启动时列出配置文件中的问题（带行号），不阻止启动
+func printConfigIssues() {
+	path, issues, err := config.ValidateConfigFile()
+	if err != nil || len(issues) == 0 {
+		return
+	}
+	fmt.Println(lipgloss.NewStyle().Foreground(lipgloss.Color("11")).Render(i18n.T("startup.config_issues", path)))
+	for _, issue := range issues {
+		fmt.Printf("  %s\n", issue)
+	}
+	fmt.Println()
 }
 
 func isTerminal() bool {
@@ -136,10 +263,3 @@ func isTerminal() bool {
 	}
 	return (fileInfo.Mode() & os.ModeCharDevice) != 0
 }
-
-func maskAPIKey(key string) string {
-	if len(key) <= 8 {
-		return "***"
-	}
-	return key[:4] + "***" + key[len(key)-4:]
-}
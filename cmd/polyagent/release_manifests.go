@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
+	"github.com/Zacy-Sokach/PolyAgent/internal/update"
+)
+
+// 生成的清单相对于工作目录的路径
+var (
+	homebrewFormulaPath = filepath.Join("packaging", "homebrew", "polyagent.rb")
+	scoopManifestPath   = filepath.Join("packaging", "scoop", "polyagent.json")
+)
+
+// runReleaseManifests 处理 polyagent release-manifests <tag> [checksums.txt]：
+// 根据发布的校验和生成或更新 Homebrew formula 和 Scoop 清单，未指定校验和文件时从发布中下载
+func runReleaseManifests(_ *cliOptions, args []string) int {
+	if len(args) < 1 || len(args) > 2 {
+		fmt.Println(i18n.T("release_manifests.usage"))
+		return 2
+	}
+	tag := args[0]
+
+	var checksums map[string]string
+	if len(args) == 2 {
+		data, err := os.ReadFile(args[1])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, i18n.T("release_manifests.read_failed", err))
+			return 1
+		}
+		checksums = update.ParseChecksums(string(data))
+	} else {
+		var err error
+		if checksums, err = update.ReleaseChecksums(tag); err != nil {
+			fmt.Fprintln(os.Stderr, i18n.T("release_manifests.fetch_failed", tag, err))
+			return 1
+		}
+	}
+
+	formula, err := update.HomebrewFormula(tag, checksums)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("release_manifests.failed", err))
+		return 1
+	}
+	scoop, err := update.ScoopManifest(tag, checksums)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, i18n.T("release_manifests.failed", err))
+		return 1
+	}
+
+	for _, file := range []struct {
+		path    string
+		content []byte
+	}{
+		{homebrewFormulaPath, []byte(formula)},
+		{scoopManifestPath, scoop},
+	} {
+		if err := os.MkdirAll(filepath.Dir(file.path), 0755); err != nil {
+			fmt.Fprintln(os.Stderr, i18n.T("release_manifests.write_failed", file.path, err))
+			return 1
+		}
+		if err := os.WriteFile(file.path, file.content, 0644); err != nil {
+			fmt.Fprintln(os.Stderr, i18n.T("release_manifests.write_failed", file.path, err))
+			return 1
+		}
+		fmt.Println(i18n.T("release_manifests.written", file.path))
+	}
+	return 0
+}
@@ -1,12 +1,12 @@
 package api
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"strings"
 	"sync"
@@ -19,52 +19,139 @@ const (
 	baseURL = "https://open.bigmodel.cn/api/paas/v4"
 )
 
-// 全局共享的HTTP客户端，实现连接池化
+// Endpoint API 服务地址，供诊断命令检查连通性
+const Endpoint = baseURL
+
+// 配置的服务地址，为空时使用 baseURL
+var (
+	baseURLMu         sync.RWMutex
+	configuredBaseURL string
+)
+
+// SetBaseURL 设置兼容 OpenAI 接口的服务地址（如 Ollama 的 http://localhost:11434/v1），为空时恢复默认地址
+func SetBaseURL(url string) {
+	baseURLMu.Lock()
+	defer baseURLMu.Unlock()
+	configuredBaseURL = strings.TrimRight(url, "/")
+}
+
+// CurrentBaseURL 返回请求使用的服务地址
+func CurrentBaseURL() string {
+	baseURLMu.RLock()
+	defer baseURLMu.RUnlock()
+	if configuredBaseURL != "" {
+		return configuredBaseURL
+	}
+	return baseURL
+}
+
+// DefaultModel 未指定模型时使用的模型
+const DefaultModel = "glm-4.5"
+
+// DefaultTemperature 未指定采样温度时使用的温度
+const DefaultTemperature = 0.6
+
+// 全局共享的HTTP客户端，实现连接池化。流式和非流式请求的超时不同，分别使用各自的客户端
 var (
-	sharedHTTPClient utils.Doer
-	httpClientOnce   sync.Once
+	httpClientsMu      sync.Mutex
+	sharedHTTPClient   utils.Doer
+	sharedStreamClient utils.Doer
 )
 
-// getSharedHTTPClient 返回共享的HTTP客户端实例
+// getSharedHTTPClient 返回非流式请求共享的HTTP客户端，整体超时为 Timeouts.Request
 func getSharedHTTPClient() utils.Doer {
-	httpClientOnce.Do(func() {
-		baseClient := &http.Client{
-			Timeout: 30 * time.Second,
-			Transport: &http.Transport{
-				MaxIdleConns:        100,
-				MaxIdleConnsPerHost: 50,        // 从10增加到50，提高并发性能
-				IdleConnTimeout:     90 * time.Second,
-				DisableCompression:  false,      // 启用压缩，减少传输数据量
-				MaxConnsPerHost:     100,        // 新增：限制每个主机的最大连接数
-			},
-		}
-		// 包装为带重试机制的客户端
-		retryConfig := &utils.RetryConfig{
-			MaxRetries:         3,
-			InitialDelay:       1 * time.Second,
-			MaxDelay:           30 * time.Second,
-			BackoffMultiplier:  2.0,
-			RetryableStatusCodes: []int{
-				http.StatusRequestTimeout,      // 408
-				http.StatusTooManyRequests,     // 429
-				http.StatusInternalServerError, // 500
-				http.StatusBadGateway,          // 502
-				http.StatusServiceUnavailable,  // 503
-				http.StatusGatewayTimeout,      // 504
-			},
-			RetryableErrors: func(err error) bool {
-				// 重试网络错误和超时
-				return true
-			},
-		}
-		sharedHTTPClient = utils.NewRetryableHTTPClient(baseClient, retryConfig)
-	})
+	httpClientsMu.Lock()
+	defer httpClientsMu.Unlock()
+	if sharedHTTPClient == nil {
+		t := CurrentTimeouts()
+		sharedHTTPClient = newRetryableClient(&http.Client{
+			Timeout:   t.Request,
+			Transport: &statsTransport{base: newAPITransport(t, CurrentTransportOptions())},
+		})
+	}
 	return sharedHTTPClient
 }
 
+// getSharedStreamClient 返回流式请求共享的HTTP客户端。没有整体超时，生成时间再长也不会被中止；
+// 收到响应头之前受 Timeouts.ResponseHeader 限制，之后由空闲检测处理停滞
+func getSharedStreamClient() utils.Doer {
+	httpClientsMu.Lock()
+	defer httpClientsMu.Unlock()
+	if sharedStreamClient == nil {
+		t := CurrentTimeouts()
+		transport := newAPITransport(t, CurrentTransportOptions())
+		transport.ResponseHeaderTimeout = t.ResponseHeader
+		sharedStreamClient = newRetryableClient(&http.Client{Transport: &statsTransport{base: transport}})
+	}
+	return sharedStreamClient
+}
+
+// newAPITransport 在应用了代理和 CA 设置的 Transport 上设置连接超时、连接池参数和协议
+func newAPITransport(t Timeouts, o TransportOptions) *http.Transport {
+	transport := utils.NewTransport()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   t.Connect,
+		KeepAlive: o.KeepAlive,
+	}).DialContext
+	transport.TLSHandshakeTimeout = t.Connect
+	transport.MaxIdleConns = 100
+	transport.MaxIdleConnsPerHost = o.MaxIdleConnsPerHost
+	transport.IdleConnTimeout = o.IdleConnTimeout
+	transport.DisableCompression = false // 启用压缩，减少传输数据量
+	transport.MaxConnsPerHost = o.MaxConnsPerHost
+	if o.ForceHTTP1 {
+		// 部分代理在 HTTP/2 下会缓冲或截断 SSE 流
+		protocols := new(http.Protocols)
+		protocols.SetHTTP1(true)
+		transport.Protocols = protocols
+		transport.ForceAttemptHTTP2 = false
+	}
+	return transport
+}
+
+// newRetryableClient 包装为带重试机制的客户端
+func newRetryableClient(baseClient *http.Client) utils.Doer {
+	retryConfig := &utils.RetryConfig{
+		MaxRetries:         3,
+		InitialDelay:       1 * time.Second,
+		MaxDelay:           30 * time.Second,
+		BackoffMultiplier:  2.0,
+		RetryableStatusCodes: []int{
+			http.StatusRequestTimeout,      // 408
+			http.StatusTooManyRequests,     // 429
+			http.StatusInternalServerError, // 500
+			http.StatusBadGateway,          // 502
+			http.StatusServiceUnavailable,  // 503
+			http.StatusGatewayTimeout,      // 504
+		},
+		RetryableErrors: func(err error) bool {
+			// 重试网络错误和超时
+			return true
+		},
+	}
+	return utils.NewRetryableHTTPClient(baseClient, retryConfig)
+}
+
 type Client struct {
-	apiKey string
-	client utils.Doer
+	apiKey     string
+	client     utils.Doer
+	toolChoice *ToolChoice // 为空时使用 auto
+	// 响应格式，为空时由服务端决定（普通文本）
+	responseFormat *ResponseFormat
+	// 限流使用的服务商标识
+	provider string
+	// 流式响应的空闲超时，为 0 时使用 DefaultStreamIdleTimeout
+	streamIdleTimeout time.Duration
+	// 仅作用于该客户端的 Hook，在全局 Hook 之后调用
+	hooks []Hook
+	// 请求使用的模型，为空时使用 DefaultModel
+	model string
+	// 采样温度，为 nil 时使用 DefaultTemperature
+	temperature *float64
+	// 流式请求使用的客户端，没有整体超时
+	streamClient utils.Doer
+	// 在提示中描述工具并从回复文本中解析工具调用，见 WithToolEmulation
+	toolEmulation bool
 }
 
 // NewClient 创建新的GLM-4.5 API客户端
@@ -72,49 +159,166 @@ type Client struct {
 // 返回配置好的API客户端实例
 func NewClient(apiKey string) *Client {
 	return &Client{
-		apiKey: apiKey,
-		client: getSharedHTTPClient(),
+		apiKey:       apiKey,
+		client:       getSharedHTTPClient(),
+		streamClient: getSharedStreamClient(),
+		provider:     ProviderGLM,
 	}
 }
 
-// ChatCompletion 发送聊天补全请求到GLM-4.5 API
-// messages: 消息历史数组
-// stream: 是否使用流式响应
-// tools: 可用的工具列表
-// 返回聊天响应或错误
-func (c *Client) ChatCompletion(messages []Message, stream bool, tools []Tool) (*ChatResponse, error) {
+// waitRateLimit 按服务商限流配置等待发送额度
+func (c *Client) waitRateLimit(ctx context.Context, body []byte) error {
+	if err := rateLimiterFor(c.provider).Wait(ctx, estimateRequestTokens(body)); err != nil {
+		return fmt.Errorf("等待限流额度时取消: %w", err)
+	}
+	return nil
+}
+
+// WithToolChoice 返回使用指定工具调用策略的客户端副本
+func (c *Client) WithToolChoice(choice *ToolChoice) *Client {
+	clone := *c
+	clone.toolChoice = choice
+	return &clone
+}
+
+// WithResponseFormat 返回使用指定响应格式的客户端副本
+func (c *Client) WithResponseFormat(format *ResponseFormat) *Client {
+	clone := *c
+	clone.responseFormat = format
+	return &clone
+}
+
+// WithModel 返回使用指定模型的客户端副本
+func (c *Client) WithModel(model string) *Client {
+	clone := *c
+	clone.model = model
+	return &clone
+}
+
+// Model 返回请求使用的模型
+func (c *Client) Model() string {
+	if c.model == "" {
+		return DefaultModel
+	}
+	return c.model
+}
+
+// WithTemperature 返回使用指定采样温度的客户端副本
+func (c *Client) WithTemperature(temperature float64) *Client {
+	clone := *c
+	clone.temperature = &temperature
+	return &clone
+}
+
+// WithStreamIdleTimeout 返回使用指定流式空闲超时的客户端副本
+func (c *Client) WithStreamIdleTimeout(timeout time.Duration) *Client {
+	clone := *c
+	clone.streamIdleTimeout = timeout
+	return &clone
+}
+
+// idleTimeout 返回流式响应的空闲超时
+func (c *Client) idleTimeout() time.Duration {
+	if c.streamIdleTimeout > 0 {
+		return c.streamIdleTimeout
+	}
+	return DefaultStreamIdleTimeout
+}
+
+// newChatRequest 构建聊天请求并设置工具调用策略
+func (c *Client) newChatRequest(messages []Message, stream bool, tools []Tool) (ChatRequest, error) {
+	temperature := DefaultTemperature
+	if c.temperature != nil {
+		temperature = *c.temperature
+	}
 	req := ChatRequest{
-		Model:       "glm-4.5",
+		Model:       c.Model(),
 		Messages:    messages,
 		Stream:      stream,
-		MaxTokens:   4096,
-		Temperature: 0.6,
+		MaxTokens:   DefaultMaxTokens,
+		Temperature: temperature,
 		Thinking: &Thinking{
 			Type: "enabled",
 		},
+		ResponseFormat: c.responseFormat,
 	}
 
-	if len(tools) > 0 {
-		req.Tools = tools
-		// 设置为自动选择工具
-		autoChoice, _ := json.Marshal("auto")
-		req.ToolChoice = autoChoice
+	if len(tools) == 0 {
+		c.applyToolEmulation(&req)
+		return req, nil
+	}
+
+	req.Tools = tools
+	choice := c.toolChoice
+	if choice == nil {
+		choice = AutoToolChoice()
+	}
+
+	switch {
+	case choice.Function != "":
+		found := false
+		for _, tool := range tools {
+			if tool.Function.Name == choice.Function {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return req, fmt.Errorf("tool_choice 指定的工具不存在: %s", choice.Function)
+		}
+	case choice.Mode != "" && choice.Mode != ToolChoiceAuto && choice.Mode != ToolChoiceNone && choice.Mode != ToolChoiceRequired:
+		return req, fmt.Errorf("无效的 tool_choice: %s", choice.Mode)
+	}
+	req.ToolChoice = choice
+	c.applyToolEmulation(&req)
+
+	return req, nil
+}
+
+// ChatCompletion 发送聊天补全请求到GLM-4.5 API
+// messages: 消息历史数组
+// stream: 是否使用流式响应
+// tools: 可用的工具列表
+// 返回聊天响应或错误
+func (c *Client) ChatCompletion(messages []Message, stream bool, tools []Tool) (*ChatResponse, error) {
+	req, err := c.newChatRequest(messages, stream, tools)
+	if err != nil {
+		return nil, err
 	}
 
+	hooks := c.activeHooks()
+	hooks.request(&req)
+
+	var resp *ChatResponse
 	if stream {
-		return c.chatStream(req)
+		resp, err = c.chatStream(req, hooks)
+	} else {
+		resp, err = c.chatNonStream(req)
+		if err == nil && c.toolEmulation {
+			parseEmulatedResponse(resp)
+		}
 	}
-	return c.chatNonStream(req)
+	if err != nil {
+		hooks.error(err)
+		return nil, err
+	}
+
+	hooks.complete(resp)
+	return resp, nil
 }
 
 func (c *Client) chatNonStream(req ChatRequest) (*ChatResponse, error) {
-	url := fmt.Sprintf("%s/chat/completions", baseURL)
+	url := fmt.Sprintf("%s/chat/completions", CurrentBaseURL())
 
 	body, err := json.Marshal(req)
 	if err != nil {
 		return nil, fmt.Errorf("序列化请求失败: %w", err)
 	}
 
+	if err := c.waitRateLimit(context.Background(), body); err != nil {
+		return nil, err
+	}
+
 	httpReq, err := http.NewRequest("POST", url, bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("创建请求失败: %w", err)
@@ -131,7 +335,7 @@ func (c *Client) chatNonStream(req ChatRequest) (*ChatResponse, error) {
 
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API请求失败 (状态码: %d): %s", resp.StatusCode, string(bodyBytes))
+		return nil, newAPIError(resp.StatusCode, bodyBytes)
 	}
 
 	var chatResp ChatResponse
@@ -142,14 +346,18 @@ func (c *Client) chatNonStream(req ChatRequest) (*ChatResponse, error) {
 	return &chatResp, nil
 }
 
-func (c *Client) chatStream(req ChatRequest) (*ChatResponse, error) {
-	url := fmt.Sprintf("%s/chat/completions", baseURL)
+func (c *Client) chatStream(req ChatRequest, hooks hookChain) (*ChatResponse, error) {
+	url := fmt.Sprintf("%s/chat/completions", CurrentBaseURL())
 
 	body, err := json.Marshal(req)
 	if err != nil {
 		return nil, fmt.Errorf("序列化请求失败: %w", err)
 	}
 
+	if err := c.waitRateLimit(context.Background(), body); err != nil {
+		return nil, err
+	}
+
 	httpReq, err := http.NewRequest("POST", url, bytes.NewReader(body))
 	if err != nil {
 		return nil, fmt.Errorf("创建请求失败: %w", err)
@@ -161,7 +369,7 @@ func (c *Client) chatStream(req ChatRequest) (*ChatResponse, error) {
 	httpReq.Header.Set("Cache-Control", "no-cache")
 	httpReq.Header.Set("Connection", "keep-alive")
 
-	resp, err := c.client.Do(httpReq)
+	resp, err := c.streamClient.Do(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("请求失败: %w", err)
 	}
@@ -169,16 +377,15 @@ func (c *Client) chatStream(req ChatRequest) (*ChatResponse, error) {
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
 		resp.Body.Close()
-		return nil, fmt.Errorf("API请求失败 (状态码: %d): %s", resp.StatusCode, string(bodyBytes))
+		return nil, newAPIError(resp.StatusCode, bodyBytes)
 	}
 
-	var fullResponse ChatResponse
-	var contentBuilder strings.Builder
-	var reasoningBuilder strings.Builder
+	var acc streamAccumulator
+	parser := c.newToolCallParser()
 
-	reader := bufio.NewReader(resp.Body)
+	reader := newSSEReader(resp.Body)
 	for {
-		line, err := reader.ReadString('\n')
+		event, err := reader.Next()
 		if err != nil {
 			if err == io.EOF {
 				break
@@ -186,87 +393,84 @@ func (c *Client) chatStream(req ChatRequest) (*ChatResponse, error) {
 			resp.Body.Close()
 			return nil, fmt.Errorf("reading stream response failed: %w", err)
 		}
+		if event.Data == sseDone {
+			break
+		}
+		if event.Event == "error" {
+			resp.Body.Close()
+			return nil, fmt.Errorf("流式响应返回错误: %s", event.Data)
+		}
 
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "data: ") {
-			data := strings.TrimPrefix(line, "data: ")
-			if data == "[DONE]" {
-				break
-			}
-
-			var chunk StreamChunk
-			if err := json.Unmarshal([]byte(data), &chunk); err != nil {
-				continue
-			}
-
-			if len(chunk.Choices) > 0 && chunk.Choices[0].Delta != nil {
-				delta := chunk.Choices[0].Delta
-				if delta.Content != "" {
-					contentBuilder.WriteString(delta.Content)
-				}
-				if delta.ReasoningContent != "" {
-					reasoningBuilder.WriteString(delta.ReasoningContent)
-				}
-			}
-
-			if fullResponse.ID == "" {
-				fullResponse = ChatResponse{
-					ID:      chunk.ID,
-					Object:  chunk.Object,
-					Created: chunk.Created,
-					Model:   chunk.Model,
-				}
-			}
+		var chunk StreamChunk
+		if err := json.Unmarshal([]byte(event.Data), &chunk); err != nil {
+			continue
 		}
+
+		parser.rewrite(&chunk)
+		hooks.chunk(&chunk)
+		acc.add(&chunk)
 	}
 	resp.Body.Close()
-
-	// 只在最后需要时调用String()，避免中间转换
-	contentBytes, _ := json.Marshal(contentBuilder.String())
-	fullResponse.Choices = []Choice{
-		{
-			Index: 0,
-			Message: &Message{
-				Role:    "assistant",
-				Content: contentBytes,
-			},
-			FinishReason: "stop",
-		},
+	if chunk := parser.finalChunk(); chunk != nil {
+		hooks.chunk(chunk)
+		acc.add(chunk)
 	}
 
-	return &fullResponse, nil
+	return acc.response(), nil
 }
 
 // StreamChat 执行流式聊天请求，支持工具调用
 func (c *Client) StreamChat(messages []Message, tools []Tool, onChunk func(string, string, []ToolCall)) error {
-	req := ChatRequest{
-		Model:       "glm-4.5",
-		Messages:    messages,
-		Stream:      true,
-		MaxTokens:   4096,
-		Temperature: 0.6,
-		Thinking: &Thinking{
-			Type: "enabled",
-		},
+	return c.streamChat(context.Background(), messages, tools, func(chunk *StreamChunk) {
+		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta != nil {
+			delta := chunk.Choices[0].Delta
+			onChunk(delta.Content, delta.ReasoningContent, delta.ToolCalls)
+		}
+	})
+}
+
+// streamChat 执行可取消的流式聊天请求，排队等待限流时也会响应取消
+func (c *Client) streamChat(ctx context.Context, messages []Message, tools []Tool, onChunk func(*StreamChunk)) error {
+	req, err := c.newChatRequest(messages, true, tools)
+	if err != nil {
+		return err
 	}
 
-	if len(tools) > 0 {
-		req.Tools = tools
-		// 设置为自动选择工具
-		autoChoice, _ := json.Marshal("auto")
-		req.ToolChoice = autoChoice
+	hooks := c.activeHooks()
+	hooks.request(&req)
+
+	resp, err := c.doStreamChat(ctx, req, hooks, onChunk)
+	if err != nil {
+		hooks.error(err)
+		return err
 	}
 
-	url := fmt.Sprintf("%s/chat/completions", baseURL)
+	hooks.complete(resp)
+	return nil
+}
+
+// doStreamChat 发送流式请求并逐块回调（经过钩子处理后），返回汇总后的响应
+func (c *Client) doStreamChat(ctx context.Context, req ChatRequest, hooks hookChain, onChunk func(*StreamChunk)) (*ChatResponse, error) {
+	url := fmt.Sprintf("%s/chat/completions", CurrentBaseURL())
 
 	body, err := json.Marshal(req)
 	if err != nil {
-		return fmt.Errorf("序列化请求失败: %w", err)
+		return nil, fmt.Errorf("序列化请求失败: %w", err)
 	}
 
-	httpReq, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err := c.waitRateLimit(ctx, body); err != nil {
+		return nil, err
+	}
+
+	// 空闲检测：超时未收到任何数据（包括心跳注释）时中止请求
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	watchdog := newIdleWatchdog(c.idleTimeout(), cancel)
+	defer watchdog.Stop()
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
 	if err != nil {
-		return fmt.Errorf("创建请求失败: %w", err)
+		return nil, fmt.Errorf("创建请求失败: %w", err)
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
@@ -275,123 +479,63 @@ func (c *Client) StreamChat(messages []Message, tools []Tool, onChunk func(strin
 	httpReq.Header.Set("Cache-Control", "no-cache")
 	httpReq.Header.Set("Connection", "keep-alive")
 
-	resp, err := c.client.Do(httpReq)
+	resp, err := c.streamClient.Do(httpReq)
 	if err != nil {
-		return fmt.Errorf("请求失败: %w", err)
+		if watchdog.Stalled() {
+			return nil, c.stalledError()
+		}
+		return nil, fmt.Errorf("请求失败: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("API请求失败 (状态码: %d): %s", resp.StatusCode, string(bodyBytes))
+		return nil, newAPIError(resp.StatusCode, bodyBytes)
 	}
 
-	reader := bufio.NewReader(resp.Body)
+	var acc streamAccumulator
+	parser := c.newToolCallParser()
+	reader := newSSEReader(resp.Body)
+	// 任何一行（包括心跳注释）都视为连接仍然活跃
+	reader.onLine = watchdog.Reset
 	for {
-		line, err := reader.ReadString('\n')
+		event, err := reader.Next()
 		if err != nil {
 			if err == io.EOF {
 				break
 			}
-			return fmt.Errorf("reading stream response failed: %w", err)
-		}
-
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "data: ") {
-			data := strings.TrimPrefix(line, "data: ")
-			if data == "[DONE]" {
-				break
-			}
-
-			var chunk StreamChunk
-			if err := json.Unmarshal([]byte(data), &chunk); err != nil {
-				continue
+			if watchdog.Stalled() {
+				return nil, c.stalledError()
 			}
+			return nil, fmt.Errorf("reading stream response failed: %w", err)
+		}
+		if event.Data == sseDone {
+			break
+		}
+		if event.Event == "error" {
+			return nil, fmt.Errorf("流式响应返回错误: %s", event.Data)
+		}
 
-			if len(chunk.Choices) > 0 && chunk.Choices[0].Delta != nil {
-				delta := chunk.Choices[0].Delta
-				onChunk(delta.Content, delta.ReasoningContent, delta.ToolCalls)
-			}
+		var chunk StreamChunk
+		if err := json.Unmarshal([]byte(event.Data), &chunk); err != nil {
+			continue
 		}
+
+		parser.rewrite(&chunk)
+		hooks.chunk(&chunk)
+		acc.add(&chunk)
+		onChunk(&chunk)
+	}
+	if chunk := parser.finalChunk(); chunk != nil {
+		hooks.chunk(chunk)
+		acc.add(chunk)
+		onChunk(chunk)
 	}
 
-	return nil
+	return acc.response(), nil
 }
 
-// StreamChatWithChannel 执行流式聊天请求并返回通道
-func (c *Client) StreamChatWithChannel(ctx context.Context, messages []Message, tools []Tool) (<-chan string, <-chan string, <-chan []ToolCall, <-chan error) {
-	chunkCh := make(chan string, 10)  // 添加缓冲区，提高吞吐量
-	reasoningCh := make(chan string, 10)
-	toolCallCh := make(chan []ToolCall, 5)
-	errCh := make(chan error, 1)
-
-	go func() {
-		// 确保所有channel在goroutine退出时关闭
-		defer func() {
-			close(chunkCh)
-			close(reasoningCh)
-			close(toolCallCh)
-			close(errCh)
-		}()
-
-		// 创建可取消的子context，关联到StreamChat调用
-		streamCtx, cancel := context.WithCancel(ctx)
-		defer cancel()
-
-		// 使用channel监听context取消信号
-		done := make(chan struct{})
-		go func() {
-			<-streamCtx.Done()
-			close(done)
-		}()
-
-		// 执行流式请求
-		err := c.StreamChat(messages, tools, func(content, reasoning string, toolCalls []ToolCall) {
-			select {
-			case <-done:
-				// context已取消，停止发送
-				return
-			default:
-				// 发送数据到channel，带超时避免阻塞
-				if content != "" {
-					select {
-					case chunkCh <- content:
-					case <-time.After(100 * time.Millisecond):
-						// 发送超时，跳过
-					}
-				}
-				if reasoning != "" {
-					select {
-					case reasoningCh <- reasoning:
-					case <-time.After(100 * time.Millisecond):
-						// 发送超时，跳过
-					}
-				}
-				if len(toolCalls) > 0 {
-					select {
-					case toolCallCh <- toolCalls:
-					case <-time.After(100 * time.Millisecond):
-						// 发送超时，跳过
-					}
-				}
-			}
-		})
-
-		if err != nil {
-			select {
-			case errCh <- err:
-			case <-done:
-				// context已取消
-			}
-		} else {
-			// 流正常结束时发送空字符串表示结束
-			select {
-			case chunkCh <- "":
-			case <-done:
-				// context已取消
-			}
-		}
-	}()
-
-	return chunkCh, reasoningCh, toolCallCh, errCh
+// stalledError 返回流式响应空闲超时的错误
+func (c *Client) stalledError() error {
+	return fmt.Errorf("%w: %v 内未收到数据", ErrStreamStalled, c.idleTimeout())
 }
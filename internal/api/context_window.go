@@ -0,0 +1,59 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/tokens"
+)
+
+// DefaultMaxTokens 每次请求允许模型生成的最大 token 数，检查上下文窗口时为输出预留这部分空间
+const DefaultMaxTokens = 4096
+
+// defaultContextWindows 常用模型的上下文窗口（token），配置文件中的 context_windows 优先
+var defaultContextWindows = map[string]int{
+	"glm-4.5":       128000,
+	"glm-4.5-air":   128000,
+	"glm-4.5-x":     128000,
+	"glm-4.5-flash": 128000,
+	"glm-4.6":       200000,
+	"glm-4-plus":    128000,
+	"glm-4-long":    1000000,
+}
+
+// ContextWindow 返回模型的上下文窗口，overrides 为用户按模型名配置的值（不区分大小写），
+// 模型为空时按 DefaultModel 查找，未知的模型返回 0
+func ContextWindow(model string, overrides map[string]int) int {
+	if model == "" {
+		model = DefaultModel
+	}
+	model = strings.ToLower(model)
+	for name, window := range overrides {
+		if strings.ToLower(name) == model {
+			return window
+		}
+	}
+	return defaultContextWindows[model]
+}
+
+// EstimateRequestTokens 估算请求的输入 token 数：消息加上工具定义
+func EstimateRequestTokens(messages []Message, tools []Tool) int {
+	n := EstimateTokens(messages)
+	if len(tools) > 0 {
+		data, _ := json.Marshal(tools)
+		n += tokens.Count(string(data))
+	}
+	return n
+}
+
+// ContextOverflowError 请求估算的 token 数加上输出预留超出了模型的上下文窗口，请求没有发送
+type ContextOverflowError struct {
+	Model     string
+	Estimated int // 估算的输入 token 数
+	Limit     int // 上下文窗口
+}
+
+func (e *ContextOverflowError) Error() string {
+	return fmt.Sprintf("请求约 %d token，加上输出预留的 %d token 超出了模型 %s 的上下文窗口 %d token", e.Estimated, DefaultMaxTokens, e.Model, e.Limit)
+}
@@ -0,0 +1,36 @@
+package api
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestContextWindow(t *testing.T) {
+	if got := ContextWindow("", nil); got != defaultContextWindows[DefaultModel] {
+		t.Errorf("default model window = %d", got)
+	}
+	if got := ContextWindow("GLM-4.6", nil); got != 200000 {
+		t.Errorf("glm-4.6 window = %d", got)
+	}
+	if got := ContextWindow("glm-4.5", map[string]int{"GLM-4.5": 32000}); got != 32000 {
+		t.Errorf("override = %d", got)
+	}
+	if got := ContextWindow("local-llama", nil); got != 0 {
+		t.Errorf("unknown model window = %d, want 0", got)
+	}
+}
+
+func TestEstimateRequestTokens(t *testing.T) {
+	messages := []Message{TextMessage("user", "hello")}
+	tools := []Tool{{Type: "function", Function: ToolFunction{Name: "read_file", Description: strings.Repeat("read a file ", 50)}}}
+	if with, without := EstimateRequestTokens(messages, tools), EstimateRequestTokens(messages, nil); with <= without || without != EstimateTokens(messages) {
+		t.Errorf("with tools %d, without %d", with, without)
+	}
+
+	var err error = &ContextOverflowError{Model: "glm-4.5", Estimated: 130000, Limit: 128000}
+	var overflow *ContextOverflowError
+	if !errors.As(err, &overflow) || !strings.Contains(err.Error(), "glm-4.5") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
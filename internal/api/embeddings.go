@@ -0,0 +1,106 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+)
+
+const (
+	// embeddingModel 智谱向量模型
+	embeddingModel = "embedding-3"
+	// maxEmbeddingBatch 单次请求最多包含的文本条数
+	maxEmbeddingBatch = 64
+)
+
+// EmbeddingRequest 向量化请求
+type EmbeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// EmbeddingResponse 向量化响应
+type EmbeddingResponse struct {
+	Model string          `json:"model"`
+	Data  []EmbeddingData `json:"data"`
+}
+
+// EmbeddingData 单条文本的向量
+type EmbeddingData struct {
+	Index     int       `json:"index"`
+	Embedding []float64 `json:"embedding"`
+}
+
+// CreateEmbeddings 将文本转换为向量，返回结果与 inputs 顺序一致
+// 超过单次上限时自动分批请求
+func (c *Client) CreateEmbeddings(ctx context.Context, inputs []string) ([][]float64, error) {
+	result := make([][]float64, 0, len(inputs))
+	for start := 0; start < len(inputs); start += maxEmbeddingBatch {
+		end := start + maxEmbeddingBatch
+		if end > len(inputs) {
+			end = len(inputs)
+		}
+
+		vectors, err := c.createEmbeddingBatch(ctx, inputs[start:end])
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, vectors...)
+	}
+	return result, nil
+}
+
+// createEmbeddingBatch 发送单批向量化请求
+func (c *Client) createEmbeddingBatch(ctx context.Context, inputs []string) ([][]float64, error) {
+	url := fmt.Sprintf("%s/embeddings", CurrentBaseURL())
+
+	body, err := json.Marshal(EmbeddingRequest{Model: embeddingModel, Input: inputs})
+	if err != nil {
+		return nil, fmt.Errorf("序列化请求失败: %w", err)
+	}
+
+	if err := c.waitRateLimit(ctx, body); err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.apiKey))
+
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, newAPIError(resp.StatusCode, bodyBytes)
+	}
+
+	var embResp EmbeddingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&embResp); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %w", err)
+	}
+	if len(embResp.Data) != len(inputs) {
+		return nil, fmt.Errorf("向量数量不匹配: 请求 %d 条，返回 %d 条", len(inputs), len(embResp.Data))
+	}
+
+	sort.Slice(embResp.Data, func(i, j int) bool {
+		return embResp.Data[i].Index < embResp.Data[j].Index
+	})
+
+	vectors := make([][]float64, len(embResp.Data))
+	for i, data := range embResp.Data {
+		vectors[i] = data.Embedding
+	}
+	return vectors, nil
+}
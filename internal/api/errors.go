@@ -0,0 +1,104 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxErrorDetailLength 错误信息中保留的响应内容长度，完整的响应体保存在 APIError.Body 中
+const maxErrorDetailLength = 300
+
+// htmlTitlePattern 提取网关错误页面的标题
+var htmlTitlePattern = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+// APIError 服务端返回的非 200 响应。Code、Type、Message 从常见的错误格式中解析，
+// 如 {"error":{"message":"...","type":"...","code":"..."}}；无法解析时为空
+type APIError struct {
+	StatusCode int
+	Code       string // 服务商的错误码，如 invalid_api_key、1113
+	Type       string
+	Message    string
+	Body       string // 原始响应体
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API请求失败 (状态码: %d): %s", e.StatusCode, e.Detail())
+}
+
+// Detail 返回便于阅读的错误说明：优先使用解析出的信息，HTML 页面使用其标题，其他内容截断显示
+func (e *APIError) Detail() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	body := strings.TrimSpace(e.Body)
+	if strings.HasPrefix(body, "<") {
+		if matches := htmlTitlePattern.FindStringSubmatch(body); matches != nil {
+			return strings.TrimSpace(html.UnescapeString(matches[1]))
+		}
+		return "HTML"
+	}
+	body = strings.Join(strings.Fields(body), " ")
+	if utf8.RuneCountInString(body) > maxErrorDetailLength {
+		body = string([]rune(body)[:maxErrorDetailLength]) + "…"
+	}
+	return body
+}
+
+// newAPIError 根据状态码和响应体构造错误，解析 OpenAI 兼容格式以及 {"code":..,"msg":..} 等常见变体
+func newAPIError(statusCode int, body []byte) *APIError {
+	e := &APIError{StatusCode: statusCode, Body: string(body)}
+
+	var payload struct {
+		Error   json.RawMessage `json:"error"`
+		Code    json.RawMessage `json:"code"`
+		Type    string          `json:"type"`
+		Message string          `json:"message"`
+		Msg     string          `json:"msg"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return e
+	}
+
+	var detail struct {
+		Code    json.RawMessage `json:"code"`
+		Type    string          `json:"type"`
+		Message string          `json:"message"`
+	}
+	if err := json.Unmarshal(payload.Error, &detail); err == nil {
+		e.Code, e.Type, e.Message = rawString(detail.Code), detail.Type, detail.Message
+	} else {
+		// error 字段是字符串或不存在
+		e.Message = rawString(payload.Error)
+	}
+	if e.Code == "" {
+		e.Code = rawString(payload.Code)
+	}
+	if e.Type == "" {
+		e.Type = payload.Type
+	}
+	if e.Message == "" {
+		e.Message = payload.Message
+	}
+	if e.Message == "" {
+		e.Message = payload.Msg
+	}
+	e.Message = strings.TrimSpace(e.Message)
+	return e
+}
+
+// rawString 将字符串或数字形式的 JSON 值转为字符串，null 和对象返回空字符串
+func rawString(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var n json.Number
+	if err := json.Unmarshal(raw, &n); err == nil {
+		return n.String()
+	}
+	return ""
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewAPIError(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		code    string
+		message string
+		detail  string
+	}{
+		{
+			name:    "openai format",
+			status:  401,
+			body:    `{"error":{"message":"Incorrect API key provided","type":"invalid_request_error","code":"invalid_api_key"}}`,
+			code:    "invalid_api_key",
+			message: "Incorrect API key provided",
+			detail:  "Incorrect API key provided",
+		},
+		{
+			name:    "numeric code",
+			status:  429,
+			body:    `{"error":{"code":1113,"message":"余额不足或无可用资源包,请充值。"}}`,
+			code:    "1113",
+			message: "余额不足或无可用资源包,请充值。",
+			detail:  "余额不足或无可用资源包,请充值。",
+		},
+		{
+			name:    "flat format",
+			status:  400,
+			body:    `{"code":"400","msg":"prompt too long"}`,
+			code:    "400",
+			message: "prompt too long",
+			detail:  "prompt too long",
+		},
+		{
+			name:    "string error",
+			status:  500,
+			body:    `{"error":"upstream unavailable"}`,
+			message: "upstream unavailable",
+			detail:  "upstream unavailable",
+		},
+		{
+			name:   "html page",
+			status: 502,
+			body:   "<html>\n<head><title>502 Bad Gateway</title></head>\n<body><center><h1>502 Bad Gateway</h1></center></body>\n</html>",
+			detail: "502 Bad Gateway",
+		},
+		{
+			name:   "plain text",
+			status: 503,
+			body:   "service\nunavailable\n",
+			detail: "service unavailable",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newAPIError(tt.status, []byte(tt.body))
+			if err.Code != tt.code || err.Message != tt.message || err.Detail() != tt.detail {
+				t.Errorf("got code=%q message=%q detail=%q", err.Code, err.Message, err.Detail())
+			}
+			if err.Body != tt.body {
+				t.Error("raw body should be kept")
+			}
+			if !strings.Contains(err.Error(), "状态码: ") || !strings.HasSuffix(err.Error(), tt.detail) {
+				t.Errorf("Error() = %q", err.Error())
+			}
+		})
+	}
+}
+
+func TestAPIErrorDetailTruncatesLongBodies(t *testing.T) {
+	err := newAPIError(500, []byte(strings.Repeat("x", 1000)))
+	if detail := err.Detail(); len(detail) > maxErrorDetailLength+len("…") {
+		t.Errorf("detail is %d bytes", len(detail))
+	}
+}
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/tokens"
+)
+
+// EstimateMessageTokens 估算一条消息占用的 token 数
+func EstimateMessageTokens(msg Message) int {
+	// 每条消息另有角色等固定的格式开销
+	n := 4 + tokens.Count(MessageText(msg)) + tokens.Count(msg.Name)
+	for _, call := range msg.ToolCalls {
+		n += 8 + tokens.Count(call.Function.Name) + tokens.Count(string(call.Function.Arguments))
+	}
+	return n
+}
+
+// EstimateTokens 估算一组消息占用的 token 数
+func EstimateTokens(messages []Message) int {
+	n := 0
+	for _, msg := range messages {
+		n += EstimateMessageTokens(msg)
+	}
+	return n
+}
+
+// TrimHistory 返回发送给模型的历史：消息数不超过 maxMessages、估算 token 数不超过 maxTokens
+// （小于等于 0 表示不限制）。系统消息总是保留，从最早的非系统消息开始裁剪；
+// 带工具调用的助手消息与其后的工具结果作为整体保留或丢弃，最后一条用户消息及之后的内容不会被裁剪。
+// 不修改传入的切片
+func TrimHistory(messages []Message, maxMessages, maxTokens int) []Message {
+	if maxMessages <= 0 && maxTokens <= 0 {
+		return messages
+	}
+
+	count, tokens := 0, 0
+	for _, msg := range messages {
+		count++
+		tokens += EstimateMessageTokens(msg)
+	}
+	over := func() bool {
+		return (maxMessages > 0 && count > maxMessages) || (maxTokens > 0 && tokens > maxTokens)
+	}
+	if !over() {
+		return messages
+	}
+
+	// 最后一条用户消息之后的内容是当前这一轮，必须保留
+	protected := len(messages)
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			protected = i
+			break
+		}
+	}
+
+	dropped := make([]bool, len(messages))
+	i := 0
+	for i < protected && over() {
+		if messages[i].Role == "system" {
+			i++
+			continue
+		}
+		end := historyUnitEnd(messages, i)
+		if end > protected {
+			break
+		}
+		for j := i; j < end; j++ {
+			dropped[j] = true
+			count--
+			tokens -= EstimateMessageTokens(messages[j])
+		}
+		i = end
+	}
+	// 裁剪后的历史从用户消息开始，避免以助手回复或工具结果开头
+	for i < protected && messages[i].Role != "user" {
+		if messages[i].Role == "system" {
+			i++
+			continue
+		}
+		end := historyUnitEnd(messages, i)
+		if end > protected {
+			break
+		}
+		for j := i; j < end; j++ {
+			dropped[j] = true
+		}
+		i = end
+	}
+
+	result := make([]Message, 0, len(messages))
+	for j, msg := range messages {
+		if !dropped[j] {
+			result = append(result, msg)
+		}
+	}
+	// 按单元裁剪不会拆开工具调用和结果，这里兜底保证不产生孤立的工具结果
+	if CheckToolPairing(result) != nil {
+		result = dropOrphanToolMessages(result)
+	}
+	return result
+}
+
+// CheckToolPairing 检查每条工具结果都跟在包含其 tool_call_id 的助手消息之后（中间只能有其他工具结果），
+// 服务商会拒绝包含孤立工具结果的请求
+func CheckToolPairing(messages []Message) error {
+	var calls map[string]bool
+	for i, msg := range messages {
+		switch {
+		case msg.Role == "tool":
+			if !calls[msg.ToolCallID] {
+				return fmt.Errorf("第 %d 条消息是孤立的工具结果 (tool_call_id=%q)", i, msg.ToolCallID)
+			}
+		case len(msg.ToolCalls) > 0:
+			calls = make(map[string]bool, len(msg.ToolCalls))
+			for _, call := range msg.ToolCalls {
+				calls[call.ID] = true
+			}
+		default:
+			calls = nil
+		}
+	}
+	return nil
+}
+
+// dropOrphanToolMessages 移除找不到对应工具调用的工具结果
+func dropOrphanToolMessages(messages []Message) []Message {
+	result := make([]Message, 0, len(messages))
+	var calls map[string]bool
+	for _, msg := range messages {
+		switch {
+		case msg.Role == "tool":
+			if !calls[msg.ToolCallID] {
+				continue
+			}
+		case len(msg.ToolCalls) > 0:
+			calls = make(map[string]bool, len(msg.ToolCalls))
+			for _, call := range msg.ToolCalls {
+				calls[call.ID] = true
+			}
+		default:
+			calls = nil
+		}
+		result = append(result, msg)
+	}
+	return result
+}
+
+// historyUnitEnd 返回从 start 开始的裁剪单元的结束位置（不含）：
+// 带工具调用的助手消息连同其后连续的工具结果为一个单元，其他消息各自为一个单元
+func historyUnitEnd(messages []Message, start int) int {
+	end := start + 1
+	if len(messages[start].ToolCalls) == 0 {
+		return end
+	}
+	for end < len(messages) && messages[end].Role == "tool" {
+		end++
+	}
+	return end
+}
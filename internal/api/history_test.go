@@ -0,0 +1,134 @@
+package api
+
+import (
+	"strings"
+	"testing"
+)
+
+func toolCall(id string) ToolCall {
+	return ToolCall{ID: id, Type: "function", Function: ToolCallFunction{Name: "read_file", Arguments: []byte(`{}`)}}
+}
+
+func roles(messages []Message) string {
+	parts := make([]string, len(messages))
+	for i, msg := range messages {
+		parts[i] = msg.Role
+		if msg.Role == "tool" {
+			parts[i] += ":" + msg.ToolCallID
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// interleavedHistory 两轮对话，每轮都穿插文本、多个工具调用和结果
+func interleavedHistory() []Message {
+	return []Message{
+		TextMessage("system", "prompt"),
+		TextMessage("user", "q1"),
+		AssistantToolCallMessage("looking", []ToolCall{toolCall("a"), toolCall("b")}),
+		ToolResultMessage("a", "ra"),
+		ToolResultMessage("b", "rb"),
+		ToolCallMessage([]ToolCall{toolCall("c")}),
+		ToolResultMessage("c", "rc"),
+		TextMessage("assistant", "done 1"),
+		TextMessage("user", "q2"),
+		ToolCallMessage([]ToolCall{toolCall("d")}),
+		ToolResultMessage("d", "rd"),
+		TextMessage("assistant", "done 2"),
+		TextMessage("user", "q3"),
+		ToolCallMessage([]ToolCall{toolCall("e")}),
+		ToolResultMessage("e", "re"),
+	}
+}
+
+func TestTrimHistoryByCount(t *testing.T) {
+	history := interleavedHistory()
+	original := roles(history)
+
+	for limit := 1; limit <= len(history); limit++ {
+		trimmed := TrimHistory(history, limit, 0)
+		if err := CheckToolPairing(trimmed); err != nil {
+			t.Fatalf("limit %d: %v\n%s", limit, err, roles(trimmed))
+		}
+		if trimmed[0].Role != "system" {
+			t.Errorf("limit %d: system message dropped: %s", limit, roles(trimmed))
+		}
+		if len(trimmed) > 1 && trimmed[1].Role != "user" {
+			t.Errorf("limit %d: history should start with a user message: %s", limit, roles(trimmed))
+		}
+		// 当前这一轮总是完整保留
+		if !strings.HasSuffix(roles(trimmed), "user assistant tool:e") {
+			t.Errorf("limit %d: current turn trimmed: %s", limit, roles(trimmed))
+		}
+	}
+
+	if got := roles(TrimHistory(history, 8, 0)); got != "system user assistant tool:d assistant user assistant tool:e" {
+		t.Errorf("unexpected trimming at 8 messages: %s", got)
+	}
+	if roles(history) != original {
+		t.Error("TrimHistory modified its input")
+	}
+}
+
+func TestTrimHistoryByTokens(t *testing.T) {
+	history := interleavedHistory()
+	// 第一轮中的一个工具结果很大
+	history[3] = ToolResultMessage("a", strings.Repeat("x", 40000))
+
+	trimmed := TrimHistory(history, 0, 1000)
+	if err := CheckToolPairing(trimmed); err != nil {
+		t.Fatalf("%v\n%s", err, roles(trimmed))
+	}
+	if got := roles(trimmed); got != "system user assistant tool:d assistant user assistant tool:e" {
+		t.Errorf("unexpected trimming: %s", got)
+	}
+
+	if got := TrimHistory(history, -1, -1); len(got) != len(history) {
+		t.Errorf("negative limits should not trim, got %d messages", len(got))
+	}
+}
+
+func TestCheckToolPairing(t *testing.T) {
+	valid := interleavedHistory()
+	if err := CheckToolPairing(valid); err != nil {
+		t.Fatalf("valid history rejected: %v", err)
+	}
+
+	cases := map[string][]Message{
+		"missing call": {TextMessage("user", "q"), ToolResultMessage("a", "r")},
+		"wrong id":     {TextMessage("user", "q"), ToolCallMessage([]ToolCall{toolCall("a")}), ToolResultMessage("b", "r")},
+		"separated by text": {
+			ToolCallMessage([]ToolCall{toolCall("a")}),
+			TextMessage("assistant", "hm"),
+			ToolResultMessage("a", "r"),
+		},
+	}
+	for name, messages := range cases {
+		if err := CheckToolPairing(messages); err == nil {
+			t.Errorf("%s: expected an orphan tool message error", name)
+		}
+		if err := CheckToolPairing(dropOrphanToolMessages(messages)); err != nil {
+			t.Errorf("%s: orphans remain after repair: %v", name, err)
+		}
+	}
+}
+
+func TestTrimHistoryRepairsOrphansInInput(t *testing.T) {
+	// 已损坏的历史（开头是孤立的工具结果）裁剪后也不能带着孤立结果发送
+	history := []Message{
+		ToolResultMessage("lost", "r"),
+		TextMessage("user", "q1"),
+		TextMessage("assistant", "a1"),
+		TextMessage("user", "q2"),
+		ToolCallMessage([]ToolCall{toolCall("x")}),
+		ToolResultMessage("y", "stale"),
+		ToolResultMessage("x", "r"),
+	}
+	trimmed := TrimHistory(history, 4, 0)
+	if err := CheckToolPairing(trimmed); err != nil {
+		t.Fatalf("%v\n%s", err, roles(trimmed))
+	}
+	if got := roles(trimmed); got != "user assistant tool:x" {
+		t.Errorf("unexpected trimming: %s", got)
+	}
+}
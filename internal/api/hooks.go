@@ -0,0 +1,108 @@
+package api
+
+import "sync"
+
+// Hook 观察聊天请求流量的中间件，用于日志、token 统计、费用跟踪、脱敏等
+// 各方法在发起请求的 goroutine 中同步调用，实现应尽快返回
+type Hook interface {
+	// OnRequest 在请求发送前调用，可以修改请求（例如脱敏）
+	OnRequest(req *ChatRequest)
+	// OnResponseChunk 在收到每个流式数据块时调用
+	OnResponseChunk(chunk *StreamChunk)
+	// OnComplete 在请求成功结束时调用，流式请求传入汇总后的响应
+	OnComplete(resp *ChatResponse)
+	// OnError 在请求失败时调用
+	OnError(err error)
+}
+
+// HookFuncs 用函数实现 Hook，未设置的方法不做任何事
+type HookFuncs struct {
+	Request       func(req *ChatRequest)
+	ResponseChunk func(chunk *StreamChunk)
+	Complete      func(resp *ChatResponse)
+	Error         func(err error)
+}
+
+func (h HookFuncs) OnRequest(req *ChatRequest) {
+	if h.Request != nil {
+		h.Request(req)
+	}
+}
+
+func (h HookFuncs) OnResponseChunk(chunk *StreamChunk) {
+	if h.ResponseChunk != nil {
+		h.ResponseChunk(chunk)
+	}
+}
+
+func (h HookFuncs) OnComplete(resp *ChatResponse) {
+	if h.Complete != nil {
+		h.Complete(resp)
+	}
+}
+
+func (h HookFuncs) OnError(err error) {
+	if h.Error != nil {
+		h.Error(err)
+	}
+}
+
+// 全局 Hook，作用于进程内所有客户端
+var (
+	globalHooksMu sync.RWMutex
+	globalHooks   []Hook
+)
+
+// RegisterHook 注册作用于所有客户端的 Hook，按注册顺序调用
+func RegisterHook(hook Hook) {
+	globalHooksMu.Lock()
+	defer globalHooksMu.Unlock()
+	globalHooks = append(globalHooks, hook)
+}
+
+// WithHooks 返回追加了指定 Hook 的客户端副本，客户端 Hook 在全局 Hook 之后调用
+func (c *Client) WithHooks(hooks ...Hook) *Client {
+	clone := *c
+	clone.hooks = append(append([]Hook(nil), c.hooks...), hooks...)
+	return &clone
+}
+
+// activeHooks 返回本次请求需要调用的 Hook
+func (c *Client) activeHooks() hookChain {
+	globalHooksMu.RLock()
+	defer globalHooksMu.RUnlock()
+
+	if len(globalHooks) == 0 {
+		return c.hooks
+	}
+	chain := make(hookChain, 0, len(globalHooks)+len(c.hooks))
+	chain = append(chain, globalHooks...)
+	return append(chain, c.hooks...)
+}
+
+// hookChain 按顺序调用的一组 Hook
+type hookChain []Hook
+
+func (h hookChain) request(req *ChatRequest) {
+	for _, hook := range h {
+		hook.OnRequest(req)
+	}
+}
+
+func (h hookChain) chunk(chunk *StreamChunk) {
+	for _, hook := range h {
+		hook.OnResponseChunk(chunk)
+	}
+}
+
+func (h hookChain) complete(resp *ChatResponse) {
+	for _, hook := range h {
+		hook.OnComplete(resp)
+	}
+}
+
+func (h hookChain) error(err error) {
+	for _, hook := range h {
+		hook.OnError(err)
+	}
+}
@@ -0,0 +1,238 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxSchemaErrors 单次校验最多报告的错误数，避免反馈给模型的内容过长
+const maxSchemaErrors = 10
+
+// ValidateJSONSchema 按 JSON Schema 的常用子集校验已解码的 JSON 值
+// 支持 type、enum、properties、required、additionalProperties、items、
+// minItems/maxItems、minLength/maxLength、minimum/maximum
+func ValidateJSONSchema(schema map[string]interface{}, value interface{}) error {
+	var errs []string
+	validateSchemaNode(schema, value, "$", &errs)
+	if len(errs) == 0 {
+		return nil
+	}
+	if len(errs) > maxSchemaErrors {
+		errs = append(errs[:maxSchemaErrors], fmt.Sprintf("... 以及另外 %d 处错误", len(errs)-maxSchemaErrors))
+	}
+	return fmt.Errorf("JSON 不符合 schema: %s", strings.Join(errs, "; "))
+}
+
+// validateSchemaNode 递归校验单个节点，错误追加到 errs
+func validateSchemaNode(schema map[string]interface{}, value interface{}, path string, errs *[]string) {
+	if schema == nil {
+		return
+	}
+
+	if types := schemaTypes(schema["type"]); len(types) > 0 {
+		matched := false
+		for _, t := range types {
+			if jsonTypeMatches(t, value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			*errs = append(*errs, fmt.Sprintf("%s: 期望类型 %s，实际为 %s", path, strings.Join(types, "|"), jsonTypeName(value)))
+			return
+		}
+	}
+
+	if enum := schemaEnum(schema["enum"]); enum != nil && !enumContains(enum, value) {
+		*errs = append(*errs, fmt.Sprintf("%s: 取值必须是 %s 之一", path, formatEnum(enum)))
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		validateSchemaObject(schema, v, path, errs)
+	case []interface{}:
+		if n, ok := schemaNumber(schema["minItems"]); ok && float64(len(v)) < n {
+			*errs = append(*errs, fmt.Sprintf("%s: 至少需要 %d 项，实际 %d 项", path, int(n), len(v)))
+		}
+		if n, ok := schemaNumber(schema["maxItems"]); ok && float64(len(v)) > n {
+			*errs = append(*errs, fmt.Sprintf("%s: 最多允许 %d 项，实际 %d 项", path, int(n), len(v)))
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				validateSchemaNode(items, item, fmt.Sprintf("%s[%d]", path, i), errs)
+			}
+		}
+	case string:
+		length := float64(utf8.RuneCountInString(v))
+		if n, ok := schemaNumber(schema["minLength"]); ok && length < n {
+			*errs = append(*errs, fmt.Sprintf("%s: 长度不能少于 %d", path, int(n)))
+		}
+		if n, ok := schemaNumber(schema["maxLength"]); ok && length > n {
+			*errs = append(*errs, fmt.Sprintf("%s: 长度不能超过 %d", path, int(n)))
+		}
+	case float64:
+		if n, ok := schemaNumber(schema["minimum"]); ok && v < n {
+			*errs = append(*errs, fmt.Sprintf("%s: 不能小于 %v", path, n))
+		}
+		if n, ok := schemaNumber(schema["maximum"]); ok && v > n {
+			*errs = append(*errs, fmt.Sprintf("%s: 不能大于 %v", path, n))
+		}
+	}
+}
+
+// validateSchemaObject 校验对象的属性、必填字段和额外字段
+func validateSchemaObject(schema map[string]interface{}, obj map[string]interface{}, path string, errs *[]string) {
+	for _, name := range schemaStrings(schema["required"]) {
+		if _, ok := obj[name]; !ok {
+			*errs = append(*errs, fmt.Sprintf("%s: 缺少必填字段 %q", path, name))
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+
+	// 按字段名排序，保证错误信息稳定
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		propSchema, ok := properties[key].(map[string]interface{})
+		if !ok {
+			if additional, isBool := schema["additionalProperties"].(bool); isBool && !additional {
+				*errs = append(*errs, fmt.Sprintf("%s: 不允许的字段 %q", path, key))
+			}
+			continue
+		}
+		validateSchemaNode(propSchema, obj[key], path+"."+key, errs)
+	}
+}
+
+// schemaTypes 读取 type 字段，兼容字符串和字符串数组
+func schemaTypes(raw interface{}) []string {
+	if t, ok := raw.(string); ok {
+		return []string{t}
+	}
+	return schemaStrings(raw)
+}
+
+// schemaStrings 将 []interface{} 或 []string 转为 []string
+func schemaStrings(raw interface{}) []string {
+	switch v := raw.(type) {
+	case []string:
+		return v
+	case []interface{}:
+		result := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+// schemaEnum 读取 enum 字段，兼容 Go 代码中常用的 []string 写法
+func schemaEnum(raw interface{}) []interface{} {
+	switch v := raw.(type) {
+	case []interface{}:
+		return v
+	case []string:
+		result := make([]interface{}, len(v))
+		for i, s := range v {
+			result[i] = s
+		}
+		return result
+	}
+	return nil
+}
+
+// schemaNumber 读取数值约束
+func schemaNumber(raw interface{}) (float64, bool) {
+	switch v := raw.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// jsonTypeMatches 判断值是否属于 JSON Schema 类型
+func jsonTypeMatches(schemaType string, value interface{}) bool {
+	switch schemaType {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == math.Trunc(f)
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	}
+	// 未知类型不做限制
+	return true
+}
+
+// jsonTypeName 返回值对应的 JSON 类型名
+func jsonTypeName(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// enumContains 判断值是否在枚举列表中
+func enumContains(enum []interface{}, value interface{}) bool {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return false
+	}
+	for _, candidate := range enum {
+		if c, err := json.Marshal(candidate); err == nil && string(c) == string(encoded) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatEnum 格式化枚举值用于错误提示
+func formatEnum(enum []interface{}) string {
+	parts := make([]string, 0, len(enum))
+	for _, candidate := range enum {
+		encoded, _ := json.Marshal(candidate)
+		parts = append(parts, string(encoded))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// ProbeTimeout 启动检查最长等待的时间，超时视为服务不可用
+const ProbeTimeout = 15 * time.Second
+
+// Probe 发送一个只生成 1 个 token 的请求，确认 API Key 有效、服务可以访问，返回从发出请求到收到响应的耗时。
+// 不经过限流和 Hook，不计入会话统计和费用；ctx 超时或取消时立即返回
+func (c *Client) Probe(ctx context.Context) (time.Duration, error) {
+	req, err := c.newChatRequest([]Message{TextMessage("user", "ping")}, false, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.MaxTokens = 1
+	req.Thinking = &Thinking{Type: "disabled"}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return 0, fmt.Errorf("序列化请求失败: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, CurrentBaseURL()+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("创建请求失败: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.apiKey))
+
+	start := time.Now()
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return 0, fmt.Errorf("请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+	latency := time.Since(start)
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return latency, newAPIError(resp.StatusCode, bodyBytes)
+	}
+	// 读完响应体，连接可以放回连接池供之后的请求复用
+	io.Copy(io.Discard, resp.Body)
+	return latency, nil
+}
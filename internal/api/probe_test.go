@@ -0,0 +1,52 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// doerFunc 用函数代替 HTTP 客户端
+type doerFunc func(*http.Request) (*http.Response, error)
+
+func (f doerFunc) Do(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestProbe(t *testing.T) {
+	var sent ChatRequest
+	client := &Client{apiKey: "k", model: "glm-4.6", client: doerFunc(func(req *http.Request) (*http.Response, error) {
+		if got := req.Header.Get("Authorization"); got != "Bearer k" {
+			t.Errorf("Authorization = %q", got)
+		}
+		if err := json.NewDecoder(req.Body).Decode(&sent); err != nil {
+			t.Fatal(err)
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(`{"choices":[]}`))}, nil
+	})}
+	if _, err := client.Probe(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if sent.Model != "glm-4.6" || sent.MaxTokens != 1 || sent.Stream || sent.Thinking == nil || sent.Thinking.Type != "disabled" {
+		t.Errorf("probe request = %+v", sent)
+	}
+
+	client.client = doerFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusUnauthorized, Body: io.NopCloser(strings.NewReader(`{"error":{"code":"1002","message":"令牌已过期"}}`))}, nil
+	})
+	var apiErr *APIError
+	if _, err := client.Probe(context.Background()); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("err = %v, want a 401 APIError", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	client.client = doerFunc(func(req *http.Request) (*http.Response, error) {
+		return nil, req.Context().Err()
+	})
+	if _, err := client.Probe(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,184 @@
+package api
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/tokens"
+)
+
+// ProviderGLM 智谱 GLM 服务商标识，用于限流配置
+const ProviderGLM = "glm"
+
+// 全局的服务商限流器，同一进程内的所有客户端共享
+var (
+	rateLimitersMu sync.RWMutex
+	rateLimiters   = make(map[string]*RateLimiter)
+)
+
+// SetRateLimit 设置服务商的客户端限流，rpm 和 tpm 均为 0 时取消限流
+func SetRateLimit(provider string, requestsPerMinute, tokensPerMinute int) {
+	rateLimitersMu.Lock()
+	defer rateLimitersMu.Unlock()
+
+	if requestsPerMinute <= 0 && tokensPerMinute <= 0 {
+		delete(rateLimiters, provider)
+		return
+	}
+	rateLimiters[provider] = NewRateLimiter(requestsPerMinute, tokensPerMinute)
+}
+
+// rateLimiterFor 返回服务商的限流器，未配置时返回 nil
+func rateLimiterFor(provider string) *RateLimiter {
+	rateLimitersMu.RLock()
+	defer rateLimitersMu.RUnlock()
+	return rateLimiters[provider]
+}
+
+// QueuedRequests 返回所有服务商中正在等待限流的请求数
+func QueuedRequests() int {
+	rateLimitersMu.RLock()
+	defer rateLimitersMu.RUnlock()
+
+	total := 0
+	for _, limiter := range rateLimiters {
+		total += limiter.QueueLength()
+	}
+	return total
+}
+
+// tokenBucket 令牌桶，容量为每分钟配额，按秒匀速补充
+type tokenBucket struct {
+	capacity float64
+	tokens   float64
+	rate     float64 // 每秒补充数量
+	last     time.Time
+}
+
+// newTokenBucket 创建满额的令牌桶，perMinute <= 0 时返回 nil 表示不限制
+func newTokenBucket(perMinute int) *tokenBucket {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &tokenBucket{
+		capacity: float64(perMinute),
+		tokens:   float64(perMinute),
+		rate:     float64(perMinute) / 60,
+		last:     time.Now(),
+	}
+}
+
+// refill 按经过的时间补充令牌
+func (b *tokenBucket) refill(now time.Time) {
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(b.capacity, b.tokens+elapsed*b.rate)
+		b.last = now
+	}
+}
+
+// wait 返回获得 n 个令牌还需等待的时间，n 超过容量时按容量计算
+func (b *tokenBucket) wait(n float64) time.Duration {
+	n = math.Min(n, b.capacity)
+	if b.tokens >= n {
+		return 0
+	}
+	return time.Duration((n - b.tokens) / b.rate * float64(time.Second))
+}
+
+// RateLimiter 按请求数和 token 数双重限流的客户端限流器
+type RateLimiter struct {
+	mu       sync.Mutex
+	requests *tokenBucket
+	tokens   *tokenBucket
+	waiting  int
+}
+
+// NewRateLimiter 创建限流器，参数为 0 表示该维度不限制
+func NewRateLimiter(requestsPerMinute, tokensPerMinute int) *RateLimiter {
+	return &RateLimiter{
+		requests: newTokenBucket(requestsPerMinute),
+		tokens:   newTokenBucket(tokensPerMinute),
+	}
+}
+
+// QueueLength 返回正在等待的请求数
+func (l *RateLimiter) QueueLength() int {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.waiting
+}
+
+// Wait 阻塞直到可以发送一个预计消耗 tokens 个 token 的请求，或 ctx 被取消
+func (l *RateLimiter) Wait(ctx context.Context, tokens int) error {
+	if l == nil {
+		return nil
+	}
+
+	queued := false
+	defer func() {
+		if queued {
+			l.mu.Lock()
+			l.waiting--
+			l.mu.Unlock()
+		}
+	}()
+
+	for {
+		l.mu.Lock()
+		delay := l.reserve(float64(tokens), time.Now())
+		if delay > 0 && !queued {
+			queued = true
+			l.waiting++
+		}
+		l.mu.Unlock()
+
+		if delay == 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// reserve 在两个令牌桶都足够时扣除并返回 0，否则返回需要等待的时间
+func (l *RateLimiter) reserve(tokens float64, now time.Time) time.Duration {
+	var delay time.Duration
+	if l.requests != nil {
+		l.requests.refill(now)
+		delay = l.requests.wait(1)
+	}
+	if l.tokens != nil {
+		l.tokens.refill(now)
+		if d := l.tokens.wait(tokens); d > delay {
+			delay = d
+		}
+	}
+	if delay > 0 {
+		return delay
+	}
+
+	if l.requests != nil {
+		l.requests.tokens--
+	}
+	if l.tokens != nil {
+		l.tokens.tokens -= math.Min(tokens, l.tokens.capacity)
+	}
+	return 0
+}
+
+// estimateRequestTokens 估算请求体对应的 token 数
+func estimateRequestTokens(body []byte) int {
+	return tokens.Count(string(body)) + 1
+}
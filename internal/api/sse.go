@@ -0,0 +1,119 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+)
+
+// sseMaxLineSize 单行 SSE 数据的长度上限，工具调用参数可能很长
+const sseMaxLineSize = 16 * 1024 * 1024
+
+// sseDone OpenAI 兼容接口表示流结束的数据
+const sseDone = "[DONE]"
+
+// sseEvent 一个完整的 Server-Sent Event
+type sseEvent struct {
+	// Event 事件类型，未指定时为空（即 "message"）
+	Event string
+	// Data 多行 data 字段以换行符拼接
+	Data string
+	// ID 最近一次出现的 id 字段
+	ID string
+}
+
+// sseReader 按 SSE 规范解析事件流：支持 LF、CRLF 和单独 CR 换行，
+// 注释行，多行 data 拼接，以及 "field:value" 和 "field: value" 两种写法
+type sseReader struct {
+	scanner *bufio.Scanner
+	lastID  string
+	// onLine 每读到一行（包括注释和空行）时调用，用于空闲检测
+	onLine func()
+}
+
+func newSSEReader(r io.Reader) *sseReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), sseMaxLineSize)
+	scanner.Split(scanSSELines)
+	return &sseReader{scanner: scanner}
+}
+
+// Next 返回下一个事件，流结束时返回 io.EOF
+// 流在事件末尾缺少空行就结束时（部分代理会这样），仍返回已读取的事件
+func (r *sseReader) Next() (sseEvent, error) {
+	var (
+		event   string
+		data    strings.Builder
+		hasData bool
+	)
+	for r.scanner.Scan() {
+		if r.onLine != nil {
+			r.onLine()
+		}
+		line := r.scanner.Text()
+		if line == "" {
+			if hasData {
+				return sseEvent{Event: event, Data: data.String(), ID: r.lastID}, nil
+			}
+			// 没有 data 的事件直接丢弃
+			event = ""
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			// 注释行，部分服务商用作心跳保活
+			continue
+		}
+
+		field, value, found := strings.Cut(line, ":")
+		if found {
+			value = strings.TrimPrefix(value, " ")
+		}
+		switch field {
+		case "event":
+			event = value
+		case "data":
+			if hasData {
+				data.WriteByte('\n')
+			}
+			data.WriteString(value)
+			hasData = true
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				r.lastID = value
+			}
+		}
+		// retry 和未知字段忽略
+	}
+	if err := r.scanner.Err(); err != nil {
+		return sseEvent{}, err
+	}
+	if hasData {
+		return sseEvent{Event: event, Data: data.String(), ID: r.lastID}, nil
+	}
+	return sseEvent{}, io.EOF
+}
+
+// scanSSELines 按 CRLF、LF 或单独的 CR 切分行
+func scanSSELines(data []byte, atEOF bool) (int, []byte, error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		if data[i] == '\n' {
+			return i + 1, data[:i], nil
+		}
+		// CR 后面的 LF 可能还没读到
+		if i+1 == len(data) && !atEOF {
+			return 0, nil, nil
+		}
+		if i+1 < len(data) && data[i+1] == '\n' {
+			return i + 2, data[:i], nil
+		}
+		return i + 1, data[:i], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// readSSE 读取流中的全部事件
+func readSSE(t *testing.T, r io.Reader) []sseEvent {
+	t.Helper()
+	reader := newSSEReader(r)
+	var events []sseEvent
+	for {
+		event, err := reader.Next()
+		if err == io.EOF {
+			return events
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, event)
+	}
+}
+
+func TestSSEReader(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []sseEvent
+	}{
+		{
+			name:  "single data",
+			input: "data: {\"a\":1}\n\n",
+			want:  []sseEvent{{Data: `{"a":1}`}},
+		},
+		{
+			name:  "multi-line data",
+			input: "data: first\ndata:second\ndata\n\n",
+			want:  []sseEvent{{Data: "first\nsecond\n"}},
+		},
+		{
+			name:  "crlf line endings",
+			input: "event: delta\r\ndata: a\r\ndata: b\r\n\r\ndata: c\r\n\r\n",
+			want:  []sseEvent{{Event: "delta", Data: "a\nb"}, {Data: "c"}},
+		},
+		{
+			name:  "bare cr line endings",
+			input: "data: a\r\rdata: b\r\r",
+			want:  []sseEvent{{Data: "a"}, {Data: "b"}},
+		},
+		{
+			name:  "comments and keep-alive",
+			input: ": keep-alive\n\n:ping\ndata: x\n: between fields\n\n",
+			want:  []sseEvent{{Data: "x"}},
+		},
+		{
+			name:  "event without data is dropped",
+			input: "event: ping\n\ndata: x\n\n",
+			want:  []sseEvent{{Data: "x"}},
+		},
+		{
+			name:  "id and unknown fields",
+			input: "id: 7\nretry: 1000\nfoo: bar\ndata: x\n\ndata: y\n\n",
+			want:  []sseEvent{{Data: "x", ID: "7"}, {Data: "y", ID: "7"}},
+		},
+		{
+			name:  "done marker",
+			input: "data: {\"a\":1}\n\ndata: [DONE]\n\n",
+			want:  []sseEvent{{Data: `{"a":1}`}, {Data: sseDone}},
+		},
+		{
+			name:  "missing final blank line",
+			input: "data: a\n\ndata: b",
+			want:  []sseEvent{{Data: "a"}, {Data: "b"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := readSSE(t, strings.NewReader(tt.input)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("events = %#v, want %#v", got, tt.want)
+			}
+			// 每次只读一个字节：事件、行和 CRLF 都会被拆到多次读取中
+			if got := readSSE(t, iotest.OneByteReader(strings.NewReader(tt.input))); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("one byte reads: events = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSSEReaderSplitReads(t *testing.T) {
+	pr, pw := io.Pipe()
+	go func() {
+		// CR 和 LF 分到两次写入，不能被当作两个换行
+		for _, part := range []string{"data: hel", "lo\r", "\ndata: wor", "ld\r", "\n\r", "\n"} {
+			pw.Write([]byte(part))
+		}
+		pw.Close()
+	}()
+	want := []sseEvent{{Data: "hello\nworld"}}
+	if got := readSSE(t, pr); !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %#v, want %#v", got, want)
+	}
+}
+
+func TestSSEReaderLongLines(t *testing.T) {
+	// 超过 bufio 默认 64KB 的行仍能读取
+	long := strings.Repeat("x", 1<<20)
+	want := []sseEvent{{Data: long}}
+	if got := readSSE(t, strings.NewReader("data: "+long+"\n\n")); !reflect.DeepEqual(got, want) {
+		t.Errorf("1MB line was not read intact (%d events)", len(got))
+	}
+
+	// 超过上限的行返回错误而不是无限占用内存
+	reader := newSSEReader(strings.NewReader("data: " + strings.Repeat("x", sseMaxLineSize) + "\n\n"))
+	if _, err := reader.Next(); !errors.Is(err, bufio.ErrTooLong) {
+		t.Errorf("err = %v, want bufio.ErrTooLong", err)
+	}
+}
+
+func TestSSEReaderOnLine(t *testing.T) {
+	reader := newSSEReader(strings.NewReader(": ping\n: ping\ndata: x\n\n"))
+	var lines int
+	reader.onLine = func() { lines++ }
+	if _, err := reader.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if lines != 4 {
+		t.Errorf("onLine called %d times, want 4 (comments count as activity)", lines)
+	}
+}
@@ -0,0 +1,89 @@
+package api
+
+import "context"
+
+// streamChannelBuffer StreamChatWithChannel 事件通道的缓冲区大小
+const streamChannelBuffer = 256
+
+// StreamEventType 流式事件的类型
+type StreamEventType int
+
+const (
+	// StreamEventContent 回复内容增量
+	StreamEventContent StreamEventType = iota + 1
+	// StreamEventReasoning 思考内容增量
+	StreamEventReasoning
+	// StreamEventToolCall 工具调用
+	StreamEventToolCall
+	// StreamEventUsage token 用量，通常在流末尾
+	StreamEventUsage
+	// StreamEventDone 流正常结束，之后通道关闭
+	StreamEventDone
+	// StreamEventError 流出错结束，之后通道关闭
+	StreamEventError
+)
+
+// StreamEvent 流式响应中的一个事件，按到达顺序发送
+type StreamEvent struct {
+	Type      StreamEventType
+	Content   string
+	ToolCalls []ToolCall
+	Usage     *Usage
+	Err       error
+}
+
+// sendCtx 阻塞发送直到读取端接收或 ctx 取消，取消时返回 false
+func sendCtx[T any](ctx context.Context, ch chan<- T, v T) bool {
+	select {
+	case ch <- v:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// StreamChatWithChannel 执行流式聊天请求，按到达顺序返回事件
+// 最后一个事件为 StreamEventDone 或 StreamEventError，随后通道关闭；
+// 请求被取消时可能不发送结束事件而直接关闭通道
+func (c *Client) StreamChatWithChannel(ctx context.Context, messages []Message, tools []Tool) <-chan StreamEvent {
+	// 缓冲区按峰值速率（每秒数百个小块）留出余量，界面短暂繁忙时读取端不会拖慢网络读取
+	events := make(chan StreamEvent, streamChannelBuffer)
+
+	go func() {
+		defer close(events)
+
+		// 创建可取消的子context，关联到StreamChat调用
+		streamCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		// 缓冲区满时阻塞等待读取端，只有 context 取消才放弃发送，保证不丢事件
+		send := func(event StreamEvent) bool {
+			return sendCtx(streamCtx, events, event)
+		}
+		err := c.streamChat(streamCtx, messages, tools, func(chunk *StreamChunk) {
+			if len(chunk.Choices) > 0 && chunk.Choices[0].Delta != nil {
+				delta := chunk.Choices[0].Delta
+				if delta.ReasoningContent != "" && !send(StreamEvent{Type: StreamEventReasoning, Content: delta.ReasoningContent}) {
+					return
+				}
+				if delta.Content != "" && !send(StreamEvent{Type: StreamEventContent, Content: delta.Content}) {
+					return
+				}
+				if len(delta.ToolCalls) > 0 && !send(StreamEvent{Type: StreamEventToolCall, ToolCalls: delta.ToolCalls}) {
+					return
+				}
+			}
+			if chunk.Usage != nil {
+				send(StreamEvent{Type: StreamEventUsage, Usage: chunk.Usage})
+			}
+		})
+
+		if err != nil {
+			send(StreamEvent{Type: StreamEventError, Err: err})
+			return
+		}
+		send(StreamEvent{Type: StreamEventDone})
+	}()
+
+	return events
+}
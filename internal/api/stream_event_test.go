@@ -0,0 +1,172 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newStreamServer 启动返回 SSE 响应的测试服务，并返回指向它的客户端
+func newStreamServer(t *testing.T, handler func(w http.ResponseWriter, r *http.Request, flush func())) *Client {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		flusher := w.(http.Flusher)
+		handler(w, r, flusher.Flush)
+	}))
+	t.Cleanup(server.Close)
+	SetBaseURL(server.URL)
+	t.Cleanup(func() { SetBaseURL("") })
+	return &Client{apiKey: "k", client: server.Client(), streamClient: server.Client()}
+}
+
+// contentChunk 构造只包含回复内容的 SSE 数据块
+func contentChunk(content string) string {
+	return fmt.Sprintf("data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":%q}}]}\n\n", content)
+}
+
+// collectEvents 读取通道直到关闭，超时则失败
+func collectEvents(t *testing.T, events <-chan StreamEvent) []StreamEvent {
+	t.Helper()
+	var got []StreamEvent
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return got
+			}
+			got = append(got, event)
+		case <-timeout:
+			t.Fatalf("stream did not finish, got %d events", len(got))
+		}
+	}
+}
+
+func TestStreamChatWithChannelOrderAndBackpressure(t *testing.T) {
+	// 块数远多于通道缓冲区，读取端暂停期间不能丢弃任何块
+	const chunks = streamChannelBuffer * 3
+	client := newStreamServer(t, func(w http.ResponseWriter, r *http.Request, flush func()) {
+		for i := 0; i < chunks; i++ {
+			fmt.Fprint(w, contentChunk(fmt.Sprintf("%d,", i)))
+		}
+		fmt.Fprint(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"tool_calls\":[{\"index\":0,\"id\":\"call_1\",\"type\":\"function\",\"function\":{\"name\":\"read_file\",\"arguments\":\"{}\"}}]}}]}\n\n")
+		fmt.Fprint(w, "data: {\"choices\":[],\"usage\":{\"prompt_tokens\":3,\"completion_tokens\":5,\"total_tokens\":8}}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+		// [DONE] 之后的数据不再处理
+		fmt.Fprint(w, contentChunk("after done"))
+		flush()
+	})
+
+	events := client.StreamChatWithChannel(context.Background(), []Message{TextMessage("user", "hi")}, nil)
+	// 等缓冲区填满后再开始读取
+	time.Sleep(100 * time.Millisecond)
+	got := collectEvents(t, events)
+
+	var content strings.Builder
+	var types []StreamEventType
+	for _, event := range got {
+		if event.Type == StreamEventContent {
+			content.WriteString(event.Content)
+			continue
+		}
+		types = append(types, event.Type)
+	}
+	var want strings.Builder
+	for i := 0; i < chunks; i++ {
+		fmt.Fprintf(&want, "%d,", i)
+	}
+	if content.String() != want.String() {
+		t.Errorf("content was dropped or reordered:\n got %.80q…\nwant %.80q…", content.String(), want.String())
+	}
+	if len(types) != 3 || types[0] != StreamEventToolCall || types[1] != StreamEventUsage || types[2] != StreamEventDone {
+		t.Fatalf("trailing events = %v, want tool call, usage, done", types)
+	}
+	if usage := got[len(got)-2].Usage; usage == nil || usage.TotalTokens != 8 {
+		t.Errorf("usage = %+v", usage)
+	}
+}
+
+func TestStreamChatWithChannelCancel(t *testing.T) {
+	client := newStreamServer(t, func(w http.ResponseWriter, r *http.Request, flush func()) {
+		for i := 0; i < streamChannelBuffer*2; i++ {
+			fmt.Fprint(w, contentChunk("x"))
+		}
+		flush()
+	})
+
+	// 读取端不再读取时，取消 ctx 后发送端必须退出并关闭通道
+	ctx, cancel := context.WithCancel(context.Background())
+	events := client.StreamChatWithChannel(ctx, []Message{TextMessage("user", "hi")}, nil)
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-events:
+			if !ok {
+				return
+			}
+		case <-deadline:
+			t.Fatal("channel was not closed after cancel")
+		}
+	}
+}
+
+func TestStreamChatWithChannelError(t *testing.T) {
+	client := newStreamServer(t, func(w http.ResponseWriter, r *http.Request, flush func()) {
+		fmt.Fprint(w, contentChunk("partial"))
+		fmt.Fprint(w, "event: error\ndata: overloaded\n\n")
+		flush()
+	})
+
+	got := collectEvents(t, client.StreamChatWithChannel(context.Background(), []Message{TextMessage("user", "hi")}, nil))
+	if len(got) != 2 || got[0].Content != "partial" || got[1].Type != StreamEventError || !strings.Contains(got[1].Err.Error(), "overloaded") {
+		t.Fatalf("events = %+v", got)
+	}
+}
+
+func TestStreamIdleWatchdog(t *testing.T) {
+	t.Run("stalled", func(t *testing.T) {
+		client := newStreamServer(t, func(w http.ResponseWriter, r *http.Request, flush func()) {
+			fmt.Fprint(w, contentChunk("hello"))
+			flush()
+			// 之后不再发送任何数据，直到客户端断开
+			<-r.Context().Done()
+		}).WithStreamIdleTimeout(100 * time.Millisecond)
+
+		start := time.Now()
+		got := collectEvents(t, client.StreamChatWithChannel(context.Background(), []Message{TextMessage("user", "hi")}, nil))
+		if len(got) != 2 || got[0].Content != "hello" || got[1].Type != StreamEventError || !errors.Is(got[1].Err, ErrStreamStalled) {
+			t.Fatalf("events = %+v, want content then ErrStreamStalled", got)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("stall detected after %v, want about 100ms", elapsed)
+		}
+	})
+
+	t.Run("heartbeats keep the stream alive", func(t *testing.T) {
+		client := newStreamServer(t, func(w http.ResponseWriter, r *http.Request, flush func()) {
+			// 总时长超过空闲超时，但心跳注释的间隔小于超时
+			for i := 0; i < 8; i++ {
+				fmt.Fprint(w, ": keep-alive\n\n")
+				flush()
+				time.Sleep(40 * time.Millisecond)
+			}
+			fmt.Fprint(w, contentChunk("done"))
+			fmt.Fprint(w, "data: [DONE]\n\n")
+			flush()
+		}).WithStreamIdleTimeout(150 * time.Millisecond)
+
+		got := collectEvents(t, client.StreamChatWithChannel(context.Background(), []Message{TextMessage("user", "hi")}, nil))
+		if len(got) != 2 || got[0].Content != "done" || got[1].Type != StreamEventDone {
+			t.Fatalf("events = %+v", got)
+		}
+	})
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// streamAccumulator 将流式数据块汇总为完整响应
+type streamAccumulator struct {
+	resp         ChatResponse
+	content      strings.Builder
+	toolCalls    []ToolCall
+	finishReason string
+}
+
+// add 累加一个数据块
+func (a *streamAccumulator) add(chunk *StreamChunk) {
+	if a.resp.ID == "" {
+		a.resp = ChatResponse{
+			ID:      chunk.ID,
+			Object:  chunk.Object,
+			Created: chunk.Created,
+			Model:   chunk.Model,
+		}
+	}
+	if chunk.Usage != nil {
+		a.resp.Usage = chunk.Usage
+	}
+	if len(chunk.Choices) == 0 {
+		return
+	}
+
+	choice := chunk.Choices[0]
+	if choice.FinishReason != "" {
+		a.finishReason = choice.FinishReason
+	}
+	if choice.Delta != nil {
+		a.content.WriteString(choice.Delta.Content)
+		a.toolCalls = append(a.toolCalls, choice.Delta.ToolCalls...)
+	}
+}
+
+// response 返回汇总后的响应
+func (a *streamAccumulator) response() *ChatResponse {
+	resp := a.resp
+	finishReason := a.finishReason
+	if finishReason == "" {
+		finishReason = "stop"
+	}
+
+	contentBytes, _ := json.Marshal(a.content.String())
+	resp.Choices = []Choice{
+		{
+			Index: 0,
+			Message: &Message{
+				Role:      "assistant",
+				Content:   contentBytes,
+				ToolCalls: a.toolCalls,
+			},
+			FinishReason: finishReason,
+		},
+	}
+	return &resp
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultStreamIdleTimeout 流式响应默认的空闲超时
+const DefaultStreamIdleTimeout = 60 * time.Second
+
+// ErrStreamStalled 流式响应在空闲超时内没有收到任何数据（包括心跳）
+var ErrStreamStalled = errors.New("stream stalled")
+
+// idleWatchdog 空闲检测：超时未调用 Reset 时取消请求
+type idleWatchdog struct {
+	timeout time.Duration
+	timer   *time.Timer
+	stalled atomic.Bool
+}
+
+// newIdleWatchdog 启动空闲检测，超时后调用 cancel
+func newIdleWatchdog(timeout time.Duration, cancel context.CancelFunc) *idleWatchdog {
+	w := &idleWatchdog{timeout: timeout}
+	w.timer = time.AfterFunc(timeout, func() {
+		w.stalled.Store(true)
+		cancel()
+	})
+	return w
+}
+
+// Reset 收到数据后重新计时
+func (w *idleWatchdog) Reset() {
+	w.timer.Reset(w.timeout)
+}
+
+// Stop 停止检测
+func (w *idleWatchdog) Stop() {
+	w.timer.Stop()
+}
+
+// Stalled 是否因空闲超时取消了请求
+func (w *idleWatchdog) Stalled() bool {
+	return w.stalled.Load()
+}
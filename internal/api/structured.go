@@ -0,0 +1,120 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// defaultStructuredAttempts 结构化输出默认最多尝试次数（含首次请求）
+const defaultStructuredAttempts = 3
+
+// StructuredOutput 描述期望的 JSON 输出
+type StructuredOutput struct {
+	// Name 输出的用途，写入提示中帮助模型理解
+	Name string
+	// Schema 输出必须满足的 JSON Schema
+	Schema map[string]interface{}
+	// MaxAttempts 最多尝试次数，0 表示使用默认值
+	MaxAttempts int
+}
+
+// ChatJSON 请求模型返回符合 schema 的 JSON，并解码到 out
+// 使用 json_object 响应格式并在本地校验，不符合时把错误反馈给模型重新生成
+func (c *Client) ChatJSON(messages []Message, output StructuredOutput, out interface{}) error {
+	schemaBytes, err := json.MarshalIndent(output.Schema, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化 schema 失败: %w", err)
+	}
+
+	attempts := output.MaxAttempts
+	if attempts <= 0 {
+		attempts = defaultStructuredAttempts
+	}
+
+	client := c.WithResponseFormat(&ResponseFormat{Type: ResponseFormatJSON})
+
+	conversation := make([]Message, 0, len(messages)+1+attempts*2)
+	conversation = append(conversation, TextMessage("system", structuredOutputPrompt(output.Name, string(schemaBytes))))
+	conversation = append(conversation, messages...)
+
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		resp, err := client.ChatCompletion(conversation, false, nil)
+		if err != nil {
+			return err
+		}
+		if len(resp.Choices) == 0 || resp.Choices[0].Message == nil {
+			return fmt.Errorf("响应中没有内容")
+		}
+
+		content := MessageText(*resp.Choices[0].Message)
+		value, err := decodeStructuredContent(content)
+		if err == nil {
+			err = ValidateJSONSchema(output.Schema, value)
+		}
+		if err == nil {
+			normalized, _ := json.Marshal(value)
+			if err := json.Unmarshal(normalized, out); err != nil {
+				return fmt.Errorf("解码结构化输出失败: %w", err)
+			}
+			return nil
+		}
+
+		// 把错误反馈给模型，要求重新生成
+		lastErr = err
+		conversation = append(conversation,
+			TextMessage("assistant", content),
+			TextMessage("user", fmt.Sprintf("上面的输出无效：%v\n请只输出修正后的完整 JSON，不要包含任何解释或代码块标记。", err)),
+		)
+	}
+
+	return fmt.Errorf("结构化输出校验失败（已尝试 %d 次）: %w", attempts, lastErr)
+}
+
+// structuredOutputPrompt 构建要求输出 JSON 的系统提示
+func structuredOutputPrompt(name, schema string) string {
+	var builder strings.Builder
+	builder.WriteString("你必须只输出一个 JSON 值，不要输出任何解释、Markdown 或代码块标记。")
+	if name != "" {
+		builder.WriteString(fmt.Sprintf("\n输出用途：%s。", name))
+	}
+	builder.WriteString("\n输出必须符合以下 JSON Schema：\n")
+	builder.WriteString(schema)
+	return builder.String()
+}
+
+// decodeStructuredContent 解析模型输出的 JSON，兼容被代码块包裹的情况
+func decodeStructuredContent(content string) (interface{}, error) {
+	text := strings.TrimSpace(content)
+	if strings.HasPrefix(text, "```") {
+		text = strings.TrimPrefix(text, "```json")
+		text = strings.TrimPrefix(text, "```")
+		text = strings.TrimSuffix(strings.TrimSpace(text), "```")
+		text = strings.TrimSpace(text)
+	}
+	if text == "" {
+		return nil, fmt.Errorf("输出为空")
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(text), &value); err != nil {
+		return nil, fmt.Errorf("输出不是合法的 JSON: %w", err)
+	}
+	return value, nil
+}
+
+// MessageText 返回消息的文本内容，content 不是字符串时返回原始 JSON
+func MessageText(msg Message) string {
+	if len(msg.Content) == 0 {
+		return ""
+	}
+	var text string
+	if err := json.Unmarshal(msg.Content, &text); err == nil {
+		return text
+	}
+	if string(msg.Content) == "null" {
+		return ""
+	}
+	return string(msg.Content)
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"sync"
+	"time"
+)
+
+// 默认的请求超时
+const (
+	DefaultConnectTimeout        = 10 * time.Second
+	DefaultResponseHeaderTimeout = 60 * time.Second
+	DefaultRequestTimeout        = 120 * time.Second
+)
+
+// Timeouts 模型 API 请求的超时。流式请求只受 Connect、ResponseHeader 和空闲超时限制，
+// 非流式请求的整体时间受 Request 限制。为 0 的项使用默认值
+type Timeouts struct {
+	Connect        time.Duration
+	ResponseHeader time.Duration
+	Request        time.Duration
+}
+
+var (
+	timeoutsMu sync.RWMutex
+	timeouts   Timeouts
+)
+
+// SetTimeouts 设置请求超时，之后创建的客户端生效
+func SetTimeouts(t Timeouts) {
+	timeoutsMu.Lock()
+	timeouts = t
+	timeoutsMu.Unlock()
+
+	// 丢弃已创建的共享客户端，下次使用时按新的超时重建
+	httpClientsMu.Lock()
+	sharedHTTPClient, sharedStreamClient = nil, nil
+	httpClientsMu.Unlock()
+}
+
+// CurrentTimeouts 返回当前的超时设置，未设置的项使用默认值
+func CurrentTimeouts() Timeouts {
+	timeoutsMu.RLock()
+	t := timeouts
+	timeoutsMu.RUnlock()
+
+	if t.Connect <= 0 {
+		t.Connect = DefaultConnectTimeout
+	}
+	if t.ResponseHeader <= 0 {
+		t.ResponseHeader = DefaultResponseHeaderTimeout
+	}
+	if t.Request <= 0 {
+		t.Request = DefaultRequestTimeout
+	}
+	return t
+}
@@ -0,0 +1,31 @@
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeouts(t *testing.T) {
+	t.Cleanup(func() { SetTimeouts(Timeouts{}) })
+	SetTimeouts(Timeouts{Request: 5 * time.Second})
+
+	got := CurrentTimeouts()
+	if got.Connect != DefaultConnectTimeout || got.ResponseHeader != DefaultResponseHeaderTimeout || got.Request != 5*time.Second {
+		t.Errorf("CurrentTimeouts() = %+v", got)
+	}
+
+	transport := newAPITransport(got, CurrentTransportOptions())
+	if transport.TLSHandshakeTimeout != DefaultConnectTimeout || transport.ResponseHeaderTimeout != 0 {
+		t.Errorf("transport timeouts: tls %v, header %v", transport.TLSHandshakeTimeout, transport.ResponseHeaderTimeout)
+	}
+
+	client := NewClient("k")
+	if client.client == client.streamClient {
+		t.Fatal("streaming and non-streaming requests should use separate clients")
+	}
+	// 修改超时后重建共享客户端
+	SetTimeouts(Timeouts{Request: 7 * time.Second})
+	if NewClient("k").client == client.client {
+		t.Error("SetTimeouts should rebuild the shared clients")
+	}
+}
@@ -0,0 +1,286 @@
+package api
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// toolCallFence 模拟工具调用时，模型用这种语言标记的代码块输出一次工具调用
+const toolCallFence = "```tool_call"
+
+// toolEmulationPrompt 模拟工具调用时追加到系统提示中的调用格式说明，%s 为工具列表
+const toolEmulationPrompt = "## 工具调用\n" +
+	"需要调用工具时，输出一个语言标记为 tool_call 的代码块，内容是包含 name 和 arguments 的 JSON 对象，例如：\n" +
+	toolCallFence + "\n{\"name\": \"read_file\", \"arguments\": {\"file_path\": \"main.go\"}}\n```\n" +
+	"每个代码块调用一个工具，可以连续输出多个代码块。输出工具调用后立即停止回复，等待工具结果；" +
+	"工具结果会以「工具结果」开头的用户消息返回。不需要工具时直接回答，不要输出 tool_call 代码块。\n\n" +
+	"可用的工具（parameters 为 JSON Schema）：\n%s"
+
+// WithToolEmulation 返回模拟工具调用的客户端副本：工具定义写入系统提示而不是 tools 参数，
+// 从回复文本中的 tool_call 代码块解析工具调用，用于不支持原生函数调用的本地模型（Ollama、llama.cpp 等）
+func (c *Client) WithToolEmulation(enabled bool) *Client {
+	clone := *c
+	clone.toolEmulation = enabled
+	return &clone
+}
+
+// applyToolEmulation 模拟工具调用时改写请求：去掉 tools 和 tool_choice，在系统提示中说明调用格式，
+// 并将历史中的工具调用和工具结果改写为普通文本消息
+func (c *Client) applyToolEmulation(req *ChatRequest) {
+	if !c.toolEmulation {
+		return
+	}
+	tools, choice := req.Tools, req.ToolChoice
+	req.Tools, req.ToolChoice = nil, nil
+	req.Messages = emulatedHistory(req.Messages)
+	if len(tools) == 0 || (choice != nil && choice.Function == "" && choice.Mode == ToolChoiceNone) {
+		return
+	}
+	req.Messages = withSystemText(req.Messages, toolGrammar(tools, choice))
+}
+
+// toolGrammar 返回调用格式说明和工具列表，tool_choice 要求调用工具时追加相应的要求
+func toolGrammar(tools []Tool, choice *ToolChoice) string {
+	var sb strings.Builder
+	for _, tool := range tools {
+		params, _ := json.Marshal(tool.Function.Parameters)
+		fmt.Fprintf(&sb, "- %s: %s\n  parameters: %s\n", tool.Function.Name, tool.Function.Description, params)
+	}
+	prompt := fmt.Sprintf(toolEmulationPrompt, sb.String())
+	switch {
+	case choice == nil:
+	case choice.Function != "":
+		prompt += fmt.Sprintf("\n本次回复必须调用 %s 工具。", choice.Function)
+	case choice.Mode == ToolChoiceRequired:
+		prompt += "\n本次回复必须至少调用一个工具。"
+	}
+	return prompt
+}
+
+// withSystemText 将文本追加到第一条系统消息，没有系统消息时在开头插入一条
+func withSystemText(messages []Message, text string) []Message {
+	if len(messages) > 0 && messages[0].Role == "system" {
+		result := append([]Message(nil), messages...)
+		result[0] = TextMessage("system", MessageText(messages[0])+"\n\n"+text)
+		return result
+	}
+	return append([]Message{TextMessage("system", text)}, messages...)
+}
+
+// emulatedHistory 将工具调用改写为助手消息中的 tool_call 代码块，将工具结果改写为用户消息。
+// 连续的工具结果合并为一条，部分本地模型的对话模板要求用户和助手消息交替出现
+func emulatedHistory(messages []Message) []Message {
+	result := make([]Message, 0, len(messages))
+	var results []string
+	flushResults := func() {
+		if len(results) > 0 {
+			result = append(result, TextMessage("user", strings.Join(results, "\n\n")))
+			results = nil
+		}
+	}
+	for _, msg := range messages {
+		switch {
+		case msg.Role == "tool":
+			results = append(results, fmt.Sprintf("工具结果（%s %s）：\n%s", msg.Name, msg.ToolCallID, MessageText(msg)))
+			continue
+		case len(msg.ToolCalls) > 0:
+			flushResults()
+			text := strings.TrimSpace(MessageText(msg))
+			for _, call := range msg.ToolCalls {
+				text = strings.TrimSpace(text + "\n\n" + formatToolCallBlock(call))
+			}
+			result = append(result, TextMessage("assistant", text))
+		default:
+			flushResults()
+			result = append(result, msg)
+		}
+	}
+	flushResults()
+	return result
+}
+
+// formatToolCallBlock 按模拟调用的格式输出一次工具调用
+func formatToolCallBlock(call ToolCall) string {
+	args := call.Function.Arguments
+	if len(args) == 0 {
+		args = json.RawMessage("{}")
+	}
+	data, err := json.Marshal(struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}{call.Function.Name, args})
+	if err != nil {
+		data = []byte(fmt.Sprintf(`{"name":%q,"arguments":{}}`, call.Function.Name))
+	}
+	return toolCallFence + "\n" + string(data) + "\n```"
+}
+
+// toolCallParser 从流式回复文本中识别 tool_call 代码块并转换为工具调用，其余文本原样输出。
+// 可能是代码块开头的末尾文本暂时保留，直到能确定是否为工具调用
+type toolCallParser struct {
+	pending string
+	// inCall 是否处于 tool_call 代码块中，pending 为代码块标记之后的内容
+	inCall bool
+	// afterFence 刚读到结束标记，之后的一个换行属于代码块，不输出
+	afterFence bool
+	// emitted 已解析出的工具调用数
+	emitted int
+}
+
+// newToolCallParser 模拟工具调用时返回流式回复的解析器，否则返回 nil
+func (c *Client) newToolCallParser() *toolCallParser {
+	if !c.toolEmulation {
+		return nil
+	}
+	return &toolCallParser{}
+}
+
+// feed 处理一段新到达的文本，返回可以显示的文本和已完整的工具调用
+func (p *toolCallParser) feed(text string) (string, []ToolCall) {
+	p.pending += text
+	var out strings.Builder
+	var calls []ToolCall
+	for {
+		if p.afterFence && p.pending != "" {
+			p.pending = strings.TrimPrefix(p.pending, "\n")
+			p.afterFence = false
+		}
+		if !p.inCall {
+			i := strings.Index(p.pending, toolCallFence)
+			if i < 0 {
+				keep := partialFenceSuffix(p.pending)
+				out.WriteString(p.pending[:len(p.pending)-keep])
+				p.pending = p.pending[len(p.pending)-keep:]
+				return out.String(), calls
+			}
+			out.WriteString(strings.TrimRight(p.pending[:i], " \t"))
+			p.pending = p.pending[i+len(toolCallFence):]
+			p.inCall = true
+		}
+		// 结束标记必须在行首：JSON 字符串中的换行是转义的，参数内容里的 ``` 不会被误认为结束
+		end := strings.Index(p.pending, "\n```")
+		if end < 0 {
+			return out.String(), calls
+		}
+		body := p.pending[:end]
+		p.pending = p.pending[end+4:]
+		p.inCall, p.afterFence = false, true
+		if call, ok := p.parseCall(body); ok {
+			calls = append(calls, call)
+		} else {
+			// 无法解析时原样显示，让用户看到模型输出了什么
+			out.WriteString(toolCallFence + body + "\n```")
+		}
+	}
+}
+
+// flush 流结束时处理剩余的文本。模型常在代码块结束标记之前停止生成，这时仍尝试解析为工具调用
+func (p *toolCallParser) flush() (string, []ToolCall) {
+	pending, inCall := p.pending, p.inCall
+	p.pending, p.inCall, p.afterFence = "", false, false
+	if !inCall {
+		return pending, nil
+	}
+	if call, ok := p.parseCall(strings.TrimRight(strings.TrimSpace(pending), "`")); ok {
+		return "", []ToolCall{call}
+	}
+	return toolCallFence + pending, nil
+}
+
+// parseCall 解析代码块中的 {"name": ..., "arguments": {...}}，arguments 也可以是 JSON 字符串
+func (p *toolCallParser) parseCall(body string) (ToolCall, bool) {
+	var invocation struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(body)), &invocation); err != nil || invocation.Name == "" {
+		return ToolCall{}, false
+	}
+	args := bytes.TrimSpace(invocation.Arguments)
+	var encoded string
+	if json.Unmarshal(args, &encoded) == nil {
+		args = []byte(encoded)
+	}
+	if len(args) == 0 || string(args) == "null" {
+		args = []byte("{}")
+	}
+	if !json.Valid(args) || args[0] != '{' {
+		return ToolCall{}, false
+	}
+	p.emitted++
+	return ToolCall{
+		ID:       emulatedCallID(),
+		Type:     "function",
+		Function: ToolCallFunction{Name: invocation.Name, Arguments: json.RawMessage(args)},
+	}, true
+}
+
+// partialFenceSuffix 返回文本末尾可能是代码块标记开头的长度
+func partialFenceSuffix(text string) int {
+	for n := min(len(text), len(toolCallFence)-1); n > 0; n-- {
+		if strings.HasSuffix(text, toolCallFence[:n]) {
+			return n
+		}
+	}
+	return 0
+}
+
+// emulatedCallID 为解析出的工具调用生成 ID，工具结果通过它与调用对应
+func emulatedCallID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return "call_" + hex.EncodeToString(b)
+}
+
+// rewrite 将数据块中的文本交给解析器，文本替换为可以显示的部分，解析出的工具调用放入 ToolCalls；未启用模拟时不做任何事
+func (p *toolCallParser) rewrite(chunk *StreamChunk) {
+	if p == nil || len(chunk.Choices) == 0 || chunk.Choices[0].Delta == nil {
+		return
+	}
+	delta := chunk.Choices[0].Delta
+	text, calls := p.feed(delta.Content)
+	delta.Content = text
+	delta.ToolCalls = append(delta.ToolCalls, calls...)
+	if p.emitted > 0 && chunk.Choices[0].FinishReason == "stop" {
+		chunk.Choices[0].FinishReason = "tool_calls"
+	}
+}
+
+// finalChunk 流结束时剩余的文本和工具调用，没有或未启用模拟时返回 nil
+func (p *toolCallParser) finalChunk() *StreamChunk {
+	if p == nil {
+		return nil
+	}
+	text, calls := p.flush()
+	if text == "" && len(calls) == 0 {
+		return nil
+	}
+	choice := Choice{Delta: &Delta{Content: text, ToolCalls: calls}}
+	if p.emitted > 0 {
+		choice.FinishReason = "tool_calls"
+	}
+	return &StreamChunk{Choices: []Choice{choice}}
+}
+
+// parseEmulatedResponse 从非流式回复的文本中解析工具调用
+func parseEmulatedResponse(resp *ChatResponse) {
+	for i := range resp.Choices {
+		choice := &resp.Choices[i]
+		if choice.Message == nil {
+			continue
+		}
+		var p toolCallParser
+		text, calls := p.feed(MessageText(*choice.Message))
+		rest, more := p.flush()
+		calls = append(calls, more...)
+		if len(calls) == 0 {
+			continue
+		}
+		*choice.Message = AssistantToolCallMessage(strings.TrimSpace(text+rest), append(choice.Message.ToolCalls, calls...))
+		choice.FinishReason = "tool_calls"
+	}
+}
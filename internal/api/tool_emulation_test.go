@@ -0,0 +1,116 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestToolCallParser(t *testing.T) {
+	reply := "先看看文件。\n```tool_call\n{\"name\": \"read_file\", \"arguments\": {\"file_path\": \"README.md\", \"note\": \"```\"}}\n```\n" +
+		"```tool_call\n{\"name\": \"glob\", \"arguments\": \"{\\\"pattern\\\": \\\"*.go\\\"}\"}"
+	// 按各种位置切分，结果都应相同；第二个调用缺少结束标记
+	for size := 1; size <= len(reply); size += 7 {
+		var p toolCallParser
+		var text strings.Builder
+		var calls []ToolCall
+		for start := 0; start < len(reply); start += size {
+			out, got := p.feed(reply[start:min(start+size, len(reply))])
+			text.WriteString(out)
+			calls = append(calls, got...)
+		}
+		out, got := p.flush()
+		text.WriteString(out)
+		calls = append(calls, got...)
+
+		if text.String() != "先看看文件。\n" {
+			t.Fatalf("size %d: text = %q", size, text.String())
+		}
+		if len(calls) != 2 || calls[0].Function.Name != "read_file" || calls[1].Function.Name != "glob" {
+			t.Fatalf("size %d: calls = %+v", size, calls)
+		}
+		var args struct{ FilePath, Note string }
+		if err := json.Unmarshal(calls[0].Function.Arguments, &args); err != nil || args.Note != "```" {
+			t.Errorf("size %d: arguments = %s", size, calls[0].Function.Arguments)
+		}
+		if string(calls[1].Function.Arguments) != `{"pattern": "*.go"}` || calls[0].ID == calls[1].ID {
+			t.Errorf("size %d: second call = %+v", size, calls[1])
+		}
+	}
+
+	// 无法解析的代码块原样保留
+	var p toolCallParser
+	out, calls := p.feed("```tool_call\nnot json\n```\n完成")
+	rest, more := p.flush()
+	if out+rest != "```tool_call\nnot json\n```完成" || len(calls)+len(more) != 0 {
+		t.Errorf("text = %q, calls = %v", out+rest, append(calls, more...))
+	}
+}
+
+func TestApplyToolEmulation(t *testing.T) {
+	client := (&Client{}).WithToolEmulation(true)
+	tools := []Tool{{Type: "function", Function: ToolFunction{Name: "read_file", Description: "读取文件", Parameters: map[string]interface{}{"type": "object"}}}}
+	messages := []Message{
+		TextMessage("system", "你是助手"),
+		TextMessage("user", "看看 main.go"),
+		AssistantToolCallMessage("好的", []ToolCall{{ID: "c1", Type: "function", Function: ToolCallFunction{Name: "read_file", Arguments: json.RawMessage(`{"file_path":"main.go"}`)}}}),
+		ToolResultMessageWithName("c1", "read_file", "package main"),
+	}
+	req, err := client.WithToolChoice(RequiredToolChoice()).newChatRequest(messages, true, tools)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Tools != nil || req.ToolChoice != nil || len(req.Messages) != 4 {
+		t.Fatalf("request = %+v", req)
+	}
+	system := MessageText(req.Messages[0])
+	for _, want := range []string{"你是助手", "```tool_call", "- read_file: 读取文件", `parameters: {"type":"object"}`, "必须至少调用一个工具"} {
+		if !strings.Contains(system, want) {
+			t.Errorf("missing %q in system prompt:\n%s", want, system)
+		}
+	}
+	if got := MessageText(req.Messages[2]); got != "好的\n\n```tool_call\n{\"name\":\"read_file\",\"arguments\":{\"file_path\":\"main.go\"}}\n```" || len(req.Messages[2].ToolCalls) != 0 {
+		t.Errorf("assistant message = %q", got)
+	}
+	if req.Messages[3].Role != "user" || MessageText(req.Messages[3]) != "工具结果（read_file c1）：\npackage main" {
+		t.Errorf("tool result = %+v", req.Messages[3])
+	}
+	// 调用方的消息不被修改
+	if len(messages[2].ToolCalls) != 1 || MessageText(messages[0]) != "你是助手" {
+		t.Error("caller's messages were modified")
+	}
+}
+
+func TestStreamChatToolEmulation(t *testing.T) {
+	deltas := []string{"我来读取", "文件。\n``", "`tool_call\n{\"name\":\"read_file\",", "\"arguments\":{\"file_path\":\"go.mod\"}}\n```"}
+	var body strings.Builder
+	for _, delta := range deltas {
+		data, _ := json.Marshal(StreamChunk{Choices: []Choice{{Delta: &Delta{Content: delta}}}})
+		fmt.Fprintf(&body, "data: %s\n\n", data)
+	}
+	body.WriteString("data: [DONE]\n\n")
+
+	client := (&Client{apiKey: "k", streamClient: doerFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body.String()))}, nil
+	})}).WithToolEmulation(true)
+
+	var text strings.Builder
+	var calls []ToolCall
+	for event := range client.StreamChatWithChannel(context.Background(), []Message{TextMessage("user", "看看 go.mod")}, nil) {
+		switch event.Type {
+		case StreamEventContent:
+			text.WriteString(event.Content)
+		case StreamEventToolCall:
+			calls = append(calls, event.ToolCalls...)
+		case StreamEventError:
+			t.Fatal(event.Err)
+		}
+	}
+	if text.String() != "我来读取文件。\n" || len(calls) != 1 || string(calls[0].Function.Arguments) != `{"file_path":"go.mod"}` {
+		t.Errorf("text = %q, calls = %+v", text.String(), calls)
+	}
+}
@@ -0,0 +1,232 @@
+package api
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 默认的连接池参数
+const (
+	DefaultMaxConnsPerHost     = 100
+	DefaultMaxIdleConnsPerHost = 50
+	DefaultIdleConnTimeout     = 90 * time.Second
+	DefaultKeepAlive           = 30 * time.Second
+)
+
+// TransportOptions 模型 API 连接的调优参数，为 0 的项使用默认值
+type TransportOptions struct {
+	// ForceHTTP1 只使用 HTTP/1.1，用于在 HTTP/2 下破坏流式响应的代理
+	ForceHTTP1          bool
+	MaxConnsPerHost     int
+	MaxIdleConnsPerHost int
+	// IdleConnTimeout 空闲连接保留多久以便复用
+	IdleConnTimeout time.Duration
+	// KeepAlive TCP keep-alive 探测间隔，负数表示关闭
+	KeepAlive time.Duration
+}
+
+var (
+	transportOptionsMu sync.RWMutex
+	transportOptions   TransportOptions
+)
+
+// SetTransportOptions 设置连接调优参数，之后创建的客户端生效
+func SetTransportOptions(o TransportOptions) {
+	transportOptionsMu.Lock()
+	transportOptions = o
+	transportOptionsMu.Unlock()
+
+	// 丢弃已创建的共享客户端，下次使用时按新的参数重建
+	httpClientsMu.Lock()
+	sharedHTTPClient, sharedStreamClient = nil, nil
+	httpClientsMu.Unlock()
+}
+
+// CurrentTransportOptions 返回当前的连接调优参数，未设置的项使用默认值
+func CurrentTransportOptions() TransportOptions {
+	transportOptionsMu.RLock()
+	o := transportOptions
+	transportOptionsMu.RUnlock()
+
+	if o.MaxConnsPerHost <= 0 {
+		o.MaxConnsPerHost = DefaultMaxConnsPerHost
+	}
+	if o.MaxIdleConnsPerHost <= 0 {
+		o.MaxIdleConnsPerHost = DefaultMaxIdleConnsPerHost
+	}
+	if o.IdleConnTimeout <= 0 {
+		o.IdleConnTimeout = DefaultIdleConnTimeout
+	}
+	if o.KeepAlive == 0 {
+		o.KeepAlive = DefaultKeepAlive
+	}
+	return o
+}
+
+// ConnStats 本进程中模型 API 请求的连接统计，用于排查网络问题
+type ConnStats struct {
+	Requests    int
+	Failed      int
+	NewConns    int
+	ReusedConns int
+	// Protocols 按响应协议（如 HTTP/2.0）统计的请求数
+	Protocols map[string]int
+	// 新建连接时各阶段的平均耗时，没有新建连接时为 0
+	AvgDNS, AvgConnect, AvgTLS time.Duration
+	// AvgFirstByte 从发出请求到收到响应第一个字节的平均耗时
+	AvgFirstByte   time.Duration
+	LastRemoteAddr string
+	LastError      string
+}
+
+// ProtocolNames 按请求数从多到少返回出现过的协议
+func (s ConnStats) ProtocolNames() []string {
+	names := make([]string, 0, len(s.Protocols))
+	for name := range s.Protocols {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if s.Protocols[names[i]] != s.Protocols[names[j]] {
+			return s.Protocols[names[i]] > s.Protocols[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// connStatsTotals 累计的连接统计
+type connStatsTotals struct {
+	mu    sync.Mutex
+	stats ConnStats
+	// 各阶段的耗时总和和次数，用于计算平均值
+	dns, connect, tls, firstByte     time.Duration
+	dnsN, connectN, tlsN, firstByteN int
+}
+
+var connStats = &connStatsTotals{}
+
+// ConnectionStats 返回模型 API 请求的连接统计
+func ConnectionStats() ConnStats {
+	connStats.mu.Lock()
+	defer connStats.mu.Unlock()
+	s := connStats.stats
+	s.Protocols = make(map[string]int, len(connStats.stats.Protocols))
+	for name, n := range connStats.stats.Protocols {
+		s.Protocols[name] = n
+	}
+	s.AvgDNS = average(connStats.dns, connStats.dnsN)
+	s.AvgConnect = average(connStats.connect, connStats.connectN)
+	s.AvgTLS = average(connStats.tls, connStats.tlsN)
+	s.AvgFirstByte = average(connStats.firstByte, connStats.firstByteN)
+	return s
+}
+
+func average(total time.Duration, n int) time.Duration {
+	if n == 0 {
+		return 0
+	}
+	return total / time.Duration(n)
+}
+
+// connTrace 单次请求的连接过程。拨号可能在其他 goroutine 中进行，字段由 mu 保护
+type connTrace struct {
+	mu                               sync.Mutex
+	gotConn, reused                  bool
+	remoteAddr                       string
+	dnsStart, connectStart, tlsStart time.Time
+	dns, connect, tls, firstByte     time.Duration
+}
+
+// statsTransport 记录每次请求的连接是否复用、各阶段耗时和响应协议
+type statsTransport struct {
+	base http.RoundTripper
+}
+
+func (t *statsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rec := &connTrace{}
+	start := time.Now()
+	since := func(from *time.Time, into *time.Duration) {
+		rec.mu.Lock()
+		defer rec.mu.Unlock()
+		if !from.IsZero() {
+			*into = time.Since(*from)
+		}
+	}
+	mark := func(at *time.Time) {
+		rec.mu.Lock()
+		defer rec.mu.Unlock()
+		*at = time.Now()
+	}
+	trace := &httptrace.ClientTrace{
+		DNSStart:          func(httptrace.DNSStartInfo) { mark(&rec.dnsStart) },
+		DNSDone:           func(httptrace.DNSDoneInfo) { since(&rec.dnsStart, &rec.dns) },
+		ConnectStart:      func(string, string) { mark(&rec.connectStart) },
+		ConnectDone:       func(string, string, error) { since(&rec.connectStart, &rec.connect) },
+		TLSHandshakeStart: func() { mark(&rec.tlsStart) },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { since(&rec.tlsStart, &rec.tls) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			rec.mu.Lock()
+			defer rec.mu.Unlock()
+			rec.gotConn, rec.reused = true, info.Reused
+			if info.Conn != nil {
+				rec.remoteAddr = info.Conn.RemoteAddr().String()
+			}
+		},
+		GotFirstResponseByte: func() {
+			rec.mu.Lock()
+			defer rec.mu.Unlock()
+			rec.firstByte = time.Since(start)
+		},
+	}
+
+	resp, err := t.base.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+	connStats.record(rec, resp, err)
+	return resp, err
+}
+
+// record 将一次请求计入统计
+func (c *connStatsTotals) record(rec *connTrace, resp *http.Response, err error) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stats.Requests++
+	if err != nil {
+		c.stats.Failed++
+		c.stats.LastError = err.Error()
+	}
+	if rec.gotConn {
+		if rec.reused {
+			c.stats.ReusedConns++
+		} else {
+			c.stats.NewConns++
+		}
+		c.stats.LastRemoteAddr = rec.remoteAddr
+	}
+	if resp != nil {
+		if c.stats.Protocols == nil {
+			c.stats.Protocols = make(map[string]int)
+		}
+		c.stats.Protocols[resp.Proto]++
+	}
+	for _, phase := range []struct {
+		value time.Duration
+		total *time.Duration
+		n     *int
+	}{
+		{rec.dns, &c.dns, &c.dnsN},
+		{rec.connect, &c.connect, &c.connectN},
+		{rec.tls, &c.tls, &c.tlsN},
+		{rec.firstByte, &c.firstByte, &c.firstByteN},
+	} {
+		if phase.value > 0 {
+			*phase.total += phase.value
+			*phase.n++
+		}
+	}
+}
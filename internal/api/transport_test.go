@@ -0,0 +1,67 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTransportOptions(t *testing.T) {
+	t.Cleanup(func() { SetTransportOptions(TransportOptions{}) })
+	SetTransportOptions(TransportOptions{ForceHTTP1: true, MaxConnsPerHost: 4, KeepAlive: -1})
+
+	got := CurrentTransportOptions()
+	if got.MaxConnsPerHost != 4 || got.MaxIdleConnsPerHost != DefaultMaxIdleConnsPerHost || got.IdleConnTimeout != DefaultIdleConnTimeout || got.KeepAlive != -1 {
+		t.Errorf("CurrentTransportOptions() = %+v", got)
+	}
+
+	transport := newAPITransport(CurrentTimeouts(), got)
+	if transport.MaxConnsPerHost != 4 || transport.ForceAttemptHTTP2 {
+		t.Errorf("transport: max conns %d, force h2 %v", transport.MaxConnsPerHost, transport.ForceAttemptHTTP2)
+	}
+	if transport.Protocols == nil || !transport.Protocols.HTTP1() || transport.Protocols.HTTP2() {
+		t.Errorf("protocols = %v, want HTTP/1.1 only", transport.Protocols)
+	}
+
+	if p := newAPITransport(CurrentTimeouts(), TransportOptions{}).Protocols; p != nil {
+		t.Errorf("default protocols = %v, want nil", p)
+	}
+}
+
+func TestStatsTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	before := ConnectionStats()
+	client := &http.Client{Transport: &statsTransport{base: newAPITransport(Timeouts{Connect: time.Second}, CurrentTransportOptions())}}
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	if _, err := client.Get("http://127.0.0.1:1"); err == nil {
+		t.Fatal("expected connection error")
+	}
+
+	after := ConnectionStats()
+	if after.Requests-before.Requests != 3 || after.Failed-before.Failed != 1 {
+		t.Errorf("requests %d, failed %d", after.Requests-before.Requests, after.Failed-before.Failed)
+	}
+	// 第二次请求复用第一次的连接
+	if after.NewConns-before.NewConns != 1 || after.ReusedConns-before.ReusedConns != 1 {
+		t.Errorf("new %d, reused %d", after.NewConns-before.NewConns, after.ReusedConns-before.ReusedConns)
+	}
+	if after.Protocols["HTTP/1.1"]-before.Protocols["HTTP/1.1"] != 2 || after.LastRemoteAddr != server.Listener.Addr().String() {
+		t.Errorf("protocols %v, remote %q", after.Protocols, after.LastRemoteAddr)
+	}
+	if after.AvgFirstByte <= 0 || after.LastError == "" {
+		t.Errorf("first byte %v, last error %q", after.AvgFirstByte, after.LastError)
+	}
+}
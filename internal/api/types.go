@@ -13,14 +13,27 @@ type Message struct {
 }
 
 type ChatRequest struct {
-	Model       string          `json:"model"`
-	Messages    []Message       `json:"messages"`
-	Stream      bool            `json:"stream"`
-	MaxTokens   int             `json:"max_tokens,omitempty"`
-	Temperature float64         `json:"temperature,omitempty"`
-	Thinking    *Thinking       `json:"thinking,omitempty"`
-	Tools       []Tool          `json:"tools,omitempty"`
-	ToolChoice  json.RawMessage `json:"tool_choice,omitempty"`
+	Model       string      `json:"model"`
+	Messages    []Message   `json:"messages"`
+	Stream      bool        `json:"stream"`
+	MaxTokens   int         `json:"max_tokens,omitempty"`
+	Temperature float64     `json:"temperature"`
+	Thinking    *Thinking   `json:"thinking,omitempty"`
+	Tools       []Tool      `json:"tools,omitempty"`
+	ToolChoice  *ToolChoice `json:"tool_choice,omitempty"`
+	// 响应格式，json_object 时模型只输出 JSON
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+}
+
+// 响应格式类型
+const (
+	ResponseFormatText = "text"
+	ResponseFormatJSON = "json_object"
+)
+
+// ResponseFormat 指定模型输出格式
+type ResponseFormat struct {
+	Type string `json:"type"`
 }
 
 type Thinking struct {
@@ -33,6 +46,30 @@ type ChatResponse struct {
 	Created int64    `json:"created"`
 	Model   string   `json:"model"`
 	Choices []Choice `json:"choices"`
+	Usage   *Usage   `json:"usage,omitempty"`
+}
+
+// Usage 请求消耗的 token 数，流式响应通常在最后一个数据块中返回
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+	// 命中提示缓存的输入 token：OpenAI 兼容接口放在 prompt_tokens_details 中，DeepSeek 使用 prompt_cache_hit_tokens
+	PromptTokensDetails  *PromptTokensDetails `json:"prompt_tokens_details,omitempty"`
+	PromptCacheHitTokens int                  `json:"prompt_cache_hit_tokens,omitempty"`
+}
+
+// PromptTokensDetails 输入 token 的明细
+type PromptTokensDetails struct {
+	CachedTokens int `json:"cached_tokens"`
+}
+
+// CachedTokens 返回命中提示缓存的输入 token 数，服务商没有报告时返回 0
+func (u *Usage) CachedTokens() int {
+	if u.PromptTokensDetails != nil && u.PromptTokensDetails.CachedTokens > 0 {
+		return u.PromptTokensDetails.CachedTokens
+	}
+	return u.PromptCacheHitTokens
 }
 
 type Choice struct {
@@ -56,6 +93,7 @@ type StreamChunk struct {
 	Created int64    `json:"created"`
 	Model   string   `json:"model"`
 	Choices []Choice `json:"choices"`
+	Usage   *Usage   `json:"usage,omitempty"`
 }
 
 // 工具相关类型
@@ -64,6 +102,56 @@ type Tool struct {
 	Function ToolFunction `json:"function"`
 }
 
+// 工具调用策略
+const (
+	ToolChoiceAuto     = "auto"     // 由模型决定是否调用工具
+	ToolChoiceNone     = "none"     // 禁止调用工具
+	ToolChoiceRequired = "required" // 必须调用至少一个工具
+)
+
+// ToolChoice 控制模型的工具调用行为
+// Function 非空时强制调用该工具，否则按 Mode 处理（为空视为 auto）
+type ToolChoice struct {
+	Mode     string
+	Function string
+}
+
+// AutoToolChoice 由模型自行决定是否调用工具
+func AutoToolChoice() *ToolChoice {
+	return &ToolChoice{Mode: ToolChoiceAuto}
+}
+
+// NoneToolChoice 禁止模型调用工具
+func NoneToolChoice() *ToolChoice {
+	return &ToolChoice{Mode: ToolChoiceNone}
+}
+
+// RequiredToolChoice 要求模型至少调用一个工具
+func RequiredToolChoice() *ToolChoice {
+	return &ToolChoice{Mode: ToolChoiceRequired}
+}
+
+// NamedToolChoice 强制模型调用指定名称的工具
+func NamedToolChoice(name string) *ToolChoice {
+	return &ToolChoice{Function: name}
+}
+
+// MarshalJSON 序列化为 "auto"/"none"/"required" 或 {"type":"function","function":{"name":...}}
+func (c ToolChoice) MarshalJSON() ([]byte, error) {
+	if c.Function != "" {
+		return json.Marshal(map[string]interface{}{
+			"type": "function",
+			"function": map[string]string{
+				"name": c.Function,
+			},
+		})
+	}
+	if c.Mode == "" {
+		return json.Marshal(ToolChoiceAuto)
+	}
+	return json.Marshal(c.Mode)
+}
+
 type ToolFunction struct {
 	Name        string                 `json:"name"`
 	Description string                 `json:"description,omitempty"`
@@ -106,6 +194,16 @@ func ToolCallMessage(toolCalls []ToolCall) Message {
 	}
 }
 
+// AssistantToolCallMessage 创建带有文本内容的工具调用消息，文本为空时与 ToolCallMessage 相同
+func AssistantToolCallMessage(content string, toolCalls []ToolCall) Message {
+	if content == "" {
+		return ToolCallMessage(toolCalls)
+	}
+	msg := TextMessage("assistant", content)
+	msg.ToolCalls = toolCalls
+	return msg
+}
+
 // 创建工具结果消息
 func ToolResultMessage(toolCallID string, result interface{}) Message {
 	resultBytes, _ := json.Marshal(result)
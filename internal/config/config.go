@@ -4,16 +4,166 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/Zacy-Sokach/PolyAgent/internal/utils"
 	"gopkg.in/yaml.v3"
 )
 
+// DefaultLanguage 默认界面语言
+const DefaultLanguage = "zh"
+
 type Config struct {
-	APIKey       string           `yaml:"api_key"`
-	Model        string           `yaml:"model"`
-	TavilyAPIKey string           `yaml:"tavily_api_key"`
-	FileEngine   FileEngineConfig `yaml:"file_engine"`
+	APIKey       string             `yaml:"api_key"`
+	Model        string             `yaml:"model"`
+	TavilyAPIKey string             `yaml:"tavily_api_key"`
+	FileEngine   FileEngineConfig   `yaml:"file_engine"`
+	Notification NotificationConfig `yaml:"notification"`
+	WebPolicy    WebPolicyConfig    `yaml:"web_policy"`
+	// 按任务类型使用的模型，标题、总结等轻量任务可以使用更便宜、更快的模型
+	ModelRouting ModelRoutingConfig `yaml:"model_routing"`
+	// 兼容 OpenAI 接口的模型服务地址，留空时使用智谱 GLM（本地模型如 Ollama 为 http://localhost:11434/v1）
+	BaseURL string `yaml:"base_url"`
+	// 模拟工具调用：在系统提示中描述工具，从回复的 tool_call 代码块中解析调用，用于不支持原生函数调用的本地模型
+	ToolEmulation bool `yaml:"tool_emulation"`
+	// 界面语言（zh/en）
+	Language string `yaml:"language"`
+	// 要求模型使用的回答语言，留空时跟随界面语言
+	ResponseLanguage string `yaml:"response_language"`
+	// 离线模式：禁用联网工具和更新检查
+	Offline bool `yaml:"offline"`
+	// 匿名使用统计（只记录命令、工具和错误类别的计数），默认关闭，可用 /telemetry on 开启
+	Telemetry bool `yaml:"telemetry"`
+	// 详细模式：展开工具调用的参数和结果，完整显示推理过程，可用 /verbose 切换
+	Verbose bool `yaml:"verbose"`
+	// 命令执行审批规则
+	Shell ShellConfig `yaml:"shell"`
+	// 已信任的工作区目录，未信任的目录只开放只读工具
+	TrustedWorkspaces []string `yaml:"trusted_workspaces"`
+	// 会话自动保存间隔（秒），0 表示默认 30 秒，负数表示禁用
+	AutosaveInterval int `yaml:"autosave_interval"`
+	// 流式响应空闲超时（秒），超时未收到数据时中止并重试，0 表示默认值
+	StreamIdleTimeout int `yaml:"stream_idle_timeout"`
+	// 连接、响应头和非流式请求的超时，流式请求不受整体超时限制
+	Timeouts TimeoutConfig `yaml:"timeouts"`
+	// 启动后不检查 API 是否可用（检查会发送一个只生成 1 个 token 的请求）
+	SkipHealthCheck bool `yaml:"skip_health_check"`
+	// 出站网络配置（代理和额外 CA 证书）
+	Network NetworkConfig `yaml:"network"`
+	// 按服务商配置的客户端限流（键为服务商，如 glm）
+	RateLimits map[string]RateLimitConfig `yaml:"rate_limits"`
+	// API Key 的保存位置：keyring（默认，系统密钥环）或 file（明文保存在配置文件中）
+	// 密钥环不可用时（如没有 Secret Service 的服务器）自动保存在配置文件中
+	SecretStorage string `yaml:"secret_storage,omitempty"`
+	// 每个会话的写入预算，超出后修改类工具失败，直到用户用 /quota 提高上限
+	WriteQuota WriteQuotaConfig `yaml:"write_quota"`
+	// 递归删除目录前的确认阈值
+	Delete DeleteConfig `yaml:"delete"`
+	// 发送给模型的对话历史上限，界面上的记录不受影响
+	History HistoryConfig `yaml:"history"`
+	// 重复的只读工具调用（相同的工具和参数）返回已有结果的引用而不是再次执行：
+	// turn（默认）只在同一轮对话内去重，session 在整个会话内去重，off 关闭；修改类工具执行后之前的结果不再复用
+	ToolResultCache string `yaml:"tool_result_cache"`
+	// 按模型名配置的上下文窗口（token），覆盖内置的默认值；发送前估算请求超出窗口时先自动裁剪历史，仍超出时不发送
+	ContextWindows map[string]int `yaml:"context_windows"`
+	// 费用上限，接近时在状态栏提醒，超出后每次发送消息都需要确认
+	Budget BudgetConfig `yaml:"budget"`
+}
+
+// ModelRoutingConfig 按任务类型选择模型，留空的任务使用主模型（model 或命令行 --model）
+type ModelRoutingConfig struct {
+	// 生成会话标题
+	Title string `yaml:"title"`
+	// 总结对话（/new keep-context 和上下文过长时总结较早的对话）
+	Summary string `yaml:"summary"`
+	// 工具执行完成后继续生成的请求，多步工具调用的任务中占大多数
+	ToolSteps string `yaml:"tool_steps"`
+}
+
+// tool_result_cache 的取值
+const (
+	ToolResultCacheTurn    = "turn"
+	ToolResultCacheSession = "session"
+	ToolResultCacheOff     = "off"
+)
+
+// TimeoutConfig 模型 API 请求的超时（秒），0 表示默认值（连接 10 秒、响应头 60 秒、非流式请求 120 秒）。
+// 流式请求只受连接、响应头和 stream_idle_timeout 限制，生成时间再长也不会被中止
+type TimeoutConfig struct {
+	// 建立连接（包括 TLS 握手）
+	Connect int `yaml:"connect"`
+	// 流式请求发出后等待响应头
+	ResponseHeader int `yaml:"response_header"`
+	// 非流式请求（生成标题、总结等）的整体时间
+	Request int `yaml:"request"`
+}
+
+// BudgetConfig 按 token 用量估算费用。价格按服务商的报价填写（每百万 token），
+// 上限使用与价格相同的货币单位；价格都为 0 时无法估算费用，上限不生效，上限为 0 表示不限制
+type BudgetConfig struct {
+	InputPrice   float64 `yaml:"input_price"`
+	OutputPrice  float64 `yaml:"output_price"`
+	SessionLimit float64 `yaml:"session_limit"`
+	DailyLimit   float64 `yaml:"daily_limit"`
+	// 达到上限的百分之多少时开始提醒，0 表示默认 80
+	WarnPercent int `yaml:"warn_percent"`
+}
+
+// HistoryConfig 每次请求发送给模型的历史上限，超出时从最早的非系统消息开始裁剪
+// 0 表示默认值（50 条消息、64000 token），负数表示该项不限制
+type HistoryConfig struct {
+	MaxMessages int `yaml:"max_messages"`
+	MaxTokens   int `yaml:"max_tokens"`
+}
+
+// DeleteConfig 递归删除超过阈值或包含重要文件（如 .git、.env）时需要用户确认
+// 0 表示默认值（100 个文件、10MB），负数表示该项不限制
+type DeleteConfig struct {
+	ConfirmFiles int `yaml:"confirm_files"`
+	ConfirmMB    int `yaml:"confirm_mb"`
+}
+
+// WriteQuotaConfig 会话写入预算，0 表示默认值（20MB、500 个文件），负数表示不限制
+type WriteQuotaConfig struct {
+	MaxMB    int `yaml:"max_mb"`
+	MaxFiles int `yaml:"max_files"`
+}
+
+// NetworkConfig 出站网络配置，作用于 API、联网工具和更新检查
+type NetworkConfig struct {
+	// 代理地址（http/https/socks5），留空时使用 HTTP_PROXY/HTTPS_PROXY/NO_PROXY 环境变量
+	Proxy string `yaml:"proxy"`
+	// 额外信任的 CA 证书文件（PEM），追加到系统证书池
+	CAFiles []string `yaml:"ca_files"`
+	// 以下只作用于模型 API 的连接
+	// 只使用 HTTP/1.1，部分代理在 HTTP/2 下会缓冲或截断流式响应
+	ForceHTTP1 bool `yaml:"force_http1"`
+	// 每个主机的最大连接数，0 表示默认 100
+	MaxConnsPerHost int `yaml:"max_conns_per_host"`
+	// 每个主机保留的空闲连接数，0 表示默认 50
+	MaxIdleConnsPerHost int `yaml:"max_idle_conns_per_host"`
+	// 空闲连接保留的秒数，0 表示默认 90
+	IdleConnTimeout int `yaml:"idle_conn_timeout"`
+	// TCP keep-alive 探测间隔（秒），0 表示默认 30，负数关闭
+	KeepAlive int `yaml:"keep_alive"`
+}
+
+// ShellConfig 命令执行审批规则，按命令前缀匹配（如 "go test" 匹配 "go test ./..."）
+// 组合命令的每一段都命中自动批准且没有命中始终询问时才自动执行，其余情况需要用户确认
+type ShellConfig struct {
+	AutoApprove []string `yaml:"auto_approve"`
+	AlwaysAsk   []string `yaml:"always_ask"`
+	// 每个输出流（stdout/stderr）最多保留的字节数，超出时保留开头和结尾，0 表示默认 32KB
+	MaxOutputBytes int `yaml:"max_output_bytes"`
+	// 传递给命令的环境变量名（支持 * 通配，如 "GO*"），为空时只传递不含凭据的最小集合
+	// 通配匹配到的名称含 KEY/TOKEN/SECRET 等的变量总是被过滤，需要时请写出完整变量名
+	PassEnv []string `yaml:"pass_env"`
+}
+
+// RateLimitConfig 单个服务商的客户端限流，0 表示不限制
+type RateLimitConfig struct {
+	RequestsPerMinute int `yaml:"requests_per_minute"`
+	TokensPerMinute   int `yaml:"tokens_per_minute"`
 }
 
 type FileEngineConfig struct {
@@ -25,6 +175,30 @@ type FileEngineConfig struct {
 	CacheTTLMinutes int      `yaml:"cache_ttl_minutes"`
 }
 
+// NotificationConfig 长任务完成时的终端提醒配置
+type NotificationConfig struct {
+	// 完成时发送终端响铃（BEL）
+	Bell bool `yaml:"bell"`
+	// 完成时发送 OSC 777 桌面通知
+	Desktop bool `yaml:"desktop"`
+	// 终端处于焦点时也发送提醒（默认仅在切走窗口时提醒）
+	NotifyWhenFocused bool `yaml:"notify_when_focused"`
+	// 响应耗时超过该秒数才提醒，0 表示使用默认值
+	MinDurationSeconds int `yaml:"min_duration_seconds"`
+}
+
+// WebPolicyConfig 联网工具（web_search/web_crawl/web_extract）的出站访问策略
+type WebPolicyConfig struct {
+	// 域名白名单，非空时只允许访问列表中的域名及其子域名
+	AllowedDomains []string `yaml:"allowed_domains"`
+	// 域名黑名单，优先级高于白名单
+	DeniedDomains []string `yaml:"denied_domains"`
+	// 允许访问内网、回环等私有地址（默认拒绝，防止 SSRF）
+	AllowPrivateNetworks bool `yaml:"allow_private_networks"`
+	// 爬取时忽略 robots.txt（默认遵守）
+	IgnoreRobots bool `yaml:"ignore_robots"`
+}
+
 func LoadConfig() (*Config, error) {
 	configPath, err := getConfigPath()
 	if err != nil {
@@ -35,6 +209,7 @@ func LoadConfig() (*Config, error) {
 		return &Config{
 			Model:      "glm-4.5",
 			FileEngine: DefaultFileEngineConfig(),
+			Language:   DefaultLanguage,
 		}, nil
 	}
 
@@ -52,11 +227,22 @@ func LoadConfig() (*Config, error) {
 		config.Model = "glm-4.5"
 	}
 
+	if config.Language == "" {
+		config.Language = DefaultLanguage
+	}
+
 	// 设置 FileEngine 默认值
 	if config.FileEngine.MaxFileSize == 0 {
 		config.FileEngine = DefaultFileEngineConfig()
 	}
 
+	// 从密钥环读取 API Key；旧配置文件中的明文 Key 迁移到密钥环后从文件中移除
+	if config.usesKeyring() && loadSecrets(&config) {
+		if err := SaveConfig(&config); err != nil {
+			return nil, fmt.Errorf("迁移 API Key 到系统密钥环失败: %w", err)
+		}
+	}
+
 	return &config, nil
 }
 
@@ -83,7 +269,13 @@ func SaveConfig(config *Config) error {
 		return fmt.Errorf("创建配置目录失败: %w", err)
 	}
 
-	data, err := yaml.Marshal(config)
+	// 使用密钥环时配置文件中不保存 API Key
+	fileConfig := *config
+	if fileConfig.usesKeyring() {
+		stripSecrets(&fileConfig)
+	}
+
+	data, err := yaml.Marshal(&fileConfig)
 	if err != nil {
 		return fmt.Errorf("序列化配置失败: %w", err)
 	}
@@ -95,6 +287,22 @@ func SaveConfig(config *Config) error {
 	return nil
 }
 
+// ConfigModTime 返回配置文件的修改时间，用于检测运行期间的修改；文件不存在时返回零值
+func ConfigModTime() (time.Time, error) {
+	configPath, err := getConfigPath()
+	if err != nil {
+		return time.Time{}, err
+	}
+	info, err := os.Stat(configPath)
+	if os.IsNotExist(err) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("读取配置文件信息失败: %w", err)
+	}
+	return info.ModTime(), nil
+}
+
 // GetTavilyAPIKey 获取 Tavily API Key
 func GetTavilyAPIKey() (string, error) {
 	config, err := LoadConfig()
@@ -104,6 +312,15 @@ func GetTavilyAPIKey() (string, error) {
 	return config.TavilyAPIKey, nil
 }
 
+// GetWebPolicy 获取联网工具的访问策略
+func GetWebPolicy() (WebPolicyConfig, error) {
+	config, err := LoadConfig()
+	if err != nil {
+		return WebPolicyConfig{}, err
+	}
+	return config.WebPolicy, nil
+}
+
 // SaveTavilyAPIKey 保存 Tavily API Key
 func SaveTavilyAPIKey(key string) error {
 	config, err := LoadConfig()
@@ -114,7 +331,23 @@ func SaveTavilyAPIKey(key string) error {
 	return SaveConfig(config)
 }
 
+// ConfigPath 返回配置文件路径
+func ConfigPath() (string, error) {
+	return getConfigPath()
+}
+
+// configPathOverride 命令行 --config 指定的配置文件路径
+var configPathOverride string
+
+// SetConfigPath 使用指定的配置文件代替默认的 config.yaml，空字符串恢复默认
+func SetConfigPath(path string) {
+	configPathOverride = path
+}
+
 func getConfigPath() (string, error) {
+	if configPathOverride != "" {
+		return filepath.Abs(configPathOverride)
+	}
 	configDir, err := utils.GetConfigDir()
 	if err != nil {
 		return "", fmt.Errorf("获取配置目录失败: %w", err)
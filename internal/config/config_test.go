@@ -139,4 +139,50 @@ func TestLoadInvalidConfig(t *testing.T) {
 	if err == nil {
 		t.Error("Expected error for invalid YAML")
 	}
-}
\ No newline at end of file
+}
+func TestConfigModTime(t *testing.T) {
+	tmpDir := t.TempDir()
+	originalHome := os.Getenv("HOME")
+	os.Setenv("HOME", tmpDir)
+	defer os.Setenv("HOME", originalHome)
+
+	modTime, err := ConfigModTime()
+	if err != nil {
+		t.Fatalf("ConfigModTime failed: %v", err)
+	}
+	if !modTime.IsZero() {
+		t.Errorf("expected zero time without a config file, got %v", modTime)
+	}
+
+	if err := SaveConfig(&Config{APIKey: "key"}); err != nil {
+		t.Fatalf("SaveConfig failed: %v", err)
+	}
+	modTime, err = ConfigModTime()
+	if err != nil {
+		t.Fatalf("ConfigModTime failed: %v", err)
+	}
+	if modTime.IsZero() {
+		t.Error("expected modification time after saving the config")
+	}
+}
+
+func TestSetConfigPath(t *testing.T) {
+	useTempHome(t)
+	custom := filepath.Join(t.TempDir(), "custom.yaml")
+	SetConfigPath(custom)
+	defer SetConfigPath("")
+
+	if err := SaveConfig(&Config{Model: "glm-4.6", SecretStorage: SecretStorageFile}); err != nil {
+		t.Fatalf("SaveConfig failed: %v", err)
+	}
+	if _, err := os.Stat(custom); err != nil {
+		t.Fatalf("expected config at %s: %v", custom, err)
+	}
+	loaded, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if loaded.Model != "glm-4.6" {
+		t.Errorf("Model = %q, want glm-4.6", loaded.Model)
+	}
+}
@@ -0,0 +1,303 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// KeyValue 一个配置项及其取值的文本形式
+type KeyValue struct {
+	Key   string
+	Value string
+}
+
+// GetValue 按点分路径（如 file_engine.max_file_size）读取配置项，返回取值的文本形式
+// 结构体、列表和映射以 YAML 形式返回
+func GetValue(c *Config, key string) (string, error) {
+	v, canonical, err := lookup(reflect.ValueOf(c).Elem(), splitKey(key), "")
+	if err != nil {
+		return "", err
+	}
+	if !v.IsValid() {
+		return "", fmt.Errorf("配置项 %s 未设置", canonical)
+	}
+	return formatValue(v)
+}
+
+// SetValue 按点分路径设置配置项，取值按字段类型解析：列表可写为 YAML 列表或逗号分隔，结构体和映射写为 YAML
+func SetValue(c *Config, key, value string) error {
+	parts := splitKey(key)
+	if len(parts) == 0 {
+		return fmt.Errorf("无效的配置项: %q", key)
+	}
+	return assign(reflect.ValueOf(c).Elem(), parts, "", value)
+}
+
+// ListValues 列出所有已设置的叶子配置项，API Key 以掩码显示
+func ListValues(c *Config) []KeyValue {
+	var values []KeyValue
+	flatten(reflect.ValueOf(c).Elem(), "", &values)
+	for i, kv := range values {
+		if IsSecretKey(kv.Key) {
+			values[i].Value = MaskSecret(kv.Value)
+		}
+	}
+	return values
+}
+
+// Keys 返回所有配置项的点分路径（列表和映射作为一个整体），用于命令行补全
+func Keys() []string {
+	var keys []string
+	collectKeys(reflect.TypeOf(Config{}), "", &keys)
+	return keys
+}
+
+func collectKeys(t reflect.Type, prefix string, out *[]string) {
+	if t.Kind() != reflect.Struct {
+		*out = append(*out, prefix)
+		return
+	}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if tag == "-" || !field.IsExported() {
+			continue
+		}
+		if tag == "" {
+			tag = strings.ToLower(field.Name)
+		}
+		collectKeys(field.Type, joinKey(prefix, tag), out)
+	}
+}
+
+// MaskSecret 只保留密钥首尾各 4 个字符
+func MaskSecret(value string) string {
+	if len(value) <= 8 {
+		return "***"
+	}
+	return value[:4] + "***" + value[len(value)-4:]
+}
+
+// IsSecretKey 报告配置项是否为 API Key（名称写法同 GetValue）
+func IsSecretKey(key string) bool {
+	for _, secret := range secretFields {
+		if normalizeKeyName(secret.name) == normalizeKeyName(key) {
+			return true
+		}
+	}
+	return false
+}
+
+func splitKey(key string) []string {
+	var parts []string
+	for _, part := range strings.Split(strings.TrimSpace(key), ".") {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return parts
+}
+
+// normalizeKeyName 比较配置项名称时忽略大小写、下划线和连字符，fileengine 与 file_engine 等价
+func normalizeKeyName(name string) string {
+	name = strings.ToLower(name)
+	return strings.NewReplacer("_", "", "-", "").Replace(name)
+}
+
+// structField 查找名称对应的字段，返回字段下标和规范的 yaml 名称
+func structField(t reflect.Type, name string) (int, string, bool) {
+	want := normalizeKeyName(name)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if tag == "-" || !field.IsExported() {
+			continue
+		}
+		if tag == "" {
+			tag = strings.ToLower(field.Name)
+		}
+		if normalizeKeyName(tag) == want {
+			return i, tag, true
+		}
+	}
+	return 0, "", false
+}
+
+// lookup 沿路径查找取值，映射中不存在的键返回无效的 Value
+func lookup(v reflect.Value, parts []string, prefix string) (reflect.Value, string, error) {
+	if len(parts) == 0 {
+		return v, prefix, nil
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		i, name, ok := structField(v.Type(), parts[0])
+		if !ok {
+			return reflect.Value{}, "", unknownKeyError(v.Type(), joinKey(prefix, parts[0]))
+		}
+		return lookup(v.Field(i), parts[1:], joinKey(prefix, name))
+	case reflect.Map:
+		elem := v.MapIndex(reflect.ValueOf(parts[0]))
+		if !elem.IsValid() {
+			return reflect.Value{}, joinKey(prefix, parts[0]), nil
+		}
+		return lookup(elem, parts[1:], joinKey(prefix, parts[0]))
+	default:
+		return reflect.Value{}, "", fmt.Errorf("配置项 %s 没有子项 %s", prefix, parts[0])
+	}
+}
+
+// assign 沿路径设置取值，映射中不存在的键会被创建
+func assign(v reflect.Value, parts []string, prefix, value string) error {
+	if len(parts) == 0 {
+		return parseInto(v, prefix, value)
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		i, name, ok := structField(v.Type(), parts[0])
+		if !ok {
+			return unknownKeyError(v.Type(), joinKey(prefix, parts[0]))
+		}
+		return assign(v.Field(i), parts[1:], joinKey(prefix, name), value)
+	case reflect.Map:
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		// 映射的元素不可寻址，修改副本后写回
+		key := reflect.ValueOf(parts[0])
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if existing := v.MapIndex(key); existing.IsValid() {
+			elem.Set(existing)
+		}
+		if err := assign(elem, parts[1:], joinKey(prefix, parts[0]), value); err != nil {
+			return err
+		}
+		v.SetMapIndex(key, elem)
+		return nil
+	default:
+		return fmt.Errorf("配置项 %s 没有子项 %s", prefix, parts[0])
+	}
+}
+
+// parseInto 按字段类型解析文本取值
+func parseInto(v reflect.Value, key, value string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+		return nil
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("配置项 %s 应为 true 或 false: %q", key, value)
+		}
+		v.SetBool(b)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || v.OverflowInt(n) {
+			return fmt.Errorf("配置项 %s 应为整数: %q", key, value)
+		}
+		v.SetInt(n)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil || v.OverflowFloat(f) {
+			return fmt.Errorf("配置项 %s 应为数字: %q", key, value)
+		}
+		v.SetFloat(f)
+		return nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.String && !strings.HasPrefix(strings.TrimSpace(value), "[") {
+			// 逗号分隔的字符串列表，空字符串表示清空
+			items := reflect.MakeSlice(v.Type(), 0, 0)
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = reflect.Append(items, reflect.ValueOf(item))
+				}
+			}
+			v.Set(items)
+			return nil
+		}
+	}
+
+	target := reflect.New(v.Type())
+	if err := yaml.Unmarshal([]byte(value), target.Interface()); err != nil {
+		return fmt.Errorf("配置项 %s 的取值无效: %w", key, err)
+	}
+	v.Set(target.Elem())
+	return nil
+}
+
+// formatValue 将取值格式化为文本：标量直接输出，列表以 YAML 行内形式输出，结构体和映射以 YAML 输出
+func formatValue(v reflect.Value) (string, error) {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Float32, reflect.Float64:
+		return fmt.Sprint(v.Interface()), nil
+	}
+
+	var node yaml.Node
+	if err := node.Encode(v.Interface()); err != nil {
+		return "", fmt.Errorf("序列化配置失败: %w", err)
+	}
+	if v.Kind() == reflect.Slice {
+		node.Style = yaml.FlowStyle
+	}
+	data, err := yaml.Marshal(&node)
+	if err != nil {
+		return "", fmt.Errorf("序列化配置失败: %w", err)
+	}
+	return strings.TrimRight(string(data), "\n"), nil
+}
+
+// flatten 展开为叶子配置项，跳过空值；列表以 YAML 行内形式输出
+func flatten(v reflect.Value, prefix string, out *[]KeyValue) {
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			tag := strings.Split(field.Tag.Get("yaml"), ",")[0]
+			if tag == "-" || !field.IsExported() {
+				continue
+			}
+			if tag == "" {
+				tag = strings.ToLower(field.Name)
+			}
+			flatten(v.Field(i), joinKey(prefix, tag), out)
+		}
+	case reflect.Map:
+		keys := make([]string, 0, v.Len())
+		for _, key := range v.MapKeys() {
+			keys = append(keys, key.String())
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			flatten(v.MapIndex(reflect.ValueOf(key)), joinKey(prefix, key), out)
+		}
+	default:
+		if v.IsZero() || (v.Kind() == reflect.Slice && v.Len() == 0) {
+			return
+		}
+		value, err := formatValue(v)
+		if err != nil {
+			return
+		}
+		*out = append(*out, KeyValue{Key: prefix, Value: value})
+	}
+}
+
+// unknownKeyError 未知配置项的错误，附带拼写相近的配置项
+func unknownKeyError(t reflect.Type, key string) error {
+	parts := strings.Split(key, ".")
+	if suggestion := closestKey(parts[len(parts)-1], yamlFields(t)); suggestion != "" {
+		parts[len(parts)-1] = suggestion
+		return fmt.Errorf("未知的配置项 %s（是否为 %s？）", key, strings.Join(parts, "."))
+	}
+	return fmt.Errorf("未知的配置项 %s", key)
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSetAndGetValue(t *testing.T) {
+	c := &Config{}
+	tests := []struct {
+		key, value, get, want string
+	}{
+		{"model", "glm-4.6", "model", "glm-4.6"},
+		{"fileengine.max_file_size", "2048", "file_engine.max_file_size", "2048"},
+		{"offline", "true", "offline", "true"},
+		{"shell.auto_approve", "go test, go build", "shell.auto_approve", "[go test, go build]"},
+		{"file_engine.blacklisted_exts", `[".exe", ".dll"]`, "file_engine.blacklisted_exts", "[.exe, .dll]"},
+		{"rate_limits.glm.requests_per_minute", "60", "rate_limits.glm.requests_per_minute", "60"},
+		{"write_quota", "{max_mb: 5, max_files: 10}", "write_quota.max_files", "10"},
+	}
+	for _, tt := range tests {
+		if err := SetValue(c, tt.key, tt.value); err != nil {
+			t.Fatalf("SetValue(%s) failed: %v", tt.key, err)
+		}
+		got, err := GetValue(c, tt.get)
+		if err != nil {
+			t.Fatalf("GetValue(%s) failed: %v", tt.get, err)
+		}
+		if got != tt.want {
+			t.Errorf("GetValue(%s) = %q, want %q", tt.get, got, tt.want)
+		}
+	}
+	if c.RateLimits["glm"].RequestsPerMinute != 60 || c.WriteQuota.MaxMB != 5 {
+		t.Errorf("config = %+v", c)
+	}
+}
+
+func TestSetValueErrors(t *testing.T) {
+	c := &Config{}
+	tests := []struct {
+		key, value, message string
+	}{
+		{"ofline", "true", "offline"},
+		{"offline", "maybe", "true 或 false"},
+		{"file_engine.max_file_size", "big", "整数"},
+		{"model.name", "x", "没有子项"},
+	}
+	for _, tt := range tests {
+		err := SetValue(c, tt.key, tt.value)
+		if err == nil || !strings.Contains(err.Error(), tt.message) {
+			t.Errorf("SetValue(%s, %s) error = %v, want containing %q", tt.key, tt.value, err, tt.message)
+		}
+	}
+	if _, err := GetValue(c, "rate_limits.missing"); err == nil {
+		t.Error("expected error for unset map key")
+	}
+}
+
+func TestListValuesMasksSecrets(t *testing.T) {
+	c := &Config{APIKey: "abcd1234efgh5678", Model: "glm-4.5"}
+	c.Shell.PassEnv = []string{"PATH", "GO*"}
+	values := ListValues(c)
+
+	got := make(map[string]string)
+	for _, kv := range values {
+		got[kv.Key] = kv.Value
+	}
+	if got["api_key"] != "abcd***5678" {
+		t.Errorf("api_key = %q", got["api_key"])
+	}
+	if got["shell.pass_env"] != "[PATH, GO*]" {
+		t.Errorf("shell.pass_env = %q", got["shell.pass_env"])
+	}
+	if _, ok := got["offline"]; ok {
+		t.Error("zero values should be skipped")
+	}
+}
+
+func TestKeysResolve(t *testing.T) {
+	keys := Keys()
+	if len(keys) == 0 {
+		t.Fatal("Keys() returned nothing")
+	}
+	c := &Config{}
+	for _, key := range keys {
+		if _, _, err := lookup(reflect.ValueOf(c).Elem(), splitKey(key), ""); err != nil {
+			t.Errorf("key %s does not resolve: %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/zalando/go-keyring"
+)
+
+const (
+	// keyringService 系统密钥环中的服务名
+	keyringService = "polyagent"
+
+	// SecretStorageKeyring API Key 保存在系统密钥环（macOS 钥匙串、Windows 凭据管理器、Secret Service）
+	SecretStorageKeyring = "keyring"
+	// SecretStorageFile API Key 以明文保存在配置文件中
+	SecretStorageFile = "file"
+)
+
+// secretFields 保存在密钥环中的配置项，name 同时作为密钥环中的账户名
+var secretFields = []struct {
+	name  string
+	field func(*Config) *string
+}{
+	{"api_key", func(c *Config) *string { return &c.APIKey }},
+	{"tavily_api_key", func(c *Config) *string { return &c.TavilyAPIKey }},
+}
+
+var (
+	secretMu sync.Mutex
+	// secretCache 已读取或写入的密钥，避免每次加载配置都访问密钥环
+	secretCache = make(map[string]string)
+	// keyringErr 密钥环不可用时的错误，之后不再重复尝试
+	keyringErr error
+)
+
+// usesKeyring 报告 API Key 是否应保存在系统密钥环中
+func (c *Config) usesKeyring() bool {
+	return c.SecretStorage != SecretStorageFile
+}
+
+// getSecret 从密钥环读取密钥，不存在时返回空字符串
+func getSecret(name string) (string, error) {
+	secretMu.Lock()
+	defer secretMu.Unlock()
+	if value, ok := secretCache[name]; ok {
+		return value, nil
+	}
+	if keyringErr != nil {
+		return "", keyringErr
+	}
+
+	value, err := keyring.Get(keyringService, name)
+	if errors.Is(err, keyring.ErrNotFound) {
+		value, err = "", nil
+	}
+	if err != nil {
+		keyringErr = err
+		return "", err
+	}
+	secretCache[name] = value
+	return value, nil
+}
+
+// setSecret 将密钥写入密钥环
+func setSecret(name, value string) error {
+	secretMu.Lock()
+	defer secretMu.Unlock()
+	if cached, ok := secretCache[name]; ok && cached == value {
+		return nil
+	}
+	if keyringErr != nil {
+		return keyringErr
+	}
+	if err := keyring.Set(keyringService, name, value); err != nil {
+		keyringErr = err
+		return err
+	}
+	secretCache[name] = value
+	return nil
+}
+
+// loadSecrets 用密钥环中的密钥填充配置中为空的 API Key
+// 配置文件中仍有明文 Key 时将其迁移到密钥环，返回是否有 Key 迁移成功（需要重写配置文件）
+// 密钥环不可用时保持原样，Key 继续保存在配置文件中
+func loadSecrets(c *Config) (migrated bool) {
+	for _, secret := range secretFields {
+		field := secret.field(c)
+		if *field != "" {
+			if setSecret(secret.name, *field) == nil {
+				migrated = true
+			}
+			continue
+		}
+		if value, err := getSecret(secret.name); err == nil {
+			*field = value
+		}
+	}
+	return migrated
+}
+
+// stripSecrets 将 API Key 写入密钥环，并从要写入配置文件的副本中移除写入成功的 Key
+// 空值不会删除密钥环中已有的 Key
+func stripSecrets(c *Config) {
+	for _, secret := range secretFields {
+		field := secret.field(c)
+		if *field != "" && setSecret(secret.name, *field) == nil {
+			*field = ""
+		}
+	}
+}
+
+// KeyringStatus 检查系统密钥环是否可用，不可用时返回原因
+func KeyringStatus() error {
+	_, err := getSecret("api_key")
+	return err
+}
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/zalando/go-keyring"
+)
+
+func TestMain(m *testing.M) {
+	// 测试不访问真实的系统密钥环
+	keyring.MockInit()
+	os.Exit(m.Run())
+}
+
+// useTempHome 将配置目录指向临时目录，并清空密钥缓存和模拟密钥环
+func useTempHome(t *testing.T) string {
+	t.Helper()
+	tmpDir := t.TempDir()
+	t.Setenv("HOME", tmpDir)
+	keyring.MockInit()
+	secretMu.Lock()
+	secretCache = make(map[string]string)
+	keyringErr = nil
+	secretMu.Unlock()
+	return tmpDir
+}
+
+func readConfigFile(t *testing.T) string {
+	t.Helper()
+	path, err := getConfigPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestSaveConfigStoresKeysInKeyring(t *testing.T) {
+	useTempHome(t)
+
+	if err := SaveConfig(&Config{APIKey: "glm-secret", TavilyAPIKey: "tvly-secret", Model: "glm-4.5"}); err != nil {
+		t.Fatalf("SaveConfig failed: %v", err)
+	}
+	if content := readConfigFile(t); strings.Contains(content, "secret") {
+		t.Errorf("config file contains plaintext keys:\n%s", content)
+	}
+	if value, err := keyring.Get(keyringService, "api_key"); err != nil || value != "glm-secret" {
+		t.Errorf("keyring api_key = %q, %v", value, err)
+	}
+
+	loaded, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if loaded.APIKey != "glm-secret" || loaded.TavilyAPIKey != "tvly-secret" {
+		t.Errorf("loaded keys = %q, %q", loaded.APIKey, loaded.TavilyAPIKey)
+	}
+}
+
+func TestLoadConfigMigratesPlaintextKeys(t *testing.T) {
+	useTempHome(t)
+
+	// 旧版本写入的配置文件包含明文 Key
+	if err := SaveConfig(&Config{SecretStorage: SecretStorageFile, APIKey: "old-key", Model: "glm-4.5"}); err != nil {
+		t.Fatal(err)
+	}
+	path, _ := getConfigPath()
+	content := strings.Replace(readConfigFile(t), "secret_storage: file\n", "", 1)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if loaded.APIKey != "old-key" {
+		t.Errorf("APIKey = %q, want old-key", loaded.APIKey)
+	}
+	if strings.Contains(readConfigFile(t), "old-key") {
+		t.Error("plaintext key was not removed from the config file")
+	}
+	if value, _ := keyring.Get(keyringService, "api_key"); value != "old-key" {
+		t.Errorf("keyring api_key = %q, want old-key", value)
+	}
+}
+
+func TestFileSecretStorage(t *testing.T) {
+	useTempHome(t)
+
+	if err := SaveConfig(&Config{SecretStorage: SecretStorageFile, APIKey: "file-key"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(readConfigFile(t), "file-key") {
+		t.Error("file storage should keep the key in the config file")
+	}
+	if _, err := keyring.Get(keyringService, "api_key"); !errors.Is(err, keyring.ErrNotFound) {
+		t.Errorf("keyring should not be used with file storage, got %v", err)
+	}
+}
+
+func TestKeyringUnavailableFallsBackToFile(t *testing.T) {
+	useTempHome(t)
+	keyring.MockInitWithError(errors.New("no secret service"))
+	defer keyring.MockInit()
+
+	if err := SaveConfig(&Config{APIKey: "fallback-key"}); err != nil {
+		t.Fatalf("SaveConfig failed: %v", err)
+	}
+	if !strings.Contains(readConfigFile(t), "fallback-key") {
+		t.Error("key should stay in the config file when the keyring is unavailable")
+	}
+	loaded, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if loaded.APIKey != "fallback-key" {
+		t.Errorf("APIKey = %q, want fallback-key", loaded.APIKey)
+	}
+}
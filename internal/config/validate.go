@@ -0,0 +1,376 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
+	"github.com/Zacy-Sokach/PolyAgent/internal/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// ValidationIssue 配置文件中的一个问题，Line/Column 从 1 开始，未知位置时为 0
+type ValidationIssue struct {
+	Line   int
+	Column int
+	// Key 出问题的配置项，如 file_engine.allowed_roots[0]
+	Key     string
+	Message string
+	// Warning 为 true 时只是提示（如未知的配置项），不影响使用
+	Warning bool
+}
+
+func (i ValidationIssue) String() string {
+	var sb strings.Builder
+	if i.Line > 0 {
+		sb.WriteString(fmt.Sprintf("%d:%d: ", i.Line, i.Column))
+	}
+	if i.Key != "" {
+		sb.WriteString(i.Key)
+		sb.WriteString(": ")
+	}
+	sb.WriteString(i.Message)
+	return sb.String()
+}
+
+// yamlLinePattern 从 yaml 解析错误中提取行号
+var yamlLinePattern = regexp.MustCompile(`line (\d+)`)
+
+// ValidateConfigFile 校验配置文件，返回配置文件路径和发现的问题；文件不存在时没有问题
+func ValidateConfigFile() (string, []ValidationIssue, error) {
+	configPath, err := getConfigPath()
+	if err != nil {
+		return "", nil, err
+	}
+	data, err := os.ReadFile(configPath)
+	if os.IsNotExist(err) {
+		return configPath, nil, nil
+	}
+	if err != nil {
+		return configPath, nil, fmt.Errorf("读取配置文件失败: %w", err)
+	}
+	return configPath, Validate(data), nil
+}
+
+// Validate 按 Config 的结构校验配置内容：语法错误、未知的配置项、类型错误，以及路径、通配符等取值
+func Validate(data []byte) []ValidationIssue {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		issue := ValidationIssue{Message: fmt.Sprintf("YAML 语法错误: %v", strings.TrimPrefix(err.Error(), "yaml: "))}
+		if m := yamlLinePattern.FindStringSubmatch(err.Error()); m != nil {
+			issue.Line, _ = strconv.Atoi(m[1])
+		}
+		return []ValidationIssue{issue}
+	}
+	if len(root.Content) == 0 {
+		return nil
+	}
+
+	v := &validator{nodes: make(map[string]*yaml.Node)}
+	v.check(root.Content[0], root.Content[0], reflect.TypeOf(Config{}), "")
+	for _, issue := range v.issues {
+		if !issue.Warning {
+			// 类型有误时不再检查取值，避免同一问题重复报告
+			return v.issues
+		}
+	}
+
+	var config Config
+	if err := root.Content[0].Decode(&config); err != nil {
+		return []ValidationIssue{{Message: err.Error()}}
+	}
+	v.checkValues(&config)
+	return v.issues
+}
+
+// ValidateConfig 校验内存中的配置（如命令行修改后、保存前）
+func ValidateConfig(c *Config) []ValidationIssue {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return []ValidationIssue{{Message: err.Error()}}
+	}
+	return Validate(data)
+}
+
+// validator 校验过程中的状态
+type validator struct {
+	issues []ValidationIssue
+	// nodes 按配置项路径记录节点，用于为取值问题报告行号
+	nodes map[string]*yaml.Node
+}
+
+func (v *validator) add(node *yaml.Node, key, message string, warning bool) {
+	issue := ValidationIssue{Key: key, Message: message, Warning: warning}
+	if node != nil {
+		issue.Line, issue.Column = node.Line, node.Column
+	}
+	v.issues = append(v.issues, issue)
+}
+
+// addAt 为已记录位置的配置项报告取值问题
+func (v *validator) addAt(key, message string) {
+	v.add(v.nodes[key], key, message, false)
+}
+
+// check 检查节点的结构和类型是否与 Go 类型匹配，pos 为报告问题时使用的位置（映射中为键所在的节点）
+func (v *validator) check(node, pos *yaml.Node, t reflect.Type, key string) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	v.nodes[key] = pos
+	// null 等价于未设置
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			v.add(pos, key, "应为键值映射", false)
+			return
+		}
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			name := node.Content[i].Value
+			field, ok := fields[name]
+			if !ok {
+				message := "未知的配置项，将被忽略"
+				if suggestion := closestKey(name, fields); suggestion != "" {
+					message += fmt.Sprintf("（是否为 %s？）", suggestion)
+				}
+				v.add(node.Content[i], joinKey(key, name), message, true)
+				continue
+			}
+			v.check(node.Content[i+1], node.Content[i], field.Type, joinKey(key, name))
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			v.add(pos, key, "应为键值映射", false)
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			v.check(node.Content[i+1], node.Content[i], t.Elem(), joinKey(key, node.Content[i].Value))
+		}
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			v.add(pos, key, "应为列表", false)
+			return
+		}
+		for i, item := range node.Content {
+			v.check(item, item, t.Elem(), fmt.Sprintf("%s[%d]", key, i))
+		}
+	default:
+		if node.Kind != yaml.ScalarNode {
+			v.add(pos, key, fmt.Sprintf("应为%s", kindName(t.Kind())), false)
+			return
+		}
+		if err := node.Decode(reflect.New(t).Interface()); err != nil {
+			v.add(pos, key, fmt.Sprintf("应为%s，实际为 %q", kindName(t.Kind()), node.Value), false)
+		}
+	}
+}
+
+// checkValues 检查类型正确但取值无效的配置项
+func (v *validator) checkValues(c *Config) {
+	if _, ok := v.nodes["language"]; ok {
+		if _, ok := i18n.ParseLanguage(c.Language); !ok {
+			v.addAt("language", fmt.Sprintf("不支持的界面语言 %q，可选 zh 或 en", c.Language))
+		}
+	}
+	if c.BaseURL != "" {
+		if u, err := url.Parse(c.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			v.addAt("base_url", fmt.Sprintf("无效的服务地址 %q，应为 http:// 或 https:// 开头的 URL", c.BaseURL))
+		}
+	}
+	switch c.SecretStorage {
+	case "", SecretStorageKeyring, SecretStorageFile:
+	default:
+		v.addAt("secret_storage", fmt.Sprintf("无效的取值 %q，可选 keyring 或 file", c.SecretStorage))
+	}
+	switch c.ToolResultCache {
+	case "", ToolResultCacheTurn, ToolResultCacheSession, ToolResultCacheOff:
+	default:
+		v.addAt("tool_result_cache", fmt.Sprintf("无效的取值 %q，可选 turn、session 或 off", c.ToolResultCache))
+	}
+
+	for i, root := range c.FileEngine.AllowedRoots {
+		key := fmt.Sprintf("file_engine.allowed_roots[%d]", i)
+		if info, err := os.Stat(root); err != nil {
+			v.addAt(key, fmt.Sprintf("目录不存在: %s", root))
+		} else if !info.IsDir() {
+			v.addAt(key, fmt.Sprintf("不是目录: %s", root))
+		}
+	}
+	for i, ext := range c.FileEngine.BlacklistedExts {
+		if !strings.HasPrefix(ext, ".") {
+			v.addAt(fmt.Sprintf("file_engine.blacklisted_exts[%d]", i), fmt.Sprintf("扩展名应以 . 开头，如 .%s", ext))
+		}
+	}
+	if c.FileEngine.MaxFileSize < 0 {
+		v.addAt("file_engine.max_file_size", "不能为负数")
+	}
+
+	for i, pattern := range c.Shell.PassEnv {
+		if _, err := filepath.Match(pattern, ""); errors.Is(err, filepath.ErrBadPattern) {
+			v.addAt(fmt.Sprintf("shell.pass_env[%d]", i), fmt.Sprintf("无效的通配符: %s", pattern))
+		}
+	}
+	if c.Shell.MaxOutputBytes < 0 {
+		v.addAt("shell.max_output_bytes", "不能为负数")
+	}
+
+	for _, list := range []struct {
+		name    string
+		domains []string
+	}{{"allowed_domains", c.WebPolicy.AllowedDomains}, {"denied_domains", c.WebPolicy.DeniedDomains}} {
+		for i, domain := range list.domains {
+			if domain == "" || strings.ContainsAny(domain, "/: ") {
+				v.addAt(fmt.Sprintf("web_policy.%s[%d]", list.name, i), fmt.Sprintf("应为域名（如 example.com），不含协议和路径: %q", domain))
+			}
+		}
+	}
+
+	if c.Network.Proxy != "" {
+		if _, err := utils.ParseProxyURL(c.Network.Proxy); err != nil {
+			v.addAt("network.proxy", err.Error())
+		}
+	}
+	for i, file := range c.Network.CAFiles {
+		if _, err := os.Stat(file); err != nil {
+			v.addAt(fmt.Sprintf("network.ca_files[%d]", i), fmt.Sprintf("文件不存在: %s", file))
+		}
+	}
+	for _, item := range []struct {
+		key   string
+		value int
+	}{
+		{"network.max_conns_per_host", c.Network.MaxConnsPerHost},
+		{"network.max_idle_conns_per_host", c.Network.MaxIdleConnsPerHost},
+		{"network.idle_conn_timeout", c.Network.IdleConnTimeout},
+	} {
+		if item.value < 0 {
+			v.addAt(item.key, "不能为负数，0 表示默认值")
+		}
+	}
+
+	for provider, limit := range c.RateLimits {
+		if limit.RequestsPerMinute < 0 || limit.TokensPerMinute < 0 {
+			v.addAt(joinKey("rate_limits", provider), "限流值不能为负数，0 表示不限制")
+		}
+	}
+	for model, window := range c.ContextWindows {
+		if window <= 0 {
+			v.addAt(joinKey("context_windows", model), "上下文窗口必须为正数")
+		}
+	}
+	if c.StreamIdleTimeout < 0 {
+		v.addAt("stream_idle_timeout", "不能为负数，0 表示默认值")
+	}
+	for _, item := range []struct {
+		key   string
+		value int
+	}{
+		{"timeouts.connect", c.Timeouts.Connect},
+		{"timeouts.response_header", c.Timeouts.ResponseHeader},
+		{"timeouts.request", c.Timeouts.Request},
+	} {
+		if item.value < 0 {
+			v.addAt(item.key, "不能为负数，0 表示默认值")
+		}
+	}
+
+	for _, item := range []struct {
+		key   string
+		value float64
+	}{
+		{"budget.input_price", c.Budget.InputPrice},
+		{"budget.output_price", c.Budget.OutputPrice},
+		{"budget.session_limit", c.Budget.SessionLimit},
+		{"budget.daily_limit", c.Budget.DailyLimit},
+	} {
+		if item.value < 0 {
+			v.addAt(item.key, "不能为负数")
+		}
+	}
+	if c.Budget.WarnPercent < 0 || c.Budget.WarnPercent > 100 {
+		v.addAt("budget.warn_percent", "应在 0 到 100 之间，0 表示默认 80")
+	}
+}
+
+// yamlFields 按 yaml 标签名索引结构体字段
+func yamlFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fields[name] = field
+	}
+	return fields
+}
+
+// closestKey 返回与未知配置项最接近的已知配置项（编辑距离不超过 2），用于提示拼写错误
+func closestKey(name string, fields map[string]reflect.StructField) string {
+	best, bestDistance := "", 3
+	for candidate := range fields {
+		if d := editDistance(name, candidate); d < bestDistance || (d == bestDistance && candidate < best) {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+// editDistance 计算两个字符串的编辑距离
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func joinKey(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+// kindName 返回类型的中文名称
+func kindName(kind reflect.Kind) string {
+	switch kind {
+	case reflect.Bool:
+		return "布尔值（true/false）"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "整数"
+	case reflect.Float32, reflect.Float64:
+		return "数字"
+	default:
+		return "字符串"
+	}
+}
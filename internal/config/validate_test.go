@@ -0,0 +1,81 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		content string
+		line    int
+		key     string
+		message string
+		warning bool
+	}{
+		{"syntax", "model: glm\n  bad: [\n", 2, "", "YAML 语法错误", false},
+		{"unknown key", "model: glm\nfile_engin:\n  max_file_size: 10\n", 2, "file_engin", "file_engine", true},
+		{"wrong type", "file_engine:\n  max_file_size: big\n", 2, "file_engine.max_file_size", "应为整数", false},
+		{"wrong kind", "shell:\n  auto_approve: go test\n", 2, "shell.auto_approve", "应为列表", false},
+		{"bool", "offline: maybe\n", 1, "offline", "布尔值", false},
+		{"missing root", "file_engine:\n  allowed_roots:\n    - " + dir + "/missing\n", 3, "file_engine.allowed_roots[0]", "目录不存在", false},
+		{"bad glob", "shell:\n  pass_env: [\"GO[\"]\n", 2, "shell.pass_env[0]", "无效的通配符", false},
+		{"language", "language: fr\n", 1, "language", "不支持的界面语言", false},
+		{"proxy", "network:\n  proxy: ftp://host\n", 2, "network.proxy", "scheme", false},
+		{"domain", "web_policy:\n  denied_domains: [\"https://example.com\"]\n", 2, "web_policy.denied_domains[0]", "应为域名", false},
+		{"rate limit", "rate_limits:\n  glm:\n    requests_per_minute: -1\n", 2, "rate_limits.glm", "负数", false},
+		{"secret storage", "secret_storage: vault\n", 1, "secret_storage", "keyring", false},
+		{"budget", "budget:\n  daily_limit: -5\n", 2, "budget.daily_limit", "负数", false},
+		{"context window", "context_windows:\n  glm-4.5: 0\n", 2, "context_windows.glm-4.5", "正数", false},
+		{"timeout", "timeouts:\n  request: -1\n", 2, "timeouts.request", "负数", false},
+		{"tool result cache", "tool_result_cache: always\n", 1, "tool_result_cache", "turn", false},
+		{"base url", "base_url: localhost:11434\n", 1, "base_url", "http://", false},
+		{"max conns", "network:\n  max_conns_per_host: -2\n", 2, "network.max_conns_per_host", "负数", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := Validate([]byte(tt.content))
+			if len(issues) != 1 {
+				t.Fatalf("issues = %v, want exactly one", issues)
+			}
+			issue := issues[0]
+			if issue.Line != tt.line || issue.Key != tt.key || issue.Warning != tt.warning || !strings.Contains(issue.Message, tt.message) {
+				t.Errorf("issue = %+v (%s)", issue, issue)
+			}
+		})
+	}
+}
+
+func TestValidateAcceptsValidConfig(t *testing.T) {
+	content := `api_key: key
+model: glm-4.5
+language: en
+secret_storage: file
+file_engine:
+  allowed_roots: ["` + t.TempDir() + `"]
+  blacklisted_exts: [".exe"]
+  max_file_size: 1024
+shell:
+  pass_env: ["PATH", "GO*"]
+rate_limits:
+  glm:
+    requests_per_minute: 60
+write_quota:
+  max_mb: -1
+`
+	if issues := Validate([]byte(content)); len(issues) != 0 {
+		t.Errorf("unexpected issues: %v", issues)
+	}
+	if issues := Validate(nil); len(issues) != 0 {
+		t.Errorf("empty config should be valid, got %v", issues)
+	}
+}
+
+func TestValidationIssueString(t *testing.T) {
+	issue := ValidationIssue{Line: 3, Column: 5, Key: "shell.pass_env[0]", Message: "无效的通配符: GO["}
+	if got := issue.String(); got != "3:5: shell.pass_env[0]: 无效的通配符: GO[" {
+		t.Errorf("String() = %q", got)
+	}
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// IsWorkspaceTrusted 判断目录是否已被信任，已信任目录的子目录同样视为信任
+func (c *Config) IsWorkspaceTrusted(dir string) bool {
+	dir = normalizeWorkspace(dir)
+	for _, trusted := range c.TrustedWorkspaces {
+		trusted = normalizeWorkspace(trusted)
+		if dir == trusted {
+			return true
+		}
+		rel, err := filepath.Rel(trusted, dir)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// TrustWorkspace 将目录加入信任列表，调用方负责保存配置
+func (c *Config) TrustWorkspace(dir string) {
+	if c.IsWorkspaceTrusted(dir) {
+		return
+	}
+	c.TrustedWorkspaces = append(c.TrustedWorkspaces, normalizeWorkspace(dir))
+}
+
+// normalizeWorkspace 转换为清理后的绝对路径
+func normalizeWorkspace(dir string) string {
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	return filepath.Clean(dir)
+}
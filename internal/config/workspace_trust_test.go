@@ -0,0 +1,36 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestWorkspaceTrust(t *testing.T) {
+	root := t.TempDir()
+	project := filepath.Join(root, "project")
+	cfg := &Config{}
+
+	if cfg.IsWorkspaceTrusted(project) {
+		t.Fatal("Expected new workspace to be untrusted")
+	}
+
+	cfg.TrustWorkspace(project)
+	if !cfg.IsWorkspaceTrusted(project) {
+		t.Error("Expected workspace to be trusted after TrustWorkspace")
+	}
+	if !cfg.IsWorkspaceTrusted(filepath.Join(project, "sub", "dir")) {
+		t.Error("Expected subdirectory of trusted workspace to be trusted")
+	}
+	if cfg.IsWorkspaceTrusted(filepath.Join(root, "project-other")) {
+		t.Error("Expected sibling with common prefix to be untrusted")
+	}
+	if cfg.IsWorkspaceTrusted(root) {
+		t.Error("Expected parent of trusted workspace to be untrusted")
+	}
+
+	// 重复信任不应产生重复条目
+	cfg.TrustWorkspace(filepath.Join(project, "sub"))
+	if len(cfg.TrustedWorkspaces) != 1 {
+		t.Errorf("Expected 1 trusted workspace, got %d", len(cfg.TrustedWorkspaces))
+	}
+}
@@ -0,0 +1,152 @@
+// Package i18n 提供界面文本的多语言支持
+// 文本目录以 YAML 形式存放在 locales/ 目录下，并在编译时嵌入二进制
+package i18n
+
+import (
+	"embed"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Language 界面语言
+type Language string
+
+const (
+	Chinese Language = "zh"
+	English Language = "en"
+
+	// DefaultLanguage 默认语言，同时作为缺失文本的回退语言
+	DefaultLanguage = Chinese
+)
+
+//go:embed locales/*.yaml
+var localeFS embed.FS
+
+var (
+	mu       sync.RWMutex
+	current  = DefaultLanguage
+	catalogs map[Language]map[string]string
+	loadOnce sync.Once
+	loadErr  error
+)
+
+// loadCatalogs 加载所有嵌入的语言目录
+func loadCatalogs() {
+	catalogs = make(map[Language]map[string]string)
+
+	entries, err := localeFS.ReadDir("locales")
+	if err != nil {
+		loadErr = fmt.Errorf("读取语言目录失败: %w", err)
+		return
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if path.Ext(name) != ".yaml" {
+			continue
+		}
+
+		data, err := localeFS.ReadFile(path.Join("locales", name))
+		if err != nil {
+			loadErr = fmt.Errorf("读取语言文件 %s 失败: %w", name, err)
+			continue
+		}
+
+		var catalog map[string]string
+		if err := yaml.Unmarshal(data, &catalog); err != nil {
+			loadErr = fmt.Errorf("解析语言文件 %s 失败: %w", name, err)
+			continue
+		}
+
+		catalogs[Language(strings.TrimSuffix(name, ".yaml"))] = catalog
+	}
+}
+
+func ensureLoaded() {
+	loadOnce.Do(loadCatalogs)
+}
+
+// ParseLanguage 将配置中的语言名称规范化，支持 zh/zh-CN/chinese/en/en-US/english 等写法
+func ParseLanguage(name string) (Language, bool) {
+	normalized := strings.ToLower(strings.TrimSpace(name))
+	normalized = strings.ReplaceAll(normalized, "_", "-")
+
+	switch {
+	case normalized == "":
+		return "", false
+	case normalized == "zh" || strings.HasPrefix(normalized, "zh-") ||
+		normalized == "chinese" || normalized == "中文":
+		return Chinese, true
+	case normalized == "en" || strings.HasPrefix(normalized, "en-") ||
+		normalized == "english" || normalized == "英文":
+		return English, true
+	}
+
+	return "", false
+}
+
+// SetLanguage 设置当前界面语言，无法识别的语言会回退到默认语言并返回错误
+func SetLanguage(name string) error {
+	ensureLoaded()
+
+	lang, ok := ParseLanguage(name)
+	if !ok {
+		lang = DefaultLanguage
+	}
+
+	mu.Lock()
+	current = lang
+	mu.Unlock()
+
+	if !ok && strings.TrimSpace(name) != "" {
+		return fmt.Errorf("unsupported language: %s", name)
+	}
+	return loadErr
+}
+
+// CurrentLanguage 返回当前界面语言
+func CurrentLanguage() Language {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// T 按当前语言查找文本，若带参数则按 fmt.Sprintf 格式化
+// 查找顺序：当前语言 -> 默认语言 -> key 本身
+func T(key string, args ...interface{}) string {
+	return TIn(CurrentLanguage(), key, args...)
+}
+
+// TIn 按指定语言查找文本
+func TIn(lang Language, key string, args ...interface{}) string {
+	ensureLoaded()
+
+	text, ok := catalogs[lang][key]
+	if !ok {
+		text, ok = catalogs[DefaultLanguage][key]
+	}
+	if !ok {
+		text = key
+	}
+
+	if len(args) > 0 {
+		return fmt.Sprintf(text, args...)
+	}
+	return text
+}
+
+// ResponseLanguageInstruction 生成要求模型使用指定语言回答的系统提示
+// 对于没有文本目录的语言（如 ja、fr），使用英文模板并直接填入语言名称
+func ResponseLanguageInstruction(name string) string {
+	if lang, ok := ParseLanguage(name); ok {
+		return TIn(lang, "prompt.respond_in_language")
+	}
+	if strings.TrimSpace(name) == "" {
+		return ""
+	}
+	return TIn(English, "prompt.respond_in_other_language", strings.TrimSpace(name))
+}
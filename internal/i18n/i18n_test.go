@@ -0,0 +1,70 @@
+package i18n
+
+import "testing"
+
+func TestCatalogsHaveSameKeys(t *testing.T) {
+	ensureLoaded()
+	if loadErr != nil {
+		t.Fatalf("loading catalogs failed: %v", loadErr)
+	}
+
+	zh, en := catalogs[Chinese], catalogs[English]
+	if len(zh) == 0 || len(en) == 0 {
+		t.Fatalf("expected both catalogs to be loaded, got zh=%d en=%d", len(zh), len(en))
+	}
+
+	for key := range zh {
+		if _, ok := en[key]; !ok {
+			t.Errorf("key %q missing from en catalog", key)
+		}
+	}
+	for key := range en {
+		if _, ok := zh[key]; !ok {
+			t.Errorf("key %q missing from zh catalog", key)
+		}
+	}
+}
+
+func TestParseLanguage(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Language
+		ok    bool
+	}{
+		{"zh", Chinese, true},
+		{"zh-CN", Chinese, true},
+		{"zh_tw", Chinese, true},
+		{"English", English, true},
+		{"en-US", English, true},
+		{"ja", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParseLanguage(tt.input)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseLanguage(%q) = %q, %v; want %q, %v", tt.input, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestTFallback(t *testing.T) {
+	if got := TIn(English, "editor.saved", 3); got != "Saved 3 edits to disk" {
+		t.Errorf("unexpected English text: %q", got)
+	}
+	if got := TIn(Language("fr"), "ui.role_user"); got != "你: " {
+		t.Errorf("expected fallback to default language, got %q", got)
+	}
+	if got := T("no.such.key"); got != "no.such.key" {
+		t.Errorf("expected key as fallback, got %q", got)
+	}
+}
+
+func TestResponseLanguageInstruction(t *testing.T) {
+	if got := ResponseLanguageInstruction(""); got != "" {
+		t.Errorf("expected empty instruction, got %q", got)
+	}
+	if got := ResponseLanguageInstruction("ja"); got == "" || got == TIn(English, "prompt.respond_in_language") {
+		t.Errorf("expected templated instruction for ja, got %q", got)
+	}
+}
@@ -0,0 +1,451 @@
+package mcp
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// defaultAdvancedSearchResults advanced_search 默认最多返回的匹配数
+	defaultAdvancedSearchResults = 200
+	// maxAdvancedSearchResults advanced_search 最多返回的匹配数
+	maxAdvancedSearchResults = 2000
+	// maxSearchContext 最多显示的上下文行数
+	maxSearchContext = 10
+	// maxMultilineMatchLines 多行匹配结果最多显示的行数
+	maxMultilineMatchLines = 20
+)
+
+// searchOptions advanced_search 选项
+type searchOptions struct {
+	Pattern         string
+	Literal         bool
+	Word            bool
+	CaseInsensitive bool
+	Multiline       bool
+	Include         []string
+	Exclude         []string
+	Gitignore       bool
+	Context         int
+	MaxResults      int
+}
+
+// buildSearchRegexp 按选项把用户模式转换为正则表达式
+func buildSearchRegexp(opts searchOptions) (*regexp.Regexp, error) {
+	pattern := opts.Pattern
+	if opts.Literal {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if opts.Word {
+		pattern = `\b(?:` + pattern + `)\b`
+	}
+	flags := ""
+	if opts.CaseInsensitive {
+		flags += "i"
+	}
+	if opts.Multiline {
+		// ^ 和 $ 匹配每一行的开头和结尾，\n 和 \s 可跨行匹配
+		flags += "m"
+	}
+	if flags != "" {
+		pattern = "(?" + flags + ")" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("无效的正则表达式: %w", err)
+	}
+	return re, nil
+}
+
+// searchGlobMatch 不含 / 的模式匹配文件名，含 / 的模式（可用 **）匹配相对路径
+func searchGlobMatch(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		pattern = filepath.ToSlash(pattern)
+		if !strings.Contains(pattern, "/") {
+			if matchGlobSegment(pattern, filepath.Base(rel), true) {
+				return true
+			}
+			continue
+		}
+		if matchGlobSegments(splitGlobPattern(pattern), strings.Split(rel, "/"), true, false) {
+			return true
+		}
+	}
+	return false
+}
+
+// searchMatch 一条匹配
+type searchMatch struct {
+	Path      string
+	StartLine int
+	EndLine   int
+	// Lines 匹配及上下文所在的行，从 FirstLine 开始
+	Lines     []string
+	FirstLine int
+}
+
+// AdvancedSearchTool 支持字面量、整词、多行匹配并遵循 .gitignore 的内容搜索
+type AdvancedSearchTool struct {
+	engine *FileEngine
+}
+
+func NewAdvancedSearchTool(engine *FileEngine) *AdvancedSearchTool {
+	return &AdvancedSearchTool{engine: engine}
+}
+
+func (t *AdvancedSearchTool) Name() string {
+	return "advanced_search"
+}
+
+func (t *AdvancedSearchTool) Description() string {
+	return "高级内容搜索：支持字面量（非正则）模式、整词匹配、跨行的多行正则、include/exclude glob 和上下文行，默认遵循 .gitignore"
+}
+
+func (t *AdvancedSearchTool) GetSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"pattern": map[string]interface{}{
+				"type":        "string",
+				"description": "搜索模式，默认为正则表达式",
+			},
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "搜索根目录，默认当前目录",
+			},
+			"literal": map[string]interface{}{
+				"type":        "boolean",
+				"description": "按字面量匹配，不解析正则元字符",
+			},
+			"word": map[string]interface{}{
+				"type":        "boolean",
+				"description": "只匹配整词（等价于 \\b...\\b）",
+			},
+			"case_insensitive": map[string]interface{}{
+				"type":        "boolean",
+				"description": "忽略大小写",
+			},
+			"multiline": map[string]interface{}{
+				"type":        "boolean",
+				"description": "在整个文件上匹配，模式可用 \\n 或 \\s 跨越多行，^/$ 匹配行首行尾",
+			},
+			"include": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "只搜索匹配的文件，如 *.go 或 internal/**/*.go",
+			},
+			"exclude": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "排除匹配的文件或目录",
+			},
+			"respect_gitignore": map[string]interface{}{
+				"type":        "boolean",
+				"description": "跳过 .gitignore 忽略的文件，默认 true",
+			},
+			"context_lines": map[string]interface{}{
+				"type":        "integer",
+				"description": "每个匹配前后显示的行数 (0-10，默认 0)",
+			},
+			"max_results": map[string]interface{}{
+				"type":        "integer",
+				"description": "最多返回的匹配数，默认 200",
+			},
+		},
+		"required": []string{"pattern"},
+	}
+}
+
+func (t *AdvancedSearchTool) Execute(args map[string]interface{}) (interface{}, error) {
+	pattern, ok := args["pattern"].(string)
+	if !ok || pattern == "" {
+		return nil, fmt.Errorf("缺少或无效的pattern参数")
+	}
+	root := "."
+	if p, ok := args["path"].(string); ok && p != "" {
+		root = p
+	}
+
+	opts := searchOptions{Pattern: pattern, Gitignore: true, MaxResults: defaultAdvancedSearchResults}
+	opts.Literal, _ = args["literal"].(bool)
+	opts.Word, _ = args["word"].(bool)
+	opts.CaseInsensitive, _ = args["case_insensitive"].(bool)
+	opts.Multiline, _ = args["multiline"].(bool)
+	if v, ok := args["respect_gitignore"].(bool); ok {
+		opts.Gitignore = v
+	}
+	opts.Include = stringList(args["include"])
+	opts.Exclude = stringList(args["exclude"])
+	if n, ok := args["context_lines"].(float64); ok && n > 0 {
+		opts.Context = int(n)
+		if opts.Context > maxSearchContext {
+			opts.Context = maxSearchContext
+		}
+	}
+	if n, ok := args["max_results"].(float64); ok && n > 0 {
+		opts.MaxResults = int(n)
+		if opts.MaxResults > maxAdvancedSearchResults {
+			opts.MaxResults = maxAdvancedSearchResults
+		}
+	}
+
+	matches, truncated, err := t.search(root, opts)
+	if err != nil {
+		return nil, err
+	}
+	if len(matches) == 0 {
+		return "未找到匹配项", nil
+	}
+
+	output := formatSearchMatches(matches, opts)
+	if truncated {
+		output += fmt.Sprintf("\n[truncated] 仅显示前 %d 个匹配，请缩小搜索范围", opts.MaxResults)
+	}
+	return output, nil
+}
+
+// search 遍历 root 并发搜索，结果按路径和行号排序
+func (t *AdvancedSearchTool) search(root string, opts searchOptions) ([]searchMatch, bool, error) {
+	re, err := buildSearchRegexp(opts)
+	if err != nil {
+		return nil, false, err
+	}
+	files, err := collectSearchFiles(t.engine, root, opts)
+	if err != nil {
+		return nil, false, err
+	}
+
+	maxSize := int64(5 * 1024 * 1024)
+	if t.engine != nil && t.engine.config.MaxFileSize > 0 {
+		maxSize = t.engine.config.MaxFileSize
+	}
+
+	var (
+		mu      sync.Mutex
+		matches []searchMatch
+		wg      sync.WaitGroup
+	)
+	jobs := make(chan string)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range jobs {
+				found := searchFile(path, re, opts, maxSize)
+				if len(found) > 0 {
+					mu.Lock()
+					matches = append(matches, found...)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, path := range files {
+		jobs <- path
+	}
+	close(jobs)
+	wg.Wait()
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Path != matches[j].Path {
+			return matches[i].Path < matches[j].Path
+		}
+		return matches[i].StartLine < matches[j].StartLine
+	})
+	truncated := false
+	if len(matches) > opts.MaxResults {
+		matches = matches[:opts.MaxResults]
+		truncated = true
+	}
+	return matches, truncated, nil
+}
+
+// collectSearchFiles 按 include/exclude、.gitignore 和 AllowedRoots 收集 root 下要搜索的文件
+func collectSearchFiles(engine *FileEngine, root string, opts searchOptions) ([]string, error) {
+	for _, pattern := range append(append([]string(nil), opts.Include...), opts.Exclude...) {
+		if _, err := filepath.Match(filepath.ToSlash(pattern), ""); err != nil {
+			return nil, fmt.Errorf("无效的glob模式 %q: %w", pattern, err)
+		}
+	}
+
+	var ignore *gitignoreSet
+	if opts.Gitignore {
+		ignore = newGitignoreSet(root)
+	}
+
+	var files []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			return nil
+		}
+		if path == root {
+			return nil
+		}
+		rel, _ := filepath.Rel(root, path)
+		rel = filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			if d.Name() == ".git" || searchGlobMatch(opts.Exclude, rel) {
+				return filepath.SkipDir
+			}
+			if ignore != nil {
+				if abs, err := filepath.Abs(path); err == nil && ignore.Ignored(abs, true) {
+					return filepath.SkipDir
+				}
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if len(opts.Include) > 0 && !searchGlobMatch(opts.Include, rel) {
+			return nil
+		}
+		if searchGlobMatch(opts.Exclude, rel) {
+			return nil
+		}
+		if ignore != nil {
+			if abs, err := filepath.Abs(path); err == nil && ignore.Ignored(abs, false) {
+				return nil
+			}
+		}
+		if engine != nil && engine.ValidatePath(path) != nil {
+			return nil
+		}
+		files = append(files, path)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("遍历目录失败: %w", err)
+	}
+
+	return files, nil
+}
+
+// searchFile 在单个文件中搜索，跳过过大和二进制文件
+func searchFile(path string, re *regexp.Regexp, opts searchOptions, maxSize int64) []searchMatch {
+	info, err := os.Stat(path)
+	if err != nil || info.Size() > maxSize {
+		return nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil || isBinaryContent(content) {
+		return nil
+	}
+
+	text := string(content)
+	lines := strings.Split(text, "\n")
+	var matches []searchMatch
+
+	if opts.Multiline {
+		// lineStarts[i] 为第 i 行（从 0 开始）的起始偏移
+		lineStarts := make([]int, 0, len(lines))
+		offset := 0
+		for _, line := range lines {
+			lineStarts = append(lineStarts, offset)
+			offset += len(line) + 1
+		}
+		lineAt := func(pos int) int {
+			return sort.Search(len(lineStarts), func(i int) bool { return lineStarts[i] > pos }) - 1
+		}
+		for _, loc := range re.FindAllStringIndex(text, opts.MaxResults+1) {
+			end := loc[1]
+			if end > loc[0] {
+				end--
+			}
+			matches = append(matches, newSearchMatch(path, lines, lineAt(loc[0]), lineAt(end), opts.Context))
+		}
+		return matches
+	}
+
+	for i, line := range lines {
+		if re.MatchString(line) {
+			matches = append(matches, newSearchMatch(path, lines, i, i, opts.Context))
+			if len(matches) > opts.MaxResults {
+				break
+			}
+		}
+	}
+	return matches
+}
+
+// newSearchMatch 构造匹配结果，start/end 为从 0 开始的行号
+func newSearchMatch(path string, lines []string, start, end, context int) searchMatch {
+	first := start - context
+	if first < 0 {
+		first = 0
+	}
+	last := end + context
+	if last >= len(lines) {
+		last = len(lines) - 1
+	}
+	return searchMatch{
+		Path:      path,
+		StartLine: start + 1,
+		EndLine:   end + 1,
+		Lines:     lines[first : last+1],
+		FirstLine: first + 1,
+	}
+}
+
+// formatSearchMatches 格式化结果：匹配行为 path:行号: 内容，上下文行为 path-行号- 内容，多行匹配之间用 -- 分隔
+func formatSearchMatches(matches []searchMatch, opts searchOptions) string {
+	var sb strings.Builder
+	for i, match := range matches {
+		if i > 0 && (opts.Context > 0 || opts.Multiline) {
+			sb.WriteString("--\n")
+		}
+		shown := 0
+		for j, line := range match.Lines {
+			lineNo := match.FirstLine + j
+			sep := "-"
+			if lineNo >= match.StartLine && lineNo <= match.EndLine {
+				sep = ":"
+				shown++
+				if shown > maxMultilineMatchLines {
+					if lineNo == match.StartLine+maxMultilineMatchLines {
+						fmt.Fprintf(&sb, "%s:...: (省略 %d 行)\n", match.Path, match.EndLine-lineNo+1)
+					}
+					continue
+				}
+			}
+			sb.WriteString(match.Path)
+			sb.WriteString(sep)
+			sb.WriteString(strconv.Itoa(lineNo))
+			sb.WriteString(sep)
+			sb.WriteString(" ")
+			sb.WriteString(strings.TrimSuffix(line, "\r"))
+			sb.WriteString("\n")
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// stringList 将 JSON 数组参数转换为字符串切片，也接受单个字符串
+func stringList(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		if v != "" {
+			return []string{v}
+		}
+	case []interface{}:
+		var out []string
+		for _, item := range v {
+			if s, ok := item.(string); ok && s != "" {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
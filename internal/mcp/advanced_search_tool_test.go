@@ -0,0 +1,67 @@
+package mcp
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAdvancedSearch(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		".gitignore":      "ignored/\n",
+		"a.go":            "func main() {\n\treturn a.b\n}\n",
+		"b.txt":           "a+b and ab\nsubstring abc\n",
+		"ignored/c.go":    "a.b\n",
+		"internal/d.go":   "x := a.b\n",
+		"internal/e_test": "a.b\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tool := &AdvancedSearchTool{}
+
+	paths := func(matches []searchMatch) string {
+		var out []string
+		for _, m := range matches {
+			rel, _ := filepath.Rel(root, m.Path)
+			out = append(out, filepath.ToSlash(rel)+":"+string(rune('0'+m.StartLine))+"-"+string(rune('0'+m.EndLine)))
+		}
+		return strings.Join(out, ",")
+	}
+
+	run := func(opts searchOptions) string {
+		t.Helper()
+		if opts.MaxResults == 0 {
+			opts.MaxResults = 100
+		}
+		matches, _, err := tool.search(root, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return paths(matches)
+	}
+
+	if got := run(searchOptions{Pattern: "a.b", Literal: true, Gitignore: true, Include: []string{"*.go"}}); got != "a.go:2-2,internal/d.go:1-1" {
+		t.Errorf("literal search = %s", got)
+	}
+	if got := run(searchOptions{Pattern: "a.b", Literal: true, Include: []string{"*.go"}}); got != "a.go:2-2,ignored/c.go:1-1,internal/d.go:1-1" {
+		t.Errorf("search without gitignore = %s", got)
+	}
+	if got := run(searchOptions{Pattern: "ab", Word: true, Gitignore: true}); got != "b.txt:1-1" {
+		t.Errorf("word search = %s", got)
+	}
+	if got := run(searchOptions{Pattern: `main\(\) \{\n\s*return`, Multiline: true, Gitignore: true}); got != "a.go:1-2" {
+		t.Errorf("multiline search = %s", got)
+	}
+	if got := run(searchOptions{Pattern: "a.b", Literal: true, Gitignore: true, Exclude: []string{"internal"}}); got != "a.go:2-2" {
+		t.Errorf("exclude search = %s", got)
+	}
+}
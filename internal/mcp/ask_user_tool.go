@@ -0,0 +1,50 @@
+package mcp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AskUserToolName 向用户提问的工具名。交互界面在执行前拦截该工具，
+// 显示问题并等待用户回答，回答作为工具结果返回给模型
+const AskUserToolName = "ask_user"
+
+// AskUserTool 需求不明确时向用户提问，而不是让模型猜测
+type AskUserTool struct{}
+
+func (t *AskUserTool) Name() string {
+	return AskUserToolName
+}
+
+func (t *AskUserTool) Description() string {
+	return "向用户提问并等待回答，用于需求不明确、有多种合理做法或需要用户做决定时澄清，而不是自行猜测。" +
+		"参数：question（问题，必填），options（可选的候选答案列表，用户也可以输入其他回答）。" +
+		"不要用它询问可以通过读取文件或搜索得到的信息，每次只问一个问题"
+}
+
+func (t *AskUserTool) GetSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"question": map[string]interface{}{
+				"type":        "string",
+				"description": "向用户提出的问题",
+			},
+			"options": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "候选答案（可选，最多 9 个）",
+			},
+		},
+		"required": []string{"question"},
+	}
+}
+
+// Execute 交互界面之外（如作为 MCP 服务运行时）没有可以回答问题的用户
+func (t *AskUserTool) Execute(args map[string]interface{}) (interface{}, error) {
+	question, _ := args["question"].(string)
+	if strings.TrimSpace(question) == "" {
+		return nil, fmt.Errorf("invalid argument: question is required")
+	}
+	return nil, fmt.Errorf("ask_user is only available in an interactive session; make a reasonable assumption and state it")
+}
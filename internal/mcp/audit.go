@@ -0,0 +1,207 @@
+package mcp
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/utils"
+)
+
+// auditTargetMaxRunes 审计记录中目标（路径或命令）的最大长度
+const auditTargetMaxRunes = 200
+
+// AuditEntry 一次工具调用的审计记录
+type AuditEntry struct {
+	Time     time.Time `json:"time"`
+	Tool     string    `json:"tool"`
+	ArgsHash string    `json:"args_hash"`
+	// Target 调用作用的路径或命令，便于回顾；完整参数只记录哈希
+	Target     string `json:"target,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+	Success    bool   `json:"success"`
+	Error      string `json:"error,omitempty"`
+	// ErrorCode/ErrorType 失败时的 MCP 错误码及类型
+	ErrorCode    int    `json:"error_code,omitempty"`
+	ErrorType    string `json:"error_type,omitempty"`
+	BytesWritten int64  `json:"bytes_written,omitempty"`
+}
+
+// AuditLog 每个会话一个只追加的 JSONL 审计文件
+type AuditLog struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+// NewAuditLog 在配置目录 logs/audit/ 下为会话创建审计文件
+func NewAuditLog(sessionID string) (*AuditLog, error) {
+	configDir, err := utils.GetConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(configDir, "logs", "audit")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create audit directory: %w", err)
+	}
+
+	path := filepath.Join(dir, sessionID+".jsonl")
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return &AuditLog{path: path, file: file}, nil
+}
+
+var (
+	sessionIDOnce sync.Once
+	sessionID     string
+)
+
+// SessionID 返回本进程的会话标识（按时间排序），用于审计日志和回收站目录
+func SessionID() string {
+	sessionIDOnce.Do(func() {
+		sessionID = fmt.Sprintf("%s-%d", time.Now().Format("20060102-150405"), os.Getpid())
+	})
+	return sessionID
+}
+
+// Path 审计文件路径
+func (a *AuditLog) Path() string {
+	return a.path
+}
+
+// Record 追加一条审计记录
+func (a *AuditLog) Record(entry AuditEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_, err = a.file.Write(append(data, '\n'))
+	return err
+}
+
+// Entries 读取本会话的全部审计记录，无法解析的行被跳过
+func (a *AuditLog) Entries() ([]AuditEntry, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	file, err := os.Open(a.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err == nil {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// Close 关闭审计文件
+func (a *AuditLog) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.file.Close()
+}
+
+// SetAuditLog 设置审计日志，nil 表示不记录
+func (r *ToolRegistry) SetAuditLog(log *AuditLog) {
+	r.audit = log
+}
+
+// AuditLog 返回当前审计日志，未启用时为 nil
+func (r *ToolRegistry) AuditLog() *AuditLog {
+	return r.audit
+}
+
+// recordAudit 记录一次工具调用，写入失败不影响工具结果
+func (r *ToolRegistry) recordAudit(req CallToolRequest, start time.Time, written int64, err error) {
+	if r.audit == nil {
+		return
+	}
+
+	entry := AuditEntry{
+		Time:       start,
+		Tool:       req.Name,
+		ArgsHash:   hashArguments(req.Arguments),
+		Target:     auditTarget(req.Arguments),
+		DurationMs: time.Since(start).Milliseconds(),
+		Success:    err == nil,
+	}
+	if err != nil {
+		toolErr := NewToolError(err)
+		entry.Error = toolErr.Message
+		entry.ErrorCode = toolErr.Code
+		entry.ErrorType = toolErr.Type
+	} else {
+		entry.BytesWritten = written
+	}
+	r.audit.Record(entry)
+}
+
+// hashArguments 计算参数的 SHA-256（JSON 对象键有序，结果稳定）
+func hashArguments(args map[string]interface{}) string {
+	data, err := json.Marshal(args)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// auditTarget 提取调用作用的路径或命令
+func auditTarget(args map[string]interface{}) string {
+	for _, key := range []string{"command", "file_path", "path", "destination", "source", "url", "query"} {
+		if value, ok := args[key].(string); ok && value != "" {
+			if key == "destination" {
+				if source, ok := args["source"].(string); ok {
+					value = source + " -> " + value
+				}
+			}
+			if truncated, ok := truncateRunes(value, auditTargetMaxRunes); ok {
+				value = truncated + "…"
+			}
+			return value
+		}
+	}
+	return ""
+}
+
+// bytesWritten 估算成功调用写入磁盘的字节数：写入类工具按内容长度，其余按目标文件（或目录树）写入后的大小
+func bytesWritten(tool string, args map[string]interface{}) int64 {
+	if tool == "write_file" || tool == "create_file" {
+		content, _ := args["content"].(string)
+		return int64(len(content))
+	}
+	path := writeTarget(tool, args)
+	if path == "" {
+		return 0
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	// 复制或移动整个目录时按目录树的总大小计算，否则会绕过写入预算
+	if info.IsDir() {
+		_, size := treeSize(path)
+		return size
+	}
+	return info.Size()
+}
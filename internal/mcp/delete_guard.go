@@ -0,0 +1,120 @@
+package mcp
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// DefaultDeleteConfirmFiles 递归删除超过该文件数时需要用户确认
+	DefaultDeleteConfirmFiles = 100
+	// DefaultDeleteConfirmBytes 递归删除超过该总大小时需要用户确认
+	DefaultDeleteConfirmBytes int64 = 10 * 1024 * 1024
+	// maxDeletionScan 统计时最多遍历的条目数，超出后停止并视为需要确认
+	maxDeletionScan = 50000
+	// deletionLargestFiles 报告中列出的最大文件数
+	deletionLargestFiles = 5
+)
+
+// importantNames 删除前需要特别提示的文件或目录（版本库、密钥和项目清单）
+var importantNames = map[string]bool{
+	".git": true, ".hg": true, ".svn": true,
+	".env": true, ".ssh": true, ".gnupg": true,
+	"id_rsa": true, "id_ed25519": true,
+	"go.mod": true, "package.json": true, "Cargo.toml": true, "pyproject.toml": true,
+}
+
+// FileSize 文件路径及大小
+type FileSize struct {
+	Path string
+	Size int64
+}
+
+// DeletionReport 递归删除前对目录的统计
+type DeletionReport struct {
+	Path       string
+	Files      int
+	TotalBytes int64
+	// Important 将被删除的重要文件或目录（相对路径）
+	Important []string
+	// Largest 最大的几个文件（相对路径）
+	Largest []FileSize
+	// Truncated 条目过多，统计未完成
+	Truncated bool
+}
+
+// InspectDeletion 统计将被递归删除的目录内容，path 不是目录时返回 nil
+func InspectDeletion(path string) (*DeletionReport, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, nil
+	}
+
+	report := &DeletionReport{Path: path}
+	scanned := 0
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// 无法读取的子目录不影响其余统计
+			return nil
+		}
+		scanned++
+		if scanned > maxDeletionScan {
+			report.Truncated = true
+			return filepath.SkipAll
+		}
+
+		rel, _ := filepath.Rel(path, p)
+		if p != path && importantNames[d.Name()] {
+			report.Important = append(report.Important, rel)
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		report.Files++
+		if info, err := d.Info(); err == nil && info.Mode().IsRegular() {
+			report.TotalBytes += info.Size()
+			report.addLargest(FileSize{Path: rel, Size: info.Size()})
+		}
+		return nil
+	})
+	return report, err
+}
+
+// addLargest 维护按大小降序的前几个文件
+func (r *DeletionReport) addLargest(file FileSize) {
+	r.Largest = append(r.Largest, file)
+	sort.Slice(r.Largest, func(i, j int) bool { return r.Largest[i].Size > r.Largest[j].Size })
+	if len(r.Largest) > deletionLargestFiles {
+		r.Largest = r.Largest[:deletionLargestFiles]
+	}
+}
+
+// NeedsApproval 超过阈值、包含重要文件或统计未完成时需要用户确认
+// 阈值为 0 时使用默认值，负数表示该项不限制
+func (r *DeletionReport) NeedsApproval(maxFiles int, maxBytes int64) bool {
+	if maxFiles == 0 {
+		maxFiles = DefaultDeleteConfirmFiles
+	}
+	if maxBytes == 0 {
+		maxBytes = DefaultDeleteConfirmBytes
+	}
+	return r.Truncated || len(r.Important) > 0 ||
+		(maxFiles > 0 && r.Files > maxFiles) ||
+		(maxBytes > 0 && r.TotalBytes > maxBytes)
+}
+
+// ImportantSummary 以逗号分隔列出重要文件，最多 limit 个
+func (r *DeletionReport) ImportantSummary(limit int) string {
+	items := r.Important
+	if len(items) > limit {
+		items = append(append([]string(nil), items[:limit]...), "...")
+	}
+	return strings.Join(items, ", ")
+}
@@ -0,0 +1,206 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/utils"
+)
+
+// EditLinesTool 按行号插入、删除或替换文件内容，可要求修改前某些行包含指定文本。
+// 修改先应用到编辑会话（记录在操作日志中，可随会话回退），再经 FileEngine 写入磁盘
+type EditLinesTool struct {
+	engine *FileEngine
+	mu     sync.Mutex
+	editor *utils.Editor
+}
+
+// NewEditLinesTool 创建按行编辑工具
+func NewEditLinesTool(engine *FileEngine, editor *utils.Editor) *EditLinesTool {
+	return &EditLinesTool{engine: engine, editor: editor}
+}
+
+// SetEditor 改用界面的编辑会话，使工具的修改与 /save、会话回退共用同一份操作日志
+func (t *EditLinesTool) SetEditor(editor *utils.Editor) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.editor = editor
+}
+
+func (t *EditLinesTool) Name() string {
+	return "edit_lines"
+}
+
+func (t *EditLinesTool) Description() string {
+	return "Insert, delete or replace whole lines by 1-based line number. Use expect=[{line, contains}] to verify the lines still hold the text you saw; the edit is refused if they do not. Line numbers refer to the file before this edit. The file must be read with read_file first."
+}
+
+func (t *EditLinesTool) GetSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"file_path": map[string]interface{}{
+				"type":        "string",
+				"description": "Absolute path to the file",
+			},
+			"operation": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"insert", "delete", "replace"},
+				"description": "insert: add content before start_line (use line count + 1 to append); delete/replace: lines start_line..end_line",
+			},
+			"start_line": map[string]interface{}{
+				"type":        "integer",
+				"description": "First line (1-based)",
+			},
+			"end_line": map[string]interface{}{
+				"type":        "integer",
+				"description": "Last line, inclusive (defaults to start_line)",
+			},
+			"content": map[string]interface{}{
+				"type":        "string",
+				"description": "Lines to insert or to replace with; a trailing newline is added when missing",
+			},
+			"expect": map[string]interface{}{
+				"type":        "array",
+				"description": "Checks applied before editing, e.g. [{\"line\": 42, \"contains\": \"func main\"}]",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"line":     map[string]interface{}{"type": "integer"},
+						"contains": map[string]interface{}{"type": "string"},
+					},
+					"required": []string{"line", "contains"},
+				},
+			},
+			"backup": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Create backup before modification",
+				"default":     true,
+			},
+			"expected_fingerprint": expectedFingerprintSchema,
+		},
+		"required": []string{"file_path", "operation", "start_line"},
+	}
+}
+
+func (t *EditLinesTool) Execute(args map[string]interface{}) (interface{}, error) {
+	filePath, ok := args["file_path"].(string)
+	if !ok || filePath == "" {
+		return nil, fmt.Errorf("missing required parameter: file_path")
+	}
+	operation, _ := args["operation"].(string)
+	if operation != "insert" && operation != "delete" && operation != "replace" {
+		return nil, fmt.Errorf("invalid operation %q: must be insert, delete or replace", operation)
+	}
+	start := getIntArg(args, "start_line", 0)
+	if start < 1 {
+		return nil, fmt.Errorf("missing required parameter: start_line (1-based)")
+	}
+	end := getIntArg(args, "end_line", start)
+	content, hasContent := args["content"].(string)
+	if operation != "delete" && !hasContent {
+		return nil, fmt.Errorf("missing required parameter: content")
+	}
+	anchors, err := parseLineAnchors(args["expect"])
+	if err != nil {
+		return nil, err
+	}
+	backup := true
+	if b, ok := args["backup"].(bool); ok {
+		backup = b
+	}
+
+	// 先检查路径，根目录之外的文件不能通过指纹或读取检查的错误泄露是否存在及其内容摘要
+	if err := t.engine.ValidatePath(filePath); err != nil {
+		return nil, ConvertToMCPError(err)
+	}
+	if err := t.engine.CheckFingerprint(filePath, expectedFingerprint(args)); err != nil {
+		return nil, ConvertToMCPError(err)
+	}
+	if err := t.engine.RequireRead(filePath); err != nil {
+		return nil, ConvertToMCPError(err)
+	}
+	original, err := t.engine.ReadFile(filePath, true)
+	if err != nil {
+		return nil, ConvertToMCPError(fmt.Errorf("failed to read file: %w", err))
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// 文件可能被 FileEngine 之外的途径（如 shell 命令）修改过，先同步到编辑会话
+	key := utils.EditorKey(filePath)
+	if current, err := t.editor.GetFileContent(key); err == nil && current != string(original) {
+		if err := t.editor.SyncFile(key); err != nil {
+			return nil, ConvertToMCPError(fmt.Errorf("failed to load file: %w", err))
+		}
+	}
+
+	edits := len(t.editor.GetCurrentEdits())
+	switch operation {
+	case "insert":
+		err = t.editor.InsertLines(key, start, content, anchors...)
+	case "delete":
+		err = t.editor.DeleteLines(key, start, end, anchors...)
+	case "replace":
+		err = t.editor.ReplaceLines(key, start, end, content, anchors...)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	updated, _ := t.editor.GetFileContent(key)
+	if err := t.engine.WriteFile(filePath, []byte(updated), backup); err != nil {
+		// 写入失败时撤销编辑会话中的修改，避免与磁盘不一致
+		if undoErr := t.editor.UndoTo(edits); undoErr != nil {
+			t.editor.LoadFile(key)
+		}
+		return nil, ConvertToMCPError(fmt.Errorf("failed to write file: %w", err))
+	}
+	// 与界面共用会话时写入回调已同步过缓冲区，这里保证单独使用时也不会被当作未保存
+	t.editor.MarkSaved(key)
+
+	before, after := utils.LineCount(string(original)), utils.LineCount(updated)
+	result := map[string]interface{}{
+		"success":     true,
+		"file_path":   filePath,
+		"operation":   operation,
+		"total_lines": after,
+		// 之后的行号整体移动的行数，后续编辑需要据此调整
+		"line_delta": after - before,
+		// 之后继续编辑时作为 expected_fingerprint，不必重新读取
+		"fingerprint": t.engine.writtenFingerprint(filePath),
+	}
+	if operation != "delete" && content != "" {
+		result["new_lines"] = fmt.Sprintf("%d-%d", start, start+utils.LineCount(content)-1)
+	}
+
+	jsonResult, _ := json.Marshal(result)
+	return string(jsonResult), nil
+}
+
+// parseLineAnchors 解析 expect 参数
+func parseLineAnchors(value interface{}) ([]utils.LineAnchor, error) {
+	if value == nil {
+		return nil, nil
+	}
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid parameter: expect must be an array of {line, contains}")
+	}
+	anchors := make([]utils.LineAnchor, 0, len(items))
+	for _, item := range items {
+		fields, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid parameter: expect must be an array of {line, contains}")
+		}
+		contains, _ := fields["contains"].(string)
+		line := getIntArg(fields, "line", 0)
+		if line < 1 || contains == "" {
+			return nil, fmt.Errorf("invalid expect entry: line must be >= 1 and contains must be non-empty")
+		}
+		anchors = append(anchors, utils.LineAnchor{Line: line, Contains: contains})
+	}
+	return anchors, nil
+}
@@ -0,0 +1,99 @@
+package mcp
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/utils"
+)
+
+func TestEditLinesTool(t *testing.T) {
+	root := t.TempDir()
+	engine := newTestEngine(t, root)
+	editor := utils.NewEditor()
+	tool := NewEditLinesTool(engine, editor)
+
+	path := filepath.Join(root, "main.go")
+	original := "package main\r\n\r\nfunc main() {\r\n\tprintln(1)\r\n}\r\n"
+	if err := os.WriteFile(path, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	args := map[string]interface{}{
+		"file_path":  path,
+		"operation":  "replace",
+		"start_line": float64(4),
+		"content":    "\tprintln(1)\n\tprintln(2)",
+		"expect":     []interface{}{map[string]interface{}{"line": float64(4), "contains": "println(1)"}},
+		"backup":     false,
+	}
+	if _, err := tool.Execute(args); err == nil || !strings.Contains(err.Error(), "read_file") {
+		t.Fatalf("expected read requirement, got %v", err)
+	}
+	engine.MarkRead(path, []byte(original))
+
+	out, err := tool.Execute(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result map[string]interface{}
+	if err := json.Unmarshal([]byte(out.(string)), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result["line_delta"] != float64(1) || result["new_lines"] != "4-5" {
+		t.Errorf("result = %v", result)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != "package main\r\n\r\nfunc main() {\r\n\tprintln(1)\r\n\tprintln(2)\r\n}\r\n" {
+		t.Errorf("content = %q", data)
+	}
+	if len(editor.GetCurrentEdits()) != 2 {
+		t.Errorf("expected the edit in the operation log, got %d operations", len(editor.GetCurrentEdits()))
+	}
+
+	// 过期的行号被拒绝，文件不变
+	args = map[string]interface{}{
+		"file_path":  path,
+		"operation":  "delete",
+		"start_line": float64(3),
+		"expect":     []interface{}{map[string]interface{}{"line": float64(3), "contains": "println(2)"}},
+	}
+	if _, err := tool.Execute(args); err == nil || !strings.Contains(err.Error(), "第 5 行") {
+		t.Fatalf("expected anchor mismatch, got %v", err)
+	}
+	if after, _ := os.ReadFile(path); string(after) != string(data) {
+		t.Errorf("rejected edit changed the file: %q", after)
+	}
+
+	// 其他工具写入后以磁盘内容为准
+	changed := "package main\n"
+	if err := engine.WriteFile(path, []byte(changed), false); err != nil {
+		t.Fatal(err)
+	}
+	args = map[string]interface{}{"file_path": path, "operation": "insert", "start_line": float64(2), "content": "// end"}
+	if _, err := tool.Execute(args); err != nil {
+		t.Fatal(err)
+	}
+	if after, _ := os.ReadFile(path); string(after) != "package main\r\n// end\r\n" {
+		t.Errorf("content = %q", after)
+	}
+}
+
+func TestEditLinesArguments(t *testing.T) {
+	tool := NewEditLinesTool(newTestEngine(t, t.TempDir()), utils.NewEditor())
+	for _, args := range []map[string]interface{}{
+		{"operation": "delete", "start_line": float64(1)},
+		{"file_path": "a.go", "operation": "move", "start_line": float64(1)},
+		{"file_path": "a.go", "operation": "delete"},
+		{"file_path": "a.go", "operation": "insert", "start_line": float64(1)},
+		{"file_path": "a.go", "operation": "delete", "start_line": float64(1), "expect": "line 1"},
+		{"file_path": "a.go", "operation": "delete", "start_line": float64(1), "expect": []interface{}{map[string]interface{}{"line": float64(1)}}},
+	} {
+		if _, err := tool.Execute(args); err == nil {
+			t.Errorf("Execute(%v) should fail", args)
+		}
+	}
+}
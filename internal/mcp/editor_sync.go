@@ -0,0 +1,21 @@
+package mcp
+
+import "github.com/Zacy-Sokach/PolyAgent/internal/utils"
+
+// SetEditor 设置与文件写入同步的编辑会话：FileEngine 每次写入后以磁盘内容更新其缓冲区并记录差异，
+// 使 write_file、replace 等工具的修改也能随会话回退；edit_lines 直接在该会话中编辑
+func (r *ToolRegistry) SetEditor(editor *utils.Editor) {
+	r.editor.Store(editor)
+	if handler, ok := r.GetTool("edit_lines"); ok {
+		if tool, ok := handler.(*EditLinesTool); ok {
+			tool.SetEditor(editor)
+		}
+	}
+}
+
+// syncEditor FileEngine 的写入回调
+func (r *ToolRegistry) syncEditor(path string) {
+	if editor := r.editor.Load(); editor != nil {
+		editor.SyncFile(path)
+	}
+}
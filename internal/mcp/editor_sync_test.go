@@ -0,0 +1,47 @@
+package mcp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/utils"
+)
+
+func TestEngineWritesSyncEditor(t *testing.T) {
+	root := t.TempDir()
+	engine := newTestEngine(t, root)
+	registry := NewToolRegistry()
+	registry.Register(&ReplaceTool{engine: engine})
+	engine.OnChange(registry.syncEditor)
+
+	editor := utils.NewEditor()
+	registry.SetEditor(editor)
+
+	path := filepath.Join(root, "main.go")
+	if err := os.WriteFile(path, []byte("package main\n\nvar x = 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := editor.LoadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	engine.MarkRead(path, []byte("package main\n\nvar x = 1\n"))
+
+	if _, err := registry.HandleCallTool(CallToolRequest{Name: "replace", Arguments: map[string]interface{}{
+		"file_path":  path,
+		"old_string": "x = 1",
+		"new_string": "x = 2",
+		"backup":     false,
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := editor.GetFileContent(path); content != "package main\n\nvar x = 2\n" {
+		t.Errorf("editor buffer = %q", content)
+	}
+	if edits := editor.GetCurrentEdits(); len(edits) != 2 {
+		t.Errorf("expected the write to be recorded as edits, got %+v", edits)
+	}
+	if dirty := editor.DirtyFiles(); len(dirty) != 0 {
+		t.Errorf("written file reported as unsaved: %v", dirty)
+	}
+}
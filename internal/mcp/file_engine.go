@@ -9,6 +9,7 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -16,6 +17,14 @@ import (
 type FileEngine struct {
 	cache  *fileCache
 	config *FileEngineConfig
+	// 保护 config.AllowedRoots，会话中可通过 AddRoot 添加根目录
+	rootsMu sync.RWMutex
+	// 文件写入后的回调（如项目索引），路径为绝对路径
+	listenersMu sync.RWMutex
+	listeners   []func(path string)
+	// 本次会话中已通过 read_file 读取（或由工具写入）的文件快照，键为规范化的绝对路径
+	readMu    sync.Mutex
+	readFiles map[string]readSnapshot
 }
 
 // FileEngineConfig 文件引擎配置
@@ -50,7 +59,8 @@ func NewFileEngine(config *FileEngineConfig) *FileEngine {
 	}
 	
 	engine := &FileEngine{
-		config: config,
+		config:    config,
+		readFiles: make(map[string]readSnapshot),
 	}
 	
 	if config.EnableCache {
@@ -61,46 +71,94 @@ func NewFileEngine(config *FileEngineConfig) *FileEngine {
 }
 
 // ValidatePath 验证路径是否允许访问
+// 路径逐级解析符号链接（不存在的部分按字面拼接），再按目录边界判断是否位于允许的根目录内
 func (e *FileEngine) ValidatePath(path string) error {
-	absPath, err := filepath.Abs(path)
+	realPath, err := resolvePath(path)
 	if err != nil {
 		return fmt.Errorf("invalid path: %w", err)
 	}
-	
-	// 解析符号链接，防止路径遍历
-	realPath, err := filepath.EvalSymlinks(absPath)
-	if err != nil && !os.IsNotExist(err) {
-		return fmt.Errorf("failed to evaluate symlinks: %w", err)
-	}
-	if err == nil {
-		absPath = realPath
-	}
-	
+
 	// 检查是否在允许的根目录内
 	allowed := false
-	for _, root := range e.config.AllowedRoots {
-		absRoot, _ := filepath.Abs(root)
-		if strings.HasPrefix(absPath, absRoot) {
+	for _, root := range e.Roots() {
+		realRoot, err := resolvePath(root)
+		if err != nil {
+			continue
+		}
+		if pathWithin(realRoot, realPath) {
 			allowed = true
 			break
 		}
 	}
-	
+
 	if !allowed {
 		return fmt.Errorf("path outside allowed roots: %s", path)
 	}
-	
-	// 检查文件扩展名
-	ext := strings.ToLower(filepath.Ext(absPath))
-	for _, blacklisted := range e.config.BlacklistedExts {
-		if ext == blacklisted {
-			return fmt.Errorf("file type not allowed: %s", ext)
+
+	// 检查文件扩展名（请求的路径和符号链接指向的路径都检查）
+	for _, p := range []string{path, realPath} {
+		ext := strings.ToLower(filepath.Ext(p))
+		for _, blacklisted := range e.config.BlacklistedExts {
+			if ext == blacklisted {
+				return fmt.Errorf("file type not allowed: %s", ext)
+			}
 		}
 	}
-	
+
 	return nil
 }
 
+// resolvePath 将路径转换为解析过符号链接的绝对路径，路径不存在时也能得到其真实位置
+// 逐个路径段解析，使 ".." 与操作系统的语义一致（作用于符号链接的目标而不是链接本身）
+func resolvePath(path string) (string, error) {
+	if !filepath.IsAbs(path) {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return "", err
+		}
+		// 不使用 filepath.Join，避免在解析符号链接前按字面消去 ".."
+		path = cwd + string(filepath.Separator) + path
+	}
+
+	volume := filepath.VolumeName(path)
+	parts := strings.FieldsFunc(path[len(volume):], func(r rune) bool {
+		return os.IsPathSeparator(uint8(r))
+	})
+
+	resolved := volume + string(filepath.Separator)
+	for i, part := range parts {
+		switch part {
+		case ".":
+			continue
+		case "..":
+			resolved = filepath.Dir(resolved)
+			continue
+		}
+
+		next := filepath.Join(resolved, part)
+		real, err := filepath.EvalSymlinks(next)
+		if err == nil {
+			resolved = real
+			continue
+		}
+		if os.IsNotExist(err) {
+			// 剩余部分尚不存在，其中不可能有符号链接，按字面拼接
+			return filepath.Join(append([]string{resolved}, parts[i:]...)...), nil
+		}
+		return "", fmt.Errorf("failed to evaluate symlinks: %w", err)
+	}
+	return resolved, nil
+}
+
+// pathWithin 判断 path 是否为 root 本身或位于 root 之下（按目录边界，/project-evil 不属于 /project）
+func pathWithin(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || filepath.IsAbs(rel) {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
 // ReadFile 读取文件内容（带缓存）
 func (e *FileEngine) ReadFile(path string, forceRefresh bool) ([]byte, error) {
 	if err := e.ValidatePath(path); err != nil {
@@ -110,8 +168,10 @@ func (e *FileEngine) ReadFile(path string, forceRefresh bool) ([]byte, error) {
 	// 检查缓存（如果未强制刷新）
 	if !forceRefresh && e.cache != nil {
 		if content, hit := e.cache.get(path); hit {
+			e.cache.hits.Add(1)
 			return content, nil
 		}
+		e.cache.misses.Add(1)
 	}
 	
 	// 检查文件大小
@@ -121,7 +181,7 @@ func (e *FileEngine) ReadFile(path string, forceRefresh bool) ([]byte, error) {
 	}
 	
 	if info.Size() > e.config.MaxFileSize {
-		return nil, fmt.Errorf("file too large: %s (%.2f MB)", path, float64(info.Size())/1024/1024)
+		return nil, fmt.Errorf("file too large: %s (%.2f MB, limit %.2f MB)", path, float64(info.Size())/1024/1024, float64(e.config.MaxFileSize)/1024/1024)
 	}
 	
 	content, err := os.ReadFile(path)
@@ -138,10 +198,15 @@ func (e *FileEngine) ReadFile(path string, forceRefresh bool) ([]byte, error) {
 }
 
 // WriteFile 写入文件（带备份）
+// 覆盖已有文本文件时保留其权限、换行符（CRLF/LF）、结尾换行和 BOM，写入的内容记录为模型已读
 func (e *FileEngine) WriteFile(path string, content []byte, backup bool) error {
 	if err := e.ValidatePath(path); err != nil {
 		return err
 	}
+
+	if original, err := os.ReadFile(path); err == nil {
+		content = preserveTextFormat(original, content)
+	}
 	
 	// 创建备份
 	if backup {
@@ -150,9 +215,11 @@ func (e *FileEngine) WriteFile(path string, content []byte, backup bool) error {
 		}
 	}
 	
-	// 使用临时文件保证原子性
+	// 使用临时文件保证原子性，临时文件沿用原文件权限，避免替换后丢失可执行位
+	mode, _ := existingFileMode(path)
 	tempFile := path + ".tmp"
-	if err := os.WriteFile(tempFile, content, 0644); err != nil {
+	if err := writeFileWithMode(tempFile, content, mode); err != nil {
+		os.Remove(tempFile)
 		return err
 	}
 	
@@ -166,10 +233,33 @@ func (e *FileEngine) WriteFile(path string, content []byte, backup bool) error {
 	if e.cache != nil {
 		e.cache.set(path, content)
 	}
+	e.MarkRead(path, content)
+	
+	e.notifyChange(path)
 	
 	return nil
 }
 
+// OnChange 注册文件写入后的回调
+func (e *FileEngine) OnChange(fn func(path string)) {
+	e.listenersMu.Lock()
+	defer e.listenersMu.Unlock()
+	e.listeners = append(e.listeners, fn)
+}
+
+// notifyChange 通知所有回调文件已变化
+func (e *FileEngine) notifyChange(path string) {
+	if absPath, err := filepath.Abs(path); err == nil {
+		path = absPath
+	}
+
+	e.listenersMu.RLock()
+	defer e.listenersMu.RUnlock()
+	for _, fn := range e.listeners {
+		fn(path)
+	}
+}
+
 // createBackup 创建文件备份
 func (e *FileEngine) createBackup(path string) error {
 	content, err := os.ReadFile(path)
@@ -187,15 +277,27 @@ func (e *FileEngine) createBackup(path string) error {
 	}
 	
 	// 生成备份文件名
-	hash := sha256.Sum256([]byte(path))
 	timestamp := time.Now().Format("20060102-150405")
-	backupName := fmt.Sprintf("%s-%x-%s.backup", 
-		filepath.Base(path), hash[:8], timestamp)
+	backupName := backupPrefix(path) + timestamp + ".backup"
 	backupPath := filepath.Join(backupDir, backupName)
 	
 	return os.WriteFile(backupPath, content, 0644)
 }
 
+// ContentHash 计算文件内容的哈希（sha256 十六进制）
+func ContentHash(content []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(content))
+}
+
+// FileHash 从磁盘读取文件并返回内容哈希
+func (e *FileEngine) FileHash(path string) (string, error) {
+	content, err := e.ReadFile(path, true)
+	if err != nil {
+		return "", err
+	}
+	return ContentHash(content), nil
+}
+
 // FileWalker 文件遍历器
 type FileWalker struct {
 	engine      *FileEngine
@@ -204,6 +306,7 @@ type FileWalker struct {
 	exclude     string
 	maxDepth    int
 	currentDepth int
+	skipDir     func(name string) bool
 }
 
 // NewFileWalker 创建文件遍历器
@@ -222,11 +325,20 @@ func (w *FileWalker) SetMaxDepth(depth int) {
 	w.maxDepth = depth
 }
 
+// SetSkipDir 设置跳过目录的判断（按目录名），根目录本身不会被跳过
+func (w *FileWalker) SetSkipDir(fn func(name string) bool) {
+	w.skipDir = fn
+}
+
 // Walk 遍历文件并执行回调
 func (w *FileWalker) Walk(fn func(path string, info fs.FileInfo) error) error {
 	return filepath.Walk(w.root, func(path string, info fs.FileInfo, err error) error {
 		if err != nil {
-			return err
+			// 根目录不可读时报错，其下无法访问的条目直接跳过
+			if path == w.root {
+				return err
+			}
+			return nil
 		}
 		
 		// 深度检查
@@ -246,6 +358,9 @@ func (w *FileWalker) Walk(fn func(path string, info fs.FileInfo) error) error {
 		
 		// 跳过目录
 		if info.IsDir() {
+			if w.skipDir != nil && path != w.root && w.skipDir(info.Name()) {
+				return filepath.SkipDir
+			}
 			return nil
 		}
 		
@@ -279,6 +394,8 @@ type fileCache struct {
 	mu    sync.RWMutex
 	items map[string]*cacheItem
 	maxSize int
+	// ReadFile 的命中和未命中次数，用于会话统计
+	hits, misses atomic.Int64
 }
 
 type cacheItem struct {
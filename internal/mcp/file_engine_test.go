@@ -0,0 +1,164 @@
+package mcp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newTestEngine 创建以 root 为唯一允许目录的文件引擎
+func newTestEngine(t *testing.T, root string) *FileEngine {
+	t.Helper()
+	config := DefaultConfig()
+	config.AllowedRoots = []string{root}
+	config.BackupDir = filepath.Join(t.TempDir(), "backups")
+	return NewFileEngine(config)
+}
+
+// symlinkOrSkip 创建符号链接，平台不支持时跳过测试
+func symlinkOrSkip(t *testing.T, target, link string) {
+	t.Helper()
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+}
+
+func TestValidatePathContainment(t *testing.T) {
+	base := t.TempDir()
+	root := filepath.Join(base, "project")
+	sibling := filepath.Join(base, "project-evil")
+	for _, dir := range []string{filepath.Join(root, "src"), sibling} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, "src", "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	engine := newTestEngine(t, root)
+	tests := []struct {
+		name    string
+		path    string
+		allowed bool
+	}{
+		{"existing file", filepath.Join(root, "src", "main.go"), true},
+		{"root itself", root, true},
+		{"new file in existing dir", filepath.Join(root, "src", "new.go"), true},
+		{"new file in new nested dir", filepath.Join(root, "a", "b", "c.go"), true},
+		{"sibling with shared prefix", filepath.Join(sibling, "x.go"), false},
+		{"parent traversal", filepath.Join(root, "src", "..", "..", "project-evil", "x.go"), false},
+		{"raw dotdot string", root + string(filepath.Separator) + ".." + string(filepath.Separator) + "outside.txt", false},
+		{"traversal through nonexistent dir", filepath.Join(root, "new", "..", "..", "outside.txt"), false},
+		{"dotdot staying inside", filepath.Join(root, "src", "..", "README.md"), true},
+		{"blacklisted extension", filepath.Join(root, "tool.exe"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := engine.ValidatePath(tt.path)
+			if tt.allowed && err != nil {
+				t.Errorf("ValidatePath(%q) = %v, want allowed", tt.path, err)
+			}
+			if !tt.allowed && err == nil {
+				t.Errorf("ValidatePath(%q) allowed, want rejected", tt.path)
+			}
+		})
+	}
+}
+
+func TestValidatePathSymlinks(t *testing.T) {
+	base := t.TempDir()
+	root := filepath.Join(base, "project")
+	outside := filepath.Join(base, "outside")
+	for _, dir := range []string{filepath.Join(root, "sub"), filepath.Join(outside, "nested")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(outside, "secret.txt"), []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(outside, "payload.exe"), []byte("MZ"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	symlinkOrSkip(t, outside, filepath.Join(root, "escape"))
+	symlinkOrSkip(t, filepath.Join(outside, "secret.txt"), filepath.Join(root, "secret.txt"))
+	symlinkOrSkip(t, filepath.Join(outside, "nested"), filepath.Join(root, "deep"))
+	symlinkOrSkip(t, filepath.Join(outside, "payload.exe"), filepath.Join(root, "innocent.txt"))
+	symlinkOrSkip(t, filepath.Join(root, "sub"), filepath.Join(root, "alias"))
+
+	engine := newTestEngine(t, root)
+	tests := []struct {
+		name    string
+		path    string
+		allowed bool
+	}{
+		{"symlinked file pointing outside", filepath.Join(root, "secret.txt"), false},
+		{"existing file under symlinked dir", filepath.Join(root, "escape", "secret.txt"), false},
+		{"new file under symlinked dir", filepath.Join(root, "escape", "new.txt"), false},
+		{"new nested file under symlinked dir", filepath.Join(root, "escape", "x", "y.txt"), false},
+		{"dotdot after symlink follows target", filepath.Join(root, "deep") + string(filepath.Separator) + ".." + string(filepath.Separator) + "secret.txt", false},
+		{"symlink to blacklisted file", filepath.Join(root, "innocent.txt"), false},
+		{"symlink within root", filepath.Join(root, "alias", "new.go"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := engine.ValidatePath(tt.path)
+			if tt.allowed && err != nil {
+				t.Errorf("ValidatePath(%q) = %v, want allowed", tt.path, err)
+			}
+			if !tt.allowed && err == nil {
+				t.Errorf("ValidatePath(%q) allowed, want rejected", tt.path)
+			}
+		})
+	}
+}
+
+func TestValidatePathSymlinkedRoot(t *testing.T) {
+	base := t.TempDir()
+	real := filepath.Join(base, "real")
+	if err := os.MkdirAll(real, 0755); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(base, "link")
+	symlinkOrSkip(t, real, link)
+
+	// 根目录本身是符号链接时，通过链接或真实路径访问都应允许
+	engine := newTestEngine(t, link)
+	for _, path := range []string{
+		filepath.Join(link, "new.txt"),
+		filepath.Join(real, "new.txt"),
+		filepath.Join(link, "dir", "new.txt"),
+	} {
+		if err := engine.ValidatePath(path); err != nil {
+			t.Errorf("ValidatePath(%q) = %v, want allowed", path, err)
+		}
+	}
+	if err := engine.ValidatePath(filepath.Join(base, "other.txt")); err == nil {
+		t.Errorf("ValidatePath outside symlinked root allowed, want rejected")
+	}
+}
+
+func TestPathWithin(t *testing.T) {
+	sep := string(filepath.Separator)
+	root := sep + "project"
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{root, true},
+		{root + sep + "a", true},
+		{root + sep + "..a", true},
+		{root + "-evil", false},
+		{root + "-evil" + sep + "a", false},
+		{sep + "other", false},
+	}
+	for _, tt := range tests {
+		if got := pathWithin(root, tt.path); got != tt.want {
+			t.Errorf("pathWithin(%q, %q) = %v, want %v", root, tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package mcp
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// fingerprintLength 文件指纹取内容哈希的前多少位
+const fingerprintLength = 12
+
+// expectedFingerprintSchema 修改类工具的 expected_fingerprint 参数
+var expectedFingerprintSchema = map[string]interface{}{
+	"type":        "string",
+	"description": "Fingerprint from the read_file (or previous edit) result of the version you are editing; the edit is refused if the file has changed since",
+}
+
+// annotatedOutput 附带说明的工具结果，说明写在外部内容分隔块之外，文件内容无法伪造
+type annotatedOutput struct {
+	text string
+	note string
+}
+
+// FileFingerprint 文件内容的短哈希，随 read_file 结果返回，修改时用于确认模型编辑的是当前版本
+func FileFingerprint(content []byte) string {
+	return ContentHash(content)[:fingerprintLength]
+}
+
+// fingerprintNote read_file 结果末尾的指纹说明，包括内容哈希和修改时间
+func fingerprintNote(path string, content []byte) string {
+	fingerprint := FileFingerprint(content)
+	return fmt.Sprintf("[fingerprint: %s, mtime: %s] Pass expected_fingerprint=%q when modifying this file to make sure you are editing this version.",
+		fingerprint, fileMTime(path), fingerprint)
+}
+
+// fileMTime 文件的修改时间（UTC），无法读取时返回 unknown
+func fileMTime(path string) string {
+	if info, err := os.Stat(path); err == nil {
+		return info.ModTime().UTC().Format(time.RFC3339)
+	}
+	return "unknown"
+}
+
+// CheckFingerprint 修改文件前确认模型声明的指纹与磁盘上的当前内容一致，expected 为空时不检查。
+// 模型依据较早读到的内容（包括自己之前修改前的版本）编辑时返回明确的错误，而不是写入错位的修改
+func (e *FileEngine) CheckFingerprint(path, expected string) error {
+	expected = strings.ToLower(strings.TrimSpace(expected))
+	if expected == "" {
+		return nil
+	}
+	current, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("stale edit: %s no longer exists, but expected_fingerprint %s refers to an earlier version", path, expected)
+	}
+	if err != nil {
+		return err
+	}
+	// 也接受完整的哈希
+	if strings.HasPrefix(ContentHash(current), expected) && len(expected) >= fingerprintLength {
+		return nil
+	}
+
+	if e.cache != nil {
+		e.cache.set(path, current)
+	}
+	return fmt.Errorf("stale edit: %s is now at fingerprint %s (mtime %s), not %s; the file changed after the version you are editing, possibly by one of your own earlier edits. The write was refused. Re-read the file with read_file and re-apply your change",
+		path, FileFingerprint(current), fileMTime(path), expected)
+}
+
+// writtenFingerprint 工具写入文件后的指纹（写入时保留原有换行符等格式，与传入的内容可能不同）
+func (e *FileEngine) writtenFingerprint(path string) string {
+	snapshot, ok := e.readSnapshot(path)
+	if !ok {
+		return ""
+	}
+	return snapshot.hash[:fingerprintLength]
+}
+
+// expectedFingerprint 读取修改类工具的 expected_fingerprint 参数
+func expectedFingerprint(args map[string]interface{}) string {
+	fingerprint, _ := args["expected_fingerprint"].(string)
+	return fingerprint
+}
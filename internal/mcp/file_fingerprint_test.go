@@ -0,0 +1,93 @@
+package mcp
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/utils"
+)
+
+func TestFileFingerprints(t *testing.T) {
+	root := t.TempDir()
+	engine := newTestEngine(t, root)
+	registry := NewToolRegistry()
+	registry.Register(&ReadFileTool{engine: engine})
+	registry.Register(&ReplaceTool{engine: engine})
+	registry.Register(NewEditLinesTool(engine, utils.NewEditor()))
+
+	path := filepath.Join(root, "main.go")
+	if err := os.WriteFile(path, []byte("package main\n\nvar x = 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	call := func(name string, args map[string]interface{}) string {
+		t.Helper()
+		result, err := registry.HandleCallTool(CallToolRequest{Name: name, Arguments: args})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result.Content[0].Text
+	}
+
+	// 指纹写在文件内容的分隔块之外
+	text := call("read_file", map[string]interface{}{"path": path})
+	match := regexp.MustCompile(`<<<END_EXTERNAL_CONTENT id=\w+>>>\n\[fingerprint: ([0-9a-f]{12}), mtime: \d{4}-`).FindStringSubmatch(text)
+	if match == nil {
+		t.Fatalf("no fingerprint in read_file result:\n%s", text)
+	}
+	read := match[1]
+
+	var edited struct {
+		Fingerprint string `json:"fingerprint"`
+	}
+	text = call("replace", map[string]interface{}{"file_path": path, "old_string": "x = 1", "new_string": "x = 2", "backup": false, "expected_fingerprint": read})
+	if err := json.Unmarshal([]byte(text), &edited); err != nil || edited.Fingerprint == "" || edited.Fingerprint == read {
+		t.Fatalf("replace result = %s", text)
+	}
+
+	// 依据修改之前的版本再次编辑时拒绝，即使文件是模型自己改的
+	text = call("edit_lines", map[string]interface{}{"file_path": path, "operation": "replace", "start_line": 3, "content": "var x = 3", "backup": false, "expected_fingerprint": read})
+	if !strings.Contains(text, "stale edit") || !strings.Contains(text, edited.Fingerprint) {
+		t.Errorf("stale fingerprint accepted: %s", text)
+	}
+	text = call("edit_lines", map[string]interface{}{"file_path": path, "operation": "replace", "start_line": 3, "content": "var x = 3", "backup": false, "expected_fingerprint": edited.Fingerprint})
+	if content, _ := os.ReadFile(path); string(content) != "package main\n\nvar x = 3\n" {
+		t.Errorf("edit with current fingerprint failed: %s", text)
+	}
+}
+
+func TestFingerprintChecksStayInsideRoots(t *testing.T) {
+	root := t.TempDir()
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(outside, []byte("token=abc\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	engine := newTestEngine(t, root)
+
+	// 根目录之外的文件只返回路径错误，不返回其指纹、mtime 或是否已读取
+	tests := []struct {
+		tool ToolHandler
+		args map[string]interface{}
+	}{
+		{&WriteFileTool{engine: engine}, map[string]interface{}{"path": outside, "content": "x", "expected_fingerprint": "000000000000"}},
+		{&ReplaceTool{engine: engine}, map[string]interface{}{"file_path": outside, "old_string": "abc", "new_string": "x", "expected_fingerprint": "000000000000"}},
+		{NewEditLinesTool(engine, utils.NewEditor()), map[string]interface{}{"file_path": outside, "operation": "replace", "start_line": 1, "content": "x", "expected_fingerprint": "000000000000"}},
+		{&WriteFileTool{engine: engine}, map[string]interface{}{"path": outside, "content": "x"}},
+	}
+	for _, tt := range tests {
+		_, err := tt.tool.Execute(tt.args)
+		if err == nil || !strings.Contains(err.Error(), "outside allowed roots") {
+			t.Errorf("%s: err = %v, want a path error", tt.tool.Name(), err)
+			continue
+		}
+		if msg := err.Error(); strings.Contains(msg, FileFingerprint([]byte("token=abc\n"))) || strings.Contains(msg, "stale edit") || strings.Contains(msg, "has not been read") {
+			t.Errorf("%s: error leaks file state: %s", tt.tool.Name(), msg)
+		}
+	}
+	if content, _ := os.ReadFile(outside); string(content) != "token=abc\n" {
+		t.Errorf("file outside roots was modified: %q", content)
+	}
+}
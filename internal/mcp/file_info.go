@@ -0,0 +1,152 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// gitInfoTimeout 查询文件 git 状态的超时时间
+const gitInfoTimeout = 2 * time.Second
+
+// fileLanguageByExt 按扩展名识别文件语言（比符号提取支持的语言更全）
+var fileLanguageByExt = map[string]string{
+	".go": "go", ".py": "python", ".rb": "ruby", ".rs": "rust",
+	".js": "javascript", ".jsx": "javascript", ".mjs": "javascript", ".cjs": "javascript",
+	".ts": "typescript", ".tsx": "typescript",
+	".java": "java", ".kt": "kotlin", ".scala": "scala", ".swift": "swift",
+	".c": "c", ".h": "c", ".cc": "cpp", ".cpp": "cpp", ".hpp": "cpp", ".cs": "csharp",
+	".php": "php", ".lua": "lua", ".dart": "dart", ".ex": "elixir", ".exs": "elixir",
+	".sh": "shell", ".bash": "shell", ".zsh": "shell", ".ps1": "powershell",
+	".sql": "sql", ".html": "html", ".css": "css", ".scss": "scss", ".vue": "vue",
+	".json": "json", ".yaml": "yaml", ".yml": "yaml", ".toml": "toml", ".xml": "xml",
+	".md": "markdown", ".proto": "protobuf",
+}
+
+// fileLanguageByName 没有扩展名但名称固定的文件
+var fileLanguageByName = map[string]string{
+	"Makefile": "makefile", "Dockerfile": "dockerfile", "go.mod": "go-module",
+}
+
+// detectFileLanguage 按文件名、扩展名或 shebang 识别语言，未知时返回空字符串
+func detectFileLanguage(path string, head []byte) string {
+	name := filepath.Base(path)
+	if lang, ok := fileLanguageByName[name]; ok {
+		return lang
+	}
+	if lang, ok := fileLanguageByExt[strings.ToLower(filepath.Ext(name))]; ok {
+		return lang
+	}
+	if bytes.HasPrefix(head, []byte("#!")) {
+		line := string(head)
+		if i := strings.IndexByte(line, '\n'); i >= 0 {
+			line = line[:i]
+		}
+		for _, interp := range []struct{ name, lang string }{
+			{"python", "python"}, {"node", "javascript"}, {"ruby", "ruby"},
+			{"bash", "shell"}, {"zsh", "shell"}, {"sh", "shell"},
+		} {
+			if strings.Contains(line, interp.name) {
+				return interp.lang
+			}
+		}
+	}
+	return ""
+}
+
+// fileDetails 流式计算文件的 SHA-256、行数（二进制文件除外），并识别语言和是否为二进制文件
+func fileDetails(path string) (map[string]interface{}, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	hasher := sha256.New()
+	reader := bufio.NewReader(io.TeeReader(f, hasher))
+	head, _ := reader.Peek(512)
+	head = append([]byte(nil), head...)
+
+	lines, binary, err := countLines(reader)
+	if err != nil {
+		return nil, err
+	}
+	// 二进制文件不统计行数，但哈希需要读完整个文件
+	if _, err := io.Copy(io.Discard, reader); err != nil {
+		return nil, err
+	}
+
+	details := map[string]interface{}{
+		"sha256": hex.EncodeToString(hasher.Sum(nil)),
+		"binary": binary,
+	}
+	if !binary {
+		details["line_count"] = lines
+	}
+	if lang := detectFileLanguage(path, head); lang != "" {
+		details["language"] = lang
+	}
+	return details, nil
+}
+
+// gitFileStatus 返回文件是否被 git 跟踪以及是否有未提交的修改；不在仓库中或没有 git 时返回 nil
+func gitFileStatus(path string) map[string]interface{} {
+	ctx, cancel := context.WithTimeout(context.Background(), gitInfoTimeout)
+	defer cancel()
+
+	dir, name := filepath.Dir(path), filepath.Base(path)
+	if err := exec.CommandContext(ctx, "git", "-C", dir, "rev-parse", "--is-inside-work-tree").Run(); err != nil {
+		return nil
+	}
+
+	tracked := exec.CommandContext(ctx, "git", "-C", dir, "ls-files", "--error-unmatch", "--", name).Run() == nil
+	status := map[string]interface{}{"tracked": tracked, "dirty": false}
+	out, err := exec.CommandContext(ctx, "git", "-C", dir, "status", "--porcelain", "--", name).Output()
+	if err == nil {
+		if line := strings.TrimSpace(string(out)); line != "" {
+			status["dirty"] = true
+			status["status"] = strings.Fields(line)[0]
+		}
+	}
+	return status
+}
+
+// BackupCount 统计备份目录中属于该文件的备份数
+func (e *FileEngine) BackupCount(path string) int {
+	entries, err := os.ReadDir(e.config.BackupDir)
+	if err != nil {
+		return 0
+	}
+
+	// 备份名中的哈希取自写入时传入的路径，相对和绝对写法都要匹配
+	prefixes := []string{backupPrefix(path)}
+	if abs, err := filepath.Abs(path); err == nil && abs != path {
+		prefixes = append(prefixes, backupPrefix(abs))
+	}
+
+	count := 0
+	for _, entry := range entries {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(entry.Name(), prefix) && strings.HasSuffix(entry.Name(), ".backup") {
+				count++
+				break
+			}
+		}
+	}
+	return count
+}
+
+// backupPrefix 与 createBackup 的命名规则一致的备份文件名前缀
+func backupPrefix(path string) string {
+	hash := sha256.Sum256([]byte(path))
+	return fmt.Sprintf("%s-%x-", filepath.Base(path), hash[:8])
+}
@@ -0,0 +1,91 @@
+package mcp
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// defaultFileMode 新建文件的默认权限
+const defaultFileMode os.FileMode = 0644
+
+// executableBits 所有者、组和其他用户的可执行位
+const executableBits os.FileMode = 0111
+
+// parseFileMode 解析八进制权限字符串（"755"、"0755"、"0o755"）
+func parseFileMode(value string) (os.FileMode, error) {
+	value = strings.TrimSpace(value)
+	value = strings.TrimPrefix(strings.TrimPrefix(value, "0o"), "0O")
+	mode, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("invalid argument: mode must be an octal permission such as 0644 or 0755, got %q", value)
+	}
+	return os.FileMode(mode), nil
+}
+
+// existingFileMode 返回已存在文件的权限，文件不存在时返回默认权限
+func existingFileMode(path string) (os.FileMode, bool) {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return defaultFileMode, false
+	}
+	return info.Mode().Perm(), true
+}
+
+// writeFileWithMode 写入文件并设置精确权限（os.WriteFile 受 umask 影响且不修改已存在文件的权限）
+func writeFileWithMode(path string, content []byte, mode os.FileMode) error {
+	if err := os.WriteFile(path, content, mode); err != nil {
+		return err
+	}
+	return os.Chmod(path, mode)
+}
+
+// ChmodTool 修改文件权限工具，与其他文件工具一样只能修改允许的根目录内的文件
+type ChmodTool struct {
+	engine *FileEngine
+}
+
+func (t *ChmodTool) Name() string { return "chmod" }
+func (t *ChmodTool) Description() string {
+	return "修改文件权限（如 +x 使脚本可执行），Windows 上仅只读属性有效"
+}
+func (t *ChmodTool) GetSchema() map[string]interface{} { return ChmodSchema }
+
+func (t *ChmodTool) Execute(args map[string]interface{}) (interface{}, error) {
+	path, ok := args["path"].(string)
+	if !ok || path == "" {
+		return nil, fmt.Errorf("缺少或无效的path参数")
+	}
+	modeArg, ok := args["mode"].(string)
+	if !ok || modeArg == "" {
+		return nil, fmt.Errorf("缺少或无效的mode参数")
+	}
+	if err := t.engine.ValidatePath(path); err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	current := info.Mode().Perm()
+
+	var mode os.FileMode
+	switch strings.TrimSpace(modeArg) {
+	case "+x":
+		// 与 chmod +x 一致：只为已有读权限的用户添加执行权限
+		mode = current | (current&0444)>>2
+	case "-x":
+		mode = current &^ executableBits
+	default:
+		if mode, err = parseFileMode(modeArg); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := os.Chmod(path, mode); err != nil {
+		return nil, fmt.Errorf("修改权限失败: %w", err)
+	}
+	return fmt.Sprintf("%s 权限已从 %04o 修改为 %04o", path, current, mode), nil
+}
@@ -0,0 +1,38 @@
+package mcp
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestChmodToolRejectsPathsOutsideRoots(t *testing.T) {
+	root := t.TempDir()
+	tool := &ChmodTool{engine: newTestEngine(t, root)}
+
+	outside := filepath.Join(t.TempDir(), "secret.sh")
+	if err := os.WriteFile(outside, []byte("#!/bin/sh\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"/etc/passwd", outside, filepath.Join(root, "..", filepath.Base(filepath.Dir(outside)), "secret.sh")} {
+		if _, err := tool.Execute(map[string]interface{}{"path": path, "mode": "+x"}); err == nil || !strings.Contains(err.Error(), "outside allowed roots") {
+			t.Errorf("chmod %s: err = %v", path, err)
+		}
+	}
+	if info, _ := os.Stat(outside); info.Mode().Perm() != 0644 {
+		t.Errorf("file outside roots changed to %04o", info.Mode().Perm())
+	}
+
+	script := filepath.Join(root, "run.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tool.Execute(map[string]interface{}{"path": script, "mode": "+x"}); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := os.Stat(script); runtime.GOOS != "windows" && info.Mode().Perm() != 0755 {
+		t.Errorf("mode = %04o, want 0755", info.Mode().Perm())
+	}
+}
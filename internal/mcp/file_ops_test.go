@@ -0,0 +1,64 @@
+package mcp
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileOperationsStayInsideRoots(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	engine := newTestEngine(t, root)
+	for _, dir := range []string{root, outside} {
+		if err := os.MkdirAll(filepath.Join(dir, "pkg"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "pkg", "a.go"), []byte("package pkg\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	inside := func(name string) string { return filepath.Join(root, name) }
+	escaped := filepath.Join(root, "..", filepath.Base(outside), "pkg")
+
+	tests := []struct {
+		name string
+		tool ToolHandler
+		args map[string]interface{}
+	}{
+		{"move source", &MoveFileTool{engine: engine}, map[string]interface{}{"source": filepath.Join(outside, "pkg"), "destination": inside("stolen")}},
+		{"move destination", &MoveFileTool{engine: engine}, map[string]interface{}{"source": inside("pkg"), "destination": filepath.Join(outside, "moved")}},
+		{"copy source", &CopyFileTool{engine: engine}, map[string]interface{}{"source": escaped, "destination": inside("copied")}},
+		{"copy destination", &CopyFileTool{engine: engine}, map[string]interface{}{"source": inside("pkg"), "destination": filepath.Join(outside, "copied")}},
+		{"create", &CreateFileTool{engine: engine}, map[string]interface{}{"path": filepath.Join(outside, "new.go"), "content": "x"}},
+		{"delete", &DeleteFileTool{engine: engine}, map[string]interface{}{"path": escaped, "recursive": true}},
+	}
+	for _, tt := range tests {
+		if _, err := tt.tool.Execute(tt.args); err == nil || !strings.Contains(err.Error(), "outside allowed roots") {
+			t.Errorf("%s: err = %v", tt.name, err)
+		}
+	}
+
+	entries, _ := os.ReadDir(outside)
+	if len(entries) != 1 {
+		t.Errorf("directory outside roots was modified: %v", entries)
+	}
+	if _, err := os.Stat(filepath.Join(outside, "pkg", "a.go")); err != nil {
+		t.Errorf("file outside roots: %v", err)
+	}
+	if _, err := os.Stat(inside("stolen")); !os.IsNotExist(err) {
+		t.Errorf("moved into roots from outside: %v", err)
+	}
+
+	// 根目录内的操作不受影响
+	if _, err := (&CopyFileTool{engine: engine}).Execute(map[string]interface{}{"source": inside("pkg"), "destination": inside("pkg2")}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (&MoveFileTool{engine: engine}).Execute(map[string]interface{}{"source": inside("pkg2"), "destination": inside("pkg3")}); err != nil {
+		t.Fatal(err)
+	}
+	if content, err := os.ReadFile(inside("pkg3/a.go")); err != nil || string(content) != "package pkg\n" {
+		t.Errorf("copy then move = %q, %v", content, err)
+	}
+}
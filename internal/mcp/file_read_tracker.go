@@ -0,0 +1,96 @@
+package mcp
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/utils"
+)
+
+const (
+	// maxTrackedContent 超过该大小的文件只记录哈希，发生冲突时不附带差异
+	maxTrackedContent = 256 * 1024
+	// maxConflictDiffBytes 冲突错误中附带的差异最大长度
+	maxConflictDiffBytes = 16 * 1024
+)
+
+// readSnapshot 模型最近一次看到的文件内容
+type readSnapshot struct {
+	hash    string
+	content []byte // 文件过大时为 nil
+}
+
+// MarkRead 记录本次会话中模型已看过的文件内容（读取或由工具写入后调用）
+func (e *FileEngine) MarkRead(path string, content []byte) {
+	snapshot := readSnapshot{hash: ContentHash(content)}
+	if len(content) <= maxTrackedContent {
+		snapshot.content = append([]byte(nil), content...)
+	}
+	key := readTrackingKey(path)
+
+	e.readMu.Lock()
+	defer e.readMu.Unlock()
+	e.readFiles[key] = snapshot
+}
+
+// HasRead 该文件在本次会话中是否已被读取
+func (e *FileEngine) HasRead(path string) bool {
+	_, ok := e.readSnapshot(path)
+	return ok
+}
+
+// readSnapshot 返回最近一次读取的快照
+func (e *FileEngine) readSnapshot(path string) (readSnapshot, bool) {
+	key := readTrackingKey(path)
+
+	e.readMu.Lock()
+	defer e.readMu.Unlock()
+	snapshot, ok := e.readFiles[key]
+	return snapshot, ok
+}
+
+// RequireRead 修改已存在的文件前检查是否已读取，且读取后未被外部修改；新建文件不受限制
+func (e *FileEngine) RequireRead(path string) error {
+	current, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	snapshot, ok := e.readSnapshot(path)
+	if !ok {
+		return fmt.Errorf("file has not been read: call read_file on %s before modifying it", path)
+	}
+	if ContentHash(current) == snapshot.hash {
+		return nil
+	}
+
+	// 文件在读取后被外部修改：刷新缓存以便重新读取拿到磁盘内容，并附带差异供模型重新规划
+	if e.cache != nil {
+		e.cache.set(path, current)
+	}
+	msg := fmt.Sprintf("write conflict: %s changed since last read; the write was refused. Re-read the file (read_file with force_refresh=true) and re-apply your change", path)
+	if snapshot.content == nil || len(current) > maxTrackedContent {
+		return fmt.Errorf("%s", msg)
+	}
+	diff := utils.UnifiedDiff(path+" (last read)", path+" (on disk)", string(snapshot.content), string(current), 3)
+	if len(diff) > maxConflictDiffBytes {
+		diff = utils.TruncateBytes(diff, maxConflictDiffBytes) + "\n... (diff truncated)\n"
+	}
+	return fmt.Errorf("%s.\n\nChanges on disk since last read:\n%s", msg, diff)
+}
+
+// readTrackingKey 将路径规范化为绝对路径并解析符号链接，使不同写法指向同一记录
+func readTrackingKey(path string) string {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+	if realPath, err := filepath.EvalSymlinks(absPath); err == nil {
+		return realPath
+	}
+	return absPath
+}
@@ -0,0 +1,265 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// defaultStatsTopN file_stats 默认列出的最大/最长文件数
+	defaultStatsTopN = 10
+	// maxStatsTopN file_stats 最多列出的最大/最长文件数
+	maxStatsTopN = 100
+	// binarySniffSize 判断二进制文件时检查的字节数
+	binarySniffSize = 8 * 1024
+)
+
+// countLines 流式统计行数，不把整个文件读入内存；前 8KB 含 NUL 字节时视为二进制并停止统计
+func countLines(r io.Reader) (lines int, binary bool, err error) {
+	reader := bufio.NewReaderSize(r, 32*1024)
+	head, _ := reader.Peek(binarySniffSize)
+	if isBinaryContent(head) {
+		return 0, true, nil
+	}
+
+	last := byte('\n')
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := reader.Read(buf)
+		lines += bytes.Count(buf[:n], []byte{'\n'})
+		if n > 0 {
+			last = buf[n-1]
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return lines, false, err
+		}
+	}
+	// 最后一行没有换行符时也计入
+	if last != '\n' {
+		lines++
+	}
+	return lines, false, nil
+}
+
+// statsFile 单个文件的统计
+type statsFile struct {
+	Path  string `json:"path"`
+	Size  int64  `json:"size"`
+	Lines int    `json:"lines,omitempty"`
+}
+
+// statsGroup 按扩展名或目录汇总的统计
+type statsGroup struct {
+	Files int   `json:"files"`
+	Bytes int64 `json:"bytes"`
+	Lines int   `json:"lines"`
+}
+
+// FileStatsTool 统计目录下的文件数、大小和行数
+type FileStatsTool struct {
+	engine *FileEngine
+}
+
+func NewFileStatsTool(engine *FileEngine) *FileStatsTool {
+	return &FileStatsTool{engine: engine}
+}
+
+func (t *FileStatsTool) Name() string {
+	return "file_stats"
+}
+
+func (t *FileStatsTool) Description() string {
+	return "统计目录下的文件数、大小和代码行数，按扩展名和目录汇总，并列出最大和行数最多的文件；跳过二进制文件和依赖目录"
+}
+
+func (t *FileStatsTool) GetSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "统计的根目录，默认当前目录",
+			},
+			"include": map[string]interface{}{
+				"type":        "string",
+				"description": "只统计匹配该模式的文件名，如 *.go",
+			},
+			"exclude": map[string]interface{}{
+				"type":        "string",
+				"description": "排除匹配该模式的文件名",
+			},
+			"top_n": map[string]interface{}{
+				"type":        "integer",
+				"description": "列出的最大/最长文件数 (1-100，默认 10)",
+			},
+			"max_depth": map[string]interface{}{
+				"type":        "integer",
+				"description": "最大遍历深度，默认不限制",
+			},
+		},
+	}
+}
+
+func (t *FileStatsTool) Execute(args map[string]interface{}) (interface{}, error) {
+	root := "."
+	if p, ok := args["path"].(string); ok && p != "" {
+		root = p
+	}
+	include, _ := args["include"].(string)
+	exclude, _ := args["exclude"].(string)
+
+	topN := defaultStatsTopN
+	if n, ok := args["top_n"].(float64); ok && n > 0 {
+		topN = int(n)
+		if topN > maxStatsTopN {
+			topN = maxStatsTopN
+		}
+	}
+
+	walker := t.engine.NewFileWalker(root, include, exclude)
+	walker.SetSkipDir(skipIndexDir)
+	if depth, ok := args["max_depth"].(float64); ok && depth >= 0 {
+		walker.SetMaxDepth(int(depth))
+	}
+
+	// 遍历只收集路径，行数由多个 worker 并发统计
+	files := make(chan statsFile, 256)
+	results := make(chan statsFile, 256)
+	var walkErr error
+	go func() {
+		defer close(files)
+		walkErr = walker.Walk(func(path string, info fs.FileInfo) error {
+			if info.Mode().IsRegular() {
+				files <- statsFile{Path: path, Size: info.Size()}
+			}
+			return nil
+		})
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < runtime.NumCPU(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for file := range files {
+				f, err := os.Open(file.Path)
+				if err != nil {
+					continue
+				}
+				lines, binary, err := countLines(f)
+				f.Close()
+				if err != nil {
+					continue
+				}
+				if binary {
+					file.Lines = -1
+				} else {
+					file.Lines = lines
+				}
+				results <- file
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var all []statsFile
+	byExt := map[string]*statsGroup{}
+	byDir := map[string]*statsGroup{}
+	total := statsGroup{}
+	binaryFiles := 0
+	for file := range results {
+		rel, err := filepath.Rel(root, file.Path)
+		if err != nil {
+			rel = file.Path
+		}
+		file.Path = filepath.ToSlash(rel)
+
+		total.Files++
+		total.Bytes += file.Size
+		if file.Lines < 0 {
+			// 二进制文件计入文件数和大小，但不计行数
+			binaryFiles++
+			file.Lines = 0
+		}
+		total.Lines += file.Lines
+
+		ext := strings.ToLower(filepath.Ext(file.Path))
+		if ext == "" {
+			ext = "(none)"
+		}
+		addStats(byExt, ext, file)
+		dir := "."
+		if i := strings.IndexByte(file.Path, '/'); i >= 0 {
+			dir = file.Path[:i]
+		}
+		addStats(byDir, dir, file)
+		all = append(all, file)
+	}
+	if walkErr != nil {
+		return nil, fmt.Errorf("遍历目录失败: %w", walkErr)
+	}
+
+	result := map[string]interface{}{
+		"root":         root,
+		"total_files":  total.Files,
+		"total_bytes":  total.Bytes,
+		"total_lines":  total.Lines,
+		"binary_files": binaryFiles,
+		"by_extension": byExt,
+		"by_directory": byDir,
+		"largest":      topStatsFiles(all, topN, func(a, b statsFile) bool { return a.Size > b.Size }),
+		"longest":      topStatsFiles(all, topN, func(a, b statsFile) bool { return a.Lines > b.Lines }),
+	}
+
+	resultBytes, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("序列化结果失败: %w", err)
+	}
+	return string(resultBytes), nil
+}
+
+// addStats 将文件计入分组
+func addStats(groups map[string]*statsGroup, key string, file statsFile) {
+	group, ok := groups[key]
+	if !ok {
+		group = &statsGroup{}
+		groups[key] = group
+	}
+	group.Files++
+	group.Bytes += file.Size
+	group.Lines += file.Lines
+}
+
+// topStatsFiles 按 less 排序后返回前 n 个文件，排序相同时按路径
+func topStatsFiles(files []statsFile, n int, less func(a, b statsFile) bool) []statsFile {
+	sorted := append([]statsFile(nil), files...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if less(sorted[i], sorted[j]) {
+			return true
+		}
+		if less(sorted[j], sorted[i]) {
+			return false
+		}
+		return sorted[i].Path < sorted[j].Path
+	})
+	if len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
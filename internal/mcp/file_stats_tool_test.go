@@ -0,0 +1,31 @@
+package mcp
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCountLines(t *testing.T) {
+	tests := []struct {
+		content string
+		lines   int
+		binary  bool
+	}{
+		{"", 0, false},
+		{"one", 1, false},
+		{"one\n", 1, false},
+		{"one\ntwo", 2, false},
+		{"one\r\ntwo\r\n", 2, false},
+		{strings.Repeat("x\n", 100000), 100000, false},
+		{"ELF\x00\x01\n", 0, true},
+	}
+	for _, tt := range tests {
+		lines, binary, err := countLines(strings.NewReader(tt.content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if lines != tt.lines || binary != tt.binary {
+			t.Errorf("countLines(%.10q) = %d, %v; want %d, %v", tt.content, lines, binary, tt.lines, tt.binary)
+		}
+	}
+}
@@ -18,7 +18,7 @@ func (t *ReadFileTool) Name() string {
 }
 
 func (t *ReadFileTool) Description() string {
-	return "Read file content with caching support. Use force_refresh=true to skip cache."
+	return "Read file content with caching support. Use force_refresh=true to skip cache. The result ends with the file's fingerprint; pass it as expected_fingerprint when editing the file."
 }
 
 func (t *ReadFileTool) GetSchema() map[string]interface{} {
@@ -54,8 +54,9 @@ func (t *ReadFileTool) Execute(args map[string]interface{}) (interface{}, error)
 	if err != nil {
 		return nil, ConvertToMCPError(err)
 	}
+	t.engine.MarkRead(path, content)
 
-	return string(content), nil
+	return annotatedOutput{text: string(content), note: fingerprintNote(path, content)}, nil
 }
 
 // WriteFileTool 写入文件工具（基于 FileEngine）
@@ -68,7 +69,7 @@ func (t *WriteFileTool) Name() string {
 }
 
 func (t *WriteFileTool) Description() string {
-	return "Write content to file with automatic backup. Creates backup before overwriting."
+	return "Write content to file with automatic backup. Creates backup before overwriting. Existing files must be read with read_file first."
 }
 
 func (t *WriteFileTool) GetSchema() map[string]interface{} {
@@ -88,6 +89,7 @@ func (t *WriteFileTool) GetSchema() map[string]interface{} {
 				"description": "Create backup before writing",
 				"default":     true,
 			},
+			"expected_fingerprint": expectedFingerprintSchema,
 		},
 		"required": []string{"path", "content"},
 	}
@@ -109,14 +111,27 @@ func (t *WriteFileTool) Execute(args map[string]interface{}) (interface{}, error
 		backup = b
 	}
 
+	// 覆盖已有文件前必须先读取，且读取后未被外部修改，防止盲目覆盖
+	// 先检查路径，根目录之外的文件不能通过指纹或读取检查的错误泄露是否存在及其内容摘要
+	if err := t.engine.ValidatePath(path); err != nil {
+		return nil, ConvertToMCPError(err)
+	}
+	if err := t.engine.CheckFingerprint(path, expectedFingerprint(args)); err != nil {
+		return nil, ConvertToMCPError(err)
+	}
+	if err := t.engine.RequireRead(path); err != nil {
+		return nil, ConvertToMCPError(err)
+	}
+
 	err := t.engine.WriteFile(path, []byte(content), backup)
 	if err != nil {
 		return nil, ConvertToMCPError(err)
 	}
 
 	result := map[string]interface{}{
-		"success": true,
-		"path":    path,
+		"success":     true,
+		"path":        path,
+		"fingerprint": t.engine.writtenFingerprint(path),
 	}
 
 	if backup {
@@ -138,7 +153,7 @@ func (t *ReplaceTool) Name() string {
 }
 
 func (t *ReplaceTool) Description() string {
-	return "Replace text in file using string or regex matching. Creates backup before modification."
+	return "Replace text in file using string or regex matching. Creates backup before modification. The file must be read with read_file first."
 }
 
 func (t *ReplaceTool) GetSchema() map[string]interface{} {
@@ -167,6 +182,7 @@ func (t *ReplaceTool) GetSchema() map[string]interface{} {
 				"description": "Create backup before modification",
 				"default":     true,
 			},
+			"expected_fingerprint": expectedFingerprintSchema,
 		},
 		"required": []string{"file_path", "old_string", "new_string"},
 	}
@@ -198,8 +214,19 @@ func (t *ReplaceTool) Execute(args map[string]interface{}) (interface{}, error)
 		backup = b
 	}
 
-	// 读取文件内容
-	content, err := t.engine.ReadFile(filePath, false)
+	// 先检查路径，根目录之外的文件不能通过指纹或读取检查的错误泄露是否存在及其内容摘要
+	if err := t.engine.ValidatePath(filePath); err != nil {
+		return nil, ConvertToMCPError(err)
+	}
+	if err := t.engine.CheckFingerprint(filePath, expectedFingerprint(args)); err != nil {
+		return nil, ConvertToMCPError(err)
+	}
+	if err := t.engine.RequireRead(filePath); err != nil {
+		return nil, ConvertToMCPError(err)
+	}
+
+	// 读取文件内容（上面已确认磁盘内容与读取时一致，跳过可能过期的缓存）
+	content, err := t.engine.ReadFile(filePath, true)
 	if err != nil {
 		return nil, ConvertToMCPError(fmt.Errorf("failed to read file: %w", err))
 	}
@@ -228,6 +255,7 @@ func (t *ReplaceTool) Execute(args map[string]interface{}) (interface{}, error)
 		"success":     true,
 		"file_path":   filePath,
 		"replacements": strings.Count(string(content), oldString),
+		"fingerprint":  t.engine.writtenFingerprint(filePath),
 	}
 
 	jsonResult, _ := json.Marshal(result)
@@ -0,0 +1,378 @@
+package mcp
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	// defaultFindReplaceMatches find_and_replace 默认最多返回的匹配数
+	defaultFindReplaceMatches = 200
+	// maxReplaceBatches 内存中保留的匹配批次数
+	maxReplaceBatches = 20
+	// maxCandidatePreview 匹配预览的最大字符数
+	maxCandidatePreview = 200
+)
+
+// ReplaceCandidate 一处可替换的匹配，ID 形如 fr3.12（批次.序号）
+type ReplaceCandidate struct {
+	ID          string
+	Path        string
+	Line        int
+	Start, End  int
+	Replacement []byte
+	// Before/After 匹配所在行替换前后的内容
+	Before, After string
+}
+
+// replaceBatch 一次 find_and_replace 的结果，文件哈希用于在应用前检查文件是否被修改
+type replaceBatch struct {
+	ID          string
+	Pattern     string
+	Replacement string
+	Hashes      map[string]string
+	Candidates  []ReplaceCandidate
+}
+
+// FindAndReplaceTool 列出编号的匹配，由 apply_replacements 选择性应用
+type FindAndReplaceTool struct {
+	engine  *FileEngine
+	replace *GlobalReplaceTool
+
+	mu      sync.Mutex
+	batches []*replaceBatch
+	next    int
+}
+
+func NewFindAndReplaceTool(engine *FileEngine, replace *GlobalReplaceTool) *FindAndReplaceTool {
+	return &FindAndReplaceTool{engine: engine, replace: replace}
+}
+
+func (t *FindAndReplaceTool) Name() string {
+	return "find_and_replace"
+}
+
+func (t *FindAndReplaceTool) Description() string {
+	return "查找要替换的内容并返回带编号的匹配（替换前后对比），不修改文件；之后用 apply_replacements 传入要应用的编号"
+}
+
+func (t *FindAndReplaceTool) GetSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"pattern": map[string]interface{}{
+				"type":        "string",
+				"description": "要查找的内容，默认为正则表达式",
+			},
+			"replacement": map[string]interface{}{
+				"type":        "string",
+				"description": "替换内容，正则模式下可用 $1、${name} 引用分组",
+			},
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "搜索根目录，默认当前目录",
+			},
+			"literal": map[string]interface{}{
+				"type":        "boolean",
+				"description": "按字面量查找和替换",
+			},
+			"word": map[string]interface{}{
+				"type":        "boolean",
+				"description": "只匹配整词",
+			},
+			"case_insensitive": map[string]interface{}{
+				"type":        "boolean",
+				"description": "忽略大小写",
+			},
+			"include": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "只处理匹配的文件，如 *.go",
+			},
+			"exclude": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "排除匹配的文件或目录",
+			},
+			"max_matches": map[string]interface{}{
+				"type":        "integer",
+				"description": "最多返回的匹配数，默认 200",
+			},
+		},
+		"required": []string{"pattern", "replacement"},
+	}
+}
+
+func (t *FindAndReplaceTool) Execute(args map[string]interface{}) (interface{}, error) {
+	req, err := parseReplaceRequest(args)
+	if err != nil {
+		return nil, err
+	}
+	maxMatches := defaultFindReplaceMatches
+	if n, ok := args["max_matches"].(float64); ok && n > 0 {
+		maxMatches = int(n)
+	}
+
+	batch, truncated, err := t.find(req, maxMatches)
+	if err != nil {
+		return nil, err
+	}
+	if len(batch.Candidates) == 0 {
+		return "未找到匹配项", nil
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "批次 %s：%d 处匹配。调用 apply_replacements 并传入要应用的编号（或批次号 %s 表示全部）\n", batch.ID, len(batch.Candidates), batch.ID)
+	for _, c := range batch.Candidates {
+		fmt.Fprintf(&sb, "[%s] %s:%d\n  - %s\n  + %s\n", c.ID, c.Path, c.Line, c.Before, c.After)
+	}
+	if truncated {
+		fmt.Fprintf(&sb, "[truncated] 仅列出前 %d 处匹配，请缩小范围后再次查找\n", maxMatches)
+	}
+	return strings.TrimSuffix(sb.String(), "\n"), nil
+}
+
+// find 查找所有匹配并保存为新批次
+func (t *FindAndReplaceTool) find(req *replaceRequest, maxMatches int) (*replaceBatch, bool, error) {
+	re, err := buildSearchRegexp(req.Search)
+	if err != nil {
+		return nil, false, err
+	}
+	files, err := collectSearchFiles(t.engine, req.Root, req.Search)
+	if err != nil {
+		return nil, false, err
+	}
+
+	t.mu.Lock()
+	t.next++
+	batch := &replaceBatch{
+		ID:          "fr" + strconv.Itoa(t.next),
+		Pattern:     req.Search.Pattern,
+		Replacement: req.Replacement,
+		Hashes:      make(map[string]string),
+	}
+	t.mu.Unlock()
+
+	truncated := false
+	for _, path := range files {
+		content, err := os.ReadFile(path)
+		if err != nil || isBinaryContent(content) {
+			continue
+		}
+		locs := re.FindAllSubmatchIndex(content, -1)
+		if len(locs) == 0 {
+			continue
+		}
+		batch.Hashes[path] = ContentHash(content)
+		for _, loc := range locs {
+			if len(batch.Candidates) >= maxMatches {
+				truncated = true
+				break
+			}
+			replacement := []byte(req.Replacement)
+			if !req.Search.Literal {
+				replacement = re.Expand(nil, replacement, content, loc)
+			}
+			lineStart := bytes.LastIndexByte(content[:loc[0]], '\n') + 1
+			lineEnd := len(content)
+			if i := bytes.IndexByte(content[loc[1]:], '\n'); i >= 0 {
+				lineEnd = loc[1] + i
+			}
+			after := string(content[lineStart:loc[0]]) + string(replacement) + string(content[loc[1]:lineEnd])
+			batch.Candidates = append(batch.Candidates, ReplaceCandidate{
+				ID:          fmt.Sprintf("%s.%d", batch.ID, len(batch.Candidates)+1),
+				Path:        path,
+				Line:        bytes.Count(content[:loc[0]], []byte{'\n'}) + 1,
+				Start:       loc[0],
+				End:         loc[1],
+				Replacement: replacement,
+				Before:      previewLine(string(content[lineStart:lineEnd])),
+				After:       previewLine(after),
+			})
+		}
+		if truncated {
+			break
+		}
+	}
+
+	t.mu.Lock()
+	t.batches = append(t.batches, batch)
+	if len(t.batches) > maxReplaceBatches {
+		t.batches = t.batches[len(t.batches)-maxReplaceBatches:]
+	}
+	t.mu.Unlock()
+	return batch, truncated, nil
+}
+
+// previewLine 将多行匹配压缩为一行并截断
+func previewLine(s string) string {
+	s = strings.ReplaceAll(strings.TrimRight(s, "\r"), "\n", "⏎")
+	if utf8.RuneCountInString(s) > maxCandidatePreview {
+		s, _ = truncateRunes(s, maxCandidatePreview)
+		s += "…"
+	}
+	return s
+}
+
+// selectCandidates 根据编号选出要应用的匹配；编号为批次号时选择整个批次，所有编号须属于同一批次
+func (t *FindAndReplaceTool) selectCandidates(ids []string) (*replaceBatch, []ReplaceCandidate, error) {
+	if len(ids) == 0 {
+		return nil, nil, fmt.Errorf("缺少或无效的ids参数")
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var batch *replaceBatch
+	for _, id := range ids {
+		batchID := id
+		if i := strings.IndexByte(id, '.'); i >= 0 {
+			batchID = id[:i]
+		}
+		if batch != nil && batch.ID != batchID {
+			return nil, nil, fmt.Errorf("编号必须属于同一批次: %s 与 %s", batch.ID, batchID)
+		}
+		if batch == nil {
+			for _, b := range t.batches {
+				if b.ID == batchID {
+					batch = b
+				}
+			}
+			if batch == nil {
+				return nil, nil, fmt.Errorf("找不到批次 %s（可能已应用或已过期），请重新调用 find_and_replace", batchID)
+			}
+		}
+	}
+
+	wanted := make(map[string]bool, len(ids))
+	all := false
+	for _, id := range ids {
+		if id == batch.ID {
+			all = true
+		}
+		wanted[id] = true
+	}
+	var selected []ReplaceCandidate
+	for _, c := range batch.Candidates {
+		if all || wanted[c.ID] {
+			selected = append(selected, c)
+			delete(wanted, c.ID)
+		}
+	}
+	delete(wanted, batch.ID)
+	if len(wanted) > 0 {
+		missing := make([]string, 0, len(wanted))
+		for id := range wanted {
+			missing = append(missing, id)
+		}
+		sort.Strings(missing)
+		return nil, nil, fmt.Errorf("未知的编号: %s", strings.Join(missing, ", "))
+	}
+	return batch, selected, nil
+}
+
+// PreviewSelection 返回将要应用的匹配，供界面在审批时列出
+func (t *FindAndReplaceTool) PreviewSelection(ids []string) ([]ReplaceCandidate, error) {
+	_, selected, err := t.selectCandidates(ids)
+	return selected, err
+}
+
+// Apply 应用选中的匹配；查找后被修改过的文件会报错，整个批次在应用后失效
+func (t *FindAndReplaceTool) Apply(ids []string) (*ReplaceManifest, error) {
+	batch, selected, err := t.selectCandidates(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	byFile := make(map[string][]ReplaceCandidate)
+	var paths []string
+	for _, c := range selected {
+		if _, ok := byFile[c.Path]; !ok {
+			paths = append(paths, c.Path)
+		}
+		byFile[c.Path] = append(byFile[c.Path], c)
+	}
+
+	var changes []replaceChange
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("读取 %s 失败: %w", path, err)
+		}
+		if ContentHash(content) != batch.Hashes[path] {
+			return nil, fmt.Errorf("write conflict: %s 在 find_and_replace 之后被修改，请重新查找", path)
+		}
+
+		// 从后往前替换，前面匹配的偏移不受影响
+		candidates := byFile[path]
+		sort.Slice(candidates, func(i, j int) bool { return candidates[i].Start > candidates[j].Start })
+		updated := append([]byte(nil), content...)
+		for _, c := range candidates {
+			updated = append(updated[:c.Start], append(append([]byte(nil), c.Replacement...), updated[c.End:]...)...)
+		}
+		changes = append(changes, replaceChange{Path: path, Original: content, Updated: updated, Count: len(candidates)})
+	}
+
+	req := &replaceRequest{Search: searchOptions{Pattern: batch.Pattern}, Replacement: batch.Replacement}
+	manifest, err := t.replace.apply(req, changes)
+	if err != nil {
+		return nil, err
+	}
+
+	// 偏移已经失效，移除整个批次
+	t.mu.Lock()
+	for i, b := range t.batches {
+		if b == batch {
+			t.batches = append(t.batches[:i], t.batches[i+1:]...)
+			break
+		}
+	}
+	t.mu.Unlock()
+	return manifest, nil
+}
+
+// ApplyReplacementsTool 应用 find_and_replace 返回的部分匹配
+type ApplyReplacementsTool struct {
+	finder *FindAndReplaceTool
+}
+
+func NewApplyReplacementsTool(finder *FindAndReplaceTool) *ApplyReplacementsTool {
+	return &ApplyReplacementsTool{finder: finder}
+}
+
+func (t *ApplyReplacementsTool) Name() string {
+	return "apply_replacements"
+}
+
+func (t *ApplyReplacementsTool) Description() string {
+	return "应用 find_and_replace 返回的部分或全部匹配，可用 rollback_replace 撤销"
+}
+
+func (t *ApplyReplacementsTool) GetSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"ids": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "要应用的匹配编号（如 fr1.2），或批次号（如 fr1）表示全部",
+			},
+		},
+		"required": []string{"ids"},
+	}
+}
+
+func (t *ApplyReplacementsTool) Execute(args map[string]interface{}) (interface{}, error) {
+	manifest, err := t.finder.Apply(stringList(args["ids"]))
+	if err != nil {
+		return nil, err
+	}
+	return fmt.Sprintf("已在 %d 个文件中应用 %d 处替换，可用 rollback_replace 撤销（id: %s）",
+		len(manifest.Files), manifest.TotalReplacements, manifest.ID), nil
+}
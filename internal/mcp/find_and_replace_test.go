@@ -0,0 +1,53 @@
+package mcp
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindAndApplySelectedReplacements(t *testing.T) {
+	root := t.TempDir()
+	engine := newTestEngine(t, root)
+	finder := NewFindAndReplaceTool(engine, NewGlobalReplaceTool(engine, NewToolRegistry()))
+
+	path := filepath.Join(root, "a.go")
+	if err := os.WriteFile(path, []byte("v1 := get(1)\nv2 := get(2)\nv3 := get(3)\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	req := &replaceRequest{Search: searchOptions{Pattern: `get\((\d)\)`}, Root: root, Replacement: "fetch($1)"}
+	batch, _, err := finder.find(req, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batch.Candidates) != 3 || batch.Candidates[1].ID != batch.ID+".2" || batch.Candidates[1].After != "v2 := fetch(2)" {
+		t.Fatalf("candidates = %+v", batch.Candidates)
+	}
+
+	if _, err := finder.Apply([]string{batch.ID + ".1", batch.ID + ".3"}); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != "v1 := fetch(1)\nv2 := get(2)\nv3 := fetch(3)\n" {
+		t.Fatalf("content = %q", data)
+	}
+
+	// 批次应用后失效
+	if _, err := finder.Apply([]string{batch.ID + ".2"}); err == nil {
+		t.Error("expected applied batch to be gone")
+	}
+
+	// 查找后文件被修改时拒绝应用
+	batch, _, err = finder.find(req, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("changed get(2)\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := finder.Apply([]string{batch.ID}); err == nil || !strings.Contains(err.Error(), "write conflict") {
+		t.Errorf("expected conflict, got %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// findSymbolReadyTimeout 等待项目索引首次扫描完成的最长时间
+const findSymbolReadyTimeout = 30 * time.Second
+
+// FindSymbolTool 在项目索引中查找函数、类型等定义
+type FindSymbolTool struct {
+	project *ProjectIndex
+}
+
+// NewFindSymbolTool 创建符号查找工具
+func NewFindSymbolTool(project *ProjectIndex) *FindSymbolTool {
+	return &FindSymbolTool{project: project}
+}
+
+func (t *FindSymbolTool) Name() string {
+	return "find_symbol"
+}
+
+func (t *FindSymbolTool) Description() string {
+	return "按名称查找函数、方法、类型、类等定义的位置（基于项目索引，毫秒级返回）"
+}
+
+func (t *FindSymbolTool) GetSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"name": map[string]interface{}{
+				"type":        "string",
+				"description": "符号名称，支持部分匹配（不区分大小写）",
+			},
+			"kind": map[string]interface{}{
+				"type":        "string",
+				"description": "只返回该类型的符号（可选）",
+				"enum":        []string{"function", "method", "type", "class", "interface", "struct", "enum", "trait", "module", "constant", "variable"},
+			},
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "只在该目录下查找（相对项目根目录，可选）",
+			},
+			"limit": map[string]interface{}{
+				"type":        "integer",
+				"description": "最多返回数量 (默认 20)",
+				"default":     20,
+			},
+		},
+		"required": []string{"name"},
+	}
+}
+
+func (t *FindSymbolTool) Execute(args map[string]interface{}) (interface{}, error) {
+	name, ok := args["name"].(string)
+	if !ok || strings.TrimSpace(name) == "" {
+		return nil, fmt.Errorf("invalid argument: name is required")
+	}
+	name = strings.TrimSpace(name)
+	kind, _ := args["kind"].(string)
+	pathPrefix, _ := args["path"].(string)
+
+	limit := getIntArg(args, "limit", 20)
+	if limit < 1 {
+		limit = 1
+	} else if limit > 100 {
+		limit = 100
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), findSymbolReadyTimeout)
+	defer cancel()
+	if err := t.project.WaitReady(ctx); err != nil {
+		return nil, err
+	}
+
+	matches := t.project.FindSymbol(name, kind, pathPrefix, limit)
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("# 🔣 符号查找: %q\n\n", name))
+	if len(matches) == 0 {
+		builder.WriteString("未找到匹配的定义。\n")
+		return builder.String(), nil
+	}
+
+	for _, match := range matches {
+		builder.WriteString(fmt.Sprintf("- `%s` (%s) — %s:%d\n", match.Name, match.Kind, match.Path, match.Line))
+	}
+	return builder.String(), nil
+}
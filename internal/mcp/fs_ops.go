@@ -0,0 +1,149 @@
+package mcp
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// copyStats 一次复制或移动处理的文件数和字节数
+type copyStats struct {
+	Files int
+	Bytes int64
+}
+
+// movePath 移动文件或目录；跨设备（os.Rename 失败）时退化为复制后删除源
+func movePath(src, dst string) error {
+	_, err := movePathProgress(src, dst, nil)
+	return err
+}
+
+// movePathProgress 同 movePath，退化为复制时通过 progress 报告进度；返回的统计仅在复制时有效
+func movePathProgress(src, dst string, progress *progressReporter) (copyStats, error) {
+	renameErr := os.Rename(src, dst)
+	if renameErr == nil {
+		return copyStats{}, nil
+	}
+	// 源不存在或目标已存在时不尝试复制，直接返回原错误
+	if _, err := os.Lstat(src); err != nil {
+		return copyStats{}, renameErr
+	}
+	if _, err := os.Lstat(dst); err == nil {
+		return copyStats{}, renameErr
+	}
+
+	stats, err := copyPathProgress(src, dst, progress)
+	if err != nil {
+		os.RemoveAll(dst)
+		return stats, fmt.Errorf("%w (copy fallback failed: %v)", renameErr, err)
+	}
+	return stats, os.RemoveAll(src)
+}
+
+// copyPath 递归复制文件、目录或符号链接，保留权限和修改时间
+func copyPath(src, dst string) error {
+	_, err := copyPathProgress(src, dst, nil)
+	return err
+}
+
+// copyPathProgress 同 copyPath，每复制一个文件通过 progress 报告进度；目标已存在的目录会被合并
+func copyPathProgress(src, dst string, progress *progressReporter) (copyStats, error) {
+	var stats copyStats
+	err := copyTree(src, dst, progress, &stats)
+	return stats, err
+}
+
+// copyTree 递归复制的实现
+func copyTree(src, dst string, progress *progressReporter, stats *copyStats) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case info.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		os.Remove(dst)
+		if err := os.Symlink(target, dst); err != nil {
+			return err
+		}
+		stats.Files++
+		progress.add(0)
+		// 符号链接本身的时间无法可移植地设置
+		return nil
+
+	case info.IsDir():
+		if err := os.MkdirAll(dst, info.Mode().Perm()|0700); err != nil {
+			return err
+		}
+		entries, err := os.ReadDir(src)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := copyTree(filepath.Join(src, entry.Name()), filepath.Join(dst, entry.Name()), progress, stats); err != nil {
+				return err
+			}
+		}
+		if err := os.Chmod(dst, info.Mode().Perm()); err != nil {
+			return err
+		}
+
+	case info.Mode().IsRegular():
+		if err := copyFileContents(src, dst, info.Mode().Perm()); err != nil {
+			return err
+		}
+		stats.Files++
+		stats.Bytes += info.Size()
+		progress.add(info.Size())
+
+	default:
+		return fmt.Errorf("unsupported file type: %s", src)
+	}
+
+	return os.Chtimes(dst, info.ModTime(), info.ModTime())
+}
+
+// treeSize 统计目录下的文件数和总字节数，用于计算进度
+func treeSize(root string) (int, int64) {
+	var files int
+	var bytes int64
+	filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		files++
+		if info, err := d.Info(); err == nil && info.Mode().IsRegular() {
+			bytes += info.Size()
+		}
+		return nil
+	})
+	return files, bytes
+}
+
+// copyFileContents 流式复制单个文件，避免大文件整体读入内存
+func copyFileContents(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Chmod(dst, mode)
+}
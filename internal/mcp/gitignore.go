@@ -0,0 +1,130 @@
+package mcp
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// gitignoreRule .gitignore 中的一条规则
+type gitignoreRule struct {
+	segments []string
+	negate   bool
+	dirOnly  bool
+}
+
+// parseGitignore 解析 .gitignore 内容；不含 / 的模式可匹配任意层级，等价于 **/pattern
+func parseGitignore(content string) []gitignoreRule {
+	var rules []gitignoreRule
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := gitignoreRule{}
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+
+		anchored := strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+		rule.segments = splitGlobPattern(line)
+		if !anchored {
+			rule.segments = append([]string{"**"}, rule.segments...)
+		}
+		if len(rule.segments) > 0 {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// gitignoreSet 按目录惰性加载的 .gitignore 规则集合，包括搜索根目录到仓库根目录之间的上级目录
+type gitignoreSet struct {
+	mu sync.Mutex
+	// top 最上层加载规则的目录（仓库根目录，不在仓库中时为搜索根目录）
+	top   string
+	rules map[string][]gitignoreRule
+}
+
+// newGitignoreSet 创建以 root 为搜索根目录的规则集合
+func newGitignoreSet(root string) *gitignoreSet {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		abs = root
+	}
+	set := &gitignoreSet{top: abs, rules: make(map[string][]gitignoreRule)}
+	for dir := abs; ; {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			set.top = dir
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return set
+}
+
+// load 读取并缓存目录中的 .gitignore
+func (s *gitignoreSet) load(dir string) []gitignoreRule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if rules, ok := s.rules[dir]; ok {
+		return rules
+	}
+	var rules []gitignoreRule
+	if content, err := os.ReadFile(filepath.Join(dir, ".gitignore")); err == nil {
+		rules = parseGitignore(string(content))
+	}
+	s.rules[dir] = rules
+	return rules
+}
+
+// Ignored 判断绝对路径是否被忽略；后面的规则覆盖前面的，下层目录的规则覆盖上层的
+// 被忽略的目录应由调用方整体跳过，这里不检查上级目录本身是否被忽略
+func (s *gitignoreSet) Ignored(path string, isDir bool) bool {
+	if filepath.Base(path) == ".git" {
+		return true
+	}
+	rel, err := filepath.Rel(s.top, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return false
+	}
+
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	ignored := false
+	dir := s.top
+	for i := 0; i < len(parts); i++ {
+		segs := parts[i:]
+		for _, rule := range s.load(dir) {
+			if rule.dirOnly && !isDir {
+				continue
+			}
+			if rule.negate != ignored {
+				continue
+			}
+			if matchGlobSegments(rule.segments, segs, true, false) {
+				ignored = !rule.negate
+			}
+		}
+		dir = filepath.Join(dir, parts[i])
+	}
+	return ignored
+}
@@ -0,0 +1,51 @@
+package mcp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGitignoreSet(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	write := func(name, content string) {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(".gitignore", "# comment\n*.log\n!keep.log\n/build\ncache/\ndocs/**/*.tmp\n")
+	write("sub/.gitignore", "local.txt\n")
+
+	set := newGitignoreSet(filepath.Join(root, "sub"))
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"debug.log", false, true},
+		{"sub/deep/debug.log", false, true},
+		{"keep.log", false, false},
+		{"build", true, true},
+		{"sub/build", true, false},
+		{"cache", true, true},
+		{"cache", false, false},
+		{"docs/a/b/x.tmp", false, true},
+		{"x.tmp", false, false},
+		{"sub/local.txt", false, true},
+		{"local.txt", false, false},
+		{".git", true, true},
+		{"main.go", false, false},
+	}
+	for _, tt := range tests {
+		if got := set.Ignored(filepath.Join(root, filepath.FromSlash(tt.path)), tt.isDir); got != tt.want {
+			t.Errorf("Ignored(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
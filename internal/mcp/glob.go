@@ -0,0 +1,205 @@
+package mcp
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultGlobMaxResults glob 默认最多返回的结果数
+	defaultGlobMaxResults = 500
+	// maxGlobScan glob 最多遍历的条目数，防止在巨大的目录树上长时间运行
+	maxGlobScan = 200000
+)
+
+// globMatch 一个匹配的路径及其修改时间
+type globMatch struct {
+	path    string
+	modTime time.Time
+}
+
+// globOptions glob 搜索选项
+type globOptions struct {
+	CaseSensitive bool
+	// SortBy 为 "path"（默认）或 "mtime"（最近修改的在前）
+	SortBy     string
+	MaxResults int
+}
+
+// globResult glob 搜索结果
+type globResult struct {
+	Matches []string
+	// Total 匹配总数，Truncated 时大于 len(Matches)
+	Total     int
+	Truncated bool
+	// ScanLimited 遍历条目超过上限，结果可能不完整
+	ScanLimited bool
+}
+
+// splitGlobPattern 将模式按 / 拆分为段，去掉空段和 "."
+func splitGlobPattern(pattern string) []string {
+	var segments []string
+	for _, seg := range strings.Split(filepath.ToSlash(pattern), "/") {
+		if seg == "" || seg == "." {
+			continue
+		}
+		// 连续的 ** 等价于一个
+		if seg == "**" && len(segments) > 0 && segments[len(segments)-1] == "**" {
+			continue
+		}
+		segments = append(segments, seg)
+	}
+	return segments
+}
+
+// hasGlobMeta 判断段中是否包含通配符
+func hasGlobMeta(seg string) bool {
+	return strings.ContainsAny(seg, `*?[\`)
+}
+
+// matchGlobSegments 判断路径段是否匹配模式段，** 匹配零个或多个目录
+// prefix 为 true 时只要求 segs 能成为某个匹配路径的前缀，用于决定是否进入目录
+func matchGlobSegments(pattern, segs []string, caseSensitive, prefix bool) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			rest := pattern[1:]
+			if prefix {
+				return true
+			}
+			for i := 0; i <= len(segs); i++ {
+				if matchGlobSegments(rest, segs[i:], caseSensitive, false) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segs) == 0 {
+			return prefix
+		}
+		if !matchGlobSegment(pattern[0], segs[0], caseSensitive) {
+			return false
+		}
+		pattern, segs = pattern[1:], segs[1:]
+	}
+	return len(segs) == 0
+}
+
+// matchGlobSegment 匹配单个路径段
+func matchGlobSegment(pattern, name string, caseSensitive bool) bool {
+	if !caseSensitive {
+		pattern, name = strings.ToLower(pattern), strings.ToLower(name)
+	}
+	matched, err := path.Match(pattern, name)
+	return err == nil && matched
+}
+
+// globFiles 在 root 下按支持 ** 的模式搜索文件和目录
+func globFiles(root, pattern string, opts globOptions) (*globResult, error) {
+	segments := splitGlobPattern(pattern)
+	if filepath.IsAbs(pattern) {
+		volume := filepath.VolumeName(pattern)
+		root = volume + string(filepath.Separator)
+		segments = splitGlobPattern(pattern[len(volume):])
+	}
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("无效的glob模式: %q", pattern)
+	}
+	for _, seg := range segments {
+		if _, err := path.Match(seg, ""); err != nil {
+			return nil, fmt.Errorf("无效的glob模式 %q: %w", pattern, err)
+		}
+	}
+
+	// 不含通配符的前缀直接拼到根目录上，避免遍历无关目录
+	// 不区分大小写时仅在该目录按原样存在时才这样做
+	base := root
+	for len(segments) > 1 && !hasGlobMeta(segments[0]) {
+		next := filepath.Join(base, segments[0])
+		if !opts.CaseSensitive {
+			if info, err := os.Stat(next); err != nil || !info.IsDir() {
+				break
+			}
+		}
+		base = next
+		segments = segments[1:]
+	}
+
+	if _, err := os.Stat(base); err != nil {
+		if os.IsNotExist(err) {
+			return &globResult{}, nil
+		}
+		return nil, fmt.Errorf("glob匹配失败: %w", err)
+	}
+
+	result := &globResult{}
+	var matches []globMatch
+	scanned := 0
+	err := filepath.WalkDir(base, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// 无法读取的目录不影响其余结果
+			if d != nil && d.IsDir() && p != base {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if p == base {
+			return nil
+		}
+		scanned++
+		if scanned > maxGlobScan {
+			result.ScanLimited = true
+			return filepath.SkipAll
+		}
+
+		rel, err := filepath.Rel(base, p)
+		if err != nil {
+			return nil
+		}
+		segs := strings.Split(filepath.ToSlash(rel), "/")
+		if matchGlobSegments(segments, segs, opts.CaseSensitive, false) {
+			var modTime time.Time
+			if info, err := d.Info(); err == nil {
+				modTime = info.ModTime()
+			}
+			matches = append(matches, globMatch{path: p, modTime: modTime})
+		}
+		if d.IsDir() && !matchGlobSegments(segments, segs, opts.CaseSensitive, true) {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("glob匹配失败: %w", err)
+	}
+
+	if opts.SortBy == "mtime" {
+		sort.SliceStable(matches, func(i, j int) bool {
+			if !matches[i].modTime.Equal(matches[j].modTime) {
+				return matches[i].modTime.After(matches[j].modTime)
+			}
+			return matches[i].path < matches[j].path
+		})
+	} else {
+		sort.Slice(matches, func(i, j int) bool { return matches[i].path < matches[j].path })
+	}
+
+	result.Total = len(matches)
+	limit := opts.MaxResults
+	if limit <= 0 {
+		limit = defaultGlobMaxResults
+	}
+	if len(matches) > limit {
+		matches = matches[:limit]
+		result.Truncated = true
+	}
+	for _, m := range matches {
+		result.Matches = append(result.Matches, m.path)
+	}
+	return result, nil
+}
@@ -0,0 +1,91 @@
+package mcp
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMatchGlobSegments(t *testing.T) {
+	tests := []struct {
+		pattern       string
+		path          string
+		caseSensitive bool
+		want          bool
+	}{
+		{"**/*.go", "main.go", true, true},
+		{"**/*.go", "internal/mcp/glob.go", true, true},
+		{"**/*.go", "internal/mcp/glob.txt", true, false},
+		{"internal/**/glob.go", "internal/glob.go", true, true},
+		{"internal/**/glob.go", "internal/mcp/a/b/glob.go", true, true},
+		{"internal/**/glob.go", "cmd/glob.go", true, false},
+		{"*.go", "internal/main.go", true, false},
+		{"**", "a/b/c", true, true},
+		{"*.GO", "main.go", true, false},
+		{"*.GO", "main.go", false, true},
+		{"**/README*", "docs/readme.md", false, true},
+	}
+	for _, tt := range tests {
+		got := matchGlobSegments(splitGlobPattern(tt.pattern), strings.Split(tt.path, "/"), tt.caseSensitive, false)
+		if got != tt.want {
+			t.Errorf("match(%q, %q, %v) = %v, want %v", tt.pattern, tt.path, tt.caseSensitive, got, tt.want)
+		}
+	}
+}
+
+func TestGlobFiles(t *testing.T) {
+	root := t.TempDir()
+	files := []string{"a.go", "sub/b.go", "sub/deep/c.go", "sub/deep/d.txt", "other/E.GO"}
+	for i, name := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		modTime := time.Now().Add(time.Duration(i) * time.Minute)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rel := func(paths []string) []string {
+		var out []string
+		for _, p := range paths {
+			r, _ := filepath.Rel(root, p)
+			out = append(out, filepath.ToSlash(r))
+		}
+		return out
+	}
+
+	result, err := globFiles(root, "**/*.go", globOptions{CaseSensitive: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(rel(result.Matches), ","); got != "a.go,sub/b.go,sub/deep/c.go" {
+		t.Errorf("case sensitive matches = %s", got)
+	}
+
+	result, err = globFiles(root, "**/*.go", globOptions{CaseSensitive: false, SortBy: "mtime"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(rel(result.Matches), ","); got != "other/E.GO,sub/deep/c.go,sub/b.go,a.go" {
+		t.Errorf("mtime sorted matches = %s", got)
+	}
+
+	result, err = globFiles(root, "sub/**/*", globOptions{CaseSensitive: true, MaxResults: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Truncated || result.Total != 4 || len(result.Matches) != 2 {
+		t.Errorf("truncation: truncated=%v total=%d matches=%d", result.Truncated, result.Total, len(result.Matches))
+	}
+
+	if _, err := globFiles(root, "[", globOptions{}); err == nil {
+		t.Error("expected error for malformed pattern")
+	}
+}
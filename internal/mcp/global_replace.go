@@ -0,0 +1,534 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/utils"
+)
+
+const (
+	// defaultReplaceMaxFiles global_replace 默认最多修改的文件数
+	defaultReplaceMaxFiles = 200
+	// maxReplacePreviewDiff 预览中 diff 的最大字节数
+	maxReplacePreviewDiff = 16 * 1024
+	// replaceManifestDir 备份目录下保存替换清单和原始内容的子目录
+	replaceManifestDir = "global-replace"
+	// replaceManifestFile 每次替换的清单文件名
+	replaceManifestFile = "manifest.json"
+)
+
+// ReplaceFileChange 清单中一个文件的修改
+type ReplaceFileChange struct {
+	Path         string `json:"path"`
+	Replacements int    `json:"replacements"`
+	OldHash      string `json:"old_sha256"`
+	NewHash      string `json:"new_sha256,omitempty"`
+	// Backup 原始内容在清单目录中的文件名
+	Backup string `json:"backup,omitempty"`
+}
+
+// ReplaceManifest 一次全局替换的机器可读清单，用于回滚
+type ReplaceManifest struct {
+	ID                string              `json:"id"`
+	Time              time.Time           `json:"time"`
+	Pattern           string              `json:"pattern"`
+	Replacement       string              `json:"replacement"`
+	TotalReplacements int                 `json:"total_replacements"`
+	Files             []ReplaceFileChange `json:"files"`
+	DryRun            bool                `json:"dry_run,omitempty"`
+	RolledBack        bool                `json:"rolled_back,omitempty"`
+}
+
+// replaceChange 计划中一个文件的替换结果
+type replaceChange struct {
+	Path     string
+	Original []byte
+	Updated  []byte
+	Count    int
+}
+
+// replaceRequest 解析后的 global_replace 参数
+type replaceRequest struct {
+	Search      searchOptions
+	Root        string
+	Replacement string
+	Files       []string
+	MaxFiles    int
+	DryRun      bool
+}
+
+// GlobalReplaceTool 在多个文件中批量替换，写入经过 FileEngine，原始内容保存在备份目录中可回滚
+type GlobalReplaceTool struct {
+	engine   *FileEngine
+	registry *ToolRegistry
+}
+
+func NewGlobalReplaceTool(engine *FileEngine, registry *ToolRegistry) *GlobalReplaceTool {
+	return &GlobalReplaceTool{engine: engine, registry: registry}
+}
+
+func (t *GlobalReplaceTool) Name() string {
+	return "global_replace"
+}
+
+func (t *GlobalReplaceTool) Description() string {
+	return "在多个文件中批量替换文本或正则，返回修改清单；建议先用 dry_run=true 预览，可用 files 只修改其中一部分文件，用 rollback_replace 撤销"
+}
+
+func (t *GlobalReplaceTool) GetSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"pattern": map[string]interface{}{
+				"type":        "string",
+				"description": "要查找的内容，默认为正则表达式",
+			},
+			"replacement": map[string]interface{}{
+				"type":        "string",
+				"description": "替换内容，正则模式下可用 $1、${name} 引用分组",
+			},
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "搜索根目录，默认当前目录",
+			},
+			"literal": map[string]interface{}{
+				"type":        "boolean",
+				"description": "按字面量查找和替换，不解析正则和 $ 引用",
+			},
+			"word": map[string]interface{}{
+				"type":        "boolean",
+				"description": "只匹配整词",
+			},
+			"case_insensitive": map[string]interface{}{
+				"type":        "boolean",
+				"description": "忽略大小写",
+			},
+			"include": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "只处理匹配的文件，如 *.go",
+			},
+			"exclude": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "排除匹配的文件或目录",
+			},
+			"respect_gitignore": map[string]interface{}{
+				"type":        "boolean",
+				"description": "跳过 .gitignore 忽略的文件，默认 true",
+			},
+			"files": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "只修改这些文件（通常取自 dry_run 的结果）",
+			},
+			"dry_run": map[string]interface{}{
+				"type":        "boolean",
+				"description": "只返回将要修改的文件和 diff，不写入",
+			},
+			"max_files": map[string]interface{}{
+				"type":        "integer",
+				"description": "最多修改的文件数，超出时报错，默认 200",
+			},
+		},
+		"required": []string{"pattern", "replacement"},
+	}
+}
+
+// parseReplaceRequest 解析参数
+func parseReplaceRequest(args map[string]interface{}) (*replaceRequest, error) {
+	pattern, ok := args["pattern"].(string)
+	if !ok || pattern == "" {
+		return nil, fmt.Errorf("缺少或无效的pattern参数")
+	}
+	replacement, ok := args["replacement"].(string)
+	if !ok {
+		return nil, fmt.Errorf("缺少或无效的replacement参数")
+	}
+
+	req := &replaceRequest{
+		Search:      searchOptions{Pattern: pattern, Gitignore: true},
+		Root:        ".",
+		Replacement: replacement,
+		Files:       stringList(args["files"]),
+		MaxFiles:    defaultReplaceMaxFiles,
+	}
+	if p, ok := args["path"].(string); ok && p != "" {
+		req.Root = p
+	}
+	req.Search.Literal, _ = args["literal"].(bool)
+	req.Search.Word, _ = args["word"].(bool)
+	req.Search.CaseInsensitive, _ = args["case_insensitive"].(bool)
+	if v, ok := args["respect_gitignore"].(bool); ok {
+		req.Search.Gitignore = v
+	}
+	req.Search.Include = stringList(args["include"])
+	req.Search.Exclude = stringList(args["exclude"])
+	req.DryRun, _ = args["dry_run"].(bool)
+	if n, ok := args["max_files"].(float64); ok && n > 0 {
+		req.MaxFiles = int(n)
+	}
+	return req, nil
+}
+
+// plan 计算每个文件替换后的内容，不写入
+func (t *GlobalReplaceTool) plan(req *replaceRequest) ([]replaceChange, error) {
+	re, err := buildSearchRegexp(req.Search)
+	if err != nil {
+		return nil, err
+	}
+	files, err := collectSearchFiles(t.engine, req.Root, req.Search)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Files) > 0 {
+		files = filterPaths(files, req.Files)
+	}
+
+	maxSize := int64(5 * 1024 * 1024)
+	if t.engine != nil && t.engine.config.MaxFileSize > 0 {
+		maxSize = t.engine.config.MaxFileSize
+	}
+
+	var changes []replaceChange
+	for _, path := range files {
+		info, err := os.Stat(path)
+		if err != nil || info.Size() > maxSize {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil || isBinaryContent(content) {
+			continue
+		}
+		count := len(re.FindAllIndex(content, -1))
+		if count == 0 {
+			continue
+		}
+		var updated []byte
+		if req.Search.Literal {
+			updated = re.ReplaceAllLiteral(content, []byte(req.Replacement))
+		} else {
+			updated = re.ReplaceAll(content, []byte(req.Replacement))
+		}
+		if bytes.Equal(updated, content) {
+			continue
+		}
+		changes = append(changes, replaceChange{Path: path, Original: content, Updated: updated, Count: count})
+	}
+
+	if len(changes) > req.MaxFiles {
+		return nil, fmt.Errorf("替换将修改 %d 个文件，超过 max_files=%d，请缩小范围或提高 max_files", len(changes), req.MaxFiles)
+	}
+	return changes, nil
+}
+
+// filterPaths 只保留在 wanted 中的路径（按绝对路径比较）
+func filterPaths(paths, wanted []string) []string {
+	keep := make(map[string]bool, len(wanted))
+	for _, p := range wanted {
+		if abs, err := filepath.Abs(p); err == nil {
+			keep[abs] = true
+		}
+	}
+	var out []string
+	for _, p := range paths {
+		if abs, err := filepath.Abs(p); err == nil && keep[abs] {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// Preview 计算替换会修改的文件（不写入），供界面在审批时逐个列出
+func (t *GlobalReplaceTool) Preview(args map[string]interface{}) (*ReplaceManifest, error) {
+	req, err := parseReplaceRequest(args)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := t.plan(req)
+	if err != nil {
+		return nil, err
+	}
+	return newReplaceManifest(req, changes, true), nil
+}
+
+// newReplaceManifest 根据计划生成清单
+func newReplaceManifest(req *replaceRequest, changes []replaceChange, dryRun bool) *ReplaceManifest {
+	manifest := &ReplaceManifest{
+		Time:        time.Now(),
+		Pattern:     req.Search.Pattern,
+		Replacement: req.Replacement,
+		DryRun:      dryRun,
+	}
+	for _, change := range changes {
+		manifest.TotalReplacements += change.Count
+		manifest.Files = append(manifest.Files, ReplaceFileChange{
+			Path:         change.Path,
+			Replacements: change.Count,
+			OldHash:      ContentHash(change.Original),
+		})
+	}
+	return manifest
+}
+
+func (t *GlobalReplaceTool) Execute(args map[string]interface{}) (interface{}, error) {
+	req, err := parseReplaceRequest(args)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := t.plan(req)
+	if err != nil {
+		return nil, err
+	}
+	if len(changes) == 0 {
+		return "没有文件需要修改", nil
+	}
+
+	if req.DryRun {
+		return formatReplacePreview(newReplaceManifest(req, changes, true), changes)
+	}
+
+	manifest, err := t.apply(req, changes)
+	if err != nil {
+		return nil, err
+	}
+	resultBytes, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("序列化结果失败: %w", err)
+	}
+	return fmt.Sprintf("已在 %d 个文件中替换 %d 处，可用 rollback_replace 撤销（id: %s）\n%s",
+		len(manifest.Files), manifest.TotalReplacements, manifest.ID, resultBytes), nil
+}
+
+// formatReplacePreview 返回清单和各文件的 diff
+func formatReplacePreview(manifest *ReplaceManifest, changes []replaceChange) (string, error) {
+	resultBytes, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("序列化结果失败: %w", err)
+	}
+	var sb strings.Builder
+	sb.Write(resultBytes)
+	sb.WriteString("\n")
+	for _, change := range changes {
+		if sb.Len() > maxReplacePreviewDiff {
+			sb.WriteString("[truncated] 其余文件的 diff 已省略\n")
+			break
+		}
+		sb.WriteString(utils.UnifiedDiff(change.Path, change.Path, string(change.Original), string(change.Updated), 2))
+	}
+	return sb.String(), nil
+}
+
+// apply 保存原始内容后逐个写入；中途失败时恢复已写入的文件
+func (t *GlobalReplaceTool) apply(req *replaceRequest, changes []replaceChange) (*ReplaceManifest, error) {
+	manifest := newReplaceManifest(req, changes, false)
+	manifest.ID = newReplaceID()
+	dir, err := t.manifestDir(manifest.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建备份目录失败: %w", err)
+	}
+
+	var quota *WriteQuota
+	if t.registry != nil {
+		quota = t.registry.quota
+	}
+
+	for i, change := range changes {
+		file := &manifest.Files[i]
+		if quota != nil {
+			if err := quota.check(change.Path, int64(len(change.Updated))); err != nil {
+				t.restore(changes[:i])
+				os.RemoveAll(dir)
+				return nil, err
+			}
+		}
+
+		file.Backup = fmt.Sprintf("%03d.orig", i)
+		if err := os.WriteFile(filepath.Join(dir, file.Backup), change.Original, 0600); err != nil {
+			t.restore(changes[:i])
+			os.RemoveAll(dir)
+			return nil, fmt.Errorf("保存原始内容失败: %w", err)
+		}
+		if err := t.engine.WriteFile(change.Path, change.Updated, false); err != nil {
+			t.restore(changes[:i])
+			os.RemoveAll(dir)
+			return nil, fmt.Errorf("写入 %s 失败，已恢复之前修改的文件: %w", change.Path, err)
+		}
+		if quota != nil {
+			quota.add(change.Path, int64(len(change.Updated)))
+		}
+
+		// 写入时可能统一换行符，记录磁盘上的实际内容
+		if written, err := os.ReadFile(change.Path); err == nil {
+			file.NewHash = ContentHash(written)
+		}
+	}
+
+	if err := writeReplaceManifest(dir, manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// restore 将已写入的文件恢复为原始内容
+func (t *GlobalReplaceTool) restore(changes []replaceChange) {
+	for _, change := range changes {
+		t.engine.WriteFile(change.Path, change.Original, false)
+	}
+}
+
+// manifestDir 返回某次替换的清单目录
+func (t *GlobalReplaceTool) manifestDir(id string) (string, error) {
+	if strings.ContainsAny(id, `/\`) || id == "." || id == ".." {
+		return "", fmt.Errorf("无效的替换id: %s", id)
+	}
+	return filepath.Join(t.engine.config.BackupDir, replaceManifestDir, id), nil
+}
+
+// newReplaceID 生成按时间排序的替换 id
+func newReplaceID() string {
+	now := time.Now()
+	return fmt.Sprintf("%s-%06d", now.Format("20060102-150405"), now.Nanosecond()/1000)
+}
+
+// writeReplaceManifest 保存清单
+func writeReplaceManifest(dir string, manifest *ReplaceManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化清单失败: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, replaceManifestFile), data, 0644); err != nil {
+		return fmt.Errorf("保存清单失败: %w", err)
+	}
+	return nil
+}
+
+// loadReplaceManifest 读取清单；id 为空时返回最近一次尚未回滚的替换
+func (t *GlobalReplaceTool) loadReplaceManifest(id string) (*ReplaceManifest, string, error) {
+	if id == "" {
+		entries, err := os.ReadDir(filepath.Join(t.engine.config.BackupDir, replaceManifestDir))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, "", fmt.Errorf("读取替换记录失败: %w", err)
+		}
+		names := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			if entry.IsDir() {
+				names = append(names, entry.Name())
+			}
+		}
+		sort.Sort(sort.Reverse(sort.StringSlice(names)))
+		for _, name := range names {
+			manifest, dir, err := t.loadReplaceManifest(name)
+			if err == nil && !manifest.RolledBack {
+				return manifest, dir, nil
+			}
+		}
+		return nil, "", fmt.Errorf("没有可以回滚的全局替换")
+	}
+
+	dir, err := t.manifestDir(id)
+	if err != nil {
+		return nil, "", err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, replaceManifestFile))
+	if err != nil {
+		return nil, "", fmt.Errorf("找不到替换记录 %s: %w", id, err)
+	}
+	var manifest ReplaceManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, "", fmt.Errorf("解析替换记录失败: %w", err)
+	}
+	return &manifest, dir, nil
+}
+
+// Rollback 撤销一次全局替换；替换后又被修改过的文件会被报告，force 为 true 时仍然恢复
+func (t *GlobalReplaceTool) Rollback(id string, force bool) (*ReplaceManifest, error) {
+	manifest, dir, err := t.loadReplaceManifest(id)
+	if err != nil {
+		return nil, err
+	}
+	if manifest.RolledBack {
+		return nil, fmt.Errorf("替换 %s 已经回滚过", manifest.ID)
+	}
+
+	// 先检查所有文件，避免只恢复一部分
+	originals := make([][]byte, len(manifest.Files))
+	var conflicts []string
+	for i, file := range manifest.Files {
+		original, err := os.ReadFile(filepath.Join(dir, file.Backup))
+		if err != nil {
+			return nil, fmt.Errorf("读取 %s 的原始内容失败: %w", file.Path, err)
+		}
+		originals[i] = original
+		if current, err := os.ReadFile(file.Path); err != nil || ContentHash(current) != file.NewHash {
+			conflicts = append(conflicts, file.Path)
+		}
+	}
+	if len(conflicts) > 0 && !force {
+		return nil, fmt.Errorf("write conflict: 以下文件在替换后又被修改，回滚会丢失这些修改: %s；确认后使用 force=true", strings.Join(conflicts, ", "))
+	}
+
+	for i, file := range manifest.Files {
+		if err := t.engine.WriteFile(file.Path, originals[i], false); err != nil {
+			return nil, fmt.Errorf("恢复 %s 失败: %w", file.Path, err)
+		}
+	}
+	manifest.RolledBack = true
+	if err := writeReplaceManifest(dir, manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// RollbackReplaceTool 撤销 global_replace 的修改
+type RollbackReplaceTool struct {
+	replace *GlobalReplaceTool
+}
+
+func NewRollbackReplaceTool(replace *GlobalReplaceTool) *RollbackReplaceTool {
+	return &RollbackReplaceTool{replace: replace}
+}
+
+func (t *RollbackReplaceTool) Name() string {
+	return "rollback_replace"
+}
+
+func (t *RollbackReplaceTool) Description() string {
+	return "撤销一次 global_replace，默认撤销最近一次；替换后又被修改过的文件需要 force=true"
+}
+
+func (t *RollbackReplaceTool) GetSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"id": map[string]interface{}{
+				"type":        "string",
+				"description": "global_replace 返回的 id，默认最近一次",
+			},
+			"force": map[string]interface{}{
+				"type":        "boolean",
+				"description": "文件在替换后又被修改时仍然恢复",
+			},
+		},
+	}
+}
+
+func (t *RollbackReplaceTool) Execute(args map[string]interface{}) (interface{}, error) {
+	id, _ := args["id"].(string)
+	force, _ := args["force"].(bool)
+	manifest, err := t.replace.Rollback(id, force)
+	if err != nil {
+		return nil, err
+	}
+	return fmt.Sprintf("已回滚替换 %s，恢复了 %d 个文件", manifest.ID, len(manifest.Files)), nil
+}
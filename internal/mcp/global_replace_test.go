@@ -0,0 +1,108 @@
+package mcp
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGlobalReplaceAndRollback(t *testing.T) {
+	root := t.TempDir()
+	engine := newTestEngine(t, root)
+	registry := NewToolRegistry()
+	tool := NewGlobalReplaceTool(engine, registry)
+
+	files := map[string]string{
+		"a.go":     "oldName()\noldName()\n",
+		"sub/b.go": "x := oldName\n",
+		"c.txt":    "oldName\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	args := map[string]interface{}{
+		"pattern":     "oldName",
+		"replacement": "newName",
+		"path":        root,
+		"include":     []interface{}{"*.go"},
+	}
+
+	preview, err := tool.Preview(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(preview.Files) != 2 || preview.TotalReplacements != 3 {
+		t.Fatalf("preview = %+v", preview)
+	}
+	if read("a.go") != files["a.go"] {
+		t.Fatal("preview modified files")
+	}
+
+	if _, err := tool.Execute(args); err != nil {
+		t.Fatal(err)
+	}
+	if read("a.go") != "newName()\nnewName()\n" || read("sub/b.go") != "x := newName\n" || read("c.txt") != "oldName\n" {
+		t.Fatalf("unexpected contents after replace: %q %q %q", read("a.go"), read("sub/b.go"), read("c.txt"))
+	}
+
+	// 替换后又被修改的文件需要 force 才能回滚
+	if err := os.WriteFile(filepath.Join(root, "a.go"), []byte("edited\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tool.Rollback("", false); err == nil || !strings.Contains(err.Error(), "write conflict") {
+		t.Fatalf("expected conflict, got %v", err)
+	}
+	if read("sub/b.go") != "x := newName\n" {
+		t.Fatal("conflicting rollback restored some files")
+	}
+	if _, err := tool.Rollback("", true); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if got := read(name); got != content {
+			t.Errorf("%s after rollback = %q, want %q", name, got, content)
+		}
+	}
+	if _, err := tool.Rollback("", false); err == nil {
+		t.Error("expected no replace left to roll back")
+	}
+}
+
+func TestGlobalReplaceQuota(t *testing.T) {
+	root := t.TempDir()
+	engine := newTestEngine(t, root)
+	registry := NewToolRegistry()
+	registry.SetWriteQuota(NewWriteQuota(-1, 1))
+	tool := NewGlobalReplaceTool(engine, registry)
+
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte("foo\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, err := tool.Execute(map[string]interface{}{"pattern": "foo", "replacement": "bar", "path": root, "literal": true})
+	if err == nil || !strings.Contains(err.Error(), "write quota exceeded") {
+		t.Fatalf("expected quota error, got %v", err)
+	}
+	for _, name := range []string{"a.txt", "b.txt"} {
+		data, _ := os.ReadFile(filepath.Join(root, name))
+		if string(data) != "foo\n" {
+			t.Errorf("%s = %q, want original content after quota failure", name, data)
+		}
+	}
+}
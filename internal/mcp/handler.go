@@ -1,6 +1,7 @@
 package mcp
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -8,7 +9,10 @@ import (
 	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/utils"
 )
 
 // ToolHandler 工具处理器接口
@@ -22,6 +26,24 @@ type ToolHandler interface {
 // ToolRegistry 工具注册表
 type ToolRegistry struct {
 	tools map[string]ToolHandler
+	// 未信任的工作区只开放只读工具
+	readOnly atomic.Bool
+	// 工具调用审计日志，为 nil 时不记录
+	audit *AuditLog
+	// 会话写入预算，为 nil 时不限制
+	quota *WriteQuota
+	// delete_file 使用的会话回收站
+	trash *Trash
+	// 编辑会话，FileEngine 写入文件后同步其缓冲区
+	editor atomic.Pointer[utils.Editor]
+	// 文件工具共用的文件引擎，管理允许访问的根目录
+	engine *FileEngine
+	// 语义搜索和符号查找使用的项目索引
+	project *ProjectIndex
+	// /scope 设置的搜索范围，为 nil 时不限制
+	scope atomic.Pointer[string]
+	// 本次会话的工具使用统计
+	stats sessionStats
 }
 
 // NewToolRegistry 创建新的工具注册表
@@ -36,6 +58,11 @@ func (r *ToolRegistry) Register(tool ToolHandler) {
 	r.tools[tool.Name()] = tool
 }
 
+// Unregister 移除工具
+func (r *ToolRegistry) Unregister(name string) {
+	delete(r.tools, name)
+}
+
 // GetTool 获取工具
 func (r *ToolRegistry) GetTool(name string) (ToolHandler, bool) {
 	tool, ok := r.tools[name]
@@ -46,6 +73,9 @@ func (r *ToolRegistry) GetTool(name string) (ToolHandler, bool) {
 func (r *ToolRegistry) ListTools() []Tool {
 	tools := make([]Tool, 0, len(r.tools))
 	for _, handler := range r.tools {
+		if !r.allowed(handler.Name()) {
+			continue
+		}
 		tools = append(tools, Tool{
 			Name:        handler.Name(),
 			Description: handler.Description(),
@@ -55,17 +85,21 @@ func (r *ToolRegistry) ListTools() []Tool {
 }
 
 // HandleCallTool 处理工具调用
-func (r *ToolRegistry) HandleCallTool(req CallToolRequest) (*CallToolResult, error) {
-	// 添加恢复机制防止panic
+func (r *ToolRegistry) HandleCallTool(req CallToolRequest) (result *CallToolResult, err error) {
+	// 注册表自身发生 panic 时同样返回失败的工具结果，而不是 nil
 	defer func() {
-		if r := recover(); r != nil {
-			// fmt.Printf("[MCP] HandleCallTool 恢复panic: %v\n", r)
+		if v := recover(); v != nil {
+			result, err = NewToolError(panicError(req.Name, v)).CallToolResult(), nil
 		}
 	}()
 
+	// 工具失败时返回 IsError 的结果，错误码和附加信息序列化在内容中供模型据此恢复
 	handler, ok := r.GetTool(req.Name)
 	if !ok {
-		return nil, fmt.Errorf("工具未找到: %s", req.Name)
+		return NewToolError(fmt.Errorf("工具未找到: %s", req.Name)).CallToolResult(), nil
+	}
+	if !r.allowed(req.Name) {
+		return NewToolError(fmt.Errorf("permission denied: workspace is not trusted, %s is unavailable until the user runs /trust", req.Name)).CallToolResult(), nil
 	}
 
 	// 记录工具调用（用于调试）
@@ -77,29 +111,56 @@ func (r *ToolRegistry) HandleCallTool(req CallToolRequest) (*CallToolResult, err
 		req.Arguments = make(map[string]interface{})
 	}
 
-	// 执行工具调用（添加错误恢复）
-	result, err := func() (interface{}, error) {
-		defer func() {
-			if r := recover(); r != nil {
-				// fmt.Printf("[MCP] 工具执行恢复panic: %s, 错误: %v\n", req.Name, r)
-			}
-		}()
-		return handler.Execute(req.Arguments)
-	}()
+	// 有多个根目录时解析相对路径，结果中注明所在的根目录
+	rootNotes := r.resolveRootPaths(req.Arguments)
+	// 设置了搜索范围时，未指定 path 的搜索只在范围内进行
+	if note := r.applyScope(req.Name, req.Arguments); note != "" {
+		rootNotes = append(rootNotes, note)
+	}
 
-	if err != nil {
+	// 执行工具调用（panic 转换为 CodeInternalError），结果写入审计日志并计入写入预算
+	start := time.Now()
+	if err := r.checkWriteQuota(req); err != nil {
+		r.recordAudit(req, start, 0, err)
+		r.recordStats(req, err)
+		return NewToolError(err).CallToolResult(), nil
+	}
+	output, execErr := executeTool(handler, req.Arguments)
+	var written int64
+	if execErr == nil {
+		written = bytesWritten(req.Name, req.Arguments)
+		r.recordWrite(req, written)
+	}
+	r.recordAudit(req, start, written, execErr)
+	r.recordStats(req, execErr)
+
+	if execErr != nil {
 		// 记录详细错误信息
-		// fmt.Printf("[MCP] 工具执行失败: %s, 错误: %v\n", req.Name, err)
-		return nil, fmt.Errorf("工具执行失败: %w", err)
+		// fmt.Printf("[MCP] 工具执行失败: %s, 错误: %v\n", req.Name, execErr)
+		return NewToolError(execErr).CallToolResult(), nil
 	}
 
 	// 将结果转换为ToolResultContent，优化字符串转换
-	var textResult string
-	if str, ok := result.(string); ok {
+	var textResult, note string
+	if str, ok := output.(string); ok {
 		textResult = str
+	} else if annotated, ok := output.(annotatedOutput); ok {
+		textResult, note = annotated.text, annotated.note
 	} else {
 		// 只在非字符串类型时使用 fmt.Sprint
-		textResult = fmt.Sprint(result)
+		textResult = fmt.Sprint(output)
+	}
+	// 网页和文件内容包在分隔块中，疑似提示注入时附加警告
+	var injection []string
+	if ExternalContentTools[req.Name] {
+		injection = detectInjection(textResult)
+		textResult = wrapExternalContent(req.Name, textResult, injection)
+	}
+	if note != "" {
+		textResult += "\n" + note
+	}
+	if len(rootNotes) > 0 {
+		textResult += "\n\n" + strings.Join(rootNotes, "\n")
 	}
 
 	content := ToolResultContent{
@@ -109,15 +170,18 @@ func (r *ToolRegistry) HandleCallTool(req CallToolRequest) (*CallToolResult, err
 
 	// fmt.Printf("[MCP] 工具执行成功: %s\n", req.Name)
 	return &CallToolResult{
-		Content: []ToolResultContent{content},
+		Content:   []ToolResultContent{content},
+		Injection: injection,
 	}, nil
 }
 
 // ListDirectoryTool 列出目录工具
 type ListDirectoryTool struct{}
 
-func (t *ListDirectoryTool) Name() string                      { return "list_directory" }
-func (t *ListDirectoryTool) Description() string               { return "列出目录内容" }
+func (t *ListDirectoryTool) Name() string { return "list_directory" }
+func (t *ListDirectoryTool) Description() string {
+	return "列出目录内容，包含类型、大小和修改时间，支持忽略模式、排序和递归深度"
+}
 func (t *ListDirectoryTool) GetSchema() map[string]interface{} { return ListDirectorySchema }
 
 func (t *ListDirectoryTool) Execute(args map[string]interface{}) (interface{}, error) {
@@ -126,21 +190,37 @@ func (t *ListDirectoryTool) Execute(args map[string]interface{}) (interface{}, e
 		return nil, fmt.Errorf("缺少或无效的path参数")
 	}
 
-	entries, err := os.ReadDir(path)
+	opts := listOptions{SortBy: "name", Depth: 1}
+	if ignore, ok := args["ignore"].([]interface{}); ok {
+		for _, item := range ignore {
+			if pattern, ok := item.(string); ok && pattern != "" {
+				opts.Ignore = append(opts.Ignore, pattern)
+			}
+		}
+	}
+	if sortBy, ok := args["sort_by"].(string); ok && sortBy != "" {
+		if sortBy != "name" && sortBy != "size" && sortBy != "mtime" {
+			return nil, fmt.Errorf("无效的sort_by参数: %s（可选 name、size 或 mtime）", sortBy)
+		}
+		opts.SortBy = sortBy
+	}
+	if depth, ok := args["depth"].(float64); ok {
+		opts.Depth = int(depth)
+	}
+
+	entries, truncated, err := listDirectory(path, opts)
 	if err != nil {
 		return nil, fmt.Errorf("读取目录失败: %w", err)
 	}
-
-	var result []string
-	for _, entry := range entries {
-		name := entry.Name()
-		if entry.IsDir() {
-			name += "/"
-		}
-		result = append(result, name)
+	if len(entries) == 0 {
+		return "目录为空", nil
 	}
 
-	return strings.Join(result, "\n"), nil
+	output := formatDirEntries(entries)
+	if truncated {
+		output += fmt.Sprintf("\n[truncated] 仅显示前 %d 个条目，请减小depth或使用ignore", maxListEntries)
+	}
+	return output, nil
 }
 
 // SearchFileContentTool 搜索文件内容工具
@@ -280,8 +360,10 @@ func (t *SearchFileContentTool) Execute(args map[string]interface{}) (interface{
 // GlobTool 文件匹配工具
 type GlobTool struct{}
 
-func (t *GlobTool) Name() string                      { return "glob" }
-func (t *GlobTool) Description() string               { return "使用glob模式匹配文件" }
+func (t *GlobTool) Name() string { return "glob" }
+func (t *GlobTool) Description() string {
+	return "使用glob模式匹配文件，支持 ** 递归匹配任意层目录，可按路径或修改时间排序"
+}
 func (t *GlobTool) GetSchema() map[string]interface{} { return GlobSchema }
 
 func (t *GlobTool) Execute(args map[string]interface{}) (interface{}, error) {
@@ -295,43 +377,74 @@ func (t *GlobTool) Execute(args map[string]interface{}) (interface{}, error) {
 		path = p
 	}
 
-	matches, err := filepath.Glob(filepath.Join(path, pattern))
+	opts := globOptions{CaseSensitive: true, SortBy: "path"}
+	if cs, ok := args["case_sensitive"].(bool); ok {
+		opts.CaseSensitive = cs
+	}
+	if sortBy, ok := args["sort_by"].(string); ok && sortBy != "" {
+		if sortBy != "path" && sortBy != "mtime" {
+			return nil, fmt.Errorf("无效的sort_by参数: %s（可选 path 或 mtime）", sortBy)
+		}
+		opts.SortBy = sortBy
+	}
+	if maxResults, ok := args["max_results"].(float64); ok {
+		opts.MaxResults = int(maxResults)
+	}
+
+	result, err := globFiles(path, pattern, opts)
 	if err != nil {
-		return nil, fmt.Errorf("glob匹配失败: %w", err)
+		return nil, err
 	}
 
-	if len(matches) == 0 {
+	if len(result.Matches) == 0 {
 		return "未找到匹配的文件", nil
 	}
 
-	return strings.Join(matches, "\n"), nil
+	output := strings.Join(result.Matches, "\n")
+	if result.Truncated {
+		output += fmt.Sprintf("\n[truncated] 仅显示前 %d 个结果，共 %d 个匹配，请使用更精确的模式", len(result.Matches), result.Total)
+	}
+	if result.ScanLimited {
+		output += fmt.Sprintf("\n[truncated] 遍历条目超过 %d 个，结果可能不完整", maxGlobScan)
+	}
+	return output, nil
 }
 
-// RunShellCommandTool 执行shell命令工具
-type RunShellCommandTool struct{}
+// RunShellCommandTool 执行shell命令工具，执行目录限制在 FileEngine 允许的根目录内
+type RunShellCommandTool struct {
+	engine *FileEngine
+}
 
 func (t *RunShellCommandTool) Name() string                      { return "run_shell_command" }
 func (t *RunShellCommandTool) Description() string               { return "执行shell命令" }
 func (t *RunShellCommandTool) GetSchema() map[string]interface{} { return RunShellCommandSchema }
 
 func (t *RunShellCommandTool) Execute(args map[string]interface{}) (interface{}, error) {
-	command, ok := args["command"].(string)
-	if !ok {
-		return nil, fmt.Errorf("缺少或无效的command参数")
+	shell, err := ParseShellArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	if shell.Dir != "" {
+		if err := t.engine.ValidatePath(shell.Dir); err != nil {
+			return nil, err
+		}
 	}
 
-	// 注意：这里简化实现，实际应该使用exec.Command
-	// 由于安全考虑，这里只返回示例
-	var resultBuilder strings.Builder
-	resultBuilder.Grow(len(command) + 50)
-	resultBuilder.WriteString("执行命令: ")
-	resultBuilder.WriteString(command)
-	resultBuilder.WriteString("\n(实际实现需要使用exec.Command)")
-	return resultBuilder.String(), nil
+	// 执行前已由界面按审批规则确认；输出按配置上限截断，完整输出写入日志
+	// 命令只继承配置允许的环境变量，被过滤的变量值和配置中的密钥不会出现在返回给模型的输出中
+	settings := loadShellSettings()
+	env, scrubbed := buildShellEnv(os.Environ(), settings.PassEnv, shell.ExtraEnv)
+	result, err := runShellCommand(context.Background(), shell.Command, shell.Dir, env, settings.OutputLimit)
+	if err != nil {
+		return nil, err
+	}
+	return redactSecrets(formatShellResult(shell.Command, result), append(settings.Secrets, scrubbed...)), nil
 }
 
 // CreateFileTool 创建文件工具
-type CreateFileTool struct{}
+type CreateFileTool struct {
+	engine *FileEngine
+}
 
 func (t *CreateFileTool) Name() string                      { return "create_file" }
 func (t *CreateFileTool) Description() string               { return "创建新文件" }
@@ -347,6 +460,9 @@ func (t *CreateFileTool) Execute(args map[string]interface{}) (interface{}, erro
 	if !ok {
 		return nil, fmt.Errorf("缺少或无效的content参数")
 	}
+	if err := t.engine.ValidatePath(path); err != nil {
+		return nil, err
+	}
 
 	overwrite := false
 	if ow, ok := args["overwrite"].(bool); ok {
@@ -358,24 +474,37 @@ func (t *CreateFileTool) Execute(args map[string]interface{}) (interface{}, erro
 		return nil, fmt.Errorf("文件已存在，如需覆盖请设置overwrite=true")
 	}
 
+	// 未指定权限时，覆盖已有文件保留原权限，新文件使用默认权限
+	mode, _ := existingFileMode(path)
+	if modeArg, ok := args["mode"].(string); ok && modeArg != "" {
+		parsed, err := parseFileMode(modeArg)
+		if err != nil {
+			return nil, err
+		}
+		mode = parsed
+	}
+
 	// 确保目录存在
 	dir := filepath.Dir(path)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return nil, fmt.Errorf("创建目录失败: %w", err)
 	}
 
-	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+	if err := writeFileWithMode(path, []byte(content), mode); err != nil {
 		return nil, fmt.Errorf("创建文件失败: %w", err)
 	}
 
 	return "文件创建成功", nil
 }
 
-// DeleteFileTool 删除文件工具
-type DeleteFileTool struct{}
+// DeleteFileTool 删除文件工具，设置回收站时移入回收站而不是永久删除
+type DeleteFileTool struct {
+	engine *FileEngine
+	trash  *Trash
+}
 
 func (t *DeleteFileTool) Name() string                      { return "delete_file" }
-func (t *DeleteFileTool) Description() string               { return "删除文件或目录" }
+func (t *DeleteFileTool) Description() string               { return "删除文件或目录（移入会话回收站，用户可恢复）" }
 func (t *DeleteFileTool) GetSchema() map[string]interface{} { return DeleteFileSchema }
 
 func (t *DeleteFileTool) Execute(args map[string]interface{}) (interface{}, error) {
@@ -383,6 +512,9 @@ func (t *DeleteFileTool) Execute(args map[string]interface{}) (interface{}, erro
 	if !ok {
 		return nil, fmt.Errorf("缺少或无效的path参数")
 	}
+	if err := t.engine.ValidatePath(path); err != nil {
+		return nil, err
+	}
 
 	recursive := false
 	if rec, ok := args["recursive"].(bool); ok {
@@ -398,6 +530,14 @@ func (t *DeleteFileTool) Execute(args map[string]interface{}) (interface{}, erro
 		return nil, fmt.Errorf("目录非空，如需删除请设置recursive=true")
 	}
 
+	if t.trash != nil {
+		entry, err := t.trash.MoveToTrash(path)
+		if err != nil {
+			return nil, fmt.Errorf("删除失败: %w", err)
+		}
+		return fmt.Sprintf("已移入回收站: %s -> %s（用户可用 /trash restore %d 恢复）", path, entry.TrashPath, entry.ID), nil
+	}
+
 	if info.IsDir() {
 		if err := os.RemoveAll(path); err != nil {
 			return nil, fmt.Errorf("删除目录失败: %w", err)
@@ -411,11 +551,13 @@ func (t *DeleteFileTool) Execute(args map[string]interface{}) (interface{}, erro
 	return "删除成功", nil
 }
 
-// MoveFileTool 移动文件工具
-type MoveFileTool struct{}
+// MoveFileTool 移动文件工具，源和目标都必须位于允许的根目录内
+type MoveFileTool struct {
+	engine *FileEngine
+}
 
 func (t *MoveFileTool) Name() string                      { return "move_file" }
-func (t *MoveFileTool) Description() string               { return "移动文件或目录" }
+func (t *MoveFileTool) Description() string               { return "移动文件或目录（支持跨磁盘移动）" }
 func (t *MoveFileTool) GetSchema() map[string]interface{} { return MoveFileSchema }
 
 func (t *MoveFileTool) Execute(args map[string]interface{}) (interface{}, error) {
@@ -428,29 +570,64 @@ func (t *MoveFileTool) Execute(args map[string]interface{}) (interface{}, error)
 	if !ok {
 		return nil, fmt.Errorf("缺少或无效的destination参数")
 	}
+	if err := validateEndpoints(t.engine, source, destination); err != nil {
+		return nil, err
+	}
 
 	overwrite := false
 	if ow, ok := args["overwrite"].(bool); ok {
 		overwrite = ow
 	}
 
-	// 检查目标文件是否存在
-	if _, err := os.Stat(destination); err == nil && !overwrite {
-		return nil, fmt.Errorf("目标文件已存在，如需覆盖请设置overwrite=true")
+	srcInfo, err := os.Lstat(source)
+	if err != nil {
+		return nil, fmt.Errorf("源文件不存在: %w", err)
+	}
+	if err := checkNotInside(source, destination, srcInfo); err != nil {
+		return nil, err
 	}
 
-	if err := os.Rename(source, destination); err != nil {
-		return nil, fmt.Errorf("移动文件失败: %w", err)
+	// 检查目标文件是否存在；覆盖时只替换文件，不会整体替换已有目录
+	if dstInfo, err := os.Lstat(destination); err == nil {
+		if !overwrite {
+			return nil, fmt.Errorf("目标文件已存在，如需覆盖请设置overwrite=true")
+		}
+		if dstInfo.IsDir() {
+			return nil, fmt.Errorf("目标是已存在的目录，无法覆盖: %s", destination)
+		}
+		if err := os.Remove(destination); err != nil {
+			return nil, fmt.Errorf("移动文件失败: %w", err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(destination), 0755); err != nil {
+		return nil, fmt.Errorf("创建目录失败: %w", err)
 	}
 
+	// 同一磁盘直接重命名，跨磁盘时复制后删除源并报告进度
+	var progress *progressReporter
+	if srcInfo.IsDir() {
+		files, bytes := treeSize(source)
+		progress = newProgressReporter(t.Name(), files, bytes)
+		defer progress.finish()
+	}
+	stats, err := movePathProgress(source, destination, progress)
+	if err != nil {
+		return nil, fmt.Errorf("移动文件失败: %w", err)
+	}
+	if stats.Files > 0 {
+		return fmt.Sprintf("移动成功（跨磁盘复制 %d 个文件，%d 字节）", stats.Files, stats.Bytes), nil
+	}
 	return "移动成功", nil
 }
 
-// CopyFileTool 复制文件工具
-type CopyFileTool struct{}
+// CopyFileTool 复制文件工具，源和目标都必须位于允许的根目录内
+type CopyFileTool struct {
+	engine *FileEngine
+}
 
 func (t *CopyFileTool) Name() string                      { return "copy_file" }
-func (t *CopyFileTool) Description() string               { return "复制文件或目录" }
+func (t *CopyFileTool) Description() string               { return "复制文件或目录（目录递归复制，保留权限和修改时间）" }
 func (t *CopyFileTool) GetSchema() map[string]interface{} { return CopyFileSchema }
 
 func (t *CopyFileTool) Execute(args map[string]interface{}) (interface{}, error) {
@@ -463,21 +640,27 @@ func (t *CopyFileTool) Execute(args map[string]interface{}) (interface{}, error)
 	if !ok {
 		return nil, fmt.Errorf("缺少或无效的destination参数")
 	}
+	if err := validateEndpoints(t.engine, source, destination); err != nil {
+		return nil, err
+	}
 
 	overwrite := false
 	if ow, ok := args["overwrite"].(bool); ok {
 		overwrite = ow
 	}
 
-	// 检查目标文件是否存在
-	if _, err := os.Stat(destination); err == nil && !overwrite {
-		return nil, fmt.Errorf("目标文件已存在，如需覆盖请设置overwrite=true")
-	}
-
-	sourceContent, err := os.ReadFile(source)
+	srcInfo, err := os.Lstat(source)
 	if err != nil {
 		return nil, fmt.Errorf("读取源文件失败: %w", err)
 	}
+	if err := checkNotInside(source, destination, srcInfo); err != nil {
+		return nil, err
+	}
+
+	// 检查目标文件是否存在；覆盖目录时合并内容，同名文件被替换
+	if _, err := os.Lstat(destination); err == nil && !overwrite {
+		return nil, fmt.Errorf("目标文件已存在，如需覆盖请设置overwrite=true")
+	}
 
 	// 确保目标目录存在
 	dir := filepath.Dir(destination)
@@ -485,18 +668,59 @@ func (t *CopyFileTool) Execute(args map[string]interface{}) (interface{}, error)
 		return nil, fmt.Errorf("创建目录失败: %w", err)
 	}
 
-	if err := os.WriteFile(destination, sourceContent, 0644); err != nil {
+	var progress *progressReporter
+	if srcInfo.IsDir() {
+		files, bytes := treeSize(source)
+		progress = newProgressReporter(t.Name(), files, bytes)
+		defer progress.finish()
+	}
+	stats, err := copyPathProgress(source, destination, progress)
+	if err != nil {
 		return nil, fmt.Errorf("写入目标文件失败: %w", err)
 	}
 
+	if srcInfo.IsDir() {
+		return fmt.Sprintf("复制成功（%d 个文件，%d 字节）", stats.Files, stats.Bytes), nil
+	}
 	return "复制成功", nil
 }
 
+// validateEndpoints 检查移动或复制的源和目标是否都在允许的根目录内
+func validateEndpoints(engine *FileEngine, source, destination string) error {
+	if err := engine.ValidatePath(source); err != nil {
+		return err
+	}
+	return engine.ValidatePath(destination)
+}
+
+// checkNotInside 防止把目录复制或移动到自身内部（会无限递归）
+func checkNotInside(source, destination string, srcInfo os.FileInfo) error {
+	if !srcInfo.IsDir() {
+		return nil
+	}
+	realSrc, err := resolvePath(source)
+	if err != nil {
+		return err
+	}
+	realDst, err := resolvePath(destination)
+	if err != nil {
+		return err
+	}
+	if pathWithin(realSrc, realDst) {
+		return fmt.Errorf("无法将目录复制或移动到其自身内部: %s -> %s", source, destination)
+	}
+	return nil
+}
+
 // GetFileInfoTool 获取文件信息工具
-type GetFileInfoTool struct{}
+type GetFileInfoTool struct {
+	engine *FileEngine
+}
 
-func (t *GetFileInfoTool) Name() string                      { return "get_file_info" }
-func (t *GetFileInfoTool) Description() string               { return "获取文件或目录信息" }
+func (t *GetFileInfoTool) Name() string { return "get_file_info" }
+func (t *GetFileInfoTool) Description() string {
+	return "获取文件或目录信息；detailed=true 时额外返回 SHA-256、行数、语言、git 状态和备份数"
+}
 func (t *GetFileInfoTool) GetSchema() map[string]interface{} { return GetFileInfoSchema }
 
 func (t *GetFileInfoTool) Execute(args map[string]interface{}) (interface{}, error) {
@@ -517,6 +741,31 @@ func (t *GetFileInfoTool) Execute(args map[string]interface{}) (interface{}, err
 		"mod_time": info.ModTime().Format("2006-01-02 15:04:05"),
 		"is_dir":   info.IsDir(),
 	}
+	if linfo, err := os.Lstat(path); err == nil && linfo.Mode()&os.ModeSymlink != 0 {
+		result["symlink"] = true
+		if target, err := os.Readlink(path); err == nil {
+			result["symlink_target"] = target
+		}
+	}
+
+	// 详细信息需要读取整个文件并调用 git，仅在请求时计算
+	if detailed, _ := args["detailed"].(bool); detailed {
+		if info.Mode().IsRegular() {
+			details, err := fileDetails(path)
+			if err != nil {
+				return nil, fmt.Errorf("读取文件失败: %w", err)
+			}
+			for k, v := range details {
+				result[k] = v
+			}
+			if t.engine != nil {
+				result["backup_count"] = t.engine.BackupCount(path)
+			}
+		}
+		if git := gitFileStatus(path); git != nil {
+			result["git"] = git
+		}
+	}
 
 	resultBytes, err := json.MarshalIndent(result, "", "  ")
 	if err != nil {
@@ -607,36 +856,72 @@ func (t *GetCurrentTimeTool) Execute(args map[string]interface{}) (interface{},
 	return time.Now().Format(format), nil
 }
 
+// NetworkToolNames 需要访问外部网络的工具
+var NetworkToolNames = []string{"web_search", "web_crawl", "web_extract"}
+
+// DisableNetworkTools 从注册表中移除所有联网工具（离线模式）
+func DisableNetworkTools(registry *ToolRegistry) {
+	for _, name := range NetworkToolNames {
+		registry.Unregister(name)
+	}
+}
+
 // DefaultToolRegistry 创建默认工具注册表
 func DefaultToolRegistry(fileEngineConfig *FileEngineConfig) *ToolRegistry {
 	registry := NewToolRegistry()
 
 	// 创建 FileEngine 实例
 	engine := NewFileEngine(fileEngineConfig)
+	registry.engine = engine
 
 	// 注册文件操作工具（基于 FileEngine）
 	registry.Register(&ReadFileTool{engine: engine})
 	registry.Register(&WriteFileTool{engine: engine})
 	registry.Register(&ReplaceTool{engine: engine})
 	registry.Register(&DiagnoseFileTool{engine: engine})
+	// 按行编辑的修改记录在编辑会话中；FileEngine 的每次写入都同步到该会话，界面启动后改用界面的会话（见 SetEditor）
+	registry.Register(NewEditLinesTool(engine, nil))
+	registry.SetEditor(utils.NewEditor())
+	engine.OnChange(registry.syncEditor)
+
+	// 项目索引供语义搜索和符号查找使用，调用 StartProjectIndex 或第一次查询时才开始扫描并写入 .polyagent/index
+	project := NewProjectIndex(engine, ProjectRoot(engine))
+	registry.project = project
+	registry.Register(NewSemanticSearchTool(engine, project))
+	registry.Register(NewFindSymbolTool(project))
+	registry.Register(NewFileStatsTool(engine))
+	registry.Register(NewAdvancedSearchTool(engine))
+	// 全局替换的原始内容保存在备份目录中，可用 rollback_replace 撤销
+	globalReplace := NewGlobalReplaceTool(engine, registry)
+	registry.Register(globalReplace)
+	registry.Register(NewRollbackReplaceTool(globalReplace))
+	findAndReplace := NewFindAndReplaceTool(engine, globalReplace)
+	registry.Register(findAndReplace)
+	registry.Register(NewApplyReplacementsTool(findAndReplace))
 
 	// 注册其他工具（使用 handler.go 中的实现）
 	registry.Register(&ListDirectoryTool{})
 	registry.Register(&SearchFileContentTool{})
 	registry.Register(&GlobTool{})
-	registry.Register(&CreateFileTool{})
-	registry.Register(&DeleteFileTool{})
-	registry.Register(&GetFileInfoTool{})
-	registry.Register(&RunShellCommandTool{})
+	registry.Register(&CreateFileTool{engine: engine})
+	// 删除的文件移入会话回收站，可用 /trash 恢复
+	registry.trash = NewTrash(SessionID())
+	registry.Register(&DeleteFileTool{engine: engine, trash: registry.trash})
+	registry.Register(&GetFileInfoTool{engine: engine})
+	registry.Register(&RunShellCommandTool{engine: engine})
 	registry.Register(&GetCurrentTimeTool{})
 	registry.Register(&ExecuteCodeTool{})
 	registry.Register(&GitOperationTool{})
-	registry.Register(&MoveFileTool{})
-	registry.Register(&CopyFileTool{})
+	registry.Register(&MoveFileTool{engine: engine})
+	registry.Register(&CopyFileTool{engine: engine})
+	registry.Register(&ChmodTool{engine: engine})
+	// 向用户提问，由交互界面负责显示问题和收集回答
+	registry.Register(&AskUserTool{})
 
 	// 注册 Tavily 搜索工具
 	registry.Register(NewTavilySearchTool())
 	registry.Register(NewTavilyCrawlTool())
+	registry.Register(NewTavilyExtractTool())
 
 	// 注册高级工具（如果存在）
 	// RegisterAdvancedTools(registry) // 该函数不存在，暂时注释
@@ -0,0 +1,175 @@
+package mcp
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// maxListDepth list_directory 递归的最大深度
+	maxListDepth = 10
+	// maxListEntries list_directory 最多返回的条目数
+	maxListEntries = 1000
+)
+
+// dirEntryInfo 目录中一个条目的信息
+type dirEntryInfo struct {
+	// RelPath 相对于被列出目录的路径，使用 / 分隔
+	RelPath string
+	Type    string // "dir"、"file"、"symlink" 或 "other"
+	Size    int64
+	ModTime time.Time
+	// Target 符号链接指向的路径
+	Target string
+	depth  int
+}
+
+// listOptions list_directory 选项
+type listOptions struct {
+	Ignore []string
+	// SortBy 为 "name"（默认，目录在前）、"size"（大的在前）或 "mtime"（最近的在前）
+	SortBy string
+	// Depth 递归层数，1 表示只列出直接子项
+	Depth int
+}
+
+// listIgnored 判断条目是否被忽略：不含 / 的模式匹配名称，含 / 的模式匹配相对路径
+func listIgnored(patterns []string, relPath string) bool {
+	name := path.Base(relPath)
+	for _, pattern := range patterns {
+		pattern = strings.TrimSuffix(filepath.ToSlash(pattern), "/")
+		if !strings.Contains(pattern, "/") {
+			if matchGlobSegment(pattern, name, true) {
+				return true
+			}
+			continue
+		}
+		if matchGlobSegments(splitGlobPattern(pattern), strings.Split(relPath, "/"), true, false) {
+			return true
+		}
+	}
+	return false
+}
+
+// listDirectory 列出目录内容，返回的条目中目录的子项紧跟在目录之后；超过上限时 truncated 为 true
+func listDirectory(root string, opts listOptions) (entries []dirEntryInfo, truncated bool, err error) {
+	if opts.Depth <= 0 {
+		opts.Depth = 1
+	}
+	if opts.Depth > maxListDepth {
+		opts.Depth = maxListDepth
+	}
+	for _, pattern := range opts.Ignore {
+		if _, err := path.Match(filepath.ToSlash(pattern), ""); err != nil {
+			return nil, false, fmt.Errorf("无效的ignore模式 %q: %w", pattern, err)
+		}
+	}
+
+	var walk func(dir, rel string, depth int) error
+	walk = func(dir, rel string, depth int) error {
+		items, err := os.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+
+		var level []dirEntryInfo
+		for _, item := range items {
+			relPath := item.Name()
+			if rel != "" {
+				relPath = rel + "/" + item.Name()
+			}
+			if listIgnored(opts.Ignore, relPath) {
+				continue
+			}
+
+			entry := dirEntryInfo{RelPath: relPath, depth: depth}
+			info, err := item.Info()
+			if err != nil {
+				// 条目在读取期间被删除
+				continue
+			}
+			entry.ModTime = info.ModTime()
+			switch {
+			case info.Mode()&os.ModeSymlink != 0:
+				entry.Type = "symlink"
+				entry.Target, _ = os.Readlink(filepath.Join(dir, item.Name()))
+			case info.IsDir():
+				entry.Type = "dir"
+			case info.Mode().IsRegular():
+				entry.Type = "file"
+				entry.Size = info.Size()
+			default:
+				entry.Type = "other"
+			}
+			level = append(level, entry)
+		}
+		sortDirEntries(level, opts.SortBy)
+
+		for _, entry := range level {
+			if len(entries) >= maxListEntries {
+				truncated = true
+				return nil
+			}
+			entries = append(entries, entry)
+			// 不跟随符号链接进入目录，避免循环
+			if entry.Type == "dir" && depth < opts.Depth {
+				// 无法读取的子目录只列出自身
+				walk(filepath.Join(dir, path.Base(entry.RelPath)), entry.RelPath, depth+1)
+			}
+		}
+		return nil
+	}
+
+	if err := walk(root, "", 1); err != nil {
+		return nil, false, err
+	}
+	return entries, truncated, nil
+}
+
+// sortDirEntries 按指定方式排序同一层的条目
+func sortDirEntries(entries []dirEntryInfo, sortBy string) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		switch sortBy {
+		case "size":
+			if a.Size != b.Size {
+				return a.Size > b.Size
+			}
+		case "mtime":
+			if !a.ModTime.Equal(b.ModTime) {
+				return a.ModTime.After(b.ModTime)
+			}
+		default:
+			if (a.Type == "dir") != (b.Type == "dir") {
+				return a.Type == "dir"
+			}
+		}
+		return a.RelPath < b.RelPath
+	})
+}
+
+// formatDirEntries 每行一个条目：类型、大小、修改时间和路径，子项按深度缩进
+func formatDirEntries(entries []dirEntryInfo) string {
+	var sb strings.Builder
+	for _, entry := range entries {
+		name := path.Base(entry.RelPath)
+		size := "-"
+		switch entry.Type {
+		case "dir":
+			name += "/"
+		case "symlink":
+			name += " -> " + entry.Target
+		case "file":
+			size = fmt.Sprintf("%d", entry.Size)
+		}
+		fmt.Fprintf(&sb, "%-7s %10s  %s  %s%s\n",
+			entry.Type, size, entry.ModTime.Format("2006-01-02 15:04"),
+			strings.Repeat("  ", entry.depth-1), name)
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
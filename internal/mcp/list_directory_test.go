@@ -0,0 +1,53 @@
+package mcp
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestListDirectory(t *testing.T) {
+	root := t.TempDir()
+	for name, size := range map[string]int{"a.txt": 1, "big.bin": 100, "sub/c.go": 10, "sub/deep/d.go": 5, "node_modules/x.js": 1, "debug.log": 3} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("a.txt", filepath.Join(root, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	paths := func(entries []dirEntryInfo) string {
+		var out []string
+		for _, e := range entries {
+			out = append(out, e.RelPath)
+		}
+		return strings.Join(out, ",")
+	}
+
+	entries, _, err := listDirectory(root, listOptions{Ignore: []string{"node_modules", "*.log"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := paths(entries); got != "sub,a.txt,big.bin,link" {
+		t.Errorf("default listing = %s", got)
+	}
+	for _, e := range entries {
+		if e.RelPath == "link" && (e.Type != "symlink" || e.Target != "a.txt") {
+			t.Errorf("symlink entry = %+v", e)
+		}
+	}
+
+	entries, _, err = listDirectory(root, listOptions{Ignore: []string{"node_modules", "*.log", "sub/deep"}, Depth: 3, SortBy: "size"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := paths(entries); got != "big.bin,a.txt,link,sub,sub/c.go" {
+		t.Errorf("recursive listing = %s", got)
+	}
+}
@@ -0,0 +1,63 @@
+package mcp
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+)
+
+// maxPanicStack 返回给模型的堆栈最多保留的字符数
+const maxPanicStack = 4000
+
+// ToolPanic 工具执行时发生的 panic
+type ToolPanic struct {
+	Tool  string
+	Value interface{}
+	// Stack 完整的堆栈，返回给模型的结果中会被截断
+	Stack string
+}
+
+var (
+	panicMu      sync.RWMutex
+	panicHandler func(ToolPanic)
+)
+
+// SetPanicHandler 设置工具 panic 回调（如发布系统错误事件），nil 表示不通知
+func SetPanicHandler(fn func(ToolPanic)) {
+	panicMu.Lock()
+	defer panicMu.Unlock()
+	panicHandler = fn
+}
+
+// panicError 将 recover 得到的值转换为 CodeInternalError，附带截断的堆栈，并通知 panic 回调
+// 必须在 defer 的 recover 中调用，堆栈才包含 panic 的位置
+func panicError(tool string, value interface{}) *JSONRPCError {
+	stack := string(debug.Stack())
+
+	panicMu.RLock()
+	fn := panicHandler
+	panicMu.RUnlock()
+	if fn != nil {
+		fn(ToolPanic{Tool: tool, Value: value, Stack: stack})
+	}
+
+	truncated, _ := truncateRunes(stack, maxPanicStack)
+	return &JSONRPCError{
+		Code:    CodeInternalError,
+		Message: fmt.Sprintf("panic in tool %s: %v", tool, value),
+		Data: map[string]interface{}{
+			"stack":      truncated,
+			"suggestion": "工具内部发生错误，请勿使用相同参数重试，可换用其他工具或方式完成任务",
+		},
+	}
+}
+
+// executeTool 执行工具，将 panic 转换为错误
+func executeTool(handler ToolHandler, args map[string]interface{}) (result interface{}, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			result, err = nil, panicError(handler.Name(), v)
+		}
+	}()
+	return handler.Execute(args)
+}
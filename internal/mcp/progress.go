@@ -0,0 +1,77 @@
+package mcp
+
+import (
+	"sync"
+	"time"
+)
+
+// progressInterval 两次进度报告之间的最小间隔
+const progressInterval = 200 * time.Millisecond
+
+// Progress 长时间运行的工具的进度
+type Progress struct {
+	Tool string
+	// Done/Total 已处理和总共的文件数，Total 为 0 表示未知
+	Done  int
+	Total int
+	// Bytes/TotalBytes 已处理和总共的字节数
+	Bytes      int64
+	TotalBytes int64
+	// Finished 工具已结束，界面应清除进度显示
+	Finished bool
+}
+
+var (
+	progressMu      sync.RWMutex
+	progressHandler func(Progress)
+)
+
+// SetProgressHandler 设置进度回调（如转发给界面），nil 表示不报告
+func SetProgressHandler(fn func(Progress)) {
+	progressMu.Lock()
+	defer progressMu.Unlock()
+	progressHandler = fn
+}
+
+// reportProgress 调用进度回调
+func reportProgress(p Progress) {
+	progressMu.RLock()
+	fn := progressHandler
+	progressMu.RUnlock()
+	if fn != nil {
+		fn(p)
+	}
+}
+
+// progressReporter 按时间间隔节流的进度报告
+type progressReporter struct {
+	progress Progress
+	last     time.Time
+}
+
+// newProgressReporter 创建某个工具的进度报告器
+func newProgressReporter(tool string, totalFiles int, totalBytes int64) *progressReporter {
+	return &progressReporter{progress: Progress{Tool: tool, Total: totalFiles, TotalBytes: totalBytes}}
+}
+
+// add 记录一个已处理的文件，距上次报告超过间隔时报告
+func (r *progressReporter) add(size int64) {
+	if r == nil {
+		return
+	}
+	r.progress.Done++
+	r.progress.Bytes += size
+	if now := time.Now(); now.Sub(r.last) >= progressInterval {
+		r.last = now
+		reportProgress(r.progress)
+	}
+}
+
+// finish 报告工具结束
+func (r *progressReporter) finish() {
+	if r == nil {
+		return
+	}
+	r.progress.Finished = true
+	reportProgress(r.progress)
+}
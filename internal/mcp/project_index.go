@@ -0,0 +1,433 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// projectIndexFile 文件元数据和符号索引文件名（位于 semanticIndexDir）
+	projectIndexFile = "files.gob"
+	// projectIndexVersion 索引格式版本，格式变化时整体重建
+	projectIndexVersion = 1
+	// projectIndexMaxFileSize 超过该大小的文件不建立索引
+	projectIndexMaxFileSize = 1024 * 1024
+	// projectIndexPollInterval 后台扫描文件变化的间隔
+	projectIndexPollInterval = 15 * time.Second
+	// projectIndexEventBuffer 文件变更事件队列长度，队列满时等待下次扫描
+	projectIndexEventBuffer = 256
+)
+
+// indexSkipDirs 建立索引时跳过的目录
+var indexSkipDirs = map[string]bool{
+	"node_modules": true,
+	"vendor":       true,
+	"dist":         true,
+	"build":        true,
+	"target":       true,
+	"__pycache__":  true,
+}
+
+// FileMeta 索引中单个文件的元数据
+type FileMeta struct {
+	Size     int64
+	ModTime  time.Time
+	Hash     string
+	Language string
+	Lines    int
+	Symbols  []Symbol
+}
+
+// projectIndexData 持久化到磁盘的索引内容
+type projectIndexData struct {
+	Version int
+	Files   map[string]*FileMeta
+}
+
+// symbolMatch 一条符号查询结果
+type symbolMatch struct {
+	Path string
+	Symbol
+}
+
+// projectRefreshStats 一次扫描的统计
+type projectRefreshStats struct {
+	Updated int
+	Removed int
+	Total   int
+}
+
+// ProjectIndex 项目文件索引（元数据与符号），持久化在 .polyagent/index 下
+// 后台定期扫描并响应 FileEngine 的写入事件增量更新，查询只读内存数据
+type ProjectIndex struct {
+	mu      sync.RWMutex
+	engine  *FileEngine
+	root    string
+	path    string
+	files   map[string]*FileMeta
+	events  chan string
+	ready   chan struct{}
+	started sync.Once
+	stop    chan struct{}
+}
+
+// NewProjectIndex 创建项目索引，调用 Start 后开始后台维护
+func NewProjectIndex(engine *FileEngine, root string) *ProjectIndex {
+	return &ProjectIndex{
+		engine: engine,
+		root:   root,
+		path:   filepath.Join(root, semanticIndexDir, projectIndexFile),
+		files:  make(map[string]*FileMeta),
+		events: make(chan string, projectIndexEventBuffer),
+		ready:  make(chan struct{}),
+		stop:   make(chan struct{}),
+	}
+}
+
+// ProjectRoot 返回 FileEngine 的第一个允许根目录（绝对路径）作为项目根目录
+func ProjectRoot(engine *FileEngine) string {
+	root := "."
+	if len(engine.config.AllowedRoots) > 0 {
+		root = engine.config.AllowedRoots[0]
+	}
+	if absRoot, err := filepath.Abs(root); err == nil {
+		return absRoot
+	}
+	return root
+}
+
+// StartProjectIndex 在后台提前建立项目索引，避免第一次查找符号时等待全量扫描
+func (r *ToolRegistry) StartProjectIndex() {
+	if r.project != nil {
+		r.project.Start()
+	}
+}
+
+// Root 返回项目根目录
+func (p *ProjectIndex) Root() string {
+	return p.root
+}
+
+// Start 加载已有索引并启动后台维护，重复调用无效
+func (p *ProjectIndex) Start() {
+	p.started.Do(func() {
+		p.engine.OnChange(p.NotifyChanged)
+		go p.run()
+	})
+}
+
+// Stop 停止后台维护
+func (p *ProjectIndex) Stop() {
+	select {
+	case <-p.stop:
+	default:
+		close(p.stop)
+	}
+}
+
+// run 后台循环：首次全量扫描，之后按事件和定时扫描增量更新
+func (p *ProjectIndex) run() {
+	p.load()
+	p.Refresh()
+	close(p.ready)
+
+	ticker := time.NewTicker(projectIndexPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case path := <-p.events:
+			if p.updateFile(path) {
+				p.save()
+			}
+		case <-ticker.C:
+			p.Refresh()
+		}
+	}
+}
+
+// WaitReady 等待首次扫描完成
+func (p *ProjectIndex) WaitReady(ctx context.Context) error {
+	p.Start()
+	select {
+	case <-p.ready:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("项目索引尚未就绪: %w", ctx.Err())
+	}
+}
+
+// NotifyChanged 通知文件已变化，队列已满时留给下一次定时扫描处理
+func (p *ProjectIndex) NotifyChanged(path string) {
+	select {
+	case p.events <- path:
+	default:
+	}
+}
+
+// load 从磁盘加载索引，不存在或版本不符时从空索引开始
+func (p *ProjectIndex) load() {
+	content, err := os.ReadFile(p.path)
+	if err != nil {
+		return
+	}
+
+	var data projectIndexData
+	if err := gob.NewDecoder(bytes.NewReader(content)).Decode(&data); err != nil {
+		return
+	}
+	if data.Version != projectIndexVersion || data.Files == nil {
+		return
+	}
+
+	p.mu.Lock()
+	p.files = data.Files
+	p.mu.Unlock()
+}
+
+// save 原子写入索引文件，写入失败时只影响下次启动的速度
+func (p *ProjectIndex) save() error {
+	p.mu.RLock()
+	data := projectIndexData{Version: projectIndexVersion, Files: p.files}
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(&data)
+	p.mu.RUnlock()
+	if err != nil {
+		return fmt.Errorf("序列化索引失败: %w", err)
+	}
+
+	return writeIndexFile(p.path, buf.Bytes())
+}
+
+// Refresh 扫描项目，更新新增或修改的文件并移除已删除的文件
+func (p *ProjectIndex) Refresh() (projectRefreshStats, error) {
+	var stats projectRefreshStats
+	seen := make(map[string]bool)
+
+	err := filepath.WalkDir(p.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // 跳过无法访问的条目
+		}
+		if d.IsDir() {
+			if path != p.root && skipIndexDir(d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, ok := p.relPath(path)
+		if !ok {
+			return nil
+		}
+		seen[rel] = true
+
+		if p.updateFile(path) {
+			stats.Updated++
+		}
+		return nil
+	})
+	if err != nil {
+		return stats, fmt.Errorf("扫描项目失败: %w", err)
+	}
+
+	p.mu.Lock()
+	for rel := range p.files {
+		if !seen[rel] {
+			delete(p.files, rel)
+			stats.Removed++
+		}
+	}
+	stats.Total = len(p.files)
+	p.mu.Unlock()
+
+	if stats.Updated > 0 || stats.Removed > 0 {
+		if err := p.save(); err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
+
+// updateFile 更新单个文件的索引，返回索引是否发生变化
+func (p *ProjectIndex) updateFile(path string) bool {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(p.root, path)
+	}
+	rel, ok := p.relPath(path)
+	if !ok {
+		return false
+	}
+
+	p.mu.RLock()
+	existing := p.files[rel]
+	p.mu.RUnlock()
+
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() || info.Size() == 0 || info.Size() > projectIndexMaxFileSize || p.engine.ValidatePath(path) != nil {
+		return p.remove(rel)
+	}
+
+	// 大小和修改时间未变时跳过
+	if existing != nil && existing.Size == info.Size() && existing.ModTime.Equal(info.ModTime()) {
+		return false
+	}
+
+	content, err := p.engine.ReadFile(path, true)
+	if err != nil || isBinaryContent(content) {
+		return p.remove(rel)
+	}
+
+	hash := ContentHash(content)
+	meta := &FileMeta{
+		Size:     info.Size(),
+		ModTime:  info.ModTime(),
+		Hash:     hash,
+		Language: detectLanguage(path),
+	}
+	if existing != nil && existing.Hash == hash {
+		meta.Lines = existing.Lines
+		meta.Symbols = existing.Symbols
+	} else {
+		text := string(content)
+		meta.Lines = strings.Count(text, "\n") + 1
+		meta.Symbols = extractSymbols(meta.Language, text)
+	}
+
+	p.mu.Lock()
+	p.files[rel] = meta
+	p.mu.Unlock()
+	return true
+}
+
+// remove 从索引中移除文件，返回是否存在
+func (p *ProjectIndex) remove(rel string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.files[rel]; !ok {
+		return false
+	}
+	delete(p.files, rel)
+	return true
+}
+
+// relPath 返回相对项目根目录的路径，不在根目录内或位于跳过目录时返回 false
+func (p *ProjectIndex) relPath(path string) (string, bool) {
+	rel, err := filepath.Rel(p.root, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return "", false
+	}
+	for _, part := range strings.Split(filepath.Dir(rel), string(filepath.Separator)) {
+		if part != "." && skipIndexDir(part) {
+			return "", false
+		}
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// Snapshot 返回当前所有文件元数据的副本
+func (p *ProjectIndex) Snapshot() map[string]FileMeta {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	result := make(map[string]FileMeta, len(p.files))
+	for rel, meta := range p.files {
+		result[rel] = *meta
+	}
+	return result
+}
+
+// FindSymbol 查找符号定义：精确匹配优先，其次前缀匹配，最后是包含匹配（均不区分大小写）。
+// pathPrefix 非空时只在该目录下查找
+func (p *ProjectIndex) FindSymbol(name, kind, pathPrefix string, limit int) []symbolMatch {
+	query := strings.ToLower(name)
+	pathPrefix = strings.TrimPrefix(filepath.ToSlash(pathPrefix), "./")
+
+	type ranked struct {
+		match symbolMatch
+		rank  int
+	}
+	var results []ranked
+
+	p.mu.RLock()
+	for rel, meta := range p.files {
+		if pathPrefix != "" && !strings.HasPrefix(rel, pathPrefix) {
+			continue
+		}
+		for _, sym := range meta.Symbols {
+			if kind != "" && sym.Kind != kind {
+				continue
+			}
+			lower := strings.ToLower(sym.Name)
+			rank := -1
+			switch {
+			case sym.Name == name:
+				rank = 0
+			case lower == query:
+				rank = 1
+			case strings.HasPrefix(lower, query):
+				rank = 2
+			case strings.Contains(lower, query):
+				rank = 3
+			}
+			if rank >= 0 {
+				results = append(results, ranked{symbolMatch{Path: rel, Symbol: sym}, rank})
+			}
+		}
+	}
+	p.mu.RUnlock()
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].rank != results[j].rank {
+			return results[i].rank < results[j].rank
+		}
+		if results[i].match.Path != results[j].match.Path {
+			return results[i].match.Path < results[j].match.Path
+		}
+		return results[i].match.Line < results[j].match.Line
+	})
+
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	matches := make([]symbolMatch, len(results))
+	for i, r := range results {
+		matches[i] = r.match
+	}
+	return matches
+}
+
+// skipIndexDir 判断目录是否应跳过（隐藏目录和常见依赖/构建目录）
+func skipIndexDir(name string) bool {
+	return strings.HasPrefix(name, ".") || indexSkipDirs[name]
+}
+
+// writeIndexFile 通过临时文件原子写入索引
+func writeIndexFile(path string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("创建索引目录失败: %w", err)
+	}
+
+	tempFile := path + ".tmp"
+	if err := os.WriteFile(tempFile, content, 0644); err != nil {
+		return fmt.Errorf("写入索引失败: %w", err)
+	}
+	if err := os.Rename(tempFile, path); err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("写入索引失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package mcp
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestProjectIndexStartsLazily(t *testing.T) {
+	root := t.TempDir()
+	config := DefaultConfig()
+	config.AllowedRoots = []string{root}
+	config.BackupDir = filepath.Join(t.TempDir(), "backups")
+	registry := DefaultToolRegistry(config)
+	defer registry.project.Stop()
+
+	// 创建注册表不扫描项目，也不在工作目录中写入 .polyagent
+	select {
+	case <-registry.project.ready:
+		t.Fatal("project index started when the registry was created")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	registry.StartProjectIndex()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := registry.project.WaitReady(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if registry.project.Root() != root {
+		t.Errorf("index root = %s, want %s", registry.project.Root(), root)
+	}
+}
@@ -3,6 +3,8 @@ package mcp
 import (
 	"encoding/json"
 	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
 )
 
@@ -26,8 +28,12 @@ const (
 	CodeCacheError     = -32005
 	CodeReadError      = -32006
 	CodeWriteError     = -32007
+	CodeFileNotRead    = -32008
 )
 
+// fileTooLargePattern 从 FileEngine 的错误信息中提取文件大小和上限
+var fileTooLargePattern = regexp.MustCompile(`\(([\d.]+) MB, limit ([\d.]+) MB\)`)
+
 // ConvertToMCPError 将错误转换为 MCP 错误格式
 func ConvertToMCPError(err error) *JSONRPCError {
 	if err == nil {
@@ -41,6 +47,11 @@ func ConvertToMCPError(err error) *JSONRPCError {
 	
 	errStr := err.Error()
 	switch {
+	// 冲突错误附带文件差异，需在其他子串匹配之前判断
+	case strings.Contains(errStr, "write conflict:"):
+		code = CodeWriteError
+		data["suggestion"] = "The file was modified outside the agent; call read_file again and re-plan the change"
+
 	case strings.Contains(errStr, "outside allowed roots"):
 		code = CodePathNotAllowed
 		data["suggestion"] = "Check that the path is within your project directory"
@@ -48,12 +59,20 @@ func ConvertToMCPError(err error) *JSONRPCError {
 	case strings.Contains(errStr, "file too large"):
 		code = CodeFileTooLarge
 		data["max_size_mb"] = 10
+		if m := fileTooLargePattern.FindStringSubmatch(errStr); m != nil {
+			data["size_mb"], _ = strconv.ParseFloat(m[1], 64)
+			data["max_size_mb"], _ = strconv.ParseFloat(m[2], 64)
+		}
 		data["suggestion"] = "Try reading a portion of the file using offset and limit"
 		
 	case strings.Contains(errStr, "no such file") || strings.Contains(errStr, "file does not exist"):
 		code = CodeFileNotFound
 		data["suggestion"] = "Verify the file path exists"
 		
+	case strings.Contains(errStr, "has not been read"):
+		code = CodeFileNotRead
+		data["suggestion"] = "Call read_file on this path first, then retry the change based on its current content"
+		
 	case strings.Contains(errStr, "backup failed"):
 		code = CodeBackupFailed
 		data["suggestion"] = "Check disk space and backup directory permissions"
@@ -64,6 +83,22 @@ func ConvertToMCPError(err error) *JSONRPCError {
 	case strings.Contains(errStr, "file type not allowed"):
 		code = CodePathNotAllowed
 		data["suggestion"] = "The file extension is blacklisted for security reasons"
+
+	case strings.Contains(errStr, "blocked by web_policy") || strings.Contains(errStr, "robots.txt disallows"):
+		code = CodePathNotAllowed
+		data["suggestion"] = "The URL is not allowed by web_policy or the site's robots.txt; try a different source"
+	case strings.Contains(errStr, "工具未找到"):
+		code = CodeMethodNotFound
+		data["suggestion"] = "Only call tools from the provided tool list"
+	case strings.Contains(errStr, "workspace is not trusted"):
+		code = CodePathNotAllowed
+		data["suggestion"] = "Only read-only tools are available; ask the user to run /trust if changes are needed"
+	case strings.Contains(errStr, "write quota exceeded"):
+		code = CodeToolError
+		data["suggestion"] = "Stop writing files and ask the user to raise the limit with /quota"
+	case strings.Contains(errStr, "无效的"):
+		code = CodeInvalidParams
+		data["suggestion"] = "Check the arguments against the tool's parameter schema and retry"
 	}
 	
 	return &JSONRPCError{
@@ -148,6 +183,10 @@ type CallToolRequest struct {
 
 type CallToolResult struct {
 	Content []ToolResultContent `json:"content"`
+	// IsError 工具执行失败，Content 为序列化的 ToolError
+	IsError bool `json:"isError,omitempty"`
+	// Injection 外部内容命中的提示注入规则，只在本地提醒用户，不发送给客户端
+	Injection []string `json:"-"`
 }
 
 type ToolResultContent struct {
@@ -209,11 +248,20 @@ var (
 			},
 			"ignore": map[string]interface{}{
 				"type":        "array",
-				"description": "忽略的glob模式",
+				"description": "忽略的glob模式，不含 / 的模式匹配名称（如 node_modules、*.log），含 / 的匹配相对路径",
 				"items": map[string]interface{}{
 					"type": "string",
 				},
 			},
+			"sort_by": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"name", "size", "mtime"},
+				"description": "排序方式：name 按名称且目录在前（默认），size 按大小，mtime 按修改时间",
+			},
+			"depth": map[string]interface{}{
+				"type":        "integer",
+				"description": "递归深度，1 表示只列出直接子项（默认），最大 10",
+			},
 		},
 		"required": []string{"path"},
 	}
@@ -242,7 +290,7 @@ var (
 		"properties": map[string]interface{}{
 			"pattern": map[string]interface{}{
 				"type":        "string",
-				"description": "glob模式",
+				"description": "glob模式，如 **/*.go；** 匹配任意层目录",
 			},
 			"path": map[string]interface{}{
 				"type":        "string",
@@ -250,7 +298,16 @@ var (
 			},
 			"case_sensitive": map[string]interface{}{
 				"type":        "boolean",
-				"description": "是否区分大小写",
+				"description": "是否区分大小写，默认 true",
+			},
+			"sort_by": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"path", "mtime"},
+				"description": "排序方式：path 按路径（默认），mtime 按修改时间（最近的在前）",
+			},
+			"max_results": map[string]interface{}{
+				"type":        "integer",
+				"description": "最多返回的结果数，默认 500，超出时标记为 truncated",
 			},
 		},
 		"required": []string{"pattern"},
@@ -299,6 +356,11 @@ var (
 				"type":        "string",
 				"description": "执行目录",
 			},
+			"extra_env": map[string]interface{}{
+				"type":                 "object",
+				"description":          "仅本次调用额外设置的环境变量（变量名到值）",
+				"additionalProperties": map[string]interface{}{"type": "string"},
+			},
 		},
 		"required": []string{"command"},
 	}
@@ -320,6 +382,10 @@ var (
 				"description": "是否覆盖已存在的文件",
 				"default":     false,
 			},
+			"mode": map[string]interface{}{
+				"type":        "string",
+				"description": "八进制文件权限，如 \"0755\" 创建可执行脚本；默认 0644，覆盖时保留原权限",
+			},
 		},
 		"required": []string{"path", "content"},
 	}
@@ -373,13 +439,28 @@ var (
 			},
 			"overwrite": map[string]interface{}{
 				"type":        "boolean",
-				"description": "是否覆盖已存在的文件",
+				"description": "是否覆盖已存在的文件（目标为目录时合并内容，同名文件被替换）",
 				"default":     false,
 			},
 		},
 		"required": []string{"source", "destination"},
 	}
 
+	ChmodSchema = map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "文件路径",
+			},
+			"mode": map[string]interface{}{
+				"type":        "string",
+				"description": "八进制权限（如 \"0755\"），或 \"+x\" / \"-x\" 添加或移除可执行位",
+			},
+		},
+		"required": []string{"path", "mode"},
+	}
+
 	GetFileInfoSchema = map[string]interface{}{
 		"type": "object",
 		"properties": map[string]interface{}{
@@ -387,6 +468,10 @@ var (
 				"type":        "string",
 				"description": "文件/目录路径",
 			},
+			"detailed": map[string]interface{}{
+				"type":        "boolean",
+				"description": "是否返回 SHA-256、行数、语言、是否为二进制、git 跟踪/修改状态和备份数，默认 false",
+			},
 		},
 		"required": []string{"path"},
 	}
@@ -408,6 +493,11 @@ var (
 				"description": "超时时间（秒）",
 				"default":     30,
 			},
+			"extra_env": map[string]interface{}{
+				"type":                 "object",
+				"description":          "仅本次调用额外设置的环境变量（变量名到值）",
+				"additionalProperties": map[string]interface{}{"type": "string"},
+			},
 		},
 		"required": []string{"language", "code"},
 	}
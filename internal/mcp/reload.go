@@ -0,0 +1,26 @@
+package mcp
+
+import (
+	"sort"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/config"
+)
+
+// APIKeyReloader 缓存了 API Key 的工具，配置重新加载后由注册表更新
+type APIKeyReloader interface {
+	// ReloadAPIKeys 应用新配置中的 API Key，返回缓存的 Key 是否发生变化
+	ReloadAPIKeys(cfg *config.Config) bool
+}
+
+// ReloadAPIKeys 将重新加载的配置中的 API Key 应用到已注册的工具，返回 Key 发生变化的工具名
+// 之前因未配置 Key 而不可用的工具（如 web_search）在下次调用时即可使用，无需重启
+func (r *ToolRegistry) ReloadAPIKeys(cfg *config.Config) []string {
+	var changed []string
+	for name, handler := range r.tools {
+		if reloader, ok := handler.(APIKeyReloader); ok && reloader.ReloadAPIKeys(cfg) {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
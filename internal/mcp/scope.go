@@ -0,0 +1,79 @@
+package mcp
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ScopedToolNames 受 /scope 限制的搜索类工具：未指定 path 时只在范围目录内搜索
+var ScopedToolNames = map[string]bool{
+	"search_file_content": true,
+	"glob":                true,
+	"advanced_search":     true,
+	"semantic_search":     true,
+	"find_symbol":         true,
+	"file_stats":          true,
+}
+
+// SetScope 将搜索、符号查找和 glob 限定在 dir（如 monorepo 中的 services/api）内，dir 为空时取消限制。
+// 目录必须存在且位于允许访问的根目录中，返回记录的范围（当前目录下的目录使用相对路径）
+func (r *ToolRegistry) SetScope(dir string) (string, error) {
+	if dir == "" {
+		r.scope.Store(nil)
+		return "", nil
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("invalid path: %w", err)
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("not a directory: %s", abs)
+	}
+	if r.engine != nil {
+		if err := r.engine.ValidatePath(abs); err != nil {
+			return "", err
+		}
+	}
+
+	scope := abs
+	if cwd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(cwd, abs); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			scope = filepath.ToSlash(rel)
+		}
+	}
+	if scope == "." {
+		// 范围是整个项目时等同于不限制
+		r.scope.Store(nil)
+		return "", nil
+	}
+	r.scope.Store(&scope)
+	return scope, nil
+}
+
+// Scope 返回当前的搜索范围，没有限制时返回空字符串
+func (r *ToolRegistry) Scope() string {
+	if scope := r.scope.Load(); scope != nil {
+		return *scope
+	}
+	return ""
+}
+
+// applyScope 对未指定 path 的搜索类工具调用填入搜索范围，返回附加在工具结果后面的说明；
+// 显式指定 path 时不受限制，模型仍可按需搜索范围之外的代码
+func (r *ToolRegistry) applyScope(name string, args map[string]interface{}) string {
+	scope := r.Scope()
+	if scope == "" || !ScopedToolNames[name] {
+		return ""
+	}
+	if path, ok := args["path"].(string); ok && path != "" {
+		return ""
+	}
+	args["path"] = scope
+	return fmt.Sprintf("(搜索范围已限定在 %s，需要搜索整个仓库时请显式传入 path: \".\")", scope)
+}
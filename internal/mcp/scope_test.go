@@ -0,0 +1,70 @@
+package mcp
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScopeRestrictsSearches(t *testing.T) {
+	root := t.TempDir()
+	for name, content := range map[string]string{
+		"services/api/handler.go": "package api\n\nfunc Handle() {}\n",
+		"services/web/handler.go": "package web\n\nfunc Handle() {}\n",
+	} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Chdir(root)
+
+	config := DefaultConfig()
+	config.BackupDir = filepath.Join(t.TempDir(), "backups")
+	registry := DefaultToolRegistry(config)
+
+	if _, err := registry.SetScope("services/missing"); err == nil {
+		t.Error("missing directory should be rejected")
+	}
+	if _, err := registry.SetScope(t.TempDir()); err == nil {
+		t.Error("directory outside the allowed roots should be rejected")
+	}
+	scope, err := registry.SetScope(filepath.Join(root, "services", "api"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scope != "services/api" || registry.Scope() != scope {
+		t.Fatalf("scope = %q", scope)
+	}
+
+	search := func(args map[string]interface{}) string {
+		t.Helper()
+		result, err := registry.HandleCallTool(CallToolRequest{Name: "glob", Arguments: args})
+		if err != nil || result.IsError {
+			t.Fatalf("glob: %v %+v", err, result)
+		}
+		return result.Content[0].Text
+	}
+	text := search(map[string]interface{}{"pattern": "**/*.go"})
+	if !strings.Contains(text, "api") || strings.Contains(text, "web") || !strings.Contains(text, "services/api") {
+		t.Errorf("scoped glob = %q", text)
+	}
+	// 显式指定 path 时可以搜索范围之外的目录
+	if text := search(map[string]interface{}{"pattern": "**/*.go", "path": "."}); !strings.Contains(text, "web") {
+		t.Errorf("explicit path should not be scoped: %q", text)
+	}
+
+	// 不是搜索类工具时不填入范围
+	args := map[string]interface{}{}
+	if note := registry.applyScope("list_directory", args); note != "" || args["path"] != nil {
+		t.Errorf("list_directory should not be scoped: %v", args)
+	}
+
+	if scope, err := registry.SetScope("."); err != nil || scope != "" || registry.Scope() != "" {
+		t.Errorf("scoping to the project root should remove the scope: %q %v", scope, err)
+	}
+}
@@ -0,0 +1,308 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// semanticIndexDir 索引目录（相对项目根目录）
+	semanticIndexDir = ".polyagent/index"
+	// semanticIndexFile 向量索引文件名
+	semanticIndexFile = "embeddings.gob"
+	// semanticIndexVersion 索引格式版本，格式变化时整体重建
+	semanticIndexVersion = 2
+
+	indexChunkLines    = 60
+	indexChunkOverlap  = 10
+	indexMaxChunkChars = 2000
+	indexMaxFileSize   = 256 * 1024
+)
+
+// Embedder 文本向量化接口，由 api.Client 实现
+type Embedder interface {
+	CreateEmbeddings(ctx context.Context, inputs []string) ([][]float64, error)
+}
+
+// indexedChunk 文件中的一个片段及其归一化向量
+type indexedChunk struct {
+	StartLine int
+	EndLine   int
+	Vector    []float32
+}
+
+// indexedFile 单个文件的向量，Hash 为计算向量时的内容哈希
+type indexedFile struct {
+	Hash   string
+	Chunks []indexedChunk
+}
+
+// semanticIndexData 持久化到磁盘的索引内容
+type semanticIndexData struct {
+	Version int
+	Files   map[string]*indexedFile
+}
+
+// semanticMatch 一条语义搜索结果
+type semanticMatch struct {
+	Path      string
+	StartLine int
+	EndLine   int
+	Score     float64
+}
+
+// indexUpdateStats 一次增量更新的统计
+type indexUpdateStats struct {
+	Indexed   int
+	Unchanged int
+	Removed   int
+	Chunks    int
+}
+
+// SemanticIndex 基于向量余弦相似度的代码语义索引
+// 文件列表和哈希来自 ProjectIndex，只为哈希变化的文件重新计算向量
+type SemanticIndex struct {
+	mu       sync.Mutex
+	engine   *FileEngine
+	project  *ProjectIndex
+	embedder Embedder
+	path     string
+	data     semanticIndexData
+	loaded   bool
+}
+
+// NewSemanticIndex 创建语义索引，索引文件与项目索引位于同一目录
+func NewSemanticIndex(engine *FileEngine, project *ProjectIndex, embedder Embedder) *SemanticIndex {
+	return &SemanticIndex{
+		engine:   engine,
+		project:  project,
+		embedder: embedder,
+		path:     filepath.Join(project.Root(), semanticIndexDir, semanticIndexFile),
+	}
+}
+
+// load 从磁盘加载索引，不存在或版本不符时从空索引开始
+func (idx *SemanticIndex) load() {
+	if idx.loaded {
+		return
+	}
+	idx.loaded = true
+	idx.data = semanticIndexData{Version: semanticIndexVersion, Files: make(map[string]*indexedFile)}
+
+	content, err := os.ReadFile(idx.path)
+	if err != nil {
+		return
+	}
+
+	var data semanticIndexData
+	if err := gob.NewDecoder(bytes.NewReader(content)).Decode(&data); err != nil {
+		return
+	}
+	if data.Version != semanticIndexVersion || data.Files == nil {
+		return
+	}
+	idx.data = data
+}
+
+// save 原子写入索引文件
+func (idx *SemanticIndex) save() error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&idx.data); err != nil {
+		return fmt.Errorf("序列化索引失败: %w", err)
+	}
+	return writeIndexFile(idx.path, buf.Bytes())
+}
+
+// Update 按项目索引同步向量：为新增或哈希变化的文件计算向量，移除已删除的文件
+func (idx *SemanticIndex) Update(ctx context.Context) (indexUpdateStats, error) {
+	var stats indexUpdateStats
+	if err := idx.project.WaitReady(ctx); err != nil {
+		return stats, err
+	}
+	files := idx.project.Snapshot()
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.load()
+
+	type pendingFile struct {
+		rel    string
+		entry  *indexedFile
+		chunks []indexedChunk
+		texts  []string
+	}
+	var pending []pendingFile
+
+	for rel, meta := range files {
+		if existing := idx.data.Files[rel]; existing != nil && existing.Hash == meta.Hash {
+			stats.Unchanged++
+			continue
+		}
+		if meta.Size > indexMaxFileSize {
+			continue
+		}
+
+		path := filepath.Join(idx.project.Root(), filepath.FromSlash(rel))
+		content, err := idx.engine.ReadFile(path, true)
+		if err != nil {
+			continue
+		}
+
+		entry := &indexedFile{Hash: ContentHash(content)}
+		chunks, texts := chunkForIndex(rel, string(content))
+		pending = append(pending, pendingFile{rel: rel, entry: entry, chunks: chunks, texts: texts})
+	}
+
+	// 批量计算所有变化文件的向量
+	var texts []string
+	for _, p := range pending {
+		texts = append(texts, p.texts...)
+	}
+	if len(texts) > 0 {
+		vectors, err := idx.embedder.CreateEmbeddings(ctx, texts)
+		if err != nil {
+			return stats, fmt.Errorf("计算向量失败: %w", err)
+		}
+
+		offset := 0
+		for _, p := range pending {
+			for i := range p.chunks {
+				p.chunks[i].Vector = normalizeVector(vectors[offset])
+				offset++
+			}
+			p.entry.Chunks = p.chunks
+			idx.data.Files[p.rel] = p.entry
+			stats.Indexed++
+			stats.Chunks += len(p.chunks)
+		}
+	}
+
+	for rel := range idx.data.Files {
+		if _, ok := files[rel]; !ok {
+			delete(idx.data.Files, rel)
+			stats.Removed++
+		}
+	}
+
+	if stats.Indexed > 0 || stats.Removed > 0 {
+		if err := idx.save(); err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
+
+// Search 返回与查询语义最相近的片段，pathPrefix 非空时只在该目录下搜索
+func (idx *SemanticIndex) Search(ctx context.Context, query string, topK int, pathPrefix string) ([]semanticMatch, error) {
+	vectors, err := idx.embedder.CreateEmbeddings(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("计算查询向量失败: %w", err)
+	}
+	if len(vectors) == 0 {
+		return nil, fmt.Errorf("计算查询向量失败: 结果为空")
+	}
+	queryVec := normalizeVector(vectors[0])
+	pathPrefix = strings.TrimPrefix(filepath.ToSlash(pathPrefix), "./")
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.load()
+
+	var matches []semanticMatch
+	for rel, file := range idx.data.Files {
+		if pathPrefix != "" && !strings.HasPrefix(rel, pathPrefix) {
+			continue
+		}
+		for _, chunk := range file.Chunks {
+			matches = append(matches, semanticMatch{
+				Path:      rel,
+				StartLine: chunk.StartLine,
+				EndLine:   chunk.EndLine,
+				Score:     dotProduct(queryVec, chunk.Vector),
+			})
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	if len(matches) > topK {
+		matches = matches[:topK]
+	}
+	return matches, nil
+}
+
+// chunkForIndex 按行切分文件，返回片段位置和用于向量化的文本
+func chunkForIndex(rel, content string) ([]indexedChunk, []string) {
+	lines := strings.Split(content, "\n")
+	var chunks []indexedChunk
+	var texts []string
+
+	step := indexChunkLines - indexChunkOverlap
+	for start := 0; start < len(lines); start += step {
+		end := start + indexChunkLines
+		if end > len(lines) {
+			end = len(lines)
+		}
+
+		text := strings.Join(lines[start:end], "\n")
+		if strings.TrimSpace(text) != "" {
+			text, _ = truncateRunes(text, indexMaxChunkChars)
+			chunks = append(chunks, indexedChunk{StartLine: start + 1, EndLine: end})
+			texts = append(texts, fmt.Sprintf("文件: %s\n%s", rel, text))
+		}
+
+		if end == len(lines) {
+			break
+		}
+	}
+	return chunks, texts
+}
+
+// isBinaryContent 通过前 8KB 是否包含 NUL 字节判断二进制文件
+func isBinaryContent(content []byte) bool {
+	sample := content
+	if len(sample) > 8192 {
+		sample = sample[:8192]
+	}
+	return bytes.IndexByte(sample, 0) >= 0
+}
+
+// normalizeVector 归一化向量，之后余弦相似度等于点积
+func normalizeVector(vec []float64) []float32 {
+	var norm float64
+	for _, v := range vec {
+		norm += v * v
+	}
+	norm = math.Sqrt(norm)
+
+	result := make([]float32, len(vec))
+	if norm == 0 {
+		return result
+	}
+	for i, v := range vec {
+		result[i] = float32(v / norm)
+	}
+	return result
+}
+
+// dotProduct 计算两个归一化向量的点积
+func dotProduct(a, b []float32) float64 {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	var sum float64
+	for i := 0; i < n; i++ {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
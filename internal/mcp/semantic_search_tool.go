@@ -0,0 +1,182 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/api"
+	"github.com/Zacy-Sokach/PolyAgent/internal/config"
+)
+
+const (
+	semanticSearchTimeout = 5 * time.Minute
+	semanticPreviewLines  = 8
+)
+
+// SemanticSearchTool 按语义搜索项目代码
+type SemanticSearchTool struct {
+	engine  *FileEngine
+	project *ProjectIndex
+	mu      sync.Mutex
+	index   *SemanticIndex
+	// apiKey 创建 index 时使用的 API Key
+	apiKey string
+}
+
+// NewSemanticSearchTool 创建语义搜索工具，向量在首次使用时计算
+func NewSemanticSearchTool(engine *FileEngine, project *ProjectIndex) *SemanticSearchTool {
+	return &SemanticSearchTool{engine: engine, project: project}
+}
+
+func (t *SemanticSearchTool) Name() string {
+	return "semantic_search"
+}
+
+func (t *SemanticSearchTool) Description() string {
+	return "按含义搜索项目代码，返回最相关的文件片段。适合不知道具体名称、只知道功能描述时使用；精确匹配请用 search_file_content"
+}
+
+func (t *SemanticSearchTool) GetSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"query": map[string]interface{}{
+				"type":        "string",
+				"description": "用自然语言描述要查找的代码，例如“处理 API 重试的逻辑”",
+			},
+			"top_k": map[string]interface{}{
+				"type":        "integer",
+				"description": "返回结果数量 (1-20，默认 5)",
+				"default":     5,
+			},
+			"path": map[string]interface{}{
+				"type":        "string",
+				"description": "只在该目录下搜索（相对项目根目录，可选）",
+			},
+		},
+		"required": []string{"query"},
+	}
+}
+
+func (t *SemanticSearchTool) Execute(args map[string]interface{}) (interface{}, error) {
+	query, ok := args["query"].(string)
+	if !ok || strings.TrimSpace(query) == "" {
+		return nil, fmt.Errorf("invalid argument: query is required")
+	}
+
+	topK := getIntArg(args, "top_k", 5)
+	if topK < 1 {
+		topK = 1
+	} else if topK > 20 {
+		topK = 20
+	}
+	pathPrefix, _ := args["path"].(string)
+
+	index, err := t.ensureIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), semanticSearchTimeout)
+	defer cancel()
+
+	// 增量更新：只重新计算变化文件的向量
+	stats, err := index.Update(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("更新语义索引失败: %w", err)
+	}
+
+	matches, err := index.Search(ctx, query, topK, pathPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.formatResults(query, matches, stats), nil
+}
+
+// ensureIndex 使用配置中的 API Key 创建索引
+func (t *SemanticSearchTool) ensureIndex() (*SemanticIndex, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.index != nil {
+		return t.index, nil
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load API key: %w", err)
+	}
+	if cfg.APIKey == "" {
+		return nil, fmt.Errorf("API key not configured")
+	}
+
+	t.index = NewSemanticIndex(t.engine, t.project, api.NewClient(cfg.APIKey))
+	t.apiKey = cfg.APIKey
+	return t.index, nil
+}
+
+// ReloadAPIKeys API Key 变化时丢弃内存中的索引，下次搜索用新 Key 重建（已计算的向量从磁盘加载）
+func (t *SemanticSearchTool) ReloadAPIKeys(cfg *config.Config) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.index == nil || t.apiKey == cfg.APIKey {
+		return false
+	}
+	t.index = nil
+	t.apiKey = ""
+	return true
+}
+
+// formatResults 格式化搜索结果，附带每个片段开头几行作为预览
+func (t *SemanticSearchTool) formatResults(query string, matches []semanticMatch, stats indexUpdateStats) string {
+	var builder strings.Builder
+
+	builder.WriteString(fmt.Sprintf("# 🔎 语义搜索: %q\n\n", query))
+	if stats.Indexed > 0 || stats.Removed > 0 {
+		builder.WriteString(fmt.Sprintf("_索引已更新：%d 个文件重新计算，%d 个文件移除_\n\n", stats.Indexed, stats.Removed))
+	}
+
+	if len(matches) == 0 {
+		builder.WriteString("未找到相关代码。\n")
+		return builder.String()
+	}
+
+	for i, match := range matches {
+		builder.WriteString(fmt.Sprintf("## %d. %s:%d-%d (相似度 %.3f)\n\n", i+1, match.Path, match.StartLine, match.EndLine, match.Score))
+		if preview := t.preview(match); preview != "" {
+			builder.WriteString("```\n")
+			builder.WriteString(preview)
+			builder.WriteString("\n```\n\n")
+		}
+	}
+
+	return builder.String()
+}
+
+// preview 读取片段开头的几行
+func (t *SemanticSearchTool) preview(match semanticMatch) string {
+	path := filepath.Join(t.project.Root(), filepath.FromSlash(match.Path))
+	content, err := t.engine.ReadFile(path, false)
+	if err != nil {
+		return ""
+	}
+
+	lines := strings.Split(string(content), "\n")
+	start := match.StartLine - 1
+	if start < 0 || start >= len(lines) {
+		return ""
+	}
+	end := start + semanticPreviewLines
+	if end > match.EndLine {
+		end = match.EndLine
+	}
+	if end > len(lines) {
+		end = len(lines)
+	}
+	return strings.Join(lines[start:end], "\n")
+}
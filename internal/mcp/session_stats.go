@@ -0,0 +1,80 @@
+package mcp
+
+import (
+	"maps"
+	"path/filepath"
+	"sync"
+)
+
+// SessionStats 本次会话的工具使用统计
+type SessionStats struct {
+	// Calls 按工具统计的调用次数，Failed 为其中失败的次数
+	Calls  map[string]int
+	Failed int
+	// FilesRead/FilesWritten 读取和写入过的不同文件数
+	FilesRead    int
+	FilesWritten int
+	// CacheHits/CacheMisses 读取文件时内容缓存的命中和未命中次数
+	CacheHits   int64
+	CacheMisses int64
+}
+
+// sessionStats 注册表记录的会话统计，工具在后台执行，读写时需要加锁
+type sessionStats struct {
+	mu      sync.Mutex
+	calls   map[string]int
+	failed  int
+	read    map[string]bool
+	written map[string]bool
+}
+
+// recordStats 记录一次工具调用，成功时记下读取或写入的文件
+func (r *ToolRegistry) recordStats(req CallToolRequest, err error) {
+	s := &r.stats
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.calls == nil {
+		s.calls = make(map[string]int)
+		s.read = make(map[string]bool)
+		s.written = make(map[string]bool)
+	}
+	s.calls[req.Name]++
+	if err != nil {
+		s.failed++
+		return
+	}
+	if req.Name == "read_file" {
+		if path, ok := req.Arguments["path"].(string); ok && path != "" {
+			s.read[statsPath(path)] = true
+		}
+	}
+	if path := writeTarget(req.Name, req.Arguments); path != "" {
+		s.written[statsPath(path)] = true
+	}
+}
+
+// statsPath 统一路径的写法，同一文件的相对和绝对路径只计一次
+func statsPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
+// SessionStats 返回本次会话的工具使用统计
+func (r *ToolRegistry) SessionStats() SessionStats {
+	s := &r.stats
+	s.mu.Lock()
+	stats := SessionStats{
+		Calls:        maps.Clone(s.calls),
+		Failed:       s.failed,
+		FilesRead:    len(s.read),
+		FilesWritten: len(s.written),
+	}
+	s.mu.Unlock()
+	if r.engine != nil && r.engine.cache != nil {
+		stats.CacheHits = r.engine.cache.hits.Load()
+		stats.CacheMisses = r.engine.cache.misses.Load()
+	}
+	return stats
+}
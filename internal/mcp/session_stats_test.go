@@ -0,0 +1,45 @@
+package mcp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSessionStats(t *testing.T) {
+	root := t.TempDir()
+	t.Chdir(root)
+	if err := os.WriteFile("main.go", []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config := DefaultConfig()
+	config.BackupDir = filepath.Join(t.TempDir(), "backups")
+	registry := DefaultToolRegistry(config)
+
+	for _, req := range []CallToolRequest{
+		{Name: "read_file", Arguments: map[string]interface{}{"path": "main.go"}},
+		{Name: "read_file", Arguments: map[string]interface{}{"path": filepath.Join(root, "main.go")}},
+		{Name: "read_file", Arguments: map[string]interface{}{"path": "main.go"}},
+		{Name: "write_file", Arguments: map[string]interface{}{"path": "out.txt", "content": "x"}},
+		{Name: "read_file", Arguments: map[string]interface{}{"path": "missing.go"}},
+	} {
+		if _, err := registry.HandleCallTool(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats := registry.SessionStats()
+	if stats.Calls["read_file"] != 4 || stats.Calls["write_file"] != 1 {
+		t.Errorf("calls = %v", stats.Calls)
+	}
+	if stats.Failed != 1 {
+		t.Errorf("failed = %d", stats.Failed)
+	}
+	// 同一文件的相对和绝对路径只计一次
+	if stats.FilesRead != 1 || stats.FilesWritten != 1 {
+		t.Errorf("files read %d, written %d", stats.FilesRead, stats.FilesWritten)
+	}
+	if stats.CacheHits != 1 || stats.CacheMisses != 3 {
+		t.Errorf("cache hits %d, misses %d", stats.CacheHits, stats.CacheMisses)
+	}
+}
@@ -0,0 +1,169 @@
+package mcp
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/config"
+)
+
+// defaultPassEnv 默认传递给命令的环境变量（支持 * 通配），不包含任何凭据
+var defaultPassEnv = []string{
+	"PATH", "HOME", "USER", "LOGNAME", "SHELL", "PWD",
+	"LANG", "LANGUAGE", "LC_*", "TERM", "TZ",
+	"TMPDIR", "TMP", "TEMP",
+	"GOPATH", "GOROOT", "GOCACHE", "GOMODCACHE", "GOFLAGS",
+	// Windows 运行命令所需的变量
+	"SYSTEMROOT", "SYSTEMDRIVE", "COMSPEC", "PATHEXT", "WINDIR",
+	"USERPROFILE", "APPDATA", "LOCALAPPDATA", "PROGRAMDATA", "PROGRAMFILES*",
+	"NUMBER_OF_PROCESSORS", "PROCESSOR_ARCHITECTURE",
+}
+
+// secretEnvName 看起来像凭据的变量名，通配匹配时总是排除
+var secretEnvName = regexp.MustCompile(`(?i)(KEY|TOKEN|SECRET|PASSWORD|PASSWD|CREDENTIAL|AUTH|PRIVATE|COOKIE|SESSION)`)
+
+// envNamePattern 合法的环境变量名
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// minRedactLength 短于该长度的值不做脱敏，避免误伤普通文本
+const minRedactLength = 8
+
+// shellSettings 从配置加载的命令执行设置
+type shellSettings struct {
+	OutputLimit int
+	PassEnv     []string
+	// Secrets 需要从输出中脱敏的凭据（如配置中的 API Key）
+	Secrets []string
+}
+
+// loadShellSettings 读取命令执行相关配置，读取失败时使用默认值
+func loadShellSettings() shellSettings {
+	settings := shellSettings{OutputLimit: defaultShellOutputLimit}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return settings
+	}
+	if cfg.Shell.MaxOutputBytes > 0 {
+		settings.OutputLimit = cfg.Shell.MaxOutputBytes
+	}
+	settings.PassEnv = cfg.Shell.PassEnv
+	settings.Secrets = []string{cfg.APIKey, cfg.TavilyAPIKey}
+	return settings
+}
+
+// ShellArgs 解析后的 run_shell_command 参数
+type ShellArgs struct {
+	Command  string
+	Dir      string
+	ExtraEnv map[string]string
+}
+
+// ParseShellArgs 解析 run_shell_command 的参数，界面的审批检查与工具执行使用同一个解析，两者对参数的理解不会不一致
+func ParseShellArgs(args map[string]interface{}) (ShellArgs, error) {
+	command, ok := args["command"].(string)
+	if !ok || strings.TrimSpace(command) == "" {
+		return ShellArgs{}, fmt.Errorf("缺少或无效的command参数")
+	}
+	extraEnv, err := parseExtraEnv(args["extra_env"])
+	if err != nil {
+		return ShellArgs{}, err
+	}
+	dir, _ := args["dir_path"].(string)
+	return ShellArgs{Command: command, Dir: dir, ExtraEnv: extraEnv}, nil
+}
+
+// parseExtraEnv 解析单次调用的 extra_env 参数
+func parseExtraEnv(value interface{}) (map[string]string, error) {
+	if value == nil {
+		return nil, nil
+	}
+	raw, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid argument: extra_env must be an object")
+	}
+
+	env := make(map[string]string, len(raw))
+	for name, v := range raw {
+		if !envNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("invalid argument: invalid environment variable name %q", name)
+		}
+		env[name] = fmt.Sprint(v)
+	}
+	return env, nil
+}
+
+// buildShellEnv 从父进程环境中筛选允许传递的变量并追加 extra
+// passEnv 为空时使用 defaultPassEnv；精确列出的变量总是传递，通配匹配到的凭据类变量会被排除
+// 返回新环境和被过滤掉的凭据类变量值（用于输出脱敏）
+func buildShellEnv(parent []string, passEnv []string, extra map[string]string) ([]string, []string) {
+	if len(passEnv) == 0 {
+		passEnv = defaultPassEnv
+	}
+
+	var env, scrubbed []string
+	for _, entry := range parent {
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok || name == "" {
+			continue
+		}
+		if _, overridden := extra[name]; overridden {
+			continue
+		}
+
+		secret := secretEnvName.MatchString(name)
+		exact, wildcard := matchEnvName(name, passEnv)
+		switch {
+		case exact || (wildcard && !secret):
+			env = append(env, entry)
+		case secret:
+			scrubbed = append(scrubbed, value)
+		}
+	}
+
+	names := make([]string, 0, len(extra))
+	for name := range extra {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		env = append(env, name+"="+extra[name])
+	}
+	return env, scrubbed
+}
+
+// matchEnvName 判断变量名是否被精确列出或被通配规则匹配（Windows 下不区分大小写）
+func matchEnvName(name string, patterns []string) (exact, wildcard bool) {
+	if runtime.GOOS == "windows" {
+		name = strings.ToUpper(name)
+	}
+	for _, pattern := range patterns {
+		if runtime.GOOS == "windows" {
+			pattern = strings.ToUpper(pattern)
+		}
+		if !strings.ContainsAny(pattern, "*?[") {
+			if pattern == name {
+				return true, false
+			}
+			continue
+		}
+		if matched, _ := path.Match(pattern, name); matched {
+			wildcard = true
+		}
+	}
+	return false, wildcard
+}
+
+// redactSecrets 将输出中出现的凭据替换为占位符
+func redactSecrets(output string, secrets []string) string {
+	for _, secret := range secrets {
+		if len(secret) >= minRedactLength {
+			output = strings.ReplaceAll(output, secret, "[REDACTED]")
+		}
+	}
+	return output
+}
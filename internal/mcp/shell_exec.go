@@ -0,0 +1,220 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/utils"
+)
+
+const (
+	// defaultShellOutputLimit 每个输出流默认最多保留的字节数
+	defaultShellOutputLimit = 32 * 1024
+	// shellCommandTimeout 命令的最长执行时间
+	shellCommandTimeout = 2 * time.Minute
+	// shellWaitDelay 命令结束或被终止后等待输出管道关闭的最长时间（后台子进程可能一直占用管道）
+	shellWaitDelay = 5 * time.Second
+)
+
+// shellResult 命令执行结果
+type shellResult struct {
+	ExitCode int
+	Duration time.Duration
+	Stdout   *headTailBuffer
+	Stderr   *headTailBuffer
+	// LogPath 完整输出的日志文件，输出未被截断时为空
+	LogPath  string
+	TimedOut bool
+}
+
+// runShellCommand 在给定环境中执行命令，每个输出流只在内存中保留开头和结尾，完整输出写入日志文件
+func runShellCommand(ctx context.Context, command, dir string, env []string, limit int) (*shellResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, shellCommandTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.Dir = dir
+	cmd.Env = env
+	setProcessGroup(cmd)
+	cmd.WaitDelay = shellWaitDelay
+
+	result := &shellResult{
+		Stdout: newHeadTailBuffer(limit),
+		Stderr: newHeadTailBuffer(limit),
+	}
+
+	// 完整输出写入日志文件，失败时只保留内存中的部分
+	logFile, logErr := createShellLog()
+	if logErr == nil {
+		defer logFile.Close()
+		fmt.Fprintf(logFile, "$ %s\n\n", command)
+		log := &syncWriter{w: logFile}
+		cmd.Stdout = io.MultiWriter(result.Stdout, log)
+		cmd.Stderr = io.MultiWriter(result.Stderr, log)
+	} else {
+		cmd.Stdout = result.Stdout
+		cmd.Stderr = result.Stderr
+	}
+
+	start := time.Now()
+	err := cmd.Run()
+	result.Duration = time.Since(start)
+	result.TimedOut = errors.Is(ctx.Err(), context.DeadlineExceeded)
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+	case errors.Is(err, exec.ErrWaitDelay):
+		// 命令已正常退出，只是留在后台的子进程仍占用输出管道
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+	case result.TimedOut:
+		result.ExitCode = -1
+	default:
+		if logFile != nil {
+			os.Remove(logFile.Name())
+		}
+		return nil, fmt.Errorf("failed to run command: %w", err)
+	}
+
+	if logFile != nil {
+		if result.Stdout.Truncated() || result.Stderr.Truncated() {
+			result.LogPath = logFile.Name()
+		} else {
+			os.Remove(logFile.Name())
+		}
+	}
+	return result, nil
+}
+
+// createShellLog 在配置目录下创建命令输出日志文件
+func createShellLog() (*os.File, error) {
+	configDir, err := utils.GetConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	logDir := filepath.Join(configDir, "logs", "shell")
+	if err := os.MkdirAll(logDir, 0700); err != nil {
+		return nil, err
+	}
+	return os.CreateTemp(logDir, time.Now().Format("20060102-150405")+"-*.log")
+}
+
+// formatShellResult 格式化命令结果供模型阅读
+func formatShellResult(command string, result *shellResult) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("$ %s\n", command))
+	if result.TimedOut {
+		sb.WriteString(fmt.Sprintf("[超时: 命令运行超过 %v 被终止]\n", shellCommandTimeout))
+	}
+	sb.WriteString(fmt.Sprintf("[退出码: %d, 耗时 %.1fs]\n", result.ExitCode, result.Duration.Seconds()))
+
+	if stdout := result.Stdout.String(); stdout != "" {
+		sb.WriteString("\n--- stdout ---\n")
+		sb.WriteString(stdout)
+		if !strings.HasSuffix(stdout, "\n") {
+			sb.WriteString("\n")
+		}
+	}
+	if stderr := result.Stderr.String(); stderr != "" {
+		sb.WriteString("\n--- stderr ---\n")
+		sb.WriteString(stderr)
+		if !strings.HasSuffix(stderr, "\n") {
+			sb.WriteString("\n")
+		}
+	}
+	if result.LogPath != "" {
+		sb.WriteString(fmt.Sprintf("\n[输出过长已截断，完整输出: %s]\n", result.LogPath))
+	}
+	return sb.String()
+}
+
+// headTailBuffer 只保留开头和结尾各一半容量的输出缓冲区，内存占用与输出总量无关
+type headTailBuffer struct {
+	headCap int
+	tailCap int
+	head    []byte
+	tail    []byte
+	total   int64
+}
+
+// newHeadTailBuffer 创建总容量为 limit 字节的缓冲区
+func newHeadTailBuffer(limit int) *headTailBuffer {
+	return &headTailBuffer{
+		headCap: limit / 2,
+		tailCap: limit - limit/2,
+	}
+}
+
+func (b *headTailBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	b.total += int64(n)
+
+	if room := b.headCap - len(b.head); room > 0 {
+		if room > len(p) {
+			room = len(p)
+		}
+		b.head = append(b.head, p[:room]...)
+		p = p[room:]
+	}
+	if len(p) > 0 {
+		b.tail = append(b.tail, p...)
+		if len(b.tail) > b.tailCap {
+			b.tail = b.tail[len(b.tail)-b.tailCap:]
+		}
+	}
+	return n, nil
+}
+
+// Truncated 输出是否超过容量
+func (b *headTailBuffer) Truncated() bool {
+	return b.total > int64(len(b.head)+len(b.tail))
+}
+
+// String 返回保留的输出，截断时在中间插入省略标记，并尽量在行边界处截断
+func (b *headTailBuffer) String() string {
+	if !b.Truncated() {
+		return string(b.head) + string(b.tail)
+	}
+
+	head := b.head
+	if i := bytes.LastIndexByte(head, '\n'); i > 0 {
+		head = head[:i+1]
+	}
+	tail := b.tail
+	if i := bytes.IndexByte(tail, '\n'); i >= 0 && i < len(tail)-1 {
+		tail = tail[i+1:]
+	}
+
+	omitted := b.total - int64(len(head)+len(tail))
+	return strings.ToValidUTF8(string(head), "") +
+		fmt.Sprintf("\n... [省略 %d 字节] ...\n", omitted) +
+		strings.ToValidUTF8(string(tail), "")
+}
+
+// syncWriter 串行化 stdout 和 stderr 对同一日志文件的写入
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
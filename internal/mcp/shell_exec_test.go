@@ -0,0 +1,47 @@
+package mcp
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunShellCommandDirOutsideRoots(t *testing.T) {
+	t.Setenv("POLYAGENT_CONFIG_HOME", t.TempDir())
+	root := t.TempDir()
+	tool := &RunShellCommandTool{engine: newTestEngine(t, root)}
+
+	for _, dir := range []string{t.TempDir(), root + "/../"} {
+		if _, err := tool.Execute(map[string]interface{}{"command": "echo hi", "dir_path": dir}); err == nil || !strings.Contains(err.Error(), "outside allowed roots") {
+			t.Errorf("dir_path %s: err = %v", dir, err)
+		}
+	}
+	output, err := tool.Execute(map[string]interface{}{"command": "echo hi", "dir_path": root})
+	if err != nil || !strings.Contains(output.(string), "hi") {
+		t.Errorf("dir_path inside root: %v, %v", output, err)
+	}
+}
+
+func TestRunShellCommandKillsProcessGroup(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("process groups are not used on Windows")
+	}
+	t.Setenv("POLYAGENT_CONFIG_HOME", t.TempDir())
+
+	// 后台子进程继承了输出管道，只终止 sh 时 Wait 会一直等到它结束
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	result, err := runShellCommand(ctx, "sleep 30 & sleep 30", "", nil, defaultShellOutputLimit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.TimedOut {
+		t.Errorf("result = %+v, want timed out", result)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("command returned after %v", elapsed)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build windows
+
+package mcp
+
+import "os/exec"
+
+// setProcessGroup Windows 上只终止命令本身，残留子进程占用的输出管道由 WaitDelay 关闭
+func setProcessGroup(cmd *exec.Cmd) {}
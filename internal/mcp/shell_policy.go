@@ -0,0 +1,229 @@
+package mcp
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// ShellDecision 命令审批规则的匹配结果
+type ShellDecision struct {
+	// AutoApprove 为 true 时无需用户确认即可执行
+	AutoApprove bool
+	// Rule 决定结果的规则，如 "always-ask: rm"；未匹配任何规则时为空
+	Rule string
+	// Reason 需要确认时的原因说明
+	Reason string
+}
+
+// ShellPolicy 按命令前缀匹配的审批规则
+// 命令中的每一段（以 ; && || | 分隔）都命中自动批准规则且没有命中始终询问规则时才自动执行
+type ShellPolicy struct {
+	autoApprove [][]string
+	alwaysAsk   [][]string
+}
+
+// NewShellPolicy 创建审批规则，规则为命令前缀，例如 "go test"、"npm run lint"、"git push"
+func NewShellPolicy(autoApprove, alwaysAsk []string) *ShellPolicy {
+	return &ShellPolicy{
+		autoApprove: parseShellRules(autoApprove),
+		alwaysAsk:   parseShellRules(alwaysAsk),
+	}
+}
+
+// parseShellRules 将规则字符串拆分为单词，无法解析的规则被忽略
+func parseShellRules(rules []string) [][]string {
+	var parsed [][]string
+	for _, rule := range rules {
+		segments, err := ParseShellCommand(rule)
+		if err != nil || len(segments) != 1 || len(segments[0]) == 0 {
+			continue
+		}
+		parsed = append(parsed, segments[0])
+	}
+	return parsed
+}
+
+// Evaluate 判断命令是否可以自动执行
+func (p *ShellPolicy) Evaluate(command string) ShellDecision {
+	segments, err := ParseShellCommand(command)
+	if err != nil {
+		return ShellDecision{Reason: err.Error()}
+	}
+	if len(segments) == 0 {
+		return ShellDecision{Reason: "empty command"}
+	}
+
+	// 始终询问优先于自动批准
+	for _, words := range segments {
+		if rule := matchShellRule(p.alwaysAsk, words); rule != "" {
+			return ShellDecision{Rule: "always-ask: " + rule, Reason: "matched always-ask rule"}
+		}
+	}
+
+	var matched []string
+	for _, words := range segments {
+		// 命令前的赋值（如 LD_PRELOAD=… 或 PATH=…）可以改变命令的行为，与 extra_env 一样需要确认
+		if len(stripEnvAssignments(words)) != len(words) {
+			return ShellDecision{Reason: "sets inline environment variables"}
+		}
+		rule := matchShellRule(p.autoApprove, words)
+		if rule == "" {
+			return ShellDecision{Reason: fmt.Sprintf("no auto-approve rule for %q", strings.Join(words, " "))}
+		}
+		matched = append(matched, rule)
+	}
+	return ShellDecision{AutoApprove: true, Rule: "auto-approve: " + strings.Join(uniqueStrings(matched), ", ")}
+}
+
+// matchShellRule 返回第一个作为命令前缀的规则
+func matchShellRule(rules [][]string, words []string) string {
+	words = stripEnvAssignments(words)
+	if len(words) == 0 {
+		return ""
+	}
+	// 按可执行文件名匹配，/bin/rm 与 rm 等价
+	program := filepath.Base(words[0])
+
+	for _, rule := range rules {
+		if len(rule) > len(words) || filepath.Base(rule[0]) != program {
+			continue
+		}
+		matched := true
+		for i := 1; i < len(rule); i++ {
+			if rule[i] != words[i] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return strings.Join(rule, " ")
+		}
+	}
+	return ""
+}
+
+// stripEnvAssignments 去掉命令前的环境变量赋值（如 FOO=1 go test）
+func stripEnvAssignments(words []string) []string {
+	for len(words) > 0 {
+		name, _, ok := strings.Cut(words[0], "=")
+		if !ok || name == "" || strings.ContainsAny(name, "/-.") {
+			break
+		}
+		words = words[1:]
+	}
+	return words
+}
+
+// ParseShellCommand 将命令按 ; && || | 和换行拆分为多段，每段拆分为单词（处理引号和转义）
+// 命令替换、重定向、后台执行和子 shell 等无法静态分析的语法返回错误
+func ParseShellCommand(command string) ([][]string, error) {
+	var (
+		segments [][]string
+		words    []string
+		word     strings.Builder
+		inWord   bool
+	)
+
+	endWord := func() {
+		if inWord {
+			words = append(words, word.String())
+			word.Reset()
+			inWord = false
+		}
+	}
+	endSegment := func() {
+		endWord()
+		if len(words) > 0 {
+			segments = append(segments, words)
+			words = nil
+		}
+	}
+
+	runes := []rune(command)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\'':
+			inWord = true
+			end := indexRune(runes, i+1, '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated single quote")
+			}
+			word.WriteString(string(runes[i+1 : end]))
+			i = end
+		case r == '"':
+			inWord = true
+			for i++; ; i++ {
+				if i >= len(runes) {
+					return nil, fmt.Errorf("unterminated double quote")
+				}
+				if runes[i] == '"' {
+					break
+				}
+				if runes[i] == '$' || runes[i] == '`' {
+					return nil, fmt.Errorf("command substitution or variable expansion is not supported")
+				}
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				word.WriteRune(runes[i])
+			}
+		case r == '\\':
+			inWord = true
+			if i+1 < len(runes) {
+				i++
+				word.WriteRune(runes[i])
+			}
+		case r == '$' || r == '`':
+			return nil, fmt.Errorf("command substitution or variable expansion is not supported")
+		case r == '>' || r == '<':
+			return nil, fmt.Errorf("redirection is not supported")
+		case r == '(' || r == ')' || r == '{' || r == '}':
+			return nil, fmt.Errorf("subshells and command groups are not supported")
+		case r == '&':
+			if i+1 < len(runes) && runes[i+1] == '&' {
+				i++
+				endSegment()
+				continue
+			}
+			return nil, fmt.Errorf("background execution is not supported")
+		case r == ';' || r == '|' || r == '\n':
+			if r == '|' && i+1 < len(runes) && runes[i+1] == '|' {
+				i++
+			}
+			endSegment()
+		case r == ' ' || r == '\t' || r == '\r':
+			endWord()
+		default:
+			inWord = true
+			word.WriteRune(r)
+		}
+	}
+	endSegment()
+
+	return segments, nil
+}
+
+// indexRune 从 start 开始查找字符，找不到返回 -1
+func indexRune(runes []rune, start int, target rune) int {
+	for i := start; i < len(runes); i++ {
+		if runes[i] == target {
+			return i
+		}
+	}
+	return -1
+}
+
+// uniqueStrings 按出现顺序去重
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var result []string
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			result = append(result, v)
+		}
+	}
+	return result
+}
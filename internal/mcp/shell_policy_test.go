@@ -0,0 +1,32 @@
+package mcp
+
+import "testing"
+
+func TestShellPolicyEvaluate(t *testing.T) {
+	policy := NewShellPolicy([]string{"go test", "git status", "ls"}, []string{"rm"})
+	tests := []struct {
+		command string
+		auto    bool
+		reason  string
+	}{
+		{"go test ./...", true, ""},
+		{"git status && ls -la", true, ""},
+		{"go build ./...", false, `no auto-approve rule for "go build ./..."`},
+		{"ls && rm -rf build", false, "matched always-ask rule"},
+		{"FOO=1 rm -rf build", false, "matched always-ask rule"},
+		{"LD_PRELOAD=/tmp/x.so go test ./...", false, "sets inline environment variables"},
+		{"PATH=/tmp/evil go test", false, "sets inline environment variables"},
+		{"PATH=/tmp/evil:$PATH go test", false, ""},
+		{"git status; GOFLAGS=-exec=/tmp/x go test", false, "sets inline environment variables"},
+		{"go test $(cat list)", false, ""},
+	}
+	for _, tt := range tests {
+		decision := policy.Evaluate(tt.command)
+		if decision.AutoApprove != tt.auto {
+			t.Errorf("Evaluate(%q) = %+v, want auto-approve %v", tt.command, decision, tt.auto)
+		}
+		if tt.reason != "" && decision.Reason != tt.reason {
+			t.Errorf("Evaluate(%q) reason = %q, want %q", tt.command, decision.Reason, tt.reason)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package mcp
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Symbol 源码中的一个定义
+type Symbol struct {
+	Name string
+	Kind string
+	Line int
+}
+
+// symbolPattern 按行匹配定义的规则，nameGroup 为名称所在的分组
+type symbolPattern struct {
+	re        *regexp.Regexp
+	kind      string
+	nameGroup int
+}
+
+// symbolPatterns 各语言的定义匹配规则（基于正则，按行匹配，覆盖常见写法）
+var symbolPatterns = map[string][]symbolPattern{
+	"go": {
+		{regexp.MustCompile(`^func\s+\([^)]*\)\s*(\w+)`), "method", 1},
+		{regexp.MustCompile(`^func\s+(\w+)`), "function", 1},
+		{regexp.MustCompile(`^type\s+(\w+)\s+(struct|interface)\b`), "type", 1},
+		{regexp.MustCompile(`^type\s+(\w+)\b`), "type", 1},
+		{regexp.MustCompile(`^const\s+(\w+)`), "constant", 1},
+		{regexp.MustCompile(`^var\s+(\w+)`), "variable", 1},
+	},
+	"python": {
+		{regexp.MustCompile(`^\s*class\s+(\w+)`), "class", 1},
+		{regexp.MustCompile(`^\s*(?:async\s+)?def\s+(\w+)`), "function", 1},
+	},
+	"javascript": {
+		{regexp.MustCompile(`^\s*(?:export\s+)?(?:default\s+)?(?:abstract\s+)?class\s+(\w+)`), "class", 1},
+		{regexp.MustCompile(`^\s*(?:export\s+)?(?:default\s+)?(?:async\s+)?function\*?\s+(\w+)`), "function", 1},
+		{regexp.MustCompile(`^\s*(?:export\s+)?(?:const|let|var)\s+(\w+)\s*=\s*(?:async\s+)?(?:\([^)]*\)|\w+)\s*=>`), "function", 1},
+		{regexp.MustCompile(`^\s*(?:export\s+)?interface\s+(\w+)`), "interface", 1},
+		{regexp.MustCompile(`^\s*(?:export\s+)?type\s+(\w+)\s*=`), "type", 1},
+		{regexp.MustCompile(`^\s*(?:export\s+)?enum\s+(\w+)`), "enum", 1},
+	},
+	"rust": {
+		{regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?(?:async\s+)?(?:unsafe\s+)?fn\s+(\w+)`), "function", 1},
+		{regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?struct\s+(\w+)`), "struct", 1},
+		{regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?enum\s+(\w+)`), "enum", 1},
+		{regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?trait\s+(\w+)`), "trait", 1},
+		{regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?mod\s+(\w+)`), "module", 1},
+		{regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?type\s+(\w+)`), "type", 1},
+	},
+	"java": {
+		{regexp.MustCompile(`^\s*(?:(?:public|protected|private|static|final|abstract)\s+)*(?:class|interface|enum|record)\s+(\w+)`), "class", 1},
+		{regexp.MustCompile(`^\s*(?:(?:public|protected|private|static|final|abstract|synchronized)\s+)+[\w<>\[\],\s]+\s+(\w+)\s*\(`), "method", 1},
+	},
+}
+
+// languageByExt 文件扩展名到语言的映射
+var languageByExt = map[string]string{
+	".go":   "go",
+	".py":   "python",
+	".js":   "javascript",
+	".jsx":  "javascript",
+	".mjs":  "javascript",
+	".ts":   "javascript",
+	".tsx":  "javascript",
+	".rs":   "rust",
+	".java": "java",
+	".kt":   "java",
+}
+
+// detectLanguage 根据扩展名判断语言，未知时返回空字符串
+func detectLanguage(path string) string {
+	return languageByExt[strings.ToLower(filepath.Ext(path))]
+}
+
+// extractSymbols 提取文件中的定义，每行最多匹配一条规则
+func extractSymbols(language, content string) []Symbol {
+	patterns, ok := symbolPatterns[language]
+	if !ok {
+		return nil
+	}
+
+	var symbols []Symbol
+	for i, line := range strings.Split(content, "\n") {
+		for _, p := range patterns {
+			if m := p.re.FindStringSubmatch(line); m != nil {
+				symbols = append(symbols, Symbol{Name: m[p.nameGroup], Kind: p.kind, Line: i + 1})
+				break
+			}
+		}
+	}
+	return symbols
+}
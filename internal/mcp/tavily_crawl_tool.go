@@ -6,7 +6,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Zacy-Sokach/PolyAgent/internal/config"
@@ -14,7 +16,7 @@ import (
 )
 
 const (
-	tavilyCrawlURL = "https://api.tavily.com/crawl"
+	tavilyCrawlURL = TavilyEndpoint + "/crawl"
 	crawlTimeout   = 10 * time.Second
 )
 
@@ -22,12 +24,18 @@ const (
 type TavilyCrawlTool struct {
 	Client utils.Doer
 	APIKey string
+	// keyMu 保护 APIKey，配置重新加载时可能在执行期间被更新
+	keyMu sync.Mutex
+	// Policy 出站访问策略，为空时在第一次使用时从配置加载
+	Policy     *WebPolicy
+	policyOnce sync.Once
 }
 
 // NewTavilyCrawlTool 创建新的 TavilyCrawlTool 实例
 func NewTavilyCrawlTool() *TavilyCrawlTool {
 	baseClient := &http.Client{
-		Timeout: crawlTimeout,
+		Timeout:   crawlTimeout,
+		Transport: utils.NewTransport(),
 	}
 	
 	// 配置重试参数
@@ -64,9 +72,7 @@ func (t *TavilyCrawlTool) Description() string {
 }
 
 func (t *TavilyCrawlTool) GetSchema() map[string]interface{} {
-	return map[string]interface{}{
-		"type": "object",
-		"properties": map[string]interface{}{
+	properties := map[string]interface{}{
 			"base_url": map[string]interface{}{
 				"type":        "string",
 				"description": "起始 URL，爬取的根地址",
@@ -111,8 +117,15 @@ func (t *TavilyCrawlTool) GetSchema() map[string]interface{} {
 					"type": "string",
 				},
 			},
-		},
-		"required": []string{"base_url"},
+	}
+	for name, prop := range contentFilterSchemaProperties() {
+		properties[name] = prop
+	}
+
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+		"required":   []string{"base_url"},
 	}
 }
 
@@ -143,7 +156,8 @@ type TavilyCrawlResult struct {
 
 func (t *TavilyCrawlTool) Execute(args map[string]interface{}) (interface{}, error) {
 	// 1. 确保有 API Key
-	if err := t.ensureAPIKey(); err != nil {
+	apiKey, err := t.ensureAPIKey()
+	if err != nil {
 		return t.getAPIKeyPrompt(), nil
 	}
 
@@ -152,6 +166,10 @@ func (t *TavilyCrawlTool) Execute(args map[string]interface{}) (interface{}, err
 	if !ok || strings.TrimSpace(baseURL) == "" {
 		return nil, fmt.Errorf("invalid argument: base_url is required")
 	}
+	baseURL = strings.TrimSpace(baseURL)
+	if !strings.Contains(baseURL, "://") {
+		baseURL = "https://" + baseURL
+	}
 
 	maxDepth := getIntArg(args, "max_depth", 2)
 	maxLinksPerLevel := getIntArg(args, "max_links_per_level", 10)
@@ -177,7 +195,17 @@ func (t *TavilyCrawlTool) Execute(args map[string]interface{}) (interface{}, err
 		excludePatterns = toStringSlice(patterns)
 	}
 
-	// 3. 构建请求
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout+10)*time.Second)
+	defer cancel()
+
+	// 3. 检查访问策略与 robots.txt
+	robots, err := t.checkAccess(ctx, baseURL)
+	if err != nil {
+		return nil, err
+	}
+	excludePatterns = append(excludePatterns, robots.ExcludePatterns()...)
+
+	// 4. 构建请求
 	reqBody := TavilyCrawlRequest{
 		BaseURL:          baseURL,
 		MaxDepth:         maxDepth,
@@ -187,7 +215,7 @@ func (t *TavilyCrawlTool) Execute(args map[string]interface{}) (interface{}, err
 		Timeout:          timeout,
 		IncludePatterns:  includePatterns,
 		ExcludePatterns:  excludePatterns,
-		APIKey:           t.APIKey,
+		APIKey:           apiKey,
 	}
 
 	jsonData, err := json.Marshal(reqBody)
@@ -195,9 +223,6 @@ func (t *TavilyCrawlTool) Execute(args map[string]interface{}) (interface{}, err
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout+10)*time.Second)
-	defer cancel()
-
 	req, err := http.NewRequestWithContext(ctx, "POST", tavilyCrawlURL, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
@@ -205,7 +230,7 @@ func (t *TavilyCrawlTool) Execute(args map[string]interface{}) (interface{}, err
 
 	req.Header.Set("Content-Type", "application/json")
 
-	// 4. 发送请求
+	// 5. 发送请求
 	resp, err := t.Client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("network request failed: %w", err)
@@ -216,34 +241,106 @@ func (t *TavilyCrawlTool) Execute(args map[string]interface{}) (interface{}, err
 		return nil, fmt.Errorf("crawl API error: status %d", resp.StatusCode)
 	}
 
-	// 5. 解析响应
+	// 6. 解析响应
 	var crawlResp TavilyCrawlResponse
 	if err := json.NewDecoder(resp.Body).Decode(&crawlResp); err != nil {
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
+	crawlResp.Results = t.filterAllowed(baseURL, robots, crawlResp.Results)
 
-	// 6. 格式化结果
-	return t.formatResults(baseURL, &crawlResp), nil
+	// 7. 过滤并格式化结果
+	return t.formatResults(baseURL, &crawlResp, parseContentFilterOptions(args)), nil
+}
+
+// checkAccess 按 web_policy 检查起始 URL，并在需要时获取 robots.txt 规则
+// robots.txt 无法获取时不做限制，返回的规则为 nil
+func (t *TavilyCrawlTool) checkAccess(ctx context.Context, baseURL string) (*robotsRules, error) {
+	policy := t.policy()
+
+	if err := policy.CheckURL(ctx, baseURL); err != nil {
+		return nil, fmt.Errorf("url blocked by web_policy: %w", err)
+	}
+
+	if !policy.RespectRobots {
+		return nil, nil
+	}
+
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base_url: %w", err)
+	}
+
+	robots, err := fetchRobots(ctx, policy, parsed)
+	if err != nil {
+		return nil, nil
+	}
+	if !robots.AllowsURL(parsed) {
+		return nil, fmt.Errorf("robots.txt disallows crawling %s", baseURL)
+	}
+	return robots, nil
+}
+
+// filterAllowed 丢弃不符合域名策略或被 robots.txt 禁止的页面
+func (t *TavilyCrawlTool) filterAllowed(baseURL string, robots *robotsRules, results []TavilyCrawlResult) []TavilyCrawlResult {
+	policy := t.policy()
+	base, _ := url.Parse(baseURL)
+
+	allowed := results[:0]
+	for _, result := range results {
+		if !policy.AllowsURL(result.URL) {
+			continue
+		}
+		if robots != nil && base != nil {
+			if u, err := url.Parse(result.URL); err == nil && strings.EqualFold(u.Host, base.Host) && !robots.AllowsURL(u) {
+				continue
+			}
+		}
+		allowed = append(allowed, result)
+	}
+	return allowed
+}
+
+// policy 返回出站访问策略，未设置时从配置加载一次
+func (t *TavilyCrawlTool) policy() *WebPolicy {
+	t.policyOnce.Do(func() {
+		if t.Policy == nil {
+			t.Policy = loadWebPolicy()
+		}
+	})
+	return t.Policy
 }
 
 // ensureAPIKey 确保 API Key 已加载
-func (t *TavilyCrawlTool) ensureAPIKey() error {
+func (t *TavilyCrawlTool) ensureAPIKey() (string, error) {
+	t.keyMu.Lock()
+	defer t.keyMu.Unlock()
 	if t.APIKey != "" {
-		return nil
+		return t.APIKey, nil
 	}
 
 	// 从配置加载
 	key, err := config.GetTavilyAPIKey()
 	if err != nil {
-		return fmt.Errorf("failed to load API key: %w", err)
+		return "", fmt.Errorf("failed to load API key: %w", err)
 	}
 
 	if key == "" {
-		return fmt.Errorf("API key not configured")
+		return "", fmt.Errorf("API key not configured")
 	}
 
 	t.APIKey = key
-	return nil
+	return key, nil
+}
+
+// ReloadAPIKeys 应用重新加载的配置中的 Tavily API Key，返回是否发生变化
+func (t *TavilyCrawlTool) ReloadAPIKeys(cfg *config.Config) bool {
+	t.keyMu.Lock()
+	defer t.keyMu.Unlock()
+	if t.APIKey == cfg.TavilyAPIKey {
+		return false
+	}
+	t.APIKey = cfg.TavilyAPIKey
+	return true
 }
 
 // getAPIKeyPrompt 返回 API Key 配置提示
@@ -266,8 +363,8 @@ func (t *TavilyCrawlTool) getAPIKeyPrompt() string {
 配置完成后，请重新运行爬取。`
 }
 
-// formatResults 格式化爬取结果
-func (t *TavilyCrawlTool) formatResults(baseURL string, resp *TavilyCrawlResponse) string {
+// formatResults 格式化爬取结果，按过滤参数去除样板内容并控制总长度
+func (t *TavilyCrawlTool) formatResults(baseURL string, resp *TavilyCrawlResponse, opts contentFilterOptions) string {
 	var builder strings.Builder
 	builder.Grow(1000 + len(resp.Results)*500)
 
@@ -280,17 +377,12 @@ func (t *TavilyCrawlTool) formatResults(baseURL string, resp *TavilyCrawlRespons
 
 	builder.WriteString(fmt.Sprintf("爬取了 %d 个页面：\n\n", len(resp.Results)))
 
+	pages := make([]webPage, len(resp.Results))
 	for i, result := range resp.Results {
-		builder.WriteString(fmt.Sprintf("## 页面 %d: %s\n\n", i+1, result.URL))
-
-		if result.Content != "" {
-			// 内容已经是 markdown 或 text 格式
-			builder.WriteString(result.Content)
-			builder.WriteString("\n\n")
-		}
-
-		builder.WriteString("---\n\n")
+		// 内容已经是 markdown 或 text 格式
+		pages[i] = webPage{URL: result.URL, Content: result.Content}
 	}
+	writeFilteredPages(&builder, filterWebPages(pages, opts))
 
 	return builder.String()
 }
@@ -0,0 +1,323 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/config"
+	"github.com/Zacy-Sokach/PolyAgent/internal/utils"
+)
+
+const (
+	tavilyExtractURL = TavilyEndpoint + "/extract"
+	extractTimeout   = 30 * time.Second
+	maxExtractURLs   = 20
+)
+
+// TavilyExtractTool Tavily 网页内容提取工具
+type TavilyExtractTool struct {
+	Client utils.Doer
+	APIKey string
+	// keyMu 保护 APIKey，配置重新加载时可能在执行期间被更新
+	keyMu sync.Mutex
+	// Policy 出站访问策略，为空时在第一次使用时从配置加载
+	Policy     *WebPolicy
+	policyOnce sync.Once
+}
+
+// NewTavilyExtractTool 创建新的 TavilyExtractTool 实例
+func NewTavilyExtractTool() *TavilyExtractTool {
+	baseClient := &http.Client{
+		Timeout:   extractTimeout,
+		Transport: utils.NewTransport(),
+	}
+
+	// 配置重试参数
+	retryConfig := &utils.RetryConfig{
+		MaxRetries:        3,
+		InitialDelay:      1 * time.Second,
+		MaxDelay:          30 * time.Second,
+		BackoffMultiplier: 2.0,
+		RetryableStatusCodes: []int{
+			http.StatusRequestTimeout,      // 408
+			http.StatusTooManyRequests,     // 429
+			http.StatusInternalServerError, // 500
+			http.StatusBadGateway,          // 502
+			http.StatusServiceUnavailable,  // 503
+			http.StatusGatewayTimeout,      // 504
+		},
+		RetryableErrors: func(err error) bool {
+			// 重试网络错误和超时
+			return true
+		},
+	}
+
+	return &TavilyExtractTool{
+		Client: utils.NewRetryableHTTPClient(baseClient, retryConfig),
+	}
+}
+
+func (t *TavilyExtractTool) Name() string {
+	return "web_extract"
+}
+
+func (t *TavilyExtractTool) Description() string {
+	return "提取指定 URL 列表的正文内容。已知具体页面地址时使用，比 web_crawl 更快更省"
+}
+
+func (t *TavilyExtractTool) GetSchema() map[string]interface{} {
+	properties := map[string]interface{}{
+		"urls": map[string]interface{}{
+			"type":        "array",
+			"description": fmt.Sprintf("要提取内容的 URL 列表（最多 %d 个）", maxExtractURLs),
+			"items": map[string]interface{}{
+				"type": "string",
+			},
+		},
+		"extract_depth": map[string]interface{}{
+			"type":        "string",
+			"description": "提取深度：basic 或 advanced（advanced 可提取表格和嵌入内容）",
+			"enum":        []string{"basic", "advanced"},
+			"default":     "basic",
+		},
+		"format": map[string]interface{}{
+			"type":        "string",
+			"description": "输出格式：markdown 或 text",
+			"enum":        []string{"markdown", "text"},
+			"default":     "markdown",
+		},
+	}
+	for name, prop := range contentFilterSchemaProperties() {
+		properties[name] = prop
+	}
+
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+		"required":   []string{"urls"},
+	}
+}
+
+// TavilyExtractRequest Tavily 提取请求结构
+type TavilyExtractRequest struct {
+	URLs         []string `json:"urls"`
+	ExtractDepth string   `json:"extract_depth,omitempty"`
+	Format       string   `json:"format,omitempty"`
+	APIKey       string   `json:"api_key"`
+}
+
+// TavilyExtractResponse Tavily 提取响应结构
+type TavilyExtractResponse struct {
+	Results       []TavilyExtractResult       `json:"results"`
+	FailedResults []TavilyExtractFailedResult `json:"failed_results"`
+}
+
+// TavilyExtractResult 提取成功的结果项
+type TavilyExtractResult struct {
+	URL        string `json:"url"`
+	RawContent string `json:"raw_content"`
+}
+
+// TavilyExtractFailedResult 提取失败的结果项
+type TavilyExtractFailedResult struct {
+	URL   string `json:"url"`
+	Error string `json:"error"`
+}
+
+func (t *TavilyExtractTool) Execute(args map[string]interface{}) (interface{}, error) {
+	// 1. 确保有 API Key
+	apiKey, err := t.ensureAPIKey()
+	if err != nil {
+		return t.getAPIKeyPrompt(), nil
+	}
+
+	// 2. 解析参数
+	var urls []string
+	switch v := args["urls"].(type) {
+	case []interface{}:
+		urls = toStringSlice(v)
+	case []string:
+		urls = v
+	case string:
+		urls = []string{v}
+	}
+	cleaned := make([]string, 0, len(urls))
+	for _, u := range urls {
+		if u = strings.TrimSpace(u); u != "" {
+			cleaned = append(cleaned, u)
+		}
+	}
+	if len(cleaned) == 0 {
+		return nil, fmt.Errorf("invalid argument: urls is required")
+	}
+	if len(cleaned) > maxExtractURLs {
+		return nil, fmt.Errorf("invalid argument: at most %d urls are allowed, got %d", maxExtractURLs, len(cleaned))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), extractTimeout+10*time.Second)
+	defer cancel()
+
+	// 3. 检查访问策略，被拒绝的 URL 直接记为失败
+	policy := t.policy()
+	permitted := make([]string, 0, len(cleaned))
+	var blocked []TavilyExtractFailedResult
+	for _, u := range cleaned {
+		if err := policy.CheckURL(ctx, u); err != nil {
+			blocked = append(blocked, TavilyExtractFailedResult{URL: u, Error: "blocked by web_policy: " + err.Error()})
+			continue
+		}
+		permitted = append(permitted, u)
+	}
+	if len(permitted) == 0 {
+		return nil, fmt.Errorf("all urls blocked by web_policy: %s", blocked[0].Error)
+	}
+
+	extractDepth := "basic"
+	if d, ok := args["extract_depth"].(string); ok && (d == "basic" || d == "advanced") {
+		extractDepth = d
+	}
+
+	format := "markdown"
+	if f, ok := args["format"].(string); ok && (f == "markdown" || f == "text") {
+		format = f
+	}
+
+	// 4. 构建请求
+	reqBody := TavilyExtractRequest{
+		URLs:         permitted,
+		ExtractDepth: extractDepth,
+		Format:       format,
+		APIKey:       apiKey,
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", tavilyExtractURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	// 5. 发送请求
+	resp, err := t.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("network request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("extract API error: status %d", resp.StatusCode)
+	}
+
+	// 6. 解析响应
+	var extractResp TavilyExtractResponse
+	if err := json.NewDecoder(resp.Body).Decode(&extractResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	extractResp.FailedResults = append(blocked, extractResp.FailedResults...)
+
+	// 7. 过滤并格式化结果
+	return t.formatResults(&extractResp, parseContentFilterOptions(args)), nil
+}
+
+// policy 返回出站访问策略，未设置时从配置加载一次
+func (t *TavilyExtractTool) policy() *WebPolicy {
+	t.policyOnce.Do(func() {
+		if t.Policy == nil {
+			t.Policy = loadWebPolicy()
+		}
+	})
+	return t.Policy
+}
+
+// ensureAPIKey 确保 API Key 已加载
+func (t *TavilyExtractTool) ensureAPIKey() (string, error) {
+	t.keyMu.Lock()
+	defer t.keyMu.Unlock()
+	if t.APIKey != "" {
+		return t.APIKey, nil
+	}
+
+	// 从配置加载
+	key, err := config.GetTavilyAPIKey()
+	if err != nil {
+		return "", fmt.Errorf("failed to load API key: %w", err)
+	}
+
+	if key == "" {
+		return "", fmt.Errorf("API key not configured")
+	}
+
+	t.APIKey = key
+	return key, nil
+}
+
+// ReloadAPIKeys 应用重新加载的配置中的 Tavily API Key，返回是否发生变化
+func (t *TavilyExtractTool) ReloadAPIKeys(cfg *config.Config) bool {
+	t.keyMu.Lock()
+	defer t.keyMu.Unlock()
+	if t.APIKey == cfg.TavilyAPIKey {
+		return false
+	}
+	t.APIKey = cfg.TavilyAPIKey
+	return true
+}
+
+// getAPIKeyPrompt 返回 API Key 配置提示
+func (t *TavilyExtractTool) getAPIKeyPrompt() string {
+	return `# ⚠️ Tavily API Key 未配置
+
+要使用网页内容提取功能，需要配置 Tavily API Key。
+
+## 设置步骤：
+
+1. 访问 https://tavily.com/ 注册账号
+2. 获取免费 API Key
+3. 在配置文件中添加：
+   ` + "```yaml" + `
+   tavily_api_key: "tvly-xxxxxx"
+   ` + "```" + `
+
+   配置文件位置：` + "`" + utils.GetConfigPathForDisplay() + "`" + `
+
+配置完成后，请重新运行提取。`
+}
+
+// formatResults 格式化提取结果，按过滤参数去除样板内容并控制总长度
+func (t *TavilyExtractTool) formatResults(resp *TavilyExtractResponse, opts contentFilterOptions) string {
+	var builder strings.Builder
+	builder.Grow(1000 + len(resp.Results)*500)
+
+	builder.WriteString("# 📄 网页内容提取结果\n\n")
+
+	if len(resp.Results) == 0 {
+		builder.WriteString("未提取到任何内容。\n")
+	} else {
+		builder.WriteString(fmt.Sprintf("提取了 %d 个页面：\n\n", len(resp.Results)))
+
+		pages := make([]webPage, len(resp.Results))
+		for i, result := range resp.Results {
+			pages[i] = webPage{URL: result.URL, Content: result.RawContent}
+		}
+		writeFilteredPages(&builder, filterWebPages(pages, opts))
+	}
+
+	if len(resp.FailedResults) > 0 {
+		builder.WriteString(fmt.Sprintf("\n## ❌ 提取失败 (%d)\n\n", len(resp.FailedResults)))
+		for _, failed := range resp.FailedResults {
+			builder.WriteString(fmt.Sprintf("- %s: %s\n", failed.URL, failed.Error))
+		}
+	}
+
+	return builder.String()
+}
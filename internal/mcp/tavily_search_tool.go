@@ -7,14 +7,18 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Zacy-Sokach/PolyAgent/internal/config"
 	"github.com/Zacy-Sokach/PolyAgent/internal/utils"
 )
 
+// TavilyEndpoint Tavily API 服务地址，供诊断命令检查连通性
+const TavilyEndpoint = "https://api.tavily.com"
+
 const (
-	tavilySearchURL = "https://api.tavily.com/search"
+	tavilySearchURL = TavilyEndpoint + "/search"
 	tavilyTimeout   = 10 * time.Second
 )
 
@@ -22,12 +26,18 @@ const (
 type TavilySearchTool struct {
 	Client utils.Doer
 	APIKey string
+	// keyMu 保护 APIKey，配置重新加载时可能在执行期间被更新
+	keyMu sync.Mutex
+	// Policy 出站访问策略，为空时在第一次使用时从配置加载
+	Policy     *WebPolicy
+	policyOnce sync.Once
 }
 
 // NewTavilySearchTool 创建新的 TavilySearchTool 实例
 func NewTavilySearchTool() *TavilySearchTool {
 	baseClient := &http.Client{
-		Timeout: tavilyTimeout,
+		Timeout:   tavilyTimeout,
+		Transport: utils.NewTransport(),
 	}
 	
 	// 配置重试参数
@@ -99,7 +109,10 @@ type TavilySearchRequest struct {
 	MaxResults  int    `json:"max_results,omitempty"`
 	SearchDepth string `json:"search_depth,omitempty"`
 	TimeRange   string `json:"time_range,omitempty"`
-	APIKey      string `json:"api_key"`
+	// 域名白名单/黑名单，来自 web_policy
+	IncludeDomains []string `json:"include_domains,omitempty"`
+	ExcludeDomains []string `json:"exclude_domains,omitempty"`
+	APIKey         string   `json:"api_key"`
 }
 
 // TavilySearchResponse Tavily 搜索响应结构
@@ -118,7 +131,8 @@ type TavilySearchResult struct {
 
 func (t *TavilySearchTool) Execute(args map[string]interface{}) (interface{}, error) {
 	// 1. 确保有 API Key
-	if err := t.ensureAPIKey(); err != nil {
+	apiKey, err := t.ensureAPIKey()
+	if err != nil {
 		return t.getAPIKeyPrompt(), nil
 	}
 
@@ -148,13 +162,17 @@ func (t *TavilySearchTool) Execute(args map[string]interface{}) (interface{}, er
 		timeRange = tr
 	}
 
+	policy := t.policy()
+
 	// 3. 构建请求
 	reqBody := TavilySearchRequest{
-		Query:       query,
-		MaxResults:  maxResults,
-		SearchDepth: searchDepth,
-		TimeRange:   timeRange,
-		APIKey:      t.APIKey,
+		Query:          query,
+		MaxResults:     maxResults,
+		SearchDepth:    searchDepth,
+		TimeRange:      timeRange,
+		IncludeDomains: policy.AllowedDomains,
+		ExcludeDomains: policy.DeniedDomains,
+		APIKey:         apiKey,
 	}
 
 	jsonData, err := json.Marshal(reqBody)
@@ -189,28 +207,59 @@ func (t *TavilySearchTool) Execute(args map[string]interface{}) (interface{}, er
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	// 6. 格式化结果
+	// 6. 按域名策略过滤后格式化结果
+	allowed := searchResp.Results[:0]
+	for _, result := range searchResp.Results {
+		if policy.AllowsURL(result.URL) {
+			allowed = append(allowed, result)
+		}
+	}
+	searchResp.Results = allowed
+
 	return t.formatResults(query, &searchResp), nil
 }
 
+// policy 返回出站访问策略，未设置时从配置加载一次
+func (t *TavilySearchTool) policy() *WebPolicy {
+	t.policyOnce.Do(func() {
+		if t.Policy == nil {
+			t.Policy = loadWebPolicy()
+		}
+	})
+	return t.Policy
+}
+
 // ensureAPIKey 确保 API Key 已加载
-func (t *TavilySearchTool) ensureAPIKey() error {
+func (t *TavilySearchTool) ensureAPIKey() (string, error) {
+	t.keyMu.Lock()
+	defer t.keyMu.Unlock()
 	if t.APIKey != "" {
-		return nil
+		return t.APIKey, nil
 	}
 
 	// 从配置加载
 	key, err := config.GetTavilyAPIKey()
 	if err != nil {
-		return fmt.Errorf("failed to load API key: %w", err)
+		return "", fmt.Errorf("failed to load API key: %w", err)
 	}
 
 	if key == "" {
-		return fmt.Errorf("API key not configured")
+		return "", fmt.Errorf("API key not configured")
 	}
 
 	t.APIKey = key
-	return nil
+	return key, nil
+}
+
+// ReloadAPIKeys 应用重新加载的配置中的 Tavily API Key，返回是否发生变化
+func (t *TavilySearchTool) ReloadAPIKeys(cfg *config.Config) bool {
+	t.keyMu.Lock()
+	defer t.keyMu.Unlock()
+	if t.APIKey == cfg.TavilyAPIKey {
+		return false
+	}
+	t.APIKey = cfg.TavilyAPIKey
+	return true
 }
 
 // getAPIKeyPrompt 返回 API Key 配置提示
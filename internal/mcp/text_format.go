@@ -0,0 +1,78 @@
+package mcp
+
+import (
+	"bytes"
+)
+
+// utf8BOM UTF-8 字节序标记
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// textFormat 已有文件的文本格式，写入时按此规范化模型输出
+type textFormat struct {
+	bom bool
+	// crlf 为 true 时使用 \r\n；eolKnown 为 false 时（原文件没有换行）保留模型输出的换行符
+	crlf     bool
+	eolKnown bool
+	// trailingNewline 原文件是否以换行结尾；原文件为空时不做调整
+	trailingNewline bool
+	nonEmpty        bool
+}
+
+// detectTextFormat 检测文件的 BOM、换行符和结尾换行，二进制内容返回 false
+func detectTextFormat(content []byte) (textFormat, bool) {
+	if bytes.IndexByte(content, 0) >= 0 {
+		return textFormat{}, false
+	}
+
+	var format textFormat
+	if bytes.HasPrefix(content, utf8BOM) {
+		format.bom = true
+		content = content[len(utf8BOM):]
+	}
+
+	crlf := bytes.Count(content, []byte("\r\n"))
+	lf := bytes.Count(content, []byte("\n")) - crlf
+	format.eolKnown = crlf+lf > 0
+	format.crlf = crlf > lf
+
+	format.nonEmpty = len(content) > 0
+	format.trailingNewline = bytes.HasSuffix(content, []byte("\n"))
+	return format, true
+}
+
+// apply 将内容转换为与原文件一致的格式
+func (f textFormat) apply(content []byte) []byte {
+	content = bytes.TrimPrefix(content, utf8BOM)
+
+	if f.eolKnown {
+		content = bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n"))
+	}
+	if f.nonEmpty && len(content) > 0 {
+		hasNewline := bytes.HasSuffix(content, []byte("\n"))
+		switch {
+		case f.trailingNewline && !hasNewline:
+			content = append(content, '\n')
+		case !f.trailingNewline && hasNewline:
+			// 只去掉最后一个换行，内容末尾有意保留的空行不受影响
+			content = bytes.TrimSuffix(content, []byte("\n"))
+			content = bytes.TrimSuffix(content, []byte("\r"))
+		}
+	}
+	if f.eolKnown && f.crlf {
+		content = bytes.ReplaceAll(content, []byte("\n"), []byte("\r\n"))
+	}
+
+	if f.bom {
+		content = append(append([]byte(nil), utf8BOM...), content...)
+	}
+	return content
+}
+
+// preserveTextFormat 若目标文件已存在且为文本，按其格式规范化新内容
+func preserveTextFormat(original, content []byte) []byte {
+	format, ok := detectTextFormat(original)
+	if !ok || bytes.IndexByte(content, 0) >= 0 {
+		return content
+	}
+	return format.apply(content)
+}
@@ -0,0 +1,28 @@
+package mcp
+
+import "testing"
+
+func TestPreserveTextFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		original string
+		content  string
+		want     string
+	}{
+		{"new file keeps content", "", "a\r\nb", "a\r\nb"},
+		{"crlf file", "a\r\nb\r\n", "x\ny\n", "x\r\ny\r\n"},
+		{"lf file", "a\nb\n", "x\r\ny\r\n", "x\ny\n"},
+		{"adds missing trailing newline", "a\n", "x", "x\n"},
+		{"removes trailing newline", "a\nb", "x\ny\n", "x\ny"},
+		{"removes only the final newline", "a\nb", "x\n\n\n", "x\n\n"},
+		{"removes only the final crlf", "a\r\nb", "x\r\n\r\n", "x\r\n"},
+		{"no line endings in original", "a", "x\r\ny\n", "x\r\ny"},
+		{"bom", "\xEF\xBB\xBFa\n", "x\n", "\xEF\xBB\xBFx\n"},
+		{"binary original", "a\x00b", "x\n", "x\n"},
+	}
+	for _, tt := range tests {
+		if got := string(preserveTextFormat([]byte(tt.original), []byte(tt.content))); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package mcp
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// codeTypes 错误码对应的类型名，便于模型按类型决定如何恢复
+var codeTypes = map[int]string{
+	CodeParseError:     "parse_error",
+	CodeInvalidRequest: "invalid_request",
+	CodeMethodNotFound: "unknown_tool",
+	CodeInvalidParams:  "invalid_params",
+	CodeInternalError:  "internal_error",
+	CodeToolError:      "tool_error",
+	CodePathNotAllowed: "path_not_allowed",
+	CodeFileTooLarge:   "file_too_large",
+	CodeFileNotFound:   "file_not_found",
+	CodeBackupFailed:   "backup_failed",
+	CodeCacheError:     "cache_error",
+	CodeReadError:      "read_error",
+	CodeWriteError:     "write_error",
+	CodeFileNotRead:    "file_not_read",
+}
+
+// ToolError 返回给模型的结构化工具错误
+type ToolError struct {
+	Code       int    `json:"code"`
+	Type       string `json:"type"`
+	Message    string `json:"message"`
+	Suggestion string `json:"suggestion,omitempty"`
+	// Data 错误的附加信息（如大小上限），不含已在其他字段中的内容
+	Data map[string]interface{} `json:"data,omitempty"`
+}
+
+// NewToolError 将工具执行错误归类；已是 MCP 错误时沿用其错误码
+func NewToolError(err error) *ToolError {
+	var rpcErr *JSONRPCError
+	if !errors.As(err, &rpcErr) {
+		rpcErr = ConvertToMCPError(err)
+	}
+
+	toolErr := &ToolError{
+		Code:    rpcErr.Code,
+		Type:    codeTypes[rpcErr.Code],
+		Message: err.Error(),
+	}
+	if toolErr.Type == "" {
+		toolErr.Type = "tool_error"
+	}
+	if data, ok := rpcErr.Data.(map[string]interface{}); ok {
+		toolErr.Suggestion, _ = data["suggestion"].(string)
+		for key, value := range data {
+			if key == "suggestion" || key == "original_error" {
+				continue
+			}
+			if toolErr.Data == nil {
+				toolErr.Data = make(map[string]interface{})
+			}
+			toolErr.Data[key] = value
+		}
+	}
+	return toolErr
+}
+
+// CallToolResult 包装为失败的工具调用结果
+func (e *ToolError) CallToolResult() *CallToolResult {
+	return &CallToolResult{
+		Content: []ToolResultContent{{Type: "text", Text: e.Result()}},
+		IsError: true,
+	}
+}
+
+// Result 序列化为工具结果内容
+func (e *ToolError) Result() string {
+	data, err := json.Marshal(map[string]interface{}{"error": e})
+	if err != nil {
+		return e.Message
+	}
+	return string(data)
+}
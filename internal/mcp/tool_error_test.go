@@ -0,0 +1,111 @@
+package mcp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestNewToolError(t *testing.T) {
+	tests := []struct {
+		err      error
+		code     int
+		typeName string
+	}{
+		{fmt.Errorf("工具执行失败: %w", ConvertToMCPError(errors.New("path outside allowed roots: /etc"))), CodePathNotAllowed, "path_not_allowed"},
+		{fmt.Errorf("工具执行失败: %w", errors.New("write conflict: a.go changed since last read")), CodeWriteError, "write_error"},
+		{errors.New("工具未找到: nope"), CodeMethodNotFound, "unknown_tool"},
+		{errors.New("缺少或无效的path参数"), CodeInvalidParams, "invalid_params"},
+		{errors.New("something odd"), CodeInternalError, "internal_error"},
+	}
+	for _, tt := range tests {
+		got := NewToolError(tt.err)
+		if got.Code != tt.code || got.Type != tt.typeName {
+			t.Errorf("NewToolError(%q) = %d/%s, want %d/%s", tt.err, got.Code, got.Type, tt.code, tt.typeName)
+		}
+		if got.Message != tt.err.Error() {
+			t.Errorf("message = %q, want %q", got.Message, tt.err.Error())
+		}
+	}
+
+	var payload struct {
+		Error ToolError `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(NewToolError(errors.New("open x: no such file or directory")).Result()), &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Error.Code != CodeFileNotFound || payload.Error.Suggestion == "" {
+		t.Errorf("payload = %+v", payload.Error)
+	}
+}
+
+func TestToolErrorCarriesLimits(t *testing.T) {
+	err := ConvertToMCPError(errors.New("file too large: big.log (12.50 MB, limit 10.00 MB)"))
+	toolErr := NewToolError(fmt.Errorf("wrapped: %w", err))
+	if toolErr.Code != CodeFileTooLarge || toolErr.Data["size_mb"] != 12.5 || toolErr.Data["max_size_mb"] != 10.0 {
+		t.Errorf("tool error = %+v", toolErr)
+	}
+	if _, ok := toolErr.Data["original_error"]; ok {
+		t.Error("original_error should not be duplicated in data")
+	}
+
+	result := toolErr.CallToolResult()
+	if !result.IsError || len(result.Content) != 1 {
+		t.Errorf("result = %+v", result)
+	}
+}
+
+func TestHandleCallToolReturnsStructuredErrors(t *testing.T) {
+	registry := NewToolRegistry()
+	result, err := registry.HandleCallTool(CallToolRequest{Name: "missing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.IsError || !strings.Contains(result.Content[0].Text, `"type":"unknown_tool"`) {
+		t.Errorf("result = %+v", result)
+	}
+}
+
+// panicTool 执行时 panic 的测试工具
+type panicTool struct{}
+
+func (panicTool) Name() string                                        { return "boom" }
+func (panicTool) Description() string                                 { return "panics" }
+func (panicTool) GetSchema() map[string]interface{}                   { return nil }
+func (panicTool) Execute(map[string]interface{}) (interface{}, error) { panic("kaboom") }
+
+func TestHandleCallToolRecoversPanics(t *testing.T) {
+	registry := NewToolRegistry()
+	registry.Register(panicTool{})
+
+	var reported ToolPanic
+	SetPanicHandler(func(p ToolPanic) { reported = p })
+	defer SetPanicHandler(nil)
+
+	result, err := registry.HandleCallTool(CallToolRequest{Name: "boom"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result == nil || !result.IsError {
+		t.Fatalf("result = %+v", result)
+	}
+
+	var payload struct {
+		Error ToolError `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(result.Content[0].Text), &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Error.Code != CodeInternalError || !strings.Contains(payload.Error.Message, "kaboom") {
+		t.Errorf("payload = %+v", payload.Error)
+	}
+	stack, _ := payload.Error.Data["stack"].(string)
+	if stack == "" || len([]rune(stack)) > maxPanicStack {
+		t.Errorf("stack length = %d", len([]rune(stack)))
+	}
+	if reported.Tool != "boom" || reported.Value != "kaboom" || reported.Stack == "" {
+		t.Errorf("reported = %+v", reported)
+	}
+}
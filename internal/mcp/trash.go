@@ -0,0 +1,187 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/utils"
+)
+
+const (
+	// trashManifestName 会话回收站的清单文件
+	trashManifestName = "manifest.json"
+	// trashRetention 旧会话回收站的保留时间
+	trashRetention = 7 * 24 * time.Hour
+)
+
+// TrashEntry 回收站中的一项
+type TrashEntry struct {
+	ID           int       `json:"id"`
+	OriginalPath string    `json:"original_path"`
+	TrashPath    string    `json:"trash_path"`
+	IsDir        bool      `json:"is_dir"`
+	DeletedAt    time.Time `json:"deleted_at"`
+}
+
+// Trash 会话回收站，delete_file 删除的内容移动到配置目录 trash/<会话>/ 下，可用 /trash 恢复
+type Trash struct {
+	mu        sync.Mutex
+	sessionID string
+	dir       string // 首次使用时创建
+	entries   []TrashEntry
+	nextID    int
+}
+
+// NewTrash 创建会话回收站，目录在第一次删除时才创建
+func NewTrash(sessionID string) *Trash {
+	return &Trash{sessionID: sessionID, nextID: 1}
+}
+
+// Dir 返回回收站目录
+func (t *Trash) Dir() (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.ensureDir()
+}
+
+// ensureDir 创建会话回收站目录，同时清理过期的旧会话回收站（调用方持有锁）
+func (t *Trash) ensureDir() (string, error) {
+	if t.dir != "" {
+		return t.dir, nil
+	}
+	configDir, err := utils.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	root := filepath.Join(configDir, "trash")
+	dir := filepath.Join(root, t.sessionID)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create trash directory: %w", err)
+	}
+	t.dir = dir
+	pruneTrash(root, dir)
+	return dir, nil
+}
+
+// pruneTrash 删除超过保留期的旧会话回收站
+func pruneTrash(root, current string) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		path := filepath.Join(root, entry.Name())
+		info, err := entry.Info()
+		if err != nil || !entry.IsDir() || path == current {
+			continue
+		}
+		if time.Since(info.ModTime()) > trashRetention {
+			os.RemoveAll(path)
+		}
+	}
+}
+
+// MoveToTrash 将文件或目录移入回收站
+func (t *Trash) MoveToTrash(path string) (TrashEntry, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return TrashEntry{}, err
+	}
+	info, err := os.Lstat(absPath)
+	if err != nil {
+		return TrashEntry{}, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	dir, err := t.ensureDir()
+	if err != nil {
+		return TrashEntry{}, err
+	}
+
+	entry := TrashEntry{
+		ID:           t.nextID,
+		OriginalPath: absPath,
+		TrashPath:    filepath.Join(dir, fmt.Sprintf("%d-%s", t.nextID, filepath.Base(absPath))),
+		IsDir:        info.IsDir(),
+		DeletedAt:    time.Now(),
+	}
+	if err := movePath(absPath, entry.TrashPath); err != nil {
+		return TrashEntry{}, fmt.Errorf("failed to move to trash: %w", err)
+	}
+
+	t.nextID++
+	t.entries = append(t.entries, entry)
+	t.saveManifest()
+	return entry, nil
+}
+
+// Entries 返回回收站中的项目（按删除顺序）
+func (t *Trash) Entries() []TrashEntry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]TrashEntry(nil), t.entries...)
+}
+
+// Restore 将指定项目恢复到原位置，原位置已存在时失败
+func (t *Trash) Restore(id int) (TrashEntry, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for i, entry := range t.entries {
+		if entry.ID != id {
+			continue
+		}
+		if _, err := os.Lstat(entry.OriginalPath); err == nil {
+			return entry, fmt.Errorf("%s already exists", entry.OriginalPath)
+		}
+		if err := os.MkdirAll(filepath.Dir(entry.OriginalPath), 0755); err != nil {
+			return entry, err
+		}
+		if err := movePath(entry.TrashPath, entry.OriginalPath); err != nil {
+			return entry, err
+		}
+		t.entries = append(t.entries[:i], t.entries[i+1:]...)
+		t.saveManifest()
+		return entry, nil
+	}
+	return TrashEntry{}, fmt.Errorf("no trash entry with id %d", id)
+}
+
+// Empty 永久删除回收站中的所有项目，返回删除的数量
+func (t *Trash) Empty() (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	count := len(t.entries)
+	for _, entry := range t.entries {
+		if err := os.RemoveAll(entry.TrashPath); err != nil {
+			return 0, err
+		}
+	}
+	t.entries = nil
+	t.saveManifest()
+	return count, nil
+}
+
+// saveManifest 写入清单，便于会话结束后手动恢复（调用方持有锁）
+func (t *Trash) saveManifest() {
+	if t.dir == "" {
+		return
+	}
+	data, err := json.MarshalIndent(t.entries, "", "  ")
+	if err != nil {
+		return
+	}
+	os.WriteFile(filepath.Join(t.dir, trashManifestName), data, 0600)
+}
+
+// Trash 返回当前回收站，未启用时为 nil
+func (r *ToolRegistry) Trash() *Trash {
+	return r.trash
+}
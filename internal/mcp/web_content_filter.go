@@ -0,0 +1,208 @@
+package mcp
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/utils"
+)
+
+const (
+	// 默认的单页字符上限
+	defaultMaxPageChars = 4000
+	// 默认的总字符预算，防止一次爬取塞满模型上下文
+	defaultMaxTotalChars = 20000
+	// 短于该长度的行不参与样板去重（如空行、单个符号）
+	minBoilerplateLineLen = 12
+)
+
+// webPage 待过滤的网页内容
+type webPage struct {
+	URL     string
+	Content string
+}
+
+// contentFilterOptions 网页内容过滤参数
+type contentFilterOptions struct {
+	MaxPageChars  int
+	MaxTotalChars int
+	Dedupe        bool
+}
+
+// filteredPage 过滤后的网页
+type filteredPage struct {
+	URL          string
+	Content      string
+	Truncated    bool
+	RemovedLines int
+}
+
+// contentFilterResult 过滤结果
+type contentFilterResult struct {
+	Pages        []filteredPage
+	OmittedPages []string // 超出总预算而未包含的页面
+	TotalChars   int
+}
+
+// parseContentFilterOptions 从工具参数中解析过滤参数
+func parseContentFilterOptions(args map[string]interface{}) contentFilterOptions {
+	opts := contentFilterOptions{
+		MaxPageChars:  getIntArg(args, "max_chars_per_page", defaultMaxPageChars),
+		MaxTotalChars: getIntArg(args, "max_total_chars", defaultMaxTotalChars),
+		Dedupe:        true,
+	}
+	if d, ok := args["dedupe"].(bool); ok {
+		opts.Dedupe = d
+	}
+	if opts.MaxPageChars <= 0 {
+		opts.MaxPageChars = defaultMaxPageChars
+	}
+	if opts.MaxTotalChars <= 0 {
+		opts.MaxTotalChars = defaultMaxTotalChars
+	}
+	return opts
+}
+
+// contentFilterSchemaProperties 过滤参数的 schema 定义，供 web_crawl / web_extract 共用
+func contentFilterSchemaProperties() map[string]interface{} {
+	return map[string]interface{}{
+		"max_chars_per_page": map[string]interface{}{
+			"type":        "integer",
+			"description": fmt.Sprintf("每个页面保留的最大字符数 (默认 %d)", defaultMaxPageChars),
+			"default":     defaultMaxPageChars,
+		},
+		"max_total_chars": map[string]interface{}{
+			"type":        "integer",
+			"description": fmt.Sprintf("所有页面合计的最大字符数 (默认 %d)，超出的页面只列出 URL", defaultMaxTotalChars),
+			"default":     defaultMaxTotalChars,
+		},
+		"dedupe": map[string]interface{}{
+			"type":        "boolean",
+			"description": "移除在多个页面重复出现的样板内容（导航、页脚等）",
+			"default":     true,
+		},
+	}
+}
+
+// filterWebPages 对多个网页内容执行样板去重、单页截断和总预算控制
+func filterWebPages(pages []webPage, opts contentFilterOptions) contentFilterResult {
+	var boilerplate map[string]bool
+	if opts.Dedupe && len(pages) > 1 {
+		boilerplate = findBoilerplateLines(pages)
+	}
+
+	var result contentFilterResult
+	for _, page := range pages {
+		content := page.Content
+		removed := 0
+		if boilerplate != nil {
+			content, removed = stripBoilerplate(content, boilerplate)
+		}
+
+		content, truncated := truncateRunes(content, opts.MaxPageChars)
+
+		remaining := opts.MaxTotalChars - result.TotalChars
+		if remaining <= 0 {
+			result.OmittedPages = append(result.OmittedPages, page.URL)
+			continue
+		}
+		if length := len([]rune(content)); length > remaining {
+			content, _ = truncateRunes(content, remaining)
+			truncated = true
+		}
+
+		result.TotalChars += len([]rune(content))
+		result.Pages = append(result.Pages, filteredPage{
+			URL:          page.URL,
+			Content:      content,
+			Truncated:    truncated,
+			RemovedLines: removed,
+		})
+	}
+
+	return result
+}
+
+// findBoilerplateLines 找出在至少两个页面中出现的行
+// 返回值的 value 表示该行是否已在某个页面中保留过
+func findBoilerplateLines(pages []webPage) map[string]bool {
+	pageCount := make(map[string]int)
+	for _, page := range pages {
+		seen := make(map[string]bool)
+		for _, line := range strings.Split(page.Content, "\n") {
+			key := strings.TrimSpace(line)
+			if len(key) < minBoilerplateLineLen || seen[key] {
+				continue
+			}
+			seen[key] = true
+			pageCount[key]++
+		}
+	}
+
+	boilerplate := make(map[string]bool)
+	for line, count := range pageCount {
+		if count >= 2 {
+			boilerplate[line] = false
+		}
+	}
+	return boilerplate
+}
+
+// stripBoilerplate 移除样板行，返回剩余内容和移除的行数
+// 样板行只在首次出现的页面保留，之后的页面中全部移除
+func stripBoilerplate(content string, boilerplate map[string]bool) (string, int) {
+	lines := strings.Split(content, "\n")
+	kept := make([]string, 0, len(lines))
+	var firstSeen []string
+	removed := 0
+
+	for _, line := range lines {
+		key := strings.TrimSpace(line)
+		if emitted, ok := boilerplate[key]; ok {
+			if emitted {
+				removed++
+				continue
+			}
+			firstSeen = append(firstSeen, key)
+		}
+		kept = append(kept, line)
+	}
+
+	for _, key := range firstSeen {
+		boilerplate[key] = true
+	}
+
+	return strings.Join(kept, "\n"), removed
+}
+
+// truncateRunes 按字符数截断文本（不切开 emoji 等组合字符），返回截断后的文本和是否发生截断
+func truncateRunes(text string, maxChars int) (string, bool) {
+	return utils.TruncateText(text, maxChars)
+}
+
+// writeFilteredPages 将过滤结果格式化为 Markdown
+func writeFilteredPages(builder *strings.Builder, result contentFilterResult) {
+	for i, page := range result.Pages {
+		builder.WriteString(fmt.Sprintf("## 页面 %d: %s\n\n", i+1, page.URL))
+
+		if page.Content != "" {
+			builder.WriteString(page.Content)
+			builder.WriteString("\n\n")
+		}
+		if page.Truncated {
+			builder.WriteString("_（内容过长，已截断）_\n\n")
+		}
+
+		builder.WriteString("---\n\n")
+	}
+
+	if len(result.OmittedPages) > 0 {
+		builder.WriteString(fmt.Sprintf("以下 %d 个页面因超出 max_total_chars 预算未包含内容：\n\n", len(result.OmittedPages)))
+		for _, url := range result.OmittedPages {
+			builder.WriteString("- ")
+			builder.WriteString(url)
+			builder.WriteString("\n")
+		}
+		builder.WriteString("\n")
+	}
+}
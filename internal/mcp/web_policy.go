@@ -0,0 +1,220 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/config"
+	"github.com/Zacy-Sokach/PolyAgent/internal/utils"
+)
+
+// WebPolicy 联网工具的出站访问策略
+type WebPolicy struct {
+	// 域名白名单，非空时只允许列表中的域名及其子域名
+	AllowedDomains []string
+	// 域名黑名单，优先级高于白名单
+	DeniedDomains []string
+	// 允许访问私有网络地址
+	AllowPrivateNetworks bool
+	// 爬取时遵守 robots.txt
+	RespectRobots bool
+}
+
+// lookupIPAddr 解析主机地址，测试时可替换
+var lookupIPAddr = net.DefaultResolver.LookupIPAddr
+
+// cgnatRange 运营商级 NAT 地址段（100.64.0.0/10），net.IP.IsPrivate 不包含
+var cgnatRange = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// DefaultWebPolicy 返回默认策略：不限制域名、拒绝私有地址、遵守 robots.txt
+func DefaultWebPolicy() *WebPolicy {
+	return &WebPolicy{RespectRobots: true}
+}
+
+// loadWebPolicy 从配置文件加载策略，读取失败时使用默认策略
+func loadWebPolicy() *WebPolicy {
+	cfg, err := config.GetWebPolicy()
+	if err != nil {
+		return DefaultWebPolicy()
+	}
+	return &WebPolicy{
+		AllowedDomains:       normalizeDomains(cfg.AllowedDomains),
+		DeniedDomains:        normalizeDomains(cfg.DeniedDomains),
+		AllowPrivateNetworks: cfg.AllowPrivateNetworks,
+		RespectRobots:        !cfg.IgnoreRobots,
+	}
+}
+
+// normalizeDomains 统一域名格式，去掉通配前缀和末尾的点
+func normalizeDomains(domains []string) []string {
+	result := make([]string, 0, len(domains))
+	for _, d := range domains {
+		d = strings.ToLower(strings.TrimSpace(d))
+		d = strings.TrimPrefix(d, "*.")
+		d = strings.Trim(d, ".")
+		if d != "" {
+			result = append(result, d)
+		}
+	}
+	return result
+}
+
+// domainMatches 判断 host 是否为 domain 本身或其子域名
+func domainMatches(host, domain string) bool {
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// CheckHost 按域名黑白名单检查主机
+func (p *WebPolicy) CheckHost(host string) error {
+	host = strings.Trim(strings.ToLower(host), ".")
+	if host == "" {
+		return fmt.Errorf("empty host")
+	}
+
+	for _, d := range p.DeniedDomains {
+		if domainMatches(host, d) {
+			return fmt.Errorf("domain %s is denied by web_policy", host)
+		}
+	}
+
+	if len(p.AllowedDomains) == 0 {
+		return nil
+	}
+	for _, d := range p.AllowedDomains {
+		if domainMatches(host, d) {
+			return nil
+		}
+	}
+	return fmt.Errorf("domain %s is not in web_policy.allowed_domains", host)
+}
+
+// AllowsURL 仅按域名策略判断 URL 是否可用，用于过滤搜索和爬取结果
+func (p *WebPolicy) AllowsURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	return p.CheckHost(u.Hostname()) == nil
+}
+
+// CheckURL 检查即将访问的 URL：协议、域名策略以及是否指向私有地址
+func (p *WebPolicy) CheckURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return fmt.Errorf("invalid url %q: %w", rawURL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported url scheme %q: only http and https are allowed", u.Scheme)
+	}
+
+	host := u.Hostname()
+	if err := p.CheckHost(host); err != nil {
+		return err
+	}
+
+	if p.AllowPrivateNetworks {
+		return nil
+	}
+	return checkPublicHost(ctx, host)
+}
+
+// checkPublicHost 解析主机并拒绝任何私有、回环或链路本地地址
+func checkPublicHost(ctx context.Context, host string) error {
+	if ip := net.ParseIP(host); ip != nil {
+		if isPrivateIP(ip) {
+			return fmt.Errorf("address %s is in a private network range", host)
+		}
+		return nil
+	}
+
+	addrs, err := lookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("failed to resolve host %s: %w", host, err)
+	}
+	if len(addrs) == 0 {
+		return fmt.Errorf("failed to resolve host %s: no addresses", host)
+	}
+	for _, addr := range addrs {
+		if isPrivateIP(addr.IP) {
+			return fmt.Errorf("host %s resolves to private address %s", host, addr.IP)
+		}
+	}
+	return nil
+}
+
+// transport 返回在建立连接时按策略检查目标地址的 Transport。
+// CheckURL 只检查请求前的解析结果，DNS 可能在之后改为指向私有地址（DNS rebinding），
+// 因此实际连接的 IP 也要检查；经代理访问时目标由代理解析，只有到代理本身的连接不受限制
+func (p *WebPolicy) transport() *http.Transport {
+	transport := utils.NewTransport()
+	if p.AllowPrivateNetworks {
+		return transport
+	}
+
+	var proxies sync.Map
+	if proxy := transport.Proxy; proxy != nil {
+		transport.Proxy = func(req *http.Request) (*url.URL, error) {
+			u, err := proxy(req)
+			if u != nil {
+				proxies.Store(proxyDialAddress(u), true)
+			}
+			return u, err
+		}
+	}
+
+	direct := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	checked := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: rejectPrivateDial}
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if _, ok := proxies.Load(addr); ok {
+			return direct.DialContext(ctx, network, addr)
+		}
+		return checked.DialContext(ctx, network, addr)
+	}
+	return transport
+}
+
+// proxyDialAddress 返回 Transport 连接代理时使用的 host:port
+func proxyDialAddress(u *url.URL) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	port := "80"
+	switch u.Scheme {
+	case "https":
+		port = "443"
+	case "socks5", "socks5h":
+		port = "1080"
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+// rejectPrivateDial net.Dialer 的 Control 回调，此时 address 已解析为实际连接的 IP
+func rejectPrivateDial(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || isPrivateIP(ip) {
+		return fmt.Errorf("connection to private address %s blocked by web_policy", host)
+	}
+	return nil
+}
+
+// isPrivateIP 判断地址是否属于不应从外部访问的网段
+func isPrivateIP(ip net.IP) bool {
+	return ip.IsPrivate() ||
+		ip.IsLoopback() ||
+		ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		cgnatRange.Contains(ip)
+}
@@ -0,0 +1,142 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/utils"
+)
+
+func TestWebPolicyCheckHost(t *testing.T) {
+	policy := &WebPolicy{
+		AllowedDomains: normalizeDomains([]string{"*.example.com", "Docs.Go.dev."}),
+		DeniedDomains:  normalizeDomains([]string{"private.example.com"}),
+	}
+	tests := []struct {
+		host string
+		ok   bool
+	}{
+		{"example.com", true},
+		{"api.example.com", true},
+		{"API.Example.COM.", true},
+		{"docs.go.dev", true},
+		{"notexample.com", false},
+		{"go.dev", false},
+		{"private.example.com", false},
+		{"a.private.example.com", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if err := policy.CheckHost(tt.host); (err == nil) != tt.ok {
+			t.Errorf("CheckHost(%q) = %v, want ok=%v", tt.host, err, tt.ok)
+		}
+	}
+
+	if !DefaultWebPolicy().AllowsURL("https://anything.org/page") {
+		t.Error("default policy should allow any domain")
+	}
+	if policy.AllowsURL("https://private.example.com/x") || policy.AllowsURL("://bad") {
+		t.Error("AllowsURL accepted a denied or invalid url")
+	}
+}
+
+func TestWebPolicyCheckURL(t *testing.T) {
+	resolved := map[string][]string{
+		"public.test":   {"93.184.216.34"},
+		"internal.test": {"93.184.216.34", "10.0.0.5"},
+		"cgnat.test":    {"100.64.1.1"},
+	}
+	original := lookupIPAddr
+	lookupIPAddr = func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		addrs, ok := resolved[host]
+		if !ok {
+			return nil, fmt.Errorf("no such host")
+		}
+		var result []net.IPAddr
+		for _, a := range addrs {
+			result = append(result, net.IPAddr{IP: net.ParseIP(a)})
+		}
+		return result, nil
+	}
+	defer func() { lookupIPAddr = original }()
+
+	tests := []struct {
+		url    string
+		reason string // 为空表示允许
+	}{
+		{"https://public.test/page", ""},
+		{"http://93.184.216.34/", ""},
+		{"ftp://public.test/file", "unsupported url scheme"},
+		{"file:///etc/passwd", "unsupported url scheme"},
+		{"http://127.0.0.1:8080/", "private network"},
+		{"http://[::1]/", "private network"},
+		{"http://169.254.169.254/latest/meta-data", "private network"},
+		{"http://internal.test/", "private address 10.0.0.5"},
+		{"http://cgnat.test/", "private address"},
+		{"http://missing.test/", "failed to resolve"},
+	}
+	policy := DefaultWebPolicy()
+	for _, tt := range tests {
+		err := policy.CheckURL(context.Background(), tt.url)
+		if tt.reason == "" && err != nil {
+			t.Errorf("CheckURL(%q) = %v, want allowed", tt.url, err)
+		}
+		if tt.reason != "" && (err == nil || !strings.Contains(err.Error(), tt.reason)) {
+			t.Errorf("CheckURL(%q) = %v, want error containing %q", tt.url, err, tt.reason)
+		}
+	}
+
+	allowPrivate := &WebPolicy{AllowPrivateNetworks: true}
+	if err := allowPrivate.CheckURL(context.Background(), "http://internal.test/"); err != nil {
+		t.Errorf("allow_private_networks: %v", err)
+	}
+}
+
+func TestWebPolicyTransportRejectsPrivateConnections(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	// CheckURL 之后 DNS 改为指向私有地址时，连接本身也会被拒绝
+	client := &http.Client{Transport: DefaultWebPolicy().transport()}
+	if _, err := client.Get(server.URL); err == nil || !strings.Contains(err.Error(), "blocked by web_policy") {
+		t.Fatalf("connection to loopback server: err = %v", err)
+	}
+
+	client = &http.Client{Transport: (&WebPolicy{AllowPrivateNetworks: true}).transport()}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+}
+
+func TestWebPolicyTransportAllowsLocalProxy(t *testing.T) {
+	// 本地代理（如 127.0.0.1:7890）很常见，到代理本身的连接不受私有地址限制
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "proxied "+r.URL.String())
+	}))
+	defer proxy.Close()
+	if err := utils.ConfigureNetwork(proxy.URL, nil); err != nil {
+		t.Fatal(err)
+	}
+	defer utils.ConfigureNetwork("", nil)
+
+	client := &http.Client{Transport: DefaultWebPolicy().transport()}
+	resp, err := client.Get("http://public.test/robots.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "proxied http://public.test/robots.txt" {
+		t.Errorf("body = %q", body)
+	}
+}
@@ -0,0 +1,209 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	robotsUserAgent = "polyagent"
+	robotsTimeout   = 5 * time.Second
+	maxRobotsSize   = 512 * 1024
+)
+
+// robotsRule robots.txt 中的一条 Allow/Disallow 规则
+type robotsRule struct {
+	pattern string
+	allow   bool
+}
+
+// robotsRules 适用于本工具的 robots.txt 规则集
+type robotsRules struct {
+	rules       []robotsRule
+	disallowAll bool
+}
+
+// fetchRobots 获取并解析站点的 robots.txt
+// 按 RFC 9309：4xx 视为无限制，5xx 视为全站禁止；网络错误时返回 error，由调用方决定
+func fetchRobots(ctx context.Context, policy *WebPolicy, siteURL *url.URL) (*robotsRules, error) {
+	robotsURL := &url.URL{Scheme: siteURL.Scheme, Host: siteURL.Host, Path: "/robots.txt"}
+
+	client := &http.Client{
+		Timeout:   robotsTimeout,
+		Transport: policy.transport(),
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return fmt.Errorf("too many redirects")
+			}
+			// 重定向目标同样需要符合访问策略
+			return policy.CheckURL(req.Context(), req.URL.String())
+		},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", robotsURL.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create robots request: %w", err)
+	}
+	req.Header.Set("User-Agent", robotsUserAgent)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch robots.txt: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 500:
+		return &robotsRules{disallowAll: true}, nil
+	case resp.StatusCode >= 400:
+		return &robotsRules{}, nil
+	case resp.StatusCode != http.StatusOK:
+		return &robotsRules{}, nil
+	}
+
+	return parseRobots(io.LimitReader(resp.Body, maxRobotsSize), robotsUserAgent), nil
+}
+
+// parseRobots 解析 robots.txt，优先使用匹配 agent 的分组，否则使用 * 分组
+func parseRobots(r io.Reader, agent string) *robotsRules {
+	agent = strings.ToLower(agent)
+
+	var (
+		specific, wildcard []robotsRule
+		hasSpecific        bool
+		groupAgents        []string
+		inRules            bool
+	)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = line[:idx]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// 规则之后出现的 user-agent 开启新分组
+			if inRules {
+				groupAgents = nil
+				inRules = false
+			}
+			groupAgents = append(groupAgents, strings.ToLower(value))
+		case "allow", "disallow":
+			inRules = true
+			if value == "" {
+				// 空的 Disallow 表示不限制
+				continue
+			}
+			rule := robotsRule{pattern: value, allow: key == "allow"}
+			for _, ua := range groupAgents {
+				switch {
+				case ua == "*":
+					wildcard = append(wildcard, rule)
+				case strings.Contains(agent, ua):
+					specific = append(specific, rule)
+					hasSpecific = true
+				}
+			}
+		}
+	}
+
+	if hasSpecific {
+		return &robotsRules{rules: specific}
+	}
+	return &robotsRules{rules: wildcard}
+}
+
+// Allowed 判断路径是否允许访问：最长匹配优先，长度相同时 Allow 优先
+func (r *robotsRules) Allowed(path string) bool {
+	if r == nil {
+		return true
+	}
+	if r.disallowAll {
+		return false
+	}
+	if path == "" {
+		path = "/"
+	}
+
+	bestLen := -1
+	allowed := true
+	for _, rule := range r.rules {
+		if !robotsPatternMatches(rule.pattern, path) {
+			continue
+		}
+		if l := len(rule.pattern); l > bestLen || (l == bestLen && rule.allow) {
+			bestLen = l
+			allowed = rule.allow
+		}
+	}
+	return allowed
+}
+
+// AllowsURL 判断同站点的 URL 是否允许访问
+func (r *robotsRules) AllowsURL(u *url.URL) bool {
+	path := u.EscapedPath()
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	return r.Allowed(path)
+}
+
+// ExcludePatterns 将 Disallow 规则转换为爬取接口使用的 URL 排除正则
+func (r *robotsRules) ExcludePatterns() []string {
+	if r == nil {
+		return nil
+	}
+	if r.disallowAll {
+		return []string{".*"}
+	}
+	var patterns []string
+	for _, rule := range r.rules {
+		if !rule.allow {
+			patterns = append(patterns, robotsPatternRegexp(rule.pattern))
+		}
+	}
+	return patterns
+}
+
+// robotsPatternMatches 按 robots.txt 语义（* 通配、$ 结尾锚定）匹配路径
+func robotsPatternMatches(pattern, path string) bool {
+	if !strings.ContainsAny(pattern, "*$") {
+		return strings.HasPrefix(path, pattern)
+	}
+	re, err := regexp.Compile("^" + robotsPatternRegexp(pattern))
+	if err != nil {
+		return false
+	}
+	return re.MatchString(path)
+}
+
+// robotsPatternRegexp 将 robots.txt 路径模式转换为正则表达式（不含开头锚点）
+func robotsPatternRegexp(pattern string) string {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	re := strings.Join(parts, ".*")
+	if anchored {
+		re += "$"
+	}
+	return re
+}
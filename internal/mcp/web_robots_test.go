@@ -0,0 +1,132 @@
+package mcp
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseRobots(t *testing.T) {
+	robots := `# comment
+User-agent: *
+Disallow: /private/
+Allow: /private/public   # trailing comment
+Disallow: /*.pdf$
+
+User-agent: otherbot
+Disallow: /
+`
+	rules := parseRobots(strings.NewReader(robots), robotsUserAgent)
+	tests := []struct {
+		path    string
+		allowed bool
+	}{
+		{"", true},
+		{"/", true},
+		{"/private/", false},
+		{"/private/secret", false},
+		{"/private/public", true},
+		{"/private/public/page", true},
+		{"/docs/a.pdf", false},
+		{"/docs/a.pdf?x=1", true},
+		{"/docs/a.pdfx", true},
+	}
+	for _, tt := range tests {
+		if got := rules.Allowed(tt.path); got != tt.allowed {
+			t.Errorf("Allowed(%q) = %v, want %v", tt.path, got, tt.allowed)
+		}
+	}
+
+	want := []string{`/private/`, `/.*\.pdf$`}
+	if got := rules.ExcludePatterns(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ExcludePatterns = %q, want %q", got, want)
+	}
+}
+
+func TestParseRobotsSpecificGroup(t *testing.T) {
+	// 匹配本工具的分组优先于 *，多个 user-agent 可以共用一个分组
+	robots := `User-agent: *
+Disallow: /
+
+User-agent: googlebot
+User-agent: PolyAgent
+Disallow: /admin
+Disallow:
+`
+	rules := parseRobots(strings.NewReader(robots), robotsUserAgent)
+	if !rules.Allowed("/docs") || rules.Allowed("/admin/users") {
+		t.Errorf("specific group not used: %+v", rules)
+	}
+
+	u, _ := url.Parse("https://example.com/admin?page=1")
+	if rules.AllowsURL(u) {
+		t.Error("AllowsURL ignored the disallow rule")
+	}
+
+	var nilRules *robotsRules
+	if !nilRules.Allowed("/anything") || nilRules.ExcludePatterns() != nil {
+		t.Error("nil rules should not restrict anything")
+	}
+	if all := (&robotsRules{disallowAll: true}); all.Allowed("/") || !reflect.DeepEqual(all.ExcludePatterns(), []string{".*"}) {
+		t.Error("disallowAll should block every path")
+	}
+}
+
+func TestFetchRobots(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/robots.txt" {
+			t.Errorf("requested %s", r.URL.Path)
+		}
+		if ua := r.Header.Get("User-Agent"); ua != robotsUserAgent {
+			t.Errorf("User-Agent = %q", ua)
+		}
+		w.WriteHeader(status)
+		io.WriteString(w, "User-agent: *\nDisallow: /private\n")
+	}))
+	defer server.Close()
+	site, _ := url.Parse(server.URL + "/some/page")
+	policy := &WebPolicy{AllowPrivateNetworks: true}
+
+	rules, err := fetchRobots(context.Background(), policy, site)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rules.Allowed("/private/x") || !rules.Allowed("/public") {
+		t.Errorf("rules = %+v", rules)
+	}
+
+	// RFC 9309：4xx 不限制，5xx 视为全站禁止
+	status = http.StatusNotFound
+	if rules, err := fetchRobots(context.Background(), policy, site); err != nil || !rules.Allowed("/private/x") {
+		t.Errorf("404: rules = %+v, err = %v", rules, err)
+	}
+	status = http.StatusServiceUnavailable
+	if rules, err := fetchRobots(context.Background(), policy, site); err != nil || rules.Allowed("/") {
+		t.Errorf("503: rules = %+v, err = %v", rules, err)
+	}
+
+	// 默认策略不允许连接私有地址
+	if _, err := fetchRobots(context.Background(), DefaultWebPolicy(), site); err == nil {
+		t.Error("fetched robots.txt from a loopback address with the default policy")
+	}
+}
+
+func TestFetchRobotsRedirectPolicy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://denied.test/robots.txt", http.StatusFound)
+	}))
+	defer server.Close()
+	site, _ := url.Parse(server.URL)
+
+	// 重定向目标同样要符合域名策略
+	policy := &WebPolicy{AllowPrivateNetworks: true, DeniedDomains: []string{"denied.test"}}
+	if _, err := fetchRobots(context.Background(), policy, site); err == nil || !strings.Contains(err.Error(), "denied by web_policy") {
+		t.Errorf("err = %v, want redirect blocked by web_policy", err)
+	}
+}
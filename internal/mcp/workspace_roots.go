@@ -0,0 +1,117 @@
+package mcp
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// rootPathArgs 工具参数中表示文件或目录路径的字段，相对路径按工作区根目录解析
+var rootPathArgs = []string{"file_path", "path", "source", "destination"}
+
+// Roots 返回允许访问的根目录（绝对路径），第一个是启动时的项目目录
+func (e *FileEngine) Roots() []string {
+	e.rootsMu.RLock()
+	defer e.rootsMu.RUnlock()
+	roots := make([]string, 0, len(e.config.AllowedRoots))
+	for _, root := range e.config.AllowedRoots {
+		if abs, err := filepath.Abs(root); err == nil {
+			root = abs
+		}
+		roots = append(roots, root)
+	}
+	return roots
+}
+
+// AddRoot 在本次会话中允许访问另一个目录（如相邻的前端或后端仓库），返回其绝对路径。
+// 目录必须存在，已在某个根目录内时返回错误
+func (e *FileEngine) AddRoot(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("invalid path: %w", err)
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("not a directory: %s", abs)
+	}
+	if root := e.rootOf(abs); root != "" {
+		return "", fmt.Errorf("%s is already within allowed root %s", abs, root)
+	}
+
+	e.rootsMu.Lock()
+	defer e.rootsMu.Unlock()
+	// 配置中的切片可能与调用方共享底层数组，追加到新数组中
+	e.config.AllowedRoots = append(slices.Clip(e.config.AllowedRoots), abs)
+	return abs, nil
+}
+
+// rootOf 返回包含 path 的根目录，不在任何根目录内时返回空字符串
+func (e *FileEngine) rootOf(path string) string {
+	realPath, err := resolvePath(path)
+	if err != nil {
+		return ""
+	}
+	for _, root := range e.Roots() {
+		if realRoot, err := resolvePath(root); err == nil && pathWithin(realRoot, realPath) {
+			return root
+		}
+	}
+	return ""
+}
+
+// resolveRootPath 解析相对路径：在当前目录下存在时保持不变，否则依次在其他根目录中查找，
+// 都不存在时（如新建文件）仍按当前目录解析。返回实际使用的路径和它所在的根目录
+func (e *FileEngine) resolveRootPath(path string) (string, string) {
+	if _, err := os.Lstat(path); err == nil {
+		return path, e.rootOf(path)
+	}
+	for _, root := range e.Roots() {
+		candidate := filepath.Join(root, path)
+		if _, err := os.Lstat(candidate); err == nil && e.ValidatePath(candidate) == nil {
+			return candidate, root
+		}
+	}
+	return path, e.rootOf(path)
+}
+
+// AddRoot 在本次会话中允许文件工具访问另一个目录，注册表没有文件引擎时返回错误
+func (r *ToolRegistry) AddRoot(dir string) (string, error) {
+	if r.engine == nil {
+		return "", fmt.Errorf("file tools are not available")
+	}
+	return r.engine.AddRoot(dir)
+}
+
+// Roots 返回文件工具可以访问的根目录
+func (r *ToolRegistry) Roots() []string {
+	if r.engine == nil {
+		return nil
+	}
+	return r.engine.Roots()
+}
+
+// resolveRootPaths 有多个根目录时解析参数中的相对路径（在当前目录下找不到时使用其他根目录中的同名路径），
+// 返回附加在工具结果后面的说明，告诉模型每个相对路径位于哪个根目录
+func (r *ToolRegistry) resolveRootPaths(args map[string]interface{}) []string {
+	if r.engine == nil || len(r.engine.Roots()) < 2 {
+		return nil
+	}
+	var notes []string
+	for _, key := range rootPathArgs {
+		path, ok := args[key].(string)
+		if !ok || path == "" || filepath.IsAbs(path) {
+			continue
+		}
+		resolved, root := r.engine.resolveRootPath(path)
+		if root == "" {
+			continue
+		}
+		args[key] = resolved
+		notes = append(notes, fmt.Sprintf("(%s 位于根目录 %s)", path, root))
+	}
+	return notes
+}
@@ -0,0 +1,103 @@
+package mcp
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAddRoot(t *testing.T) {
+	base := t.TempDir()
+	backend := filepath.Join(base, "backend")
+	frontend := filepath.Join(base, "frontend")
+	for _, dir := range []string{filepath.Join(backend, "internal"), filepath.Join(frontend, "src")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	page := filepath.Join(frontend, "src", "App.tsx")
+	if err := os.WriteFile(page, []byte("export {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	engine := newTestEngine(t, backend)
+	if err := engine.ValidatePath(page); err == nil {
+		t.Fatal("sibling repository should not be accessible before it is added")
+	}
+	if _, err := engine.AddRoot(filepath.Join(backend, "internal")); err == nil {
+		t.Error("directory within an existing root should be rejected")
+	}
+	if _, err := engine.AddRoot(filepath.Join(base, "missing")); err == nil {
+		t.Error("missing directory should be rejected")
+	}
+
+	root, err := engine.AddRoot(frontend)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root != frontend {
+		t.Errorf("AddRoot() = %s, want %s", root, frontend)
+	}
+	if err := engine.ValidatePath(page); err != nil {
+		t.Errorf("added root should be accessible: %v", err)
+	}
+	if roots := engine.Roots(); len(roots) != 2 || roots[1] != frontend {
+		t.Errorf("Roots() = %v", roots)
+	}
+	if _, err := engine.AddRoot(frontend); err == nil {
+		t.Error("adding the same root twice should be rejected")
+	}
+}
+
+func TestResolveRootPaths(t *testing.T) {
+	base := t.TempDir()
+	backend := filepath.Join(base, "backend")
+	frontend := filepath.Join(base, "frontend")
+	for _, dir := range []string{backend, filepath.Join(frontend, "src")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(frontend, "src", "App.tsx"), []byte("export {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(backend)
+
+	config := DefaultConfig()
+	config.BackupDir = filepath.Join(t.TempDir(), "backups")
+	registry := DefaultToolRegistry(config)
+
+	// 只有一个根目录时不改写参数
+	args := map[string]interface{}{"file_path": "src/App.tsx"}
+	if notes := registry.resolveRootPaths(args); notes != nil || args["file_path"] != "src/App.tsx" {
+		t.Fatalf("single root: notes=%v args=%v", notes, args)
+	}
+
+	if _, err := registry.AddRoot(frontend); err != nil {
+		t.Fatal(err)
+	}
+	args = map[string]interface{}{"file_path": "src/App.tsx", "content": "x"}
+	notes := registry.resolveRootPaths(args)
+	if args["file_path"] != filepath.Join(frontend, "src", "App.tsx") {
+		t.Errorf("file_path = %v", args["file_path"])
+	}
+	if len(notes) != 1 || !strings.Contains(notes[0], frontend) {
+		t.Errorf("notes = %v", notes)
+	}
+
+	// 不存在的新文件仍按当前目录解析
+	args = map[string]interface{}{"file_path": "new.go"}
+	registry.resolveRootPaths(args)
+	if args["file_path"] != "new.go" {
+		t.Errorf("new file path = %v", args["file_path"])
+	}
+
+	result, err := registry.HandleCallTool(CallToolRequest{Name: "read_file", Arguments: map[string]interface{}{"path": "src/App.tsx"}})
+	if err != nil || result.IsError {
+		t.Fatalf("read_file: %v %+v", err, result)
+	}
+	if text := result.Content[len(result.Content)-1].Text; !strings.Contains(text, frontend) {
+		t.Errorf("result should note the root: %q", text)
+	}
+}
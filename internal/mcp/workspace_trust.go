@@ -0,0 +1,41 @@
+package mcp
+
+// ReadOnlyToolNames 不修改本地文件、不执行命令的工具，未信任的工作区只开放这些工具
+var ReadOnlyToolNames = map[string]bool{
+	"read_file":           true,
+	"diagnose_file":       true,
+	"list_directory":      true,
+	"search_file_content": true,
+	"glob":                true,
+	"get_file_info":       true,
+	"get_current_time":    true,
+	"file_stats":          true,
+	"advanced_search":     true,
+	"find_and_replace":    true,
+	"web_search":          true,
+	"web_crawl":           true,
+	"web_extract":         true,
+	"ask_user":            true,
+}
+
+// IndexToolNames 不修改工作区文件但会写入项目索引（.polyagent/index）的工具，
+// 语义搜索还会把代码内容发送到 embeddings 接口，因此未信任的工作区不开放
+var IndexToolNames = map[string]bool{
+	"semantic_search": true,
+	"find_symbol":     true,
+}
+
+// SetReadOnly 切换只读模式，只读时只列出和允许调用 ReadOnlyToolNames 中的工具
+func (r *ToolRegistry) SetReadOnly(readOnly bool) {
+	r.readOnly.Store(readOnly)
+}
+
+// ReadOnly 是否处于只读模式
+func (r *ToolRegistry) ReadOnly() bool {
+	return r.readOnly.Load()
+}
+
+// allowed 判断当前模式下是否允许使用该工具
+func (r *ToolRegistry) allowed(name string) bool {
+	return !r.ReadOnly() || ReadOnlyToolNames[name]
+}
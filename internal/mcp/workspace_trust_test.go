@@ -0,0 +1,47 @@
+package mcp
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestReadOnlyRegistry(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "main.go"), []byte("package main\n\nfunc Run() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	engine := newTestEngine(t, root)
+	project := NewProjectIndex(engine, root)
+	registry := NewToolRegistry()
+	registry.Register(&ReadFileTool{engine: engine})
+	registry.Register(&WriteFileTool{engine: engine})
+	registry.Register(NewFindSymbolTool(project))
+	registry.Register(NewSemanticSearchTool(engine, project))
+	registry.SetReadOnly(true)
+
+	var names []string
+	for _, tool := range registry.ListTools() {
+		names = append(names, tool.Name)
+	}
+	sort.Strings(names)
+	if got := strings.Join(names, ","); got != "read_file" {
+		t.Errorf("read-only tools = %s, want only read_file", got)
+	}
+
+	// 索引类工具会写入 .polyagent/index 并上传代码，未信任的工作区不能调用
+	for _, name := range []string{"find_symbol", "semantic_search", "write_file"} {
+		result, err := registry.HandleCallTool(CallToolRequest{Name: name, Arguments: map[string]interface{}{"name": "Run", "query": "run"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !result.IsError || !strings.Contains(result.Content[0].Text, "workspace is not trusted") {
+			t.Errorf("%s in read-only mode: %+v", name, result)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, ".polyagent")); !os.IsNotExist(err) {
+		t.Errorf("project index written in an untrusted workspace: %v", err)
+	}
+}
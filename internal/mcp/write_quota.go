@@ -0,0 +1,123 @@
+package mcp
+
+import (
+	"fmt"
+	"sync"
+)
+
+const (
+	// DefaultWriteQuotaBytes 每个会话默认允许写入的总字节数
+	DefaultWriteQuotaBytes int64 = 20 * 1024 * 1024
+	// DefaultWriteQuotaFiles 每个会话默认允许写入的文件数
+	DefaultWriteQuotaFiles = 500
+)
+
+// WriteQuota 会话级写入预算，防止失控的生成循环写满磁盘
+// 超出后所有会修改文件或执行命令的工具都会失败，直到用户提高上限
+type WriteQuota struct {
+	mu       sync.Mutex
+	maxBytes int64 // <= 0 表示不限制
+	maxFiles int   // <= 0 表示不限制
+	bytes    int64
+	files    map[string]bool
+}
+
+// NewWriteQuota 创建写入预算，0 表示使用默认值，负数表示不限制
+func NewWriteQuota(maxBytes int64, maxFiles int) *WriteQuota {
+	q := &WriteQuota{files: make(map[string]bool)}
+	q.SetLimits(maxBytes, maxFiles)
+	return q
+}
+
+// SetLimits 修改上限，规则同 NewWriteQuota
+func (q *WriteQuota) SetLimits(maxBytes int64, maxFiles int) {
+	if maxBytes == 0 {
+		maxBytes = DefaultWriteQuotaBytes
+	}
+	if maxFiles == 0 {
+		maxFiles = DefaultWriteQuotaFiles
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.maxBytes = maxBytes
+	q.maxFiles = maxFiles
+}
+
+// Usage 返回已写入的字节数和文件数及当前上限（上限 <= 0 表示不限制）
+func (q *WriteQuota) Usage() (bytes int64, files int, maxBytes int64, maxFiles int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.bytes, len(q.files), q.maxBytes, q.maxFiles
+}
+
+// check 在执行修改类工具前检查预算，incoming 为本次调用已知将写入的字节数
+// 再次写入已计数的文件不受文件数上限限制
+func (q *WriteQuota) check(path string, incoming int64) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.maxBytes > 0 && q.bytes+incoming > q.maxBytes {
+		return fmt.Errorf("write quota exceeded: this session has written %d of %d bytes allowed; stop writing and ask the user to raise the limit with /quota", q.bytes, q.maxBytes)
+	}
+	if q.maxFiles > 0 && len(q.files) >= q.maxFiles && (path == "" || !q.files[readTrackingKey(path)]) {
+		return fmt.Errorf("write quota exceeded: this session has written %d files (limit %d); stop writing and ask the user to raise the limit with /quota", len(q.files), q.maxFiles)
+	}
+	return nil
+}
+
+// add 记录一次成功的写入
+func (q *WriteQuota) add(path string, n int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.bytes += n
+	if path != "" {
+		q.files[readTrackingKey(path)] = true
+	}
+}
+
+// SetWriteQuota 设置写入预算，nil 表示不限制
+func (r *ToolRegistry) SetWriteQuota(quota *WriteQuota) {
+	r.quota = quota
+}
+
+// WriteQuota 返回当前写入预算，未启用时为 nil
+func (r *ToolRegistry) WriteQuota() *WriteQuota {
+	return r.quota
+}
+
+// checkWriteQuota 只读工具和只写入项目索引的工具不受预算限制
+func (r *ToolRegistry) checkWriteQuota(req CallToolRequest) error {
+	if r.quota == nil || ReadOnlyToolNames[req.Name] || IndexToolNames[req.Name] {
+		return nil
+	}
+	var incoming int64
+	if content, ok := req.Arguments["content"].(string); ok && (req.Name == "write_file" || req.Name == "create_file") {
+		incoming = int64(len(content))
+	}
+	return r.quota.check(writeTarget(req.Name, req.Arguments), incoming)
+}
+
+// recordWrite 将成功调用写入的字节数计入预算
+func (r *ToolRegistry) recordWrite(req CallToolRequest, written int64) {
+	if r.quota == nil || ReadOnlyToolNames[req.Name] || IndexToolNames[req.Name] {
+		return
+	}
+	if path := writeTarget(req.Name, req.Arguments); path != "" {
+		r.quota.add(path, written)
+	}
+}
+
+// writeTarget 返回写入类工具修改的文件路径，其他工具返回空
+func writeTarget(tool string, args map[string]interface{}) string {
+	var path string
+	switch tool {
+	case "write_file", "create_file":
+		path, _ = args["path"].(string)
+	case "replace", "edit_lines":
+		path, _ = args["file_path"].(string)
+	case "copy_file", "move_file":
+		path, _ = args["destination"].(string)
+	}
+	return path
+}
@@ -0,0 +1,53 @@
+package mcp
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteQuotaCountsDirectoryCopies(t *testing.T) {
+	root := t.TempDir()
+	engine := newTestEngine(t, root)
+	registry := NewToolRegistry()
+	registry.Register(&CopyFileTool{engine: engine})
+	registry.Register(&MoveFileTool{engine: engine})
+	registry.SetWriteQuota(NewWriteQuota(3000, -1))
+
+	src := filepath.Join(root, "src", "nested")
+	if err := os.MkdirAll(src, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.bin", "nested/b.bin"} {
+		if err := os.WriteFile(filepath.Join(root, "src", filepath.FromSlash(name)), make([]byte, 1000), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	call := func(tool, dst string) *CallToolResult {
+		result, err := registry.HandleCallTool(CallToolRequest{Name: tool, Arguments: map[string]interface{}{
+			"source":      filepath.Join(root, "src"),
+			"destination": filepath.Join(root, dst),
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	if result := call("copy_file", "copy1"); result.IsError {
+		t.Fatalf("first copy failed: %+v", result)
+	}
+	if bytes, _, _, _ := registry.WriteQuota().Usage(); bytes != 2000 {
+		t.Fatalf("usage after copying a 2000 byte tree = %d", bytes)
+	}
+	if result := call("move_file", "moved"); result.IsError {
+		t.Fatalf("move failed: %+v", result)
+	}
+
+	// 已写入 4000 字节，超过 3000 的预算，后续复制必须失败
+	result := call("copy_file", "copy2")
+	if !result.IsError || len(result.Content) != 1 || !strings.Contains(result.Content[0].Text, "write quota exceeded") {
+		t.Fatalf("expected quota error, got %+v", result)
+	}
+}
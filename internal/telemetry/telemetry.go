@@ -0,0 +1,177 @@
+// Package telemetry 记录匿名的使用统计，需要用户明确开启。
+//
+// 只记录计数：使用过的命令、调用过的工具和错误类别，从不记录消息、文件路径、
+// 参数或错误详情等内容。统计保存在配置目录的 telemetry.json 中，不会自动上传，
+// 用户可以随时查看或导出后自行提交。
+package telemetry
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/utils"
+)
+
+// Stats 匿名使用统计
+type Stats struct {
+	// Since 开始统计的日期
+	Since    string         `json:"since"`
+	Commands map[string]int `json:"commands"`
+	Tools    map[string]int `json:"tools"`
+	Errors   map[string]int `json:"errors"`
+}
+
+var (
+	mu      sync.Mutex
+	enabled bool
+)
+
+// SetEnabled 开启或关闭统计，关闭时不再记录，已有的统计保留到调用 Clear
+func SetEnabled(on bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	enabled = on
+}
+
+// Enabled 报告是否正在记录统计
+func Enabled() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return enabled
+}
+
+// RecordCommand 记录一次斜杠命令的使用，name 为命令类型（如 "CLEAR"），不含参数
+func RecordCommand(name string) {
+	record(func(s *Stats) { s.Commands[name]++ })
+}
+
+// RecordTool 记录一次工具调用，name 为工具名称，不含参数
+func RecordTool(name string) {
+	record(func(s *Stats) { s.Tools[name]++ })
+}
+
+// RecordError 记录一次错误，class 为错误类别（如 "api.timeout"），不含错误详情
+func RecordError(class string) {
+	record(func(s *Stats) { s.Errors[class]++ })
+}
+
+// record 未开启时不做任何事；统计只是辅助信息，写入失败时直接忽略
+func record(update func(*Stats)) {
+	mu.Lock()
+	defer mu.Unlock()
+	if !enabled {
+		return
+	}
+	stats, err := load()
+	if err != nil {
+		return
+	}
+	update(stats)
+	save(stats)
+}
+
+// Load 读取已记录的统计，没有记录时返回空的统计
+func Load() (*Stats, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	return load()
+}
+
+// Export 将统计以便于阅读的 JSON 格式写入指定文件
+func Export(path string) error {
+	stats, err := Load()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化使用统计失败: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("导出使用统计失败: %w", err)
+	}
+	return nil
+}
+
+// Clear 删除已记录的统计
+func Clear() error {
+	mu.Lock()
+	defer mu.Unlock()
+	statsPath, err := Path()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(statsPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("删除使用统计失败: %w", err)
+	}
+	return nil
+}
+
+// Path 返回统计文件的路径
+func Path() (string, error) {
+	configDir, err := utils.GetConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("获取配置目录失败: %w", err)
+	}
+	return filepath.Join(configDir, "telemetry.json"), nil
+}
+
+func load() (*Stats, error) {
+	statsPath, err := Path()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &Stats{}
+	data, err := os.ReadFile(statsPath)
+	switch {
+	case os.IsNotExist(err):
+		stats.Since = time.Now().Format("2006-01-02")
+	case err != nil:
+		return nil, fmt.Errorf("读取使用统计失败: %w", err)
+	default:
+		if err := json.Unmarshal(data, stats); err != nil {
+			return nil, fmt.Errorf("解析使用统计失败: %w", err)
+		}
+	}
+
+	if stats.Commands == nil {
+		stats.Commands = map[string]int{}
+	}
+	if stats.Tools == nil {
+		stats.Tools = map[string]int{}
+	}
+	if stats.Errors == nil {
+		stats.Errors = map[string]int{}
+	}
+	return stats, nil
+}
+
+func save(stats *Stats) error {
+	statsPath, err := Path()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化使用统计失败: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(statsPath), 0755); err != nil {
+		return fmt.Errorf("创建配置目录失败: %w", err)
+	}
+
+	tempFile := statsPath + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0600); err != nil {
+		return fmt.Errorf("写入使用统计失败: %w", err)
+	}
+	if err := os.Rename(tempFile, statsPath); err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("写入使用统计失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRecordRequiresOptIn(t *testing.T) {
+	t.Setenv("POLYAGENT_CONFIG_HOME", t.TempDir())
+	SetEnabled(false)
+
+	RecordCommand("CLEAR")
+	RecordTool("read_file")
+	if path, _ := Path(); fileExists(path) {
+		t.Fatal("stats written while telemetry is disabled")
+	}
+
+	SetEnabled(true)
+	defer SetEnabled(false)
+	RecordCommand("CLEAR")
+	RecordCommand("CLEAR")
+	RecordTool("read_file")
+	RecordError("api.timeout")
+
+	stats, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if stats.Commands["CLEAR"] != 2 || stats.Tools["read_file"] != 1 || stats.Errors["api.timeout"] != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+	if stats.Since == "" {
+		t.Error("expected start date to be recorded")
+	}
+
+	// 关闭后不再记录，已有统计保留
+	SetEnabled(false)
+	RecordTool("read_file")
+	if stats, _ := Load(); stats.Tools["read_file"] != 1 {
+		t.Errorf("recorded while disabled: %+v", stats)
+	}
+}
+
+func TestExportAndClear(t *testing.T) {
+	t.Setenv("POLYAGENT_CONFIG_HOME", t.TempDir())
+	SetEnabled(true)
+	defer SetEnabled(false)
+	RecordTool("write_file")
+
+	out := filepath.Join(t.TempDir(), "export.json")
+	if err := Export(out); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("read export: %v", err)
+	}
+	var exported Stats
+	if err := json.Unmarshal(data, &exported); err != nil || exported.Tools["write_file"] != 1 {
+		t.Errorf("unexpected export %s: %v", data, err)
+	}
+
+	if err := Clear(); err != nil {
+		t.Fatalf("Clear failed: %v", err)
+	}
+	if stats, _ := Load(); len(stats.Tools) != 0 {
+		t.Errorf("stats not cleared: %+v", stats)
+	}
+	// 重复删除不应报错
+	if err := Clear(); err != nil {
+		t.Errorf("Clear on missing file failed: %v", err)
+	}
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,188 @@
+// Package tokens 提供不依赖词表的 token 数估算，用于历史裁剪、限流预算和状态栏显示。
+//
+// 估算先按 tiktoken（cl100k）的预分词规则把文本切成单词、数字、标点和空白，
+// 再按各类片段在 BPE 词表中的典型长度计数；中日韩文字按字符计数，
+// GLM 的词表对中文更高效，每个汉字对应的 token 数比 OpenAI 模型少。
+// 估算值用于预算和提示，不保证与服务商返回的用量完全一致。
+package tokens
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Encoding 估算使用的分词器类型
+type Encoding int
+
+const (
+	// EncodingGLM 智谱 GLM 系列模型
+	EncodingGLM Encoding = iota
+	// EncodingOpenAI OpenAI 的 cl100k / o200k 系列模型
+	EncodingOpenAI
+)
+
+// cjkPerMille 每 1000 个中日韩字符对应的 token 数
+var cjkPerMille = map[Encoding]int{
+	EncodingGLM:    700,
+	EncodingOpenAI: 1100,
+}
+
+// EncodingForModel 根据模型名称选择估算方式，未知模型按 GLM 处理
+func EncodingForModel(model string) Encoding {
+	model = strings.ToLower(model)
+	for _, prefix := range []string{"gpt-", "o1", "o3", "o4", "text-embedding-", "chatgpt"} {
+		if strings.HasPrefix(model, prefix) {
+			return EncodingOpenAI
+		}
+	}
+	return EncodingGLM
+}
+
+// Count 按 GLM 的分词器估算文本的 token 数
+func Count(text string) int {
+	return CountFor(EncodingGLM, text)
+}
+
+// CountFor 按指定分词器估算文本的 token 数
+func CountFor(enc Encoding, text string) int {
+	var (
+		total int
+		// cjk 当前连续的中日韩字符数，连续段结束时按比例折算
+		cjk int
+	)
+	flushCJK := func() {
+		if cjk > 0 {
+			total += (cjk*cjkPerMille[enc] + 999) / 1000
+			cjk = 0
+		}
+	}
+
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if isCJK(r) {
+			cjk++
+			i += size
+			continue
+		}
+		flushCJK()
+
+		switch {
+		case r == ' ' && i+1 < len(text) && !isSpaceByte(text[i+1]):
+			// 单个前导空格与后面的单词或标点合并
+			i++
+		case r == '\n' || r == '\r':
+			// 连续换行通常是一个 token
+			for i < len(text) && (text[i] == '\n' || text[i] == '\r') {
+				i++
+			}
+			total++
+		case unicode.IsSpace(r):
+			n := 0
+			for i < len(text) && (text[i] == ' ' || text[i] == '\t') {
+				i++
+				n++
+			}
+			if n == 0 {
+				i += size
+				n = 1
+			}
+			// 缩进等连续空白约 8 个一个 token（最后一个空格会与后面的单词合并）
+			total += (n + 7) / 8
+		case r < utf8.RuneSelf && isASCIILetter(byte(r)):
+			end := i
+			for end < len(text) && isASCIILetter(text[end]) {
+				end++
+			}
+			total += countWord(text[i:end])
+			i = end
+		case r >= '0' && r <= '9':
+			end := i
+			for end < len(text) && text[end] >= '0' && text[end] <= '9' {
+				end++
+			}
+			// cl100k 把数字按最多 3 位切分
+			total += (end - i + 2) / 3
+			i = end
+		case unicode.IsLetter(r) || unicode.IsMark(r):
+			// 其他文字（带重音的拉丁字母、西里尔字母等）约 2 个字符一个 token
+			n := 0
+			for i < len(text) {
+				r, size := utf8.DecodeRuneInString(text[i:])
+				if isCJK(r) || !(unicode.IsLetter(r) || unicode.IsMark(r)) {
+					break
+				}
+				n++
+				i += size
+			}
+			total += (n + 1) / 2
+		case r < utf8.RuneSelf:
+			// ASCII 标点：常见组合（如 "()"、"//"、":="）合并为一个 token
+			n := 0
+			for i < len(text) && text[i] < utf8.RuneSelf && isASCIIPunct(text[i]) {
+				i++
+				n++
+			}
+			if n == 0 {
+				i += size
+				n = 1
+			}
+			// 标点之后的换行合并到同一个 token（如 "{\n"）
+			for i < len(text) && (text[i] == '\n' || text[i] == '\r') {
+				i++
+			}
+			total += (n + 1) / 2
+		default:
+			// emoji 和其他符号按 UTF-8 字节拆分，通常 2 个 token 左右
+			total += 2
+			i += size
+		}
+	}
+	flushCJK()
+	return total
+}
+
+// countWord 估算一个 ASCII 单词的 token 数：按驼峰拆分，常见长度的部分是一个 token，长的部分按每 5 个字母计
+func countWord(word string) int {
+	total := 0
+	start := 0
+	for i := 1; i <= len(word); i++ {
+		if i < len(word) && !(isUpper(word[i]) && !isUpper(word[i-1])) {
+			continue
+		}
+		n := i - start
+		if n <= 8 {
+			total++
+		} else {
+			total += (n + 4) / 5
+		}
+		start = i
+	}
+	return total
+}
+
+// isCJK 报告字符是否为中日韩文字或全角标点
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) ||
+		unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) ||
+		unicode.Is(unicode.Hangul, r) ||
+		(r >= 0x3000 && r <= 0x303F) || // 中日韩标点
+		(r >= 0xFF00 && r <= 0xFFEF) // 全角字符
+}
+
+func isSpaceByte(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isUpper(c byte) bool {
+	return c >= 'A' && c <= 'Z'
+}
+
+func isASCIIPunct(c byte) bool {
+	return c > ' ' && c < 0x7f && !isASCIILetter(c) && !(c >= '0' && c <= '9')
+}
@@ -0,0 +1,66 @@
+package tokens
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCountKnownTexts(t *testing.T) {
+	// want 为 cl100k 分词器的实际 token 数，估算允许 max(1, 20%) 的误差
+	cases := []struct {
+		enc  Encoding
+		text string
+		want int
+	}{
+		{EncodingOpenAI, "hello world", 2},
+		{EncodingOpenAI, "Hello, world!", 4},
+		{EncodingOpenAI, "The quick brown fox jumps over the lazy dog.", 10},
+		{EncodingOpenAI, "1234567", 3},
+		{EncodingOpenAI, "你好", 2},
+	}
+	for _, c := range cases {
+		got := CountFor(c.enc, c.text)
+		tolerance := max(1, c.want/5)
+		if got < c.want-tolerance || got > c.want+tolerance {
+			t.Errorf("CountFor(%d, %q) = %d, want %d±%d", c.enc, c.text, got, c.want, tolerance)
+		}
+	}
+}
+
+func TestCountProperties(t *testing.T) {
+	if got := Count(""); got != 0 {
+		t.Errorf("empty text = %d tokens", got)
+	}
+
+	chinese := strings.Repeat("这是一个用于估算的中文句子。", 20)
+	if glm, openai := CountFor(EncodingGLM, chinese), CountFor(EncodingOpenAI, chinese); glm >= openai {
+		t.Errorf("GLM should need fewer tokens for Chinese: glm=%d openai=%d", glm, openai)
+	}
+
+	// 估算随文本线性增长，不会因为长文本失真
+	english := "Token estimation should scale linearly with the input length."
+	one, hundred := Count(english), Count(strings.Repeat(english+" ", 99)+english)
+	if hundred < one*95 || hundred > one*105 {
+		t.Errorf("estimate does not scale linearly: 1x=%d 100x=%d", one, hundred)
+	}
+
+	// 比按字节除以 4 更接近中文的实际长度
+	if got := Count(chinese); got > len(chinese)/4 {
+		t.Errorf("Chinese estimate %d should be below the byte heuristic %d", got, len(chinese)/4)
+	}
+}
+
+func TestEncodingForModel(t *testing.T) {
+	cases := map[string]Encoding{
+		"glm-4.6":     EncodingGLM,
+		"GLM-4.5-Air": EncodingGLM,
+		"gpt-4o":      EncodingOpenAI,
+		"o3-mini":     EncodingOpenAI,
+		"":            EncodingGLM,
+	}
+	for model, want := range cases {
+		if got := EncodingForModel(model); got != want {
+			t.Errorf("EncodingForModel(%q) = %d, want %d", model, got, want)
+		}
+	}
+}
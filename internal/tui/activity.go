@@ -0,0 +1,174 @@
+package tui
+
+import (
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/api"
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
+	"github.com/Zacy-Sokach/PolyAgent/internal/mcp"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// statusTickInterval 思考期间刷新状态栏（等待动画、已用时间、排队状态）的间隔
+const statusTickInterval = 100 * time.Millisecond
+
+// spinnerFrames 思考期间状态栏的等待动画
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// statusTickMsg 定期刷新思考期间的状态栏
+type statusTickMsg struct{}
+
+// statusTickCmd 定时刷新状态栏
+func statusTickCmd() tea.Cmd {
+	return tea.Tick(statusTickInterval, func(time.Time) tea.Msg {
+		return statusTickMsg{}
+	})
+}
+
+// toolOutcome 一次已结束的工具调用
+type toolOutcome struct {
+	Failed   bool
+	Duration time.Duration
+	// Injection 结果中命中的提示注入规则
+	Injection []string
+}
+
+// toolActivity 通过事件总线记录正在执行的工具和已结束调用的结果，工具在后台执行，读取时需要加锁
+type toolActivity struct {
+	mu          sync.Mutex
+	running     string
+	runningCall string
+	outcomes    map[string]toolOutcome // 按调用 ID 记录，由工具调用面板取走
+}
+
+var (
+	currentToolActivity = &toolActivity{}
+	toolActivityOnce    sync.Once
+)
+
+// subscribeToolActivity 订阅工具调用事件，只注册一次
+func subscribeToolActivity() {
+	toolActivityOnce.Do(func() {
+		bus := GetGlobalEventBus()
+		bus.Subscribe(EventTypeToolCalled, currentToolActivity)
+		bus.Subscribe(EventTypeToolCompleted, currentToolActivity)
+		bus.Subscribe(EventTypeToolFailed, currentToolActivity)
+	})
+}
+
+func (a *toolActivity) CanHandle(event Event) bool {
+	switch event.(type) {
+	case *ToolCalledEvent, *ToolCompletedEvent, *ToolFailedEvent:
+		return true
+	}
+	return false
+}
+
+func (a *toolActivity) Handle(event Event) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	switch e := event.(type) {
+	case *ToolCalledEvent:
+		a.running, a.runningCall = e.ToolName, e.CallID
+		return nil
+	case *ToolCompletedEvent:
+		result, _ := e.Result.(*mcp.CallToolResult)
+		outcome := toolOutcome{Duration: e.Duration}
+		if result != nil {
+			outcome.Failed, outcome.Injection = result.IsError, result.Injection
+		}
+		a.finish(e.CallID, outcome)
+	case *ToolFailedEvent:
+		a.finish(e.CallID, toolOutcome{Failed: true, Duration: e.Duration})
+	}
+	return nil
+}
+
+// finish 记录结束的调用，调用方持有锁
+func (a *toolActivity) finish(callID string, outcome toolOutcome) {
+	a.running, a.runningCall = "", ""
+	if callID == "" {
+		return
+	}
+	if a.outcomes == nil {
+		a.outcomes = make(map[string]toolOutcome)
+	}
+	a.outcomes[callID] = outcome
+}
+
+func (a *toolActivity) Priority() int {
+	return 0
+}
+
+// Running 返回正在执行的工具名，没有时返回空字符串
+func (a *toolActivity) Running() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.running
+}
+
+// Snapshot 返回正在执行的调用 ID 和已结束调用的结果
+func (a *toolActivity) Snapshot() (string, map[string]toolOutcome) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.runningCall, maps.Clone(a.outcomes)
+}
+
+// Forget 丢弃已显示的调用结果
+func (a *toolActivity) Forget(callIDs []string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, id := range callIDs {
+		delete(a.outcomes, id)
+	}
+}
+
+// activityStatus 返回思考期间状态栏显示的等待动画、当前阶段和本轮已用时间
+func (m Model) activityStatus() string {
+	status := spinnerFrames[m.spinnerFrame%len(spinnerFrames)] + " " + m.activityPhase()
+	if !m.turnStartedAt.IsZero() {
+		status += " · " + formatElapsed(time.Since(m.turnStartedAt))
+	}
+	return status + " "
+}
+
+// activityPhase 描述当前在等待什么
+func (m Model) activityPhase() string {
+	if len(m.pendingQuestions) > 0 {
+		return i18n.T("ui.phase_question")
+	}
+	if progress := m.toolProgressStatus(); progress != "" {
+		return progress
+	}
+	if m.toolsRunning {
+		if tool := currentToolActivity.Running(); tool != "" {
+			return i18n.T("ui.phase_tool", tool)
+		}
+		return i18n.T("ui.phase_tools")
+	}
+	if queued := api.QueuedRequests(); queued > 0 {
+		return i18n.T("ui.rate_limited", queued)
+	}
+	if m.stallRetries > 0 {
+		return i18n.T("ui.phase_retry", m.stallRetries, maxStreamStallRetries)
+	}
+	if m.currentResp != "" {
+		return i18n.T("ui.phase_generating")
+	}
+	if m.currentThink != "" {
+		return i18n.T("ui.phase_reasoning")
+	}
+	return i18n.T("ui.phase_waiting")
+}
+
+// formatElapsed 格式化已用时间，如 8s、2m05s
+func formatElapsed(d time.Duration) string {
+	seconds := int(d.Seconds())
+	if seconds < 60 {
+		return fmt.Sprintf("%ds", seconds)
+	}
+	return fmt.Sprintf("%dm%02ds", seconds/60, seconds%60)
+}
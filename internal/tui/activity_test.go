@@ -0,0 +1,41 @@
+package tui
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestActivityStatus(t *testing.T) {
+	m := goldenModel(t)
+	m.thinking = true
+	m.turnStartedAt = time.Now().Add(-75 * time.Second)
+
+	status := m.activityStatus()
+	if !strings.HasPrefix(status, spinnerFrames[0]) || !strings.Contains(status, "等待模型响应") || !strings.Contains(status, "1m15s") {
+		t.Errorf("status = %q", status)
+	}
+
+	m = drive(t, m, statusTickMsg{})
+	if !strings.HasPrefix(m.activityStatus(), spinnerFrames[1]) {
+		t.Errorf("spinner did not advance: %q", m.activityStatus())
+	}
+
+	m.stallRetries = 1
+	if status := m.activityStatus(); !strings.Contains(status, "重试 1/2") {
+		t.Errorf("retry status = %q", status)
+	}
+
+	// 正在执行的工具通过事件总线报告
+	m.toolsRunning = true
+	bus := GetGlobalEventBus()
+	bus.Publish(NewToolCalledEvent("read_file", nil))
+	if status := m.activityStatus(); !strings.Contains(status, "正在运行 read_file") {
+		t.Errorf("tool status = %q", status)
+	}
+	bus.Publish(NewToolFailedEvent("read_file", errors.New("boom"), time.Second))
+	if status := m.activityStatus(); !strings.Contains(status, "正在运行工具") {
+		t.Errorf("status after tool finished = %q", status)
+	}
+}
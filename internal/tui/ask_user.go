@@ -0,0 +1,144 @@
+package tui
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/api"
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
+	"github.com/Zacy-Sokach/PolyAgent/internal/mcp"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// maxQuestionOptions 问题最多显示的候选答案数，对应数字键 1-9
+const maxQuestionOptions = 9
+
+// userQuestion 模型通过 ask_user 向用户提出的问题
+type userQuestion struct {
+	CallID   string
+	Question string
+	Options  []string
+}
+
+// pendingUserQuestions 返回挂起的工具调用中向用户提出的问题，参数无效的调用交给工具本身报错
+func (m *Model) pendingUserQuestions() []*userQuestion {
+	var questions []*userQuestion
+	for _, call := range m.pendingToolCalls {
+		if call.Function.Name != mcp.AskUserToolName {
+			continue
+		}
+		var args struct {
+			Question string        `json:"question"`
+			Options  []interface{} `json:"options"`
+		}
+		if err := json.Unmarshal(call.Function.Arguments, &args); err != nil || strings.TrimSpace(args.Question) == "" {
+			continue
+		}
+		q := &userQuestion{CallID: call.ID, Question: strings.TrimSpace(args.Question)}
+		for _, option := range args.Options {
+			if text := strings.TrimSpace(fmt.Sprint(option)); text != "" && len(q.Options) < maxQuestionOptions {
+				q.Options = append(q.Options, text)
+			}
+		}
+		questions = append(questions, q)
+	}
+	return questions
+}
+
+// askQuestions 暂停执行工具，依次显示问题并等待用户回答
+func (m *Model) askQuestions(questions []*userQuestion) tea.Cmd {
+	m.pendingQuestions = questions
+	m.questionAnswers = make(map[string]string, len(questions))
+	return m.showQuestion()
+}
+
+// showQuestion 显示队列中的第一个问题
+func (m *Model) showQuestion() tea.Cmd {
+	q := m.pendingQuestions[0]
+	m.messages = append(m.messages, Message{Role: "system", Content: q.text(), Question: q})
+	return m.updateViewport()
+}
+
+// handleQuestionKey 处理回答问题期间的按键：Enter 提交输入框中的回答，有候选答案且输入框为空时按数字键直接选择，
+// Esc 跳过问题。返回 false 表示按键交给输入框处理
+func (m *Model) handleQuestionKey(msg tea.KeyMsg) (tea.Cmd, bool) {
+	q := m.pendingQuestions[0]
+	switch msg.Type {
+	case tea.KeyEsc:
+		return m.answerQuestion("", false), true
+	case tea.KeyEnter:
+		answer := strings.TrimSpace(m.textarea.Value())
+		if answer == "" {
+			return nil, true
+		}
+		m.textarea.Reset()
+		return m.answerQuestion(answer, true), true
+	case tea.KeyRunes:
+		if len(msg.Runes) == 1 && m.textarea.Value() == "" {
+			if n := int(msg.Runes[0] - '0'); n >= 1 && n <= len(q.Options) {
+				return m.answerQuestion(q.Options[n-1], true), true
+			}
+		}
+	}
+	return nil, false
+}
+
+// answerQuestion 记录回答并显示下一个问题，全部回答后继续执行挂起的工具调用
+func (m *Model) answerQuestion(answer string, answered bool) tea.Cmd {
+	q := m.pendingQuestions[0]
+	m.pendingQuestions = m.pendingQuestions[1:]
+	if answered {
+		m.questionAnswers[q.CallID] = i18n.T("question.answer_result", answer)
+		m.messages = append(m.messages, Message{Role: "system", Content: i18n.T("question.answered", answer)})
+	} else {
+		m.questionAnswers[q.CallID] = i18n.T("question.skipped_result")
+		m.messages = append(m.messages, Message{Role: "system", Content: i18n.T("question.skipped")})
+	}
+	if len(m.pendingQuestions) > 0 {
+		return m.showQuestion()
+	}
+	m.pendingQuestions = nil
+	// 同一批调用中需要确认的命令等仍要先经过审批
+	if approvals := m.pendingApprovals(); len(approvals) > 0 {
+		return tea.Batch(m.updateViewport(), m.requestApproval(approvals))
+	}
+	return tea.Batch(m.updateViewport(), m.executePendingTools())
+}
+
+// answeredResult 已回答的问题直接作为工具结果，不经过工具注册表
+func answeredResult(call api.ToolCall, answers map[string]string) (api.Message, bool) {
+	answer, ok := answers[call.ID]
+	if !ok {
+		return api.Message{}, false
+	}
+	return api.ToolResultMessage(call.ID, answer), true
+}
+
+// text 问题的纯文本形式，用于保存历史记录
+func (q *userQuestion) text() string {
+	var sb strings.Builder
+	sb.WriteString("❓ " + q.Question)
+	for i, option := range q.Options {
+		fmt.Fprintf(&sb, "\n  %d. %s", i+1, option)
+	}
+	return sb.String()
+}
+
+// render 以醒目的竖条样式渲染问题和候选答案
+func (q *userQuestion) render(width int) string {
+	lines := []string{lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("14")).Render("❓ " + q.Question)}
+	for i, option := range q.Options {
+		lines = append(lines, fmt.Sprintf("%s %s", lipgloss.NewStyle().Foreground(lipgloss.Color("14")).Render(fmt.Sprintf("%d.", i+1)), option))
+	}
+	style := lipgloss.NewStyle().
+		Border(lipgloss.ThickBorder(), false, false, false, true).
+		BorderForeground(lipgloss.Color("14")).
+		PaddingLeft(1)
+	if width > 0 {
+		// 竖条和内边距占两列
+		style = style.Width(max(width-2, 20))
+	}
+	return style.Render(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,64 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/api"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestAskUser(t *testing.T) {
+	m := goldenModel(t)
+	m.messages = append(m.messages, Message{Role: "user", Content: "加一个缓存"})
+	m.thinking = true
+	calls := []api.ToolCall{
+		toolCall("q1", "ask_user", `{"question":"缓存放在哪里？","options":["内存","Redis"]}`),
+		toolCall("q2", "ask_user", `{"question":"过期时间是多少？"}`),
+	}
+	m = drive(t, m, ToolCallMsg{ToolCalls: calls}, CheckStreamMsg{})
+	if len(m.pendingQuestions) != 2 || m.toolsRunning {
+		t.Fatalf("tools should wait for answers: %d questions, running=%v", len(m.pendingQuestions), m.toolsRunning)
+	}
+	if view := m.viewport.View(); !strings.Contains(view, "❓ 缓存放在哪里？") || !strings.Contains(view, "2. Redis") {
+		t.Errorf("question not shown:\n%s", view)
+	}
+	if hint := m.helpView(); !strings.Contains(hint, "按 1-2 选择答案") {
+		t.Errorf("help = %q", hint)
+	}
+
+	// 数字键选择候选答案，随后显示下一个问题
+	answers := m.questionAnswers
+	m = drive(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("2")})
+	if len(m.pendingQuestions) != 1 || !strings.Contains(m.viewport.View(), "❓ 过期时间是多少？") {
+		t.Fatalf("second question not shown:\n%s", m.viewport.View())
+	}
+
+	// 没有候选答案时输入回答后按 Enter 提交
+	m = drive(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("10 分钟")}, tea.KeyMsg{Type: tea.KeyEnter})
+	if len(m.pendingQuestions) != 0 || !m.toolsRunning || m.textarea.Value() != "" {
+		t.Fatalf("tools should run after the last answer: %d questions, running=%v", len(m.pendingQuestions), m.toolsRunning)
+	}
+	results, err := m.runToolCalls(calls, nil, answers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := decodeToolResult(results[0].Content); got != "用户的回答：Redis" {
+		t.Errorf("first result = %q", got)
+	}
+	if got := decodeToolResult(results[1].Content); got != "用户的回答：10 分钟" {
+		t.Errorf("second result = %q", got)
+	}
+}
+
+func TestAskUserSkip(t *testing.T) {
+	m := goldenModel(t)
+	m.messages = append(m.messages, Message{Role: "user", Content: "重构一下"})
+	m.thinking = true
+	m = drive(t, m, ToolCallMsg{ToolCalls: []api.ToolCall{toolCall("q1", "ask_user", `{"question":"重构哪个模块？"}`)}}, CheckStreamMsg{})
+	answers := m.questionAnswers
+	m = drive(t, m, tea.KeyMsg{Type: tea.KeyEsc})
+	if !m.thinking || len(m.pendingQuestions) != 0 || !strings.Contains(answers["q1"], "跳过") {
+		t.Errorf("Esc should skip the question without cancelling the turn: thinking=%v answers=%v", m.thinking, answers)
+	}
+}
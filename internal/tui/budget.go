@@ -0,0 +1,190 @@
+package tui
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/api"
+	"github.com/Zacy-Sokach/PolyAgent/internal/config"
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
+	"github.com/Zacy-Sokach/PolyAgent/internal/tokens"
+	"github.com/Zacy-Sokach/PolyAgent/internal/utils"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// defaultBudgetWarnPercent 默认在达到上限的 80% 时开始提醒
+const defaultBudgetWarnPercent = 80
+
+// budgetLevel 当前估算费用相对于上限的状态
+type budgetLevel int
+
+const (
+	budgetOK budgetLevel = iota
+	budgetWarn
+	budgetExceeded
+)
+
+// costTracker 累计本次会话和当天的估算费用。
+// 由 API 客户端的 Hook 在请求所在的 goroutine 中更新，Model 的副本共享同一个实例
+type costTracker struct {
+	mu      sync.Mutex
+	session float64
+	day     string
+	daily   float64
+}
+
+func newCostTracker() *costTracker {
+	return &costTracker{}
+}
+
+// totals 返回本次会话和当天的累计费用，跨过零点后重新读取当天的记录
+func (t *costTracker) totals() (float64, float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	if day := now.Format("2006-01-02"); day != t.day {
+		t.day = day
+		t.daily, _ = utils.DailySpend(now)
+	}
+	return t.session, t.daily
+}
+
+// add 记入一次请求的费用，当天的累计值同时写入配置目录，多个进程共享
+func (t *costTracker) add(cost float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	t.session += cost
+	if daily, err := utils.AddDailySpend(now, cost); err == nil {
+		t.day, t.daily = now.Format("2006-01-02"), daily
+	} else {
+		// 记录写入失败时至少在本进程内继续累计
+		t.daily += cost
+	}
+}
+
+// hook 返回按 budget 中的价格记账的 Hook，服务商返回用量时以其为准，否则按消息估算。
+// 每个客户端使用单独的 Hook，请求和完成回调在同一个 goroutine 中依次调用
+func (t *costTracker) hook(budget config.BudgetConfig) api.Hook {
+	var promptTokens int
+	return api.HookFuncs{
+		Request: func(req *api.ChatRequest) {
+			promptTokens = api.EstimateTokens(req.Messages)
+			if len(req.Tools) > 0 {
+				// 工具定义同样计入输入
+				if data, err := json.Marshal(req.Tools); err == nil {
+					promptTokens += tokens.Count(string(data))
+				}
+			}
+		},
+		Complete: func(resp *api.ChatResponse) {
+			input, output := promptTokens, 0
+			if resp.Usage != nil && resp.Usage.TotalTokens > 0 {
+				input, output = resp.Usage.PromptTokens, resp.Usage.CompletionTokens
+			} else {
+				for _, choice := range resp.Choices {
+					if choice.Message != nil {
+						output += api.EstimateMessageTokens(*choice.Message)
+					}
+				}
+			}
+			t.add(float64(input)*budget.InputPrice/1e6 + float64(output)*budget.OutputPrice/1e6)
+		},
+	}
+}
+
+// budgetConfig 返回费用设置，未配置价格时返回 false
+func (m *Model) budgetConfig() (config.BudgetConfig, bool) {
+	if m.config == nil || m.budget == nil {
+		return config.BudgetConfig{}, false
+	}
+	budget := m.config.Budget
+	return budget, budget.InputPrice > 0 || budget.OutputPrice > 0
+}
+
+// withBudget 为客户端加上记账的 Hook，未配置价格时原样返回
+func (m *Model) withBudget(client *api.Client) *api.Client {
+	budget, ok := m.budgetConfig()
+	if !ok {
+		return client
+	}
+	return client.WithHooks(m.budget.hook(budget))
+}
+
+// budgetState 返回会话和当日上限中占比最高的一项：状态、已用费用、上限和对应的 i18n 键
+func (m *Model) budgetState() (budgetLevel, float64, float64, string) {
+	budget, ok := m.budgetConfig()
+	if !ok {
+		return budgetOK, 0, 0, ""
+	}
+	warnPercent := budget.WarnPercent
+	if warnPercent == 0 {
+		warnPercent = defaultBudgetWarnPercent
+	}
+
+	session, daily := m.budget.totals()
+	level, spent, limit, key := budgetOK, 0.0, 0.0, ""
+	ratio := -1.0
+	for _, item := range []struct {
+		spent, limit float64
+		key          string
+	}{
+		{session, budget.SessionLimit, "budget.session"},
+		{daily, budget.DailyLimit, "budget.daily"},
+	} {
+		if item.limit <= 0 || item.spent/item.limit <= ratio {
+			continue
+		}
+		ratio = item.spent / item.limit
+		spent, limit, key = item.spent, item.limit, item.key
+		switch {
+		case item.spent >= item.limit:
+			level = budgetExceeded
+		case item.spent*100 >= item.limit*float64(warnPercent):
+			level = budgetWarn
+		default:
+			level = budgetOK
+		}
+	}
+	return level, spent, limit, key
+}
+
+// budgetStatus 状态栏中的费用提醒，未接近上限时为空
+func (m Model) budgetStatus() string {
+	level, spent, limit, key := m.budgetState()
+	if level == budgetOK {
+		return ""
+	}
+	status := i18n.T(key, spent, limit)
+	if level == budgetExceeded {
+		status = "⚠️ " + status
+	}
+	return status
+}
+
+// confirmOverBudget 超出费用上限时先请求确认，返回 false 表示可以直接发送
+func (m *Model) confirmOverBudget() (tea.Cmd, bool) {
+	level, spent, limit, key := m.budgetState()
+	if level != budgetExceeded {
+		return nil, false
+	}
+	m.awaitingBudgetConfirm = true
+	m.messages = append(m.messages, Message{Role: "system", Content: i18n.T("budget.confirm", i18n.T(key, spent, limit))})
+	return m.updateViewport(), true
+}
+
+// handleBudgetConfirmKey 处理超出上限确认期间的按键：y 发送这一条消息，n 或 Esc 取消并保留输入
+func (m *Model) handleBudgetConfirmKey(msg tea.KeyMsg) tea.Cmd {
+	switch strings.ToLower(msg.String()) {
+	case "y":
+		m.awaitingBudgetConfirm = false
+		return m.sendInput(m.textarea.Value())
+	case "n", "esc":
+		m.awaitingBudgetConfirm = false
+		m.messages = append(m.messages, Message{Role: "system", Content: i18n.T("budget.cancelled")})
+		return m.updateViewport()
+	}
+	return nil
+}
@@ -27,6 +27,28 @@ const (
 	CommandTypeCoTDisable
 	CommandTypeCoTToggle
 	CommandTypeCoTHistory
+	CommandTypeRename
+	CommandTypeHistory
+	CommandTypeTrust
+	CommandTypeAudit
+	CommandTypeQuota
+	CommandTypeTrash
+	CommandTypeReloadConfig
+	CommandTypeOpen
+	CommandTypeShow
+	CommandTypeRetry
+	CommandTypeNew
+	CommandTypePin
+	CommandTypeTelemetry
+	CommandTypeRecover
+	CommandTypeVerbose
+	CommandTypeDebug
+	CommandTypeAddRoot
+	CommandTypeScope
+	CommandTypeDiff
+	CommandTypeRevert
+	CommandTypeStats
+	CommandTypePlan
 )
 
 // Command 解析后的命令
@@ -57,6 +79,28 @@ type CommandParser struct {
 	cotDisablePatterns   []*regexp.Regexp
 	cotTogglePatterns    []*regexp.Regexp
 	cotHistoryPatterns   []*regexp.Regexp
+	renamePatterns       []*regexp.Regexp
+	historyPatterns      []*regexp.Regexp
+	trustPatterns        []*regexp.Regexp
+	auditPatterns        []*regexp.Regexp
+	quotaPatterns        []*regexp.Regexp
+	trashPatterns        []*regexp.Regexp
+	reloadConfigPatterns []*regexp.Regexp
+	openPatterns         []*regexp.Regexp
+	showPatterns         []*regexp.Regexp
+	retryPatterns        []*regexp.Regexp
+	newPatterns          []*regexp.Regexp
+	pinPatterns          []*regexp.Regexp
+	telemetryPatterns    []*regexp.Regexp
+	recoverPatterns      []*regexp.Regexp
+	verbosePatterns      []*regexp.Regexp
+	debugPatterns        []*regexp.Regexp
+	addRootPatterns      []*regexp.Regexp
+	scopePatterns        []*regexp.Regexp
+	diffPatterns         []*regexp.Regexp
+	revertPatterns       []*regexp.Regexp
+	statsPatterns        []*regexp.Regexp
+	planPatterns         []*regexp.Regexp
 }
 
 // NewCommandParser 创建新的命令解析器
@@ -178,6 +222,114 @@ func (p *CommandParser) initializePatterns() {
 		regexp.MustCompile(`(?i)^思考历史$`),
 		regexp.MustCompile(`^/cot-history$`),
 	}
+
+	// 重命名会话命令模式，不带参数时提示用法
+	p.renamePatterns = []*regexp.Regexp{
+		regexp.MustCompile(`^/rename(?:\s+(.*))?$`),
+	}
+
+	// 会话历史命令模式
+	p.historyPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`^/history\s*$`),
+	}
+
+	// 信任工作区命令模式
+	p.trustPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`^/trust\s*$`),
+	}
+
+	// 工具调用审计命令模式
+	p.auditPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`^/audit\s*$`),
+	}
+
+	// 写入预算命令模式
+	p.quotaPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`^/quota(?:\s+(.*))?$`),
+	}
+
+	// 回收站命令模式
+	p.trashPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`^/trash(?:\s+(.*))?$`),
+	}
+
+	// 重新加载配置命令模式
+	p.reloadConfigPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`^/reload-config\s*$`),
+	}
+
+	// 打开文件引用命令模式
+	p.openPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`^/open(?:\s+(.*))?$`),
+	}
+
+	// 查看图表和图片命令模式
+	p.showPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`^/show(?:\s+(.*))?$`),
+	}
+
+	// 重新生成回复命令模式
+	p.retryPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`^/retry(?:\s+(.*))?$`),
+	}
+
+	// 开始新会话命令模式
+	p.newPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`^/new(?:\s+(.*))?$`),
+	}
+
+	// 固定文件命令模式
+	p.pinPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`^/pin(?:\s+(.*))?$`),
+	}
+
+	// 使用统计命令模式
+	p.telemetryPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`^/telemetry(?:\s+(.*))?$`),
+	}
+
+	// 编辑恢复命令模式
+	p.recoverPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`^/recover(?:\s+(.*))?$`),
+	}
+
+	// 显示模式命令模式
+	p.verbosePatterns = []*regexp.Regexp{
+		regexp.MustCompile(`^/verbose(?:\s+(.*))?$`),
+	}
+
+	// 调试信息命令模式
+	p.debugPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`^/debug(?:\s+(.*))?$`),
+	}
+
+	// 工作区根目录命令模式
+	p.addRootPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`^/add-root(?:\s+(.*))?$`),
+	}
+
+	// 搜索范围命令模式
+	p.scopePatterns = []*regexp.Regexp{
+		regexp.MustCompile(`^/scope(?:\s+(.*))?$`),
+	}
+
+	// 本轮修改的差异和撤销命令模式
+	p.diffPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`^/diff(?:\s+(.*))?$`),
+	}
+	p.revertPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`^/revert(?:\s+(.*))?$`),
+	}
+
+	// 会话统计命令模式
+	p.statsPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`^/stats\s*$`),
+	}
+
+	// 计划模式命令模式
+	p.planPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`^/plan(?:\s+(.*))?$`),
+	}
 }
 
 // Parse 解析命令字符串
@@ -372,6 +524,241 @@ func (p *CommandParser) Parse(input string) *Command {
 		}
 	}
 
+	// 检查重命名命令
+	for _, pattern := range p.renamePatterns {
+		if matches := pattern.FindStringSubmatch(input); matches != nil {
+			return &Command{
+				Type:    CommandTypeRename,
+				Raw:     input,
+				Content: strings.TrimSpace(matches[1]),
+			}
+		}
+	}
+
+	// 检查会话历史命令
+	for _, pattern := range p.historyPatterns {
+		if pattern.MatchString(input) {
+			return &Command{
+				Type: CommandTypeHistory,
+				Raw:  input,
+			}
+		}
+	}
+
+	// 检查信任工作区命令
+	for _, pattern := range p.trustPatterns {
+		if pattern.MatchString(input) {
+			return &Command{
+				Type: CommandTypeTrust,
+				Raw:  input,
+			}
+		}
+	}
+
+	// 检查工具调用审计命令
+	for _, pattern := range p.auditPatterns {
+		if pattern.MatchString(input) {
+			return &Command{
+				Type: CommandTypeAudit,
+				Raw:  input,
+			}
+		}
+	}
+
+	// 检查写入预算命令
+	for _, pattern := range p.quotaPatterns {
+		if matches := pattern.FindStringSubmatch(input); matches != nil {
+			return &Command{
+				Type:    CommandTypeQuota,
+				Raw:     input,
+				Content: strings.TrimSpace(matches[1]),
+			}
+		}
+	}
+
+	// 检查回收站命令
+	for _, pattern := range p.trashPatterns {
+		if matches := pattern.FindStringSubmatch(input); matches != nil {
+			return &Command{
+				Type:    CommandTypeTrash,
+				Raw:     input,
+				Content: strings.TrimSpace(matches[1]),
+			}
+		}
+	}
+
+	// 检查重新加载配置命令
+	for _, pattern := range p.reloadConfigPatterns {
+		if pattern.MatchString(input) {
+			return &Command{
+				Type: CommandTypeReloadConfig,
+				Raw:  input,
+			}
+		}
+	}
+
+	// 检查打开文件引用命令
+	for _, pattern := range p.openPatterns {
+		if matches := pattern.FindStringSubmatch(input); matches != nil {
+			return &Command{
+				Type:    CommandTypeOpen,
+				Raw:     input,
+				Content: strings.TrimSpace(matches[1]),
+			}
+		}
+	}
+
+	// 检查查看图表和图片命令
+	for _, pattern := range p.showPatterns {
+		if matches := pattern.FindStringSubmatch(input); matches != nil {
+			return &Command{
+				Type:    CommandTypeShow,
+				Raw:     input,
+				Content: strings.TrimSpace(matches[1]),
+			}
+		}
+	}
+
+	// 检查重新生成回复命令
+	for _, pattern := range p.retryPatterns {
+		if matches := pattern.FindStringSubmatch(input); matches != nil {
+			return &Command{
+				Type:    CommandTypeRetry,
+				Raw:     input,
+				Content: strings.TrimSpace(matches[1]),
+			}
+		}
+	}
+
+	// 检查开始新会话命令
+	for _, pattern := range p.newPatterns {
+		if matches := pattern.FindStringSubmatch(input); matches != nil {
+			return &Command{
+				Type:    CommandTypeNew,
+				Raw:     input,
+				Content: strings.TrimSpace(matches[1]),
+			}
+		}
+	}
+
+	// 检查固定文件命令
+	for _, pattern := range p.pinPatterns {
+		if matches := pattern.FindStringSubmatch(input); matches != nil {
+			return &Command{
+				Type:    CommandTypePin,
+				Raw:     input,
+				Content: strings.TrimSpace(matches[1]),
+			}
+		}
+	}
+
+	// 检查使用统计命令
+	for _, pattern := range p.telemetryPatterns {
+		if matches := pattern.FindStringSubmatch(input); matches != nil {
+			return &Command{
+				Type:    CommandTypeTelemetry,
+				Raw:     input,
+				Content: strings.TrimSpace(matches[1]),
+			}
+		}
+	}
+
+	// 检查编辑恢复命令
+	for _, pattern := range p.recoverPatterns {
+		if matches := pattern.FindStringSubmatch(input); matches != nil {
+			return &Command{
+				Type:    CommandTypeRecover,
+				Raw:     input,
+				Content: strings.TrimSpace(matches[1]),
+			}
+		}
+	}
+
+	// 检查显示模式命令
+	for _, pattern := range p.verbosePatterns {
+		if matches := pattern.FindStringSubmatch(input); matches != nil {
+			return &Command{
+				Type:    CommandTypeVerbose,
+				Raw:     input,
+				Content: strings.TrimSpace(matches[1]),
+			}
+		}
+	}
+
+	// 检查调试信息命令
+	for _, pattern := range p.debugPatterns {
+		if matches := pattern.FindStringSubmatch(input); matches != nil {
+			return &Command{
+				Type:    CommandTypeDebug,
+				Raw:     input,
+				Content: strings.TrimSpace(matches[1]),
+			}
+		}
+	}
+
+	// 检查工作区根目录命令
+	for _, pattern := range p.addRootPatterns {
+		if matches := pattern.FindStringSubmatch(input); matches != nil {
+			return &Command{
+				Type:    CommandTypeAddRoot,
+				Raw:     input,
+				Content: strings.TrimSpace(matches[1]),
+			}
+		}
+	}
+
+	// 检查搜索范围命令
+	for _, pattern := range p.scopePatterns {
+		if matches := pattern.FindStringSubmatch(input); matches != nil {
+			return &Command{
+				Type:    CommandTypeScope,
+				Raw:     input,
+				Content: strings.TrimSpace(matches[1]),
+			}
+		}
+	}
+
+	// 检查差异和撤销命令
+	for _, pattern := range p.diffPatterns {
+		if matches := pattern.FindStringSubmatch(input); matches != nil {
+			return &Command{
+				Type:    CommandTypeDiff,
+				Raw:     input,
+				Content: strings.TrimSpace(matches[1]),
+			}
+		}
+	}
+	for _, pattern := range p.revertPatterns {
+		if matches := pattern.FindStringSubmatch(input); matches != nil {
+			return &Command{
+				Type:    CommandTypeRevert,
+				Raw:     input,
+				Content: strings.TrimSpace(matches[1]),
+			}
+		}
+	}
+
+	// 检查会话统计命令
+	for _, pattern := range p.statsPatterns {
+		if pattern.MatchString(input) {
+			return &Command{
+				Type: CommandTypeStats,
+				Raw:  input,
+			}
+		}
+	}
+
+	// 检查计划模式命令
+	for _, pattern := range p.planPatterns {
+		if matches := pattern.FindStringSubmatch(input); matches != nil {
+			return &Command{
+				Type:    CommandTypePlan,
+				Raw:     input,
+				Content: strings.TrimSpace(matches[1]),
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -415,6 +802,50 @@ func FormatCommandType(cmdType CommandType) string {
 		return "COT_TOGGLE"
 	case CommandTypeCoTHistory:
 		return "COT_HISTORY"
+	case CommandTypeRename:
+		return "RENAME"
+	case CommandTypeHistory:
+		return "HISTORY"
+	case CommandTypeTrust:
+		return "TRUST"
+	case CommandTypeAudit:
+		return "AUDIT"
+	case CommandTypeQuota:
+		return "QUOTA"
+	case CommandTypeTrash:
+		return "TRASH"
+	case CommandTypeReloadConfig:
+		return "RELOAD_CONFIG"
+	case CommandTypeOpen:
+		return "OPEN"
+	case CommandTypeShow:
+		return "SHOW"
+	case CommandTypeRetry:
+		return "RETRY"
+	case CommandTypeNew:
+		return "NEW"
+	case CommandTypePin:
+		return "PIN"
+	case CommandTypeTelemetry:
+		return "TELEMETRY"
+	case CommandTypeRecover:
+		return "RECOVER"
+	case CommandTypeVerbose:
+		return "VERBOSE"
+	case CommandTypeDebug:
+		return "DEBUG"
+	case CommandTypeAddRoot:
+		return "ADD_ROOT"
+	case CommandTypeScope:
+		return "SCOPE"
+	case CommandTypeDiff:
+		return "DIFF"
+	case CommandTypeRevert:
+		return "REVERT"
+	case CommandTypeStats:
+		return "STATS"
+	case CommandTypePlan:
+		return "PLAN"
 	default:
 		return "UNKNOWN"
 	}
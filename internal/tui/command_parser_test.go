@@ -0,0 +1,53 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+)
+
+func FuzzCommandParserParse(f *testing.F) {
+	for _, seed := range []string{
+		"/clear",
+		"/retry t=0.2 更简洁一些",
+		"/new keep-context",
+		"/pin ./main.go",
+		"/telemetry export stats.json",
+		"/debug error",
+		"/add-root ../frontend",
+		"/scope services/api",
+		"/diff 2",
+		"/revert all",
+		"/stats",
+		"/plan add rate limiting",
+		"/verbose on",
+		"/quota 50 200",
+		"/trash restore 3",
+		"/open 2",
+		"添加任务: 修复登录 优先级:高",
+		"完成任务 3",
+		"edit 在文件 a.go 的第 3 行插入: x",
+		"check update",
+		"  /show   list  ",
+		"/🙂",
+		"完成任务 99999999999999999999",
+	} {
+		f.Add(seed)
+	}
+
+	parser := NewCommandParser()
+	f.Fuzz(func(t *testing.T, input string) {
+		cmd := parser.Parse(input)
+		if (cmd != nil) != parser.IsCommand(input) {
+			t.Fatalf("Parse and IsCommand disagree for %q", input)
+		}
+		if cmd == nil {
+			return
+		}
+		if cmd.Raw != strings.TrimSpace(input) {
+			t.Errorf("Raw = %q, want trimmed input %q", cmd.Raw, strings.TrimSpace(input))
+		}
+		if FormatCommandType(cmd.Type) == "UNKNOWN" {
+			t.Errorf("Parse(%q) returned unnamed command type %d", input, cmd.Type)
+		}
+	})
+}
@@ -0,0 +1,100 @@
+package tui
+
+import (
+	"strings"
+	"time"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/config"
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
+	"github.com/Zacy-Sokach/PolyAgent/internal/telemetry"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// configWatchInterval 检查配置文件是否被修改的间隔
+const configWatchInterval = 2 * time.Second
+
+// configWatchTickMsg 定期检查配置文件的修改时间
+type configWatchTickMsg struct{}
+
+// configWatchTickCmd 安排下一次配置文件检查，没有配置时返回 nil
+func (m *Model) configWatchTickCmd() tea.Cmd {
+	if m.config == nil {
+		return nil
+	}
+	return tea.Tick(configWatchInterval, func(time.Time) tea.Msg {
+		return configWatchTickMsg{}
+	})
+}
+
+// handleConfigWatchTick 配置文件被修改时自动重新加载，只在 API Key 变化时提示
+func (m *Model) handleConfigWatchTick() tea.Cmd {
+	modTime, err := config.ConfigModTime()
+	if err != nil || modTime.Equal(m.configModTime) {
+		return m.configWatchTickCmd()
+	}
+	m.configModTime = modTime
+
+	apiKey := m.apiKey
+	changes, err := m.reloadConfig()
+	switch {
+	case err != nil:
+		m.messages = append(m.messages, Message{Role: "system", Content: i18n.T("command.config_reload_failed", err)})
+	case len(changes) > 0:
+		m.messages = append(m.messages, Message{Role: "system", Content: i18n.T("command.config_auto_reloaded", strings.Join(changes, "; "))})
+	default:
+		return m.configWatchTickCmd()
+	}
+	return tea.Batch(m.updateViewport(), m.configWatchTickCmd(), m.reprobeIfKeyChanged(apiKey))
+}
+
+// handleReloadConfigCommand 处理 /reload-config 命令：立即重新读取配置文件
+func (m *Model) handleReloadConfigCommand() tea.Cmd {
+	if modTime, err := config.ConfigModTime(); err == nil {
+		m.configModTime = modTime
+	}
+	apiKey := m.apiKey
+	changes, err := m.reloadConfig()
+	probe := m.reprobeIfKeyChanged(apiKey)
+	return tea.Batch(probe, func() tea.Msg {
+		switch {
+		case err != nil:
+			return ResponseMsg{Content: i18n.T("command.config_reload_failed", err)}
+		case len(changes) == 0:
+			return ResponseMsg{Content: i18n.T("command.config_unchanged")}
+		default:
+			return ResponseMsg{Content: i18n.T("command.config_reloaded", strings.Join(changes, "; "))}
+		}
+	})
+}
+
+// reprobeIfKeyChanged API Key 被修改后重新检查 API 是否可用
+func (m *Model) reprobeIfKeyChanged(previous string) tea.Cmd {
+	if m.apiKey == previous {
+		return nil
+	}
+	return m.startHealthProbe()
+}
+
+// reloadConfig 重新读取配置文件，更新对话使用的 API Key 和工具缓存的 Key，返回变化说明
+// 离线模式决定了启动时注册的工具，重新加载后保持不变
+func (m *Model) reloadConfig() ([]string, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	if m.config != nil {
+		cfg.Offline = m.config.Offline
+	}
+
+	var changes []string
+	if cfg.APIKey != "" && cfg.APIKey != m.apiKey {
+		m.apiKey = cfg.APIKey
+		changes = append(changes, i18n.T("command.config_api_key_changed"))
+	}
+	if tools := m.toolManager.ReloadAPIKeys(cfg); len(tools) > 0 {
+		changes = append(changes, i18n.T("command.config_tools_changed", strings.Join(tools, ", ")))
+	}
+	telemetry.SetEnabled(cfg.Telemetry)
+	m.config = cfg
+	return changes, nil
+}
@@ -0,0 +1,170 @@
+package tui
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/api"
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// contextRetryToolTokens 缩减上下文时省略超过该 token 数的较早工具结果
+const contextRetryToolTokens = 500
+
+// earlyTurnsSummaryPrompt 缩减上下文时总结较早对话的系统提示
+const earlyTurnsSummaryPrompt = `下面是一段编程助手对话的较早部分，对话还在继续，这部分将被替换为你的总结。
+要求：使用与用户相同的语言；用要点列出用户的目标和要求、已确认的事实（文件路径、接口、约定）、已完成的修改和仍未解决的问题；省略寒暄和中间的试错过程；不超过 300 字。`
+
+// earlySummaryHeading 替换较早对话的总结消息的开头
+const earlySummaryHeading = "## 之前对话的总结\n\n"
+
+// contextShrunkMsg 较早对话的总结已生成（或失败），可以用缩减后的上下文重试
+type contextShrunkMsg struct {
+	end     int // 被总结的消息为 apiMessages[:end]
+	turns   int
+	summary string
+	err     error
+	// 已省略的工具结果数和节省的 token 数
+	dropped, saved int
+}
+
+// isContextTooLong 请求是否因超出上下文长度失败（服务商拒绝或发送前的检查）
+func isContextTooLong(err error) bool {
+	var overflow *api.ContextOverflowError
+	return errors.As(err, &overflow) || newErrorBanner(err).Cause == errorCauseContextTooLong
+}
+
+// shrinkContext 请求因上下文过长失败后，每轮自动重试一次：省略较早的大工具结果，
+// 并让模型将前一半较早的对话总结为摘要。没有可以缩减的内容时返回 nil
+func (m *Model) shrinkContext() tea.Cmd {
+	dropped, saved := m.dropLargeToolResults()
+	end, turns := earlyTurns(m.apiMessages)
+	if dropped == 0 && turns == 0 {
+		return nil
+	}
+	m.contextRetried = true
+	m.stats.addRetry()
+	if turns == 0 {
+		return m.retryShrunkContext(i18n.T("context_retry.dropped", dropped, formatTokenCount(saved)))
+	}
+
+	m.messages = append(m.messages, Message{Role: "system", Content: i18n.T("context_retry.summarizing", turns)})
+	client := m.withBudget(m.withStats(api.NewClient(m.apiKey)))
+	if model := m.taskModel(modelTaskSummary); model != "" {
+		client = client.WithModel(model)
+	}
+	transcript := sessionTranscript(api.TrimHistory(m.apiMessages[:end], 0, summaryMaxTokens))
+	return tea.Batch(m.updateViewport(), func() tea.Msg {
+		msg := contextShrunkMsg{end: end, turns: turns, dropped: dropped, saved: saved}
+		resp, err := client.ChatCompletion([]api.Message{
+			api.TextMessage("system", earlyTurnsSummaryPrompt),
+			api.TextMessage("user", transcript),
+		}, false, nil)
+		switch {
+		case err != nil:
+			msg.err = err
+		case len(resp.Choices) == 0 || resp.Choices[0].Message == nil:
+			msg.err = errors.New("模型没有返回总结")
+		default:
+			msg.summary = strings.TrimSpace(api.MessageText(*resp.Choices[0].Message))
+		}
+		return msg
+	})
+}
+
+// handleContextShrunk 用总结替换较早的对话后重试；总结失败时直接丢弃这些对话
+func (m *Model) handleContextShrunk(msg contextShrunkMsg) tea.Cmd {
+	if !m.thinking {
+		// 总结期间用户取消了请求
+		return nil
+	}
+	var notes []string
+	if msg.dropped > 0 {
+		notes = append(notes, i18n.T("context_retry.dropped", msg.dropped, formatTokenCount(msg.saved)))
+	}
+	rest := m.apiMessages[msg.end:]
+	if msg.err != nil || msg.summary == "" {
+		m.apiMessages = append([]api.Message(nil), rest...)
+		notes = append(notes, i18n.T("context_retry.turns_dropped", msg.turns, msg.err))
+	} else {
+		m.apiMessages = append([]api.Message{api.TextMessage("user", earlySummaryHeading+msg.summary)}, rest...)
+		notes = append(notes, i18n.T("context_retry.turns_summarized", msg.turns))
+	}
+	return m.retryShrunkContext(strings.Join(notes, "\n"))
+}
+
+// retryShrunkContext 告知用户缩减了哪些内容，然后重新发起请求
+func (m *Model) retryShrunkContext(notes string) tea.Cmd {
+	m.messages = append(m.messages, Message{Role: "system", Content: i18n.T("context_retry.retrying", notes)})
+	return tea.Batch(m.updateViewport(), m.retryStream())
+}
+
+// dropLargeToolResults 将较早的大工具结果替换为占位说明，返回省略的结果数和节省的 token 数。
+// 模型还没有看到的最新一批工具结果保留不动
+func (m *Model) dropLargeToolResults() (int, int) {
+	protected := len(m.apiMessages)
+	for i := len(m.apiMessages) - 1; i >= 0; i-- {
+		msg := m.apiMessages[i]
+		if msg.Role == "user" {
+			break
+		}
+		if len(msg.ToolCalls) > 0 {
+			protected = i
+			break
+		}
+	}
+
+	type candidate struct{ index, tokens int }
+	var candidates []candidate
+	for i, msg := range m.apiMessages[:protected] {
+		if msg.Role != "tool" {
+			continue
+		}
+		if n := api.EstimateMessageTokens(msg); n > contextRetryToolTokens {
+			candidates = append(candidates, candidate{i, n})
+		}
+	}
+	// 从最大的开始省略，便于在说明中看出节省了多少
+	sort.Slice(candidates, func(a, b int) bool { return candidates[a].tokens > candidates[b].tokens })
+
+	saved := 0
+	for _, c := range candidates {
+		msg := m.apiMessages[c.index]
+		placeholder := api.ToolResultMessage(msg.ToolCallID, i18n.T("context_retry.placeholder", formatTokenCount(c.tokens)))
+		placeholder.Name = msg.Name
+		saved += c.tokens - api.EstimateMessageTokens(placeholder)
+		m.apiMessages[c.index] = placeholder
+	}
+	return len(candidates), saved
+}
+
+// isEarlySummary 消息是否为 handleContextShrunk 插入的较早对话总结，它不对应界面上的任何一轮对话
+func isEarlySummary(msg api.Message) bool {
+	return msg.Role == "user" && strings.HasPrefix(api.MessageText(msg), earlySummaryHeading)
+}
+
+// isOmittedResult 工具结果是否为 dropLargeToolResults 留下的占位说明
+func isOmittedResult(result string) bool {
+	prefix, _, _ := strings.Cut(i18n.T("context_retry.placeholder", "\x00"), "\x00")
+	return prefix != "" && strings.HasPrefix(result, prefix)
+}
+
+// earlyTurns 返回可以总结的较早对话：当前这一轮之前的前一半轮次（至少一轮），
+// 结果为这些消息的结束位置和轮数，没有较早的对话时轮数为 0
+func earlyTurns(messages []api.Message) (int, int) {
+	var starts []int
+	for i, msg := range messages {
+		if msg.Role == "user" {
+			starts = append(starts, i)
+		}
+	}
+	// 最后一条用户消息开始的是当前这一轮
+	old := len(starts) - 1
+	if old < 1 {
+		return 0, 0
+	}
+	turns := (old + 1) / 2
+	return starts[turns], turns
+}
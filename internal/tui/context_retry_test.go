@@ -0,0 +1,73 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/api"
+)
+
+func TestContextRetry(t *testing.T) {
+	m := goldenModel(t)
+	big := strings.Repeat("func handler() { return nil }\n", 200)
+	withCall := func(id string) api.Message {
+		msg := api.TextMessage("assistant", "")
+		msg.ToolCalls = []api.ToolCall{toolCall(id, "read_file", `{"path":"main.go"}`)}
+		return msg
+	}
+	m.apiMessages = []api.Message{
+		api.TextMessage("user", "看看 main.go"),
+		withCall("c1"),
+		api.ToolResultMessage("c1", big),
+		api.TextMessage("assistant", "main.go 里有一个 handler"),
+		api.TextMessage("user", "改成返回错误"),
+		api.TextMessage("assistant", "好的"),
+		api.TextMessage("user", "再检查一遍"),
+		withCall("c2"),
+		api.ToolResultMessage("c2", big),
+	}
+	m.messages = append(m.messages, Message{Role: "user", Content: "再检查一遍"})
+	m.thinking = true
+
+	m = drive(t, m, StreamErrorMsg{Error: &api.APIError{StatusCode: 400, Message: "maximum context length exceeded"}})
+	if !m.thinking || !m.contextRetried {
+		t.Fatalf("should be retrying: thinking=%v retried=%v", m.thinking, m.contextRetried)
+	}
+	if text := api.MessageText(m.apiMessages[2]); !strings.Contains(text, "已省略这次工具调用的结果") {
+		t.Errorf("old tool result not dropped: %.80q", text)
+	}
+	if text := api.MessageText(m.apiMessages[8]); !strings.Contains(text, "func handler") {
+		t.Errorf("latest tool result should be kept: %.80q", text)
+	}
+	if last := m.messages[len(m.messages)-1]; !strings.Contains(last.Content, "正在总结较早的 1 轮对话") {
+		t.Errorf("no summarizing notice: %q", last.Content)
+	}
+
+	// 取消请求上下文，重试的请求立即结束，不访问网络
+	m.cancel()
+	m = drive(t, m, contextShrunkMsg{end: 4, turns: 1, summary: "用户想了解 main.go 中的 handler", dropped: 1, saved: 1000})
+	if len(m.apiMessages) != 6 || !strings.HasPrefix(api.MessageText(m.apiMessages[0]), "## 之前对话的总结") || api.MessageText(m.apiMessages[1]) != "改成返回错误" {
+		t.Errorf("early turn not replaced by summary: %+v", m.apiMessages)
+	}
+	last := m.messages[len(m.messages)-1]
+	if !strings.Contains(last.Content, "省略了 1 个较早的工具结果") || !strings.Contains(last.Content, "替换为摘要") {
+		t.Errorf("retry notice = %q", last.Content)
+	}
+
+	// 每轮只自动重试一次
+	m = drive(t, m, StreamErrorMsg{Error: &api.APIError{StatusCode: 400, Message: "maximum context length exceeded"}})
+	if m.thinking || m.messages[len(m.messages)-1].Banner == nil {
+		t.Errorf("second failure should show the error banner")
+	}
+}
+
+func TestEarlyTurns(t *testing.T) {
+	user, assistant := api.TextMessage("user", "q"), api.TextMessage("assistant", "a")
+	if end, turns := earlyTurns([]api.Message{user, assistant}); turns != 0 || end != 0 {
+		t.Errorf("single turn: end %d, turns %d", end, turns)
+	}
+	messages := []api.Message{user, assistant, user, assistant, user, assistant, user}
+	if end, turns := earlyTurns(messages); turns != 2 || end != 4 {
+		t.Errorf("end %d, turns %d, want 4, 2", end, turns)
+	}
+}
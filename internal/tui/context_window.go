@@ -0,0 +1,62 @@
+package tui
+
+import (
+	"github.com/Zacy-Sokach/PolyAgent/internal/api"
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// contextWindow 模型的上下文窗口（token），未知的模型返回 0，不做发送前检查
+func (m *Model) contextWindow(model string) int {
+	var overrides map[string]int
+	if m.config != nil {
+		overrides = m.config.ContextWindows
+	}
+	return api.ContextWindow(model, overrides)
+}
+
+// fitContextWindow 发送前估算请求的 token 数：加上输出预留超出上下文窗口时按剩余空间重新裁剪历史，
+// 本轮的内容本身就放不下时返回 *api.ContextOverflowError，不发送注定被服务商拒绝的请求
+func (m *Model) fitContextWindow(model string, messages []api.Message, tools []api.Tool) ([]api.Message, error) {
+	window := m.contextWindow(model)
+	if window <= 0 {
+		return messages, nil
+	}
+	budget := window - api.DefaultMaxTokens
+	estimated := api.EstimateRequestTokens(messages, tools)
+	if estimated <= budget {
+		return messages, nil
+	}
+
+	overhead := estimated - api.EstimateTokens(messages)
+	trimmed := api.TrimHistory(messages, 0, budget-overhead)
+	if fitted := api.EstimateRequestTokens(trimmed, tools); fitted <= budget {
+		m.messages = append(m.messages, Message{Role: "system", Content: i18n.T("ui.context_compacted",
+			formatTokenCount(estimated), formatTokenCount(window), len(messages)-len(trimmed), formatTokenCount(fitted))})
+		return trimmed, nil
+	}
+	return nil, &api.ContextOverflowError{Model: model, Estimated: estimated, Limit: window}
+}
+
+// requestModel 请求实际使用的模型名
+func (m *Model) requestModel() string {
+	if model := m.chatModel(); model != "" {
+		return model
+	}
+	return api.DefaultModel
+}
+
+// streamRequest 检查上下文窗口后发起流式请求，超出窗口时不发送，直接按请求失败处理
+func (m *Model) streamRequest(client *api.Client, messages []api.Message, tools []api.Tool) tea.Cmd {
+	m.resetPrefetch()
+	messages, err := m.fitContextWindow(client.Model(), messages, tools)
+	if err != nil {
+		events := make(chan api.StreamEvent, 1)
+		events <- api.StreamEvent{Type: api.StreamEventError, Err: err}
+		close(events)
+		m.streamEvents = events
+		return m.checkStream()
+	}
+	m.streamEvents = client.StreamChatWithChannel(m.ctx, messages, tools)
+	return m.checkStream()
+}
@@ -0,0 +1,57 @@
+package tui
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/api"
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// handleDebugCommand 处理 /debug 命令：error（默认）显示最近一次请求失败的原始响应，
+// messages [文件] 显示下一次请求将发送的消息，指定文件时同时导出，net 显示连接设置和统计
+func (m *Model) handleDebugCommand(cmd *Command) tea.Cmd {
+	fields := strings.Fields(cmd.Content)
+	action := "error"
+	if len(fields) > 0 {
+		action = strings.ToLower(fields[0])
+	}
+	var content string
+	switch action {
+	case "error":
+		content = m.debugLastError()
+	case "messages":
+		content = m.debugMessages(fields[1:])
+	case "net":
+		content = m.debugNet()
+	default:
+		content = i18n.T("command.debug_usage")
+	}
+	return func() tea.Msg {
+		return ResponseMsg{Content: content}
+	}
+}
+
+// debugLastError 最近一次请求失败的完整信息，包括服务商返回的原始响应体
+func (m *Model) debugLastError() string {
+	if m.lastAPIError == nil {
+		return i18n.T("command.debug_no_error")
+	}
+	var sb strings.Builder
+	sb.WriteString(i18n.T("command.debug_error_title"))
+	var apiErr *api.APIError
+	if !errors.As(m.lastAPIError, &apiErr) {
+		sb.WriteString("\n" + m.lastAPIError.Error())
+		return sb.String()
+	}
+	sb.WriteString(i18n.T("command.debug_error_status", apiErr.StatusCode))
+	if apiErr.Code != "" {
+		sb.WriteString(i18n.T("command.debug_error_code", apiErr.Code))
+	}
+	if apiErr.Type != "" {
+		sb.WriteString(i18n.T("command.debug_error_type", apiErr.Type))
+	}
+	sb.WriteString(i18n.T("command.debug_error_body", "\n```\n"+strings.TrimRight(apiErr.Body, "\n")+"\n```"))
+	return sb.String()
+}
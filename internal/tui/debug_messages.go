@@ -0,0 +1,168 @@
+package tui
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/api"
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
+)
+
+// debugLargeMessageTokens 超过该 token 数的消息在 /debug messages 中标为过大
+const debugLargeMessageTokens = 8000
+
+// debugContentPreview /debug messages 中每条消息的文本内容最多显示的字符数，导出的文件保留完整内容
+const debugContentPreview = 300
+
+// messageProblem 消息序列中可能导致服务商拒绝请求或浪费上下文的问题
+type messageProblem struct {
+	Index  int
+	Detail string
+}
+
+// debugMessages 列出下一次请求将发送的消息（角色、工具调用、内容大小），标出问题并显示 JSON。
+// args 中指定文件时将完整的 JSON 导出到文件
+func (m *Model) debugMessages(args []string) string {
+	messages := m.requestMessages(m.requestTools())
+	if len(messages) == 0 {
+		return i18n.T("debug_messages.empty")
+	}
+
+	var sb strings.Builder
+	sb.WriteString(i18n.T("debug_messages.title", len(messages), formatTokenCount(api.EstimateTokens(messages)), m.requestModel()))
+
+	if len(args) > 0 {
+		path := args[0]
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		if err := exportMessages(path, messages); err != nil {
+			sb.WriteString("\n" + i18n.T("debug_messages.export_failed", err))
+		} else {
+			sb.WriteString("\n" + i18n.T("debug_messages.exported", displayPath(path)))
+		}
+	}
+
+	problems := inspectMessages(messages)
+	flagged := make(map[int]bool, len(problems))
+	if len(problems) == 0 {
+		sb.WriteString("\n" + i18n.T("debug_messages.no_problems"))
+	} else {
+		sb.WriteString("\n" + i18n.T("debug_messages.problems", len(problems)))
+		for _, p := range problems {
+			flagged[p.Index] = true
+			fmt.Fprintf(&sb, "\n⚠️ #%d %s：%s", p.Index, messages[p.Index].Role, p.Detail)
+		}
+	}
+
+	sb.WriteString("\n```\n")
+	for i, msg := range messages {
+		size := i18n.T("debug_messages.size", formatTokenCount(api.EstimateMessageTokens(msg)), len([]rune(api.MessageText(msg))))
+		line := fmt.Sprintf("#%-3d %-9s %s", i, msg.Role, size)
+		for _, call := range msg.ToolCalls {
+			line += fmt.Sprintf("  call %s %s", call.ID, call.Function.Name)
+		}
+		if msg.ToolCallID != "" {
+			line += "  tool_call_id " + msg.ToolCallID
+		}
+		if flagged[i] {
+			line += "  ⚠"
+		}
+		sb.WriteString(line + "\n")
+	}
+	sb.WriteString("```\n")
+
+	data, err := json.MarshalIndent(previewMessages(messages), "", "  ")
+	if err != nil {
+		sb.WriteString(i18n.T("debug_messages.json_failed", err))
+		return sb.String()
+	}
+	sb.WriteString(i18n.T("debug_messages.json_title", debugContentPreview))
+	sb.WriteString("\n```json\n" + string(data) + "\n```")
+	return sb.String()
+}
+
+// inspectMessages 检查消息序列：工具结果必须紧跟在包含对应调用的助手消息之后，每个工具调用都要有结果，
+// 没有工具调用的消息不能为空，单条消息不宜过大
+func inspectMessages(messages []api.Message) []messageProblem {
+	var problems []messageProblem
+	// open 最近一条助手消息中还没有结果的工具调用，只在紧随其后的工具结果中有效
+	var open map[string]bool
+	var openIndex int
+	closeCalls := func() {
+		for _, id := range sortedKeys(open) {
+			problems = append(problems, messageProblem{openIndex, i18n.T("debug_messages.unanswered_call", id)})
+		}
+		open = nil
+	}
+
+	for i, msg := range messages {
+		if msg.Role != "tool" {
+			closeCalls()
+		}
+		switch {
+		case msg.Role == "tool" && msg.ToolCallID == "":
+			problems = append(problems, messageProblem{i, i18n.T("debug_messages.missing_call_id")})
+		case msg.Role == "tool" && !open[msg.ToolCallID]:
+			problems = append(problems, messageProblem{i, i18n.T("debug_messages.orphaned_tool", msg.ToolCallID)})
+		case msg.Role == "tool":
+			delete(open, msg.ToolCallID)
+		case len(msg.ToolCalls) > 0:
+			open = make(map[string]bool, len(msg.ToolCalls))
+			openIndex = i
+			for _, call := range msg.ToolCalls {
+				open[call.ID] = true
+			}
+		case strings.TrimSpace(api.MessageText(msg)) == "":
+			problems = append(problems, messageProblem{i, i18n.T("debug_messages.empty_content")})
+		}
+		if n := api.EstimateMessageTokens(msg); n > debugLargeMessageTokens {
+			problems = append(problems, messageProblem{i, i18n.T("debug_messages.oversized", formatTokenCount(n))})
+		}
+	}
+	closeCalls()
+	sort.SliceStable(problems, func(a, b int) bool { return problems[a].Index < problems[b].Index })
+	return problems
+}
+
+// sortedKeys 按字典序返回 map 的键，使问题列表的顺序稳定
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// previewMessages 复制消息并截断过长的文本内容，用于在界面中显示
+func previewMessages(messages []api.Message) []api.Message {
+	preview := make([]api.Message, len(messages))
+	for i, msg := range messages {
+		preview[i] = msg
+		var text string
+		if json.Unmarshal(msg.Content, &text) != nil {
+			continue
+		}
+		if runes := []rune(text); len(runes) > debugContentPreview {
+			preview[i].Content, _ = json.Marshal(string(runes[:debugContentPreview]) + i18n.T("debug_messages.truncated", len(runes)))
+		}
+	}
+	return preview
+}
+
+// exportMessages 将消息按发送时的 JSON 格式完整写入文件，文件包含对话内容，只允许当前用户读写
+func exportMessages(path string, messages []api.Message) error {
+	data, err := json.MarshalIndent(messages, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化消息失败: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("写入 %s 失败: %w", path, err)
+	}
+	return nil
+}
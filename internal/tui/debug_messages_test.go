@@ -0,0 +1,82 @@
+package tui
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/api"
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
+)
+
+func TestInspectMessages(t *testing.T) {
+	if err := i18n.SetLanguage("zh"); err != nil {
+		t.Fatal(err)
+	}
+	messages := []api.Message{
+		api.TextMessage("user", "看看 main.go"),
+		api.AssistantToolCallMessage("", []api.ToolCall{toolCall("c1", "read_file", `{}`), toolCall("c2", "glob", `{}`)}),
+		api.ToolResultMessage("c1", "package main"),
+		// c2 没有结果，用户消息之后的 c2 结果是孤立的
+		api.TextMessage("user", strings.Repeat("日志 ", debugLargeMessageTokens)),
+		api.ToolResultMessage("c2", "main.go"),
+		api.TextMessage("assistant", ""),
+		api.ToolResultMessage("", "?"),
+	}
+	var got []string
+	for _, p := range inspectMessages(messages) {
+		got = append(got, fmt.Sprintf("%d %s", p.Index, p.Detail))
+	}
+	want := []string{
+		"1 工具调用 c2 没有对应的工具结果",
+		"3 内容过大（约 16k tokens）",
+		"4 孤立的工具结果，前面的助手消息中没有 ID 为 c2 的工具调用",
+		"5 内容为空且没有工具调用",
+		"6 工具结果缺少 tool_call_id",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("problems:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if problems := inspectMessages(messages[:3]); len(problems) != 1 {
+		t.Errorf("an unanswered call at the end should be reported, got %v", problems)
+	}
+}
+
+func TestDebugMessagesExport(t *testing.T) {
+	m := goldenModel(t)
+	long := strings.Repeat("很长的内容", 100)
+	m.apiMessages = []api.Message{
+		api.TextMessage("user", long),
+		api.AssistantToolCallMessage("", []api.ToolCall{toolCall("c1", "read_file", `{"file_path":"main.go"}`)}),
+	}
+
+	msg := m.handleDebugCommand(NewCommandParser().Parse("/debug messages out.json"))()
+	content := msg.(ResponseMsg).Content
+	for _, want := range []string{"发现 1 个问题", "工具调用 c1 没有对应的工具结果", "call c1 read_file", "（共 500 字符）", "out.json"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("missing %q in:\n%s", want, content)
+		}
+	}
+
+	// 导出的文件是完整的请求消息，不截断
+	data, err := os.ReadFile("out.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var exported []api.Message
+	if err := json.Unmarshal(data, &exported); err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, msg := range exported {
+		if api.MessageText(msg) == long {
+			found = true
+		}
+	}
+	if !found || exported[len(exported)-1].ToolCalls[0].ID != "c1" {
+		t.Errorf("exported messages = %s", data)
+	}
+}
@@ -0,0 +1,89 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/api"
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
+	"github.com/Zacy-Sokach/PolyAgent/internal/utils"
+)
+
+// debugNet 显示模型 API 连接的代理、协议、连接池和超时设置，以及本次运行的连接统计，
+// 用于排查代理破坏流式响应、连接无法复用等网络问题
+func (m *Model) debugNet() string {
+	var sb strings.Builder
+	sb.WriteString(i18n.T("debug_net.title"))
+
+	sb.WriteString("\n" + i18n.T("debug_net.endpoint", api.CurrentBaseURL()))
+	sb.WriteString("\n" + i18n.T("debug_net.proxy", m.proxyDescription()))
+	o := api.CurrentTransportOptions()
+	if o.ForceHTTP1 {
+		sb.WriteString("\n" + i18n.T("debug_net.protocol_http1"))
+	} else {
+		sb.WriteString("\n" + i18n.T("debug_net.protocol_auto"))
+	}
+	keepAlive := i18n.T("debug_net.disabled")
+	if o.KeepAlive > 0 {
+		keepAlive = o.KeepAlive.String()
+	}
+	sb.WriteString("\n" + i18n.T("debug_net.pool", o.MaxConnsPerHost, o.MaxIdleConnsPerHost, o.IdleConnTimeout, keepAlive))
+
+	t := api.CurrentTimeouts()
+	idle := api.DefaultStreamIdleTimeout
+	if m.config != nil && m.config.StreamIdleTimeout > 0 {
+		idle = time.Duration(m.config.StreamIdleTimeout) * time.Second
+	}
+	sb.WriteString("\n" + i18n.T("debug_net.timeouts", t.Connect, t.ResponseHeader, t.Request, idle))
+
+	s := api.ConnectionStats()
+	if s.Requests == 0 {
+		sb.WriteString("\n" + i18n.T("debug_net.no_requests"))
+		return sb.String()
+	}
+	sb.WriteString("\n" + i18n.T("debug_net.requests", s.Requests, s.Failed, s.NewConns, s.ReusedConns))
+	var protocols []string
+	for _, name := range s.ProtocolNames() {
+		protocols = append(protocols, fmt.Sprintf("%s × %d", name, s.Protocols[name]))
+	}
+	if len(protocols) > 0 {
+		sb.WriteString("\n" + i18n.T("debug_net.protocols", strings.Join(protocols, ", ")))
+	}
+	sb.WriteString("\n" + i18n.T("debug_net.latency", roundMillis(s.AvgDNS), roundMillis(s.AvgConnect), roundMillis(s.AvgTLS), roundMillis(s.AvgFirstByte)))
+	if s.LastRemoteAddr != "" {
+		sb.WriteString("\n" + i18n.T("debug_net.remote", s.LastRemoteAddr))
+	}
+	if s.LastError != "" {
+		sb.WriteString("\n" + i18n.T("debug_net.last_error", s.LastError))
+	}
+	return sb.String()
+}
+
+// proxyDescription 当前使用的代理，隐藏地址中的密码
+func (m *Model) proxyDescription() string {
+	if m.config != nil && m.config.Network.Proxy != "" {
+		if u, err := utils.ParseProxyURL(m.config.Network.Proxy); err == nil {
+			return u.Redacted()
+		}
+		return m.config.Network.Proxy
+	}
+	for _, name := range []string{"HTTPS_PROXY", "https_proxy", "HTTP_PROXY", "http_proxy"} {
+		if value := os.Getenv(name); value != "" {
+			if u, err := utils.ParseProxyURL(value); err == nil {
+				value = u.Redacted()
+			}
+			return i18n.T("debug_net.proxy_env", value, name)
+		}
+	}
+	return i18n.T("debug_net.no_proxy")
+}
+
+// roundMillis 将耗时取整到毫秒，没有数据时显示 -
+func roundMillis(d time.Duration) string {
+	if d <= 0 {
+		return "-"
+	}
+	return d.Round(time.Millisecond).String()
+}
@@ -0,0 +1,70 @@
+package tui
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
+	"github.com/Zacy-Sokach/PolyAgent/internal/mcp"
+)
+
+// deleteToolName 递归删除前需要检查的工具
+const deleteToolName = "delete_file"
+
+// pendingDeleteApprovals 返回挂起的递归删除中规模过大或包含重要文件、需要用户确认的调用
+func (m *Model) pendingDeleteApprovals() []approvalRequest {
+	var maxFiles int
+	var maxBytes int64
+	if m.config != nil {
+		maxFiles = m.config.Delete.ConfirmFiles
+		maxBytes = int64(m.config.Delete.ConfirmMB) * 1024 * 1024
+	}
+
+	var requests []approvalRequest
+	for _, call := range m.pendingToolCalls {
+		if call.Function.Name != deleteToolName {
+			continue
+		}
+		var args struct {
+			Path      string `json:"path"`
+			Recursive bool   `json:"recursive"`
+		}
+		// 参数无法解析或非递归删除时由工具本身处理
+		if err := json.Unmarshal(call.Function.Arguments, &args); err != nil || args.Path == "" || !args.Recursive {
+			continue
+		}
+
+		report, err := mcp.InspectDeletion(args.Path)
+		if err != nil || report == nil || !report.NeedsApproval(maxFiles, maxBytes) {
+			continue
+		}
+		requests = append(requests, approvalRequest{
+			CallID: call.ID,
+			Item:   formatDeletionReport(report),
+		})
+	}
+	return requests
+}
+
+// formatDeletionReport 格式化删除统计，列出重要文件和最大的文件
+func formatDeletionReport(report *mcp.DeletionReport) string {
+	truncated := ""
+	if report.Truncated {
+		truncated = i18n.T("approval.delete_truncated")
+	}
+
+	var sb strings.Builder
+	sb.WriteString(i18n.T("approval.delete_item", report.Path, report.Files, formatByteCount(report.TotalBytes), truncated))
+	if len(report.Important) > 0 {
+		sb.WriteString(i18n.T("approval.delete_important", report.ImportantSummary(5)))
+	}
+	if len(report.Largest) > 0 {
+		largest := make([]string, len(report.Largest))
+		for i, file := range report.Largest {
+			largest[i] = fmt.Sprintf("%s (%s)", file.Path, formatByteCount(file.Size))
+		}
+		sb.WriteString(i18n.T("approval.delete_largest", strings.Join(largest, ", ")))
+	}
+	return sb.String()
+}
@@ -0,0 +1,72 @@
+package tui
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/config"
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// handleVerboseCommand 处理 /verbose 命令：不带参数时切换，on/off 切换到详细或简洁模式，并保存到配置
+func (m *Model) handleVerboseCommand(cmd *Command) tea.Cmd {
+	respond := func(content string) tea.Cmd {
+		return func() tea.Msg {
+			return ResponseMsg{Content: content}
+		}
+	}
+
+	verbose := !m.verbose
+	switch strings.ToLower(cmd.Content) {
+	case "":
+	case "on":
+		verbose = true
+	case "off":
+		verbose = false
+	default:
+		return respond(i18n.T("command.verbose_usage"))
+	}
+	m.setVerbose(verbose)
+
+	content := i18n.T("command.verbose_off")
+	if verbose {
+		content = i18n.T("command.verbose_on")
+	}
+	if m.config != nil && m.config.Verbose != verbose {
+		m.config.Verbose = verbose
+		if err := config.SaveConfig(m.config); err != nil {
+			content += "\n" + i18n.T("command.verbose_save_failed", err)
+		}
+	}
+	return respond(content)
+}
+
+// setVerbose 切换显示模式：所有工具调用面板随之展开或折叠
+func (m *Model) setVerbose(verbose bool) {
+	m.verbose = verbose
+	for i := range m.messages {
+		if panel := m.messages[i].Panel; panel != nil && panel.Expanded != verbose {
+			panel.Expanded = verbose
+			m.syncToolPanel(i)
+		}
+	}
+	m.redraw()
+}
+
+// reasoningView 流式输出时推理过程的显示内容：详细模式完整显示，简洁模式只显示最新的一行
+func (m Model) reasoningView() string {
+	if m.verbose {
+		return m.currentThink
+	}
+	lines := strings.Split(strings.TrimRight(m.currentThink, "\n"), "\n")
+	last := strings.TrimSpace(lines[len(lines)-1])
+	// 标签和光标之外的宽度
+	width := m.viewport.Width - lipgloss.Width(i18n.T("ui.role_thinking")) - 1
+	if len(lines) == 1 && lipgloss.Width(last) <= width {
+		return last
+	}
+	more := markdownRuleStyle.Render(i18n.T("ui.reasoning_more", utf8.RuneCountInString(m.currentThink)))
+	return truncateWidth(last, max(width-lipgloss.Width(more)-1, 10)) + " " + more
+}
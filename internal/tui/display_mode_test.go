@@ -0,0 +1,80 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/api"
+	"github.com/Zacy-Sokach/PolyAgent/internal/config"
+)
+
+func TestVerboseCommand(t *testing.T) {
+	m := goldenModel(t)
+	m.messages = append(m.messages, Message{Role: "user", Content: "看看 main.go"})
+	m.thinking = true
+	m = drive(t, m, ToolCallMsg{ToolCalls: []api.ToolCall{toolCall("c1", "read_file", `{"file_path":"main.go"}`)}})
+	m.thinking = false
+	m = drive(t, m, ToolResultMsg{ResultMessages: []api.Message{api.ToolResultMessage("c1", "package main")}})
+	if view := m.viewport.View(); strings.Contains(view, "package main") {
+		t.Fatalf("compact mode should collapse the panel:\n%s", view)
+	}
+
+	parser := NewCommandParser()
+	cmd := parser.Parse("/verbose")
+	m.handleVerboseCommand(cmd)
+	if !m.verbose || !m.messages[1].Panel.Expanded {
+		t.Fatal("/verbose should switch to verbose mode and expand existing panels")
+	}
+	if view := m.viewport.View(); !strings.Contains(view, "package main") {
+		t.Errorf("verbose mode should show tool results:\n%s", view)
+	}
+
+	// 新的面板跟随当前模式
+	m.thinking = true
+	m = drive(t, m, ToolCallMsg{ToolCalls: []api.ToolCall{toolCall("c2", "list_dir", `{"path":"."}`)}})
+	if panel := m.messages[len(m.messages)-1].Panel; panel == nil || !panel.Expanded {
+		t.Error("new panels should be expanded in verbose mode")
+	}
+
+	cmd = parser.Parse("/verbose off")
+	m.handleVerboseCommand(cmd)
+	if m.verbose || m.messages[1].Panel.Expanded {
+		t.Error("/verbose off should collapse the panels")
+	}
+
+	cmd = parser.Parse("/verbose maybe")
+	if msg := m.handleVerboseCommand(cmd)(); !strings.Contains(msg.(ResponseMsg).Content, "/verbose [on|off]") {
+		t.Errorf("unexpected reply to an invalid argument: %+v", msg)
+	}
+}
+
+func TestVerboseCommandSavesConfig(t *testing.T) {
+	t.Setenv("POLYAGENT_CONFIG_HOME", t.TempDir())
+	m := goldenModel(t)
+	m.config = &config.Config{}
+	parser := NewCommandParser()
+	cmd := parser.Parse("/verbose on")
+	if msg := m.handleVerboseCommand(cmd)(); strings.Contains(msg.(ResponseMsg).Content, "❌") {
+		t.Fatalf("saving failed: %s", msg.(ResponseMsg).Content)
+	}
+	if !m.config.Verbose {
+		t.Error("config should record verbose mode")
+	}
+}
+
+func TestReasoningView(t *testing.T) {
+	m := goldenModel(t)
+	m.currentThink = "先看看目录结构\n再读取 main.go"
+	if got := m.reasoningView(); !strings.HasPrefix(got, "再读取 main.go") || strings.Contains(got, "目录结构") {
+		t.Errorf("compact reasoning = %q", got)
+	}
+	m.currentThink = "一行推理"
+	if got := m.reasoningView(); got != "一行推理" {
+		t.Errorf("single short line should be shown as is, got %q", got)
+	}
+	m.verbose = true
+	m.currentThink = "先看看目录结构\n再读取 main.go"
+	if got := m.reasoningView(); got != m.currentThink {
+		t.Errorf("verbose reasoning = %q", got)
+	}
+}
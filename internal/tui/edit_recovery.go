@@ -0,0 +1,81 @@
+package tui
+
+import (
+	"strings"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
+	"github.com/Zacy-Sokach/PolyAgent/internal/utils"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// showEditRecovery 上次异常退出时编辑器中有未保存的修改时，启动后列出恢复摘要；修改都已在磁盘上时直接清除记录
+func (m *Model) showEditRecovery() {
+	if m.editor == nil {
+		return
+	}
+	report := m.editor.PendingRecovery()
+	if report == nil {
+		return
+	}
+	if report.Count(utils.RecoverySaved) == len(report.Files) {
+		m.editor.DiscardRecovery()
+		return
+	}
+	m.messages = append(m.messages, Message{Role: "system", Content: editRecoverySummary(report)})
+	m.updateViewport()
+}
+
+// handleRecoverCommand 处理 /recover 命令：无参数时显示摘要，apply 恢复，discard 放弃
+func (m *Model) handleRecoverCommand(cmd *Command) tea.Cmd {
+	respond := func(content string) tea.Cmd {
+		return func() tea.Msg {
+			return ResponseMsg{Content: content}
+		}
+	}
+	if m.editor == nil {
+		return respond(i18n.T("editor.not_initialized"))
+	}
+
+	switch strings.ToLower(cmd.Content) {
+	case "":
+		report := m.editor.PendingRecovery()
+		if report == nil {
+			return respond(i18n.T("editor.recovery_none"))
+		}
+		return respond(editRecoverySummary(report))
+	case "apply":
+		if m.editor.PendingRecovery() == nil {
+			return respond(i18n.T("editor.recovery_none"))
+		}
+		applied, err := m.editor.ApplyRecovery()
+		if err != nil {
+			return respond(i18n.T("editor.recovery_failed", err))
+		}
+		if len(applied) == 0 {
+			return respond(i18n.T("editor.recovery_nothing_applied"))
+		}
+		return respond(i18n.T("editor.recovery_applied", len(applied), strings.Join(applied, ", ")))
+	case "discard":
+		if err := m.editor.DiscardRecovery(); err != nil {
+			return respond(i18n.T("editor.recovery_failed", err))
+		}
+		return respond(i18n.T("editor.recovery_discarded"))
+	default:
+		return respond(i18n.T("editor.recovery_usage"))
+	}
+}
+
+// editRecoverySummary 逐个文件列出能否恢复
+func editRecoverySummary(report *utils.RecoveryReport) string {
+	keys := map[string]string{
+		utils.RecoveryPending:  "editor.recovery_file_pending",
+		utils.RecoverySaved:    "editor.recovery_file_saved",
+		utils.RecoveryConflict: "editor.recovery_file_conflict",
+		utils.RecoveryMissing:  "editor.recovery_file_missing",
+	}
+	lines := make([]string, len(report.Files))
+	for i, file := range report.Files {
+		lines[i] = i18n.T(keys[file.Status], file.Path, file.Edits)
+	}
+	return i18n.T("editor.recovery_summary", report.Timestamp.Format("2006-01-02 15:04"), strings.Join(lines, "\n"))
+}
@@ -5,6 +5,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/Zacy-Sokach/PolyAgent/internal/mcp"
 	"github.com/Zacy-Sokach/PolyAgent/internal/utils"
@@ -150,7 +151,11 @@ func (p *EditorParser) parseNaturalLanguage(text string) (*EditCommand, bool) {
 			return nil, false
 		}
 
-		startLine, _ := strconv.Atoi(matches[2])
+		startLine, err := strconv.Atoi(matches[2])
+		if err != nil || startLine < 1 {
+			// 行号从 1 开始
+			return nil, false
+		}
 		cmd.Offset = p.lineToOffset(content, startLine)
 
 		if matches[3] != "" {
@@ -162,7 +167,7 @@ func (p *EditorParser) parseNaturalLanguage(text string) (*EditCommand, bool) {
 			lines := strings.Split(content, "\n")
 			if startLine-1 < len(lines) {
 				lineContent := lines[startLine-1]
-				cmd.Length = len(lineContent)
+				cmd.Length = utf8.RuneCountInString(lineContent)
 				if startLine < len(lines) {
 					cmd.Length += 1 // 包括换行符
 				}
@@ -185,7 +190,11 @@ func (p *EditorParser) parseNaturalLanguage(text string) (*EditCommand, bool) {
 			return nil, false
 		}
 
-		startLine, _ := strconv.Atoi(matches[2])
+		startLine, err := strconv.Atoi(matches[2])
+		if err != nil || startLine < 1 {
+			// 行号从 1 开始
+			return nil, false
+		}
 		cmd.Offset = p.lineToOffset(content, startLine)
 
 		if matches[3] != "" {
@@ -197,7 +206,7 @@ func (p *EditorParser) parseNaturalLanguage(text string) (*EditCommand, bool) {
 			lines := strings.Split(content, "\n")
 			if startLine-1 < len(lines) {
 				lineContent := lines[startLine-1]
-				cmd.Length = len(lineContent)
+				cmd.Length = utf8.RuneCountInString(lineContent)
 				if startLine < len(lines) {
 					cmd.Length += 1 // 包括换行符
 				}
@@ -255,20 +264,15 @@ func (p *EditorParser) executeCommand(cmd *EditCommand) (string, error) {
 	}
 }
 
-// lineToOffset 将行号转换为字符偏移量
+// lineToOffset 将行号转换为行首的字符偏移量，超出最后一行时返回文件末尾
 func (p *EditorParser) lineToOffset(content string, lineNum int) int {
 	if lineNum <= 1 {
 		return 0
 	}
-
-	lines := strings.Split(content, "\n")
-	offset := 0
-
-	for i := 0; i < lineNum-1 && i < len(lines); i++ {
-		offset += len(lines[i]) + 1 // +1 为换行符
+	if offset, err := utils.LineColumnToOffset(content, lineNum, 1); err == nil {
+		return offset
 	}
-
-	return offset
+	return utf8.RuneCountInString(content)
 }
 
 // LoadFile 加载文件到编辑器（辅助方法）
@@ -0,0 +1,63 @@
+package tui
+
+import (
+	"os"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/utils"
+)
+
+// fuzzEditFile 模糊测试中唯一允许执行编辑的文件，避免读写测试目录之外的路径
+const fuzzEditFile = "f.go"
+
+func FuzzEditorParser(f *testing.F) {
+	for _, seed := range []string{
+		"EDIT insert f.go 0 0 // 注释",
+		"EDIT delete f.go 3 5",
+		"EDIT replace f.go 10 4 世界",
+		"EDIT delete f.go 3 -5",
+		"EDIT create f.go",
+		"在文件 f.go 的第 2 行插入: fmt.Println(\"你好\")",
+		"删除文件 f.go 的第 0 行",
+		"删除文件 f.go 的第 3 行到第 1 行",
+		"删除文件 f.go 的第 99999999999999999999 行",
+		"将文件 f.go 的第 1 行到第 2 行替换为: 🙂",
+	} {
+		f.Add(seed)
+	}
+
+	dir := f.TempDir()
+	f.Chdir(dir)
+	original := "package main\n\n// 你好，世界 🙂\nfunc main() {}\n"
+
+	f.Fuzz(func(t *testing.T, text string) {
+		if err := os.WriteFile(fuzzEditFile, []byte(original), 0644); err != nil {
+			t.Fatal(err)
+		}
+		editor := utils.NewEditor()
+		if err := editor.LoadFile(fuzzEditFile); err != nil {
+			t.Fatal(err)
+		}
+		p := NewEditorParser(editor)
+
+		cmd, ok := p.parseStructuredCommand(text)
+		if !ok {
+			cmd, ok = p.parseNaturalLanguage(text)
+		}
+		// create 会写入磁盘，其他文件可能是任意路径，只执行针对测试文件的编辑
+		if !ok || cmd.Type == "create" || cmd.FilePath != fuzzEditFile {
+			return
+		}
+		if _, err := p.executeCommand(cmd); err != nil {
+			return
+		}
+		content, err := editor.GetFileContent(fuzzEditFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if utf8.ValidString(cmd.Content) && !utf8.ValidString(content) {
+			t.Errorf("edit %+v corrupted UTF-8 content: %q", cmd, content)
+		}
+	})
+}
@@ -0,0 +1,155 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/api"
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// 请求失败的原因分类，决定横幅的标题和建议
+const (
+	errorCauseInvalidKey     = "invalid_key"
+	errorCauseQuota          = "quota"
+	errorCauseRateLimit      = "rate_limit"
+	errorCauseContextTooLong = "context_too_long"
+	errorCauseServer         = "server"
+	errorCauseNetwork        = "network"
+	errorCauseTimeout        = "timeout"
+	errorCauseUnknown        = "unknown"
+)
+
+// 服务商错误码或说明中表示各类原因的关键字（小写），按顺序匹配
+var (
+	contextTooLongHints = []string{"context_length", "context length", "maximum context", "too many tokens", "too long", "token limit", "超长", "超过最大", "1261"}
+	quotaHints          = []string{"insufficient_quota", "quota", "billing", "balance", "余额", "欠费", "资源包", "1113"}
+	invalidKeyHints     = []string{"invalid_api_key", "invalid api key", "api key", "apikey", "authentication", "unauthorized", "令牌", "鉴权", "身份验证"}
+)
+
+// errorBanner 请求失败时显示的横幅：可读的原因、服务商给出的说明和建议的处理方法，原始响应通过 /debug error 查看
+type errorBanner struct {
+	Cause  string
+	Status int    // HTTP 状态码，不是 HTTP 错误时为 0
+	Detail string // 服务商或底层错误给出的说明
+	Raw    bool   // 是否有原始响应可供 /debug error 查看
+}
+
+// newErrorBanner 分析请求失败的错误
+func newErrorBanner(err error) *errorBanner {
+	b := &errorBanner{Cause: errorCauseUnknown, Detail: err.Error()}
+
+	var apiErr *api.APIError
+	var overflow *api.ContextOverflowError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &overflow):
+		// 发送前的检查，请求没有到达服务商
+		b.Cause = errorCauseContextTooLong
+		b.Detail = i18n.T("error.context_overflow", formatTokenCount(overflow.Estimated), formatTokenCount(api.DefaultMaxTokens), overflow.Model, formatTokenCount(overflow.Limit))
+	case errors.As(err, &apiErr):
+		b.Status, b.Detail, b.Raw = apiErr.StatusCode, apiErr.Detail(), true
+		b.Cause = classifyHTTPError(apiErr.StatusCode, apiErr.Code+" "+apiErr.Type+" "+apiErr.Message)
+	case errors.Is(err, api.ErrStreamStalled), errors.Is(err, context.DeadlineExceeded):
+		b.Cause = errorCauseTimeout
+	case errors.As(err, &netErr) && netErr.Timeout():
+		b.Cause = errorCauseTimeout
+	case errors.As(err, &netErr):
+		b.Cause = errorCauseNetwork
+	default:
+		// 没有结构化信息的错误从文本中提取状态码
+		if matches := statusCodePattern.FindStringSubmatch(b.Detail); matches != nil {
+			b.Status, _ = strconv.Atoi(matches[1])
+			if _, detail, ok := strings.Cut(b.Detail, "): "); ok {
+				b.Detail = detail
+			}
+			b.Cause = classifyHTTPError(b.Status, b.Detail)
+		}
+	}
+	return b
+}
+
+// classifyHTTPError 根据状态码和服务商的错误码、说明判断原因；额度不足和上下文过长也可能以 400、429 返回
+func classifyHTTPError(status int, detail string) string {
+	detail = strings.ToLower(detail)
+	containsAny := func(hints []string) bool {
+		for _, hint := range hints {
+			if strings.Contains(detail, hint) {
+				return true
+			}
+		}
+		return false
+	}
+	switch {
+	case status == 413 || containsAny(contextTooLongHints):
+		return errorCauseContextTooLong
+	case containsAny(quotaHints):
+		return errorCauseQuota
+	case status == 401 || status == 403 || containsAny(invalidKeyHints):
+		return errorCauseInvalidKey
+	case status == 429:
+		return errorCauseRateLimit
+	case status >= 500:
+		return errorCauseServer
+	}
+	return errorCauseUnknown
+}
+
+// title 横幅标题，如“服务端错误 (HTTP 500)”
+func (b *errorBanner) title() string {
+	title := i18n.T("error.cause_" + b.Cause)
+	if b.Status != 0 {
+		title += i18n.T("error.banner_status", b.Status)
+	}
+	return title
+}
+
+// suggestions 建议的处理方法，原因不明时没有建议
+func (b *errorBanner) suggestions() []string {
+	if b.Cause == errorCauseUnknown {
+		return nil
+	}
+	return strings.Split(i18n.T("error.suggest_"+b.Cause), "\n")
+}
+
+// text 横幅的纯文本形式，用于保存历史记录
+func (b *errorBanner) text() string {
+	var sb strings.Builder
+	sb.WriteString("❌ " + b.title())
+	if b.Detail != "" {
+		sb.WriteString(": " + b.Detail)
+	}
+	for _, suggestion := range b.suggestions() {
+		sb.WriteString("\n→ " + suggestion)
+	}
+	return sb.String()
+}
+
+// render 渲染横幅：左侧红色竖条，标题加粗，说明和建议按可用宽度换行
+func (b *errorBanner) render(width int) string {
+	var lines []string
+	lines = append(lines, lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("9")).Render("❌ "+b.title()))
+	if b.Detail != "" {
+		lines = append(lines, b.Detail)
+	}
+	for _, suggestion := range b.suggestions() {
+		lines = append(lines, "→ "+suggestion)
+	}
+	if b.Raw {
+		lines = append(lines, markdownRuleStyle.Render(i18n.T("error.banner_debug")))
+	}
+
+	style := lipgloss.NewStyle().
+		Border(lipgloss.ThickBorder(), false, false, false, true).
+		BorderForeground(lipgloss.Color("9")).
+		PaddingLeft(1)
+	if width > 0 {
+		// 竖条和内边距占两列
+		style = style.Width(max(width-2, 20))
+	}
+	return style.Render(strings.Join(lines, "\n"))
+}
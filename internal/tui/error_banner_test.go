@@ -0,0 +1,62 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/api"
+)
+
+// apiError 构造服务商返回的错误，message 为解析出的说明
+func apiError(status int, message string) error {
+	return fmt.Errorf("stream: %w", &api.APIError{StatusCode: status, Message: message})
+}
+
+func TestErrorBannerCause(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"unauthorized", apiError(401, "Incorrect API key provided"), errorCauseInvalidKey},
+		{"quota as 429", apiError(429, "余额不足或无可用资源包,请充值。"), errorCauseQuota},
+		{"rate limit", apiError(429, "Rate limit reached"), errorCauseRateLimit},
+		{"context as 400", apiError(400, "This model's maximum context length is 128000 tokens"), errorCauseContextTooLong},
+		{"server", apiError(502, "Bad Gateway"), errorCauseServer},
+		{"stalled", fmt.Errorf("%w: 60s 内未收到数据", api.ErrStreamStalled), errorCauseTimeout},
+		{"deadline", context.DeadlineExceeded, errorCauseTimeout},
+		{"status in text", errors.New("API请求失败 (状态码: 401): bad key"), errorCauseInvalidKey},
+		{"unknown", errors.New("boom"), errorCauseUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newErrorBanner(tt.err).Cause; got != tt.want {
+				t.Errorf("cause = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorBannerAndDebug(t *testing.T) {
+	m := conversation(goldenModel(t))
+	m.thinking = true
+	body := "<html><head><title>413 Request Entity Too Large</title></head><body>nginx</body></html>"
+	m = drive(t, m, StreamErrorMsg{Error: &api.APIError{StatusCode: 413, Body: body}})
+
+	last := m.messages[len(m.messages)-1]
+	if last.Banner == nil || last.Banner.Cause != errorCauseContextTooLong {
+		t.Fatalf("expected a context-too-long banner, got %+v", last)
+	}
+	view := m.viewport.View()
+	if !strings.Contains(view, "/new keep-context") || !strings.Contains(view, "/debug error") {
+		t.Errorf("banner should suggest fixes and point to /debug:\n%s", view)
+	}
+
+	msg := m.handleDebugCommand(NewCommandParser().Parse("/debug"))()
+	if content := msg.(ResponseMsg).Content; !strings.Contains(content, "nginx") || !strings.Contains(content, "413") {
+		t.Errorf("/debug should show the raw response:\n%s", content)
+	}
+}
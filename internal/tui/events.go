@@ -171,6 +171,7 @@ func NewStreamErrorEvent(err error, retry bool, attempt int) *StreamErrorEvent {
 // ToolCalledEvent 工具调用事件
 type ToolCalledEvent struct {
 	*BaseEvent
+	CallID   string // 对应的工具调用 ID，可为空
 	ToolName string
 	Args     map[string]interface{}
 }
@@ -192,6 +193,7 @@ func NewToolCalledEvent(toolName string, args map[string]interface{}) *ToolCalle
 // ToolCompletedEvent 工具完成事件
 type ToolCompletedEvent struct {
 	*BaseEvent
+	CallID   string // 对应的工具调用 ID，可为空
 	ToolName string
 	Result   interface{}
 	Duration time.Duration
@@ -216,6 +218,7 @@ func NewToolCompletedEvent(toolName string, result interface{}, duration time.Du
 // ToolFailedEvent 工具失败事件
 type ToolFailedEvent struct {
 	*BaseEvent
+	CallID   string // 对应的工具调用 ID，可为空
 	ToolName string
 	Error    error
 	Duration time.Duration
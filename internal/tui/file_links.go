@@ -0,0 +1,143 @@
+package tui
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
+	"github.com/Zacy-Sokach/PolyAgent/internal/utils"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// fileRefListLimit /open 最多列出的文件引用数
+const fileRefListLimit = 20
+
+// resolvedFileRef 已确认存在的文件引用
+type resolvedFileRef struct {
+	utils.FileRef
+	AbsPath string
+}
+
+// fileOpenedMsg 编辑器退出后的结果
+type fileOpenedMsg struct {
+	Ref resolvedFileRef
+	Err error
+}
+
+// linkFileRefs 在支持 OSC 8 的终端中（enabled 为 true）将消息里存在的 path:line 引用渲染为可点击的文件链接
+func linkFileRefs(content string, enabled bool) string {
+	if !enabled {
+		return content
+	}
+	refs := utils.FindFileRefs(content)
+	if len(refs) == 0 {
+		return content
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return content
+	}
+
+	var sb strings.Builder
+	last := 0
+	for _, ref := range refs {
+		path, ok := utils.ResolveFileRef(ref, dir)
+		if !ok {
+			continue
+		}
+		sb.WriteString(content[last:ref.Start])
+		sb.WriteString(utils.Hyperlink(utils.FileURL(path), content[ref.Start:ref.End]))
+		last = ref.End
+	}
+	sb.WriteString(content[last:])
+	return sb.String()
+}
+
+// recentFileRefs 从最新的消息开始收集存在的文件引用（去重），最多 limit 个
+func (m *Model) recentFileRefs(limit int) []resolvedFileRef {
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil
+	}
+	seen := make(map[string]bool)
+	var result []resolvedFileRef
+	for i := len(m.messages) - 1; i >= 0 && len(result) < limit; i-- {
+		refs := utils.FindFileRefs(m.messages[i].Content)
+		// 同一条消息中按出现顺序排列
+		for _, ref := range refs {
+			path, ok := utils.ResolveFileRef(ref, dir)
+			key := path + ":" + strconv.Itoa(ref.Line)
+			if !ok || seen[key] {
+				continue
+			}
+			seen[key] = true
+			result = append(result, resolvedFileRef{FileRef: ref, AbsPath: path})
+			if len(result) == limit {
+				break
+			}
+		}
+	}
+	return result
+}
+
+// openFileRef 暂停界面，用 $VISUAL / $EDITOR 在引用的行打开文件
+func openFileRef(ref resolvedFileRef) tea.Cmd {
+	args := utils.EditorArgs(utils.DefaultEditor(), ref.AbsPath, ref.Line, ref.Column)
+	cmd := exec.Command(args[0], args[1:]...)
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		return fileOpenedMsg{Ref: ref, Err: err}
+	})
+}
+
+// handleOpenLatestFileRef 处理 Ctrl+O：打开最近一条消息中引用的文件位置
+func (m *Model) handleOpenLatestFileRef() tea.Cmd {
+	refs := m.recentFileRefs(1)
+	if len(refs) == 0 {
+		return func() tea.Msg {
+			return ResponseMsg{Content: i18n.T("command.open_none")}
+		}
+	}
+	return openFileRef(refs[0])
+}
+
+// handleOpenCommand 处理 /open 命令：不带参数时列出最近的文件引用，/open N 打开第 N 个
+func (m *Model) handleOpenCommand(cmd *Command) tea.Cmd {
+	refs := m.recentFileRefs(fileRefListLimit)
+	if len(refs) == 0 {
+		return func() tea.Msg {
+			return ResponseMsg{Content: i18n.T("command.open_none")}
+		}
+	}
+
+	if cmd.Content != "" {
+		n, err := strconv.Atoi(cmd.Content)
+		if err != nil || n < 1 || n > len(refs) {
+			return func() tea.Msg {
+				return ResponseMsg{Content: i18n.T("command.open_usage", len(refs))}
+			}
+		}
+		return openFileRef(refs[n-1])
+	}
+
+	var sb strings.Builder
+	sb.WriteString(i18n.T("command.open_header"))
+	for i, ref := range refs {
+		sb.WriteString(i18n.T("command.open_item", i+1, ref.String()))
+	}
+	sb.WriteString(i18n.T("command.open_footer"))
+	return func() tea.Msg {
+		return ResponseMsg{Content: sb.String()}
+	}
+}
+
+// handleFileOpened 编辑器退出后，打开失败时提示原因
+func (m *Model) handleFileOpened(msg fileOpenedMsg) tea.Cmd {
+	if msg.Err == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		return ResponseMsg{Content: i18n.T("command.open_failed", msg.Ref.String(), msg.Err)}
+	}
+}
@@ -0,0 +1,86 @@
+package tui
+
+import (
+	"context"
+	"time"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/api"
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
+	"github.com/Zacy-Sokach/PolyAgent/internal/telemetry"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// providerHealth 启动检查的结果，显示在状态栏
+type providerHealth struct {
+	checking bool
+	latency  time.Duration
+	err      error
+}
+
+// healthProbeMsg 启动检查完成
+type healthProbeMsg struct {
+	apiKey  string // 发起检查时的 Key，检查期间 Key 被修改时丢弃结果
+	latency time.Duration
+	err     error
+}
+
+// startHealthProbe 在后台检查 API Key 和服务是否可用，没有 Key 或配置了 skip_health_check 时不检查
+func (m *Model) startHealthProbe() tea.Cmd {
+	if m.apiKey == "" || (m.config != nil && m.config.SkipHealthCheck) {
+		return nil
+	}
+	m.health = &providerHealth{checking: true}
+	apiKey := m.apiKey
+	client := api.NewClient(apiKey)
+	if model := m.chatModel(); model != "" {
+		client = client.WithModel(model)
+	}
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), api.ProbeTimeout)
+		defer cancel()
+		latency, err := client.Probe(ctx)
+		return healthProbeMsg{apiKey: apiKey, latency: latency, err: err}
+	}
+}
+
+// handleHealthProbe 记录检查结果；失败时立即显示原因和处理方法，不必等到第一次提问才发现
+func (m *Model) handleHealthProbe(msg healthProbeMsg) tea.Cmd {
+	if msg.apiKey != m.apiKey {
+		return nil
+	}
+	m.health = &providerHealth{latency: msg.latency, err: msg.err}
+	if msg.err == nil {
+		return nil
+	}
+	telemetry.RecordError(apiErrorClass(msg.err))
+	m.lastAPIError = msg.err
+	banner := newErrorBanner(msg.err)
+	m.messages = append(m.messages,
+		Message{Role: "system", Content: i18n.T("health.failed")},
+		Message{Role: "system", Content: banner.text(), Banner: banner},
+	)
+	return m.updateViewport()
+}
+
+// markHealthy 之后的请求成功时清除检查失败的状态
+func (m *Model) markHealthy() {
+	if m.health != nil && m.health.err != nil {
+		m.health = &providerHealth{}
+	}
+}
+
+// healthStatus 状态栏中显示的检查结果，如 "API ✓ 320ms"
+func (m Model) healthStatus() string {
+	switch h := m.health; {
+	case h == nil:
+		return ""
+	case h.checking:
+		return i18n.T("health.checking")
+	case h.err != nil:
+		return i18n.T("health.status_failed", newErrorBanner(h.err).title())
+	case h.latency > 0:
+		return i18n.T("health.status_ok_latency", h.latency.Round(time.Millisecond))
+	default:
+		return i18n.T("health.status_ok")
+	}
+}
@@ -0,0 +1,50 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/api"
+)
+
+func TestHealthProbe(t *testing.T) {
+	m := goldenModel(t)
+	if m.health != nil || strings.Contains(m.helpView(), "API") {
+		t.Fatalf("no probe should run without an API key, help = %q", m.helpView())
+	}
+
+	m.apiKey = "k"
+	m.health = &providerHealth{checking: true}
+	if help := m.helpView(); !strings.Contains(help, "API 检查中") {
+		t.Errorf("help while checking = %q", help)
+	}
+
+	// Key 已修改，旧的检查结果被丢弃
+	m = drive(t, m, healthProbeMsg{apiKey: "old", err: &api.APIError{StatusCode: 401}})
+	if !m.health.checking {
+		t.Errorf("stale probe result should be ignored")
+	}
+
+	m = drive(t, m, healthProbeMsg{apiKey: "k", latency: 1234567 * time.Microsecond, err: &api.APIError{StatusCode: 401, Message: "令牌已过期"}})
+	if help := m.helpView(); !strings.Contains(help, "API ✗ API Key 无效或没有权限 (HTTP 401)") {
+		t.Errorf("help after failure = %q", help)
+	}
+	last := m.messages[len(m.messages)-1]
+	if last.Banner == nil || last.Banner.Cause != errorCauseInvalidKey || !strings.Contains(m.messages[len(m.messages)-2].Content, "启动检查") {
+		t.Errorf("failure should be shown with guidance, got %+v", m.messages[len(m.messages)-2:])
+	}
+	if m.lastAPIError == nil {
+		t.Errorf("/debug error should show the probe failure")
+	}
+
+	m.markHealthy()
+	if help := m.helpView(); !strings.Contains(help, "API ✓") {
+		t.Errorf("help after a successful request = %q", help)
+	}
+
+	m = drive(t, m, healthProbeMsg{apiKey: "k", latency: 320400 * time.Microsecond})
+	if help := m.helpView(); !strings.Contains(help, "API ✓ 320ms") {
+		t.Errorf("help after success = %q", help)
+	}
+}
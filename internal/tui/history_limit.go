@@ -0,0 +1,63 @@
+package tui
+
+import (
+	"fmt"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/api"
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
+)
+
+// defaultHistoryMaxTokens 发送给模型的历史默认 token 上限
+const defaultHistoryMaxTokens = 64000
+
+// historyLimits 返回发送给模型的历史消息数和 token 上限，小于等于 0 表示不限制
+func (m *Model) historyLimits() (int, int) {
+	maxMessages, maxTokens := m.maxMessages, defaultHistoryMaxTokens
+	if m.config != nil {
+		if m.config.History.MaxMessages != 0 {
+			maxMessages = m.config.History.MaxMessages
+		}
+		if m.config.History.MaxTokens != 0 {
+			maxTokens = m.config.History.MaxTokens
+		}
+	}
+	return maxMessages, maxTokens
+}
+
+// trimmedHistory 按上限裁剪后发送给模型的历史，完整记录仍保留在 apiMessages 中
+func (m *Model) trimmedHistory() []api.Message {
+	maxMessages, maxTokens := m.historyLimits()
+	return api.TrimHistory(m.apiMessages, maxMessages, maxTokens)
+}
+
+// requestMessages 返回本次请求发送给模型的消息：裁剪历史，有工具时加入系统提示
+func (m *Model) requestMessages(tools []api.Tool) []api.Message {
+	messages := m.trimmedHistory()
+	if len(tools) > 0 {
+		messages = addSystemPromptIfNeeded(messages, m.systemInstructions()...)
+	}
+	return messages
+}
+
+// contextUsage 状态栏中显示的上下文用量，如 "上下文 ~12.3k/64k"
+func (m Model) contextUsage() string {
+	if m.contextTokens == 0 {
+		return ""
+	}
+	if _, maxTokens := m.historyLimits(); maxTokens > 0 {
+		return i18n.T("ui.context_tokens_limit", formatTokenCount(m.contextTokens), formatTokenCount(maxTokens))
+	}
+	return i18n.T("ui.context_tokens", formatTokenCount(m.contextTokens))
+}
+
+// formatTokenCount 将 token 数格式化为 950、12.3k 这样的短格式
+func formatTokenCount(n int) string {
+	switch {
+	case n < 1000:
+		return fmt.Sprint(n)
+	case n < 10000:
+		return fmt.Sprintf("%.1fk", float64(n)/1000)
+	default:
+		return fmt.Sprintf("%dk", (n+500)/1000)
+	}
+}
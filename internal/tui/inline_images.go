@@ -0,0 +1,178 @@
+package tui
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
+	"github.com/Zacy-Sokach/PolyAgent/internal/utils"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// diagramRenderTimeout 渲染一张图表的超时
+const diagramRenderTimeout = 60 * time.Second
+
+// imagePathPattern 匹配回复中提到的图片路径
+var imagePathPattern = regexp.MustCompile(`(?:^|[\s(\["'` + "`" + `])((?:~?/|\.{1,2}/)?(?:[\w.\-@+]+/)*[\w\-@+.]+\.(?:png|jpe?g|gif|webp))\b`)
+
+// visual 回复中可查看的图表或图片
+type visual struct {
+	diagram *utils.Diagram
+	// path 图片文件的绝对路径
+	path string
+}
+
+func (v visual) label() string {
+	if v.diagram != nil {
+		return v.diagram.Kind
+	}
+	return v.path
+}
+
+// visualReadyMsg 图表渲染完成（或图片已就绪）
+type visualReadyMsg struct {
+	label  string
+	source string
+	png    string
+	err    error
+}
+
+// imageViewer 以外部命令的方式在主屏幕上显示图片，按回车后返回界面
+type imageViewer struct {
+	title  string
+	image  string
+	stdin  io.Reader
+	stdout io.Writer
+}
+
+func (v *imageViewer) Run() error {
+	fmt.Fprintf(v.stdout, "%s\n\n%s\n\n%s", v.title, v.image, i18n.T("ui.image_return"))
+	_, err := bufio.NewReader(v.stdin).ReadString('\n')
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
+
+func (v *imageViewer) SetStdin(r io.Reader)  { v.stdin = r }
+func (v *imageViewer) SetStdout(w io.Writer) { v.stdout = w }
+func (v *imageViewer) SetStderr(io.Writer)   {}
+
+// recentVisuals 从最新的回复开始收集图表代码块和存在的图片文件
+func (m *Model) recentVisuals() []visual {
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil
+	}
+	seen := make(map[string]bool)
+	var result []visual
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		msg := m.messages[i]
+		if msg.Role != "assistant" && msg.Role != "system" {
+			continue
+		}
+		for _, d := range utils.ExtractDiagrams(msg.Content) {
+			if !seen[d.Source] {
+				seen[d.Source] = true
+				result = append(result, visual{diagram: &d})
+			}
+		}
+		for _, match := range imagePathPattern.FindAllStringSubmatch(msg.Content, -1) {
+			path, ok := utils.ResolveFileRef(utils.FileRef{Path: match[1]}, dir)
+			if ok && !seen[path] {
+				seen[path] = true
+				result = append(result, visual{path: path})
+			}
+		}
+	}
+	return result
+}
+
+// handleShowCommand 处理 /show 命令：显示最近回复中的图表或图片，/show N 显示第 N 个，/show list 列出全部
+func (m *Model) handleShowCommand(cmd *Command) tea.Cmd {
+	visuals := m.recentVisuals()
+	if len(visuals) == 0 {
+		return func() tea.Msg {
+			return ResponseMsg{Content: i18n.T("command.show_none")}
+		}
+	}
+
+	switch cmd.Content {
+	case "":
+		return renderVisualCmd(visuals[0])
+	case "list":
+		content := i18n.T("command.show_header")
+		for i, v := range visuals {
+			content += i18n.T("command.show_item", i+1, v.label())
+		}
+		return func() tea.Msg {
+			return ResponseMsg{Content: content}
+		}
+	}
+	n, err := strconv.Atoi(cmd.Content)
+	if err != nil || n < 1 || n > len(visuals) {
+		return func() tea.Msg {
+			return ResponseMsg{Content: i18n.T("command.show_usage", len(visuals))}
+		}
+	}
+	return renderVisualCmd(visuals[n-1])
+}
+
+// renderVisualCmd 在后台渲染图表，图片文件直接就绪
+func renderVisualCmd(v visual) tea.Cmd {
+	return func() tea.Msg {
+		if v.diagram == nil {
+			return visualReadyMsg{label: v.label(), png: v.path}
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), diagramRenderTimeout)
+		defer cancel()
+		dir := filepath.Join(os.TempDir(), "polyagent-diagrams")
+		source, png, err := utils.WriteDiagram(ctx, *v.diagram, dir)
+		return visualReadyMsg{label: v.label(), source: source, png: png, err: err}
+	}
+}
+
+// handleVisualReady 终端支持图片协议时暂停界面内联显示，否则提示文件路径
+func (m *Model) handleVisualReady(msg visualReadyMsg) tea.Cmd {
+	respond := func(content string) tea.Cmd {
+		return func() tea.Msg {
+			return ResponseMsg{Content: content}
+		}
+	}
+
+	switch {
+	case errors.Is(msg.err, utils.ErrDiagramRendererMissing):
+		return respond(i18n.T("command.show_no_renderer", msg.source))
+	case msg.err != nil && msg.source != "":
+		return respond(i18n.T("command.show_render_failed", msg.err, msg.source))
+	case msg.err != nil:
+		return respond(i18n.T("command.show_render_failed", msg.err, msg.label))
+	}
+
+	protocol := utils.DetectImageProtocol()
+	if protocol == utils.ImageProtocolNone {
+		return respond(i18n.T("command.show_saved", msg.png))
+	}
+	data, err := os.ReadFile(msg.png)
+	if err != nil {
+		return respond(i18n.T("command.show_render_failed", err, msg.png))
+	}
+	image, err := utils.EncodeInlineImage(protocol, data, filepath.Base(msg.png))
+	if err != nil {
+		return respond(i18n.T("command.show_saved", msg.png))
+	}
+	return tea.Exec(&imageViewer{title: msg.png, image: image}, func(err error) tea.Msg {
+		if err != nil {
+			return ResponseMsg{Content: i18n.T("command.show_render_failed", err, msg.png)}
+		}
+		return nil
+	})
+}
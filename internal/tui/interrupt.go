@@ -0,0 +1,41 @@
+package tui
+
+import (
+	"strings"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/api"
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
+)
+
+// interruptedMarker 附加在被中断的回复之后，让模型知道上一条回复不完整
+const interruptedMarker = "(interrupted)"
+
+// keepInterruptedResponse 按 Esc 取消生成时保留已收到的部分回复，在界面和 API 历史中标记为已中断
+func (m *Model) keepInterruptedResponse() {
+	partial := m.currentResp
+	m.currentResp = ""
+	m.currentThink = ""
+
+	if m.toolsRunning {
+		// 工具正在执行，结果返回后仍会写入历史，保持工具调用与结果成对
+		return
+	}
+	// 工具调用之前的文本已显示在界面上，移除调用时保留到 API 历史
+	var earlier string
+	if len(m.pendingToolCalls) > 0 {
+		// 尚未执行的工具调用没有结果，留在历史中会导致下一次请求被拒绝
+		m.pendingToolCalls = nil
+		for len(m.apiMessages) > 0 && len(m.apiMessages[len(m.apiMessages)-1].ToolCalls) > 0 {
+			earlier = joinSegments(api.MessageText(m.apiMessages[len(m.apiMessages)-1]), earlier)
+			m.apiMessages = m.apiMessages[:len(m.apiMessages)-1]
+		}
+	}
+
+	if strings.TrimSpace(partial) != "" {
+		m.messages = append(m.messages, Message{Role: "assistant", Content: partial + "\n\n" + i18n.T("ui.interrupted")})
+		m.updateRenderedLinesCache()
+	}
+	if text := joinSegments(earlier, strings.TrimSpace(partial)); text != "" {
+		m.apiMessages = append(m.apiMessages, api.TextMessage("assistant", text+"\n\n"+interruptedMarker))
+	}
+}
@@ -0,0 +1,313 @@
+package tui
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/rivo/uniseg"
+)
+
+// 表格对齐方式
+const (
+	alignLeft = iota
+	alignCenter
+	alignRight
+)
+
+// minTableColumnWidth 终端过窄时表格列最多压缩到的宽度
+const minTableColumnWidth = 3
+
+var (
+	// listItemPattern 匹配无序（-、*、+）和有序（1. 或 1)）列表项
+	listItemPattern = regexp.MustCompile(`^([ \t]*)([-*+]|\d{1,9}[.)])[ \t]+(.*)$`)
+	// tableDelimiterCell 匹配表格分隔行中的一格，如 ---、:--、--:、:-:
+	tableDelimiterCell = regexp.MustCompile(`^:?-+:?$`)
+
+	markdownRuleStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	markdownHeaderStyle = lipgloss.NewStyle().Bold(true)
+	markdownQuoteStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("8")).Italic(true)
+)
+
+// listBullets 各层无序列表使用的符号
+var listBullets = []string{"•", "◦", "▪"}
+
+// renderMarkdown 排版助手回复中的 GFM 表格、引用块和嵌套列表，使其在终端中保持可读；
+// 代码块和其他内容原样保留。width 为可用的显示宽度，<= 0 时不限制表格宽度。
+// 流式输出时内容可能不完整：表格在分隔行到达之前按普通文本显示
+func renderMarkdown(content string, width int) string {
+	if !strings.ContainsAny(content, "|>-*+") && !strings.Contains(content, ". ") && !strings.Contains(content, ") ") {
+		return content
+	}
+
+	lines := strings.Split(content, "\n")
+	out := make([]string, 0, len(lines))
+	var fence string
+	var listIndents []int
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		// 代码块内容不做处理
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			out = append(out, line)
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = trimmed[:3]
+			listIndents = nil
+			out = append(out, line)
+			continue
+		}
+
+		if trimmed == "" {
+			// 列表项之间允许空行，不结束列表
+			out = append(out, line)
+			continue
+		}
+
+		if i+1 < len(lines) {
+			if header := splitTableRow(line); len(header) > 0 {
+				if aligns := parseTableDelimiter(lines[i+1], len(header)); aligns != nil {
+					rows := [][]string{header}
+					j := i + 2
+					for ; j < len(lines); j++ {
+						row := splitTableRow(lines[j])
+						if row == nil {
+							break
+						}
+						rows = append(rows, row)
+					}
+					out = append(out, renderTable(rows, aligns, width)...)
+					listIndents = nil
+					i = j - 1
+					continue
+				}
+			}
+		}
+
+		if depth, rest := blockquoteDepth(line); depth > 0 {
+			bar := markdownRuleStyle.Render(strings.Repeat("│ ", depth))
+			out = append(out, strings.TrimRight(bar+markdownQuoteStyle.Render(rest), " "))
+			listIndents = nil
+			continue
+		}
+
+		indent := indentWidth(line)
+		if m := listItemPattern.FindStringSubmatch(line); m != nil {
+			// 缩进比上一项深时进入下一层，回到某一层的缩进时返回该层
+			for len(listIndents) > 0 && listIndents[len(listIndents)-1] > indent {
+				listIndents = listIndents[:len(listIndents)-1]
+			}
+			if len(listIndents) == 0 || listIndents[len(listIndents)-1] < indent {
+				listIndents = append(listIndents, indent)
+			}
+			level := len(listIndents) - 1
+			marker := m[2]
+			if !strings.ContainsAny(marker, ".)") {
+				marker = listBullets[level%len(listBullets)]
+			}
+			out = append(out, strings.Repeat("  ", level)+marker+" "+m[3])
+			continue
+		}
+
+		if len(listIndents) > 0 && indent > 0 {
+			// 列表项的续行与所属列表项的文字对齐
+			level := 0
+			for _, n := range listIndents {
+				if n < indent {
+					level++
+				}
+			}
+			out = append(out, strings.Repeat("  ", level)+trimmed)
+			continue
+		}
+
+		listIndents = nil
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n")
+}
+
+// indentWidth 返回行首空白的宽度，制表符按 4 列计算
+func indentWidth(line string) int {
+	n := 0
+	for _, r := range line {
+		switch r {
+		case ' ':
+			n++
+		case '\t':
+			n += 4 - n%4
+		default:
+			return n
+		}
+	}
+	return n
+}
+
+// blockquoteDepth 返回引用块的嵌套层数（> > 或 >> 都算两层）和去掉标记后的内容
+func blockquoteDepth(line string) (int, string) {
+	rest := strings.TrimLeft(line, " ")
+	if len(line)-len(rest) > 3 {
+		return 0, line
+	}
+	depth := 0
+	for strings.HasPrefix(rest, ">") {
+		depth++
+		rest = strings.TrimLeft(rest[1:], " ")
+	}
+	return depth, rest
+}
+
+// splitTableRow 拆分表格行的单元格，不是表格行时返回 nil；\| 表示单元格中的竖线
+func splitTableRow(line string) []string {
+	trimmed := strings.TrimSpace(line)
+	if !strings.Contains(trimmed, "|") {
+		return nil
+	}
+	trimmed = strings.TrimPrefix(trimmed, "|")
+	if strings.HasSuffix(trimmed, "|") && !strings.HasSuffix(trimmed, `\|`) {
+		trimmed = trimmed[:len(trimmed)-1]
+	}
+
+	var cells []string
+	var cell strings.Builder
+	for i := 0; i < len(trimmed); i++ {
+		switch {
+		case trimmed[i] == '\\' && i+1 < len(trimmed) && trimmed[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case trimmed[i] == '|':
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(trimmed[i])
+		}
+	}
+	return append(cells, strings.TrimSpace(cell.String()))
+}
+
+// parseTableDelimiter 解析表头下方的分隔行，列数与表头不一致或不是分隔行时返回 nil
+func parseTableDelimiter(line string, columns int) []int {
+	cells := splitTableRow(line)
+	if len(cells) != columns {
+		return nil
+	}
+	aligns := make([]int, columns)
+	for i, cell := range cells {
+		if !tableDelimiterCell.MatchString(cell) {
+			return nil
+		}
+		left, right := strings.HasPrefix(cell, ":"), strings.HasSuffix(cell, ":")
+		switch {
+		case left && right:
+			aligns[i] = alignCenter
+		case right:
+			aligns[i] = alignRight
+		}
+	}
+	return aligns
+}
+
+// renderTable 按显示宽度（中日韩文字占两列）对齐表格；超出 width 时压缩最宽的列并截断其内容
+func renderTable(rows [][]string, aligns []int, width int) []string {
+	columns := len(aligns)
+	widths := make([]int, columns)
+	for r, row := range rows {
+		// 单元格数与表头不一致时补齐或截掉多余的
+		if len(row) < columns {
+			row = append(row, make([]string, columns-len(row))...)
+		}
+		rows[r] = row[:columns]
+		for c, cell := range rows[r] {
+			widths[c] = max(widths[c], lipgloss.Width(cell))
+		}
+	}
+
+	if width > 0 {
+		// 每列两侧各留一个空格，列之间用 │ 分隔
+		total := func() int {
+			sum := 3*columns - 1
+			for _, w := range widths {
+				sum += w
+			}
+			return sum
+		}
+		for total() > width {
+			widest := 0
+			for c, w := range widths {
+				if w > widths[widest] {
+					widest = c
+				}
+			}
+			if widths[widest] <= minTableColumnWidth {
+				break
+			}
+			widths[widest]--
+		}
+	}
+
+	sep := markdownRuleStyle.Render("│")
+	lines := make([]string, 0, len(rows)+1)
+	for r, row := range rows {
+		cells := make([]string, columns)
+		for c, cell := range row {
+			cell = padCell(truncateWidth(cell, widths[c]), widths[c], aligns[c])
+			if r == 0 {
+				cell = markdownHeaderStyle.Render(cell)
+			}
+			cells[c] = cell
+		}
+		lines = append(lines, strings.TrimRight(" "+strings.Join(cells, " "+sep+" "), " "))
+
+		if r == 0 {
+			segments := make([]string, columns)
+			for c, w := range widths {
+				segments[c] = strings.Repeat("─", w+2)
+			}
+			lines = append(lines, markdownRuleStyle.Render(strings.Join(segments, "┼")))
+		}
+	}
+	return lines
+}
+
+// padCell 用空格把单元格补齐到 width 列
+func padCell(cell string, width, align int) string {
+	gap := width - lipgloss.Width(cell)
+	if gap <= 0 {
+		return cell
+	}
+	switch align {
+	case alignRight:
+		return strings.Repeat(" ", gap) + cell
+	case alignCenter:
+		return strings.Repeat(" ", gap/2) + cell + strings.Repeat(" ", gap-gap/2)
+	default:
+		return cell + strings.Repeat(" ", gap)
+	}
+}
+
+// truncateWidth 将文本截断到 width 列以内，按字形簇截断并以 … 结尾
+func truncateWidth(s string, width int) string {
+	if lipgloss.Width(s) <= width {
+		return s
+	}
+	var sb strings.Builder
+	used := 0
+	state := -1
+	for s != "" {
+		var cluster string
+		var w int
+		cluster, s, w, state = uniseg.FirstGraphemeClusterInString(s, state)
+		if used+w > width-1 {
+			break
+		}
+		sb.WriteString(cluster)
+		used += w
+	}
+	return sb.String() + "…"
+}
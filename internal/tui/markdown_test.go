@@ -0,0 +1,125 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// columnOffsets 返回一行中每个 │ 所在的显示列
+func columnOffsets(line string) []int {
+	var offsets []int
+	col := 0
+	for _, r := range line {
+		if r == '│' {
+			offsets = append(offsets, col)
+		}
+		col += lipgloss.Width(string(r))
+	}
+	return offsets
+}
+
+func TestRenderMarkdownTable(t *testing.T) {
+	content := "结果如下：\n\n| 名称 | 说明 | 数量 |\n|:-----|:----:|----:|\n| go | 编译器 | 1 |\n| 中文字段 | x | 12345 |\n\n完毕"
+	lines := strings.Split(renderMarkdown(content, 80), "\n")
+	if len(lines) != 8 || lines[0] != "结果如下：" || lines[7] != "完毕" {
+		t.Fatalf("unexpected layout:\n%s", strings.Join(lines, "\n"))
+	}
+
+	header := columnOffsets(lines[2])
+	if len(header) != 2 {
+		t.Fatalf("header = %q", lines[2])
+	}
+	for _, line := range lines[4:6] {
+		if got := columnOffsets(line); len(got) != 2 || got[0] != header[0] || got[1] != header[1] {
+			t.Errorf("columns of %q at %v, header at %v", line, got, header)
+		}
+	}
+	if !strings.Contains(lines[3], "┼") {
+		t.Errorf("separator = %q", lines[3])
+	}
+	// 右对齐的列
+	if !strings.HasSuffix(lines[4], "    1") {
+		t.Errorf("right aligned cell = %q", lines[4])
+	}
+}
+
+func TestRenderMarkdownTableFitsWidth(t *testing.T) {
+	content := "| a | b |\n|---|---|\n| " + strings.Repeat("很长的内容", 10) + " | x |"
+	for _, line := range strings.Split(renderMarkdown(content, 30), "\n") {
+		if w := lipgloss.Width(line); w > 30 {
+			t.Errorf("line %q is %d columns wide", line, w)
+		}
+	}
+}
+
+func TestRenderMarkdownBlocks(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{
+			name:    "nested lists",
+			content: "- a\n  - b\n    - c\n      continued\n  - d\n1. one\n   1. nested",
+			want:    "• a\n  ◦ b\n    ▪ c\n      continued\n  ◦ d\n1. one\n  1. nested",
+		},
+		{
+			name:    "blockquote",
+			content: "> 引用\n>> 嵌套\n>",
+			want:    "│ 引用\n│ │ 嵌套\n│",
+		},
+		{
+			name:    "code blocks are untouched",
+			content: "```md\n| a | b |\n|---|---|\n- x\n> y\n```",
+			want:    "```md\n| a | b |\n|---|---|\n- x\n> y\n```",
+		},
+		{
+			// 流式输出时分隔行还没有到达
+			name:    "incomplete table",
+			content: "| a | b |",
+			want:    "| a | b |",
+		},
+		{
+			name:    "plain text",
+			content: "a - b\n\nfoo",
+			want:    "a - b\n\nfoo",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderMarkdown(tt.content, 80); got != tt.want {
+				t.Errorf("renderMarkdown() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResizeRerendersCachedHistory(t *testing.T) {
+	m := goldenModel(t)
+	m.messages = append(m.messages,
+		Message{Role: "user", Content: "列出文件"},
+		Message{Role: "assistant", Content: "| 文件 | 说明 |\n|---|---|\n| main.go | " + strings.Repeat("入口", 30) + " |"},
+		Message{Role: "user", Content: "继续"},
+	)
+	m.thinking = true
+	m.updateRenderedLinesCache()
+	if !m.renderedLinesValid() {
+		t.Fatal("fresh cache should be valid")
+	}
+
+	m = drive(t, m, tea.WindowSizeMsg{Width: 40, Height: 24})
+	if !m.renderedLinesValid() {
+		t.Fatal("cache should be rebuilt for the new width")
+	}
+	for _, line := range m.renderedLines {
+		if w := lipgloss.Width(line); w > 40 {
+			t.Errorf("line %q is %d columns wide after resize", line, w)
+		}
+	}
+	if !strings.Contains(strings.Join(m.renderedLines, "\n"), "…") {
+		t.Error("table should be truncated to the new width")
+	}
+}
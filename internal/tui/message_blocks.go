@@ -0,0 +1,128 @@
+package tui
+
+import (
+	"strings"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// messageBlocks 按消息 ID 缓存每条消息渲染后的文本块，历史消息不变时不再重复排版和设置样式。
+// 终端状态或界面语言变化时整体失效
+type messageBlocks struct {
+	key    renderKey
+	blocks map[uint64]string
+}
+
+func newMessageBlocks() *messageBlocks {
+	return &messageBlocks{blocks: make(map[uint64]string)}
+}
+
+// forget 丢弃一条消息的缓存块，消息内容在原处变化（如工具调用面板）后调用
+func (b *messageBlocks) forget(id uint64) {
+	if b != nil {
+		delete(b.blocks, id)
+	}
+}
+
+// assignMessageIDs 为新追加到末尾的消息分配递增的 ID，并丢弃已不在消息列表中的缓存块
+func (m *Model) assignMessageIDs() {
+	i := len(m.messages)
+	for i > 0 && m.messages[i-1].ID == 0 {
+		i--
+	}
+	for ; i < len(m.messages); i++ {
+		m.nextMessageID++
+		m.messages[i].ID = m.nextMessageID
+	}
+
+	if m.blocks == nil {
+		return
+	}
+	if len(m.messages) == 0 {
+		clear(m.blocks.blocks)
+		return
+	}
+	// ID 随追加递增，比第一条消息还旧的块属于已清空或截断的历史
+	oldest := m.messages[0].ID
+	for id := range m.blocks.blocks {
+		if id < oldest {
+			delete(m.blocks.blocks, id)
+		}
+	}
+}
+
+// messageBlock 返回一条消息在视口中的文本块（含角色标签和结尾的空行），不显示的消息返回空字符串
+func (m *Model) messageBlock(msg Message) string {
+	if m.blocks == nil || msg.ID == 0 {
+		return m.renderMessage(msg)
+	}
+	if key := m.currentRenderKey(); key != m.blocks.key {
+		m.blocks.key = key
+		clear(m.blocks.blocks)
+	}
+	if block, ok := m.blocks.blocks[msg.ID]; ok {
+		return block
+	}
+	block := m.renderMessage(msg)
+	m.blocks.blocks[msg.ID] = block
+	return block
+}
+
+// renderMessage 渲染一条消息
+func (m *Model) renderMessage(msg Message) string {
+	var sb strings.Builder
+	if msg.Panel != nil {
+		sb.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("13")).Render(i18n.T("ui.role_system")))
+		sb.WriteString(msg.Panel.render(m.viewport.Width))
+		sb.WriteString("\n\n")
+		return sb.String()
+	}
+	if msg.Banner != nil {
+		sb.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("13")).Render(i18n.T("ui.role_system")))
+		sb.WriteString("\n")
+		sb.WriteString(msg.Banner.render(m.viewport.Width))
+		sb.WriteString("\n\n")
+		return sb.String()
+	}
+	if msg.Checklist != nil {
+		sb.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("13")).Render(i18n.T("ui.role_system")))
+		sb.WriteString("\n")
+		sb.WriteString(msg.Checklist.render(m.viewport.Width))
+		sb.WriteString("\n\n")
+		return sb.String()
+	}
+	if msg.Question != nil {
+		sb.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("13")).Render(i18n.T("ui.role_system")))
+		sb.WriteString("\n")
+		sb.WriteString(msg.Question.render(m.viewport.Width))
+		sb.WriteString("\n\n")
+		return sb.String()
+	}
+	switch msg.Role {
+	case "user":
+		sb.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("12")).Render(i18n.T("ui.role_user")))
+		sb.WriteString(msg.Content)
+	case "assistant":
+		sb.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("10")).Render(i18n.T("ui.role_assistant")))
+		sb.WriteString(linkFileRefs(renderMarkdown(msg.Content, m.viewport.Width), m.caps.Hyperlinks))
+	case "system":
+		// 只显示工具调用、工具结果和错误消息，不显示长的系统提示
+		content := msg.Content
+		if len(content) >= 100 &&
+			!strings.Contains(content, "🔧") &&
+			!strings.Contains(content, "✅") &&
+			!strings.Contains(content, "❌") &&
+			!strings.Contains(content, "⚠️") &&
+			!strings.Contains(content, "工具执行") &&
+			!strings.Contains(content, "AI 请求使用工具") {
+			return ""
+		}
+		sb.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("13")).Render(i18n.T("ui.role_system")))
+		sb.WriteString(linkFileRefs(content, m.caps.Hyperlinks))
+	default:
+		return ""
+	}
+	sb.WriteString("\n\n")
+	return sb.String()
+}
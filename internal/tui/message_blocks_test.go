@@ -0,0 +1,45 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestMessageBlocksMemoized(t *testing.T) {
+	m := conversation(goldenModel(t))
+	for i, msg := range m.messages {
+		if msg.ID != uint64(i+1) {
+			t.Fatalf("message %d has ID %d", i, msg.ID)
+		}
+	}
+	if len(m.blocks.blocks) != len(m.messages) {
+		t.Fatalf("cached %d blocks for %d messages", len(m.blocks.blocks), len(m.messages))
+	}
+
+	// 未变化的历史直接使用缓存的块，新消息单独渲染
+	m.blocks.blocks[m.messages[0].ID] = "cached\n\n"
+	m.messages = append(m.messages, Message{Role: "user", Content: "怎么改？"})
+	m.updateViewport()
+	view := m.viewport.View()
+	if !strings.Contains(view, "cached") || !strings.Contains(view, "怎么改？") {
+		t.Fatalf("viewport not composed from cached blocks:\n%s", view)
+	}
+	if m.messages[3].ID != 4 {
+		t.Errorf("new message ID = %d", m.messages[3].ID)
+	}
+
+	// 宽度变化后缓存失效
+	m = drive(t, m, tea.WindowSizeMsg{Width: 60, Height: 24})
+	if strings.Contains(m.viewport.View(), "cached") {
+		t.Error("stale block survived a resize")
+	}
+
+	// 清空的消息不再保留缓存
+	m.messages = m.messages[:0]
+	m.updateViewport()
+	if len(m.blocks.blocks) != 0 {
+		t.Errorf("%d blocks left after clearing messages", len(m.blocks.blocks))
+	}
+}
@@ -0,0 +1,337 @@
+package tui
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/api"
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// selectableMessages 选择模式下可以选中的消息：视口中显示出来的消息
+func (m *Model) selectableMessages() []int {
+	var indexes []int
+	for i := m.displayStart(); i < len(m.messages); i++ {
+		if m.messageBlock(m.messages[i]) != "" {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// startSelection 进入消息选择模式并选中最后一条消息，没有可选的消息时返回 false
+func (m *Model) startSelection() (tea.Cmd, bool) {
+	m.assignMessageIDs()
+	indexes := m.selectableMessages()
+	if len(indexes) == 0 {
+		return nil, false
+	}
+	m.selecting = true
+	m.selectedMessage = indexes[len(indexes)-1]
+	return m.showSelection(), true
+}
+
+// handleSelectionKey 处理选择模式下的按键：↑/↓ 在消息之间移动，字母键对选中的消息执行操作
+func (m *Model) handleSelectionKey(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "up", "k":
+		return m.moveSelection(-1)
+	case "down", "j":
+		return m.moveSelection(1)
+	case "c", "y":
+		return m.copySelected()
+	case "q":
+		return m.quoteSelected()
+	case "d":
+		return m.deleteSelected()
+	case "r":
+		return m.rerunSelected()
+	case "v":
+		return m.viewSelectedJSON()
+	case "esc":
+		return m.exitSelection()
+	}
+	return nil
+}
+
+// moveSelection 选中上一条或下一条消息，越过最后一条时退出选择模式
+func (m *Model) moveSelection(delta int) tea.Cmd {
+	indexes := m.selectableMessages()
+	pos := len(indexes) - 1
+	for i, index := range indexes {
+		if index >= m.selectedMessage {
+			pos = i
+			break
+		}
+	}
+	pos += delta
+	if pos >= len(indexes) {
+		return m.exitSelection()
+	}
+	m.selectedMessage = indexes[max(pos, 0)]
+	return m.showSelection()
+}
+
+// exitSelection 退出选择模式，视口回到底部
+func (m *Model) exitSelection() tea.Cmd {
+	m.selecting = false
+	return m.updateViewport()
+}
+
+// showSelection 重新渲染消息并滚动视口，让选中的消息从顶部开始显示
+func (m *Model) showSelection() tea.Cmd {
+	m.updateViewport()
+	content := m.formatMessages()
+	if i := strings.Index(content, selectionMarker()); i >= 0 {
+		m.viewport.SetYOffset(strings.Count(content[:i], "\n"))
+	}
+	return nil
+}
+
+// selectionMarker 显示在选中消息上方的标记行
+func selectionMarker() string {
+	return lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("14")).Render("▶ "+i18n.T("select.marker")) + "\n"
+}
+
+// selectionNotice 在界面上显示操作结果，保持选择模式和当前的滚动位置
+func (m *Model) selectionNotice(content string) tea.Cmd {
+	m.messages = append(m.messages, Message{Role: "system", Content: content})
+	return m.showSelection()
+}
+
+// oscFlushDelay 控制序列随界面输出后保留的时间，需长于渲染器的一帧
+const oscFlushDelay = 200 * time.Millisecond
+
+// oscFlushedMsg 待输出的控制序列已随界面写出，可以清除
+type oscFlushedMsg struct{}
+
+// copySelected 通过 OSC 52 将选中消息的文本复制到终端的剪贴板，SSH 和 tmux 中同样可用。
+// 终端由 bubbletea 的渲染器控制，序列放在下一帧界面中输出，而不是直接写入 os.Stdout
+func (m *Model) copySelected() tea.Cmd {
+	text := m.messages[m.selectedMessage].Content
+	m.pendingOSC = buildCopySequence(text)
+	return tea.Batch(m.selectionNotice(i18n.T("select.copied", len([]rune(text)))), tea.Tick(oscFlushDelay, func(time.Time) tea.Msg {
+		return oscFlushedMsg{}
+	}))
+}
+
+// buildCopySequence 构造将文本写入剪贴板的 OSC 52 转义序列
+func buildCopySequence(text string) string {
+	osc := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\x07"
+	// tmux 需要通过 DCS passthrough 转发 OSC 序列
+	if os.Getenv("TMUX") != "" {
+		osc = "\x1bPtmux;" + strings.ReplaceAll(osc, "\x1b", "\x1b\x1b") + "\x1b\\"
+	}
+	return osc
+}
+
+// quoteSelected 将选中的消息以引用格式放入输入框并退出选择模式
+func (m *Model) quoteSelected() tea.Cmd {
+	var sb strings.Builder
+	for _, line := range strings.Split(strings.TrimSpace(m.messages[m.selectedMessage].Content), "\n") {
+		sb.WriteString(strings.TrimRight("> "+line, " ") + "\n")
+	}
+	sb.WriteString("\n")
+	m.textarea.SetValue(sb.String())
+	m.textarea.CursorEnd()
+	return m.exitSelection()
+}
+
+// deleteSelected 从界面和发送给模型的上下文中删除选中的消息。
+// 删除工具调用面板时同时删除其中的工具调用和结果，保持调用与结果成对
+func (m *Model) deleteSelected() tea.Cmd {
+	i := m.selectedMessage
+	msg := m.messages[i]
+	indexes := m.contextIndexes(i)
+	removed := len(indexes)
+	if removed > 0 {
+		remove := make(map[int]bool, len(indexes))
+		for _, index := range indexes {
+			remove[index] = true
+		}
+		var calls map[string]bool
+		if msg.Panel != nil {
+			calls = make(map[string]bool, len(msg.Panel.Calls))
+			for _, call := range msg.Panel.Calls {
+				calls[call.CallID] = true
+			}
+		}
+		kept := make([]api.Message, 0, len(m.apiMessages))
+		for index, apiMsg := range m.apiMessages {
+			if !remove[index] {
+				kept = append(kept, apiMsg)
+				continue
+			}
+			if rest, ok := withoutSelected(apiMsg, msg, calls); ok {
+				kept = append(kept, rest)
+			}
+		}
+		m.apiMessages = kept
+	}
+
+	m.messages = append(m.messages[:i:i], m.messages[i+1:]...)
+	m.blocks.forget(msg.ID)
+	notice := i18n.T("select.deleted_display_only")
+	if removed > 0 {
+		notice = i18n.T("select.deleted", removed)
+	}
+	m.messages = append(m.messages, Message{Role: "system", Content: notice})
+
+	// 选中原位置的下一条消息，已是最后一条时选中前一条
+	m.assignMessageIDs()
+	indexes = m.selectableMessages()
+	if len(indexes) == 0 {
+		return m.exitSelection()
+	}
+	m.selectedMessage = indexes[len(indexes)-1]
+	for _, index := range indexes {
+		if index >= i {
+			m.selectedMessage = index
+			break
+		}
+	}
+	return m.showSelection()
+}
+
+// withoutSelected 从 API 消息中去掉界面消息对应的部分：助手消息去掉这段文本，工具调用消息去掉面板中的调用。
+// 剩下的内容为空时返回 false，表示整条删除
+func withoutSelected(apiMsg api.Message, msg Message, calls map[string]bool) (api.Message, bool) {
+	if apiMsg.Role != "assistant" || (msg.Role != "assistant" && calls == nil) {
+		return api.Message{}, false
+	}
+	text := api.MessageText(apiMsg)
+	toolCalls := apiMsg.ToolCalls
+	if calls == nil {
+		text = strings.TrimSpace(strings.Replace(text, msg.Content, "", 1))
+	} else {
+		toolCalls = nil
+		for _, call := range apiMsg.ToolCalls {
+			if !calls[call.ID] {
+				toolCalls = append(toolCalls, call)
+			}
+		}
+	}
+	switch {
+	case len(toolCalls) > 0:
+		return api.AssistantToolCallMessage(text, toolCalls), true
+	case text != "":
+		return api.TextMessage("assistant", text), true
+	}
+	return api.Message{}, false
+}
+
+// rerunSelected 删除选中消息所在这一轮的回复及之后的所有对话，从这一轮的用户消息重新请求
+func (m *Model) rerunSelected() tea.Cmd {
+	user, start, _ := m.apiTurn(m.selectedMessage)
+	if start < 0 {
+		return m.selectionNotice(i18n.T("select.rerun_unavailable"))
+	}
+	m.selecting = false
+	m.messages = m.messages[:user+1]
+	m.apiMessages = m.apiMessages[:start+1]
+	m.messages = append(m.messages, Message{Role: "system", Content: i18n.T("select.rerunning")})
+	m.retryTemperature = nil
+	return m.rerunLastTurn()
+}
+
+// viewSelectedJSON 显示选中消息在上下文中对应的原始 API 消息
+func (m *Model) viewSelectedJSON() tea.Cmd {
+	indexes := m.contextIndexes(m.selectedMessage)
+	if len(indexes) == 0 {
+		return m.selectionNotice(i18n.T("select.not_in_context"))
+	}
+	messages := make([]api.Message, len(indexes))
+	for i, index := range indexes {
+		messages[i] = m.apiMessages[index]
+	}
+	data, err := json.MarshalIndent(messages, "", "  ")
+	if err != nil {
+		return m.selectionNotice(i18n.T("select.json_failed", err))
+	}
+	m.selecting = false
+	m.messages = append(m.messages, Message{Role: "assistant", Content: i18n.T("select.raw_json", len(messages)) + "\n```json\n" + string(data) + "\n```"})
+	return m.updateViewport()
+}
+
+// apiTurn 返回界面消息所在的一轮对话：这一轮的用户消息在 messages 中的下标，
+// 以及这一轮在 apiMessages 中的起止位置。两边按从后往前数第几条用户消息对应，
+// 较早对话的总结不计入，被总结的轮次在界面上仍然显示但找不到对应位置。找不到时起始位置为 -1
+func (m *Model) apiTurn(i int) (int, int, int) {
+	user := -1
+	for j := i; j >= 0; j-- {
+		if m.messages[j].Role == "user" {
+			user = j
+			break
+		}
+	}
+	if user < 0 {
+		return -1, -1, -1
+	}
+	n := 0
+	for _, msg := range m.messages[user:] {
+		if msg.Role == "user" {
+			n++
+		}
+	}
+	end := len(m.apiMessages)
+	for j := len(m.apiMessages) - 1; j >= 0; j-- {
+		if m.apiMessages[j].Role != "user" || isEarlySummary(m.apiMessages[j]) {
+			continue
+		}
+		if n--; n == 0 {
+			return user, j, end
+		}
+		end = j
+	}
+	return user, -1, -1
+}
+
+// contextIndexes 返回界面消息在 apiMessages 中对应的消息下标：用户消息对应这一轮的用户消息，
+// 助手消息对应包含这段文本的助手消息，工具调用面板对应其中的调用和结果。命令输出等只显示在界面上的消息返回 nil
+func (m *Model) contextIndexes(i int) []int {
+	msg := m.messages[i]
+	_, start, end := m.apiTurn(i)
+	if start < 0 {
+		return nil
+	}
+	switch {
+	case msg.Panel != nil:
+		calls := make(map[string]bool, len(msg.Panel.Calls))
+		for _, call := range msg.Panel.Calls {
+			calls[call.CallID] = true
+		}
+		var indexes []int
+		for j := start + 1; j < end; j++ {
+			apiMsg := m.apiMessages[j]
+			if calls[apiMsg.ToolCallID] || hasToolCall(apiMsg, calls) {
+				indexes = append(indexes, j)
+			}
+		}
+		return indexes
+	case msg.Role == "user":
+		return []int{start}
+	case msg.Role == "assistant" && msg.Content != "":
+		for j := end - 1; j > start; j-- {
+			apiMsg := m.apiMessages[j]
+			if apiMsg.Role == "assistant" && strings.Contains(api.MessageText(apiMsg), msg.Content) {
+				return []int{j}
+			}
+		}
+	}
+	return nil
+}
+
+// hasToolCall 助手消息是否包含其中任一工具调用
+func hasToolCall(msg api.Message, calls map[string]bool) bool {
+	for _, call := range msg.ToolCalls {
+		if calls[call.ID] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,180 @@
+package tui
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/api"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// selectionModel 两轮对话，第一轮包含一次工具调用；API 历史开头是较早对话的总结
+func selectionModel(t *testing.T) Model {
+	m := goldenModel(t)
+	m.apiMessages = []api.Message{
+		api.TextMessage("user", "## 之前对话的总结\n\n- 项目使用 Go"),
+		api.TextMessage("user", "main.go 做什么？"),
+		api.AssistantToolCallMessage("先看看文件。", []api.ToolCall{toolCall("c1", "read_file", `{"file_path":"main.go"}`)}),
+		api.ToolResultMessageWithName("c1", "read_file", "package main"),
+		api.TextMessage("assistant", "这是程序入口。"),
+		api.TextMessage("user", "谢谢"),
+		api.TextMessage("assistant", "不客气。"),
+	}
+	m.messages = []Message{
+		{Role: "user", Content: "main.go 做什么？"},
+		{Role: "assistant", Content: "先看看文件。"},
+		{Role: "system", Content: "🔧 read_file main.go", Panel: &toolPanel{Calls: []toolPanelCall{{CallID: "c1", Name: "read_file", Args: `{"file_path":"main.go"}`, Status: toolStatusOK}}}},
+		{Role: "assistant", Content: "这是程序入口。"},
+		{Role: "user", Content: "谢谢"},
+		{Role: "assistant", Content: "不客气。"},
+		{Role: "assistant", Content: "📊 本次会话统计"},
+	}
+	m.updateViewport()
+	return m
+}
+
+func TestContextIndexes(t *testing.T) {
+	m := selectionModel(t)
+	tests := []struct {
+		message int
+		want    []int
+	}{
+		{0, []int{1}},
+		{1, []int{2}},
+		{2, []int{2, 3}},
+		{3, []int{4}},
+		{4, []int{5}},
+		{5, []int{6}},
+		{6, nil},
+	}
+	for _, tt := range tests {
+		if got := m.contextIndexes(tt.message); !equalInts(got, tt.want) {
+			t.Errorf("contextIndexes(%d) = %v, want %v", tt.message, got, tt.want)
+		}
+	}
+}
+
+func TestContextIndexesSummarizedTurn(t *testing.T) {
+	// 被总结的轮次仍显示在界面上，但不应对应到开头的总结消息
+	m := selectionModel(t)
+	m.messages = append([]Message{
+		{Role: "user", Content: "项目用什么语言？"},
+		{Role: "assistant", Content: "Go。"},
+	}, m.messages...)
+	for _, i := range []int{0, 1} {
+		if got := m.contextIndexes(i); got != nil {
+			t.Errorf("contextIndexes(%d) = %v, want nil for a summarized turn", i, got)
+		}
+	}
+	if got := m.contextIndexes(2); !equalInts(got, []int{1}) {
+		t.Errorf("contextIndexes(2) = %v, want [1]", got)
+	}
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestMessageSelectionKeys(t *testing.T) {
+	m := selectionModel(t)
+	up := tea.KeyMsg{Type: tea.KeyUp}
+	key := func(s string) tea.KeyMsg { return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)} }
+
+	m = drive(t, m, up)
+	if !m.selecting || m.selectedMessage != 6 {
+		t.Fatalf("selecting = %v, selected = %d", m.selecting, m.selectedMessage)
+	}
+	if !strings.Contains(m.helpView(), "从上下文删除") {
+		t.Errorf("help = %q", m.helpView())
+	}
+
+	// 引用到输入框后退出选择模式
+	m = drive(t, m, up, up, key("q"))
+	if m.selecting || m.textarea.Value() != "> 谢谢\n\n" {
+		t.Errorf("selecting = %v, textarea = %q", m.selecting, m.textarea.Value())
+	}
+	m.textarea.Reset()
+
+	// 删除工具调用面板：调用和结果一起从上下文中删除，调用之前的文本保留
+	m = drive(t, m, up, up, up, up, up)
+	if m.selectedMessage != 2 {
+		t.Fatalf("selected = %d", m.selectedMessage)
+	}
+	m = drive(t, m, key("d"))
+	if len(m.apiMessages) != 6 || len(m.apiMessages[2].ToolCalls) != 0 || api.MessageText(m.apiMessages[2]) != "先看看文件。" {
+		t.Errorf("apiMessages after deleting panel = %+v", m.apiMessages)
+	}
+	if m.messages[2].Content != "这是程序入口。" || m.selectedMessage != 2 {
+		t.Errorf("messages = %+v, selected = %d", m.messages, m.selectedMessage)
+	}
+
+	// 删除助手消息的文本
+	m = drive(t, m, up, key("d"))
+	if len(m.apiMessages) != 5 || api.MessageText(m.apiMessages[2]) != "这是程序入口。" {
+		t.Errorf("apiMessages after deleting text = %+v", m.apiMessages)
+	}
+
+	// 查看原始 JSON
+	m = drive(t, m, key("v"))
+	last := m.messages[len(m.messages)-1].Content
+	if m.selecting || !strings.Contains(last, `"content": "这是程序入口。"`) {
+		t.Errorf("selecting = %v, raw JSON = %q", m.selecting, last)
+	}
+}
+
+func TestRerunSelected(t *testing.T) {
+	m := selectionModel(t)
+	// 取消请求上下文，重新请求立即结束，不访问网络
+	m.cancel()
+	m = drive(t, m, tea.KeyMsg{Type: tea.KeyUp}, tea.KeyMsg{Type: tea.KeyUp}, tea.KeyMsg{Type: tea.KeyUp}, tea.KeyMsg{Type: tea.KeyUp})
+	if m.selectedMessage != 3 {
+		t.Fatalf("selected = %d", m.selectedMessage)
+	}
+	m = drive(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")})
+	if m.selecting || !m.thinking {
+		t.Fatalf("selecting = %v, thinking = %v", m.selecting, m.thinking)
+	}
+	if len(m.apiMessages) != 2 || api.MessageText(m.apiMessages[1]) != "main.go 做什么？" {
+		t.Errorf("apiMessages = %+v", m.apiMessages)
+	}
+	if len(m.messages) != 2 || m.messages[0].Content != "main.go 做什么？" {
+		t.Errorf("messages = %+v", m.messages)
+	}
+}
+
+func TestBuildCopySequence(t *testing.T) {
+	t.Setenv("TMUX", "")
+	want := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte("你好")) + "\x07"
+	if got := buildCopySequence("你好"); got != want {
+		t.Errorf("sequence = %q, want %q", got, want)
+	}
+	t.Setenv("TMUX", "/tmp/tmux-1000/default,1,0")
+	if got := buildCopySequence("x"); !strings.HasPrefix(got, "\x1bPtmux;\x1b\x1b]52;c;") || !strings.HasSuffix(got, "\x1b\\") {
+		t.Errorf("tmux sequence = %q", got)
+	}
+}
+
+func TestCopySelectedRendersSequence(t *testing.T) {
+	t.Setenv("TMUX", "")
+	m := selectionModel(t)
+	m = drive(t, m, tea.KeyMsg{Type: tea.KeyUp}, tea.KeyMsg{Type: tea.KeyUp}, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("c")})
+
+	// 复制序列随下一帧界面由渲染器输出，写出后清除
+	seq := buildCopySequence("不客气。")
+	if view := m.View(); !strings.HasSuffix(view, seq) {
+		t.Fatalf("view does not end with the copy sequence: %q", view[max(0, len(view)-80):])
+	}
+	m = drive(t, m, oscFlushedMsg{})
+	if strings.Contains(m.View(), "\x1b]52;") {
+		t.Error("copy sequence still rendered after flush")
+	}
+}
@@ -3,12 +3,17 @@ package tui
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
 	"strings"
 	"time"
 
 	"github.com/Zacy-Sokach/PolyAgent/internal/api"
+	"github.com/Zacy-Sokach/PolyAgent/internal/config"
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
 	"github.com/Zacy-Sokach/PolyAgent/internal/mcp"
+	"github.com/Zacy-Sokach/PolyAgent/internal/telemetry"
 	"github.com/Zacy-Sokach/PolyAgent/internal/update"
 	"github.com/Zacy-Sokach/PolyAgent/internal/utils"
 	"github.com/charmbracelet/bubbles/textarea"
@@ -17,6 +22,9 @@ import (
 	"github.com/charmbracelet/lipgloss"
 )
 
+// maxStreamStallRetries 流式响应停滞后的最大自动重试次数
+const maxStreamStallRetries = 2
+
 // Version 是当前的 PolyAgent 版本，由 main 包设置
 var Version string
 
@@ -45,9 +53,24 @@ type StreamErrorMsg struct {
 	Error error
 }
 
+// StreamUsageMsg 流式响应报告的 token 用量
+type StreamUsageMsg struct {
+	Usage *api.Usage
+}
+
+
 type Message struct {
+	ID      uint64 // 界面内唯一，追加后由 assignMessageIDs 分配，用于缓存渲染结果
 	Role    string
 	Content string
+	// Panel 非空时消息是一组工具调用，按面板显示，Content 为其纯文本形式
+	Panel *toolPanel
+	// Banner 非空时消息是一次请求失败，按错误横幅显示，Content 为其纯文本形式
+	Banner *errorBanner
+	// Question 非空时消息是模型向用户提出的问题，醒目显示，Content 为其纯文本形式
+	Question *userQuestion
+	// Checklist 非空时消息是计划模式生成的计划清单，Content 为其纯文本形式
+	Checklist *planChecklist
 }
 
 type Task struct {
@@ -66,6 +89,10 @@ type PlanDoc struct {
 // ToolManager wraps MCP ToolRegistry for TUI usage
 type ToolManager struct {
 	registry *mcp.ToolRegistry
+	// 流式响应期间提前执行的只读调用
+	prefetch toolPrefetch
+	// 调用执行时目标路径的状态，用于判断之前的结果能否复用
+	stamps toolStamps
 }
 
 // NewToolManager creates a new ToolManager with default tools
@@ -82,6 +109,92 @@ func NewToolManagerWithRegistry(registry *mcp.ToolRegistry) *ToolManager {
 	}
 }
 
+// ReadOnly reports whether only read-only tools are available (untrusted workspace)
+func (tm *ToolManager) ReadOnly() bool {
+	return tm.registry.ReadOnly()
+}
+
+// SetReadOnly restricts the registry to read-only tools
+func (tm *ToolManager) SetReadOnly(readOnly bool) {
+	tm.registry.SetReadOnly(readOnly)
+}
+
+// AddRoot allows the file tools to access another directory for this session
+func (tm *ToolManager) AddRoot(dir string) (string, error) {
+	return tm.registry.AddRoot(dir)
+}
+
+// Roots returns the directories the file tools may access, the project directory first
+func (tm *ToolManager) Roots() []string {
+	return tm.registry.Roots()
+}
+
+// SetScope restricts searches to dir, an empty dir removes the restriction
+func (tm *ToolManager) SetScope(dir string) (string, error) {
+	return tm.registry.SetScope(dir)
+}
+
+// Scope returns the directory searches are restricted to, or "" when unrestricted
+func (tm *ToolManager) Scope() string {
+	return tm.registry.Scope()
+}
+
+// SessionStats returns the tool usage statistics of this session
+func (tm *ToolManager) SessionStats() mcp.SessionStats {
+	return tm.registry.SessionStats()
+}
+
+// AuditLog returns the tool call audit log, or nil when auditing is disabled
+func (tm *ToolManager) AuditLog() *mcp.AuditLog {
+	return tm.registry.AuditLog()
+}
+
+// ReloadAPIKeys applies API keys from a reloaded config to the registered tools
+func (tm *ToolManager) ReloadAPIKeys(cfg *config.Config) []string {
+	return tm.registry.ReloadAPIKeys(cfg)
+}
+
+// WriteQuota returns the session write quota, or nil when writes are unlimited
+func (tm *ToolManager) WriteQuota() *mcp.WriteQuota {
+	return tm.registry.WriteQuota()
+}
+
+// Trash returns the session trash used by delete_file, or nil when deletions are permanent
+func (tm *ToolManager) Trash() *mcp.Trash {
+	return tm.registry.Trash()
+}
+
+// PreviewGlobalReplace 计算 global_replace 会修改的文件，工具未注册时返回 nil
+func (tm *ToolManager) PreviewGlobalReplace(args map[string]interface{}) (*mcp.ReplaceManifest, error) {
+	handler, ok := tm.registry.GetTool("global_replace")
+	if !ok {
+		return nil, nil
+	}
+	tool, ok := handler.(*mcp.GlobalReplaceTool)
+	if !ok {
+		return nil, nil
+	}
+	return tool.Preview(args)
+}
+
+// UseEditor 让工具的文件写入与界面的编辑会话保持同步，与 /save、会话回退共用同一份操作日志
+func (tm *ToolManager) UseEditor(editor *utils.Editor) {
+	tm.registry.SetEditor(editor)
+}
+
+// PreviewReplacements 返回 apply_replacements 将应用的匹配，工具未注册时返回 nil
+func (tm *ToolManager) PreviewReplacements(ids []string) ([]mcp.ReplaceCandidate, error) {
+	handler, ok := tm.registry.GetTool("find_and_replace")
+	if !ok {
+		return nil, nil
+	}
+	tool, ok := handler.(*mcp.FindAndReplaceTool)
+	if !ok {
+		return nil, nil
+	}
+	return tool.PreviewSelection(ids)
+}
+
 // GetToolsForAPI returns tools in API format
 func (tm *ToolManager) GetToolsForAPI() []api.Tool {
 	mcpTools := tm.registry.ListTools()
@@ -105,44 +218,71 @@ func (tm *ToolManager) GetToolsForAPI() []api.Tool {
 }
 
 // HandleToolCalls executes tool calls and returns API messages
+// 流式响应期间已提前执行的只读调用（见 Prefetch）等待并使用其结果，不重复执行
 func (tm *ToolManager) HandleToolCalls(toolCalls []api.ToolCall) ([]api.Message, error) {
 	var messages []api.Message
 	
 	for _, call := range toolCalls {
-		// Convert json.RawMessage to map[string]interface{}
-		var args map[string]interface{}
-		if err := json.Unmarshal(call.Function.Arguments, &args); err != nil {
-			// If unmarshaling fails, try to use as string
-			args = map[string]interface{}{
-				"input": string(call.Function.Arguments),
-			}
-		}
-		
-		// Convert to MCP request
-		mcpRequest := mcp.CallToolRequest{
-			Name:      call.Function.Name,
-			Arguments: args,
-		}
-		
-		// Execute via MCP registry
-		result, err := tm.registry.HandleCallTool(mcpRequest)
-		if err != nil {
-			return nil, err
-		}
-		
-		// Convert to API message
-		if len(result.Content) > 0 {
-			content := result.Content[0].Text
-			messages = append(messages, api.ToolResultMessage(call.ID, content))
+		if result, ok := tm.prefetch.take(call.ID, call.Function.Arguments); ok {
+			messages = append(messages, result)
+			continue
 		}
+		messages = append(messages, tm.callTool(call))
 	}
 	
 	return messages, nil
 }
 
+// callTool 执行一次工具调用并返回工具结果消息
+func (tm *ToolManager) callTool(call api.ToolCall) api.Message {
+	// Convert json.RawMessage to map[string]interface{}
+	var args map[string]interface{}
+	if err := json.Unmarshal(call.Function.Arguments, &args); err != nil {
+		// If unmarshaling fails, try to use as string
+		args = map[string]interface{}{
+			"input": string(call.Function.Arguments),
+		}
+	}
+	
+	// Convert to MCP request
+	mcpRequest := mcp.CallToolRequest{
+		Name:      call.Function.Name,
+		Arguments: args,
+	}
+	
+	// Execute via MCP registry
+	// 失败的调用作为结构化错误结果（IsError）返回给模型，不影响同一批次的其他调用
+	telemetry.RecordTool(call.Function.Name)
+	bus := GetGlobalEventBus()
+	called := NewToolCalledEvent(call.Function.Name, args)
+	called.CallID = call.ID
+	bus.Publish(called)
+	tm.stamps.record(call)
+	started := time.Now()
+	result, err := tm.registry.HandleCallTool(mcpRequest)
+	if err != nil {
+		failed := NewToolFailedEvent(call.Function.Name, err, time.Since(started))
+		failed.CallID = call.ID
+		bus.Publish(failed)
+		toolErr := mcp.NewToolError(err)
+		telemetry.RecordError("tool." + toolErr.Type)
+		return api.ToolResultMessage(call.ID, toolErr.Result())
+	}
+
+	// Convert to API message；每个调用都必须有结果，否则下一次请求会被 API 拒绝
+	content := i18n.T("tool.empty_result")
+	if result != nil && len(result.Content) > 0 {
+		content = result.Content[0].Text
+	}
+	completed := NewToolCompletedEvent(call.Function.Name, result, time.Since(started))
+	completed.CallID = call.ID
+	bus.Publish(completed)
+	return api.ToolResultMessage(call.ID, content)
+}
+
 // FormatToolCallForDisplay formats tool call for UI display
 func (tm *ToolManager) FormatToolCallForDisplay(call api.ToolCall) string {
-	return fmt.Sprintf("🔧 调用工具: %s\n参数: %v", call.Function.Name, call.Function.Arguments)
+	return i18n.T("tool.call_display", call.Function.Name, call.Function.Arguments)
 }
 
 type Model struct {
@@ -154,28 +294,75 @@ type Model struct {
 	thinking         bool
 	currentResp      string
 	currentThink     string
-	streamCh         <-chan string
-	reasoningCh      <-chan string
-	toolCallCh       <-chan []api.ToolCall
-	streamErrCh      <-chan error
+	// streamEvents 当前流式请求的事件，按到达顺序读取
+	streamEvents <-chan api.StreamEvent
+	// lastUsage 最近一次请求报告的 token 用量
+	lastUsage *api.Usage
 	editor           *utils.Editor
 	tasks            []Task
 	planDoc          PlanDoc
 	currentTaskIndex int
 	pendingToolCalls []api.ToolCall
+	// prefetchBlocked 本次响应已出现不能提前执行的工具调用，见 prefetchToolCalls
+	prefetchBlocked bool
+
+	// pendingOSC 随下一帧界面输出的终端控制序列（如复制到剪贴板），见 copySelected
+	pendingOSC string
+	// toolProgress 正在执行的工具报告的进度
+	toolProgress *mcp.Progress
 	toolManager      *ToolManager
 	apiMessages      []api.Message
 	commandParser    *CommandParser
-	maxMessages      int // 最大消息数量限制
+	maxMessages      int // 发送给模型的默认历史消息数上限
 	renderedLines    []string // 缓存已渲染的行，避免重复渲染
+	renderedKey      renderKey // 渲染缓存对应的终端宽度和颜色能力
+	caps             terminalCaps // 当前终端的颜色和超链接支持
+	blocks           *messageBlocks // 按消息 ID 缓存的渲染结果
+	nextMessageID    uint64         // 上一次分配的消息 ID
+	spinnerFrame     int            // 状态栏等待动画的当前帧
+	verbose          bool           // 详细模式：展开工具调用的参数和结果，完整显示推理过程
+	lastAPIError     error          // 最近一次请求失败的错误，/debug error 显示其原始响应
+	health           *providerHealth // 启动检查的结果，未检查时为 nil
 	lastRenderedHash uint64   // 上次渲染的内容哈希，用于检测变化
 	ctx              context.Context // 用于取消操作的context
 	cancel           context.CancelFunc // 取消函数
+	config           *config.Config     // 用户配置（可能为 nil）
+	focused          bool               // 终端窗口是否处于焦点
+	turnStartedAt    time.Time          // 当前轮次开始时间，用于完成提醒
+	nextToolChoice   *api.ToolChoice    // 仅作用于下一次请求的工具调用策略
+	stallRetries     int                // 当前请求因流式空闲超时已重试的次数
+	contextRetried   bool               // 本轮是否已因上下文过长缩减上下文重试过
+	sessionTitle     string             // 会话标题，用于历史记录和终端窗口标题
+	titleManual      bool               // 标题是否由 /rename 手动设置
+	titleRequested   bool               // 是否已请求自动生成标题
+	lastAutosave     string             // 上次自动保存的内容签名
+	awaitingApproval []approvalRequest      // 等待用户确认的工具调用
+	deniedToolCalls  map[string]bool        // 用户拒绝执行的工具调用 ID
+	pendingQuestions []*userQuestion        // 模型通过 ask_user 提出、等待用户回答的问题，第一个为当前问题
+	questionAnswers  map[string]string      // 已回答问题的工具结果，按调用 ID 记录
+	planMode         bool                   // 本轮是否为 /plan 制定计划，只开放只读工具
+	planTask         string                 // 正在制定计划的任务
+	planChecklist    *planChecklist         // 等待用户审阅的计划清单
+	selecting        bool                   // 是否处于消息选择模式
+	selectedMessage  int                    // 选择模式下选中的消息在 messages 中的下标
+	configModTime    time.Time              // 配置文件上次加载时的修改时间
+	modelOverride    string                 // 命令行 --model 指定的模型，优先于配置文件
+	retryTemperature *float64               // /retry 指定的采样温度，作用于重新生成的这一轮
+	toolsRunning     bool                   // 工具调用是否正在执行
+	pinnedFiles      []string               // /pin 固定的文件（绝对路径），/new keep-context 时带入新会话
+	contextTokens    int                    // 下一次请求将发送的历史的估算 token 数，显示在状态栏
+	budget           *costTracker           // 本次会话和当天的估算费用
+	stats            *sessionStats          // 本次会话的请求和 token 统计（/stats）
+	awaitingBudgetConfirm bool              // 超出费用上限，等待用户确认是否仍然发送
+	pendingRoot           string            // /add-root 等待用户确认添加的目录
+	turnCheckpoint        *utils.GitCheckpoint // 本轮开始时的工作区检查点，不在 git 仓库中时为 nil
+	lastChanges           *turnChanges      // 上一轮修改的文件，用于 /diff 和 /revert
+	changeKeys            bool              // 修改汇总刚显示，数字键用于查看差异
 }
 
 func InitialModel(apiKey string, toolManager *ToolManager) Model {
 	ta := textarea.New()
-	ta.Placeholder = "输入你的问题..."
+	ta.Placeholder = i18n.T("ui.placeholder")
 	ta.Focus()
 	ta.CharLimit = 0
 	ta.SetWidth(80)
@@ -184,24 +371,26 @@ func InitialModel(apiKey string, toolManager *ToolManager) Model {
 	ta.KeyMap.InsertNewline.SetEnabled(false)
 
 	vp := viewport.New(80, 20)
-	vp.SetContent("欢迎使用 PolyAgent - 类似 Claude Code 的 Vibe Coding 工具\n\n")
+	vp.SetContent(i18n.T("ui.welcome"))
 
 	editor := utils.NewEditor()
 	// 安全地初始化编辑器，捕获可能的panic
 	func() {
 		defer func() {
 			if r := recover(); r != nil {
-				vp.SetContent(fmt.Sprintf("编辑器初始化时发生错误: %v\n\n", r))
+				vp.SetContent(i18n.T("editor.init_panic", r))
 			}
 		}()
 		if err := editor.StartSession(); err != nil {
-			vp.SetContent(fmt.Sprintf("初始化编辑会话失败: %v\n\n", err))
+			vp.SetContent(i18n.T("editor.init_failed", err))
 		}
 	}()
 
 	if toolManager == nil {
 		toolManager = NewToolManager()
 	}
+	subscribeToolActivity()
+	toolManager.UseEditor(editor)
 	commandParser := NewCommandParser()
 
 	// 创建context用于取消操作
@@ -218,14 +407,38 @@ func InitialModel(apiKey string, toolManager *ToolManager) Model {
 		currentTaskIndex: -1,
 		toolManager:      toolManager,
 		commandParser:    commandParser,
-		maxMessages:      50,  // 限制最多显示50条消息
+		maxMessages:      50,  // 默认最多发送50条历史消息
 		ctx:              ctx,
 		cancel:           cancel,
+		focused:          true,
+		budget:           newCostTracker(),
+		stats:            newSessionStats(),
+		caps:             detectTerminalCaps(),
+		blocks:           newMessageBlocks(),
+	}
+}
+
+// InitialModelWithConfig 使用完整配置创建模型
+func InitialModelWithConfig(cfg *config.Config, toolManager *ToolManager) Model {
+	m := InitialModel(cfg.APIKey, toolManager)
+	m.config = cfg
+	telemetry.SetEnabled(cfg.Telemetry)
+	// 记录启动时配置文件的修改时间，之后的修改由 configWatchTickCmd 检测并重新加载
+	m.configModTime, _ = config.ConfigModTime()
+	m.verbose = cfg.Verbose
+	if m.autosaveInterval() > 0 {
+		m.restoreAutosave()
 	}
+	m.showEditRecovery()
+	return m
 }
 
 func (m Model) Init() tea.Cmd {
-	return textarea.Blink
+	cmds := []tea.Cmd{textarea.Blink, m.autosaveTickCmd(), m.configWatchTickCmd()}
+	if m.sessionTitle != "" {
+		cmds = append(cmds, tea.SetWindowTitle(windowTitle(m.sessionTitle)))
+	}
+	return tea.Batch(cmds...)
 }
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -236,12 +449,42 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		// 等待命令审批时只响应 y/n（Ctrl+C 仍可退出）
+		if len(m.awaitingApproval) > 0 && msg.Type != tea.KeyCtrlC {
+			return m, m.handleApprovalKey(msg)
+		}
+		if len(m.pendingQuestions) > 0 && msg.Type != tea.KeyCtrlC {
+			if cmd, handled := m.handleQuestionKey(msg); handled {
+				return m, cmd
+			}
+		}
+		if m.planChecklist != nil && !m.thinking && msg.Type != tea.KeyCtrlC {
+			if cmd, handled := m.handlePlanKey(msg); handled {
+				return m, cmd
+			}
+		}
+		if m.awaitingBudgetConfirm && msg.Type != tea.KeyCtrlC {
+			return m, m.handleBudgetConfirmKey(msg)
+		}
+		if m.pendingRoot != "" && msg.Type != tea.KeyCtrlC {
+			return m, m.handleAddRootKey(msg)
+		}
+		if m.selecting && msg.Type != tea.KeyCtrlC {
+			return m, m.handleSelectionKey(msg)
+		}
+		if m.changeKeys && !m.thinking && m.textarea.Value() == "" {
+			if cmd, handled := m.handleChangeKey(msg); handled {
+				return m, cmd
+			}
+		}
 		switch msg.Type {
 		case tea.KeyCtrlC:
 			m.saveHistory()
 			if m.editor != nil {
 				m.editor.EndSession()
 			}
+			// 正常退出，不再需要恢复
+			utils.ClearAutosave()
 			return m, tea.Quit
 		case tea.KeyEnter:
 			if !m.thinking {
@@ -250,24 +493,37 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					// 检查是否是命令
 					if cmd := m.commandParser.Parse(input); cmd != nil {
 						m.textarea.Reset()
-						return m, m.handleCommand(cmd)
+						return m, tea.Batch(m.handleCommand(cmd), statusTickCmd())
 					}
 
-					// 不是命令，发送给AI
-					m.messages = append(m.messages, Message{Role: "user", Content: input})
-					m.textarea.Reset()
-					m.thinking = true
-					m.currentResp = ""
-					return m, tea.Batch(
-						m.updateViewport(),
-						m.startStream(input),
-					)
+					// 不是命令，超出费用上限时先确认，再发送给AI
+					if cmd, ok := m.confirmOverBudget(); ok {
+						return m, cmd
+					}
+					return m, m.sendInput(input)
+				}
+			}
+		case tea.KeyUp:
+			// 输入框为空时按 ↑ 进入消息选择模式
+			if !m.thinking && m.textarea.Value() == "" {
+				if cmd, ok := m.startSelection(); ok {
+					return m, cmd
 				}
 			}
 		case tea.KeyCtrlS:
 			if m.editor != nil {
 				return m, m.saveChangesToDisk()
 			}
+		case tea.KeyCtrlR:
+			if m.toggleToolPanel() {
+				m.redraw()
+			}
+			return m, nil
+		case tea.KeyCtrlO:
+			// 思考期间不挂起界面
+			if !m.thinking {
+				return m, m.handleOpenLatestFileRef()
+			}
 		case tea.KeyEsc:
 			if m.thinking {
 				m.thinking = false
@@ -277,42 +533,137 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 				// 重新创建context以便下次使用
 				m.ctx, m.cancel = context.WithCancel(context.Background())
+				m.keepInterruptedResponse()
+				m.updateViewport()
+			}
+		}
+
+	case oscFlushedMsg:
+		m.pendingOSC = ""
+		return m, nil
+
+	case tea.FocusMsg:
+		m.focused = true
+		if m.refreshTerminalCaps() {
+			m.redraw()
+		}
+
+	case statusTickMsg:
+		// 思考期间定期刷新状态栏中的等待动画、阶段和已用时间
+		if m.thinking {
+			m.spinnerFrame++
+			if m.toolsRunning && m.refreshToolPanel() {
+				m.updateViewport()
 			}
+			return m, statusTickCmd()
 		}
+		return m, nil
+
+	case tea.BlurMsg:
+		m.focused = false
+
+	case autosaveTickMsg:
+		m.autosave()
+		return m, m.autosaveTickCmd()
+
+	case configWatchTickMsg:
+		return m, m.handleConfigWatchTick()
+
+	case fileOpenedMsg:
+		return m, m.handleFileOpened(msg)
+
+	case visualReadyMsg:
+		return m, m.handleVisualReady(msg)
+
+	case newSessionSeedMsg:
+		return m, m.handleNewSessionSeed(msg)
+
+	case contextShrunkMsg:
+		return m, m.handleContextShrunk(msg)
+
+	case healthProbeMsg:
+		return m, m.handleHealthProbe(msg)
+
+	case ShutdownMsg:
+		// 被信号终止时保留编辑历史和会话快照，下次启动时恢复
+		m.saveHistory()
+		m.autosave()
+		return m, tea.Quit
+
+	case sessionTitleMsg:
+		// 手动命名优先于自动生成的标题
+		if m.titleManual || m.sessionTitle != "" {
+			return m, nil
+		}
+		return m, m.setSessionTitle(msg.Title, false)
 
 	case tea.WindowSizeMsg:
 		if !m.ready {
-			m.viewport = viewport.New(msg.Width, msg.Height-4)
+			// 调整初始视口的大小而不是重新创建，保留其中的欢迎信息
+			m.viewport.Width = msg.Width
+			m.viewport.Height = msg.Height - 4
 			m.viewport.YPosition = 0
 			m.ready = true
+			// 显示恢复的会话
+			if len(m.messages) > 0 {
+				m.updateViewport()
+			}
+			// 界面显示后在后台检查 API 是否可用
+			cmds = append(cmds, m.startHealthProbe())
 		} else {
+			resized := m.viewport.Width != msg.Width
 			m.viewport.Width = msg.Width
 			m.viewport.Height = msg.Height - 4
+			// 调整大小可能来自 tmux 重新连接，终端能力也可能随之改变
+			if m.refreshTerminalCaps() || resized {
+				m.redraw()
+			}
 		}
 		m.textarea.SetWidth(msg.Width)
 
 	case CheckStreamMsg:
+		if !m.thinking {
+			// 已取消的请求
+			return m, nil
+		}
 		// 流结束了，更新历史消息缓存
 		if len(m.pendingToolCalls) > 0 {
+			// 最后一次工具调用之后的文本按顺序显示在工具结果之前
+			m.appendTrailingText(m.flushStreamedText())
+			// 模型向用户提出的问题先等待回答
+			if questions := m.pendingUserQuestions(); len(questions) > 0 {
+				return m, m.askQuestions(questions)
+			}
+			// 需要确认的命令和大规模删除先等待用户审批
+			if approvals := m.pendingApprovals(); len(approvals) > 0 {
+				return m, m.requestApproval(approvals)
+			}
 			// 如果有挂起的工具调用，不要停止思考，执行工具
 			return m, m.executePendingTools()
 		}
 
 		m.thinking = false
+		m.markHealthy()
+		notifyCmd := tea.Batch(m.completionNotifyCmd(i18n.T("notify.response_done")), m.turnChangesCmd())
 		// 将累积的响应保存到消息历史中
 		if m.currentResp != "" {
+			// 显示和保存之前运行后处理器（格式化代码块等）
+			m.currentResp = postProcessAssistant(m.currentResp)
 			m.messages = append(m.messages, Message{Role: "assistant", Content: m.currentResp})
 			// 同时也保存到API历史
 			m.apiMessages = append(m.apiMessages, api.TextMessage("assistant", m.currentResp))
+			// 计划模式的回复显示为可操作的计划清单
+			m.finishPlanTurn(m.currentResp)
 
 			// 更新渲染缓存
 			m.updateRenderedLinesCache()
 
+			titleCmd := m.maybeGenerateSessionTitle(m.currentResp)
 			m.currentResp = ""
 			m.currentThink = ""
-			return m, m.updateViewport()
+			return m, tea.Batch(m.updateViewport(), notifyCmd, titleCmd)
 		}
-		return m, nil
+		return m, notifyCmd
 
 	case ResponseMsg:
 		m.thinking = false
@@ -322,6 +673,9 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, m.updateViewport()
 
 	case StreamChunkMsg:
+		if !m.thinking {
+			return m, nil
+		}
 		if msg.Reasoning != "" {
 			m.currentThink += msg.Reasoning
 		} else {
@@ -362,27 +716,42 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, m.checkStream()
 
 	case ToolCallMsg:
-		// 收集工具调用，等待流结束后执行
-		m.pendingToolCalls = append(m.pendingToolCalls, msg.ToolCalls...)
+		if !m.thinking {
+			return m, nil
+		}
+		// 工具调用之前的文本先作为一段回复显示，保持与流中的顺序一致
+		text := m.flushStreamedText()
 
-		// 将工具调用添加到API历史
-		m.apiMessages = append(m.apiMessages, api.ToolCallMessage(msg.ToolCalls))
+		// 将工具调用添加到API历史（需在记录挂起调用之前，以判断是否与本轮之前的调用合并）
+		m.appendAssistantToolCalls(text, msg.ToolCalls)
 
-		// 显示工具调用信息
-		var toolCallDisplay []string
-		for _, toolCall := range msg.ToolCalls {
-			toolCallDisplay = append(toolCallDisplay, m.toolManager.FormatToolCallForDisplay(toolCall))
-		}
+		// 收集工具调用，等待流结束后执行；只读的调用提前在后台执行
+		m.pendingToolCalls = append(m.pendingToolCalls, msg.ToolCalls...)
+		m.prefetchToolCalls(msg.ToolCalls)
 
-		display := "🔧 AI 请求使用工具:\n" + strings.Join(toolCallDisplay, "\n\n")
-		m.messages = append(m.messages, Message{Role: "system", Content: display})
+		// 在工具调用面板中显示，同一批连续的调用合并到一个面板
+		m.addToolCalls(msg.ToolCalls)
 
 		// 关键修复：工具调用后继续读取流
 		return m, tea.Batch(m.updateViewport(), m.checkStream())
 
+	case StreamUsageMsg:
+		if !m.thinking {
+			return m, nil
+		}
+		m.lastUsage = msg.Usage
+		return m, m.checkStream()
+
+	case ToolProgressMsg:
+		m.handleToolProgress(msg)
+		return m, nil
+
 	case ToolResultMsg:
-		// 显示工具执行结果
-		m.messages = append(m.messages, Message{Role: "system", Content: msg.DisplayContent})
+		m.toolProgress = nil
+		// 执行结果填入工具调用面板，无法对应到调用时（如整批执行失败）单独显示
+		if !m.finishToolPanel(msg.ResultMessages) {
+			m.messages = append(m.messages, Message{Role: "system", Content: msg.DisplayContent})
+		}
 
 		// 将工具结果添加到API历史
 		for _, resultMsg := range msg.ResultMessages {
@@ -391,15 +760,43 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		// 清空挂起的工具调用
 		m.pendingToolCalls = nil
+		m.toolsRunning = false
+		if !m.thinking {
+			// 执行期间已取消，保留结果但不再继续请求
+			return m, m.updateViewport()
+		}
 
 		// 继续与AI对话（发送工具结果）
 		return m, tea.Batch(m.updateViewport(), m.continueStream())
 
 	case StreamErrorMsg:
+		if !m.thinking {
+			// 取消请求导致的错误不再提示
+			return m, nil
+		}
+		telemetry.RecordError(apiErrorClass(msg.Error))
+		// 流式响应停滞且尚未收到工具调用时，丢弃部分输出并重新请求
+		if errors.Is(msg.Error, api.ErrStreamStalled) && m.stallRetries < maxStreamStallRetries && len(m.pendingToolCalls) == 0 {
+			m.stallRetries++
+			m.stats.addRetry()
+			m.messages = append(m.messages, Message{Role: "system", Content: i18n.T("error.stream_stalled_retry", m.stallRetries, maxStreamStallRetries)})
+			return m, tea.Batch(m.updateViewport(), m.retryStream())
+		}
+		m.stallRetries = 0
+		// 上下文过长时缩减较早的内容，每轮自动重试一次
+		if isContextTooLong(msg.Error) && !m.contextRetried && len(m.pendingToolCalls) == 0 {
+			if cmd := m.shrinkContext(); cmd != nil {
+				return m, cmd
+			}
+		}
 		m.thinking = false
-		errorMsg := fmt.Sprintf("❌ API Error: %v", msg.Error)
-		m.messages = append(m.messages, Message{Role: "system", Content: errorMsg})
-		return m, m.updateViewport()
+		m.lastAPIError = msg.Error
+		banner := newErrorBanner(msg.Error)
+		m.messages = append(m.messages, Message{Role: "system", Content: banner.text(), Banner: banner})
+		return m, tea.Batch(m.updateViewport(), m.completionNotifyCmd(i18n.T("notify.request_failed")), m.turnChangesCmd())
+
+	case turnChangesMsg:
+		return m, m.handleTurnChanges(msg)
 	}
 
 	m.textarea, cmd = m.textarea.Update(msg)
@@ -413,46 +810,58 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 func (m *Model) saveHistory() {
 	if len(m.messages) > 0 {
-		historyMessages := make([]utils.Message, len(m.messages))
-		for i, msg := range m.messages {
-			historyMessages[i] = utils.Message{
-				Role:    msg.Role,
-				Content: msg.Content,
-			}
-		}
-		utils.SaveHistory(historyMessages)
+		utils.SaveHistoryEntry(utils.HistoryEntry{Title: m.sessionTitle, Messages: m.historyMessages(), Stats: m.sessionStatsSnapshot()})
 	}
 }
 
 func (m Model) saveChangesToDisk() tea.Cmd {
 	return func() tea.Msg {
 		if m.editor == nil {
-			return ResponseMsg{Content: "编辑系统未初始化"}
+			return ResponseMsg{Content: i18n.T("editor.not_initialized")}
 		}
 
-		if err := m.editor.SaveToDisk(); err != nil {
-			return ResponseMsg{Content: "保存失败: " + err.Error()}
+		saved, err := m.editor.SaveToDisk()
+		if err != nil {
+			if len(saved) > 0 {
+				return ResponseMsg{Content: i18n.T("editor.save_partial", formatSavedFiles(saved), err)}
+			}
+			return ResponseMsg{Content: i18n.T("editor.save_failed", err)}
+		}
+		if len(saved) == 0 {
+			return ResponseMsg{Content: i18n.T("editor.nothing_to_save")}
 		}
+		return ResponseMsg{Content: i18n.T("editor.saved_files", len(saved), formatSavedFiles(saved))}
+	}
+}
 
-		edits := m.editor.GetCurrentEdits()
-		return ResponseMsg{Content: fmt.Sprintf("已保存 %d 个修改到磁盘", len(edits))}
+// savedFilesListLimit 保存结果中最多列出的文件数
+const savedFilesListLimit = 10
+
+// formatSavedFiles 列出保存的文件，过多时只列出前几个
+func formatSavedFiles(paths []string) string {
+	if len(paths) <= savedFilesListLimit {
+		return strings.Join(paths, ", ")
 	}
+	return fmt.Sprintf("%s … (+%d)", strings.Join(paths[:savedFilesListLimit], ", "), len(paths)-savedFilesListLimit)
 }
 
 func (m Model) View() string {
 	if !m.ready {
-		return "初始化中..."
+		return i18n.T("ui.initializing")
 	}
 
+	// 控制序列不占位置，放在最后一行末尾，由渲染器与界面一起写出
 	return fmt.Sprintf(
 		"%s\n\n%s\n%s",
 		m.viewport.View(),
 		m.textarea.View(),
 		m.helpView(),
-	)
+	) + m.pendingOSC
 }
 
 func (m *Model) updateViewport() tea.Cmd {
+	m.assignMessageIDs()
+	m.contextTokens = api.EstimateTokens(m.trimmedHistory())
 	m.viewport.SetContent(m.formatMessages())
 	m.viewport.GotoBottom()
 	return nil
@@ -469,60 +878,41 @@ func (m Model) formatMessages() string {
 	sb.Grow(messageCount * 200)
 	
 	// 限制显示的消息数量，只显示最近的消息
-	// 保留最近10条用户消息和对应的AI回复，以及所有系统消息
-	const maxUserMessages = 10
-	userMessageCount := 0
-	
-	// 计算需要显示的消息起始位置（从后向前遍历更高效）
-	startIndex := 0
-	for i := messageCount - 1; i >= 0; i-- {
-		if m.messages[i].Role == "user" {
-			userMessageCount++
-			if userMessageCount > maxUserMessages {
-				startIndex = i + 1
-				break
-			}
-		}
-	}
+	startIndex := m.displayStart()
 	
 	// 如果有消息被跳过，显示提示
 	if startIndex > 0 {
 		sb.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("8")).Render(
-			fmt.Sprintf("... (显示最近 %d 条对话，共 %d 条) ...\n\n", 
-				messageCount-startIndex, messageCount)))
+			i18n.T("ui.history_truncated", messageCount-startIndex, messageCount)))
 	}
 	
 	// 渲染从startIndex开始的消息
 	for i := startIndex; i < messageCount; i++ {
 		msg := m.messages[i]
-		switch msg.Role {
-		case "user":
-			sb.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("12")).Render("你: "))
-			sb.WriteString(msg.Content)
-			sb.WriteString("\n\n")
-		case "assistant":
-			sb.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("10")).Render("AI: "))
-			// 直接显示原始内容
-			sb.WriteString(msg.Content)
-			sb.WriteString("\n\n")
-		case "system":
-			// 只显示工具调用、工具结果和错误消息，不显示长的系统提示
-			content := msg.Content
-			if len(content) < 100 ||
-				strings.Contains(content, "🔧") ||
-				strings.Contains(content, "✅") ||
-				strings.Contains(content, "❌") ||
-				strings.Contains(content, "工具执行") ||
-							strings.Contains(content, "AI 请求使用工具") {
-							sb.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("13")).Render("系统: "))
-							// 直接显示原始内容
-							sb.WriteString(content)
-							sb.WriteString("\n\n")			}
+		if m.selecting && i == m.selectedMessage {
+			sb.WriteString(selectionMarker())
 		}
+		sb.WriteString(m.messageBlock(msg))
 	}
 	return sb.String()
 }
 
+// displayStart 视口中显示的第一条消息：保留最近10条用户消息和对应的AI回复，以及所有系统消息
+func (m Model) displayStart() int {
+	const maxUserMessages = 10
+	userMessageCount := 0
+	// 从后向前遍历更高效
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if m.messages[i].Role == "user" {
+			userMessageCount++
+			if userMessageCount > maxUserMessages {
+				return i + 1
+			}
+		}
+	}
+	return 0
+}
+
 // formatMessagesWithoutLastAssistant 格式化消息但不包含最后一条AI消息（用于流式渲染）
 func (m Model) formatMessagesWithoutLastAssistant() string {
 	messageCount := len(m.messages)
@@ -567,35 +957,13 @@ func (m Model) formatMessagesWithoutLastAssistant() string {
 	// 如果有消息被跳过，显示提示
 	if startIndex > 0 {
 		sb.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("8")).Render(
-			fmt.Sprintf("... (显示最近 %d 条对话，共 %d 条) ...\n\n", 
-				endIndex-startIndex, messageCount)))
+			i18n.T("ui.history_truncated", endIndex-startIndex, messageCount)))
 	}
 	
 	// 渲染从startIndex开始的消息
 	for i := startIndex; i < endIndex; i++ {
 		msg := tempMessages[i]
-		switch msg.Role {
-		case "user":
-			sb.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("12")).Render("你: "))
-			sb.WriteString(msg.Content)
-			sb.WriteString("\n\n")
-		case "assistant":
-			sb.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("10")).Render("AI: "))
-			// 直接显示原始内容
-			sb.WriteString(msg.Content)
-			sb.WriteString("\n\n")
-		case "system":
-			content := msg.Content
-			if len(content) < 100 ||
-				strings.Contains(content, "🔧") ||
-				strings.Contains(content, "✅") ||
-				strings.Contains(content, "❌") ||
-				strings.Contains(content, "工具执行") ||
-							strings.Contains(content, "AI 请求使用工具") {
-							sb.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("13")).Render("系统: "))
-							sb.WriteString(content)
-							sb.WriteString("\n\n")			}
-		}
+		sb.WriteString(m.messageBlock(msg))
 	}
 	return sb.String()
 }
@@ -608,8 +976,9 @@ func (m *Model) renderOptimizedViewport() {
 	var displayContent strings.Builder
 	displayContent.Grow(4096)
 	
+	m.assignMessageIDs()
 	// 只在首次或消息完成时渲染历史消息
-	if m.renderedLines == nil || len(m.messages) == 0 {
+	if !m.renderedLinesValid() || len(m.messages) == 0 {
 		displayContent.WriteString(m.formatMessagesWithoutLastAssistant())
 	} else {
 		// 复用已缓存的渲染结果
@@ -622,16 +991,16 @@ func (m *Model) renderOptimizedViewport() {
 	// 添加思考内容（增量更新）
 	if m.currentThink != "" {
 		displayContent.WriteString("\n")
-		displayContent.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("13")).Render("思考: "))
-		displayContent.WriteString(m.currentThink)
+		displayContent.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("13")).Render(i18n.T("ui.role_thinking")))
+		displayContent.WriteString(m.reasoningView())
 		displayContent.WriteString("█")
 	}
 	
 	// 添加实时AI响应（增量更新）
 	if m.currentResp != "" {
 		displayContent.WriteString("\n")
-		displayContent.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("10")).Render("AI: "))
-		displayContent.WriteString(m.currentResp)
+		displayContent.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("10")).Render(i18n.T("ui.role_assistant")))
+		displayContent.WriteString(renderMarkdown(m.currentResp, m.viewport.Width))
 		displayContent.WriteString("█")
 	}
 	
@@ -641,6 +1010,7 @@ func (m *Model) renderOptimizedViewport() {
 
 // updateRenderedLinesCache 更新历史消息的渲染缓存
 func (m *Model) updateRenderedLinesCache() {
+	m.assignMessageIDs()
 	messageCount := len(m.messages)
 	if messageCount == 0 {
 		m.renderedLines = nil
@@ -666,122 +1036,165 @@ func (m *Model) updateRenderedLinesCache() {
 	
 	for i := startIndex; i < endIndex; i++ {
 		msg := m.messages[i]
-		switch msg.Role {
-		case "user":
-					sb.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("12")).Render("你: "))
-					sb.WriteString(msg.Content)
-					sb.WriteString("\n\n")
-				case "assistant":
-					sb.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("10")).Render("AI: "))
-					// 直接显示原始内容
-					sb.WriteString(msg.Content)
-					sb.WriteString("\n\n")
-				case "system":
-					content := msg.Content
-					if len(content) < 100 ||
-						strings.Contains(content, "🔧") ||
-						strings.Contains(content, "✅") ||
-						strings.Contains(content, "❌") ||
-						strings.Contains(content, "工具执行") ||
-						strings.Contains(content, "AI 请求使用工具") {
-						sb.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("13")).Render("系统: "))
-						sb.WriteString(content)
-						sb.WriteString("\n\n")
-					}
-				}	}
+		sb.WriteString(m.messageBlock(msg))
+	}
 	
 	// 将渲染结果按行缓存
 	content := sb.String()
 	if content != "" {
 		// 使用高效的字符串分割
 		m.renderedLines = strings.Split(strings.TrimRight(content, "\n"), "\n")
+		m.renderedKey = m.currentRenderKey()
 	} else {
 		m.renderedLines = nil
 	}
 }
 
 func (m Model) helpView() string {
-	help := "Enter: 发送消息 • Ctrl+S: 保存修改 • Esc: 取消思考 • Ctrl+C: 退出"
+	help := i18n.T("ui.help")
+	if usage := m.contextUsage(); usage != "" {
+		help = usage + " • " + help
+	}
+	if status := m.budgetStatus(); status != "" {
+		help = status + " • " + help
+	}
+	if status := m.healthStatus(); status != "" {
+		help = status + " • " + help
+	}
+	if len(m.awaitingApproval) > 0 {
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("11")).Render(i18n.T("ui.approval_hint"))
+	}
+	if len(m.pendingQuestions) > 0 {
+		hint := i18n.T("ui.question_hint")
+		if len(m.pendingQuestions[0].Options) > 0 {
+			hint = i18n.T("ui.question_options_hint", len(m.pendingQuestions[0].Options))
+		}
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("14")).Render(hint)
+	}
+	if c := m.planChecklist; c != nil && !m.thinking {
+		hint := i18n.T("ui.plan_hint")
+		if c.Editing {
+			hint = i18n.T("ui.plan_edit_hint", c.Cursor+1)
+		}
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("14")).Render(hint)
+	}
+	if m.selecting {
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("14")).Render(i18n.T("ui.select_hint"))
+	}
+	if m.awaitingBudgetConfirm {
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("11")).Render(i18n.T("ui.budget_confirm_hint"))
+	}
+	if m.pendingRoot != "" {
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("11")).Render(i18n.T("ui.add_root_hint"))
+	}
 	if m.thinking {
-		help = lipgloss.NewStyle().Foreground(lipgloss.Color("11")).Render("AI正在思考中... ") + "Esc: 取消"
+		help = lipgloss.NewStyle().Foreground(lipgloss.Color("11")).Render(m.activityStatus()) + i18n.T("ui.cancel_hint")
+	} else if m.changeKeys && m.textarea.Value() == "" {
+		help = i18n.T("ui.change_keys_hint") + " • " + help
 	}
 	return lipgloss.NewStyle().Foreground(lipgloss.Color("8")).Render(help)
 }
 
+// sendInput 清空输入框并将输入发送给AI
+func (m *Model) sendInput(input string) tea.Cmd {
+	m.textarea.Reset()
+	// startStream 会将用户消息添加到界面，之后再刷新视口
+	stream := m.startStream(input)
+	return tea.Batch(
+		m.updateViewport(),
+		stream,
+		statusTickCmd(),
+	)
+}
+
 func (m *Model) startStream(input string) tea.Cmd {
+	m.planMode = false
+	return m.startTurn(input, input)
+}
+
+// startTurn 开始新的一轮对话：display 显示在界面上，input 作为用户消息发送给模型
+func (m *Model) startTurn(display, input string) tea.Cmd {
 	m.thinking = true
+	m.turnStartedAt = time.Now()
+	m.beginTurnCheckpoint()
+	m.stats.addTurn()
 	m.currentResp = ""
 	m.currentThink = ""
+	m.stallRetries = 0
+	m.contextRetried = false
+	m.retryTemperature = nil
 
 	// 添加用户消息到API历史
 	m.apiMessages = append(m.apiMessages, api.TextMessage("user", input))
 
 	// 添加用户消息到界面
-	m.messages = append(m.messages, Message{Role: "user", Content: input})
+	m.messages = append(m.messages, Message{Role: "user", Content: display})
 
 	// 创建统一的API客户端
-	client := api.NewClient(m.apiKey)
+	client := m.newAPIClient()
 
 	// 准备工具
-	tools := m.toolManager.GetToolsForAPI()
+	tools := m.requestTools()
 
-	// 如果有工具，添加系统提示
-	finalMessages := m.apiMessages
-	if len(tools) > 0 {
-		finalMessages = addSystemPromptIfNeeded(m.apiMessages)
-	}
-
-	// 启动流式请求
-	m.streamCh, m.reasoningCh, m.toolCallCh, m.streamErrCh = client.StreamChatWithChannel(m.ctx, finalMessages, tools)
+	// 裁剪历史，有工具时添加系统提示
+	finalMessages := m.requestMessages(tools)
 
-	return func() tea.Msg {
-		select {
-		case chunk := <-m.streamCh:
-			if chunk == "" {
-				// 流结束
-				return CheckStreamMsg{}
-			}
-			return StreamChunkMsg{Chunk: chunk}
-		case reasoning := <-m.reasoningCh:
-			return StreamChunkMsg{Reasoning: reasoning}
-		case toolCalls := <-m.toolCallCh:
-			return ToolCallMsg{ToolCalls: toolCalls}
-		case err := <-m.streamErrCh:
-			return StreamErrorMsg{Error: err}
-		}
-	}
+	// 检查上下文窗口后启动流式请求
+	return m.streamRequest(client, finalMessages, tools)
 }
 
+// checkStream 读取当前流的下一个事件并转换为界面消息
 func (m *Model) checkStream() tea.Cmd {
+	// 绑定当前通道，重新发起请求后旧的读取不会串到新流上
+	events := m.streamEvents
 	return func() tea.Msg {
-		select {
-		case chunk := <-m.streamCh:
-			if chunk == "" {
-				// 流结束
-				return CheckStreamMsg{}
-			}
-			return StreamChunkMsg{Chunk: chunk}
-		case reasoning := <-m.reasoningCh:
-			return StreamChunkMsg{Reasoning: reasoning}
-		case toolCalls := <-m.toolCallCh:
-			return ToolCallMsg{ToolCalls: toolCalls}
-		case err := <-m.streamErrCh:
-			return StreamErrorMsg{Error: err}
+		event, ok := <-events
+		if !ok {
+			// 请求被取消时通道直接关闭
+			return CheckStreamMsg{}
+		}
+		switch event.Type {
+		case api.StreamEventContent:
+			return StreamChunkMsg{Chunk: event.Content}
+		case api.StreamEventReasoning:
+			return StreamChunkMsg{Reasoning: event.Content}
+		case api.StreamEventToolCall:
+			return ToolCallMsg{ToolCalls: event.ToolCalls}
+		case api.StreamEventUsage:
+			return StreamUsageMsg{Usage: event.Usage}
+		case api.StreamEventError:
+			return StreamErrorMsg{Error: event.Err}
+		default:
+			return CheckStreamMsg{}
 		}
 	}
 }
 
 func (m *Model) executePendingTools() tea.Cmd {
+	denied := m.deniedToolCalls
+	m.deniedToolCalls = nil
+	answers := m.questionAnswers
+	m.questionAnswers = nil
+	// 重复的只读调用直接引用之前的结果，与用户的回答一样不再执行
+	for id, reference := range m.duplicateToolResults() {
+		if answers == nil {
+			answers = make(map[string]string)
+		}
+		answers[id] = reference
+	}
+	m.toolsRunning = true
+	m.markDeniedToolCalls(denied)
+
 	return func() tea.Msg {
 		if len(m.pendingToolCalls) == 0 {
 			return nil
 		}
 
-		// 执行工具调用
-		resultMessages, err := m.toolManager.HandleToolCalls(m.pendingToolCalls)
+		// 执行工具调用（跳过用户拒绝的命令）
+		resultMessages, err := m.runToolCalls(m.pendingToolCalls, denied, answers)
 		if err != nil {
 			// 创建错误消息
-			errorMsg := fmt.Sprintf("工具执行失败: %v", err)
+			errorMsg := i18n.T("tool.failed", err)
 			return ToolResultMsg{
 				ResultMessages: []api.Message{api.TextMessage("system", errorMsg)},
 				DisplayContent: errorMsg,
@@ -790,15 +1203,15 @@ func (m *Model) executePendingTools() tea.Cmd {
 
 		// 格式化显示内容
 		var displayContent strings.Builder
-		displayContent.WriteString("✅ 工具执行完成:\n")
+		displayContent.WriteString(i18n.T("tool.completed"))
 		for _, msg := range resultMessages {
 			if msg.Role == "tool" {
 				// 显示工具名称和结果
 				toolName := msg.Name
 				if toolName == "" {
-					toolName = "未知工具"
+					toolName = i18n.T("tool.unknown")
 				}
-				displayContent.WriteString(fmt.Sprintf("🔧 %s 结果:\n%s\n\n", toolName, string(msg.Content)))
+				displayContent.WriteString(i18n.T("tool.result", toolName, string(msg.Content)))
 			}
 		}
 
@@ -813,55 +1226,128 @@ func (m *Model) continueStream() tea.Cmd {
 	m.thinking = true
 	m.currentResp = ""
 	m.currentThink = ""
+	m.stallRetries = 0
 
-	// 创建统一的API客户端
-	client := api.NewClient(m.apiKey)
+	// 创建统一的API客户端，工具执行后的请求可以使用单独配置的模型
+	client := m.newAPIClient()
+	if model := m.taskModel(modelTaskToolSteps); model != "" {
+		client = client.WithModel(model)
+	}
 
 	// 准备工具
-	tools := m.toolManager.GetToolsForAPI()
+	tools := m.requestTools()
 
-	// 启动流式请求（使用当前的API历史）
-	m.streamCh, m.reasoningCh, m.toolCallCh, m.streamErrCh = client.StreamChatWithChannel(m.ctx, m.apiMessages, tools)
+	// 工具结果中带有外部内容，系统提示（包括处理外部内容的说明）每次请求都要带上
+	return m.streamRequest(client, m.requestMessages(tools), tools)
+}
 
-	return func() tea.Msg {
-		select {
-		case chunk := <-m.streamCh:
-			if chunk == "" {
-				// 流结束
-				return CheckStreamMsg{}
-			}
-			return StreamChunkMsg{Chunk: chunk}
-		case reasoning := <-m.reasoningCh:
-			return StreamChunkMsg{Reasoning: reasoning}
-		case toolCalls := <-m.toolCallCh:
-			return ToolCallMsg{ToolCalls: toolCalls}
-		case err := <-m.streamErrCh:
-			return StreamErrorMsg{Error: err}
-		}
-	}
+// retryStream 使用当前 API 历史重新发起流式请求，用于流式响应停滞后的重试
+func (m *Model) retryStream() tea.Cmd {
+	m.currentResp = ""
+	m.currentThink = ""
+
+	client := m.newAPIClient()
+	tools := m.requestTools()
+
+	finalMessages := m.requestMessages(tools)
+
+	return m.streamRequest(client, finalMessages, tools)
 }
 
 // handleCommand 处理命令
 func (m *Model) handleCommand(cmd *Command) tea.Cmd {
+	telemetry.RecordCommand(FormatCommandType(cmd.Type))
 	switch cmd.Type {
 	case CommandTypeClear:
-		return m.handleClearCommand()
+		return tea.Batch(m.resetSessionTitle(), m.handleClearCommand())
 	case CommandTypeInit:
 		return m.handleInitCommand()
 	case CommandTypeCheckUpdate:
 		return m.handleCheckUpdateCommand()
 	case CommandTypeUpdate:
 		return m.handleUpdateCommand()
+	case CommandTypeRename:
+		return m.handleRenameCommand(cmd)
+	case CommandTypeHistory:
+		return m.handleHistoryCommand()
+	case CommandTypeTrust:
+		return m.handleTrustCommand()
+	case CommandTypeAudit:
+		return m.handleAuditCommand()
+	case CommandTypeQuota:
+		return m.handleQuotaCommand(cmd)
+	case CommandTypeTrash:
+		return m.handleTrashCommand(cmd)
+	case CommandTypeReloadConfig:
+		return m.handleReloadConfigCommand()
+	case CommandTypeOpen:
+		return m.handleOpenCommand(cmd)
+	case CommandTypeShow:
+		return m.handleShowCommand(cmd)
+	case CommandTypeRetry:
+		return m.handleRetryCommand(cmd)
+	case CommandTypeNew:
+		return m.handleNewCommand(cmd)
+	case CommandTypePin:
+		return m.handlePinCommand(cmd)
+	case CommandTypeTelemetry:
+		return m.handleTelemetryCommand(cmd)
+	case CommandTypeRecover:
+		return m.handleRecoverCommand(cmd)
+	case CommandTypeVerbose:
+		return m.handleVerboseCommand(cmd)
+	case CommandTypeDebug:
+		return m.handleDebugCommand(cmd)
+	case CommandTypeAddRoot:
+		return m.handleAddRootCommand(cmd)
+	case CommandTypeScope:
+		return m.handleScopeCommand(cmd)
+	case CommandTypeDiff:
+		return m.handleDiffCommand(cmd)
+	case CommandTypeRevert:
+		return m.handleRevertCommand(cmd)
+	case CommandTypeStats:
+		return m.handleStatsCommand()
+	case CommandTypePlan:
+		return m.handlePlanCommand(cmd)
 	default:
 		// 对于其他命令，显示不支持的消息
 		return func() tea.Msg {
 			return ResponseMsg{
-				Content: fmt.Sprintf("命令 '%s' 暂不支持", FormatCommandType(cmd.Type)),
+				Content: i18n.T("command.unsupported", FormatCommandType(cmd.Type)),
 			}
 		}
 	}
 }
 
+// handleTrustCommand 处理 /trust 命令：信任当前目录并开放全部工具
+func (m *Model) handleTrustCommand() tea.Cmd {
+	if !m.toolManager.ReadOnly() {
+		return func() tea.Msg {
+			return ResponseMsg{Content: i18n.T("command.trust_already")}
+		}
+	}
+
+	m.toolManager.SetReadOnly(false)
+	workspace, err := os.Getwd()
+	if err != nil || m.config == nil {
+		// 无法持久化时仅对本次会话生效
+		return func() tea.Msg {
+			return ResponseMsg{Content: i18n.T("command.trusted_session")}
+		}
+	}
+
+	m.config.TrustWorkspace(workspace)
+	if err := config.SaveConfig(m.config); err != nil {
+		return func() tea.Msg {
+			return ResponseMsg{Content: i18n.T("command.trust_save_failed", err)}
+		}
+	}
+	return func() tea.Msg {
+		return ResponseMsg{Content: i18n.T("command.trusted", workspace)}
+	}
+}
+
 // handleInitCommand 处理 init 命令
 func (m *Model) handleInitCommand() tea.Cmd {
 	// 发送一个特殊的消息给 AI，让 AI 使用工具来分析项目
@@ -885,70 +1371,49 @@ AGENT.md 应该包含：
 	m.messages = append(m.messages, Message{Role: "user", Content: specialMessage})
 	m.textarea.Reset()
 	m.thinking = true
+	m.turnStartedAt = time.Now()
+	m.beginTurnCheckpoint()
+	m.stats.addTurn()
 	m.currentResp = ""
 	m.currentThink = ""
+	m.stallRetries = 0
+	m.contextRetried = false
 
 	// 添加到 API 历史
 	m.apiMessages = append(m.apiMessages, api.TextMessage("user", specialMessage))
 
-	// 启动流式请求
-	client := api.NewClient(m.apiKey)
-	tools := m.toolManager.GetToolsForAPI()
+	// 启动流式请求，首轮强制使用工具探索项目，后续由模型自行决定
+	m.nextToolChoice = api.RequiredToolChoice()
+	client := m.newAPIClient()
+	tools := m.requestTools()
 
-	// 如果有工具，添加系统提示
-	finalMessages := m.apiMessages
-	if len(tools) > 0 {
-		finalMessages = addSystemPromptIfNeeded(m.apiMessages)
-	}
-
-	m.streamCh, m.reasoningCh, m.toolCallCh, m.streamErrCh = client.StreamChatWithChannel(m.ctx, finalMessages, tools)
+	// 裁剪历史，有工具时添加系统提示
+	finalMessages := m.requestMessages(tools)
 
-	return func() tea.Msg {
-		select {
-		case chunk := <-m.streamCh:
-			if chunk == "" {
-				// 流结束
-				return CheckStreamMsg{}
-			}
-			return StreamChunkMsg{Chunk: chunk}
-		case reasoning := <-m.reasoningCh:
-			return StreamChunkMsg{Reasoning: reasoning}
-		case toolCalls := <-m.toolCallCh:
-			return ToolCallMsg{ToolCalls: toolCalls}
-		case err := <-m.streamErrCh:
-			return StreamErrorMsg{Error: err}
-		}
-	}
+	return m.streamRequest(client, finalMessages, tools)
 }
 
 // handleCheckUpdateCommand 处理检查更新命令
 func (m *Model) handleCheckUpdateCommand() tea.Cmd {
+	if m.offline() {
+		return offlineResponseCmd()
+	}
 	return func() tea.Msg {
-		checker := update.NewChecker()
-		
-		latestVersion, err := checker.GetLatestVersion()
+		latest, err := update.NewChecker().Latest()
 		if err != nil {
 			return ResponseMsg{
-				Content: fmt.Sprintf("检查更新失败: %v", err),
+				Content: i18n.T("command.check_update_unavailable", err),
 			}
 		}
-		
-		hasUpdate, _, err := checker.CheckForUpdate(Version)
-		if err != nil {
-			return ResponseMsg{
-				Content: fmt.Sprintf("检查更新失败: %v", err),
-			}
+
+		content := i18n.T("command.up_to_date", Version)
+		if latest.NewerThan(Version) {
+			content = i18n.T("command.update_available", Version, latest.TagName)
 		}
-		
-		if hasUpdate {
-			return ResponseMsg{
-				Content: fmt.Sprintf("发现新版本!\n当前版本: %s\n最新版本: %s\n\n输入 update 或 /update 开始更新", Version, latestVersion),
-			}
-		} else {
-			return ResponseMsg{
-				Content: fmt.Sprintf("当前已是最新版本 (%s)", Version),
-			}
+		if latest.Stale {
+			content += i18n.T("command.check_update_stale", latest.CheckedAt.Format("2006-01-02 15:04"))
 		}
+		return ResponseMsg{Content: content}
 	}
 }
 
@@ -973,43 +1438,146 @@ func (m *Model) handleClearCommand() tea.Cmd {
 		}
 		
 		// 更新视口显示
-		m.viewport.SetContent("上下文已清空。可以开始新的对话。\n\n")
+		m.viewport.SetContent(i18n.T("command.context_cleared_banner"))
 		m.viewport.GotoBottom()
 		
 		return ResponseMsg{
-			Content: "上下文和所有消息已清空。",
+			Content: i18n.T("command.context_cleared"),
 		}
 	}
 }
 
 // handleUpdateCommand 处理更新命令
 func (m *Model) handleUpdateCommand() tea.Cmd {
+	if m.offline() {
+		return offlineResponseCmd()
+	}
 	return func() tea.Msg {
 		updater := update.NewUpdater()
 		
 		if err := updater.Update(Version); err != nil {
 			return ResponseMsg{
-				Content: fmt.Sprintf("更新失败: %v", err),
+				Content: i18n.T("command.update_failed", err),
 			}
 		}
 		
 		return ResponseMsg{
-			Content: fmt.Sprintf("更新成功! 请重启 PolyAgent 以使用新版本。"),
+			Content: i18n.T("command.update_succeeded"),
 		}
 	}
 }
 
-// addSystemPromptIfNeeded 添加系统提示（如果有工具）
-func addSystemPromptIfNeeded(messages []api.Message) []api.Message {
-	// 检查是否已经有系统提示
-	for _, msg := range messages {
-		if msg.Role == "system" {
-			return messages
+// responseLanguage 返回要求模型使用的回答语言，未配置时跟随界面语言
+func (m *Model) responseLanguage() string {
+	if m.config != nil && m.config.ResponseLanguage != "" {
+		return m.config.ResponseLanguage
+	}
+	return string(i18n.CurrentLanguage())
+}
+
+// newAPIClient 创建 API 客户端，并消费仅对下一次请求生效的工具调用策略
+func (m *Model) newAPIClient() *api.Client {
+	client := api.NewClient(m.apiKey)
+	if model := m.chatModel(); model != "" {
+		client = client.WithModel(model)
+	}
+	if m.retryTemperature != nil {
+		client = client.WithTemperature(*m.retryTemperature)
+	}
+	if m.config != nil && m.config.StreamIdleTimeout > 0 {
+		client = client.WithStreamIdleTimeout(time.Duration(m.config.StreamIdleTimeout) * time.Second)
+	}
+	if m.config != nil && m.config.ToolEmulation {
+		client = client.WithToolEmulation(true)
+	}
+	if m.nextToolChoice != nil {
+		client = client.WithToolChoice(m.nextToolChoice)
+		m.nextToolChoice = nil
+	}
+	return m.withBudget(m.withStats(client))
+}
+
+// SetModelOverride 设置仅对本次运行生效的模型（命令行 --model），为空时使用配置文件中的模型
+func (m *Model) SetModelOverride(model string) {
+	m.modelOverride = model
+}
+
+// chatModel 返回对话使用的模型，为空时由客户端使用默认模型
+func (m *Model) chatModel() string {
+	if m.modelOverride != "" {
+		return m.modelOverride
+	}
+	if m.config != nil {
+		return m.config.Model
+	}
+	return ""
+}
+
+// 可以在 model_routing 中单独配置模型的任务
+const (
+	modelTaskTitle     = "title"
+	modelTaskSummary   = "summary"
+	modelTaskToolSteps = "tool_steps"
+)
+
+// taskModel 返回任务使用的模型，model_routing 中没有配置时使用对话模型
+func (m *Model) taskModel(task string) string {
+	if m.config != nil {
+		routing := m.config.ModelRouting
+		var model string
+		switch task {
+		case modelTaskTitle:
+			model = routing.Title
+		case modelTaskSummary:
+			model = routing.Summary
+		case modelTaskToolSteps:
+			model = routing.ToolSteps
+		}
+		if model != "" {
+			return model
 		}
 	}
-	
-	// 添加系统提示
-	systemPrompt := `你是一个AI助手，可以使用各种工具来帮助用户完成任务。
+	return m.chatModel()
+}
+
+// offline 是否处于离线模式
+func (m *Model) offline() bool {
+	return m.config != nil && m.config.Offline
+}
+
+// offlineResponseCmd 返回离线模式下联网命令不可用的提示
+func offlineResponseCmd() tea.Cmd {
+	return func() tea.Msg {
+		return ResponseMsg{
+			Content: i18n.T("command.offline_unavailable"),
+		}
+	}
+}
+
+// systemInstructions 返回需要追加到系统提示中的额外要求
+func (m *Model) systemInstructions() []string {
+	var instructions []string
+	if instruction := i18n.ResponseLanguageInstruction(m.responseLanguage()); instruction != "" {
+		instructions = append(instructions, instruction)
+	}
+	if m.offline() {
+		instructions = append(instructions, i18n.T("prompt.offline"))
+	}
+	if m.toolManager.ReadOnly() {
+		instructions = append(instructions, i18n.T("prompt.untrusted_workspace"))
+	}
+	if roots := m.toolManager.Roots(); len(roots) > 1 {
+		instructions = append(instructions, i18n.T("prompt.workspace_roots", strings.Join(roots, "\n")))
+	}
+	if scope := m.toolManager.Scope(); scope != "" {
+		instructions = append(instructions, i18n.T("prompt.scope", scope))
+	}
+	instructions = append(instructions, i18n.T("prompt.external_content"))
+	return instructions
+}
+
+// toolSystemPrompt 有工具时发送的系统提示
+const toolSystemPrompt = `你是一个AI助手，可以使用各种工具来帮助用户完成任务。
 可用的工具包括：
 - 文件操作：读取、写入、搜索文件
 - 目录操作：列出目录内容
@@ -1019,6 +1587,19 @@ func addSystemPromptIfNeeded(messages []api.Message) []api.Message {
 - 时间工具：获取当前时间
 
 请根据用户需求选择合适的工具来完成任务。`
+
+// addSystemPromptIfNeeded 添加系统提示（如果有工具）
+// instructions 中的每一项会作为单独段落追加到系统提示末尾。
+// 历史中的其他系统消息（如工具执行失败的说明）不代替系统提示，只有开头已经是系统提示时才不再添加
+func addSystemPromptIfNeeded(messages []api.Message, instructions ...string) []api.Message {
+	if len(messages) > 0 && messages[0].Role == "system" && strings.HasPrefix(api.MessageText(messages[0]), toolSystemPrompt) {
+		return messages
+	}
+
+	systemPrompt := toolSystemPrompt
+	for _, instruction := range instructions {
+		systemPrompt += "\n\n" + instruction
+	}
 	
 	result := make([]api.Message, len(messages)+1)
 	result[0] = api.TextMessage("system", systemPrompt)
@@ -0,0 +1,253 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/api"
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
+	"github.com/Zacy-Sokach/PolyAgent/internal/utils"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	// agentDocName /init 生成的项目说明文档
+	agentDocName = "AGENT.md"
+	// seedFileMaxBytes 带入新会话的每个文件最多读取的字节数
+	seedFileMaxBytes = 32 * 1024
+	// summaryExcerptRunes 生成总结时每条消息最多截取的长度
+	summaryExcerptRunes = 2000
+	// summaryMaxTokens 生成总结时最多发送的历史 token 数
+	summaryMaxTokens = 32000
+)
+
+// sessionSummaryPrompt 总结上一个会话的系统提示
+const sessionSummaryPrompt = `总结下面这段编程助手的对话，供在新会话中继续工作时参考。
+要求：使用与用户相同的语言；用要点列出用户的目标、已确认的项目结构和约定、已完成的修改（包括文件路径）、未解决的问题和下一步计划；省略寒暄和中间的试错过程；不超过 400 字。`
+
+// newSessionSeedMsg 上一个会话的总结已生成，可以开始新会话
+type newSessionSeedMsg struct {
+	summary string
+	err     error
+}
+
+// handleNewCommand 处理 /new 命令：保存当前会话并开始新会话，
+// /new keep-context 将 AGENT.md、固定的文件和模型生成的会话总结带入新会话
+func (m *Model) handleNewCommand(cmd *Command) tea.Cmd {
+	respond := func(content string) tea.Cmd {
+		return func() tea.Msg {
+			return ResponseMsg{Content: content}
+		}
+	}
+	if m.thinking {
+		return respond(i18n.T("command.new_busy"))
+	}
+
+	switch cmd.Content {
+	case "":
+		m.saveHistory()
+		m.resetConversation()
+		return tea.Batch(m.resetSessionTitle(), respond(i18n.T("command.new_started")))
+	case "keep-context", "keep":
+	default:
+		return respond(i18n.T("command.new_usage"))
+	}
+
+	if len(m.apiMessages) == 0 {
+		// 没有可总结的对话，只带入项目文档和固定的文件
+		return m.startSeededSession("")
+	}
+
+	m.thinking = true
+	m.messages = append(m.messages, Message{Role: "system", Content: i18n.T("command.new_summarizing")})
+	return tea.Batch(m.updateViewport(), m.summarizeSessionCmd())
+}
+
+// summarizeSessionCmd 在后台让模型总结当前会话
+func (m *Model) summarizeSessionCmd() tea.Cmd {
+	client := m.withBudget(m.withStats(api.NewClient(m.apiKey)))
+	if model := m.taskModel(modelTaskSummary); model != "" {
+		client = client.WithModel(model)
+	}
+	transcript := sessionTranscript(api.TrimHistory(m.apiMessages, 0, summaryMaxTokens))
+	return func() tea.Msg {
+		messages := []api.Message{
+			api.TextMessage("system", sessionSummaryPrompt),
+			api.TextMessage("user", transcript),
+		}
+		resp, err := client.ChatCompletion(messages, false, nil)
+		if err != nil {
+			return newSessionSeedMsg{err: err}
+		}
+		if len(resp.Choices) == 0 || resp.Choices[0].Message == nil {
+			return newSessionSeedMsg{err: errors.New("模型没有返回总结")}
+		}
+		return newSessionSeedMsg{summary: strings.TrimSpace(api.MessageText(*resp.Choices[0].Message))}
+	}
+}
+
+// handleNewSessionSeed 总结生成后开始新会话，总结期间按 Esc 取消时保留当前会话
+func (m *Model) handleNewSessionSeed(msg newSessionSeedMsg) tea.Cmd {
+	if !m.thinking {
+		return nil
+	}
+	m.thinking = false
+	if msg.err != nil {
+		m.messages = append(m.messages, Message{Role: "system", Content: i18n.T("command.new_summary_failed", msg.err)})
+		return m.updateViewport()
+	}
+	return m.startSeededSession(msg.summary)
+}
+
+// startSeededSession 保存当前会话，开始新会话并写入带入的上下文
+func (m *Model) startSeededSession(summary string) tea.Cmd {
+	seed, carried := m.sessionSeed(summary)
+	m.saveHistory()
+	m.resetConversation()
+	if seed != "" {
+		m.apiMessages = append(m.apiMessages, api.TextMessage("user", seed))
+	}
+
+	notice := i18n.T("command.new_started")
+	if len(carried) > 0 {
+		notice = i18n.T("command.new_started_with", strings.Join(carried, ", "))
+	}
+	m.messages = append(m.messages, Message{Role: "system", Content: notice})
+	return tea.Batch(m.resetSessionTitle(), m.updateViewport())
+}
+
+// sessionSeed 组装带入新会话的上下文，返回内容和带入项的说明
+func (m *Model) sessionSeed(summary string) (string, []string) {
+	var sb strings.Builder
+	var carried []string
+
+	if content, ok := readSeedFile(agentDocName); ok {
+		fmt.Fprintf(&sb, "## %s\n\n%s\n\n", agentDocName, content)
+		carried = append(carried, agentDocName)
+	}
+	var pinned int
+	for _, path := range m.pinnedFiles {
+		content, ok := readSeedFile(path)
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(&sb, "## 固定的文件 %s\n\n```\n%s\n```\n\n", displayPath(path), content)
+		pinned++
+	}
+	if pinned > 0 {
+		carried = append(carried, i18n.T("command.new_carried_pins", pinned))
+	}
+	if summary != "" {
+		fmt.Fprintf(&sb, "## 之前会话的总结\n\n%s\n\n", summary)
+		carried = append(carried, i18n.T("command.new_carried_summary"))
+	}
+
+	if sb.Len() == 0 {
+		return "", nil
+	}
+	return "以下是之前会话中积累的项目上下文，供继续工作时参考，不需要回复：\n\n" + strings.TrimSpace(sb.String()), carried
+}
+
+// resetConversation 清空消息和请求状态，固定的文件保留
+func (m *Model) resetConversation() {
+	m.messages = []Message{}
+	m.apiMessages = []api.Message{}
+	m.currentResp = ""
+	m.currentThink = ""
+	m.pendingToolCalls = nil
+	m.retryTemperature = nil
+	m.renderedLines = nil
+}
+
+// handlePinCommand 处理 /pin 命令：不带参数时列出固定的文件，/pin <路径> 固定或取消固定文件
+func (m *Model) handlePinCommand(cmd *Command) tea.Cmd {
+	respond := func(content string) tea.Cmd {
+		return func() tea.Msg {
+			return ResponseMsg{Content: content}
+		}
+	}
+
+	if cmd.Content == "" {
+		if len(m.pinnedFiles) == 0 {
+			return respond(i18n.T("command.pin_none"))
+		}
+		var sb strings.Builder
+		sb.WriteString(i18n.T("command.pin_header"))
+		for _, path := range m.pinnedFiles {
+			sb.WriteString(i18n.T("command.pin_item", displayPath(path)))
+		}
+		return respond(sb.String())
+	}
+
+	path, err := filepath.Abs(cmd.Content)
+	if err != nil {
+		return respond(i18n.T("command.pin_failed", cmd.Content, err))
+	}
+	for i, pinned := range m.pinnedFiles {
+		if pinned == path {
+			m.pinnedFiles = append(m.pinnedFiles[:i], m.pinnedFiles[i+1:]...)
+			return respond(i18n.T("command.unpinned", displayPath(path)))
+		}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return respond(i18n.T("command.pin_failed", cmd.Content, err))
+	}
+	if info.IsDir() {
+		return respond(i18n.T("command.pin_failed", cmd.Content, errors.New("不能固定目录")))
+	}
+	m.pinnedFiles = append(m.pinnedFiles, path)
+	return respond(i18n.T("command.pinned", displayPath(path)))
+}
+
+// readSeedFile 读取带入新会话的文件，过长时截断
+func readSeedFile(path string) (string, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil || len(data) == 0 {
+		return "", false
+	}
+	if len(data) > seedFileMaxBytes {
+		return utils.TruncateBytes(string(data), seedFileMaxBytes) + "\n...", true
+	}
+	return string(data), true
+}
+
+// displayPath 工作目录内的文件显示相对路径
+func displayPath(path string) string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	if rel, err := filepath.Rel(dir, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
+
+// sessionTranscript 将对话整理为文本，用于生成总结
+func sessionTranscript(messages []api.Message) string {
+	var sb strings.Builder
+	for _, msg := range messages {
+		if msg.Role == "system" {
+			continue
+		}
+		if text := strings.TrimSpace(api.MessageText(msg)); text != "" {
+			fmt.Fprintf(&sb, "[%s] %s\n\n", msg.Role, truncateRunes(text, summaryExcerptRunes))
+		}
+		for _, call := range msg.ToolCalls {
+			fmt.Fprintf(&sb, "[tool_call] %s %s\n\n", call.Function.Name, truncateRunes(string(call.Function.Arguments), summaryExcerptRunes))
+		}
+	}
+	return sb.String()
+}
+
+// truncateRunes 截取文本开头的 n 个字符
+func truncateRunes(text string, n int) string {
+	if truncated, ok := utils.TruncateText(text, n); ok {
+		return truncated + "..."
+	}
+	return text
+}
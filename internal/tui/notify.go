@@ -0,0 +1,80 @@
+package tui
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// defaultNotifyMinDuration 默认的提醒阈值，短于该耗时的响应不打扰用户
+const defaultNotifyMinDuration = 10 * time.Second
+
+// completionNotifyCmd 在一轮对话（含工具链）结束时，根据配置发送终端响铃或桌面通知
+// 每轮最多触发一次：调用后会清除本轮的开始时间
+func (m *Model) completionNotifyCmd(body string) tea.Cmd {
+	started := m.turnStartedAt
+	m.turnStartedAt = time.Time{}
+
+	if m.config == nil || started.IsZero() {
+		return nil
+	}
+
+	cfg := m.config.Notification
+	if !cfg.Bell && !cfg.Desktop {
+		return nil
+	}
+
+	// 默认只在用户切走窗口时提醒
+	if m.focused && !cfg.NotifyWhenFocused {
+		return nil
+	}
+
+	minDuration := defaultNotifyMinDuration
+	if cfg.MinDurationSeconds > 0 {
+		minDuration = time.Duration(cfg.MinDurationSeconds) * time.Second
+	}
+	if time.Since(started) < minDuration {
+		return nil
+	}
+
+	seq := buildNotifySequence(cfg.Bell, cfg.Desktop, "PolyAgent", body)
+	return func() tea.Msg {
+		os.Stdout.WriteString(seq)
+		return nil
+	}
+}
+
+// buildNotifySequence 构造响铃和 OSC 777 通知的转义序列
+func buildNotifySequence(bell, desktop bool, title, body string) string {
+	var sb strings.Builder
+
+	if desktop {
+		osc := "\x1b]777;notify;" + sanitizeOSCField(title) + ";" + sanitizeOSCField(body) + "\x07"
+		// tmux 需要通过 DCS passthrough 转发 OSC 序列
+		if os.Getenv("TMUX") != "" {
+			osc = "\x1bPtmux;" + strings.ReplaceAll(osc, "\x1b", "\x1b\x1b") + "\x1b\\"
+		}
+		sb.WriteString(osc)
+	}
+
+	if bell {
+		sb.WriteString("\a")
+	}
+
+	return sb.String()
+}
+
+// sanitizeOSCField 移除会破坏 OSC 序列的控制字符和分隔符
+func sanitizeOSCField(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == ';' {
+			return ','
+		}
+		if r < 0x20 || r == 0x7f {
+			return ' '
+		}
+		return r
+	}, s)
+}
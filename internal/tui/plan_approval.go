@@ -0,0 +1,272 @@
+package tui
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/api"
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
+	"github.com/Zacy-Sokach/PolyAgent/internal/mcp"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// maxPlanSteps 计划最多包含的步骤数
+const maxPlanSteps = 20
+
+// planModePrompt 计划模式下随任务发送给模型的要求
+const planModePrompt = `请先为下面的任务制定执行计划，暂时不要修改文件或执行命令，可以使用只读工具了解项目。
+最后用编号列表给出计划：每一步一行，以 "1. " 这样的编号开头，具体到要修改的文件或模块，不超过 %d 步。
+
+任务：%s`
+
+// planExecutePrompt 用户批准计划后发送给模型的要求，计划以 JSON 附在后面
+const planExecutePrompt = "用户审阅并批准了计划。请按顺序只执行 approved_steps 中的步骤，步骤内容以其中的文本为准（edited 表示用户修改过）；skipped_steps 中的步骤用户没有批准，不要执行。\n\n```json\n%s\n```"
+
+// planStepPattern 编号列表的一项，如 "1. 修改 main.go"、"2) ..."、"3、..."
+var planStepPattern = regexp.MustCompile(`^ ?(\d{1,2})[.)、]\s*(.+)$`)
+
+// planStep 计划中的一步
+type planStep struct {
+	Text     string
+	Original string // 模型给出的原文，用于判断用户是否修改过
+	Selected bool
+}
+
+// planChecklist 计划模式生成的计划，以清单形式让用户选择和修改要执行的步骤
+type planChecklist struct {
+	Task    string
+	Steps   []planStep
+	Cursor  int
+	Editing bool
+	// Status 非空时清单已执行或放弃，只显示结果
+	Status string
+}
+
+// planStepJSON 发送给模型的计划步骤
+type planStepJSON struct {
+	Step   int    `json:"step"`
+	Text   string `json:"text"`
+	Edited bool   `json:"edited,omitempty"`
+}
+
+// handlePlanCommand 处理 /plan 命令：本轮只开放只读工具，让模型先给出编号的计划
+func (m *Model) handlePlanCommand(cmd *Command) tea.Cmd {
+	respond := func(content string) tea.Cmd {
+		return func() tea.Msg {
+			return ResponseMsg{Content: content}
+		}
+	}
+	if m.thinking {
+		return respond(i18n.T("plan.busy"))
+	}
+	if cmd.Content == "" {
+		return respond(i18n.T("plan.usage"))
+	}
+	m.planMode = true
+	m.planTask = cmd.Content
+	return tea.Batch(m.startTurn(i18n.T("plan.display", cmd.Content), fmt.Sprintf(planModePrompt, maxPlanSteps, cmd.Content)), statusTickCmd())
+}
+
+// requestTools 本次请求提供给模型的工具，计划模式下只提供只读工具和项目索引查询
+func (m *Model) requestTools() []api.Tool {
+	tools := m.toolManager.GetToolsForAPI()
+	if !m.planMode {
+		return tools
+	}
+	var readOnly []api.Tool
+	for _, tool := range tools {
+		if mcp.ReadOnlyToolNames[tool.Function.Name] || mcp.IndexToolNames[tool.Function.Name] {
+			readOnly = append(readOnly, tool)
+		}
+	}
+	return readOnly
+}
+
+// finishPlanTurn 计划模式的一轮结束后，将回复中的编号列表显示为可操作的清单
+func (m *Model) finishPlanTurn(reply string) {
+	if !m.planMode {
+		return
+	}
+	m.planMode = false
+	steps := parsePlanSteps(reply)
+	if len(steps) == 0 {
+		m.messages = append(m.messages, Message{Role: "system", Content: i18n.T("plan.no_steps")})
+		return
+	}
+	m.planChecklist = &planChecklist{Task: m.planTask, Steps: steps}
+	m.messages = append(m.messages, Message{Role: "system", Content: m.planChecklist.text(), Checklist: m.planChecklist})
+}
+
+// parsePlanSteps 提取回复中最后一个编号列表的各项，默认全部选中
+func parsePlanSteps(reply string) []planStep {
+	var steps, current []planStep
+	for _, line := range strings.Split(reply, "\n") {
+		matches := planStepPattern.FindStringSubmatch(strings.TrimRight(line, " \t"))
+		if matches == nil {
+			continue
+		}
+		if matches[1] == "1" {
+			current = nil
+		}
+		text := strings.TrimSpace(strings.ReplaceAll(matches[2], "**", ""))
+		if text != "" && len(current) < maxPlanSteps {
+			current = append(current, planStep{Text: text, Original: text, Selected: true})
+			steps = current
+		}
+	}
+	return steps
+}
+
+// handlePlanKey 处理计划清单显示期间的按键，编辑步骤时其他按键交给输入框处理
+func (m *Model) handlePlanKey(msg tea.KeyMsg) (tea.Cmd, bool) {
+	c := m.planChecklist
+	if c.Editing {
+		switch msg.Type {
+		case tea.KeyEnter:
+			if text := strings.TrimSpace(m.textarea.Value()); text != "" {
+				c.Steps[c.Cursor].Text = text
+			}
+		case tea.KeyEsc:
+		default:
+			return nil, false
+		}
+		c.Editing = false
+		m.textarea.Reset()
+		return m.syncPlanChecklist(), true
+	}
+
+	switch msg.String() {
+	case "up", "k":
+		c.Cursor = max(c.Cursor-1, 0)
+	case "down", "j":
+		c.Cursor = min(c.Cursor+1, len(c.Steps)-1)
+	case " ", "x":
+		c.Steps[c.Cursor].Selected = !c.Steps[c.Cursor].Selected
+	case "e":
+		c.Editing = true
+		m.textarea.SetValue(c.Steps[c.Cursor].Text)
+	case "a":
+		for i := range c.Steps {
+			c.Steps[i].Selected = true
+		}
+		return m.executePlan(), true
+	case "enter":
+		return m.executePlan(), true
+	case "esc":
+		c.Status = i18n.T("plan.dismissed")
+		m.planChecklist = nil
+		return m.syncPlanChecklistOf(c), true
+	}
+	return m.syncPlanChecklist(), true
+}
+
+// executePlan 将选中的步骤作为结构化的计划发送给模型并开始执行
+func (m *Model) executePlan() tea.Cmd {
+	c := m.planChecklist
+	var approved, skipped []planStepJSON
+	for i, step := range c.Steps {
+		item := planStepJSON{Step: i + 1, Text: step.Text, Edited: step.Text != step.Original}
+		if step.Selected {
+			approved = append(approved, item)
+		} else {
+			skipped = append(skipped, item)
+		}
+	}
+	if len(approved) == 0 {
+		m.messages = append(m.messages, Message{Role: "system", Content: i18n.T("plan.none_selected")})
+		return m.updateViewport()
+	}
+
+	data, _ := json.MarshalIndent(struct {
+		Task     string         `json:"task"`
+		Approved []planStepJSON `json:"approved_steps"`
+		Skipped  []planStepJSON `json:"skipped_steps,omitempty"`
+	}{c.Task, approved, skipped}, "", "  ")
+	c.Status = i18n.T("plan.executed", len(approved), len(c.Steps))
+	m.planChecklist = nil
+	m.syncPlanChecklistOf(c)
+	display := i18n.T("plan.execute_display", len(approved), len(c.Steps))
+	return tea.Batch(m.startTurn(display, fmt.Sprintf(planExecutePrompt, data)), statusTickCmd())
+}
+
+// syncPlanChecklist 清单变化后更新所在消息的文本并重新渲染
+func (m *Model) syncPlanChecklist() tea.Cmd {
+	return m.syncPlanChecklistOf(m.planChecklist)
+}
+
+func (m *Model) syncPlanChecklistOf(c *planChecklist) tea.Cmd {
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if m.messages[i].Checklist == c {
+			m.messages[i].Content = c.text()
+			m.blocks.forget(m.messages[i].ID)
+			break
+		}
+	}
+	return m.updateViewport()
+}
+
+// selected 已选中的步骤数
+func (c *planChecklist) selected() int {
+	n := 0
+	for _, step := range c.Steps {
+		if step.Selected {
+			n++
+		}
+	}
+	return n
+}
+
+// text 清单的纯文本形式，用于保存历史记录
+func (c *planChecklist) text() string {
+	var sb strings.Builder
+	sb.WriteString(i18n.T("plan.title", c.selected(), len(c.Steps)))
+	for i, step := range c.Steps {
+		mark := "[ ]"
+		if step.Selected {
+			mark = "[x]"
+		}
+		fmt.Fprintf(&sb, "\n%s %d. %s", mark, i+1, step.Text)
+	}
+	if c.Status != "" {
+		sb.WriteString("\n" + c.Status)
+	}
+	return sb.String()
+}
+
+// render 渲染清单：当前步骤前显示光标，未选中的步骤变暗，执行或放弃后不再显示光标
+func (c *planChecklist) render(width int) string {
+	lines := []string{lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("14")).Render(i18n.T("plan.title", c.selected(), len(c.Steps)))}
+	for i, step := range c.Steps {
+		cursor := "  "
+		if c.Status == "" && i == c.Cursor {
+			cursor = lipgloss.NewStyle().Foreground(lipgloss.Color("14")).Render("› ")
+		}
+		mark, style := "[ ]", markdownRuleStyle
+		if step.Selected {
+			mark, style = "[x]", lipgloss.NewStyle()
+		}
+		line := style.Render(fmt.Sprintf("%s %d. %s", mark, i+1, step.Text))
+		switch {
+		case c.Status == "" && c.Editing && i == c.Cursor:
+			line += markdownRuleStyle.Render(" " + i18n.T("plan.editing"))
+		case step.Text != step.Original:
+			line += markdownRuleStyle.Render(" " + i18n.T("plan.edited"))
+		}
+		lines = append(lines, cursor+line)
+	}
+	if c.Status != "" {
+		lines = append(lines, markdownRuleStyle.Render(c.Status))
+	}
+	style := lipgloss.NewStyle().
+		Border(lipgloss.ThickBorder(), false, false, false, true).
+		BorderForeground(lipgloss.Color("14")).
+		PaddingLeft(1)
+	if width > 0 {
+		// 竖条和内边距占两列
+		style = style.Width(max(width-2, 20))
+	}
+	return style.Render(strings.Join(lines, "\n"))
+}
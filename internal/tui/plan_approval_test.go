@@ -0,0 +1,94 @@
+package tui
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/api"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestParsePlanSteps(t *testing.T) {
+	reply := `项目中有两个入口：
+1. cmd/server
+2. cmd/worker
+
+计划：
+1. **在 internal/cache 中添加** LRU 缓存
+2) 在 handler.go 中使用缓存
+  - 子项不算步骤
+3、补充测试`
+	steps := parsePlanSteps(reply)
+	var texts []string
+	for _, step := range steps {
+		if !step.Selected {
+			t.Errorf("step %q should be selected by default", step.Text)
+		}
+		texts = append(texts, step.Text)
+	}
+	if got := strings.Join(texts, "|"); got != "在 internal/cache 中添加 LRU 缓存|在 handler.go 中使用缓存|补充测试" {
+		t.Errorf("steps = %q", got)
+	}
+	if steps := parsePlanSteps("没有计划"); steps != nil {
+		t.Errorf("steps = %v", steps)
+	}
+}
+
+func TestPlanChecklist(t *testing.T) {
+	m := goldenModel(t)
+	m.planMode = true
+	m.planTask = "加缓存"
+	for _, tool := range m.requestTools() {
+		if tool.Function.Name == "write_file" || tool.Function.Name == "run_shell_command" {
+			t.Errorf("plan mode should only offer read-only tools, got %s", tool.Function.Name)
+		}
+	}
+
+	m.messages = append(m.messages, Message{Role: "user", Content: "📋 制定计划：加缓存"})
+	m.thinking = true
+	m.currentResp = "计划：\n1. 添加缓存\n2. 删除旧代码\n3. 补充测试"
+	m = drive(t, m, CheckStreamMsg{})
+	if m.planChecklist == nil || m.planMode {
+		t.Fatalf("checklist not shown: planMode=%v", m.planMode)
+	}
+	if view := m.viewport.View(); !strings.Contains(view, "› [x] 1. 添加缓存") || !strings.Contains(view, "已选 3/3 步") {
+		t.Errorf("checklist view:\n%s", view)
+	}
+
+	// 取消第 2 步，修改第 3 步
+	m = drive(t, m, tea.KeyMsg{Type: tea.KeyDown}, tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(" ")}, tea.KeyMsg{Type: tea.KeyDown},
+		tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("e")})
+	if !m.planChecklist.Editing || m.textarea.Value() != "补充测试" {
+		t.Fatalf("editing = %v, textarea = %q", m.planChecklist.Editing, m.textarea.Value())
+	}
+	m.textarea.SetValue("补充缓存失效的测试")
+	m = drive(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	if view := m.viewport.View(); !strings.Contains(view, "[ ] 2. 删除旧代码") || !strings.Contains(view, "3. 补充缓存失效的测试 （已修改）") {
+		t.Errorf("checklist after edits:\n%s", view)
+	}
+
+	// 执行选中的步骤；取消请求上下文，请求立即结束，不访问网络
+	m.cancel()
+	m = drive(t, m, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.planChecklist != nil || !m.thinking {
+		t.Fatalf("plan should be executing")
+	}
+	prompt := api.MessageText(m.apiMessages[len(m.apiMessages)-1])
+	start, end := strings.Index(prompt, "{"), strings.LastIndex(prompt, "}")
+	var plan struct {
+		Task     string         `json:"task"`
+		Approved []planStepJSON `json:"approved_steps"`
+		Skipped  []planStepJSON `json:"skipped_steps"`
+	}
+	if err := json.Unmarshal([]byte(prompt[start:end+1]), &plan); err != nil {
+		t.Fatalf("plan JSON: %v\n%s", err, prompt)
+	}
+	if plan.Task != "加缓存" || len(plan.Approved) != 2 || plan.Approved[1] != (planStepJSON{Step: 3, Text: "补充缓存失效的测试", Edited: true}) ||
+		len(plan.Skipped) != 1 || plan.Skipped[0].Step != 2 {
+		t.Errorf("plan = %+v", plan)
+	}
+	if !strings.Contains(m.messages[len(m.messages)-1].Content, "执行计划中选中的 2/3 步") {
+		t.Errorf("last message = %q", m.messages[len(m.messages)-1].Content)
+	}
+}
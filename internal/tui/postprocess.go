@@ -0,0 +1,131 @@
+package tui
+
+import (
+	"encoding/json"
+	"go/format"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
+)
+
+// EventTypeMessagePostProcess 助手消息完成后、显示和保存之前同步发布，处理器可以改写消息内容
+const EventTypeMessagePostProcess = "message.post_process"
+
+// 内置后处理器的优先级，自定义处理器可以排在它们之前或之后
+const (
+	PostProcessPriorityFormat   = 100
+	PostProcessPriorityValidate = 200
+)
+
+// codeBlockPattern 匹配带语言标记的 Markdown 代码块
+var codeBlockPattern = regexp.MustCompile("(?s)```([\\w+-]+)[ \\t]*\\r?\\n(.*?)\\r?\\n([ \\t]*)```")
+
+// MessagePostProcessEvent 助手消息后处理事件，处理器直接修改 Content
+type MessagePostProcessEvent struct {
+	*BaseEvent
+	Content string
+}
+
+// NewMessagePostProcessEvent 创建助手消息后处理事件
+func NewMessagePostProcessEvent(content string) *MessagePostProcessEvent {
+	return &MessagePostProcessEvent{
+		BaseEvent: NewBaseEvent(EventTypeMessagePostProcess, nil),
+		Content:   content,
+	}
+}
+
+// Data 返回当前（可能已被前面的处理器改写的）内容
+func (e *MessagePostProcessEvent) Data() interface{} {
+	return e.Content
+}
+
+// postProcessor 以函数实现的消息后处理器
+type postProcessor struct {
+	priority int
+	process  func(string) string
+}
+
+func (p *postProcessor) CanHandle(event Event) bool {
+	_, ok := event.(*MessagePostProcessEvent)
+	return ok
+}
+
+func (p *postProcessor) Handle(event Event) error {
+	e := event.(*MessagePostProcessEvent)
+	e.Content = p.process(e.Content)
+	return nil
+}
+
+func (p *postProcessor) Priority() int {
+	return p.priority
+}
+
+// RegisterPostProcessor 在全局事件总线上注册助手消息后处理器，priority 越小越先执行
+// 返回的处理器可用于 Unsubscribe
+func RegisterPostProcessor(priority int, process func(string) string) EventHandler {
+	handler := &postProcessor{priority: priority, process: process}
+	GetGlobalEventBus().Subscribe(EventTypeMessagePostProcess, handler)
+	return handler
+}
+
+var builtinPostProcessorsOnce sync.Once
+
+// postProcessAssistant 依次运行已注册的后处理器，返回处理后的消息
+func postProcessAssistant(content string) string {
+	builtinPostProcessorsOnce.Do(func() {
+		RegisterPostProcessor(PostProcessPriorityFormat, formatGoCodeBlocks)
+		RegisterPostProcessor(PostProcessPriorityValidate, validateJSONCodeBlocks)
+	})
+	event := NewMessagePostProcessEvent(content)
+	GetGlobalEventBus().Publish(event)
+	return event.Content
+}
+
+// rewriteCodeBlocks 对指定语言的代码块调用 rewrite，返回替换后的文本
+// rewrite 返回新的代码和追加在代码块之后的说明（可为空）
+func rewriteCodeBlocks(content string, langs []string, rewrite func(code string) (string, string)) string {
+	return codeBlockPattern.ReplaceAllStringFunc(content, func(block string) string {
+		m := codeBlockPattern.FindStringSubmatch(block)
+		lang := strings.ToLower(m[1])
+		matched := false
+		for _, l := range langs {
+			if lang == l {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return block
+		}
+		code, note := rewrite(m[2])
+		result := "```" + m[1] + "\n" + code + "\n" + m[3] + "```"
+		if note != "" {
+			result += "\n" + note
+		}
+		return result
+	})
+}
+
+// formatGoCodeBlocks 用 gofmt 格式化 Go 代码块，无法解析的代码（如片段或伪代码）保持原样
+func formatGoCodeBlocks(content string) string {
+	return rewriteCodeBlocks(content, []string{"go", "golang"}, func(code string) (string, string) {
+		formatted, err := format.Source([]byte(code))
+		if err != nil {
+			return code, ""
+		}
+		return strings.TrimRight(string(formatted), "\n"), ""
+	})
+}
+
+// validateJSONCodeBlocks 检查 JSON 代码块，无效时在代码块后附上错误说明
+func validateJSONCodeBlocks(content string) string {
+	return rewriteCodeBlocks(content, []string{"json"}, func(code string) (string, string) {
+		var v interface{}
+		if err := json.Unmarshal([]byte(code), &v); err != nil {
+			return code, i18n.T("postprocess.invalid_json", err)
+		}
+		return code, ""
+	})
+}
@@ -0,0 +1,128 @@
+package tui
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/viewport"
+)
+
+// 模拟流的速率和时长，可通过 go test -bench . -stream.kbps=8 -stream.minutes=2 调整
+var (
+	streamKBps    = flag.Int("stream.kbps", 2, "synthetic stream rate in KB/s")
+	streamMinutes = flag.Float64("stream.minutes", 0.5, "synthetic stream duration in minutes")
+)
+
+// streamSamples 模拟回复中常见的内容：中英文段落、代码块、列表和文件引用
+var streamSamples = []string{
+	"Let me look at how the handler processes the request. ",
+	"这里的问题在于缓存没有在配置变化时失效，",
+	"所以第二次请求仍然读取到了旧的值。\n\n",
+	"```go\nfunc (c *Cache) Get(key string) (string, bool) {\n\tc.mu.RLock()\n\tdefer c.mu.RUnlock()\n\tv, ok := c.items[key]\n\treturn v, ok\n}\n```\n\n",
+	"- internal/tui/model.go:683 formats the messages\n",
+	"- 在 `updateViewport` 中重新计算上下文用量\n",
+	"The fix is small: invalidate the entry when the config is reloaded. ",
+	"Done!\n",
+}
+
+// syntheticStream 生成以 kbps KB/s 持续 minutes 分钟的数据量的模拟流，
+// 按服务商推送的典型大小（几个到几十个字节）切块，不在多字节字符中间切开
+func syntheticStream(kbps int, minutes float64) []string {
+	total := int(float64(kbps*1024) * 60 * minutes)
+	var chunks []string
+	size, sample := 0, 0
+	for size < total {
+		text := streamSamples[sample%len(streamSamples)]
+		sample++
+		for len(text) > 0 {
+			n := 4 + (len(chunks)*7)%37
+			for n < len(text) && !isRuneStart(text[n]) {
+				n++
+			}
+			if n > len(text) {
+				n = len(text)
+			}
+			chunks = append(chunks, text[:n])
+			size += n
+			text = text[n:]
+		}
+	}
+	return chunks
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+// benchmarkModel 返回一个已就绪、包含 turns 轮历史对话的模型
+func benchmarkModel(b *testing.B, turns int) Model {
+	b.Helper()
+	b.Setenv("POLYAGENT_CONFIG_HOME", b.TempDir())
+	m := InitialModel("", nil)
+	m.viewport = viewport.New(120, 40)
+	m.ready = true
+	for i := 0; i < turns; i++ {
+		m.messages = append(m.messages,
+			Message{Role: "user", Content: fmt.Sprintf("第 %d 个问题：为什么 internal/tui/model.go:%d 的渲染这么慢？", i, 100+i)},
+			Message{Role: "system", Content: "🔧 read_file internal/tui/model.go"},
+			Message{Role: "assistant", Content: strings.Join(streamSamples, "")},
+		)
+	}
+	m.assignMessageIDs()
+	return m
+}
+
+func BenchmarkFormatMessages(b *testing.B) {
+	for _, turns := range []int{10, 100, 1000} {
+		b.Run(fmt.Sprintf("turns=%d", turns), func(b *testing.B) {
+			m := benchmarkModel(b, turns)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_ = m.formatMessages()
+			}
+		})
+	}
+}
+
+func BenchmarkRenderOptimizedViewport(b *testing.B) {
+	for _, kb := range []int{1, 16, 128} {
+		b.Run(fmt.Sprintf("response=%dKB", kb), func(b *testing.B) {
+			m := benchmarkModel(b, 10)
+			m.updateRenderedLinesCache()
+			m.currentResp = strings.Repeat(strings.Join(streamSamples, ""), kb*1024/len(strings.Join(streamSamples, ""))+1)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				m.renderOptimizedViewport()
+			}
+		})
+	}
+}
+
+// BenchmarkStreamRender 将模拟流逐块送入 Update，覆盖流式回复的完整渲染路径
+// （追加内容、判断是否重绘、增量渲染视口）
+func BenchmarkStreamRender(b *testing.B) {
+	chunks := syntheticStream(*streamKBps, *streamMinutes)
+	size := 0
+	for _, chunk := range chunks {
+		size += len(chunk)
+	}
+	base := benchmarkModel(b, 10)
+	base.updateRenderedLinesCache()
+
+	b.SetBytes(int64(size))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m := base
+		m.thinking = true
+		m.currentResp = ""
+		for _, chunk := range chunks {
+			next, _ := m.Update(StreamChunkMsg{Chunk: chunk})
+			m = next.(Model)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package tui
+
+import (
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
+	"github.com/muesli/termenv"
+)
+
+// renderKey 影响渲染结果的终端状态：宽度决定表格布局，颜色能力决定样式输出，
+// 超链接支持决定是否输出 OSC 8 文件链接，界面语言决定角色标签
+type renderKey struct {
+	width      int
+	profile    termenv.Profile
+	hyperlinks bool
+	lang       i18n.Language
+}
+
+// currentRenderKey 返回当前视口对应的渲染键
+func (m *Model) currentRenderKey() renderKey {
+	return renderKey{
+		width:      m.viewport.Width,
+		profile:    m.caps.Profile,
+		hyperlinks: m.caps.Hyperlinks,
+		lang:       i18n.CurrentLanguage(),
+	}
+}
+
+// renderedLinesValid 历史消息的渲染缓存是否仍适用于当前终端状态
+func (m *Model) renderedLinesValid() bool {
+	return m.renderedLines != nil && m.renderedKey == m.currentRenderKey()
+}
+
+// redraw 丢弃历史消息的渲染缓存，按当前状态重新排版已显示的内容，
+// 用于终端尺寸或颜色能力变化、展开折叠工具调用面板之后
+func (m *Model) redraw() {
+	m.renderedLines = nil
+	if len(m.messages) == 0 && m.currentResp == "" {
+		// 只有欢迎信息，不需要重新排版
+		return
+	}
+	if m.thinking {
+		m.updateRenderedLinesCache()
+		m.renderOptimizedViewport()
+		return
+	}
+	m.updateViewport()
+}
@@ -0,0 +1,91 @@
+package tui
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
+	"github.com/Zacy-Sokach/PolyAgent/internal/mcp"
+)
+
+const (
+	// globalReplaceToolName 执行前需要用户确认的批量替换工具
+	globalReplaceToolName = "global_replace"
+	// applyReplacementsToolName 应用 find_and_replace 结果的工具，执行前需要用户确认
+	applyReplacementsToolName = "apply_replacements"
+	// replaceApprovalMaxFiles 审批提示中最多逐个列出的文件数
+	replaceApprovalMaxFiles = 20
+)
+
+// pendingReplaceApprovals 返回挂起的批量替换，逐个列出将被修改的文件或匹配；dry_run 不需要确认
+func (m *Model) pendingReplaceApprovals() []approvalRequest {
+	var requests []approvalRequest
+	for _, call := range m.pendingToolCalls {
+		if call.Function.Name == applyReplacementsToolName {
+			if request, ok := m.applyReplacementsApproval(call.ID, call.Function.Arguments); ok {
+				requests = append(requests, request)
+			}
+			continue
+		}
+		if call.Function.Name != globalReplaceToolName {
+			continue
+		}
+		var args map[string]interface{}
+		// 参数无法解析时由工具本身报错
+		if err := json.Unmarshal(call.Function.Arguments, &args); err != nil {
+			continue
+		}
+		if dryRun, _ := args["dry_run"].(bool); dryRun {
+			continue
+		}
+
+		manifest, err := m.toolManager.PreviewGlobalReplace(args)
+		if err != nil || manifest == nil || len(manifest.Files) == 0 {
+			continue
+		}
+		requests = append(requests, approvalRequest{
+			CallID: call.ID,
+			Item:   formatReplacePreview(manifest),
+		})
+	}
+	return requests
+}
+
+// formatReplacePreview 格式化批量替换的审批提示
+func formatReplacePreview(manifest *mcp.ReplaceManifest) string {
+	var sb strings.Builder
+	sb.WriteString(i18n.T("approval.replace_item", manifest.Pattern, manifest.Replacement, len(manifest.Files), manifest.TotalReplacements))
+	for i, file := range manifest.Files {
+		if i == replaceApprovalMaxFiles {
+			sb.WriteString(i18n.T("approval.replace_more", len(manifest.Files)-i))
+			break
+		}
+		sb.WriteString(i18n.T("approval.replace_file", file.Path, file.Replacements))
+	}
+	return sb.String()
+}
+
+// applyReplacementsApproval 列出 apply_replacements 将应用的每处替换
+func (m *Model) applyReplacementsApproval(callID string, arguments json.RawMessage) (approvalRequest, bool) {
+	var args struct {
+		IDs []string `json:"ids"`
+	}
+	if err := json.Unmarshal(arguments, &args); err != nil || len(args.IDs) == 0 {
+		return approvalRequest{}, false
+	}
+	candidates, err := m.toolManager.PreviewReplacements(args.IDs)
+	if err != nil || len(candidates) == 0 {
+		return approvalRequest{}, false
+	}
+
+	var sb strings.Builder
+	sb.WriteString(i18n.T("approval.apply_item", len(candidates)))
+	for i, c := range candidates {
+		if i == replaceApprovalMaxFiles {
+			sb.WriteString(i18n.T("approval.replace_more_matches", len(candidates)-i))
+			break
+		}
+		sb.WriteString(i18n.T("approval.apply_match", c.ID, c.Path, c.Line, c.Before, c.After))
+	}
+	return approvalRequest{CallID: callID, Item: sb.String()}, true
+}
@@ -0,0 +1,100 @@
+package tui
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/api"
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// retryOptions /retry 的参数
+type retryOptions struct {
+	// temperature 为 nil 时使用默认温度
+	temperature *float64
+	// note 附加到用户消息后的要求，如“更简洁一些”
+	note string
+}
+
+// parseRetryOptions 解析 /retry [t=温度] [附加要求]
+func parseRetryOptions(content string) (retryOptions, bool) {
+	var opts retryOptions
+	fields := strings.Fields(content)
+	if len(fields) > 0 {
+		name, value, ok := strings.Cut(fields[0], "=")
+		if ok && (name == "t" || name == "temperature") {
+			t, err := strconv.ParseFloat(value, 64)
+			if err != nil || t < 0 || t > 1 {
+				return opts, false
+			}
+			opts.temperature = &t
+			fields = fields[1:]
+		}
+	}
+	opts.note = strings.Join(fields, " ")
+	return opts, true
+}
+
+// handleRetryCommand 处理 /retry 命令：移除上一轮回复（包括工具调用和结果）并重新请求
+func (m *Model) handleRetryCommand(cmd *Command) tea.Cmd {
+	respond := func(content string) tea.Cmd {
+		return func() tea.Msg {
+			return ResponseMsg{Content: content}
+		}
+	}
+	if m.thinking {
+		return respond(i18n.T("command.retry_busy"))
+	}
+	opts, ok := parseRetryOptions(cmd.Content)
+	if !ok {
+		return respond(i18n.T("command.retry_usage"))
+	}
+
+	userIndex := -1
+	for i := len(m.apiMessages) - 1; i >= 0; i-- {
+		if m.apiMessages[i].Role == "user" {
+			userIndex = i
+			break
+		}
+	}
+	if userIndex < 0 {
+		return respond(i18n.T("command.retry_nothing"))
+	}
+
+	// API 历史保留最后一条用户消息，附加要求写入该消息
+	m.apiMessages = m.apiMessages[:userIndex+1]
+	if opts.note != "" {
+		text := api.MessageText(m.apiMessages[userIndex])
+		m.apiMessages[userIndex] = api.TextMessage("user", text+"\n\n"+opts.note)
+	}
+
+	// 界面上移除最后一条用户消息之后的内容
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if m.messages[i].Role == "user" {
+			m.messages = m.messages[:i+1]
+			break
+		}
+	}
+	notice := i18n.T("command.retrying")
+	if opts.note != "" {
+		notice = i18n.T("command.retrying_with_note", opts.note)
+	}
+	m.messages = append(m.messages, Message{Role: "system", Content: notice})
+
+	m.retryTemperature = opts.temperature
+	return m.rerunLastTurn()
+}
+
+// rerunLastTurn 从 API 历史中的最后一条用户消息重新开始这一轮
+func (m *Model) rerunLastTurn() tea.Cmd {
+	m.pendingToolCalls = nil
+	m.thinking = true
+	m.turnStartedAt = time.Now()
+	m.beginTurnCheckpoint()
+	m.stats.addRetry()
+	m.stallRetries = 0
+	m.contextRetried = false
+	return tea.Batch(m.updateViewport(), m.retryStream(), statusTickCmd())
+}
@@ -0,0 +1,36 @@
+package tui
+
+import (
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// handleScopeCommand 处理 /scope 命令：不带参数时显示当前的搜索范围，off 取消限制，
+// 带目录时将搜索、符号查找和 glob 限定在该目录内（仅对本次会话生效）
+func (m *Model) handleScopeCommand(cmd *Command) tea.Cmd {
+	var content string
+	switch cmd.Content {
+	case "":
+		if scope := m.toolManager.Scope(); scope != "" {
+			content = i18n.T("command.scope_current", scope)
+		} else {
+			content = i18n.T("command.scope_none")
+		}
+	case "off", "clear":
+		m.toolManager.SetScope("")
+		content = i18n.T("command.scope_cleared")
+	default:
+		scope, err := m.toolManager.SetScope(cmd.Content)
+		switch {
+		case err != nil:
+			content = i18n.T("command.scope_failed", err)
+		case scope == "":
+			content = i18n.T("command.scope_cleared")
+		default:
+			content = i18n.T("command.scope_set", scope)
+		}
+	}
+	return func() tea.Msg {
+		return ResponseMsg{Content: content}
+	}
+}
@@ -0,0 +1,36 @@
+package tui
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestScopeCommand(t *testing.T) {
+	m := goldenModel(t)
+	if err := os.MkdirAll("services/api", 0755); err != nil {
+		t.Fatal(err)
+	}
+	parser := NewCommandParser()
+	reply := func(input string) string {
+		t.Helper()
+		return m.handleScopeCommand(parser.Parse(input))().(ResponseMsg).Content
+	}
+
+	if got := reply("/scope services/missing"); !strings.Contains(got, "❌") {
+		t.Errorf("missing directory: %q", got)
+	}
+	if got := reply("/scope services/api"); !strings.Contains(got, "services/api") || m.toolManager.Scope() != "services/api" {
+		t.Fatalf("/scope services/api: %q", got)
+	}
+	if !strings.Contains(strings.Join(m.systemInstructions(), "\n"), "services/api") {
+		t.Error("system prompt should mention the scope")
+	}
+	if got := reply("/scope"); !strings.Contains(got, "services/api") {
+		t.Errorf("/scope should show the current scope: %q", got)
+	}
+	reply("/scope off")
+	if m.toolManager.Scope() != "" {
+		t.Error("/scope off should remove the scope")
+	}
+}
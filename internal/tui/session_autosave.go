@@ -0,0 +1,132 @@
+package tui
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/api"
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
+	"github.com/Zacy-Sokach/PolyAgent/internal/utils"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// defaultAutosaveInterval 默认的会话自动保存间隔
+const defaultAutosaveInterval = 30 * time.Second
+
+// autosaveTickMsg 定期自动保存会话
+type autosaveTickMsg struct{}
+
+// ShutdownMsg 收到终止信号（SIGTERM/SIGHUP 等）时由 main 发送，保存会话后退出
+type ShutdownMsg struct {
+	Signal os.Signal
+}
+
+// autosaveInterval 返回自动保存间隔，禁用时返回 0
+func (m *Model) autosaveInterval() time.Duration {
+	if m.config == nil || m.config.AutosaveInterval == 0 {
+		return defaultAutosaveInterval
+	}
+	if m.config.AutosaveInterval < 0 {
+		return 0
+	}
+	return time.Duration(m.config.AutosaveInterval) * time.Second
+}
+
+// autosaveTickCmd 安排下一次自动保存，禁用时返回 nil
+func (m *Model) autosaveTickCmd() tea.Cmd {
+	interval := m.autosaveInterval()
+	if interval == 0 {
+		return nil
+	}
+	return tea.Tick(interval, func(time.Time) tea.Msg {
+		return autosaveTickMsg{}
+	})
+}
+
+// autosave 保存会话快照和编辑器状态，内容未变化时跳过
+func (m *Model) autosave() error {
+	if m.editor != nil {
+		m.editor.Checkpoint()
+	}
+
+	if len(m.messages) == 0 && len(m.apiMessages) == 0 {
+		m.lastAutosave = ""
+		return utils.ClearAutosave()
+	}
+
+	apiMessages, err := json.Marshal(m.apiMessages)
+	if err != nil {
+		return err
+	}
+	snapshot := &utils.SessionSnapshot{
+		SavedAt:     time.Now(),
+		Title:       m.sessionTitle,
+		Messages:    m.historyMessages(),
+		APIMessages: apiMessages,
+	}
+
+	// 以消息内容作为签名，避免无变化时重复写盘
+	signature, err := json.Marshal(struct {
+		Title    string
+		Messages []utils.Message
+		API      json.RawMessage
+	}{snapshot.Title, snapshot.Messages, snapshot.APIMessages})
+	if err != nil {
+		return err
+	}
+	if string(signature) == m.lastAutosave {
+		return nil
+	}
+
+	if err := utils.SaveAutosave(snapshot); err != nil {
+		return err
+	}
+	m.lastAutosave = string(signature)
+	return nil
+}
+
+// restoreAutosave 恢复上次异常退出时自动保存的会话
+func (m *Model) restoreAutosave() {
+	snapshot, err := utils.LoadAutosave()
+	if err != nil || snapshot == nil || len(snapshot.Messages) == 0 {
+		return
+	}
+
+	var apiMessages []api.Message
+	if len(snapshot.APIMessages) > 0 {
+		if err := json.Unmarshal(snapshot.APIMessages, &apiMessages); err != nil {
+			return
+		}
+	}
+	// 末尾未得到结果的工具调用无法继续，丢弃以免下一次请求被拒绝
+	for len(apiMessages) > 0 && len(apiMessages[len(apiMessages)-1].ToolCalls) > 0 {
+		apiMessages = apiMessages[:len(apiMessages)-1]
+	}
+
+	for _, msg := range snapshot.Messages {
+		m.messages = append(m.messages, Message{Role: msg.Role, Content: msg.Content})
+	}
+	m.apiMessages = apiMessages
+	if snapshot.Title != "" {
+		m.sessionTitle = snapshot.Title
+		m.titleRequested = true
+	}
+	m.messages = append(m.messages, Message{
+		Role:    "system",
+		Content: i18n.T("session.restored", snapshot.SavedAt.Format("2006-01-02 15:04")),
+	})
+	m.updateViewport()
+}
+
+// historyMessages 将界面消息转换为历史记录格式
+func (m *Model) historyMessages() []utils.Message {
+	historyMessages := make([]utils.Message, len(m.messages))
+	for i, msg := range m.messages {
+		historyMessages[i] = utils.Message{
+			Role:    msg.Role,
+			Content: msg.Content,
+		}
+	}
+	return historyMessages
+}
@@ -0,0 +1,269 @@
+package tui
+
+import (
+	"fmt"
+	"maps"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/api"
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
+	"github.com/Zacy-Sokach/PolyAgent/internal/utils"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// statsTopTools /stats 中按调用次数列出的工具数
+const statsTopTools = 8
+
+// sessionStats 本次会话的请求和 token 统计。
+// 由 API 客户端的 Hook 在请求所在的 goroutine 中更新，Model 的副本共享同一个实例
+type sessionStats struct {
+	mu        sync.Mutex
+	startedAt time.Time
+	turns     int
+	retries   int
+
+	requests       int
+	failedRequests int
+	inputTokens    int
+	outputTokens   int
+	cachedTokens   int
+	estimated      bool
+	// 按模型分别统计的请求数和 token 用量
+	models map[string]utils.ModelUsage
+}
+
+func newSessionStats() *sessionStats {
+	return &sessionStats{startedAt: time.Now()}
+}
+
+// addTurn 记录用户发起的一轮对话
+func (s *sessionStats) addTurn() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.turns++
+}
+
+// addRetry 记录一次重试（/retry 或流式响应停滞后的自动重试）
+func (s *sessionStats) addRetry() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.retries++
+}
+
+// hook 返回统计请求数和 token 用量的 Hook，服务商没有返回用量时按消息估算
+func (s *sessionStats) hook() api.Hook {
+	var promptTokens int
+	var model string
+	return api.HookFuncs{
+		Request: func(req *api.ChatRequest) {
+			promptTokens = api.EstimateTokens(req.Messages)
+			model = req.Model
+		},
+		Complete: func(resp *api.ChatResponse) {
+			input, output := promptTokens, 0
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if usage := resp.Usage; usage != nil && usage.TotalTokens > 0 {
+				input, output = usage.PromptTokens, usage.CompletionTokens
+				s.cachedTokens += usage.CachedTokens()
+			} else {
+				s.estimated = true
+				for _, choice := range resp.Choices {
+					if choice.Message != nil {
+						output += api.EstimateMessageTokens(*choice.Message)
+					}
+				}
+			}
+			s.requests++
+			s.inputTokens += input
+			s.outputTokens += output
+			s.addModelUsage(model, input, output)
+		},
+		Error: func(error) {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.requests++
+			s.failedRequests++
+			s.addModelUsage(model, 0, 0)
+		},
+	}
+}
+
+// addModelUsage 记入一次请求的模型用量，调用方需持有锁
+func (s *sessionStats) addModelUsage(model string, input, output int) {
+	if model == "" {
+		return
+	}
+	if s.models == nil {
+		s.models = make(map[string]utils.ModelUsage)
+	}
+	usage := s.models[model]
+	usage.Requests++
+	usage.InputTokens += input
+	usage.OutputTokens += output
+	s.models[model] = usage
+}
+
+// withStats 为客户端加上统计用量的 Hook
+func (m *Model) withStats(client *api.Client) *api.Client {
+	if m.stats == nil {
+		return client
+	}
+	return client.WithHooks(m.stats.hook())
+}
+
+// sessionStatsSnapshot 汇总本次会话的统计，用于 /stats 和保存会话记录
+func (m *Model) sessionStatsSnapshot() *utils.SessionStats {
+	if m.stats == nil {
+		return nil
+	}
+	m.stats.mu.Lock()
+	stats := &utils.SessionStats{
+		DurationSeconds: int(time.Since(m.stats.startedAt).Seconds()),
+		Turns:           m.stats.turns,
+		Retries:         m.stats.retries,
+		Requests:        m.stats.requests,
+		FailedRequests:  m.stats.failedRequests,
+		InputTokens:     m.stats.inputTokens,
+		OutputTokens:    m.stats.outputTokens,
+		CachedTokens:    m.stats.cachedTokens,
+		TokensEstimated: m.stats.estimated,
+		Models:          maps.Clone(m.stats.models),
+	}
+	m.stats.mu.Unlock()
+
+	if _, ok := m.budgetConfig(); ok {
+		stats.EstimatedCost, _ = m.budget.totals()
+	}
+	if m.toolManager != nil {
+		tools := m.toolManager.SessionStats()
+		stats.ToolCalls = tools.Calls
+		stats.ToolFailures = tools.Failed
+		stats.FilesRead = tools.FilesRead
+		stats.FilesWritten = tools.FilesWritten
+		stats.FileCacheHits = tools.CacheHits
+		stats.FileCacheMisses = tools.CacheMisses
+	}
+	return stats
+}
+
+// handleStatsCommand 处理 /stats 命令，显示本次会话的统计
+func (m *Model) handleStatsCommand() tea.Cmd {
+	content := formatSessionStats(m.sessionStatsSnapshot())
+	return func() tea.Msg {
+		return ResponseMsg{Content: content}
+	}
+}
+
+// formatSessionStats 将会话统计排版为一屏以内的文本
+func formatSessionStats(s *utils.SessionStats) string {
+	if s == nil {
+		return i18n.T("stats.empty")
+	}
+	lines := []string{
+		i18n.T("stats.title"),
+		i18n.T("stats.overview", formatElapsed(time.Duration(s.DurationSeconds)*time.Second), s.Turns, s.Retries),
+	}
+
+	tokens := i18n.T("stats.tokens", s.Requests, formatTokenCount(s.InputTokens), formatTokenCount(s.OutputTokens))
+	if s.FailedRequests > 0 {
+		tokens += " " + i18n.T("stats.failed_requests", s.FailedRequests)
+	}
+	if s.TokensEstimated {
+		tokens += " " + i18n.T("stats.estimated")
+	}
+	lines = append(lines, tokens)
+	if len(s.Models) > 1 {
+		// 只用了一个模型时与总计相同，不重复显示
+		lines = append(lines, formatModelUsage(s.Models)...)
+	}
+	if s.EstimatedCost > 0 {
+		lines = append(lines, i18n.T("stats.cost", s.EstimatedCost))
+	}
+
+	total := 0
+	for _, n := range s.ToolCalls {
+		total += n
+	}
+	if total == 0 {
+		lines = append(lines, i18n.T("stats.no_tools"))
+	} else {
+		tools := i18n.T("stats.tools", total)
+		if s.ToolFailures > 0 {
+			tools += " " + i18n.T("stats.tool_failures", s.ToolFailures)
+		}
+		lines = append(lines, tools, "  "+formatToolCounts(s.ToolCalls))
+	}
+	lines = append(lines, i18n.T("stats.files", s.FilesRead, s.FilesWritten))
+
+	var caches []string
+	if s.CachedTokens > 0 && s.InputTokens > 0 {
+		caches = append(caches, i18n.T("stats.prompt_cache", formatRatio(s.CachedTokens, s.InputTokens)))
+	}
+	if lookups := s.FileCacheHits + s.FileCacheMisses; lookups > 0 {
+		caches = append(caches, i18n.T("stats.file_cache", formatRatio(int(s.FileCacheHits), int(lookups)), s.FileCacheHits, lookups))
+	}
+	if len(caches) > 0 {
+		lines = append(lines, i18n.T("stats.cache", strings.Join(caches, " · ")))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatModelUsage 按输入 token 从多到少列出各模型的用量，每个模型一行
+func formatModelUsage(models map[string]utils.ModelUsage) []string {
+	names := make([]string, 0, len(models))
+	for name := range models {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if models[names[i]].InputTokens != models[names[j]].InputTokens {
+			return models[names[i]].InputTokens > models[names[j]].InputTokens
+		}
+		return names[i] < names[j]
+	})
+
+	lines := make([]string, 0, len(names))
+	for _, name := range names {
+		u := models[name]
+		lines = append(lines, "  "+i18n.T("stats.model", name, u.Requests, formatTokenCount(u.InputTokens), formatTokenCount(u.OutputTokens)))
+	}
+	return lines
+}
+
+// formatToolCounts 按调用次数从多到少列出工具，如 "read_file ×12, glob ×3"
+func formatToolCounts(calls map[string]int) string {
+	names := make([]string, 0, len(calls))
+	for name := range calls {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if calls[names[i]] != calls[names[j]] {
+			return calls[names[i]] > calls[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	parts := make([]string, 0, statsTopTools+1)
+	for i, name := range names {
+		if i == statsTopTools {
+			parts = append(parts, i18n.T("stats.more_tools", len(names)-statsTopTools))
+			break
+		}
+		parts = append(parts, fmt.Sprintf("%s ×%d", name, calls[name]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatRatio 格式化百分比，如 42%
+func formatRatio(part, total int) string {
+	return fmt.Sprintf("%d%%", part*100/total)
+}
@@ -0,0 +1,72 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/api"
+	"github.com/Zacy-Sokach/PolyAgent/internal/utils"
+)
+
+func TestSessionStatsHook(t *testing.T) {
+	stats := newSessionStats()
+	hook := stats.hook()
+
+	hook.OnRequest(&api.ChatRequest{Model: "glm-4.5", Messages: []api.Message{api.TextMessage("user", "你好")}})
+	hook.OnComplete(&api.ChatResponse{Usage: &api.Usage{
+		PromptTokens: 1000, CompletionTokens: 200, TotalTokens: 1200,
+		PromptTokensDetails: &api.PromptTokensDetails{CachedTokens: 400},
+	}})
+	// 没有返回用量时按消息估算
+	hook.OnRequest(&api.ChatRequest{Model: "glm-4.5-air", Messages: []api.Message{api.TextMessage("user", "继续")}})
+	hook.OnComplete(&api.ChatResponse{Choices: []api.Choice{{Message: &api.Message{Role: "assistant"}}}})
+	hook.OnError(api.ErrStreamStalled)
+
+	if stats.requests != 3 || stats.failedRequests != 1 {
+		t.Errorf("requests %d, failed %d", stats.requests, stats.failedRequests)
+	}
+	if stats.inputTokens <= 1000 || stats.cachedTokens != 400 || !stats.estimated {
+		t.Errorf("input %d, cached %d, estimated %v", stats.inputTokens, stats.cachedTokens, stats.estimated)
+	}
+	// 按模型分别统计，失败的请求计入最后一次请求的模型
+	if main, air := stats.models["glm-4.5"], stats.models["glm-4.5-air"]; main.Requests != 1 || main.InputTokens != 1000 || air.Requests != 2 || air.InputTokens == 0 {
+		t.Errorf("models = %+v", stats.models)
+	}
+}
+
+func TestFormatSessionStats(t *testing.T) {
+	goldenModel(t)
+	text := formatSessionStats(&utils.SessionStats{
+		DurationSeconds: 125,
+		Turns:           3,
+		Retries:         1,
+		Requests:        7,
+		InputTokens:     20000,
+		OutputTokens:    1500,
+		CachedTokens:    5000,
+		EstimatedCost:   0.0123,
+		ToolCalls:       map[string]int{"read_file": 5, "glob": 2, "write_file": 2},
+		ToolFailures:    1,
+		FilesRead:       4,
+		FilesWritten:    2,
+		FileCacheHits:   1,
+		FileCacheMisses: 4,
+		Models: map[string]utils.ModelUsage{
+			"glm-4.5":       {Requests: 3, InputTokens: 18000, OutputTokens: 1200},
+			"glm-4.5-flash": {Requests: 4, InputTokens: 2000, OutputTokens: 300},
+		},
+	})
+	for _, want := range []string{"2m05s", "3 轮", "重试 1 次", "请求 7 次", "glm-4.5: 请求 3 次 · 输入 18k token", "glm-4.5-flash: 请求 4 次", "$0.0123", "工具调用 9 次", "read_file ×5, glob ×2, write_file ×2", "读取 4 个", "写入 2 个", "提示缓存 25%", "文件缓存 20% (1/5)"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("stats missing %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestStatsCommand(t *testing.T) {
+	m := goldenModel(t)
+	m.stats.addTurn()
+	if got := m.handleStatsCommand()().(ResponseMsg).Content; !strings.Contains(got, "1 轮") || !strings.Contains(got, "没有调用工具") {
+		t.Errorf("/stats = %q", got)
+	}
+}
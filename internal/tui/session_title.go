@@ -0,0 +1,200 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/api"
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
+	"github.com/Zacy-Sokach/PolyAgent/internal/utils"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	// sessionTitleMaxRunes 会话标题的最大长度
+	sessionTitleMaxRunes = 40
+	// sessionTitleExcerptRunes 生成标题时每条消息最多截取的长度
+	sessionTitleExcerptRunes = 1000
+	// historyListLimit /history 最多列出的会话数
+	historyListLimit = 20
+	// appWindowTitle 未命名会话的窗口标题
+	appWindowTitle = "PolyAgent"
+)
+
+// sessionTitlePrompt 生成会话标题的系统提示
+const sessionTitlePrompt = `根据下面的对话生成一个简短的标题，概括用户要做的事情。
+要求：使用与用户相同的语言；不超过 20 个字；只输出标题本身，不要引号、标点结尾或任何解释。`
+
+// sessionTitleMsg 后台生成的会话标题
+type sessionTitleMsg struct {
+	Title string
+}
+
+// maybeGenerateSessionTitle 在第一轮对话完成后后台生成标题，每个会话只生成一次
+func (m *Model) maybeGenerateSessionTitle(reply string) tea.Cmd {
+	if m.sessionTitle != "" || m.titleRequested || m.apiKey == "" {
+		return nil
+	}
+
+	var question string
+	for _, msg := range m.messages {
+		if msg.Role == "user" {
+			question = msg.Content
+			break
+		}
+	}
+	if strings.TrimSpace(question) == "" {
+		return nil
+	}
+	m.titleRequested = true
+
+	client := m.withBudget(m.withStats(api.NewClient(m.apiKey)))
+	if model := m.taskModel(modelTaskTitle); model != "" {
+		client = client.WithModel(model)
+	}
+	return func() tea.Msg {
+		messages := []api.Message{
+			api.TextMessage("system", sessionTitlePrompt),
+			api.TextMessage("user", fmt.Sprintf("用户: %s\n\n助手: %s", excerpt(question), excerpt(reply))),
+		}
+		resp, err := client.ChatCompletion(messages, false, nil)
+		if err != nil || len(resp.Choices) == 0 || resp.Choices[0].Message == nil {
+			// 标题只是辅助信息，生成失败时保持未命名
+			return nil
+		}
+
+		title := normalizeSessionTitle(api.MessageText(*resp.Choices[0].Message))
+		if title == "" {
+			return nil
+		}
+		return sessionTitleMsg{Title: title}
+	}
+}
+
+// setSessionTitle 设置会话标题并同步终端窗口标题
+func (m *Model) setSessionTitle(title string, manual bool) tea.Cmd {
+	m.sessionTitle = title
+	m.titleManual = manual
+	return tea.SetWindowTitle(windowTitle(title))
+}
+
+// resetSessionTitle 清除会话标题，下一轮对话后重新生成
+func (m *Model) resetSessionTitle() tea.Cmd {
+	m.titleRequested = false
+	return m.setSessionTitle("", false)
+}
+
+// handleRenameCommand 处理 /rename 命令，手动设置的标题不会被自动生成的标题覆盖
+func (m *Model) handleRenameCommand(cmd *Command) tea.Cmd {
+	title := normalizeSessionTitle(cmd.Content)
+	if title == "" {
+		return func() tea.Msg {
+			return ResponseMsg{Content: i18n.T("command.rename_usage")}
+		}
+	}
+
+	return tea.Batch(
+		m.setSessionTitle(title, true),
+		func() tea.Msg {
+			return ResponseMsg{Content: i18n.T("command.renamed", title)}
+		},
+	)
+}
+
+// handleHistoryCommand 处理 /history 命令，列出最近保存的会话
+func (m *Model) handleHistoryCommand() tea.Cmd {
+	return func() tea.Msg {
+		history, err := utils.LoadHistory()
+		if err != nil {
+			return ResponseMsg{Content: i18n.T("command.history_failed", err)}
+		}
+		if len(history) == 0 {
+			return ResponseMsg{Content: i18n.T("command.history_empty")}
+		}
+
+		var sb strings.Builder
+		sb.WriteString(i18n.T("command.history_header"))
+		for i := 0; i < len(history) && i < historyListLimit; i++ {
+			entry := history[len(history)-1-i]
+			title := entry.Title
+			if title == "" {
+				title = i18n.T("command.history_untitled")
+			}
+			sb.WriteString(i18n.T("command.history_item", i+1, entry.Timestamp.Format("2006-01-02 15:04"), title, len(entry.Messages)))
+		}
+		return ResponseMsg{Content: sb.String()}
+	}
+}
+
+// ResumeHistory 恢复 /history 列表中的第 n 个会话（1 为最近的会话），替换当前消息，用于命令行 --resume
+// 历史记录只保存文本消息，工具调用不会恢复
+func (m *Model) ResumeHistory(n int) error {
+	history, err := utils.LoadHistory()
+	if err != nil {
+		return err
+	}
+	if n < 1 || n > len(history) {
+		return errors.New(i18n.T("session.resume_missing", n, len(history)))
+	}
+	entry := history[len(history)-n]
+
+	m.messages = nil
+	m.apiMessages = nil
+	for _, msg := range entry.Messages {
+		m.messages = append(m.messages, Message{Role: msg.Role, Content: msg.Content})
+		if msg.Role == "user" || msg.Role == "assistant" {
+			m.apiMessages = append(m.apiMessages, api.TextMessage(msg.Role, msg.Content))
+		}
+	}
+	title := entry.Title
+	if title != "" {
+		m.sessionTitle = title
+		m.titleRequested = true
+	} else {
+		title = i18n.T("command.history_untitled")
+	}
+	m.messages = append(m.messages, Message{
+		Role:    "system",
+		Content: i18n.T("session.resumed", title, entry.Timestamp.Format("2006-01-02 15:04")),
+	})
+	m.updateViewport()
+	return nil
+}
+
+// normalizeSessionTitle 清理模型输出或用户输入的标题：取第一行，去掉引号和多余空白，并限制长度
+func normalizeSessionTitle(title string) string {
+	title = strings.TrimSpace(title)
+	if i := strings.IndexByte(title, '\n'); i >= 0 {
+		title = title[:i]
+	}
+	title = strings.TrimLeft(title, "# ")
+	for _, prefix := range []string{"标题：", "标题:", "Title:", "title:"} {
+		title = strings.TrimPrefix(title, prefix)
+	}
+	title = strings.Trim(strings.TrimSpace(title), "\"'`“”‘’「」《》")
+	title = strings.Join(strings.Fields(title), " ")
+	title = strings.TrimRight(title, "。.")
+
+	if utf8.RuneCountInString(title) > sessionTitleMaxRunes {
+		title = string([]rune(title)[:sessionTitleMaxRunes-1]) + "…"
+	}
+	return title
+}
+
+// windowTitle 返回终端窗口标题
+func windowTitle(sessionTitle string) string {
+	if sessionTitle == "" {
+		return appWindowTitle
+	}
+	return appWindowTitle + " - " + sanitizeOSCField(sessionTitle)
+}
+
+// excerpt 截取消息开头部分用于生成标题
+func excerpt(text string) string {
+	if truncated, ok := utils.TruncateText(text, sessionTitleExcerptRunes); ok {
+		return truncated + "..."
+	}
+	return text
+}
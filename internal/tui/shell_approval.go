@@ -0,0 +1,167 @@
+package tui
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/api"
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
+	"github.com/Zacy-Sokach/PolyAgent/internal/mcp"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// shellToolName 需要审批的命令执行工具
+const shellToolName = "run_shell_command"
+
+// approvalRequest 等待用户确认的一次工具调用
+type approvalRequest struct {
+	CallID string
+	// Item 审批提示中显示的内容
+	Item string
+}
+
+// shellPolicy 根据配置创建命令审批规则
+func (m *Model) shellPolicy() *mcp.ShellPolicy {
+	if m.config == nil {
+		return mcp.NewShellPolicy(nil, nil)
+	}
+	return mcp.NewShellPolicy(m.config.Shell.AutoApprove, m.config.Shell.AlwaysAsk)
+}
+
+// pendingApprovals 返回挂起的工具调用中需要用户确认的命令、删除和批量替换操作
+func (m *Model) pendingApprovals() []approvalRequest {
+	requests := append(m.pendingShellApprovals(), m.pendingDeleteApprovals()...)
+	return append(requests, m.pendingReplaceApprovals()...)
+}
+
+// pendingShellApprovals 返回挂起的工具调用中需要用户确认的命令
+func (m *Model) pendingShellApprovals() []approvalRequest {
+	var requests []approvalRequest
+	policy := m.shellPolicy()
+
+	for _, call := range m.pendingToolCalls {
+		if call.Function.Name != shellToolName {
+			continue
+		}
+		args, err := parseShellCall(call)
+		if err != nil {
+			// 无法解析的参数同样需要确认，不能因为这里解析失败就跳过审批
+			requests = append(requests, approvalRequest{
+				CallID: call.ID,
+				Item:   i18n.T("approval.shell_item", string(call.Function.Arguments), i18n.T("approval.no_rule", "invalid arguments: "+err.Error())),
+			})
+			continue
+		}
+
+		decision := policy.Evaluate(args.Command)
+		command := args.Command
+		// 额外的环境变量（如 LD_PRELOAD）可以改变命令行为，总是需要确认
+		if len(args.ExtraEnv) > 0 {
+			decision = mcp.ShellDecision{Reason: "sets extra_env"}
+			command = formatEnvPrefix(args.ExtraEnv) + command
+		}
+		if !decision.AutoApprove {
+			detail := i18n.T("approval.no_rule", decision.Reason)
+			if decision.Rule != "" {
+				detail = i18n.T("approval.matched_rule", decision.Rule)
+			}
+			requests = append(requests, approvalRequest{
+				CallID: call.ID,
+				Item:   i18n.T("approval.shell_item", command, detail),
+			})
+		}
+	}
+	return requests
+}
+
+// parseShellCall 用工具本身的解析规则读取命令执行调用的参数
+func parseShellCall(call api.ToolCall) (mcp.ShellArgs, error) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(call.Function.Arguments, &raw); err != nil {
+		return mcp.ShellArgs{}, err
+	}
+	return mcp.ParseShellArgs(raw)
+}
+
+// formatEnvPrefix 将环境变量格式化为命令前缀（按名称排序），用于审批提示
+func formatEnvPrefix(env map[string]string) string {
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	for _, name := range names {
+		sb.WriteString(name + "=" + strconv.Quote(env[name]) + " ")
+	}
+	return sb.String()
+}
+
+// requestApproval 显示审批提示并等待用户按键
+func (m *Model) requestApproval(requests []approvalRequest) tea.Cmd {
+	m.awaitingApproval = requests
+
+	var sb strings.Builder
+	for _, req := range requests {
+		sb.WriteString(req.Item)
+	}
+	m.messages = append(m.messages, Message{Role: "system", Content: i18n.T("approval.request", sb.String())})
+	return m.updateViewport()
+}
+
+// handleApprovalKey 处理审批期间的按键：y 允许，n 或 Esc 拒绝，其他按键忽略
+func (m *Model) handleApprovalKey(msg tea.KeyMsg) tea.Cmd {
+	switch strings.ToLower(msg.String()) {
+	case "y":
+		return m.resolveApproval(true)
+	case "n", "esc":
+		return m.resolveApproval(false)
+	}
+	return nil
+}
+
+// resolveApproval 记录用户的决定并继续执行挂起的工具调用
+func (m *Model) resolveApproval(approved bool) tea.Cmd {
+	if !approved {
+		m.deniedToolCalls = make(map[string]bool, len(m.awaitingApproval))
+		for _, req := range m.awaitingApproval {
+			m.deniedToolCalls[req.CallID] = true
+		}
+	}
+	m.awaitingApproval = nil
+
+	status := i18n.T("approval.approved")
+	if !approved {
+		status = i18n.T("approval.denied")
+	}
+	m.messages = append(m.messages, Message{Role: "system", Content: status})
+	return tea.Batch(m.updateViewport(), m.executePendingTools())
+}
+
+// runToolCalls 执行工具调用，被用户拒绝的调用直接返回拒绝结果，向用户提出的问题返回用户的回答
+func (m *Model) runToolCalls(calls []api.ToolCall, denied map[string]bool, answers map[string]string) ([]api.Message, error) {
+	if len(denied) == 0 && len(answers) == 0 {
+		return m.toolManager.HandleToolCalls(calls)
+	}
+
+	var messages []api.Message
+	for _, call := range calls {
+		if denied[call.ID] {
+			messages = append(messages, api.ToolResultMessage(call.ID, i18n.T("approval.denied_result")))
+			continue
+		}
+		if result, ok := answeredResult(call, answers); ok {
+			messages = append(messages, result)
+			continue
+		}
+		results, err := m.toolManager.HandleToolCalls([]api.ToolCall{call})
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, results...)
+	}
+	return messages, nil
+}
@@ -0,0 +1,38 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/api"
+	"github.com/Zacy-Sokach/PolyAgent/internal/config"
+)
+
+func TestPendingShellApprovalsFailClosed(t *testing.T) {
+	m := goldenModel(t)
+	// 即使命令本身命中自动批准规则，参数有问题时也要确认
+	m.config = &config.Config{Shell: config.ShellConfig{AutoApprove: []string{"rm", "git status"}}}
+	m.pendingToolCalls = []api.ToolCall{
+		// extra_env 的值不是字符串，工具仍会执行命令
+		toolCall("c1", "run_shell_command", `{"command":"rm -rf ~","extra_env":{"X":1}}`),
+		toolCall("c2", "run_shell_command", `{"command":"rm -rf ~"`),
+		toolCall("c3", "run_shell_command", `{"command":""}`),
+		toolCall("c4", "run_shell_command", `{"command":"git status"}`),
+	}
+
+	got := map[string]string{}
+	for _, req := range m.pendingShellApprovals() {
+		got[req.CallID] = req.Item
+	}
+	if !strings.Contains(got["c1"], `X="1" rm -rf ~`) {
+		t.Errorf("non-string extra_env not gated: %q", got["c1"])
+	}
+	for _, id := range []string{"c2", "c3"} {
+		if !strings.Contains(got[id], "invalid arguments") {
+			t.Errorf("unparseable call %s not gated: %q", id, got[id])
+		}
+	}
+	if _, ok := got["c4"]; ok {
+		t.Errorf("auto-approved command asked: %q", got["c4"])
+	}
+}
@@ -0,0 +1,54 @@
+package tui
+
+import (
+	"strings"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/api"
+)
+
+// flushStreamedText 将已收到的回复文本作为一段助手消息写入界面，返回该文本
+// 模型在文本和工具调用之间切换时调用，让界面按流中的真实顺序显示
+func (m *Model) flushStreamedText() string {
+	text := m.currentResp
+	m.currentResp = ""
+	if strings.TrimSpace(text) == "" {
+		return ""
+	}
+	text = postProcessAssistant(text)
+	m.messages = append(m.messages, Message{Role: "assistant", Content: text})
+	m.updateRenderedLinesCache()
+	return text
+}
+
+// appendAssistantToolCalls 将本轮的工具调用（及其之前的文本）写入 API 历史
+// 同一轮的多次工具调用合并到一条助手消息中，工具结果才能紧跟在对应的调用之后
+func (m *Model) appendAssistantToolCalls(text string, toolCalls []api.ToolCall) {
+	if n := len(m.apiMessages); n > 0 && len(m.pendingToolCalls) > 0 && len(m.apiMessages[n-1].ToolCalls) > 0 {
+		last := m.apiMessages[n-1]
+		merged := api.AssistantToolCallMessage(joinSegments(api.MessageText(last), text), append(append([]api.ToolCall(nil), last.ToolCalls...), toolCalls...))
+		m.apiMessages[n-1] = merged
+		return
+	}
+	m.apiMessages = append(m.apiMessages, api.AssistantToolCallMessage(text, toolCalls))
+}
+
+// appendTrailingText 将最后一次工具调用之后的文本并入本轮的工具调用消息
+func (m *Model) appendTrailingText(text string) {
+	n := len(m.apiMessages)
+	if text == "" || n == 0 || len(m.apiMessages[n-1].ToolCalls) == 0 {
+		return
+	}
+	last := m.apiMessages[n-1]
+	m.apiMessages[n-1] = api.AssistantToolCallMessage(joinSegments(api.MessageText(last), text), last.ToolCalls)
+}
+
+// joinSegments 拼接同一轮回复中的两段文本
+func joinSegments(a, b string) string {
+	switch {
+	case a == "":
+		return b
+	case b == "":
+		return a
+	}
+	return a + "\n\n" + b
+}
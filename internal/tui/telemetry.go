@@ -0,0 +1,150 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/api"
+	"github.com/Zacy-Sokach/PolyAgent/internal/config"
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
+	"github.com/Zacy-Sokach/PolyAgent/internal/telemetry"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// defaultTelemetryExport /telemetry export 未指定文件时导出的文件名
+const defaultTelemetryExport = "polyagent-telemetry.json"
+
+// statusCodePattern 从 API 错误中提取 HTTP 状态码
+var statusCodePattern = regexp.MustCompile(`状态码: (\d{3})`)
+
+// handleTelemetryCommand 处理 /telemetry 命令：status 查看统计，on/off 开启或关闭（关闭时删除已有统计），
+// export [文件] 导出为 JSON
+func (m *Model) handleTelemetryCommand(cmd *Command) tea.Cmd {
+	respond := func(content string) tea.Cmd {
+		return func() tea.Msg {
+			return ResponseMsg{Content: content}
+		}
+	}
+
+	fields := strings.Fields(cmd.Content)
+	action := "status"
+	if len(fields) > 0 {
+		action = strings.ToLower(fields[0])
+	}
+
+	switch action {
+	case "status":
+		return respond(telemetryStatus())
+	case "on", "off":
+		on := action == "on"
+		telemetry.SetEnabled(on)
+		if !on {
+			if err := telemetry.Clear(); err != nil {
+				return respond(i18n.T("command.telemetry_failed", err))
+			}
+		}
+		if m.config == nil {
+			return respond(i18n.T("command.telemetry_session_only"))
+		}
+		m.config.Telemetry = on
+		if err := config.SaveConfig(m.config); err != nil {
+			return respond(i18n.T("command.telemetry_save_failed", err))
+		}
+		if on {
+			return respond(i18n.T("command.telemetry_enabled"))
+		}
+		return respond(i18n.T("command.telemetry_disabled"))
+	case "export":
+		path := defaultTelemetryExport
+		if len(fields) > 1 {
+			path = fields[1]
+		}
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		if err := telemetry.Export(path); err != nil {
+			return respond(i18n.T("command.telemetry_failed", err))
+		}
+		return respond(i18n.T("command.telemetry_exported", displayPath(path)))
+	default:
+		return respond(i18n.T("command.telemetry_usage"))
+	}
+}
+
+// telemetryStatus 返回开启状态和已记录的计数
+func telemetryStatus() string {
+	var sb strings.Builder
+	if telemetry.Enabled() {
+		sb.WriteString(i18n.T("command.telemetry_status_on"))
+	} else {
+		sb.WriteString(i18n.T("command.telemetry_status_off"))
+	}
+
+	stats, err := telemetry.Load()
+	if err != nil {
+		return sb.String() + "\n" + i18n.T("command.telemetry_failed", err)
+	}
+	if path, err := telemetry.Path(); err == nil {
+		sb.WriteString(i18n.T("command.telemetry_file", path, stats.Since))
+	}
+	for _, group := range []struct {
+		key    string
+		counts map[string]int
+	}{
+		{"command.telemetry_commands", stats.Commands},
+		{"command.telemetry_tools", stats.Tools},
+		{"command.telemetry_errors", stats.Errors},
+	} {
+		if len(group.counts) > 0 {
+			sb.WriteString(i18n.T(group.key, formatCounts(group.counts)))
+		}
+	}
+	return sb.String()
+}
+
+// formatCounts 按次数从多到少列出计数，如 "read_file×12, write_file×3"
+func formatCounts(counts map[string]int) string {
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s×%d", name, counts[name])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// apiErrorClass 将请求错误归为不含细节的类别，用于使用统计
+func apiErrorClass(err error) string {
+	var netErr net.Error
+	var overflow *api.ContextOverflowError
+	switch {
+	case errors.As(err, &overflow):
+		return "api.context_overflow"
+	case errors.Is(err, api.ErrStreamStalled):
+		return "api.stream_stalled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "api.timeout"
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return "api.timeout"
+	case errors.As(err, &netErr):
+		return "api.network"
+	}
+	if matches := statusCodePattern.FindStringSubmatch(err.Error()); matches != nil {
+		return "api.http_" + matches[1]
+	}
+	return "api.other"
+}
@@ -0,0 +1,33 @@
+package tui
+
+import (
+	"github.com/Zacy-Sokach/PolyAgent/internal/utils"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// terminalCaps 渲染时使用的终端能力。启动时检测，窗口大小变化或重新获得焦点时重新检测：
+// tmux 重新连接到另一个终端时两者都会发生
+type terminalCaps struct {
+	Profile    termenv.Profile
+	Hyperlinks bool
+}
+
+// detectTerminalCaps 根据环境变量检测终端能力，不向终端发送查询，界面运行期间也可以调用
+func detectTerminalCaps() terminalCaps {
+	return terminalCaps{
+		Profile:    termenv.EnvColorProfile(),
+		Hyperlinks: utils.SupportsHyperlinks(),
+	}
+}
+
+// refreshTerminalCaps 重新检测终端能力，发生变化时更新样式的颜色输出，返回是否变化
+func (m *Model) refreshTerminalCaps() bool {
+	caps := detectTerminalCaps()
+	if caps == m.caps {
+		return false
+	}
+	m.caps = caps
+	lipgloss.SetColorProfile(caps.Profile)
+	return true
+}
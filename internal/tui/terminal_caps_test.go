@@ -0,0 +1,28 @@
+package tui
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestTerminalCapsRefreshOnFocus(t *testing.T) {
+	t.Setenv("POLYAGENT_HYPERLINKS", "0")
+	m := conversation(goldenModel(t))
+	if m.caps.Hyperlinks {
+		t.Fatal("hyperlinks should start disabled")
+	}
+	m.thinking = true
+	m.messages = append(m.messages, Message{Role: "user", Content: "继续"})
+	m.updateRenderedLinesCache()
+
+	// 例如 tmux 重新连接到支持超链接的终端
+	t.Setenv("POLYAGENT_HYPERLINKS", "1")
+	m = drive(t, m, tea.FocusMsg{})
+	if !m.caps.Hyperlinks {
+		t.Fatal("capabilities were not re-detected on focus")
+	}
+	if !m.renderedLinesValid() {
+		t.Error("render cache should be rebuilt for the new capabilities")
+	}
+}
@@ -0,0 +1,80 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// auditListLimit /audit 最多列出的调用数
+const auditListLimit = 50
+
+// handleAuditCommand 处理 /audit 命令，列出本次会话的工具调用记录
+func (m *Model) handleAuditCommand() tea.Cmd {
+	auditLog := m.toolManager.AuditLog()
+	return func() tea.Msg {
+		if auditLog == nil {
+			return ResponseMsg{Content: i18n.T("command.audit_disabled")}
+		}
+		entries, err := auditLog.Entries()
+		if err != nil {
+			return ResponseMsg{Content: i18n.T("command.audit_failed", err)}
+		}
+		if len(entries) == 0 {
+			return ResponseMsg{Content: i18n.T("command.audit_empty", auditLog.Path())}
+		}
+
+		var failed int
+		var written int64
+		for _, entry := range entries {
+			if !entry.Success {
+				failed++
+			}
+			written += entry.BytesWritten
+		}
+
+		var sb strings.Builder
+		sb.WriteString(i18n.T("command.audit_header", len(entries), failed, formatByteCount(written), auditLog.Path()))
+		if len(entries) > auditListLimit {
+			sb.WriteString(i18n.T("command.audit_truncated", len(entries)-auditListLimit))
+			entries = entries[len(entries)-auditListLimit:]
+		}
+		for _, entry := range entries {
+			status := "✓"
+			if !entry.Success {
+				status = "✗"
+			}
+			detail := ""
+			if entry.BytesWritten > 0 {
+				detail = i18n.T("command.audit_written", formatByteCount(entry.BytesWritten))
+			}
+			sb.WriteString(i18n.T("command.audit_item",
+				entry.Time.Format("15:04:05"), status, entry.Tool, entry.Target, entry.DurationMs, detail))
+			if entry.Error != "" {
+				if entry.ErrorType != "" {
+					sb.WriteString(fmt.Sprintf("    [%s %d] ", entry.ErrorType, entry.ErrorCode))
+				} else {
+					sb.WriteString("    ")
+				}
+				sb.WriteString(entry.Error + "\n")
+			}
+		}
+		return ResponseMsg{Content: sb.String()}
+	}
+}
+
+// formatByteCount 将字节数格式化为易读的大小
+func formatByteCount(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -34,7 +34,8 @@ func NewToolManagerState(toolManager interface{}, commandParser *CommandParser)
 	}()
 
 	if initError != nil {
-		// 可以选择记录错误或使用默认值
+		// 编辑器仍可使用，错误作为系统错误事件发布，不再静默丢弃
+		GetGlobalEventBus().PublishAsync(NewSystemErrorEvent(initError, "editor", nil))
 	}
 
 	return &ToolManagerState{
@@ -0,0 +1,320 @@
+package tui
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/api"
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// 工具调用在面板中的状态
+const (
+	toolStatusPending = "pending"
+	toolStatusRunning = "running"
+	toolStatusOK      = "ok"
+	toolStatusFailed  = "failed"
+	toolStatusDenied  = "denied"
+)
+
+// toolStatusIcons 各状态在面板中的图标
+var toolStatusIcons = map[string]string{
+	toolStatusPending: "·",
+	toolStatusRunning: "▸",
+	toolStatusOK:      "✓",
+	toolStatusFailed:  "✗",
+	toolStatusDenied:  "⊘",
+}
+
+// toolPanelSummaryWidth 折叠时每个调用的参数摘要最多占用的宽度
+const toolPanelSummaryWidth = 60
+
+// toolSummaryKeys 折叠时优先用来概括调用的参数
+var toolSummaryKeys = []string{"file_path", "path", "command", "pattern", "query", "url", "source", "directory"}
+
+// toolPanelCall 面板中的一次工具调用
+type toolPanelCall struct {
+	CallID   string
+	Name     string
+	Args     string
+	Status   string
+	Duration time.Duration
+	Result   string
+	// Injection 结果中疑似提示注入的规则，非空时在调用旁显示警告
+	Injection []string
+}
+
+// toolPanel 将模型连续发起的工具调用合并显示在一条消息中，默认折叠为每个调用一行
+type toolPanel struct {
+	Calls    []toolPanelCall
+	Expanded bool
+}
+
+// addToolCalls 将工具调用加入当前的面板，前面已有其他内容时新建面板
+func (m *Model) addToolCalls(calls []api.ToolCall) {
+	var panel *toolPanel
+	if n := len(m.messages); n > 0 && m.messages[n-1].Panel != nil {
+		panel = m.messages[n-1].Panel
+	} else {
+		panel = &toolPanel{Expanded: m.verbose}
+		m.messages = append(m.messages, Message{Role: "system", Panel: panel})
+	}
+	for _, call := range calls {
+		panel.Calls = append(panel.Calls, toolPanelCall{
+			CallID: call.ID,
+			Name:   call.Function.Name,
+			Args:   string(call.Function.Arguments),
+			Status: toolStatusPending,
+		})
+	}
+	m.syncToolPanel(len(m.messages) - 1)
+}
+
+// turnToolPanels 返回本轮对话（最后一条用户消息之后）中工具调用面板所在的消息下标。
+// 模型在调用之间输出文字时同一轮会有多个面板，它们的调用在流结束后一起执行
+func (m *Model) turnToolPanels() []int {
+	var panels []int
+	for i := len(m.messages) - 1; i >= 0 && m.messages[i].Role != "user"; i-- {
+		if m.messages[i].Panel != nil {
+			panels = append(panels, i)
+		}
+	}
+	return panels
+}
+
+// markDeniedToolCalls 将用户拒绝执行的调用标记为已拒绝
+func (m *Model) markDeniedToolCalls(denied map[string]bool) {
+	if len(denied) == 0 {
+		return
+	}
+	for _, i := range m.turnToolPanels() {
+		panel := m.messages[i].Panel
+		for j := range panel.Calls {
+			if denied[panel.Calls[j].CallID] {
+				panel.Calls[j].Status = toolStatusDenied
+			}
+		}
+		m.syncToolPanel(i)
+	}
+}
+
+// refreshToolPanel 根据事件总线报告的执行情况更新面板中的状态和耗时，返回是否有变化
+func (m *Model) refreshToolPanel() bool {
+	runningCall, outcomes := currentToolActivity.Snapshot()
+	changed := false
+	for _, i := range m.turnToolPanels() {
+		panel := m.messages[i].Panel
+		panelChanged := false
+		for j := range panel.Calls {
+			call := &panel.Calls[j]
+			if call.Status != toolStatusPending && call.Status != toolStatusRunning {
+				continue
+			}
+			if outcome, ok := outcomes[call.CallID]; ok {
+				call.Status = toolStatusOK
+				if outcome.Failed {
+					call.Status = toolStatusFailed
+				}
+				call.Duration = outcome.Duration
+				call.Injection = outcome.Injection
+				panelChanged = true
+			} else if call.CallID == runningCall && call.Status != toolStatusRunning {
+				call.Status = toolStatusRunning
+				panelChanged = true
+			}
+		}
+		if panelChanged {
+			m.syncToolPanel(i)
+			changed = true
+		}
+	}
+	return changed
+}
+
+// finishToolPanel 将执行结果填入本轮的面板，返回是否有结果对应到面板中的调用
+func (m *Model) finishToolPanel(results []api.Message) bool {
+	panels := m.turnToolPanels()
+	if len(panels) == 0 {
+		return false
+	}
+	m.refreshToolPanel()
+	byID := make(map[string]string, len(results))
+	for _, msg := range results {
+		if msg.Role == "tool" {
+			byID[msg.ToolCallID] = decodeToolResult(msg.Content)
+		}
+	}
+
+	var ids []string
+	matched := false
+	for _, i := range panels {
+		panel := m.messages[i].Panel
+		for j := range panel.Calls {
+			call := &panel.Calls[j]
+			ids = append(ids, call.CallID)
+			unfinished := call.Status == toolStatusPending || call.Status == toolStatusRunning
+			result, ok := byID[call.CallID]
+			switch {
+			case ok:
+				matched = true
+				call.Result = result
+				if unfinished {
+					call.Status = toolStatusOK
+				}
+			case unfinished:
+				// 没有结果的调用没有执行
+				call.Status = toolStatusFailed
+			}
+		}
+		m.syncToolPanel(i)
+	}
+	currentToolActivity.Forget(ids)
+	return matched
+}
+
+// toggleToolPanel 展开或折叠最近的工具调用面板
+func (m *Model) toggleToolPanel() bool {
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if panel := m.messages[i].Panel; panel != nil {
+			panel.Expanded = !panel.Expanded
+			m.syncToolPanel(i)
+			return true
+		}
+	}
+	return false
+}
+
+// syncToolPanel 面板变化后更新消息的文本（用于保存历史）并让缓存的渲染结果失效
+func (m *Model) syncToolPanel(i int) {
+	msg := &m.messages[i]
+	msg.Content = msg.Panel.text()
+	m.blocks.forget(msg.ID)
+}
+
+// text 面板的完整纯文本形式，用于保存历史记录和查找结果中引用的文件
+func (p *toolPanel) text() string {
+	var sb strings.Builder
+	sb.WriteString(i18n.T("tool.panel_title", len(p.Calls)))
+	for _, call := range p.Calls {
+		sb.WriteString("\n" + toolStatusIcons[call.Status] + " " + call.Name)
+		if len(call.Injection) > 0 {
+			sb.WriteString(" ⚠ " + i18n.T("tool.injection_warning", strings.Join(call.Injection, ", ")))
+		}
+		sb.WriteString("\n" + i18n.T("tool.panel_args", call.Args))
+		if call.Result != "" {
+			sb.WriteString("\n" + i18n.T("tool.panel_result", call.Result))
+		}
+	}
+	return sb.String()
+}
+
+// render 渲染面板：标题汇总调用数和总耗时，每个调用一行；展开时附带完整的参数和结果
+func (p *toolPanel) render(width int) string {
+	var total time.Duration
+	for _, call := range p.Calls {
+		total += call.Duration
+	}
+	hint := i18n.T("tool.panel_expand")
+	if p.Expanded {
+		hint = i18n.T("tool.panel_collapse")
+	}
+	title := i18n.T("tool.panel_title", len(p.Calls))
+	if total > 0 {
+		title += " · " + formatToolDuration(total)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(title)
+	sb.WriteString(markdownRuleStyle.Render(" (" + hint + ")"))
+
+	nameWidth := 0
+	for _, call := range p.Calls {
+		nameWidth = max(nameWidth, lipgloss.Width(call.Name))
+	}
+	summaryWidth := toolPanelSummaryWidth
+	if width > 0 {
+		// 缩进、图标、工具名和耗时之外的宽度
+		summaryWidth = min(summaryWidth, max(width-nameWidth-16, 10))
+	}
+
+	for _, call := range p.Calls {
+		sb.WriteString("\n  ")
+		sb.WriteString(toolStatusStyle(call.Status).Render(toolStatusIcons[call.Status]))
+		sb.WriteString(" " + padCell(call.Name, nameWidth, alignLeft))
+		if summary := summarizeToolArgs(call.Args); summary != "" && !p.Expanded {
+			sb.WriteString(" " + truncateWidth(summary, summaryWidth))
+		}
+		if call.Duration > 0 {
+			sb.WriteString(markdownRuleStyle.Render(" " + formatToolDuration(call.Duration)))
+		}
+		if len(call.Injection) > 0 {
+			sb.WriteString(toolWarningStyle.Render(" ⚠ " + i18n.T("tool.injection_warning", strings.Join(call.Injection, ", "))))
+		}
+		if p.Expanded {
+			sb.WriteString("\n" + indentBlock(i18n.T("tool.panel_args", call.Args), "    "))
+			if call.Result != "" {
+				sb.WriteString("\n" + indentBlock(i18n.T("tool.panel_result", call.Result), "    "))
+			}
+		}
+	}
+	return sb.String()
+}
+
+// toolWarningStyle 疑似提示注入的警告
+var toolWarningStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("11"))
+
+// toolStatusStyle 状态图标的颜色
+func toolStatusStyle(status string) lipgloss.Style {
+	switch status {
+	case toolStatusOK:
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("10"))
+	case toolStatusFailed:
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("9"))
+	case toolStatusRunning:
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("11"))
+	}
+	return lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+}
+
+// summarizeToolArgs 用最能说明调用目的的参数概括一次调用，找不到时使用压缩后的参数 JSON
+func summarizeToolArgs(raw string) string {
+	var args map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &args); err != nil {
+		return strings.Join(strings.Fields(raw), " ")
+	}
+	for _, key := range toolSummaryKeys {
+		if value, ok := args[key].(string); ok && value != "" {
+			return strings.Join(strings.Fields(value), " ")
+		}
+	}
+	if len(args) == 0 {
+		return ""
+	}
+	compact, _ := json.Marshal(args)
+	return string(compact)
+}
+
+// decodeToolResult 工具结果以 JSON 字符串发送给模型，显示时还原为文本
+func decodeToolResult(content json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(content, &text); err == nil {
+		return text
+	}
+	return string(content)
+}
+
+// formatToolDuration 格式化工具耗时，如 0.3s、12s
+func formatToolDuration(d time.Duration) string {
+	if d < 10*time.Second {
+		return fmt.Sprintf("%.1fs", d.Seconds())
+	}
+	return formatElapsed(d)
+}
+
+// indentBlock 为多行文本的每一行添加缩进
+func indentBlock(text, indent string) string {
+	return indent + strings.ReplaceAll(strings.TrimRight(text, "\n"), "\n", "\n"+indent)
+}
@@ -0,0 +1,19 @@
+package tui
+
+import (
+	"fmt"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/mcp"
+)
+
+// PublishToolPanic 将工具执行时发生的 panic 作为系统错误事件发布，供订阅者记录或提示
+func PublishToolPanic(p mcp.ToolPanic) {
+	GetGlobalEventBus().PublishAsync(NewSystemErrorEvent(
+		fmt.Errorf("panic in tool %s: %v", p.Tool, p.Value),
+		"mcp",
+		map[string]interface{}{
+			"tool":  p.Tool,
+			"stack": p.Stack,
+		},
+	))
+}
@@ -0,0 +1,120 @@
+package tui
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/api"
+)
+
+// localReadTools 只读取本地文件的工具：不修改任何内容、不需要用户确认，也不产生费用。
+// 这些调用可以在流式响应结束前提前执行，相同的调用可以复用之前的结果（联网和语义搜索都不包括在内）
+var localReadTools = map[string]bool{
+	"read_file":           true,
+	"list_directory":      true,
+	"search_file_content": true,
+	"glob":                true,
+	"get_file_info":       true,
+	"find_symbol":         true,
+	"file_stats":          true,
+	"advanced_search":     true,
+}
+
+// toolPrefetch 流式响应期间提前执行的工具调用，按调用 ID 保存结果。
+// 提前执行的调用依次进行，与流结束后的正常执行顺序相同
+type toolPrefetch struct {
+	mu      sync.Mutex
+	pending map[string]*prefetchedResult
+	// run 保证同一时间只执行一个提前的调用
+	run sync.Mutex
+}
+
+// prefetchedResult 一次提前执行的调用，done 关闭后 message 可用
+type prefetchedResult struct {
+	// arguments 提前执行时使用的参数，与最终的调用不一致时结果作废
+	arguments json.RawMessage
+	done      chan struct{}
+	message   api.Message
+}
+
+// Prefetch 在后台提前执行只读的工具调用，流结束后 HandleToolCalls 直接使用结果。
+// 调用方需保证同一批次中之前的调用都可以提前执行，避免读到之后才被修改的内容
+func (tm *ToolManager) Prefetch(calls []api.ToolCall) {
+	p := &tm.prefetch
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.pending == nil {
+		p.pending = make(map[string]*prefetchedResult)
+	}
+	for _, call := range calls {
+		if call.ID == "" || p.pending[call.ID] != nil {
+			continue
+		}
+		result := &prefetchedResult{arguments: append(json.RawMessage(nil), call.Function.Arguments...), done: make(chan struct{})}
+		p.pending[call.ID] = result
+		go func(call api.ToolCall) {
+			p.run.Lock()
+			defer p.run.Unlock()
+			result.message = tm.callTool(call)
+			close(result.done)
+		}(call)
+	}
+}
+
+// DiscardPrefetch 丢弃没有被使用的提前执行结果（如请求被取消），正在执行的调用完成后结果被忽略
+func (tm *ToolManager) DiscardPrefetch() {
+	p := &tm.prefetch
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pending = nil
+}
+
+// take 取出调用提前执行的结果，必要时等待执行完成；
+// 没有提前执行或提前执行时的参数与 arguments 不同时返回 false
+func (p *toolPrefetch) take(id string, arguments json.RawMessage) (api.Message, bool) {
+	p.mu.Lock()
+	result := p.pending[id]
+	delete(p.pending, id)
+	p.mu.Unlock()
+	if result == nil || !bytes.Equal(result.arguments, arguments) {
+		return api.Message{}, false
+	}
+	<-result.done
+	return result.message, true
+}
+
+// prefetchToolCalls 流式响应中收到工具调用时提前执行可以提前的调用。
+// 本轮出现过不能提前执行的调用（如写文件、执行命令）后不再提前，之后的读取要等它执行完；
+// 参数还不是完整 JSON 的调用（仍在流式接收）留到流结束后正常执行
+func (m *Model) prefetchToolCalls(calls []api.ToolCall) {
+	if m.toolManager == nil || m.prefetchBlocked {
+		return
+	}
+	// 会复用之前结果的调用不需要执行
+	duplicates := m.duplicateToolResults()
+	var ready []api.ToolCall
+	for _, call := range calls {
+		if !localReadTools[call.Function.Name] {
+			m.prefetchBlocked = true
+			break
+		}
+		if !json.Valid(call.Function.Arguments) {
+			continue
+		}
+		if _, ok := duplicates[call.ID]; !ok {
+			ready = append(ready, call)
+		}
+	}
+	if len(ready) > 0 {
+		m.toolManager.Prefetch(ready)
+	}
+}
+
+// resetPrefetch 发起新的请求前丢弃上一次响应中没有用到的提前执行结果
+func (m *Model) resetPrefetch() {
+	m.prefetchBlocked = false
+	if m.toolManager != nil {
+		m.toolManager.DiscardPrefetch()
+	}
+}
@@ -0,0 +1,105 @@
+package tui
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/api"
+	"github.com/Zacy-Sokach/PolyAgent/internal/mcp"
+)
+
+// recordingTool 记录执行顺序的测试工具
+type recordingTool struct {
+	name string
+	mu   *sync.Mutex
+	log  *[]string
+}
+
+func (t recordingTool) Name() string                      { return t.name }
+func (t recordingTool) Description() string               { return t.name }
+func (t recordingTool) GetSchema() map[string]interface{} { return nil }
+func (t recordingTool) Execute(args map[string]interface{}) (interface{}, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	*t.log = append(*t.log, t.name+" "+args["path"].(string))
+	return "ok", nil
+}
+
+func TestPrefetchToolCalls(t *testing.T) {
+	var mu sync.Mutex
+	var log []string
+	registry := mcp.NewToolRegistry()
+	registry.Register(recordingTool{"read_file", &mu, &log})
+	registry.Register(recordingTool{"write_file", &mu, &log})
+
+	m := goldenModel(t)
+	m.toolManager = NewToolManagerWithRegistry(registry)
+	m.resetPrefetch()
+	call := func(id, name, path string) api.ToolCall {
+		args, _ := json.Marshal(map[string]string{"path": path})
+		return api.ToolCall{ID: id, Type: "function", Function: api.ToolCallFunction{Name: name, Arguments: args}}
+	}
+	calls := []api.ToolCall{call("1", "read_file", "a"), call("2", "write_file", "b"), call("3", "read_file", "b")}
+
+	// 写文件之后的读取不提前执行，否则会读到修改之前的内容
+	m.prefetchToolCalls(calls[:1])
+	m.prefetchToolCalls(calls[1:])
+	results, err := m.toolManager.HandleToolCalls(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 || results[0].ToolCallID != "1" || results[2].ToolCallID != "3" {
+		t.Fatalf("results = %+v", results)
+	}
+	if want := []string{"read_file a", "write_file b", "read_file b"}; len(log) != len(want) || log[0] != want[0] || log[1] != want[1] || log[2] != want[2] {
+		t.Errorf("execution order = %v, want %v", log, want)
+	}
+
+	// 新的请求开始时丢弃没有用到的结果，允许再次提前执行
+	m.prefetchToolCalls([]api.ToolCall{call("4", "read_file", "c")})
+	m.resetPrefetch()
+	m.prefetchToolCalls([]api.ToolCall{call("5", "read_file", "d")})
+	if _, ok := m.toolManager.prefetch.take("4", call("4", "read_file", "c").Function.Arguments); ok {
+		t.Error("stale prefetch result kept")
+	}
+	if _, ok := m.toolManager.prefetch.take("5", call("5", "read_file", "d").Function.Arguments); !ok {
+		t.Error("prefetch not re-enabled for the next response")
+	}
+}
+
+func TestPrefetchUsesFinalArguments(t *testing.T) {
+	var mu sync.Mutex
+	var log []string
+	registry := mcp.NewToolRegistry()
+	registry.Register(recordingTool{"read_file", &mu, &log})
+
+	m := goldenModel(t)
+	m.toolManager = NewToolManagerWithRegistry(registry)
+	m.resetPrefetch()
+	call := func(id, args string) api.ToolCall {
+		return api.ToolCall{ID: id, Type: "function", Function: api.ToolCallFunction{Name: "read_file", Arguments: json.RawMessage(args)}}
+	}
+
+	// 参数还在流式接收中的调用不提前执行
+	m.prefetchToolCalls([]api.ToolCall{call("1", `{"path":"a`)})
+	if _, ok := m.toolManager.prefetch.take("1", json.RawMessage(`{"path":"a`)); ok {
+		t.Error("call with incomplete arguments was prefetched")
+	}
+
+	// 提前执行后参数又变化时，结果作废并按最终参数执行
+	m.prefetchToolCalls([]api.ToolCall{call("2", `{"path":"a"}`)})
+	final := call("2", `{"path":"ab"}`)
+	results, err := m.toolManager.HandleToolCalls([]api.ToolCall{final})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].ToolCallID != "2" {
+		t.Fatalf("results = %+v", results)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(log) == 0 || log[len(log)-1] != "read_file ab" {
+		t.Errorf("execution log = %v, want the final arguments to be executed last", log)
+	}
+}
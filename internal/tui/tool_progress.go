@@ -0,0 +1,30 @@
+package tui
+
+import (
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
+	"github.com/Zacy-Sokach/PolyAgent/internal/mcp"
+)
+
+// ToolProgressMsg 长时间运行的工具（如复制大目录）报告的进度
+type ToolProgressMsg struct {
+	Progress mcp.Progress
+}
+
+// handleToolProgress 更新状态栏中的工具进度，工具结束时清除
+func (m *Model) handleToolProgress(msg ToolProgressMsg) {
+	if msg.Progress.Finished {
+		m.toolProgress = nil
+		return
+	}
+	progress := msg.Progress
+	m.toolProgress = &progress
+}
+
+// toolProgressStatus 格式化当前的工具进度，没有进度时返回空字符串
+func (m Model) toolProgressStatus() string {
+	if m.toolProgress == nil {
+		return ""
+	}
+	p := m.toolProgress
+	return i18n.T("ui.tool_progress", p.Tool, p.Done, p.Total, formatByteCount(p.Bytes), formatByteCount(p.TotalBytes))
+}
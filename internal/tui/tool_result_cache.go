@@ -0,0 +1,156 @@
+package tui
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/api"
+	"github.com/Zacy-Sokach/PolyAgent/internal/config"
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
+	"github.com/Zacy-Sokach/PolyAgent/internal/mcp"
+)
+
+// toolResultCacheMode 返回重复只读调用的去重范围，未配置时只在同一轮对话内去重
+func (m *Model) toolResultCacheMode() string {
+	if m.config != nil && m.config.ToolResultCache != "" {
+		return m.config.ToolResultCache
+	}
+	return config.ToolResultCacheTurn
+}
+
+// duplicateToolResults 找出挂起的调用中与之前的只读调用相同（工具和参数都相同）、之后也没有执行过修改类工具的调用，
+// 返回调用 ID 到引用说明的映射，这些调用不再执行。被引用的结果必须仍在发送给模型的历史中
+func (m *Model) duplicateToolResults() map[string]string {
+	mode := m.toolResultCacheMode()
+	if mode == config.ToolResultCacheOff || len(m.pendingToolCalls) == 0 {
+		return nil
+	}
+	history := m.trimmedHistory()
+	if mode == config.ToolResultCacheTurn {
+		for i := len(history) - 1; i >= 0; i-- {
+			if history[i].Role == "user" {
+				history = history[i:]
+				break
+			}
+		}
+	}
+
+	pending := make(map[string]bool, len(m.pendingToolCalls))
+	for _, call := range m.pendingToolCalls {
+		pending[call.ID] = true
+	}
+	results := make(map[string]string)
+	for _, msg := range history {
+		if msg.Role == "tool" {
+			results[msg.ToolCallID] = api.MessageText(msg)
+		}
+	}
+
+	// seen 每组工具和参数最早的可用结果对应的调用，按执行顺序遍历
+	seen := make(map[string]string)
+	duplicates := make(map[string]string)
+	for _, msg := range history {
+		for _, call := range msg.ToolCalls {
+			if !localReadTools[call.Function.Name] {
+				if !mcp.ReadOnlyToolNames[call.Function.Name] && !mcp.IndexToolNames[call.Function.Name] {
+					// 修改类工具可能改变了之前读取的内容
+					clear(seen)
+				}
+				continue
+			}
+			key := toolCallKey(call)
+			original, ok := seen[key]
+			switch {
+			case ok && pending[call.ID]:
+				duplicates[call.ID] = i18n.T("tool.cached_result", original)
+			case ok:
+				// 之前已经复用过结果的调用，继续引用最早的那次
+			case pending[call.ID]:
+				// 同一批中之后的相同调用引用这一次的结果
+				seen[key] = call.ID
+			default:
+				if result, found := results[call.ID]; found && reusableResult(result) && m.toolManager.stamps.unchanged(call) {
+					seen[key] = call.ID
+				}
+			}
+		}
+	}
+	return duplicates
+}
+
+// pathStampTools 结果只取决于 path 参数所指文件或目录的工具。复用结果前确认该路径的修改时间和大小
+// 与执行时相同，避免引用在工具之外（编辑器、git 等）修改之前的内容
+var pathStampTools = map[string]bool{
+	"read_file":      true,
+	"list_directory": true,
+	"get_file_info":  true,
+}
+
+// toolStamps 调用执行时目标路径的修改时间和大小，按调用 ID 保存
+type toolStamps struct {
+	mu     sync.Mutex
+	stamps map[string]string
+}
+
+// pathStamp 返回调用目标路径当前的修改时间和大小，不适用或无法获取时返回空
+func pathStamp(call api.ToolCall) string {
+	if !pathStampTools[call.Function.Name] {
+		return ""
+	}
+	var args struct {
+		Path string `json:"path"`
+	}
+	if err := json.Unmarshal(call.Function.Arguments, &args); err != nil || args.Path == "" {
+		return ""
+	}
+	info, err := os.Stat(args.Path)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%d/%d", info.ModTime().UnixNano(), info.Size())
+}
+
+// record 在执行调用前记录目标路径的状态，执行期间的修改会使之后的比较失败，不会误用旧结果
+func (s *toolStamps) record(call api.ToolCall) {
+	stamp := pathStamp(call)
+	if stamp == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stamps == nil {
+		s.stamps = make(map[string]string)
+	}
+	s.stamps[call.ID] = stamp
+}
+
+// unchanged 之前执行的调用的目标路径是否仍与执行时相同；不依赖单个路径的工具总是返回 true，
+// 没有记录的调用（如恢复的会话）返回 false
+func (s *toolStamps) unchanged(call api.ToolCall) bool {
+	if !pathStampTools[call.Function.Name] {
+		return true
+	}
+	s.mu.Lock()
+	recorded, ok := s.stamps[call.ID]
+	s.mu.Unlock()
+	return ok && pathStamp(call) == recorded
+}
+
+// toolCallKey 工具名和规范化后的参数，参数中键的顺序和空白不影响结果
+func toolCallKey(call api.ToolCall) string {
+	var args interface{}
+	if err := json.Unmarshal(call.Function.Arguments, &args); err == nil {
+		if canonical, err := json.Marshal(args); err == nil {
+			return call.Function.Name + " " + string(canonical)
+		}
+	}
+	return call.Function.Name + " " + string(call.Function.Arguments)
+}
+
+// reusableResult 结果是否可以被之后的相同调用引用：失败的调用和为缩减上下文省略的结果不复用
+func reusableResult(result string) bool {
+	return !strings.HasPrefix(result, `{"error":`) && !isOmittedResult(result)
+}
@@ -0,0 +1,82 @@
+package tui
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/api"
+	"github.com/Zacy-Sokach/PolyAgent/internal/config"
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
+)
+
+func TestDuplicateToolResults(t *testing.T) {
+	m := goldenModel(t)
+	pending := []api.ToolCall{
+		toolCall("c2", "read_file", `{ "path": "a.go", "limit": 10 }`),
+		toolCall("c7", "read_file", `{"path":"b.go"}`),
+		toolCall("c3", "glob", `{"pattern":"*.go"}`),
+		toolCall("c4", "glob", `{"pattern":"*.go"}`),
+		toolCall("c5", "write_file", `{"path":"a.go"}`),
+		toolCall("c6", "read_file", `{"limit":10,"path":"a.go"}`),
+		toolCall("c8", "list_directory", `{"path":"."}`),
+	}
+	m.apiMessages = []api.Message{
+		api.TextMessage("user", "看看 b.go"),
+		api.AssistantToolCallMessage("", []api.ToolCall{toolCall("c0", "read_file", `{"path":"b.go"}`)}),
+		api.ToolResultMessage("c0", "package b"),
+		api.TextMessage("assistant", "看完了"),
+		api.TextMessage("user", "再看看 a.go"),
+		api.AssistantToolCallMessage("", []api.ToolCall{
+			toolCall("c1", "read_file", `{"path":"a.go","limit":10}`),
+			toolCall("c9", "list_directory", `{"path":"."}`),
+		}),
+		api.ToolResultMessage("c1", "package a"),
+		api.ToolResultMessage("c9", `{"error":{"message":"权限不足"}}`),
+		api.AssistantToolCallMessage("", pending),
+	}
+	m.pendingToolCalls = pending
+	// 之前的调用执行时记录了目标文件的状态
+	for name, content := range map[string]string{"a.go": "package a\n", "b.go": "package b\n"} {
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, msg := range m.apiMessages[:len(m.apiMessages)-1] {
+		for _, call := range msg.ToolCalls {
+			m.toolManager.stamps.record(call)
+		}
+	}
+
+	// 参数顺序和空白不同也算相同的调用；写文件之后的读取和失败过的调用重新执行；默认不跨轮次
+	got := m.duplicateToolResults()
+	if len(got) != 2 || !strings.Contains(got["c2"], "c1") || !strings.Contains(got["c4"], "c3") {
+		t.Errorf("duplicates = %v", got)
+	}
+
+	m.config = &config.Config{ToolResultCache: config.ToolResultCacheSession}
+	if got := m.duplicateToolResults(); len(got) != 3 || !strings.Contains(got["c7"], "c0") {
+		t.Errorf("session duplicates = %v", got)
+	}
+
+	// 文件在工具之外被修改后不再复用之前读取的结果
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes("b.go", later, later); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.duplicateToolResults(); len(got) != 2 || got["c7"] != "" {
+		t.Errorf("stale result reused after an external change: %v", got)
+	}
+
+	// 为缩减上下文省略过的结果不再被引用
+	m.apiMessages[6] = api.ToolResultMessage("c1", i18n.T("context_retry.placeholder", "2.0k"))
+	if got := m.duplicateToolResults(); got["c2"] != "" {
+		t.Errorf("omitted result reused: %v", got)
+	}
+
+	m.config.ToolResultCache = config.ToolResultCacheOff
+	if got := m.duplicateToolResults(); len(got) != 0 {
+		t.Errorf("cache disabled but got %v", got)
+	}
+}
@@ -179,16 +179,16 @@ func generateToolsList(tools []api.Tool, promptBuilder *strings.Builder) {
 // categorizeTool 根据工具名称确定分类
 func categorizeTool(toolName string) string {
 	fileOps := []string{
-		"read_file", "write_file", "list_directory", "glob", "replace",
-		"create_file", "delete_file", "move_file", "copy_file", "get_file_info",
+		"read_file", "write_file", "list_directory", "glob", "replace", "edit_lines",
+		"create_file", "delete_file", "move_file", "copy_file", "get_file_info", "chmod",
 	}
 
 	codeSearch := []string{
-		"search_file_content", "advanced_search",
+		"search_file_content", "advanced_search", "semantic_search", "find_symbol",
 	}
 
 	codeMod := []string{
-		"global_replace", "find_and_replace",
+		"global_replace", "find_and_replace", "apply_replacements", "rollback_replace",
 	}
 
 	systemOps := []string{
@@ -200,7 +200,7 @@ func categorizeTool(toolName string) string {
 	}
 
 	webSearch := []string{
-		"web_search", "tavily_search", "tavily_crawl",
+		"web_search", "tavily_search", "tavily_crawl", "web_crawl", "web_extract",
 	}
 
 	for _, name := range fileOps {
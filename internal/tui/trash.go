@@ -0,0 +1,59 @@
+package tui
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// handleTrashCommand 处理 /trash 命令：列出、恢复或清空本次会话删除的文件
+func (m *Model) handleTrashCommand(cmd *Command) tea.Cmd {
+	trash := m.toolManager.Trash()
+	return func() tea.Msg {
+		if trash == nil {
+			return ResponseMsg{Content: i18n.T("command.trash_disabled")}
+		}
+
+		fields := strings.Fields(cmd.Content)
+		switch {
+		case len(fields) == 0:
+			entries := trash.Entries()
+			if len(entries) == 0 {
+				return ResponseMsg{Content: i18n.T("command.trash_empty")}
+			}
+			dir, _ := trash.Dir()
+			var sb strings.Builder
+			sb.WriteString(i18n.T("command.trash_header", dir))
+			for _, entry := range entries {
+				suffix := ""
+				if entry.IsDir {
+					suffix = i18n.T("command.trash_dir_suffix")
+				}
+				sb.WriteString(i18n.T("command.trash_item", entry.ID, entry.DeletedAt.Format("15:04:05"), entry.OriginalPath, suffix))
+			}
+			sb.WriteString(i18n.T("command.trash_footer"))
+			return ResponseMsg{Content: sb.String()}
+
+		case len(fields) == 2 && fields[0] == "restore":
+			id, err := strconv.Atoi(fields[1])
+			if err != nil {
+				return ResponseMsg{Content: i18n.T("command.trash_usage")}
+			}
+			entry, err := trash.Restore(id)
+			if err != nil {
+				return ResponseMsg{Content: i18n.T("command.trash_restore_failed", err)}
+			}
+			return ResponseMsg{Content: i18n.T("command.trash_restored", entry.OriginalPath)}
+
+		case len(fields) == 1 && fields[0] == "empty":
+			count, err := trash.Empty()
+			if err != nil {
+				return ResponseMsg{Content: i18n.T("command.trash_empty_failed", err)}
+			}
+			return ResponseMsg{Content: i18n.T("command.trash_emptied", count)}
+		}
+		return ResponseMsg{Content: i18n.T("command.trash_usage")}
+	}
+}
@@ -0,0 +1,223 @@
+package tui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
+	"github.com/Zacy-Sokach/PolyAgent/internal/utils"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	// maxSummaryFiles 修改汇总中最多列出的文件数，保持在一屏以内
+	maxSummaryFiles = 12
+	// maxDiffViewLines 查看差异时最多显示的行数
+	maxDiffViewLines = 200
+)
+
+// turnChanges 一轮对话修改的文件：from 为本轮开始时的检查点，to 为结束时的工作区快照
+type turnChanges struct {
+	from, to *utils.GitCheckpoint
+	files    []utils.FileChange
+	reverted map[string]bool
+}
+
+// turnChangesMsg 本轮结束后统计修改的结果
+type turnChangesMsg struct {
+	changes *turnChanges
+}
+
+// beginTurnCheckpoint 在一轮对话开始时为工作区创建检查点，不在 git 仓库中时不统计修改
+func (m *Model) beginTurnCheckpoint() {
+	m.changeKeys = false
+	checkpoint, err := utils.CreateCheckpoint(".")
+	if err != nil {
+		m.turnCheckpoint = nil
+		return
+	}
+	m.turnCheckpoint = checkpoint
+}
+
+// turnChangesCmd 本轮结束后在后台比较工作区与检查点，有文件变化时显示修改汇总
+func (m *Model) turnChangesCmd() tea.Cmd {
+	checkpoint := m.turnCheckpoint
+	m.turnCheckpoint = nil
+	if checkpoint == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		files, current, err := checkpoint.Changes()
+		if err != nil || len(files) == 0 {
+			return nil
+		}
+		return turnChangesMsg{changes: &turnChanges{from: checkpoint, to: current, files: files, reverted: make(map[string]bool)}}
+	}
+}
+
+// handleTurnChanges 显示本轮的修改汇总，随后按数字键可以查看对应文件的差异
+func (m *Model) handleTurnChanges(msg turnChangesMsg) tea.Cmd {
+	if m.thinking {
+		// 新的一轮已经开始，汇总已过时
+		return nil
+	}
+	m.lastChanges = msg.changes
+	m.changeKeys = true
+	m.messages = append(m.messages, Message{Role: "system", Content: msg.changes.summary()})
+	return m.updateViewport()
+}
+
+// summary 修改汇总：每个文件的增删行数，超出一屏的部分只显示数量
+func (c *turnChanges) summary() string {
+	added, deleted := 0, 0
+	width := 0
+	for i, file := range c.files {
+		added += file.Added
+		deleted += file.Deleted
+		if i < maxSummaryFiles {
+			width = max(width, len(file.Path))
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(i18n.T("changes.summary_title", len(c.files), added, deleted))
+	for i, file := range c.files {
+		if i == maxSummaryFiles {
+			sb.WriteString("\n" + i18n.T("changes.summary_more", len(c.files)-maxSummaryFiles))
+			break
+		}
+		fmt.Fprintf(&sb, "\n%3d. %s  %s", i+1, padCell(file.Path, width, alignLeft), c.fileStat(file))
+	}
+	sb.WriteString("\n" + i18n.T("changes.summary_hint"))
+	return sb.String()
+}
+
+// fileStat 文件的增删行数和状态，如 "+12 −3"、"+5 (新文件)"
+func (c *turnChanges) fileStat(file utils.FileChange) string {
+	var parts []string
+	switch {
+	case file.Binary:
+		parts = append(parts, i18n.T("changes.binary"))
+	default:
+		if file.Added > 0 {
+			parts = append(parts, fmt.Sprintf("+%d", file.Added))
+		}
+		if file.Deleted > 0 {
+			parts = append(parts, fmt.Sprintf("−%d", file.Deleted))
+		}
+	}
+	switch file.Status {
+	case "A":
+		parts = append(parts, i18n.T("changes.added"))
+	case "D":
+		parts = append(parts, i18n.T("changes.deleted"))
+	}
+	if c.reverted[file.Path] {
+		parts = append(parts, i18n.T("changes.reverted"))
+	}
+	return strings.Join(parts, " ")
+}
+
+// handleChangeKey 修改汇总显示后按数字键查看对应文件的差异，按其他键时恢复正常输入。
+// 返回 false 表示按键应交给输入框处理
+func (m *Model) handleChangeKey(msg tea.KeyMsg) (tea.Cmd, bool) {
+	m.changeKeys = false
+	if msg.Type == tea.KeyEsc {
+		return nil, true
+	}
+	if msg.Type != tea.KeyRunes || len(msg.Runes) != 1 || msg.Runes[0] < '1' || msg.Runes[0] > '9' {
+		return nil, false
+	}
+	n := int(msg.Runes[0] - '0')
+	if n > len(m.lastChanges.files) {
+		return nil, false
+	}
+	// 查看差异后仍可继续按数字键查看其他文件
+	m.changeKeys = true
+	m.messages = append(m.messages, Message{Role: "system", Content: m.lastChanges.diff(n)})
+	return m.updateViewport(), true
+}
+
+// diff 第 n 个文件（从 1 开始）在本轮中的差异，过长时截断
+func (c *turnChanges) diff(n int) string {
+	file := c.files[n-1]
+	diff, err := c.from.Diff(c.to, file.Path)
+	if err != nil {
+		return i18n.T("changes.diff_failed", file.Path, err)
+	}
+	if file.Binary || strings.TrimSpace(diff) == "" {
+		return i18n.T("changes.diff_binary", file.Path)
+	}
+	lines := strings.Split(strings.TrimRight(diff, "\n"), "\n")
+	note := ""
+	if len(lines) > maxDiffViewLines {
+		note = "\n" + i18n.T("changes.diff_truncated", len(lines)-maxDiffViewLines)
+		lines = lines[:maxDiffViewLines]
+	}
+	return i18n.T("changes.diff_title", n, file.Path) + "\n```diff\n" + strings.Join(lines, "\n") + "\n```" + note
+}
+
+// handleDiffCommand 处理 /diff 命令：不带参数时重新显示上一轮的修改汇总，带编号时显示对应文件的差异
+func (m *Model) handleDiffCommand(cmd *Command) tea.Cmd {
+	content := i18n.T("changes.none")
+	if m.lastChanges != nil {
+		switch n, err := strconv.Atoi(cmd.Content); {
+		case cmd.Content == "":
+			m.changeKeys = true
+			content = m.lastChanges.summary()
+		case err != nil || n < 1 || n > len(m.lastChanges.files):
+			content = i18n.T("changes.invalid_index", cmd.Content, len(m.lastChanges.files))
+		default:
+			content = m.lastChanges.diff(n)
+		}
+	}
+	return func() tea.Msg {
+		return ResponseMsg{Content: content}
+	}
+}
+
+// handleRevertCommand 处理 /revert 命令：将上一轮修改的某个文件（或 all 表示全部）恢复为本轮开始时的内容
+func (m *Model) handleRevertCommand(cmd *Command) tea.Cmd {
+	respond := func(content string) tea.Cmd {
+		return func() tea.Msg {
+			return ResponseMsg{Content: content}
+		}
+	}
+	if m.lastChanges == nil {
+		return respond(i18n.T("changes.none"))
+	}
+	if m.thinking {
+		return respond(i18n.T("changes.revert_busy"))
+	}
+
+	var files []utils.FileChange
+	if cmd.Content == "all" {
+		files = m.lastChanges.files
+	} else if n, err := strconv.Atoi(cmd.Content); err == nil && n >= 1 && n <= len(m.lastChanges.files) {
+		files = m.lastChanges.files[n-1 : n]
+	} else {
+		return respond(i18n.T("changes.revert_usage", len(m.lastChanges.files)))
+	}
+
+	var reverted []string
+	for _, file := range files {
+		if m.lastChanges.reverted[file.Path] {
+			continue
+		}
+		if err := m.lastChanges.from.Restore(file.Path); err != nil {
+			return respond(i18n.T("changes.revert_failed", err))
+		}
+		m.lastChanges.reverted[file.Path] = true
+		if m.editor != nil {
+			// 编辑器中已加载的缓冲区与恢复后的磁盘内容保持一致
+			m.editor.SyncFile(filepath.Join(m.lastChanges.from.Root(), filepath.FromSlash(file.Path)))
+		}
+		reverted = append(reverted, file.Path)
+	}
+	if len(reverted) == 0 {
+		return respond(i18n.T("changes.revert_nothing"))
+	}
+	return respond(i18n.T("changes.reverted_files", strings.Join(reverted, "\n")))
+}
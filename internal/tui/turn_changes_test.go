@@ -0,0 +1,83 @@
+package tui
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestTurnChangesSummary(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	m := goldenModel(t)
+	if err := os.WriteFile("main.go", []byte("package main\n\nfunc main() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command("git", "init", "-q").CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+
+	m.beginTurnCheckpoint()
+	if m.turnCheckpoint == nil {
+		t.Fatal("checkpoint should be created in a git repository")
+	}
+	// 本轮中模型修改和新建的文件
+	if err := os.WriteFile("main.go", []byte("package main\n\nfunc main() {\n\tprintln()\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("util.go", []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	msg, ok := m.turnChangesCmd()().(turnChangesMsg)
+	if !ok {
+		t.Fatal("changes should be reported")
+	}
+	m.handleTurnChanges(msg)
+	summary := m.messages[len(m.messages)-1].Content
+	for _, want := range []string{"2", "main.go", "+3 −1", "util.go", "+1"} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("summary missing %q:\n%s", want, summary)
+		}
+	}
+
+	m = drive(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("1")})
+	if diff := m.messages[len(m.messages)-1].Content; !strings.Contains(diff, "+\tprintln()") {
+		t.Errorf("pressing 1 should show the diff of main.go:\n%s", diff)
+	}
+	if m.textarea.Value() != "" {
+		t.Error("quick key should not be typed into the input")
+	}
+
+	parser := NewCommandParser()
+	reply := m.handleRevertCommand(parser.Parse("/revert 2"))().(ResponseMsg).Content
+	if !strings.Contains(reply, "util.go") {
+		t.Errorf("revert reply = %q", reply)
+	}
+	if _, err := os.Stat("util.go"); !os.IsNotExist(err) {
+		t.Error("new file should be removed when reverted")
+	}
+	m.handleRevertCommand(parser.Parse("/revert all"))
+	if content, _ := os.ReadFile("main.go"); string(content) != "package main\n\nfunc main() {}\n" {
+		t.Errorf("main.go after revert = %q", content)
+	}
+
+	// 其他按键结束快捷键模式，交给输入框
+	m.changeKeys = true
+	m = drive(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")}, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("1")})
+	if m.changeKeys || m.textarea.Value() != "x1" {
+		t.Errorf("input = %q, changeKeys = %v", m.textarea.Value(), m.changeKeys)
+	}
+}
+
+func TestTurnChangesOutsideRepo(t *testing.T) {
+	m := goldenModel(t)
+	m.beginTurnCheckpoint()
+	if cmd := m.turnChangesCmd(); cmd != nil {
+		t.Error("no summary outside a git repository")
+	}
+}
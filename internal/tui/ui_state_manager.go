@@ -1,6 +1,7 @@
 package tui
 
 import (
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
 	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/viewport"
 )
@@ -15,7 +16,7 @@ type UIStateManager struct {
 // NewUIStateManager 创建新的UI状态管理器
 func NewUIStateManager() *UIStateManager {
 	ta := textarea.New()
-	ta.Placeholder = "输入你的问题..."
+	ta.Placeholder = i18n.T("ui.placeholder")
 	ta.Focus()
 	ta.CharLimit = 0
 	ta.SetWidth(80)
@@ -24,7 +25,7 @@ func NewUIStateManager() *UIStateManager {
 	ta.KeyMap.InsertNewline.SetEnabled(false)
 
 	vp := viewport.New(80, 20)
-	vp.SetContent("欢迎使用 PolyAgent - 类似 Claude Code 的 Vibe Coding 工具\n\n")
+	vp.SetContent(i18n.T("ui.welcome"))
 
 	return &UIStateManager{
 		viewport: vp,
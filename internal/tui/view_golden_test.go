@@ -0,0 +1,113 @@
+package tui
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+// 修改渲染代码后用 go test ./internal/tui -run TestViewGolden -update 重新生成，并检查差异
+var updateGolden = flag.Bool("update", false, "rewrite golden files in testdata")
+
+// goldenModel 以固定的终端大小和无颜色输出驱动模型，保证输出稳定
+func goldenModel(t *testing.T) Model {
+	t.Helper()
+	t.Setenv("POLYAGENT_CONFIG_HOME", t.TempDir())
+	// 用例中的工具调用和命令会在工作目录中读写文件
+	t.Chdir(t.TempDir())
+	lipgloss.SetColorProfile(termenv.Ascii)
+	if err := i18n.SetLanguage("zh"); err != nil {
+		t.Fatal(err)
+	}
+	m := drive(t, InitialModel("", nil), tea.WindowSizeMsg{Width: 80, Height: 24})
+	// 不在后台提前执行真实的工具，其事件会通过全局事件总线串到其他测试
+	m.prefetchBlocked = true
+	return m
+}
+
+func drive(t *testing.T, m Model, msgs ...tea.Msg) Model {
+	t.Helper()
+	for _, msg := range msgs {
+		next, _ := m.Update(msg)
+		m = next.(Model)
+	}
+	return m
+}
+
+// conversation 一轮包含工具调用的对话
+func conversation(m Model) Model {
+	m.messages = append(m.messages,
+		Message{Role: "user", Content: "为什么 main.go 启动很慢？"},
+		Message{Role: "system", Content: "🔧 read_file main.go"},
+		Message{Role: "assistant", Content: "启动时同步加载了全部插件，见 main.go:42。"},
+	)
+	m.updateViewport()
+	return m
+}
+
+func TestViewGolden(t *testing.T) {
+	// 用例会切换工作目录，先确定快照目录的绝对路径
+	testdata, err := filepath.Abs("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]func(t *testing.T) Model{
+		"welcome": func(t *testing.T) Model {
+			return goldenModel(t)
+		},
+		"conversation": func(t *testing.T) Model {
+			return conversation(goldenModel(t))
+		},
+		"streaming": func(t *testing.T) Model {
+			m := conversation(goldenModel(t))
+			m.messages = append(m.messages, Message{Role: "user", Content: "怎么改？"})
+			m.thinking = true
+			return drive(t, m,
+				StreamChunkMsg{Reasoning: "需要把插件改为按需加载"},
+				StreamChunkMsg{Chunk: "可以把插件改为按需加载：\n"},
+			)
+		},
+		"tool approval": func(t *testing.T) Model {
+			m := conversation(goldenModel(t))
+			m.thinking = true
+			m.requestApproval([]approvalRequest{{CallID: "call_1", Item: "\n  $ rm -rf build"}})
+			return m
+		},
+		"error": func(t *testing.T) Model {
+			m := conversation(goldenModel(t))
+			m.thinking = true
+			return drive(t, m, StreamErrorMsg{Error: errors.New("API请求失败 (状态码: 500): internal error")})
+		},
+	}
+
+	for name, build := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := build(t).View()
+			path := filepath.Join(testdata, "view_"+strings.ReplaceAll(name, " ", "_")+".golden")
+			if *updateGolden {
+				if err := os.MkdirAll(testdata, 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("missing golden file, run with -update: %v", err)
+			}
+			if got != string(want) {
+				t.Errorf("View() does not match %s (run with -update after checking the change)\n--- got ---\n%s\n--- want ---\n%s", path, got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package tui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// handleAddRootCommand 处理 /add-root 命令：不带参数时列出根目录，带目录时请求确认后允许文件工具在本次会话中访问该目录
+func (m *Model) handleAddRootCommand(cmd *Command) tea.Cmd {
+	respond := func(content string) tea.Cmd {
+		return func() tea.Msg {
+			return ResponseMsg{Content: content}
+		}
+	}
+
+	if cmd.Content == "" {
+		return respond(i18n.T("command.roots_list", strings.Join(m.toolManager.Roots(), "\n")))
+	}
+
+	dir := cmd.Content
+	if rest, ok := strings.CutPrefix(dir, "~"); ok && (rest == "" || strings.HasPrefix(rest, "/")) {
+		if home, err := os.UserHomeDir(); err == nil {
+			dir = home + rest
+		}
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return respond(i18n.T("command.add_root_failed", err))
+	}
+	if info, err := os.Stat(abs); err != nil || !info.IsDir() {
+		return respond(i18n.T("command.add_root_not_dir", abs))
+	}
+
+	m.pendingRoot = abs
+	m.messages = append(m.messages, Message{Role: "system", Content: i18n.T("command.add_root_confirm", abs)})
+	return m.updateViewport()
+}
+
+// handleAddRootKey 处理添加根目录确认期间的按键：y 添加，n 或 Esc 取消
+func (m *Model) handleAddRootKey(msg tea.KeyMsg) tea.Cmd {
+	var content string
+	switch strings.ToLower(msg.String()) {
+	case "y":
+		root, err := m.toolManager.AddRoot(m.pendingRoot)
+		if err != nil {
+			content = i18n.T("command.add_root_failed", err)
+		} else {
+			content = i18n.T("command.add_root_added", root, len(m.toolManager.Roots()))
+		}
+	case "n", "esc":
+		content = i18n.T("command.add_root_cancelled")
+	default:
+		return nil
+	}
+	m.pendingRoot = ""
+	m.messages = append(m.messages, Message{Role: "system", Content: content})
+	return m.updateViewport()
+}
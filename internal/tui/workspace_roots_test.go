@@ -0,0 +1,49 @@
+package tui
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestAddRootCommand(t *testing.T) {
+	m := goldenModel(t)
+	sibling := t.TempDir()
+	parser := NewCommandParser()
+
+	cmd := parser.Parse("/add-root " + filepath.Join(sibling, "missing"))
+	if msg := m.handleAddRootCommand(cmd)(); !strings.Contains(msg.(ResponseMsg).Content, "❌") || m.pendingRoot != "" {
+		t.Fatalf("missing directory should be rejected: %+v", msg)
+	}
+
+	// 取消时不添加
+	m.handleAddRootCommand(parser.Parse("/add-root " + sibling))
+	if m.pendingRoot != sibling || !strings.Contains(m.messages[len(m.messages)-1].Content, "(y/n)") {
+		t.Fatalf("should ask for confirmation, pending=%q", m.pendingRoot)
+	}
+	m = drive(t, m, tea.KeyMsg{Type: tea.KeyEsc})
+	if m.pendingRoot != "" || len(m.toolManager.Roots()) != 1 {
+		t.Fatalf("esc should cancel, roots=%v", m.toolManager.Roots())
+	}
+
+	m.handleAddRootCommand(parser.Parse("/add-root " + sibling))
+	// 确认期间其他按键不进入输入框
+	m = drive(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+	if m.pendingRoot == "" || strings.Contains(m.textarea.Value(), "x") {
+		t.Fatal("other keys should be ignored while confirming")
+	}
+	m = drive(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")})
+	roots := m.toolManager.Roots()
+	if m.pendingRoot != "" || len(roots) != 2 || roots[1] != sibling {
+		t.Fatalf("y should add the root, roots=%v", roots)
+	}
+	if !strings.Contains(strings.Join(m.systemInstructions(), "\n"), sibling) {
+		t.Error("system prompt should list the workspace roots")
+	}
+
+	if msg := m.handleAddRootCommand(parser.Parse("/add-root"))(); !strings.Contains(msg.(ResponseMsg).Content, sibling) {
+		t.Errorf("/add-root without arguments should list the roots: %+v", msg)
+	}
+}
@@ -0,0 +1,63 @@
+package tui
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/i18n"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// handleQuotaCommand 处理 /quota 命令：无参数时显示用量，否则调整本次会话的写入上限
+func (m *Model) handleQuotaCommand(cmd *Command) tea.Cmd {
+	quota := m.toolManager.WriteQuota()
+	respond := func(content string) tea.Cmd {
+		return func() tea.Msg {
+			return ResponseMsg{Content: content}
+		}
+	}
+	if quota == nil {
+		return respond(i18n.T("command.quota_disabled"))
+	}
+
+	written, files, maxBytes, maxFiles := quota.Usage()
+	if cmd.Content == "" {
+		return respond(i18n.T("command.quota_status",
+			formatByteCount(written), formatQuotaBytes(maxBytes), files, formatQuotaCount(maxFiles)))
+	}
+
+	fields := strings.Fields(cmd.Content)
+	if len(fields) > 2 {
+		return respond(i18n.T("command.quota_usage"))
+	}
+	mb, err := strconv.Atoi(fields[0])
+	if err != nil || mb == 0 {
+		return respond(i18n.T("command.quota_usage"))
+	}
+	newMaxFiles := maxFiles
+	if len(fields) == 2 {
+		if newMaxFiles, err = strconv.Atoi(fields[1]); err != nil || newMaxFiles == 0 {
+			return respond(i18n.T("command.quota_usage"))
+		}
+	}
+
+	quota.SetLimits(int64(mb)*1024*1024, newMaxFiles)
+	_, _, maxBytes, maxFiles = quota.Usage()
+	return respond(i18n.T("command.quota_updated", formatQuotaBytes(maxBytes), formatQuotaCount(maxFiles)))
+}
+
+// formatQuotaBytes 格式化字节上限，<= 0 表示不限制
+func formatQuotaBytes(n int64) string {
+	if n <= 0 {
+		return i18n.T("command.quota_unlimited")
+	}
+	return formatByteCount(n)
+}
+
+// formatQuotaCount 格式化文件数上限，<= 0 表示不限制
+func formatQuotaCount(n int) string {
+	if n <= 0 {
+		return i18n.T("command.quota_unlimited")
+	}
+	return strconv.Itoa(n)
+}
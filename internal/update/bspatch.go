@@ -0,0 +1,74 @@
+package update
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// bsdiffMagic bsdiff 4.x 补丁文件的头部标识
+const bsdiffMagic = "BSDIFF40"
+
+// bspatch 将 bsdiff 4.x 格式的补丁应用到 old，返回新文件的内容。
+// 补丁由 32 字节的头部和三个 bzip2 压缩块组成：控制块、差异块和新增数据块
+func bspatch(old, patch []byte) ([]byte, error) {
+	if len(patch) < 32 || string(patch[:8]) != bsdiffMagic {
+		return nil, errors.New("not a bsdiff patch")
+	}
+	ctrlLen := offtin(patch[8:16])
+	diffLen := offtin(patch[16:24])
+	newSize := offtin(patch[24:32])
+	if ctrlLen < 0 || diffLen < 0 || newSize < 0 || 32+ctrlLen+diffLen > int64(len(patch)) {
+		return nil, errors.New("corrupt bsdiff header")
+	}
+
+	body := patch[32:]
+	ctrl := bzip2.NewReader(bytes.NewReader(body[:ctrlLen]))
+	diff := bzip2.NewReader(bytes.NewReader(body[ctrlLen : ctrlLen+diffLen]))
+	extra := bzip2.NewReader(bytes.NewReader(body[ctrlLen+diffLen:]))
+
+	out := make([]byte, newSize)
+	var oldPos, newPos int64
+	var buf [24]byte
+	for newPos < newSize {
+		// 每条控制指令：从差异块复制 x 字节（加上旧文件对应位置的字节），
+		// 从新增数据块复制 y 字节，然后旧文件位置移动 z 字节
+		if _, err := io.ReadFull(ctrl, buf[:]); err != nil {
+			return nil, fmt.Errorf("corrupt bsdiff control block: %w", err)
+		}
+		x, y, z := offtin(buf[0:8]), offtin(buf[8:16]), offtin(buf[16:24])
+		if x < 0 || y < 0 || newPos+x > newSize || newPos+x+y > newSize {
+			return nil, errors.New("corrupt bsdiff control block")
+		}
+
+		if _, err := io.ReadFull(diff, out[newPos:newPos+x]); err != nil {
+			return nil, fmt.Errorf("corrupt bsdiff diff block: %w", err)
+		}
+		for i := int64(0); i < x; i++ {
+			if p := oldPos + i; p >= 0 && p < int64(len(old)) {
+				out[newPos+i] += old[p]
+			}
+		}
+		newPos += x
+		oldPos += x
+
+		if _, err := io.ReadFull(extra, out[newPos:newPos+y]); err != nil {
+			return nil, fmt.Errorf("corrupt bsdiff extra block: %w", err)
+		}
+		newPos += y
+		oldPos += z
+	}
+	return out, nil
+}
+
+// offtin 解码 bsdiff 的 64 位整数：小端序，最高位为符号位
+func offtin(b []byte) int64 {
+	v := int64(binary.LittleEndian.Uint64(b) &^ (1 << 63))
+	if b[7]&0x80 != 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,42 @@
+package update
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func readTestdata(t *testing.T, name string) []byte {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return content
+}
+
+func TestBspatch(t *testing.T) {
+	old := readTestdata(t, "v1.0.0.old")
+	patch := readTestdata(t, "v1.0.0-to-v1.1.0.bsdiff")
+
+	got, err := bspatch(old, patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := readTestdata(t, "v1.1.0.new"); !bytes.Equal(got, want) {
+		t.Errorf("patched = %q, want %q", got, want)
+	}
+
+	corrupt := map[string][]byte{
+		"not a patch":       []byte("#!/bin/sh\necho not a patch\n"),
+		"truncated":         patch[:40],
+		"corrupt block":     append(append([]byte(nil), patch[:40]...), bytes.Repeat([]byte{0xff}, len(patch)-40)...),
+		"negative new size": append(append(append([]byte(nil), patch[:31]...), 0x80), patch[32:]...),
+	}
+	for name, bad := range corrupt {
+		if _, err := bspatch(old, bad); err == nil {
+			t.Errorf("%s: patch accepted", name)
+		}
+	}
+}
@@ -1,12 +1,13 @@
 package update
 
 import (
-	"encoding/json"
 	"fmt"
 	"net/http"
 	"runtime"
 	"strings"
 	"time"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/utils"
 )
 
 const (
@@ -20,37 +21,35 @@ type ReleaseInfo struct {
 	HTMLURL string `json:"html_url"`
 }
 
+// Checker 查询最新发布的版本。结果在配置目录中缓存 ttl 时长，GitHub API 限流时改用发布页的 Atom 订阅，
+// 无法联网时使用过期的缓存
 type Checker struct {
-	client *http.Client
+	client    *http.Client
+	apiURL    string
+	feedURL   string
+	cachePath string // 为空时不缓存
+	ttl       time.Duration
 }
 
 func NewChecker() *Checker {
 	return &Checker{
 		client: &http.Client{
-			Timeout: 10 * time.Second,
+			Timeout:   10 * time.Second,
+			Transport: utils.NewTransport(),
 		},
+		apiURL:    fmt.Sprintf("https://api.github.com/repos/%s/releases/latest", Repo),
+		feedURL:   fmt.Sprintf("https://github.com/%s/releases.atom", Repo),
+		cachePath: defaultCachePath(),
+		ttl:       DefaultCacheTTL,
 	}
 }
 
 func (c *Checker) GetLatestVersion() (string, error) {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/releases/latest", Repo)
-	
-	resp, err := c.client.Get(url)
+	latest, err := c.Latest()
 	if err != nil {
-		return "", fmt.Errorf("failed to fetch latest version: %w", err)
-	}
-	defer resp.Body.Close()
-	
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("GitHub API returned status %d", resp.StatusCode)
-	}
-	
-	var release ReleaseInfo
-	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
+		return "", err
 	}
-	
-	return release.TagName, nil
+	return latest.TagName, nil
 }
 
 func (c *Checker) CheckForUpdate(currentVersion string) (bool, string, error) {
@@ -67,29 +66,26 @@ func (c *Checker) CheckForUpdate(currentVersion string) (bool, string, error) {
 }
 
 func (c *Checker) GetDownloadURL(version string) string {
-	os := runtime.GOOS
-	arch := runtime.GOARCH
-	
-	if os == "darwin" {
-		os = "darwin"
-	} else if os == "linux" {
-		os = "linux"
-	} else if os == "windows" {
-		os = "windows"
-	}
-	
-	if arch == "amd64" {
-		arch = "amd64"
-	} else if arch == "arm64" {
-		arch = "arm64"
-	}
-	
-	binaryName := fmt.Sprintf("polyagent-%s-%s", os, arch)
-	if os == "windows" {
+	return releaseAssetURL(version, assetName())
+}
+
+// releaseAssetURL 发布中某个文件的下载地址
+func releaseAssetURL(version, asset string) string {
+	return fmt.Sprintf("https://github.com/%s/releases/download/%s/%s", Repo, version, asset)
+}
+
+// assetName 当前平台的二进制文件在发布中的文件名
+func assetName() string {
+	return assetNameFor(runtime.GOOS, runtime.GOARCH)
+}
+
+// assetNameFor 指定平台的二进制文件在发布中的文件名，如 polyagent-linux-amd64
+func assetNameFor(goos, goarch string) string {
+	binaryName := fmt.Sprintf("polyagent-%s-%s", goos, goarch)
+	if goos == "windows" {
 		binaryName += ".exe"
 	}
-	
-	return fmt.Sprintf("https://github.com/%s/releases/download/%s/%s", Repo, version, binaryName)
+	return binaryName
 }
 
 func compareVersions(v1, v2 string) int {
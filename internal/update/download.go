@@ -0,0 +1,282 @@
+package update
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxDownloadAttempts 一次更新中下载中断后续传的最多次数
+const maxDownloadAttempts = 3
+
+// errNoDelta 发布中没有从当前版本升级的增量包
+var errNoDelta = errors.New("no delta patch for this version")
+
+// downloadDir 下载中的文件所在目录，位于用户缓存目录中，中断的下载在下次更新时继续
+func downloadDir(version string) string {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		base = os.TempDir()
+	}
+	return filepath.Join(base, "polyagent", "updates", version)
+}
+
+// deltaAssetName 从 from 版本升级到发布版本的增量包（bsdiff 格式）文件名
+func deltaAssetName(asset, from string) string {
+	return asset + "-from-" + from + ".bsdiff"
+}
+
+// downloadFull 下载完整的二进制文件，中断后可续传，校验通过后写入 destPath
+func (u *Updater) downloadFull(version, expectedSHA, destPath string) error {
+	asset := assetName()
+	partPath := filepath.Join(downloadDir(version), asset+".part")
+	if err := u.downloadResumable(releaseAssetURL(version, asset), partPath, expectedSHA); err != nil {
+		return err
+	}
+	return copyFile(partPath, destPath)
+}
+
+// downloadUpdate 将新版本的二进制文件写入 destPath：优先使用从当前版本升级的增量包，没有或应用失败时下载完整的文件
+func (u *Updater) downloadUpdate(currentVersion, latestVersion string, checksums map[string]string, destPath string) error {
+	if err := u.applyDelta(currentVersion, latestVersion, checksums, destPath); err == nil {
+		return nil
+	}
+	return u.downloadFull(latestVersion, checksums[assetName()], destPath)
+}
+
+// applyDelta 下载从当前版本升级的增量包并应用到正在运行的二进制文件，
+// 结果与完整文件的校验和一致时写入 destPath。当前文件被修改过（如本地构建）时校验失败，由调用方改为完整下载
+func (u *Updater) applyDelta(currentVersion, latestVersion string, checksums map[string]string, destPath string) error {
+	asset := assetName()
+	delta := deltaAssetName(asset, currentVersion)
+	deltaSHA, ok := checksums[delta]
+	if !ok {
+		return errNoDelta
+	}
+
+	executablePath, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	old, err := os.ReadFile(executablePath)
+	if err != nil {
+		return err
+	}
+
+	patchPath := filepath.Join(downloadDir(latestVersion), delta+".part")
+	if err := u.downloadResumable(releaseAssetURL(latestVersion, delta), patchPath, deltaSHA); err != nil {
+		return err
+	}
+	patch, err := os.ReadFile(patchPath)
+	if err != nil {
+		return err
+	}
+	patched, err := bspatch(old, patch)
+	if err != nil {
+		return err
+	}
+	if actual := fmt.Sprintf("%x", sha256.Sum256(patched)); actual != checksums[asset] {
+		return fmt.Errorf("patched binary checksum mismatch: expected %s, got %s", checksums[asset], actual)
+	}
+	return os.WriteFile(destPath, patched, 0755)
+}
+
+// copyFile 复制下载完成的文件，下载目录和安装时使用的临时目录可能不在同一个文件系统上
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// fetchChecksums 下载发布的 checksums.txt，返回文件名到 SHA256 的映射
+func (u *Updater) fetchChecksums(version string) (map[string]string, error) {
+	resp, err := u.client.Get(releaseAssetURL(version, "checksums.txt"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download checksums: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("checksum file not found")
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checksums: %w", err)
+	}
+	return parseChecksums(string(body)), nil
+}
+
+// parseChecksums 解析 sha256sum 的输出，文件名可能带有目录和表示二进制模式的 *
+func parseChecksums(content string) map[string]string {
+	checksums := make(map[string]string)
+	for _, line := range splitLines(content) {
+		parts := splitFields(line)
+		if len(parts) >= 2 {
+			checksums[path.Base(strings.TrimPrefix(parts[1], "*"))] = strings.ToLower(parts[0])
+		}
+	}
+	return checksums
+}
+
+// downloadResumable 将 url 下载到 partPath，校验 SHA256 后返回。
+// 连接中断时用 HTTP Range 从已下载的位置续传；部分文件旁记录着期望的校验和，
+// 发布的文件变化（校验和不同）时丢弃已下载的部分；续传得到的文件校验失败时从头重新下载一次
+func (u *Updater) downloadResumable(url, partPath, expectedSHA string) error {
+	if err := os.MkdirAll(filepath.Dir(partPath), 0755); err != nil {
+		return fmt.Errorf("failed to create download directory: %w", err)
+	}
+	shaPath := partPath + ".sha256"
+	if recorded, err := os.ReadFile(shaPath); err != nil || strings.TrimSpace(string(recorded)) != expectedSHA {
+		os.Remove(partPath)
+		if err := os.WriteFile(shaPath, []byte(expectedSHA+"\n"), 0644); err != nil {
+			return fmt.Errorf("failed to record checksum: %w", err)
+		}
+	}
+
+	// 续传得到的文件校验失败时，已下载的部分可能已损坏，从头下载一次
+	for fresh := false; ; fresh = true {
+		resumed, err := u.downloadWithRetry(url, partPath)
+		if err != nil {
+			return err
+		}
+
+		actual, err := calculateSHA256(partPath)
+		if err != nil {
+			return fmt.Errorf("failed to calculate checksum: %w", err)
+		}
+		if actual == expectedSHA {
+			return nil
+		}
+		os.Remove(partPath)
+		if !resumed || fresh {
+			return fmt.Errorf("checksum mismatch: expected %s, got %s", expectedSHA, actual)
+		}
+	}
+}
+
+// downloadFile 下载没有校验和的文件（如 InstallFromURL 指定的地址），连接中断时续传
+func (u *Updater) downloadFile(url, destPath string) error {
+	os.Remove(destPath)
+	_, err := u.downloadWithRetry(url, destPath)
+	return err
+}
+
+// downloadWithRetry 下载到 partPath，连接中断时从已下载的位置续传，最多尝试 maxDownloadAttempts 次。
+// 返回结果中是否包含之前下载的部分
+func (u *Updater) downloadWithRetry(url, partPath string) (bool, error) {
+	resumed := false
+	if info, err := os.Stat(partPath); err == nil && info.Size() > 0 {
+		resumed = true
+	}
+
+	var err error
+	for attempt := 1; attempt <= maxDownloadAttempts; attempt++ {
+		if err = u.downloadRange(url, partPath); err == nil {
+			return resumed, nil
+		}
+		var statusErr *httpStatusError
+		if errors.As(err, &statusErr) {
+			// 服务端明确拒绝，重试没有意义
+			break
+		}
+		resumed = true
+		if attempt < maxDownloadAttempts {
+			time.Sleep(time.Duration(attempt) * time.Second)
+		}
+	}
+	return resumed, err
+}
+
+// httpStatusError 服务端返回了无法续传的状态码
+type httpStatusError struct {
+	code   int
+	status string
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("HTTP %d: %s", e.code, e.status)
+}
+
+// downloadRange 从 partPath 已有的长度处继续下载到文件结尾
+func (u *Updater) downloadRange(url, partPath string) error {
+	var offset int64
+	if info, err := os.Stat(partPath); err == nil {
+		offset = info.Size()
+	}
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		if start, ok := contentRangeStart(resp.Header.Get("Content-Range")); !ok || start != offset {
+			// 返回的范围与请求的不一致，丢弃已下载的部分，下次从头下载
+			os.Remove(partPath)
+			return fmt.Errorf("unexpected Content-Range %q", resp.Header.Get("Content-Range"))
+		}
+		flags |= os.O_APPEND
+	case http.StatusOK:
+		// 服务端不支持 Range，从头下载
+		flags |= os.O_TRUNC
+	case http.StatusRequestedRangeNotSatisfiable:
+		// 已下载完整，由调用方校验
+		return nil
+	default:
+		return &httpStatusError{code: resp.StatusCode, status: resp.Status}
+	}
+
+	out, err := os.OpenFile(partPath, flags, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, resp.Body); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// contentRangeStart 解析 Content-Range（如 bytes 100-199/200）的起始位置
+func contentRangeStart(header string) (int64, bool) {
+	rest, ok := strings.CutPrefix(header, "bytes ")
+	if !ok {
+		return 0, false
+	}
+	start, _, ok := strings.Cut(rest, "-")
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(start, 10, 64)
+	return n, err == nil
+}
@@ -0,0 +1,248 @@
+package update
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// releaseServer 模拟发布文件的下载地址，支持 Range 请求并记录每次请求的 Range 头
+type releaseServer struct {
+	*httptest.Server
+	mu     sync.Mutex
+	files  map[string][]byte
+	ranges []string
+	paths  []string
+}
+
+func newReleaseServer(t *testing.T, files map[string][]byte) *releaseServer {
+	t.Helper()
+	s := &releaseServer{files: files}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.ranges = append(s.ranges, r.Header.Get("Range"))
+		s.paths = append(s.paths, filepath.Base(r.URL.Path))
+		content, ok := s.files[filepath.Base(r.URL.Path)]
+		s.mu.Unlock()
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// requests 返回收到的请求的 Range 头（没有 Range 时为空字符串）
+func (s *releaseServer) requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.ranges...)
+}
+
+// fetched 返回请求过的文件名
+func (s *releaseServer) fetched() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.paths...)
+}
+
+// rewriteTransport 把对 GitHub 发布地址的请求转发到测试服务器
+type rewriteTransport struct {
+	host string
+}
+
+func (t rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = "http"
+	req.URL.Host = t.host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// isolateCacheDir 让下载目录（用户缓存目录）位于测试的临时目录中
+func isolateCacheDir(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", dir)
+	t.Setenv("HOME", dir)
+	t.Setenv("LocalAppData", dir)
+}
+
+func sha256Hex(content []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(content))
+}
+
+func TestDownloadResumable(t *testing.T) {
+	content := bytes.Repeat([]byte("polyagent release binary\n"), 100)
+	sha := sha256Hex(content)
+	half := len(content) / 2
+
+	tests := []struct {
+		name string
+		// part 下载目录中已有的部分文件，recorded 是旁边记录的校验和，都为空表示没有
+		part     []byte
+		recorded string
+		served   []byte
+		expected string
+		// ranges 期望服务端依次收到的 Range 头
+		ranges  []string
+		wantErr string
+	}{
+		{
+			name:     "fresh download",
+			served:   content,
+			expected: sha,
+			ranges:   []string{""},
+		},
+		{
+			name:     "partial file is resumed",
+			part:     content[:half],
+			recorded: sha,
+			served:   content,
+			expected: sha,
+			ranges:   []string{fmt.Sprintf("bytes=%d-", half)},
+		},
+		{
+			name:     "complete cached file is reused",
+			part:     content,
+			recorded: sha,
+			served:   content,
+			expected: sha,
+			ranges:   []string{fmt.Sprintf("bytes=%d-", len(content))},
+		},
+		{
+			name:     "partial file of another release is discarded",
+			part:     []byte("old release"),
+			recorded: sha256Hex([]byte("old release binary")),
+			served:   content,
+			expected: sha,
+			ranges:   []string{""},
+		},
+		{
+			name:     "corrupt partial file is downloaded again",
+			part:     bytes.Repeat([]byte("x"), half),
+			recorded: sha,
+			served:   content,
+			expected: sha,
+			ranges:   []string{fmt.Sprintf("bytes=%d-", half), ""},
+		},
+		{
+			name:     "bad checksum",
+			served:   content,
+			expected: sha256Hex([]byte("something else")),
+			ranges:   []string{""},
+			wantErr:  "checksum mismatch",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newReleaseServer(t, map[string][]byte{"asset": tt.served})
+			partPath := filepath.Join(t.TempDir(), "asset.part")
+			if tt.part != nil {
+				if err := os.WriteFile(partPath, tt.part, 0644); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(partPath+".sha256", []byte(tt.recorded+"\n"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			u := &Updater{client: server.Client()}
+			err := u.downloadResumable(server.URL+"/asset", partPath, tt.expected)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				// 校验失败的文件不保留，下次从头下载
+				if _, err := os.Stat(partPath); !os.IsNotExist(err) {
+					t.Errorf("part file kept after checksum mismatch: %v", err)
+				}
+			} else {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got, _ := os.ReadFile(partPath); !bytes.Equal(got, content) {
+					t.Errorf("downloaded %d bytes, want %d", len(got), len(content))
+				}
+			}
+			if got := server.requests(); strings.Join(got, "|") != strings.Join(tt.ranges, "|") {
+				t.Errorf("Range headers = %q, want %q", got, tt.ranges)
+			}
+		})
+	}
+}
+
+func TestDownloadUpdate(t *testing.T) {
+	asset := assetName()
+	delta := deltaAssetName(asset, "v1.0.0")
+	// replace.bsdiff 不引用旧文件的内容，应用到任何当前二进制文件都得到同样的结果
+	patch := readTestdata(t, "replace.bsdiff")
+	patched := []byte("polyagent v1.1.0 full binary\n")
+	release := []byte("polyagent v1.1.0 release build\n")
+
+	tests := []struct {
+		name      string
+		files     map[string][]byte
+		checksums map[string]string
+		want      []byte
+		// fetched 期望依次请求的文件
+		fetched []string
+	}{
+		{
+			name:      "good patch",
+			files:     map[string][]byte{delta: patch},
+			checksums: map[string]string{asset: sha256Hex(patched), delta: sha256Hex(patch)},
+			want:      patched,
+			fetched:   []string{delta},
+		},
+		{
+			name:      "bad patch falls back to full download",
+			files:     map[string][]byte{delta: []byte("not a patch"), asset: release},
+			checksums: map[string]string{asset: sha256Hex(release), delta: sha256Hex([]byte("not a patch"))},
+			want:      release,
+			fetched:   []string{delta, asset},
+		},
+		{
+			name:      "patched binary with wrong checksum falls back",
+			files:     map[string][]byte{delta: patch, asset: release},
+			checksums: map[string]string{asset: sha256Hex(release), delta: sha256Hex(patch)},
+			want:      release,
+			fetched:   []string{delta, asset},
+		},
+		{
+			name:      "no patch for this version",
+			files:     map[string][]byte{asset: release},
+			checksums: map[string]string{asset: sha256Hex(release)},
+			want:      release,
+			fetched:   []string{asset},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			isolateCacheDir(t)
+			server := newReleaseServer(t, tt.files)
+			u := &Updater{client: &http.Client{Transport: rewriteTransport{host: server.Listener.Addr().String()}}}
+
+			dest := filepath.Join(t.TempDir(), "polyagent")
+			if err := u.downloadUpdate("v1.0.0", "v1.1.0", tt.checksums, dest); err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := os.ReadFile(dest); !bytes.Equal(got, tt.want) {
+				t.Errorf("installed %q, want %q", got, tt.want)
+			}
+			if got := server.fetched(); strings.Join(got, "|") != strings.Join(tt.fetched, "|") {
+				t.Errorf("fetched %q, want %q", got, tt.fetched)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package update
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// 包管理器清单中的项目信息
+const (
+	projectDescription = "Vibe coding tool in the terminal, powered by GLM"
+	projectHomepage    = "https://github.com/" + Repo
+	projectLicense     = "Apache-2.0"
+)
+
+// ReleaseChecksums 下载指定版本发布的 checksums.txt，返回文件名到 SHA256 的映射
+func ReleaseChecksums(version string) (map[string]string, error) {
+	return NewUpdater().fetchChecksums(version)
+}
+
+// ParseChecksums 解析 sha256sum 格式的校验和文件内容
+func ParseChecksums(content string) map[string]string {
+	return parseChecksums(content)
+}
+
+// manifestAsset 清单中一个平台的下载地址和校验和
+type manifestAsset struct {
+	URL    string
+	SHA256 string
+}
+
+// releaseAssets 从校验和中找出各平台的二进制文件，使用与自更新相同的文件名和下载地址，
+// 没有校验和的平台（未发布）不会出现在结果中
+func releaseAssets(version string, checksums map[string]string, platforms [][2]string) map[string]manifestAsset {
+	assets := make(map[string]manifestAsset)
+	for _, platform := range platforms {
+		name := assetNameFor(platform[0], platform[1])
+		if sha, ok := checksums[name]; ok {
+			assets[platform[0]+"/"+platform[1]] = manifestAsset{URL: releaseAssetURL(version, name), SHA256: sha}
+		}
+	}
+	return assets
+}
+
+// HomebrewFormula 生成 Homebrew formula，安装 macOS 和 Linux 的预编译二进制文件
+func HomebrewFormula(version string, checksums map[string]string) (string, error) {
+	assets := releaseAssets(version, checksums, [][2]string{
+		{"darwin", "arm64"}, {"darwin", "amd64"}, {"linux", "arm64"}, {"linux", "amd64"},
+	})
+	if len(assets) == 0 {
+		return "", fmt.Errorf("no macOS or Linux binaries in the checksums of %s", version)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "class Polyagent < Formula\n")
+	fmt.Fprintf(&sb, "  desc %q\n", projectDescription)
+	fmt.Fprintf(&sb, "  homepage %q\n", projectHomepage)
+	fmt.Fprintf(&sb, "  version %q\n", strings.TrimPrefix(version, "v"))
+	fmt.Fprintf(&sb, "  license %q\n", projectLicense)
+	for _, osBlock := range []struct{ block, goos string }{{"on_macos", "darwin"}, {"on_linux", "linux"}} {
+		arm, hasArm := assets[osBlock.goos+"/arm64"]
+		intel, hasIntel := assets[osBlock.goos+"/amd64"]
+		if !hasArm && !hasIntel {
+			continue
+		}
+		fmt.Fprintf(&sb, "\n  %s do\n", osBlock.block)
+		if hasArm {
+			fmt.Fprintf(&sb, "    on_arm do\n      url %q\n      sha256 %q\n    end\n", arm.URL, arm.SHA256)
+		}
+		if hasIntel {
+			fmt.Fprintf(&sb, "    on_intel do\n      url %q\n      sha256 %q\n    end\n", intel.URL, intel.SHA256)
+		}
+		fmt.Fprintf(&sb, "  end\n")
+	}
+	sb.WriteString(`
+  def install
+    bin.install Dir["polyagent-*"].first => "polyagent"
+  end
+
+  test do
+    assert_match version.to_s, shell_output("#{bin}/polyagent --version")
+  end
+end
+`)
+	return sb.String(), nil
+}
+
+// scoopArchitecture Scoop 清单中一个架构的下载地址和校验和
+type scoopArchitecture struct {
+	URL  string `json:"url"`
+	Hash string `json:"hash,omitempty"`
+}
+
+// scoopManifest Scoop 清单，字段顺序与 Scoop 官方 bucket 一致
+type scoopManifest struct {
+	Version      string                       `json:"version"`
+	Description  string                       `json:"description"`
+	Homepage     string                       `json:"homepage"`
+	License      string                       `json:"license"`
+	Architecture map[string]scoopArchitecture `json:"architecture"`
+	Bin          string                       `json:"bin"`
+	Checkver     map[string]string            `json:"checkver"`
+	Autoupdate   struct {
+		Architecture map[string]scoopArchitecture `json:"architecture"`
+	} `json:"autoupdate"`
+}
+
+// ScoopManifest 生成 Scoop 清单，下载的 exe 重命名为 polyagent.exe。
+// autoupdate 中的地址使用 $version 占位，Scoop 自动更新时计算校验和
+func ScoopManifest(version string, checksums map[string]string) ([]byte, error) {
+	assets := releaseAssets(version, checksums, [][2]string{{"windows", "amd64"}, {"windows", "arm64"}})
+	if len(assets) == 0 {
+		return nil, fmt.Errorf("no Windows binaries in the checksums of %s", version)
+	}
+
+	manifest := scoopManifest{
+		Version:      strings.TrimPrefix(version, "v"),
+		Description:  projectDescription,
+		Homepage:     projectHomepage,
+		License:      projectLicense,
+		Architecture: make(map[string]scoopArchitecture),
+		Bin:          "polyagent.exe",
+		Checkver:     map[string]string{"github": projectHomepage},
+	}
+	manifest.Autoupdate.Architecture = make(map[string]scoopArchitecture)
+	for goarch, scoopArch := range map[string]string{"amd64": "64bit", "arm64": "arm64"} {
+		asset, ok := assets["windows/"+goarch]
+		if !ok {
+			continue
+		}
+		name := assetNameFor("windows", goarch)
+		manifest.Architecture[scoopArch] = scoopArchitecture{URL: asset.URL + "#/polyagent.exe", Hash: asset.SHA256}
+		manifest.Autoupdate.Architecture[scoopArch] = scoopArchitecture{
+			URL: releaseAssetURL("v$version", name) + "#/polyagent.exe",
+		}
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "    ")
+	if err := encoder.Encode(manifest); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,187 @@
+package update
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/utils"
+)
+
+// DefaultCacheTTL 最新版本信息的缓存时长，期间重复检查不再访问网络
+const DefaultCacheTTL = time.Hour
+
+// ErrUnavailable 无法联网检查且没有缓存的结果
+var ErrUnavailable = errors.New("couldn't check for updates")
+
+// LatestRelease 最新发布的版本及其获取时间
+type LatestRelease struct {
+	ReleaseInfo
+	CheckedAt time.Time
+	// Stale 为 true 表示这次无法联网，使用的是过期的缓存
+	Stale bool
+}
+
+// NewerThan 该版本是否比 current 新
+func (r *LatestRelease) NewerThan(current string) bool {
+	return compareVersions(current, r.TagName) < 0
+}
+
+// releaseCache 缓存文件的内容，ETag 用于条件请求，未变化时 GitHub 不计入限流
+type releaseCache struct {
+	CheckedAt time.Time   `json:"checked_at"`
+	ETag      string      `json:"etag,omitempty"`
+	Release   ReleaseInfo `json:"release"`
+}
+
+// defaultCachePath 缓存文件位于配置目录，无法确定配置目录时不缓存
+func defaultCachePath() string {
+	configDir, err := utils.GetConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(configDir, "update-check.json")
+}
+
+// Latest 返回最新发布的版本：缓存未过期时直接使用，否则访问 GitHub；
+// 网络不可用时退回过期的缓存，没有缓存时返回 ErrUnavailable
+func (c *Checker) Latest() (*LatestRelease, error) {
+	cached := c.loadCache()
+	if cached != nil && time.Since(cached.CheckedAt) < c.ttl {
+		return &LatestRelease{ReleaseInfo: cached.Release, CheckedAt: cached.CheckedAt}, nil
+	}
+
+	release, etag, err := c.fetch(cached)
+	if err != nil {
+		if cached != nil {
+			return &LatestRelease{ReleaseInfo: cached.Release, CheckedAt: cached.CheckedAt, Stale: true}, nil
+		}
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+
+	fresh := &releaseCache{CheckedAt: time.Now(), ETag: etag, Release: release}
+	// 缓存只是优化，写入失败不影响结果
+	_ = c.saveCache(fresh)
+	return &LatestRelease{ReleaseInfo: release, CheckedAt: fresh.CheckedAt}, nil
+}
+
+// fetch 查询 GitHub API，带上缓存的 ETag；被限流时改用 Atom 订阅
+func (c *Checker) fetch(cached *releaseCache) (ReleaseInfo, string, error) {
+	req, err := http.NewRequest(http.MethodGet, c.apiURL, nil)
+	if err != nil {
+		return ReleaseInfo{}, "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	if cached != nil && cached.ETag != "" {
+		req.Header.Set("If-None-Match", cached.ETag)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return ReleaseInfo{}, "", fmt.Errorf("failed to fetch latest version: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached != nil:
+		return cached.Release, cached.ETag, nil
+	case resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests:
+		// 未认证的请求每小时只有 60 次，Atom 订阅不受此限制
+		release, feedErr := c.fetchFeed()
+		if feedErr != nil {
+			return ReleaseInfo{}, "", fmt.Errorf("GitHub API rate limited (status %d), feed fallback failed: %w", resp.StatusCode, feedErr)
+		}
+		return release, "", nil
+	case resp.StatusCode != http.StatusOK:
+		return ReleaseInfo{}, "", fmt.Errorf("GitHub API returned status %d", resp.StatusCode)
+	}
+
+	var release ReleaseInfo
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return ReleaseInfo{}, "", fmt.Errorf("failed to decode response: %w", err)
+	}
+	if release.TagName == "" {
+		return ReleaseInfo{}, "", errors.New("GitHub API returned no tag name")
+	}
+	return release, resp.Header.Get("ETag"), nil
+}
+
+// atomFeed 发布页 Atom 订阅中用到的字段，条目按发布时间从新到旧排列
+type atomFeed struct {
+	Entries []struct {
+		Link struct {
+			Href string `xml:"href,attr"`
+		} `xml:"link"`
+	} `xml:"entry"`
+}
+
+// fetchFeed 从 Atom 订阅中取最新的正式版本（跳过 v1.2.0-rc.1 这样的预发布版本）
+func (c *Checker) fetchFeed() (ReleaseInfo, error) {
+	resp, err := c.client.Get(c.feedURL)
+	if err != nil {
+		return ReleaseInfo{}, fmt.Errorf("failed to fetch release feed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return ReleaseInfo{}, fmt.Errorf("release feed returned status %d", resp.StatusCode)
+	}
+
+	var feed atomFeed
+	if err := xml.NewDecoder(resp.Body).Decode(&feed); err != nil {
+		return ReleaseInfo{}, fmt.Errorf("failed to decode release feed: %w", err)
+	}
+	for _, entry := range feed.Entries {
+		// 链接形如 https://github.com/owner/repo/releases/tag/v1.2.3
+		tag := path.Base(entry.Link.Href)
+		if !strings.Contains(entry.Link.Href, "/releases/tag/") || strings.Contains(tag, "-") {
+			continue
+		}
+		return ReleaseInfo{TagName: tag, HTMLURL: entry.Link.Href}, nil
+	}
+	return ReleaseInfo{}, errors.New("release feed has no releases")
+}
+
+// loadCache 读取缓存，不存在或损坏时返回 nil
+func (c *Checker) loadCache() *releaseCache {
+	if c.cachePath == "" {
+		return nil
+	}
+	data, err := os.ReadFile(c.cachePath)
+	if err != nil {
+		return nil
+	}
+	var cached releaseCache
+	if err := json.Unmarshal(data, &cached); err != nil || cached.Release.TagName == "" {
+		return nil
+	}
+	return &cached
+}
+
+func (c *Checker) saveCache(cached *releaseCache) error {
+	if c.cachePath == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(cached, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.cachePath), 0755); err != nil {
+		return err
+	}
+	tempFile := c.cachePath + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tempFile, c.cachePath); err != nil {
+		os.Remove(tempFile)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package update
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLatestReusesCache(t *testing.T) {
+	var requests atomic.Int32
+	var revalidated atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			revalidated.Store(true)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(`{"tag_name":"v1.2.0","html_url":"https://example.com/v1.2.0"}`))
+	}))
+	defer server.Close()
+
+	c := &Checker{client: server.Client(), apiURL: server.URL, cachePath: filepath.Join(t.TempDir(), "update-check.json"), ttl: time.Hour}
+	for i := 0; i < 2; i++ {
+		latest, err := c.Latest()
+		if err != nil || latest.TagName != "v1.2.0" || latest.Stale {
+			t.Fatalf("Latest() = %+v, %v", latest, err)
+		}
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("cached result not reused: %d requests", n)
+	}
+
+	// 缓存过期后带上 ETag 重新验证
+	c.ttl = 0
+	if latest, err := c.Latest(); err != nil || latest.TagName != "v1.2.0" || !revalidated.Load() {
+		t.Errorf("revalidation: %+v, %v, If-None-Match sent = %v", latest, err, revalidated.Load())
+	}
+
+	// 无法联网时退回过期的缓存
+	server.Close()
+	if latest, err := c.Latest(); err != nil || latest.TagName != "v1.2.0" || !latest.Stale {
+		t.Errorf("offline: %+v, %v", latest, err)
+	}
+}
@@ -10,6 +10,8 @@ import (
 	"path/filepath"
 	"runtime"
 	"time"
+
+	"github.com/Zacy-Sokach/PolyAgent/internal/utils"
 )
 
 type Updater struct {
@@ -21,7 +23,8 @@ func NewUpdater() *Updater {
 	return &Updater{
 		checker: NewChecker(),
 		client: &http.Client{
-			Timeout: 60 * time.Second,
+			Timeout:   60 * time.Second,
+			Transport: utils.NewTransport(),
 		},
 	}
 }
@@ -38,28 +41,30 @@ func (u *Updater) Update(currentVersion string) error {
 	
 	fmt.Printf("Updating from %s to %s...\n", currentVersion, latestVersion)
 	
-	downloadURL := u.checker.GetDownloadURL(latestVersion)
-	checksumURL := fmt.Sprintf("https://github.com/%s/releases/download/%s/checksums.txt", Repo, latestVersion)
-	
+	checksums, err := u.fetchChecksums(latestVersion)
+	if err != nil {
+		return fmt.Errorf("checksum verification failed: %w", err)
+	}
+	asset := assetName()
+	if _, ok := checksums[asset]; !ok {
+		return fmt.Errorf("checksum verification failed: checksum not found for %s", asset)
+	}
+
 	tempDir, err := os.MkdirTemp("", "polyagent-update-")
 	if err != nil {
 		return fmt.Errorf("failed to create temp directory: %w", err)
 	}
 	defer os.RemoveAll(tempDir)
-	
+
 	binaryPath := filepath.Join(tempDir, "polyagent")
 	if runtime.GOOS == "windows" {
 		binaryPath += ".exe"
 	}
-	
-	if err := u.downloadFile(downloadURL, binaryPath); err != nil {
+
+	if err := u.downloadUpdate(currentVersion, latestVersion, checksums, binaryPath); err != nil {
 		return fmt.Errorf("failed to download update: %w", err)
 	}
-	
-	if err := u.verifyChecksum(binaryPath, checksumURL); err != nil {
-		return fmt.Errorf("checksum verification failed: %w", err)
-	}
-	
+
 	if err := os.Chmod(binaryPath, 0755); err != nil {
 		return fmt.Errorf("failed to make binary executable: %w", err)
 	}
@@ -80,71 +85,13 @@ func (u *Updater) Update(currentVersion string) error {
 	}
 	
 	os.Remove(backupPath)
+	os.RemoveAll(downloadDir(latestVersion))
 	
 	fmt.Printf("Successfully updated to %s!\n", latestVersion)
 	
 	return nil
 }
 
-func (u *Updater) downloadFile(url, destPath string) error {
-	resp, err := u.client.Get(url)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
-	}
-	
-	out, err := os.Create(destPath)
-	if err != nil {
-		return err
-	}
-	defer out.Close()
-	
-	_, err = io.Copy(out, resp.Body)
-	return err
-}
-
-func (u *Updater) verifyChecksum(filePath, checksumURL string) error {
-	resp, err := u.client.Get(checksumURL)
-	if err != nil {
-		return fmt.Errorf("failed to download checksums: %w", err)
-	}
-	defer resp.Body.Close()
-	
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("checksum file not found")
-	}
-	
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to read checksums: %w", err)
-	}
-	
-	fileName := filepath.Base(filePath)
-	lines := string(body)
-	for _, line := range splitLines(lines) {
-		parts := splitFields(line)
-		if len(parts) >= 2 && parts[1] == fileName {
-			expectedChecksum := parts[0]
-			actualChecksum, err := calculateSHA256(filePath)
-			if err != nil {
-				return fmt.Errorf("failed to calculate checksum: %w", err)
-			}
-			
-			if expectedChecksum != actualChecksum {
-				return fmt.Errorf("checksum mismatch: expected %s, got %s", expectedChecksum, actualChecksum)
-			}
-			
-			return nil
-		}
-	}
-	
-	return fmt.Errorf("checksum not found for %s", fileName)
-}
-
 func calculateSHA256(filePath string) (string, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SessionSnapshot 自动保存的会话状态，用于异常退出后恢复
+type SessionSnapshot struct {
+	SavedAt  time.Time `json:"saved_at"`
+	Title    string    `json:"title,omitempty"`
+	Messages []Message `json:"messages"`
+	// APIMessages 发送给模型的完整消息历史（含工具调用），由调用方负责编解码
+	APIMessages json.RawMessage `json:"api_messages,omitempty"`
+}
+
+// SaveAutosave 原子写入会话快照，覆盖上一次的快照
+func SaveAutosave(snapshot *SessionSnapshot) error {
+	autosavePath, err := getAutosavePath()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("序列化会话快照失败: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(autosavePath), 0755); err != nil {
+		return fmt.Errorf("创建配置目录失败: %w", err)
+	}
+
+	tempFile := autosavePath + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0600); err != nil {
+		return fmt.Errorf("写入会话快照失败: %w", err)
+	}
+	if err := os.Rename(tempFile, autosavePath); err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("写入会话快照失败: %w", err)
+	}
+	return nil
+}
+
+// LoadAutosave 读取会话快照，不存在时返回 nil
+func LoadAutosave() (*SessionSnapshot, error) {
+	autosavePath, err := getAutosavePath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(autosavePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取会话快照失败: %w", err)
+	}
+
+	var snapshot SessionSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("解析会话快照失败: %w", err)
+	}
+	return &snapshot, nil
+}
+
+// ClearAutosave 删除会话快照，正常退出或清空会话时调用
+func ClearAutosave() error {
+	autosavePath, err := getAutosavePath()
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(autosavePath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("删除会话快照失败: %w", err)
+	}
+	return nil
+}
+
+func getAutosavePath() (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("获取配置目录失败: %w", err)
+	}
+	return filepath.Join(configDir, "autosave.json"), nil
+}
@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestAutosave_RoundTrip(t *testing.T) {
+	t.Setenv("POLYAGENT_CONFIG_HOME", t.TempDir())
+
+	snapshot, err := LoadAutosave()
+	if err != nil || snapshot != nil {
+		t.Fatalf("Expected no snapshot, got %v, %v", snapshot, err)
+	}
+
+	saved := &SessionSnapshot{
+		Title:       "修复登录问题",
+		Messages:    []Message{{Role: "user", Content: "hello"}},
+		APIMessages: json.RawMessage(`[{"role":"user","content":"hello"}]`),
+	}
+	if err := SaveAutosave(saved); err != nil {
+		t.Fatalf("SaveAutosave failed: %v", err)
+	}
+
+	loaded, err := LoadAutosave()
+	if err != nil {
+		t.Fatalf("LoadAutosave failed: %v", err)
+	}
+	if loaded == nil || loaded.Title != saved.Title || len(loaded.Messages) != 1 {
+		t.Fatalf("Unexpected snapshot: %+v", loaded)
+	}
+	if string(loaded.APIMessages) != string(saved.APIMessages) {
+		t.Errorf("Expected api messages %s, got %s", saved.APIMessages, loaded.APIMessages)
+	}
+
+	if err := ClearAutosave(); err != nil {
+		t.Fatalf("ClearAutosave failed: %v", err)
+	}
+	if snapshot, _ := LoadAutosave(); snapshot != nil {
+		t.Error("Expected snapshot to be removed")
+	}
+	// 重复删除不应报错
+	if err := ClearAutosave(); err != nil {
+		t.Errorf("ClearAutosave on missing file failed: %v", err)
+	}
+}
@@ -0,0 +1,208 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// checkpointTimeout 创建快照和比较差异的最长时间，超出时放弃本轮的修改汇总
+const checkpointTimeout = 30 * time.Second
+
+// ErrNotGitRepo 目录不在 git 仓库中，无法创建检查点
+var ErrNotGitRepo = errors.New("not a git repository")
+
+// GitCheckpoint 工作区在某一时刻的快照：用临时索引把所有未忽略的文件（包括未跟踪的）
+// 写成 git tree 对象，不影响用户的暂存区和提交历史
+type GitCheckpoint struct {
+	root string // 仓库根目录
+	tree string
+}
+
+// FileChange 检查点之后一个文件的变化
+type FileChange struct {
+	Path    string // 相对仓库根目录，使用 /
+	Status  string // A 新增、M 修改、D 删除
+	Added   int
+	Deleted int
+	Binary  bool
+}
+
+// CreateCheckpoint 为 dir 所在的 git 仓库创建检查点，不在仓库中时返回 ErrNotGitRepo
+func CreateCheckpoint(dir string) (*GitCheckpoint, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), checkpointTimeout)
+	defer cancel()
+
+	out, err := gitOutput(ctx, dir, nil, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, ErrNotGitRepo
+	}
+	root := strings.TrimSpace(out)
+	tree, err := writeWorktreeTree(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+	return &GitCheckpoint{root: root, tree: tree}, nil
+}
+
+// Root 返回检查点所在仓库的根目录
+func (c *GitCheckpoint) Root() string {
+	return c.root
+}
+
+// Changes 返回检查点之后工作区中变化的文件（按路径排序），以及当前工作区的快照，用于查看差异
+func (c *GitCheckpoint) Changes() ([]FileChange, *GitCheckpoint, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), checkpointTimeout)
+	defer cancel()
+
+	tree, err := writeWorktreeTree(ctx, c.root)
+	if err != nil {
+		return nil, nil, err
+	}
+	current := &GitCheckpoint{root: c.root, tree: tree}
+	if tree == c.tree {
+		return nil, current, nil
+	}
+
+	numstat, err := gitOutput(ctx, c.root, nil, "diff", "--no-renames", "--numstat", "-z", c.tree, tree)
+	if err != nil {
+		return nil, nil, err
+	}
+	nameStatus, err := gitOutput(ctx, c.root, nil, "diff", "--no-renames", "--name-status", "-z", c.tree, tree)
+	if err != nil {
+		return nil, nil, err
+	}
+	return parseFileChanges(numstat, nameStatus), current, nil
+}
+
+// Diff 返回 path 从检查点到 to 的统一格式差异
+func (c *GitCheckpoint) Diff(to *GitCheckpoint, path string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), checkpointTimeout)
+	defer cancel()
+	return gitOutput(ctx, c.root, literalPathspecs, "diff", "--no-renames", "--no-color", c.tree, to.tree, "--", path)
+}
+
+// Restore 将 path 恢复为检查点中的内容，检查点中没有该文件时删除它
+func (c *GitCheckpoint) Restore(path string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), checkpointTimeout)
+	defer cancel()
+
+	full := filepath.Join(c.root, filepath.FromSlash(path))
+	if _, err := gitOutput(ctx, c.root, nil, "cat-file", "-e", c.tree+":"+path); err != nil {
+		if err := os.Remove(full); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("删除 %s 失败: %w", path, err)
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		return fmt.Errorf("恢复 %s 失败: %w", path, err)
+	}
+	content, err := gitOutput(ctx, c.root, nil, "cat-file", "blob", c.tree+":"+path)
+	if err != nil {
+		return fmt.Errorf("恢复 %s 失败: %w", path, err)
+	}
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(full); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := os.WriteFile(full, []byte(content), mode); err != nil {
+		return fmt.Errorf("恢复 %s 失败: %w", path, err)
+	}
+	return nil
+}
+
+// literalPathspecs 让 git 按字面解释路径，文件名中的 * 等字符不作为通配符
+var literalPathspecs = []string{"GIT_LITERAL_PATHSPECS=1"}
+
+// writeWorktreeTree 用临时索引暂存工作区中所有未忽略的文件并写成 tree 对象，返回其 ID。
+// 临时索引从仓库的索引复制而来，未修改的文件可以直接复用已有的状态信息
+func writeWorktreeTree(ctx context.Context, root string) (string, error) {
+	index, err := os.CreateTemp("", "polyagent-index-*")
+	if err != nil {
+		return "", fmt.Errorf("创建临时索引失败: %w", err)
+	}
+	indexPath := index.Name()
+	defer os.Remove(indexPath)
+
+	if gitIndex, err := gitOutput(ctx, root, nil, "rev-parse", "--git-path", "index"); err == nil {
+		path := strings.TrimSpace(gitIndex)
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(root, path)
+		}
+		if src, err := os.Open(path); err == nil {
+			_, err = io.Copy(index, src)
+			src.Close()
+			if err != nil {
+				index.Truncate(0)
+			}
+		}
+	}
+	index.Close()
+	if info, err := os.Stat(indexPath); err == nil && info.Size() == 0 {
+		// git 不接受空的索引文件
+		os.Remove(indexPath)
+	}
+
+	env := []string{"GIT_INDEX_FILE=" + indexPath}
+	if _, err := gitOutput(ctx, root, env, "add", "-A"); err != nil {
+		return "", err
+	}
+	tree, err := gitOutput(ctx, root, env, "write-tree")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(tree), nil
+}
+
+// gitOutput 在 dir 中执行 git 命令并返回标准输出，失败时错误中包含标准错误的内容
+func gitOutput(ctx context.Context, dir string, env []string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+	cmd.Env = append(os.Environ(), env...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return string(out), nil
+}
+
+// parseFileChanges 合并 git diff --numstat -z 和 --name-status -z 的输出
+func parseFileChanges(numstat, nameStatus string) []FileChange {
+	status := make(map[string]string)
+	fields := strings.Split(strings.TrimSuffix(nameStatus, "\x00"), "\x00")
+	for i := 0; i+1 < len(fields); i += 2 {
+		status[fields[i+1]] = fields[i]
+	}
+
+	var changes []FileChange
+	for _, record := range strings.Split(strings.TrimSuffix(numstat, "\x00"), "\x00") {
+		parts := strings.SplitN(record, "\t", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		change := FileChange{Path: parts[2], Status: status[parts[2]]}
+		if parts[0] == "-" {
+			change.Binary = true
+		} else {
+			change.Added, _ = strconv.Atoi(parts[0])
+			change.Deleted, _ = strconv.Atoi(parts[1])
+		}
+		if change.Status == "" {
+			change.Status = "M"
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
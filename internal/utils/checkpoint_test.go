@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// initGitRepo 创建包含一次提交的 git 仓库，没有 git 时跳过测试
+func initGitRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	writeFiles(t, dir, files)
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "-A"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "init"},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	return dir
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCheckpointChanges(t *testing.T) {
+	dir := initGitRepo(t, map[string]string{
+		"main.go":    "package main\n\nfunc main() {}\n",
+		"README.md":  "# demo\n",
+		".gitignore": "*.log\n",
+	})
+	// 检查点之前已有的未提交修改不计入本轮
+	writeFiles(t, dir, map[string]string{"README.md": "# demo\n\nwip\n", "notes.txt": "draft\n"})
+
+	checkpoint, err := CreateCheckpoint(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFiles(t, dir, map[string]string{
+		"main.go":    "package main\n\nimport \"fmt\"\n\nfunc main() { fmt.Println() }\n",
+		"pkg/new.go": "package pkg\n",
+		"debug.log":  "ignored\n",
+	})
+	if err := os.Remove(filepath.Join(dir, "notes.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, current, err := checkpoint.Changes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, c := range changes {
+		got = append(got, c.Status+" "+c.Path)
+	}
+	if strings.Join(got, ",") != "M main.go,D notes.txt,A pkg/new.go" {
+		t.Fatalf("changes = %v", got)
+	}
+	if changes[0].Added != 3 || changes[0].Deleted != 1 {
+		t.Errorf("main.go +%d -%d", changes[0].Added, changes[0].Deleted)
+	}
+
+	diff, err := checkpoint.Diff(current, "main.go")
+	if err != nil || !strings.Contains(diff, "+import \"fmt\"") {
+		t.Errorf("diff = %q, %v", diff, err)
+	}
+
+	for _, c := range changes {
+		if err := checkpoint.Restore(c.Path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if changes, _, err := checkpoint.Changes(); err != nil || len(changes) != 0 {
+		t.Errorf("changes after restoring = %v, %v", changes, err)
+	}
+	// 用户的暂存区不受影响
+	if out, err := exec.Command("git", "-C", dir, "diff", "--cached", "--name-only").Output(); err != nil || len(out) != 0 {
+		t.Errorf("index was modified: %q, %v", out, err)
+	}
+	if content, _ := os.ReadFile(filepath.Join(dir, "notes.txt")); string(content) != "draft\n" {
+		t.Errorf("deleted untracked file not restored: %q", content)
+	}
+}
+
+func TestCheckpointOutsideRepo(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	if _, err := CreateCheckpoint(t.TempDir()); !errors.Is(err, ErrNotGitRepo) {
+		t.Errorf("CreateCheckpoint outside a repository: %v", err)
+	}
+}
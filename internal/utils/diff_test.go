@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiffIdentical(t *testing.T) {
+	if diff := UnifiedDiff("a", "b", "same\n", "same\n", 3); diff != "" {
+		t.Errorf("UnifiedDiff of identical text = %q, want empty", diff)
+	}
+}
+
+func TestUnifiedDiffChangedLine(t *testing.T) {
+	oldText := "one\ntwo\nthree\nfour\n"
+	newText := "one\nTWO\nthree\nfour\n"
+
+	diff := UnifiedDiff("old", "new", oldText, newText, 1)
+	want := "--- old\n+++ new\n@@ -1,3 +1,3 @@\n one\n-two\n+TWO\n three\n"
+	if diff != want {
+		t.Errorf("UnifiedDiff =\n%s\nwant\n%s", diff, want)
+	}
+}
+
+func TestUnifiedDiffSeparateHunks(t *testing.T) {
+	var oldLines, newLines []string
+	for i := 0; i < 20; i++ {
+		line := string(rune('a' + i))
+		oldLines = append(oldLines, line)
+		if i == 2 || i == 17 {
+			line = strings.ToUpper(line)
+		}
+		newLines = append(newLines, line)
+	}
+
+	diff := UnifiedDiff("old", "new", strings.Join(oldLines, "\n")+"\n", strings.Join(newLines, "\n")+"\n", 2)
+	if got := strings.Count(diff, "@@ -"); got != 2 {
+		t.Fatalf("expected 2 hunks, got %d:\n%s", got, diff)
+	}
+	if !strings.Contains(diff, "@@ -16,5 +16,5 @@") {
+		t.Errorf("second hunk header missing:\n%s", diff)
+	}
+}
+
+func TestUnifiedDiffInsertAndNoNewline(t *testing.T) {
+	diff := UnifiedDiff("old", "new", "a\nb", "a\nx\nb", 3)
+	want := "--- old\n+++ new\n@@ -1,2 +1,3 @@\n a\n+x\n b\n\\ No newline at end of file\n"
+	if diff != want {
+		t.Errorf("UnifiedDiff =\n%s\nwant\n%s", diff, want)
+	}
+}
@@ -6,15 +6,18 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"sync"
 	"time"
+	"unicode/utf8"
 )
 
 // EditOperation 原子编辑操作
 type EditOperation struct {
 	Type      string // "insert", "delete"
 	FilePath  string // 文件路径
-	Offset    int    // 字符偏移量
-	Length    int    // 删除长度（delete时）
+	Offset    int    // 字符（rune）偏移量
+	Length    int    // 删除的字符数（delete时）
 	Content   string // 插入内容（insert时）
 	Timestamp time.Time
 }
@@ -35,20 +38,27 @@ type TextBuffer struct {
 type FileState struct {
 	Path   string
 	Buffer *TextBuffer
-	Hash   string
+	Hash   string // 磁盘上内容（加载或最近一次保存时）的哈希
+	Dirty  bool   // 缓冲区被编辑过，可能与磁盘不一致
 }
 
-// Editor 编辑系统
+// Editor 编辑系统，可能同时被界面和工具（FileEngine 写入后的同步）调用，由 mu 保护
 type Editor struct {
+	mu             sync.Mutex
 	currentSession *SessionMarker
 	sessionEdits   []EditOperation
 	fileStates     map[string]*FileState
+	// baseHashes 本次会话中每个文件第一次编辑前的内容哈希，异常退出后据此判断磁盘上是否已包含修改
+	baseHashes map[string]string
+	// recovery 上次异常退出时留下的编辑记录，等待用户应用或放弃
+	recovery *editRecovery
 }
 
 // NewEditor 创建新的编辑系统
 func NewEditor() *Editor {
 	return &Editor{
 		fileStates: make(map[string]*FileState),
+		baseHashes: make(map[string]string),
 	}
 }
 
@@ -79,14 +89,16 @@ func (e *Editor) saveSessionEdits() error {
 	}
 
 	// 准备保存的数据
-	sessionData := struct {
-		SessionID string          `json:"session_id"`
-		Timestamp time.Time       `json:"timestamp"`
-		Edits     []EditOperation `json:"edits"`
-	}{
+	sessionData := sessionEditsFile{
 		SessionID: e.currentSession.ID,
 		Timestamp: e.currentSession.Timestamp,
 		Edits:     e.sessionEdits,
+		Files:     make(map[string]editedFileHashes),
+	}
+	for path, base := range e.baseHashes {
+		if state, ok := e.fileStates[path]; ok {
+			sessionData.Files[path] = editedFileHashes{Base: base, Current: e.calculateHash(state.Buffer.Content)}
+		}
 	}
 
 	data, err := json.MarshalIndent(sessionData, "", "  ")
@@ -101,42 +113,43 @@ func (e *Editor) saveSessionEdits() error {
 	return nil
 }
 
-// loadSessionEdits 从磁盘加载会话编辑历史
-func (e *Editor) loadSessionEdits() error {
+// sessionEditsFile 磁盘上的会话编辑历史
+type sessionEditsFile struct {
+	SessionID string          `json:"session_id"`
+	Timestamp time.Time       `json:"timestamp"`
+	Edits     []EditOperation `json:"edits"`
+	// Files 被编辑文件在第一次编辑前和最近一次编辑后的内容哈希，旧版本的记录中没有
+	Files map[string]editedFileHashes `json:"files,omitempty"`
+}
+
+// editedFileHashes 文件编辑前后的内容哈希
+type editedFileHashes struct {
+	Base    string `json:"base"`
+	Current string `json:"current"`
+}
+
+// loadSessionEdits 从磁盘加载会话编辑历史，没有记录时返回 nil
+func loadSessionEdits() (*sessionEditsFile, error) {
 	editsPath, err := getSessionEditsPath()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// 检查文件是否存在
 	if _, err := os.Stat(editsPath); os.IsNotExist(err) {
-		return nil // 文件不存在，无需加载
+		return nil, nil // 文件不存在，无需加载
 	}
 
 	data, err := os.ReadFile(editsPath)
 	if err != nil {
-		return fmt.Errorf("读取编辑历史文件失败: %w", err)
-	}
-
-	var sessionData struct {
-		SessionID string          `json:"session_id"`
-		Timestamp time.Time       `json:"timestamp"`
-		Edits     []EditOperation `json:"edits"`
+		return nil, fmt.Errorf("读取编辑历史文件失败: %w", err)
 	}
 
+	var sessionData sessionEditsFile
 	if err := json.Unmarshal(data, &sessionData); err != nil {
-		return fmt.Errorf("解析编辑历史失败: %w", err)
+		return nil, fmt.Errorf("解析编辑历史失败: %w", err)
 	}
-
-	// 恢复会话和编辑历史
-	e.currentSession = &SessionMarker{
-		ID:        sessionData.SessionID,
-		Timestamp: sessionData.Timestamp,
-		FileHashes: make(map[string]string),
-	}
-	e.sessionEdits = sessionData.Edits
-
-	return nil
+	return &sessionData, nil
 }
 
 // clearSessionEdits 清除磁盘上的会话编辑历史
@@ -155,19 +168,17 @@ func clearSessionEdits() error {
 
 // StartSession 开始新会话
 func (e *Editor) StartSession() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
 	if e.currentSession != nil {
 		return fmt.Errorf("已有活跃会话，请先结束当前会话")
 	}
 
-	// 尝试加载之前的编辑历史（如果存在未正常结束的会话）
-	if err := e.loadSessionEdits(); err != nil {
+	// 上次会话未正常结束时留下了编辑记录：对照文件的当前内容检查能否重放，由用户决定应用或放弃
+	if saved, err := loadSessionEdits(); err != nil {
 		fmt.Printf("警告: 加载编辑历史失败: %v\n", err)
-	}
-
-	// 如果加载到了编辑历史，恢复会话
-	if e.currentSession != nil {
-		fmt.Printf("恢复之前的编辑会话: %s\n", e.currentSession.ID)
-		return nil
+	} else if saved != nil && len(saved.Edits) > 0 {
+		e.recovery = e.newEditRecovery(saved)
 	}
 
 	// 获取当前目录所有代码文件
@@ -203,12 +214,22 @@ func (e *Editor) StartSession() error {
 		FileHashes: fileHashes,
 	}
 	e.sessionEdits = nil
+	e.baseHashes = make(map[string]string)
 
 	return nil
 }
 
+// Checkpoint 将当前会话的编辑历史写入磁盘，异常退出后下次启动时恢复
+func (e *Editor) Checkpoint() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.saveSessionEdits()
+}
+
 // EndSession 结束当前会话
 func (e *Editor) EndSession() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
 	// 清除磁盘上的编辑历史
 	if err := clearSessionEdits(); err != nil {
 		fmt.Printf("警告: 清除编辑历史失败: %v\n", err)
@@ -216,11 +237,18 @@ func (e *Editor) EndSession() {
 
 	e.currentSession = nil
 	e.sessionEdits = nil
+	e.baseHashes = make(map[string]string)
 	// 保留 fileStates 供下次会话使用
 }
 
-// InsertText 插入文本
+// InsertText 在字符偏移量 offset 处插入文本
 func (e *Editor) InsertText(filePath string, offset int, content string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.insertText(filePath, offset, content)
+}
+
+func (e *Editor) insertText(filePath string, offset int, content string) error {
 	state, ok := e.fileStates[filePath]
 	if !ok {
 		// 如果文件不在状态中，先加载
@@ -230,14 +258,23 @@ func (e *Editor) InsertText(filePath string, offset int, content string) error {
 		state = e.fileStates[filePath]
 	}
 
-	// 验证偏移量
-	if offset < 0 || offset > len(state.Buffer.Content) {
-		return fmt.Errorf("偏移量 %d 超出范围 (0-%d)", offset, len(state.Buffer.Content))
+	// 验证偏移量并转换为字节位置
+	oldContent := state.Buffer.Content
+	if err := checkEditableText(filePath, oldContent); err != nil {
+		return err
+	}
+	if !utf8.ValidString(content) {
+		return fmt.Errorf("插入的内容不是有效的 UTF-8 文本")
+	}
+	at, err := RuneToByteOffset(oldContent, offset)
+	if err != nil {
+		return err
 	}
 
 	// 执行插入
-	oldContent := state.Buffer.Content
-	state.Buffer.Content = oldContent[:offset] + content + oldContent[offset:]
+	e.noteBase(filePath, oldContent)
+	state.Buffer.Content = oldContent[:at] + content + oldContent[at:]
+	state.Dirty = true
 
 	// 记录操作
 	e.sessionEdits = append(e.sessionEdits, EditOperation{
@@ -249,32 +286,45 @@ func (e *Editor) InsertText(filePath string, offset int, content string) error {
 		Timestamp: time.Now(),
 	})
 
-	// 自动保存编辑历史到磁盘
-	if err := e.saveSessionEdits(); err != nil {
-		// 记录错误但不中断操作
-		fmt.Printf("警告: 保存编辑历史失败: %v\n", err)
-	}
+	// 自动保存编辑历史到磁盘；失败不中断操作，下次保存时重写完整历史
+	// 编辑器运行在界面中（包括 FileEngine 写入后的同步），不能向标准输出打印警告
+	e.saveSessionEdits()
 
 	return nil
 }
 
-// DeleteText 删除文本
+// DeleteText 删除从字符偏移量 offset 开始的 length 个字符
 func (e *Editor) DeleteText(filePath string, offset int, length int) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.deleteText(filePath, offset, length)
+}
+
+func (e *Editor) deleteText(filePath string, offset int, length int) error {
 	state, ok := e.fileStates[filePath]
 	if !ok {
 		return fmt.Errorf("文件未加载: %s", filePath)
 	}
 
-	// 验证偏移量和长度
-	if offset < 0 || offset+length > len(state.Buffer.Content) {
+	// 验证偏移量和长度（按减法比较，避免 offset+length 溢出）
+	content := state.Buffer.Content
+	if err := checkEditableText(filePath, content); err != nil {
+		return err
+	}
+	if offset < 0 || length < 0 || offset > utf8.RuneCountInString(content)-length {
 		return fmt.Errorf("删除范围超出文件边界")
 	}
+	start, _ := RuneToByteOffset(content, offset)
+	end, _ := RuneToByteOffset(content[start:], length)
+	end += start
 
 	// 获取被删除的内容
-	deletedContent := state.Buffer.Content[offset : offset+length]
+	deletedContent := content[start:end]
 
 	// 执行删除
-	state.Buffer.Content = state.Buffer.Content[:offset] + state.Buffer.Content[offset+length:]
+	e.noteBase(filePath, content)
+	state.Buffer.Content = content[:start] + content[end:]
+	state.Dirty = true
 
 	// 记录操作
 	e.sessionEdits = append(e.sessionEdits, EditOperation{
@@ -286,23 +336,31 @@ func (e *Editor) DeleteText(filePath string, offset int, length int) error {
 		Timestamp: time.Now(),
 	})
 
-	// 自动保存编辑历史到磁盘
-	if err := e.saveSessionEdits(); err != nil {
-		// 记录错误但不中断操作
-		fmt.Printf("警告: 保存编辑历史失败: %v\n", err)
-	}
+	// 自动保存编辑历史到磁盘；失败不中断操作，下次保存时重写完整历史
+	// 编辑器运行在界面中（包括 FileEngine 写入后的同步），不能向标准输出打印警告
+	e.saveSessionEdits()
 
 	return nil
 }
 
 // ReplaceText 替换文本（插入+删除的组合）
 func (e *Editor) ReplaceText(filePath string, offset int, length int, newContent string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.replaceText(filePath, offset, length, newContent)
+}
+
+func (e *Editor) replaceText(filePath string, offset int, length int, newContent string) error {
+	// 插入内容无效时不能先删除，否则会留下一半的修改
+	if !utf8.ValidString(newContent) {
+		return fmt.Errorf("插入的内容不是有效的 UTF-8 文本")
+	}
 	// 先删除旧内容
-	if err := e.DeleteText(filePath, offset, length); err != nil {
+	if err := e.deleteText(filePath, offset, length); err != nil {
 		return err
 	}
 	// 再插入新内容
-	if err := e.InsertText(filePath, offset, newContent); err != nil {
+	if err := e.insertText(filePath, offset, newContent); err != nil {
 		return err
 	}
 	return nil
@@ -310,6 +368,8 @@ func (e *Editor) ReplaceText(filePath string, offset int, length int, newContent
 
 // RollbackSession 回退当前会话的所有修改
 func (e *Editor) RollbackSession() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
 	if e.currentSession == nil {
 		return fmt.Errorf("没有活跃会话")
 	}
@@ -337,27 +397,78 @@ func (e *Editor) RollbackSession() error {
 
 	// 清空编辑记录
 	e.sessionEdits = nil
+	e.baseHashes = make(map[string]string)
 
 	return nil
 }
 
-// SaveToDisk 将内存中的修改保存到磁盘
-func (e *Editor) SaveToDisk() error {
-	for _, state := range e.fileStates {
+// SaveToDisk 将有修改的文件写入磁盘，返回写入的文件（按路径排序）。
+// 未修改或修改后又改回原样的文件不会被写入，避免改动修改时间、触发构建监视器；
+// 写入失败时返回失败前已写入的文件
+func (e *Editor) SaveToDisk() ([]string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	paths := make([]string, 0, len(e.fileStates))
+	for path, state := range e.fileStates {
+		if state.Dirty {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	var saved []string
+	for _, path := range paths {
+		state := e.fileStates[path]
+		hash := e.calculateHash(state.Buffer.Content)
+		if hash == state.Hash {
+			state.Dirty = false
+			continue
+		}
 		if err := os.WriteFile(state.Path, []byte(state.Buffer.Content), 0644); err != nil {
-			return fmt.Errorf("保存文件 %s 失败: %w", state.Path, err)
+			return saved, fmt.Errorf("保存文件 %s 失败: %w", state.Path, err)
 		}
+		state.Hash = hash
+		state.Dirty = false
+		saved = append(saved, state.Path)
 	}
-	return nil
+	return saved, nil
 }
 
-// GetCurrentEdits 获取当前会话的编辑记录
+// DirtyFiles 返回有未保存修改的文件（按路径排序）
+func (e *Editor) DirtyFiles() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var paths []string
+	for path, state := range e.fileStates {
+		if state.Dirty && e.calculateHash(state.Buffer.Content) != state.Hash {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// MarkSaved 记录文件的缓冲区内容已由其他途径写入磁盘，SaveToDisk 不再写入
+func (e *Editor) MarkSaved(filePath string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if state, ok := e.fileStates[filePath]; ok {
+		state.Hash = e.calculateHash(state.Buffer.Content)
+		state.Dirty = false
+	}
+}
+
+// GetCurrentEdits 获取当前会话的编辑记录（副本）
 func (e *Editor) GetCurrentEdits() []EditOperation {
-	return e.sessionEdits
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]EditOperation(nil), e.sessionEdits...)
 }
 
 // GetFileContent 获取文件当前内容
 func (e *Editor) GetFileContent(filePath string) (string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
 	state, ok := e.fileStates[filePath]
 	if !ok {
 		return "", fmt.Errorf("文件未加载: %s", filePath)
@@ -367,6 +478,8 @@ func (e *Editor) GetFileContent(filePath string) (string, error) {
 
 // LoadFile 加载文件到编辑器
 func (e *Editor) LoadFile(filePath string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
 	return e.loadFile(filePath)
 }
 
@@ -411,6 +524,24 @@ func (e *Editor) loadFile(filePath string) error {
 	return nil
 }
 
+// checkEditableText 偏移量按字符计算，无效的 UTF-8 内容（如二进制文件）无法可靠地定位
+func checkEditableText(filePath, content string) error {
+	if !utf8.ValidString(content) {
+		return fmt.Errorf("文件 %s 不是有效的 UTF-8 文本，无法按字符编辑", filePath)
+	}
+	return nil
+}
+
+// noteBase 记录文件在本次会话中第一次编辑前的内容哈希
+func (e *Editor) noteBase(filePath, content string) {
+	if e.baseHashes == nil {
+		e.baseHashes = make(map[string]string)
+	}
+	if _, ok := e.baseHashes[filePath]; !ok {
+		e.baseHashes[filePath] = e.calculateHash(content)
+	}
+}
+
 func (e *Editor) calculateHash(content string) string {
 	h := sha256.New()
 	h.Write([]byte(content))
@@ -421,10 +552,10 @@ func (e *Editor) applyInverseOperation(op EditOperation) error {
 	switch op.Type {
 	case "insert":
 		// 插入的反向操作是删除
-		return e.DeleteText(op.FilePath, op.Offset, len(op.Content))
+		return e.deleteText(op.FilePath, op.Offset, utf8.RuneCountInString(op.Content))
 	case "delete":
 		// 删除的反向操作是插入
-		return e.InsertText(op.FilePath, op.Offset, op.Content)
+		return e.insertText(op.FilePath, op.Offset, op.Content)
 	default:
 		return fmt.Errorf("未知操作类型: %s", op.Type)
 	}
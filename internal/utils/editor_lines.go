@@ -0,0 +1,211 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// LineAnchor 按行编辑前的校验条件：第 Line 行应包含 Contains。
+// 模型记忆中的行号可能已经过期，校验失败时拒绝修改，避免改错位置
+type LineAnchor struct {
+	Line     int
+	Contains string
+}
+
+// InsertLines 在第 line 行之前插入文本（行号从 1 开始），line 为总行数加一时追加到文件末尾。
+// 插入的文本按整行处理：缺少结尾换行时自动补上，换行符与文件保持一致
+func (e *Editor) InsertLines(filePath string, line int, text string, anchors ...LineAnchor) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	content, err := e.lineEditableContent(filePath, anchors)
+	if err != nil {
+		return err
+	}
+	lines := splitLines(content)
+	if line < 1 || line > len(lines)+1 {
+		return fmt.Errorf("行号 %d 超出范围，文件共 %d 行（追加到末尾请使用 %d）", line, len(lines), len(lines)+1)
+	}
+
+	eol := lineEnding(content)
+	text = normalizeLines(text, eol)
+	offset := runeCount(lines[:line-1])
+	if line == len(lines)+1 && content != "" && !strings.HasSuffix(content, "\n") {
+		// 文件没有结尾换行：在原最后一行后换行，新的最后一行同样不加换行
+		text = eol + strings.TrimSuffix(text, eol)
+	}
+	return e.insertText(filePath, offset, text)
+}
+
+// DeleteLines 删除第 start 到 end 行（含两端）
+func (e *Editor) DeleteLines(filePath string, start, end int, anchors ...LineAnchor) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.deleteLines(filePath, start, end, anchors)
+}
+
+func (e *Editor) deleteLines(filePath string, start, end int, anchors []LineAnchor) error {
+	content, err := e.lineEditableContent(filePath, anchors)
+	if err != nil {
+		return err
+	}
+	lines := splitLines(content)
+	offset, length, err := lineRange(lines, start, end)
+	if err != nil {
+		return err
+	}
+	if end == len(lines) && start > 1 && !strings.HasSuffix(content, "\n") {
+		// 删除没有结尾换行的最后几行时连同前一行的换行一起删除，保持文件原有的结尾
+		eol := utf8.RuneCountInString(lines[start-2]) - utf8.RuneCountInString(strings.TrimRight(lines[start-2], "\r\n"))
+		offset -= eol
+		length += eol
+	}
+	return e.deleteText(filePath, offset, length)
+}
+
+// ReplaceLines 将第 start 到 end 行（含两端）替换为 text，text 为空时等同于删除这些行
+func (e *Editor) ReplaceLines(filePath string, start, end int, text string, anchors ...LineAnchor) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if text == "" {
+		return e.deleteLines(filePath, start, end, anchors)
+	}
+	content, err := e.lineEditableContent(filePath, anchors)
+	if err != nil {
+		return err
+	}
+	lines := splitLines(content)
+	offset, length, err := lineRange(lines, start, end)
+	if err != nil {
+		return err
+	}
+
+	eol := lineEnding(content)
+	text = normalizeLines(text, eol)
+	if end == len(lines) && !strings.HasSuffix(content, "\n") {
+		text = strings.TrimSuffix(text, eol)
+	}
+	return e.replaceText(filePath, offset, length, text)
+}
+
+// VerifyAnchors 检查每个校验条件对应的行是否包含预期的文本
+func (e *Editor) VerifyAnchors(filePath string, anchors ...LineAnchor) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, err := e.lineEditableContent(filePath, anchors)
+	return err
+}
+
+// UndoTo 撤销第 n 个之后的编辑操作，用于放弃未能写入磁盘的修改
+func (e *Editor) UndoTo(n int) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if n < 0 {
+		n = 0
+	}
+	for i := len(e.sessionEdits) - 1; i >= n; i-- {
+		op := e.sessionEdits[i]
+		if err := e.applyInverseOperation(op); err != nil {
+			return fmt.Errorf("撤销操作失败 (操作 %d): %w", i, err)
+		}
+		// 反向操作也会被记录，一并去掉
+		e.sessionEdits = e.sessionEdits[:i]
+	}
+	// 编辑器运行在界面中，不能向标准输出打印警告，保存失败交给调用方处理
+	if err := e.saveSessionEdits(); err != nil {
+		return fmt.Errorf("保存编辑历史失败: %w", err)
+	}
+	return nil
+}
+
+// lineEditableContent 返回文件当前内容（未加载时先加载），并检查校验条件
+func (e *Editor) lineEditableContent(filePath string, anchors []LineAnchor) (string, error) {
+	if _, ok := e.fileStates[filePath]; !ok {
+		if err := e.loadFile(filePath); err != nil {
+			return "", err
+		}
+	}
+	content := e.fileStates[filePath].Buffer.Content
+	if err := checkEditableText(filePath, content); err != nil {
+		return "", err
+	}
+
+	lines := splitLines(content)
+	for _, anchor := range anchors {
+		if anchor.Line < 1 || anchor.Line > len(lines) {
+			return "", fmt.Errorf("校验失败: 第 %d 行不存在，文件共 %d 行", anchor.Line, len(lines))
+		}
+		line := strings.TrimRight(lines[anchor.Line-1], "\r\n")
+		if strings.Contains(line, anchor.Contains) {
+			continue
+		}
+		if found := nearestLineContaining(lines, anchor.Line, anchor.Contains); found > 0 {
+			return "", fmt.Errorf("校验失败: 第 %d 行不包含 %q（当前内容: %q），该文本位于第 %d 行", anchor.Line, anchor.Contains, line, found)
+		}
+		return "", fmt.Errorf("校验失败: 第 %d 行不包含 %q（当前内容: %q），文件中也找不到该文本", anchor.Line, anchor.Contains, line)
+	}
+	return content, nil
+}
+
+// LineCount 返回文本的行数，结尾的换行不单独算作一行
+func LineCount(content string) int {
+	return len(splitLines(content))
+}
+
+// lineRange 返回第 start 到 end 行（含最后一行的换行符）的字符偏移量和长度
+func lineRange(lines []string, start, end int) (int, int, error) {
+	if start < 1 || end < start || end > len(lines) {
+		return 0, 0, fmt.Errorf("行范围 %d-%d 无效，文件共 %d 行", start, end, len(lines))
+	}
+	return runeCount(lines[:start-1]), runeCount(lines[start-1 : end]), nil
+}
+
+func runeCount(lines []string) int {
+	n := 0
+	for _, line := range lines {
+		n += utf8.RuneCountInString(line)
+	}
+	return n
+}
+
+// lineEnding 返回文件主要使用的换行符
+func lineEnding(content string) string {
+	crlf := strings.Count(content, "\r\n")
+	if crlf > strings.Count(content, "\n")-crlf {
+		return "\r\n"
+	}
+	return "\n"
+}
+
+// normalizeLines 将文本的换行符转换为 eol，并保证以换行结尾
+func normalizeLines(text, eol string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	if eol != "\n" {
+		text = strings.ReplaceAll(text, "\n", eol)
+	}
+	return text
+}
+
+// nearestLineContaining 返回离 line 最近的包含 text 的行号，找不到时返回 0
+func nearestLineContaining(lines []string, line int, text string) int {
+	best := 0
+	for i, l := range lines {
+		if !strings.Contains(l, text) {
+			continue
+		}
+		if best == 0 || abs(i+1-line) < abs(best-line) {
+			best = i + 1
+		}
+	}
+	return best
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
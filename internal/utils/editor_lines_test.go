@@ -0,0 +1,165 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newLineEditor(t *testing.T, content string) (*Editor, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "f.go")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	e := NewEditor()
+	if err := e.LoadFile(path); err != nil {
+		t.Fatal(err)
+	}
+	return e, path
+}
+
+func TestLineEdits(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		edit    func(e *Editor, path string) error
+		want    string
+	}{
+		{
+			name:    "insert before line",
+			content: "a\nb\n",
+			edit:    func(e *Editor, p string) error { return e.InsertLines(p, 2, "x") },
+			want:    "a\nx\nb\n",
+		},
+		{
+			name:    "append without trailing newline",
+			content: "a\nb",
+			edit:    func(e *Editor, p string) error { return e.InsertLines(p, 3, "x\n") },
+			want:    "a\nb\nx",
+		},
+		{
+			name:    "insert into empty file",
+			content: "",
+			edit:    func(e *Editor, p string) error { return e.InsertLines(p, 1, "x") },
+			want:    "x\n",
+		},
+		{
+			name:    "delete middle lines",
+			content: "a\nb\nc\nd\n",
+			edit:    func(e *Editor, p string) error { return e.DeleteLines(p, 2, 3) },
+			want:    "a\nd\n",
+		},
+		{
+			name:    "delete last line without trailing newline",
+			content: "a\nb\nc",
+			edit:    func(e *Editor, p string) error { return e.DeleteLines(p, 3, 3) },
+			want:    "a\nb",
+		},
+		{
+			name:    "replace with more lines",
+			content: "你好\n世界\n🙂\n",
+			edit:    func(e *Editor, p string) error { return e.ReplaceLines(p, 2, 2, "地球\n月亮") },
+			want:    "你好\n地球\n月亮\n🙂\n",
+		},
+		{
+			name:    "replace keeps CRLF",
+			content: "a\r\nb\r\nc\r\n",
+			edit:    func(e *Editor, p string) error { return e.ReplaceLines(p, 2, 2, "x\ny\n") },
+			want:    "a\r\nx\r\ny\r\nc\r\n",
+		},
+		{
+			name:    "replace last line without trailing newline",
+			content: "a\nb",
+			edit:    func(e *Editor, p string) error { return e.ReplaceLines(p, 2, 2, "x") },
+			want:    "a\nx",
+		},
+		{
+			name:    "replace with empty text deletes",
+			content: "a\nb\nc\n",
+			edit:    func(e *Editor, p string) error { return e.ReplaceLines(p, 1, 2, "") },
+			want:    "c\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, path := newLineEditor(t, tt.content)
+			if err := tt.edit(e, path); err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := e.GetFileContent(path); got != tt.want {
+				t.Errorf("content = %q, want %q", got, tt.want)
+			}
+
+			// 编辑都记录在操作日志中，可以完整撤销
+			if err := e.UndoTo(0); err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := e.GetFileContent(path); got != tt.content {
+				t.Errorf("after undo = %q, want %q", got, tt.content)
+			}
+			if len(e.GetCurrentEdits()) != 0 {
+				t.Errorf("undo left %d edits", len(e.GetCurrentEdits()))
+			}
+		})
+	}
+}
+
+func TestLineEditAnchors(t *testing.T) {
+	e, path := newLineEditor(t, "package main\n\nfunc main() {\n\tprintln(1)\n}\n")
+
+	if err := e.ReplaceLines(path, 4, 4, "\tprintln(2)", LineAnchor{Line: 4, Contains: "println(1)"}); err != nil {
+		t.Fatalf("matching anchor rejected: %v", err)
+	}
+
+	// 行号过期时拒绝修改，并指出文本实际所在的行
+	err := e.DeleteLines(path, 2, 2, LineAnchor{Line: 2, Contains: "func main"})
+	if err == nil || !strings.Contains(err.Error(), "第 3 行") {
+		t.Fatalf("expected anchor mismatch pointing at line 3, got %v", err)
+	}
+	if err := e.InsertLines(path, 1, "x", LineAnchor{Line: 9, Contains: "x"}); err == nil {
+		t.Error("expected anchor beyond the end to fail")
+	}
+	if got, _ := e.GetFileContent(path); got != "package main\n\nfunc main() {\n\tprintln(2)\n}\n" {
+		t.Errorf("failed edits modified content: %q", got)
+	}
+	if len(e.GetCurrentEdits()) != 2 {
+		t.Errorf("expected only the replace to be recorded, got %d edits", len(e.GetCurrentEdits()))
+	}
+
+	for _, r := range [][2]int{{0, 1}, {2, 1}, {1, 6}} {
+		if err := e.DeleteLines(path, r[0], r[1]); err == nil {
+			t.Errorf("DeleteLines(%d, %d) should fail", r[0], r[1])
+		}
+	}
+}
+
+func TestUndoToReportsSaveFailure(t *testing.T) {
+	t.Setenv("POLYAGENT_CONFIG_HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+	if err := os.WriteFile("a.go", []byte("package a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	e := NewEditor()
+	if err := e.StartSession(); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.InsertLines("a.go", 1, "// a"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 配置目录是一个文件，编辑历史无法写入：撤销仍然完成，失败作为错误返回
+	blocked := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(blocked, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("POLYAGENT_CONFIG_HOME", blocked)
+	if err := e.UndoTo(0); err == nil || !strings.Contains(err.Error(), "保存编辑历史失败") {
+		t.Errorf("UndoTo error = %v", err)
+	}
+	if content, _ := e.GetFileContent("a.go"); content != "package a\n" {
+		t.Errorf("after undo = %q", content)
+	}
+}
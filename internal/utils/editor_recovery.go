@@ -0,0 +1,189 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// 异常退出后被编辑文件的恢复状态
+const (
+	// RecoveryPending 修改未保存，可以重放到文件的当前内容上
+	RecoveryPending = "pending"
+	// RecoverySaved 修改已经写入磁盘，无需恢复
+	RecoverySaved = "saved"
+	// RecoveryConflict 文件在此之后被修改过（或旧版本的记录无法确认状态），不能自动重放
+	RecoveryConflict = "conflict"
+	// RecoveryMissing 文件已不存在
+	RecoveryMissing = "missing"
+)
+
+// RecoveredFile 异常退出前编辑过的一个文件
+type RecoveredFile struct {
+	Path   string
+	Edits  int // 该文件的编辑操作数
+	Status string
+}
+
+// RecoveryReport 异常退出前的编辑会话摘要
+type RecoveryReport struct {
+	SessionID string
+	Timestamp time.Time
+	Files     []RecoveredFile
+}
+
+// Count 返回处于指定状态的文件数
+func (r *RecoveryReport) Count(status string) int {
+	n := 0
+	for _, file := range r.Files {
+		if file.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// editRecovery 等待用户决定的编辑记录
+type editRecovery struct {
+	report RecoveryReport
+	edits  map[string][]EditOperation
+	// diskHashes 生成摘要时文件在磁盘上的内容哈希，应用前确认文件没有再被修改
+	diskHashes map[string]string
+}
+
+// newEditRecovery 将编辑记录按文件分组，对照磁盘上的当前内容判断每个文件能否恢复
+func (e *Editor) newEditRecovery(saved *sessionEditsFile) *editRecovery {
+	r := &editRecovery{
+		report:     RecoveryReport{SessionID: saved.SessionID, Timestamp: saved.Timestamp},
+		edits:      make(map[string][]EditOperation),
+		diskHashes: make(map[string]string),
+	}
+	var order []string
+	for _, op := range saved.Edits {
+		if _, ok := r.edits[op.FilePath]; !ok {
+			order = append(order, op.FilePath)
+		}
+		r.edits[op.FilePath] = append(r.edits[op.FilePath], op)
+	}
+
+	for _, path := range order {
+		file := RecoveredFile{Path: path, Edits: len(r.edits[path]), Status: RecoveryConflict}
+		data, err := os.ReadFile(path)
+		switch {
+		case os.IsNotExist(err):
+			file.Status = RecoveryMissing
+		case err != nil:
+			// 无法读取时按冲突处理
+		default:
+			current := e.calculateHash(string(data))
+			r.diskHashes[path] = current
+			hashes, known := saved.Files[path]
+			switch {
+			case !known:
+				// 旧版本的记录没有哈希，无法区分修改是否已保存，重放可能重复应用
+			case current == hashes.Current:
+				file.Status = RecoverySaved
+			case current == hashes.Base:
+				if _, err := replayEdits(string(data), r.edits[path]); err == nil {
+					file.Status = RecoveryPending
+				}
+			}
+		}
+		r.report.Files = append(r.report.Files, file)
+	}
+	return r
+}
+
+// PendingRecovery 返回上次异常退出时留下的编辑记录摘要，没有时返回 nil
+func (e *Editor) PendingRecovery() *RecoveryReport {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.recovery == nil {
+		return nil
+	}
+	report := e.recovery.report
+	report.Files = append([]RecoveredFile(nil), report.Files...)
+	return &report
+}
+
+// ApplyRecovery 将可恢复的修改重放到编辑器的缓冲区并记录到当前会话（之后由 SaveToDisk 写入磁盘），
+// 返回恢复的文件；其他状态的文件保持不变
+func (e *Editor) ApplyRecovery() ([]string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.recovery == nil {
+		return nil, fmt.Errorf("没有需要恢复的编辑")
+	}
+
+	var applied []string
+	for _, file := range e.recovery.report.Files {
+		if file.Status != RecoveryPending {
+			continue
+		}
+		if err := e.loadFile(file.Path); err != nil {
+			return applied, fmt.Errorf("重新打开 %s 失败: %w", file.Path, err)
+		}
+		if e.calculateHash(e.fileStates[file.Path].Buffer.Content) != e.recovery.diskHashes[file.Path] {
+			return applied, fmt.Errorf("文件 %s 在检查之后又被修改，已停止恢复", file.Path)
+		}
+		for _, op := range e.recovery.edits[file.Path] {
+			var err error
+			switch op.Type {
+			case "insert":
+				err = e.insertText(op.FilePath, op.Offset, op.Content)
+			case "delete":
+				err = e.deleteText(op.FilePath, op.Offset, op.Length)
+			default:
+				err = fmt.Errorf("未知操作类型: %s", op.Type)
+			}
+			if err != nil {
+				return applied, fmt.Errorf("重放 %s 的修改失败: %w", file.Path, err)
+			}
+		}
+		applied = append(applied, file.Path)
+	}
+
+	e.recovery = nil
+	return applied, nil
+}
+
+// DiscardRecovery 放弃上次异常退出时留下的编辑记录
+func (e *Editor) DiscardRecovery() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.recovery = nil
+	if len(e.sessionEdits) == 0 {
+		return clearSessionEdits()
+	}
+	// 磁盘上的记录已被当前会话覆盖时保持不变
+	return e.saveSessionEdits()
+}
+
+// replayEdits 在 content 上依次重放编辑操作，删除的内容必须与记录一致
+func replayEdits(content string, ops []EditOperation) (string, error) {
+	if err := checkEditableText("", content); err != nil {
+		return "", err
+	}
+	for i, op := range ops {
+		start, err := RuneToByteOffset(content, op.Offset)
+		if err != nil {
+			return "", fmt.Errorf("操作 %d: %w", i, err)
+		}
+		switch op.Type {
+		case "insert":
+			content = content[:start] + op.Content + content[start:]
+		case "delete":
+			length, err := RuneToByteOffset(content[start:], op.Length)
+			if err != nil {
+				return "", fmt.Errorf("操作 %d: %w", i, err)
+			}
+			if content[start:start+length] != op.Content {
+				return "", fmt.Errorf("操作 %d: 要删除的内容与文件不符", i)
+			}
+			content = content[:start] + content[start+length:]
+		default:
+			return "", fmt.Errorf("操作 %d: 未知操作类型: %s", i, op.Type)
+		}
+	}
+	return content, nil
+}
@@ -0,0 +1,119 @@
+package utils
+
+import (
+	"os"
+	"testing"
+)
+
+// crashedSession 模拟一次异常退出：编辑后没有调用 EndSession
+func crashedSession(t *testing.T, files map[string]string, edit func(e *Editor)) {
+	t.Helper()
+	t.Setenv("POLYAGENT_CONFIG_HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	e := NewEditor()
+	if err := e.StartSession(); err != nil {
+		t.Fatal(err)
+	}
+	edit(e)
+}
+
+func TestEditRecovery(t *testing.T) {
+	crashedSession(t, map[string]string{
+		"unsaved.go":   "package a\n",
+		"saved.go":     "package b\n",
+		"changed.go":   "package c\n",
+		"removed.go":   "package d\n",
+		"untouched.go": "package e\n",
+	}, func(e *Editor) {
+		for _, path := range []string{"unsaved.go", "saved.go", "changed.go", "removed.go"} {
+			if err := e.InsertLines(path, 2, "// edited"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		content, _ := e.GetFileContent("saved.go")
+		os.WriteFile("saved.go", []byte(content), 0644)
+		os.WriteFile("changed.go", []byte("package c2\n"), 0644)
+		os.Remove("removed.go")
+	})
+
+	e := NewEditor()
+	if err := e.StartSession(); err != nil {
+		t.Fatal(err)
+	}
+	report := e.PendingRecovery()
+	if report == nil {
+		t.Fatal("expected a recovery report")
+	}
+	want := map[string]string{
+		"unsaved.go": RecoveryPending,
+		"saved.go":   RecoverySaved,
+		"changed.go": RecoveryConflict,
+		"removed.go": RecoveryMissing,
+	}
+	if len(report.Files) != len(want) {
+		t.Fatalf("files = %+v", report.Files)
+	}
+	for _, file := range report.Files {
+		if file.Status != want[file.Path] || file.Edits != 1 {
+			t.Errorf("%s: status %s with %d edits, want %s", file.Path, file.Status, file.Edits, want[file.Path])
+		}
+	}
+
+	applied, err := e.ApplyRecovery()
+	if err != nil || len(applied) != 1 || applied[0] != "unsaved.go" {
+		t.Fatalf("ApplyRecovery = %v, %v", applied, err)
+	}
+	if content, _ := e.GetFileContent("unsaved.go"); content != "package a\n// edited\n" {
+		t.Errorf("recovered buffer = %q", content)
+	}
+	if data, _ := os.ReadFile("changed.go"); string(data) != "package c2\n" {
+		t.Errorf("conflicting file was modified: %q", data)
+	}
+	if e.PendingRecovery() != nil {
+		t.Error("recovery should be cleared after applying")
+	}
+	// 重放的修改记录在当前会话中，可以回退
+	if err := e.RollbackSession(); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := e.GetFileContent("unsaved.go"); content != "package a\n" {
+		t.Errorf("after rollback = %q", content)
+	}
+}
+
+func TestDiscardEditRecovery(t *testing.T) {
+	crashedSession(t, map[string]string{"a.go": "package a\n"}, func(e *Editor) {
+		if err := e.InsertLines("a.go", 1, "// header"); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	// 文件在重新打开前被外部修改时拒绝应用
+	e := NewEditor()
+	if err := e.StartSession(); err != nil {
+		t.Fatal(err)
+	}
+	if report := e.PendingRecovery(); report == nil || report.Count(RecoveryPending) != 1 {
+		t.Fatalf("report = %+v", report)
+	}
+	os.WriteFile("a.go", []byte("package b\n"), 0644)
+	if _, err := e.ApplyRecovery(); err == nil {
+		t.Error("expected apply to fail after the file changed")
+	}
+
+	if err := e.DiscardRecovery(); err != nil {
+		t.Fatal(err)
+	}
+	e = NewEditor()
+	if err := e.StartSession(); err != nil {
+		t.Fatal(err)
+	}
+	if report := e.PendingRecovery(); report != nil {
+		t.Errorf("discarded recovery came back: %+v", report)
+	}
+}
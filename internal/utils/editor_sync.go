@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// EditorKey 返回编辑器记录文件使用的路径：工作目录内的文件使用相对路径（与 StartSession 一致），其他使用绝对路径
+func EditorKey(path string) string {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return absPath
+	}
+	rel, err := filepath.Rel(cwd, absPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return absPath
+	}
+	return rel
+}
+
+// SyncFile 文件被编辑器之外的途径写入后调用：以磁盘内容更新缓冲区，并把差异记录为编辑操作，
+// 使会话回退、哈希和未保存状态与磁盘一致。编辑器没有加载过的文件会在用到时从磁盘读取，这里忽略
+func (e *Editor) SyncFile(filePath string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	key := filePath
+	if _, ok := e.fileStates[key]; !ok {
+		key = EditorKey(filePath)
+		if _, ok := e.fileStates[key]; !ok {
+			return nil
+		}
+	}
+	state := e.fileStates[key]
+
+	data, err := os.ReadFile(key)
+	if os.IsNotExist(err) {
+		return e.forgetFile(key)
+	}
+	if err != nil {
+		return err
+	}
+
+	content := string(data)
+	var saveErr error
+	if content != state.Buffer.Content {
+		if utf8.ValidString(content) && utf8.ValidString(state.Buffer.Content) {
+			// 以删除+插入记录变化的部分，回退时可以还原
+			offset, removed, inserted := textChange(state.Buffer.Content, content)
+			if removed > 0 {
+				if err := e.deleteText(key, offset, removed); err != nil {
+					return err
+				}
+			}
+			if inserted != "" {
+				if err := e.insertText(key, offset, inserted); err != nil {
+					return err
+				}
+			}
+		} else {
+			// 无法按字符记录差异：之前的操作不再适用于该文件
+			saveErr = e.dropEdits(key)
+			state.Buffer.Content = content
+		}
+	}
+	state.Hash = e.calculateHash(content)
+	state.Dirty = false
+	return saveErr
+}
+
+// forgetFile 文件已被删除：移除缓冲区和相关的编辑操作，回退时不再处理该文件
+func (e *Editor) forgetFile(key string) error {
+	delete(e.fileStates, key)
+	return e.dropEdits(key)
+}
+
+// dropEdits 从操作日志中移除某个文件的全部操作，会话回退时不再检查该文件，返回保存编辑历史的错误
+func (e *Editor) dropEdits(key string) error {
+	kept := e.sessionEdits[:0]
+	for _, op := range e.sessionEdits {
+		if op.FilePath != key {
+			kept = append(kept, op)
+		}
+	}
+	e.sessionEdits = kept
+	delete(e.baseHashes, key)
+	if e.currentSession != nil {
+		delete(e.currentSession.FileHashes, key)
+	}
+	if err := e.saveSessionEdits(); err != nil {
+		return fmt.Errorf("保存编辑历史失败: %w", err)
+	}
+	return nil
+}
+
+// textChange 比较新旧文本，返回变化部分的字符偏移量、删除的字符数和插入的文本
+func textChange(old, new string) (int, int, string) {
+	a, b := []rune(old), []rune(new)
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	return prefix, len(a) - prefix - suffix, string(b[prefix : len(b)-suffix])
+}
@@ -0,0 +1,135 @@
+package utils
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSyncFileRecordsExternalWrites(t *testing.T) {
+	t.Setenv("POLYAGENT_CONFIG_HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+	if err := os.WriteFile("a.go", []byte("package a\n\nfunc A() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("b.go", []byte("package b\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	e := NewEditor()
+	if err := e.StartSession(); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.InsertLines("a.go", 1, "// 包注释"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.SaveToDisk(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 其他工具写入后，缓冲区与磁盘一致且不算未保存；路径写法不同也能对应
+	abs, _ := filepath.Abs("a.go")
+	os.WriteFile(abs, []byte("// 包注释\npackage a\n\nfunc A() { println(\"🙂\") }\n"), 0644)
+	if err := e.SyncFile(abs); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := e.GetFileContent("a.go"); content != "// 包注释\npackage a\n\nfunc A() { println(\"🙂\") }\n" {
+		t.Errorf("buffer = %q", content)
+	}
+	if dirty := e.DirtyFiles(); len(dirty) != 0 {
+		t.Errorf("synced file reported as dirty: %v", dirty)
+	}
+
+	// 删除的文件连同其编辑操作一起移除，不再参与回退
+	if err := e.InsertText("b.go", 0, "// b\n"); err != nil {
+		t.Fatal(err)
+	}
+	os.Remove("b.go")
+	if err := e.SyncFile("b.go"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.GetFileContent("b.go"); err == nil {
+		t.Error("deleted file is still loaded")
+	}
+
+	// 回退同时撤销编辑器的修改和外部写入
+	if err := e.RollbackSession(); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := e.GetFileContent("a.go"); content != "package a\n\nfunc A() {}\n" {
+		t.Errorf("after rollback = %q", content)
+	}
+	if dirty := e.DirtyFiles(); len(dirty) != 1 || dirty[0] != "a.go" {
+		t.Errorf("DirtyFiles after rollback = %v", dirty)
+	}
+}
+
+func TestTextChange(t *testing.T) {
+	tests := []struct {
+		old, new string
+		offset   int
+		removed  int
+		inserted string
+	}{
+		{"abc", "abc", 3, 0, ""},
+		{"abc", "abXc", 2, 0, "X"},
+		{"你好世界", "你们世界", 1, 1, "们"},
+		{"aaa", "aa", 2, 1, ""},
+		{"", "new", 0, 0, "new"},
+	}
+	for _, tt := range tests {
+		offset, removed, inserted := textChange(tt.old, tt.new)
+		if offset != tt.offset || removed != tt.removed || inserted != tt.inserted {
+			t.Errorf("textChange(%q, %q) = %d, %d, %q", tt.old, tt.new, offset, removed, inserted)
+		}
+	}
+}
+
+func TestSyncFileDoesNotPrintSaveFailures(t *testing.T) {
+	t.Setenv("POLYAGENT_CONFIG_HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+	for _, name := range []string{"a.go", "b.go"} {
+		if err := os.WriteFile(name, []byte("package x\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	e := NewEditor()
+	if err := e.StartSession(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 编辑历史无法写入（配置目录是一个文件）
+	blocked := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(blocked, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("POLYAGENT_CONFIG_HOME", blocked)
+
+	// 编辑器在界面中运行，同步时不能向标准输出打印
+	stdout := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = w
+	os.WriteFile("a.go", []byte("package x\n\nvar A = 1\n"), 0644)
+	syncErr := e.SyncFile("a.go")
+	os.Remove("b.go")
+	removeErr := e.SyncFile("b.go")
+	os.Stdout = stdout
+	w.Close()
+	printed, _ := io.ReadAll(r)
+
+	if len(printed) > 0 {
+		t.Errorf("SyncFile printed to stdout: %q", printed)
+	}
+	if syncErr != nil {
+		t.Errorf("sync of edited file = %v", syncErr)
+	}
+	if removeErr == nil {
+		t.Error("failure to save the edit log after a deletion was not reported")
+	}
+	if content, _ := e.GetFileContent("a.go"); content != "package x\n\nvar A = 1\n" {
+		t.Errorf("buffer = %q", content)
+	}
+}
@@ -0,0 +1,129 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func FuzzEditorEdits(f *testing.F) {
+	f.Add("hello world", 5, 1, "，")
+	f.Add("你好，世界", 3, 3, "🙂")
+	f.Add("", 0, 0, "new")
+	f.Add("abc", 3, -1, "")
+	f.Add("abc", -1, 2, "x")
+	f.Add("line1\nline2\n", 6, 100, "x")
+
+	dir := f.TempDir()
+	f.Fuzz(func(t *testing.T, content string, offset, length int, insert string) {
+		path := filepath.Join(dir, "f.txt")
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		e := NewEditor()
+		if err := e.LoadFile(path); err != nil {
+			t.Fatal(err)
+		}
+		current := func() string {
+			got, err := e.GetFileContent(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return got
+		}
+
+		// 插入后删除同样长度的内容应恢复原文
+		if err := e.InsertText(path, offset, insert); err == nil {
+			if got := current(); len(got) != len(content)+len(insert) {
+				t.Fatalf("insert %q at %d: length %d, want %d", insert, offset, len(got), len(content)+len(insert))
+			}
+			checkUTF8(t, content, insert, current())
+			if err := e.DeleteText(path, offset, utf8.RuneCountInString(insert)); err != nil {
+				t.Fatalf("undo insert failed: %v", err)
+			}
+			if got := current(); got != content {
+				t.Fatalf("undo insert: got %q, want %q", got, content)
+			}
+		}
+
+		// 删除后在原位置插入被删除的内容应恢复原文
+		if err := e.DeleteText(path, offset, length); err == nil {
+			if got := current(); utf8.RuneCountInString(got) != utf8.RuneCountInString(content)-length {
+				t.Fatalf("delete %d at %d: %d characters left, want %d", length, offset, utf8.RuneCountInString(got), utf8.RuneCountInString(content)-length)
+			}
+			checkUTF8(t, content, "", current())
+			deleted := e.GetCurrentEdits()
+			if err := e.InsertText(path, offset, deleted[len(deleted)-1].Content); err != nil {
+				t.Fatalf("undo delete failed: %v", err)
+			}
+			if got := current(); got != content {
+				t.Fatalf("undo delete: got %q, want %q", got, content)
+			}
+		}
+
+		// 替换失败时不能留下部分修改
+		if err := e.ReplaceText(path, offset, length, insert); err != nil {
+			if got := current(); got != content {
+				t.Fatalf("failed replace modified content: %q", got)
+			}
+		} else {
+			want := utf8.RuneCountInString(content) - length + utf8.RuneCountInString(insert)
+			if got := utf8.RuneCountInString(current()); got != want {
+				t.Fatalf("replace: %d characters, want %d", got, want)
+			}
+			checkUTF8(t, content, insert, current())
+		}
+	})
+}
+
+// checkUTF8 有效的 UTF-8 文本编辑后必须仍是有效的 UTF-8，偏移量不能切开多字节字符
+func checkUTF8(t *testing.T, content, insert, got string) {
+	t.Helper()
+	if utf8.ValidString(content) && utf8.ValidString(insert) && !utf8.ValidString(got) {
+		t.Fatalf("edit corrupted UTF-8: %q", got)
+	}
+}
+
+func TestSaveToDiskWritesOnlyModifiedFiles(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-time.Hour)
+	e := NewEditor()
+	for _, name := range []string{"a.go", "b.go", "c.go"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("package x\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(path, old, old)
+		if err := e.LoadFile(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a, b, c := filepath.Join(dir, "a.go"), filepath.Join(dir, "b.go"), filepath.Join(dir, "c.go")
+
+	e.InsertText(a, 0, "// a\n")
+	// 修改后又改回原样的文件不需要写入
+	e.InsertText(b, 0, "x")
+	e.DeleteText(b, 0, 1)
+	if dirty := e.DirtyFiles(); len(dirty) != 1 || dirty[0] != a {
+		t.Errorf("DirtyFiles = %v", dirty)
+	}
+
+	saved, err := e.SaveToDisk()
+	if err != nil || len(saved) != 1 || saved[0] != a {
+		t.Fatalf("SaveToDisk = %v, %v", saved, err)
+	}
+	if data, _ := os.ReadFile(a); string(data) != "// a\npackage x\n" {
+		t.Errorf("a.go = %q", data)
+	}
+	for _, path := range []string{b, c} {
+		if info, _ := os.Stat(path); !info.ModTime().Equal(old) {
+			t.Errorf("%s was rewritten", path)
+		}
+	}
+
+	if saved, err := e.SaveToDisk(); err != nil || len(saved) != 0 {
+		t.Errorf("second save = %v, %v", saved, err)
+	}
+}
@@ -0,0 +1,156 @@
+package utils
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// FileRef 文本中引用的文件位置，如编译错误中的 main.go:12:5
+type FileRef struct {
+	Path string
+	// Line 从 1 开始
+	Line int
+	// Column 从 1 开始，未指定时为 0
+	Column int
+	// Start/End 引用在原文中的字节范围
+	Start int
+	End   int
+}
+
+func (r FileRef) String() string {
+	s := r.Path + ":" + strconv.Itoa(r.Line)
+	if r.Column > 0 {
+		s += ":" + strconv.Itoa(r.Column)
+	}
+	return s
+}
+
+// fileRefPattern 匹配 path:line[:col]，路径需带扩展名，避免误匹配时间（12:30）和 URL 端口
+var fileRefPattern = regexp.MustCompile(`(?:^|[\s(\[{"'` + "`" + `=,])((?:[A-Za-z]:[\\/]|~?/|\.{1,2}/)?(?:[\w.\-@+]+[\\/])*[\w\-@+]+(?:\.[\w\-]+)*\.[A-Za-z]\w*):(\d+)(?::(\d+))?`)
+
+// FindFileRefs 找出文本中所有 path:line[:col] 形式的文件引用
+func FindFileRefs(text string) []FileRef {
+	var refs []FileRef
+	for _, m := range fileRefPattern.FindAllStringSubmatchIndex(text, -1) {
+		line, err := strconv.Atoi(text[m[4]:m[5]])
+		if err != nil || line == 0 {
+			continue
+		}
+		ref := FileRef{Path: text[m[2]:m[3]], Line: line, Start: m[2], End: m[5]}
+		if m[6] >= 0 {
+			ref.Column, _ = strconv.Atoi(text[m[6]:m[7]])
+			ref.End = m[7]
+		}
+		refs = append(refs, ref)
+	}
+	return refs
+}
+
+// ResolveFileRef 将引用中的路径解析为 dir 下的绝对路径，文件不存在时返回 false
+func ResolveFileRef(ref FileRef, dir string) (string, bool) {
+	path := ref.Path
+	if strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", false
+		}
+		path = filepath.Join(home, path[2:])
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return "", false
+	}
+	return path, true
+}
+
+// EditorArgs 返回用编辑器在指定行列打开文件的参数，editor 为 $VISUAL/$EDITOR 的值（可带参数）
+// 无法识别的编辑器只传入文件路径
+func EditorArgs(editor, path string, line, column int) []string {
+	fields := strings.Fields(editor)
+	if len(fields) == 0 {
+		return nil
+	}
+	name := strings.TrimSuffix(strings.ToLower(filepath.Base(fields[0])), ".exe")
+	if column < 1 {
+		column = 1
+	}
+	position := path + ":" + strconv.Itoa(line) + ":" + strconv.Itoa(column)
+
+	var args []string
+	switch name {
+	case "vi", "vim", "nvim", "gvim", "mvim", "nano", "emacs", "emacsclient", "kak", "micro", "joe", "jed", "ne":
+		args = []string{"+" + strconv.Itoa(line), path}
+	case "hx", "helix":
+		args = []string{position}
+	case "code", "code-insiders", "codium", "cursor", "windsurf":
+		args = []string{"--goto", position}
+	case "subl", "sublime_text", "zed", "mate":
+		args = []string{position}
+	case "idea", "goland", "pycharm", "webstorm", "clion":
+		args = []string{"--line", strconv.Itoa(line), "--column", strconv.Itoa(column), path}
+	default:
+		args = []string{path}
+	}
+	return append(fields, args...)
+}
+
+// DefaultEditor 返回用户的编辑器命令：$VISUAL、$EDITOR，都未设置时为 vi（Windows 为 notepad）
+func DefaultEditor() string {
+	for _, name := range []string{"VISUAL", "EDITOR"} {
+		if editor := strings.TrimSpace(os.Getenv(name)); editor != "" {
+			return editor
+		}
+	}
+	if runtime.GOOS == "windows" {
+		return "notepad"
+	}
+	return "vi"
+}
+
+// Hyperlink 用 OSC 8 转义序列生成终端超链接
+func Hyperlink(target, text string) string {
+	return "\x1b]8;;" + target + "\x1b\\" + text + "\x1b]8;;\x1b\\"
+}
+
+// FileURL 返回本地文件的 file:// URL
+func FileURL(path string) string {
+	path = filepath.ToSlash(path)
+	if runtime.GOOS == "windows" {
+		path = "/" + path
+	}
+	host, _ := os.Hostname()
+	return (&url.URL{Scheme: "file", Host: host, Path: path}).String()
+}
+
+// SupportsHyperlinks 根据环境变量判断终端是否支持 OSC 8 超链接
+// POLYAGENT_HYPERLINKS=1/0 可强制开启或关闭
+func SupportsHyperlinks() bool {
+	if value := os.Getenv("POLYAGENT_HYPERLINKS"); value != "" {
+		enabled, err := strconv.ParseBool(value)
+		return err == nil && enabled
+	}
+	if os.Getenv("TMUX") != "" || strings.HasPrefix(os.Getenv("TERM"), "screen") {
+		// tmux 和 screen 默认不转发 OSC 8
+		return false
+	}
+	switch os.Getenv("TERM_PROGRAM") {
+	case "iTerm.app", "WezTerm", "vscode", "ghostty", "Hyper", "Tabby":
+		return true
+	}
+	if os.Getenv("WT_SESSION") != "" || os.Getenv("KITTY_WINDOW_ID") != "" || os.Getenv("KONSOLE_VERSION") != "" {
+		return true
+	}
+	if vte, err := strconv.Atoi(os.Getenv("VTE_VERSION")); err == nil && vte >= 5000 {
+		return true
+	}
+	term := os.Getenv("TERM")
+	return strings.Contains(term, "kitty") || strings.Contains(term, "alacritty") || strings.Contains(term, "foot")
+}
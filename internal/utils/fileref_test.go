@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFindFileRefs(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"./main.go:12:5: undefined: foo", []string{"./main.go:12:5"}},
+		{"--- FAIL: TestX (0.00s)\n    config_test.go:42: got 1", []string{"config_test.go:42"}},
+		{"see internal/tui/model.go:1251 and /tmp/a.txt:3", []string{"internal/tui/model.go:1251", "/tmp/a.txt:3"}},
+		{"at 12:30 on http://example.com:8080/x", nil},
+		{"(src/app.test.ts:7:1)", []string{"src/app.test.ts:7:1"}},
+		{"README:3 has no extension", nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, ref := range FindFileRefs(tt.text) {
+			got = append(got, ref.String())
+			if tt.text[ref.Start:ref.End] != ref.String() {
+				t.Errorf("range of %s in %q = %q", ref, tt.text, tt.text[ref.Start:ref.End])
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("FindFileRefs(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestResolveFileRef(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if path, ok := ResolveFileRef(FileRef{Path: "a.go", Line: 1}, dir); !ok || path != filepath.Join(dir, "a.go") {
+		t.Errorf("ResolveFileRef(a.go) = %q, %v", path, ok)
+	}
+	if _, ok := ResolveFileRef(FileRef{Path: "missing.go", Line: 1}, dir); ok {
+		t.Error("expected missing file to be unresolved")
+	}
+}
+
+func TestEditorArgs(t *testing.T) {
+	tests := []struct {
+		editor string
+		want   string
+	}{
+		{"vim", "vim +12 f.go"},
+		{"/usr/bin/nvim -u NONE", "/usr/bin/nvim -u NONE +12 f.go"},
+		{"code --wait", "code --wait --goto f.go:12:3"},
+		{"hx", "hx f.go:12:3"},
+		{"goland", "goland --line 12 --column 3 f.go"},
+		{"notepad.exe", "notepad.exe f.go"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(EditorArgs(tt.editor, "f.go", 12, 3), " "); got != tt.want {
+			t.Errorf("EditorArgs(%q) = %q, want %q", tt.editor, got, tt.want)
+		}
+	}
+}
+
+func TestHyperlink(t *testing.T) {
+	got := Hyperlink("file:///tmp/a.go", "a.go:1")
+	if got != "\x1b]8;;file:///tmp/a.go\x1b\\a.go:1\x1b]8;;\x1b\\" {
+		t.Errorf("Hyperlink = %q", got)
+	}
+}
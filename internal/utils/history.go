@@ -9,8 +9,42 @@ import (
 )
 
 type HistoryEntry struct {
-	Timestamp time.Time `json:"timestamp"`
-	Messages  []Message `json:"messages"`
+	Timestamp time.Time     `json:"timestamp"`
+	Title     string        `json:"title,omitempty"`
+	Messages  []Message     `json:"messages"`
+	Stats     *SessionStats `json:"stats,omitempty"`
+}
+
+// SessionStats 会话统计（/stats），随会话记录一起保存
+type SessionStats struct {
+	DurationSeconds int `json:"duration_seconds"`
+	Turns           int `json:"turns"`
+	Retries         int `json:"retries,omitempty"`
+	Requests        int `json:"requests"`
+	FailedRequests  int `json:"failed_requests,omitempty"`
+	InputTokens     int `json:"input_tokens"`
+	OutputTokens    int `json:"output_tokens"`
+	// CachedTokens 输入中命中服务商提示缓存的 token 数
+	CachedTokens int `json:"cached_tokens,omitempty"`
+	// TokensEstimated 部分请求没有返回用量，按消息内容估算
+	TokensEstimated bool `json:"tokens_estimated,omitempty"`
+	// EstimatedCost 按配置的价格估算的费用，未配置价格时为 0
+	EstimatedCost   float64        `json:"estimated_cost,omitempty"`
+	ToolCalls       map[string]int `json:"tool_calls,omitempty"`
+	ToolFailures    int            `json:"tool_failures,omitempty"`
+	FilesRead       int            `json:"files_read"`
+	FilesWritten    int            `json:"files_written"`
+	FileCacheHits   int64          `json:"file_cache_hits,omitempty"`
+	FileCacheMisses int64          `json:"file_cache_misses,omitempty"`
+	// Models 按模型分别统计的用量（配置了 model_routing 时会使用多个模型）
+	Models map[string]ModelUsage `json:"models,omitempty"`
+}
+
+// ModelUsage 单个模型的请求数和 token 用量
+type ModelUsage struct {
+	Requests     int `json:"requests"`
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
 }
 
 type Message struct {
@@ -19,14 +53,23 @@ type Message struct {
 }
 
 func SaveHistory(messages []Message) error {
+	return SaveTitledHistory("", messages)
+}
+
+// SaveTitledHistory 保存带会话标题的历史记录
+func SaveTitledHistory(title string, messages []Message) error {
+	return SaveHistoryEntry(HistoryEntry{Title: title, Messages: messages})
+}
+
+// SaveHistoryEntry 追加一条会话记录，Timestamp 为空时使用当前时间
+func SaveHistoryEntry(entry HistoryEntry) error {
 	historyPath, err := getHistoryPath()
 	if err != nil {
 		return fmt.Errorf("获取历史文件路径失败: %w", err)
 	}
 
-	entry := HistoryEntry{
-		Timestamp: time.Now(),
-		Messages:  messages,
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
 	}
 
 	var history []HistoryEntry
@@ -0,0 +1,168 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ImageProtocol 终端的内联图片协议
+type ImageProtocol int
+
+const (
+	ImageProtocolNone ImageProtocol = iota
+	// ImageProtocolKitty kitty 图形协议（kitty、Ghostty、WezTerm），只支持 PNG
+	ImageProtocolKitty
+	// ImageProtocolITerm2 iTerm2 内联图片协议（iTerm2、WezTerm、VS Code 终端）
+	ImageProtocolITerm2
+)
+
+// kittyChunkSize kitty 协议每段传输的 base64 数据长度上限
+const kittyChunkSize = 4096
+
+// ErrDiagramRendererMissing 未安装渲染图表所需的命令行工具
+var ErrDiagramRendererMissing = errors.New("未找到图表渲染工具")
+
+// DetectImageProtocol 根据环境变量判断终端支持的图片协议
+// POLYAGENT_IMAGES=kitty/iterm2/none 可强制指定
+func DetectImageProtocol() ImageProtocol {
+	switch strings.ToLower(os.Getenv("POLYAGENT_IMAGES")) {
+	case "kitty":
+		return ImageProtocolKitty
+	case "iterm2":
+		return ImageProtocolITerm2
+	case "none", "off", "0":
+		return ImageProtocolNone
+	}
+	if os.Getenv("TMUX") != "" {
+		// tmux 默认不转发图片协议
+		return ImageProtocolNone
+	}
+	if os.Getenv("KITTY_WINDOW_ID") != "" || strings.Contains(os.Getenv("TERM"), "kitty") || os.Getenv("TERM_PROGRAM") == "ghostty" {
+		return ImageProtocolKitty
+	}
+	switch os.Getenv("TERM_PROGRAM") {
+	case "iTerm.app", "WezTerm", "vscode":
+		return ImageProtocolITerm2
+	}
+	return ImageProtocolNone
+}
+
+// IsPNG 报告数据是否为 PNG 图片
+func IsPNG(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n"))
+}
+
+// EncodeInlineImage 将图片编码为终端内联显示的转义序列，协议不支持该格式时返回错误
+func EncodeInlineImage(protocol ImageProtocol, data []byte, name string) (string, error) {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	switch protocol {
+	case ImageProtocolITerm2:
+		return fmt.Sprintf("\x1b]1337;File=name=%s;size=%d;inline=1;preserveAspectRatio=1:%s\a",
+			base64.StdEncoding.EncodeToString([]byte(name)), len(data), encoded), nil
+	case ImageProtocolKitty:
+		if !IsPNG(data) {
+			return "", fmt.Errorf("kitty 图形协议只支持 PNG: %s", name)
+		}
+		var sb strings.Builder
+		for i := 0; i < len(encoded); i += kittyChunkSize {
+			end := min(i+kittyChunkSize, len(encoded))
+			more := 0
+			if end < len(encoded) {
+				more = 1
+			}
+			if i == 0 {
+				fmt.Fprintf(&sb, "\x1b_Ga=T,f=100,m=%d;%s\x1b\\", more, encoded[i:end])
+			} else {
+				fmt.Fprintf(&sb, "\x1b_Gm=%d;%s\x1b\\", more, encoded[i:end])
+			}
+		}
+		return sb.String(), nil
+	default:
+		return "", errors.New("终端不支持内联图片")
+	}
+}
+
+// Diagram 回复中的图表代码块
+type Diagram struct {
+	// Kind 为 mermaid 或 plantuml
+	Kind   string
+	Source string
+}
+
+// diagramPattern 匹配 ```mermaid 和 ```plantuml / ```puml 代码块
+var diagramPattern = regexp.MustCompile("(?s)```(mermaid|plantuml|puml)[ \\t]*\\r?\\n(.*?)```")
+
+// ExtractDiagrams 找出文本中的 mermaid 和 PlantUML 代码块
+func ExtractDiagrams(text string) []Diagram {
+	var diagrams []Diagram
+	for _, m := range diagramPattern.FindAllStringSubmatch(text, -1) {
+		kind := m[1]
+		if kind == "puml" {
+			kind = "plantuml"
+		}
+		if source := strings.TrimSpace(m[2]); source != "" {
+			diagrams = append(diagrams, Diagram{Kind: kind, Source: source})
+		}
+	}
+	return diagrams
+}
+
+// sourceExt 图表源文件的扩展名
+func (d Diagram) sourceExt() string {
+	if d.Kind == "plantuml" {
+		return ".puml"
+	}
+	return ".mmd"
+}
+
+// WriteDiagram 将图表源码写入 dir，并尝试用 mmdc（mermaid-cli）或 plantuml 渲染为 PNG
+// 返回源文件路径和 PNG 路径；没有渲染工具时 PNG 路径为空，错误为 ErrDiagramRendererMissing
+func WriteDiagram(ctx context.Context, d Diagram, dir string) (string, string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", "", fmt.Errorf("创建图表目录失败: %w", err)
+	}
+	// 以内容哈希命名，同一张图重复查看时不重新渲染
+	sum := sha256.Sum256([]byte(d.Kind + "\x00" + d.Source))
+	base := filepath.Join(dir, fmt.Sprintf("diagram-%x", sum[:6]))
+	source := base + d.sourceExt()
+	png := base + ".png"
+	if err := os.WriteFile(source, []byte(d.Source+"\n"), 0644); err != nil {
+		return "", "", fmt.Errorf("写入图表源文件失败: %w", err)
+	}
+	if _, err := os.Stat(png); err == nil {
+		return source, png, nil
+	}
+
+	var cmd *exec.Cmd
+	switch d.Kind {
+	case "plantuml":
+		path, err := exec.LookPath("plantuml")
+		if err != nil {
+			return source, "", ErrDiagramRendererMissing
+		}
+		// plantuml 在源文件所在目录生成同名 PNG
+		cmd = exec.CommandContext(ctx, path, "-tpng", source)
+	default:
+		path, err := exec.LookPath("mmdc")
+		if err != nil {
+			return source, "", ErrDiagramRendererMissing
+		}
+		cmd = exec.CommandContext(ctx, path, "-i", source, "-o", png)
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return source, "", fmt.Errorf("渲染图表失败: %v: %s", err, strings.TrimSpace(string(output)))
+	}
+	if _, err := os.Stat(png); err != nil {
+		return source, "", fmt.Errorf("渲染图表失败: 未生成 %s", png)
+	}
+	return source, png, nil
+}
@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestExtractDiagrams(t *testing.T) {
+	text := "架构如下：\n```mermaid\ngraph TD\n  A --> B\n```\n以及\n```puml\n@startuml\nA -> B\n@enduml\n```\n```go\nfunc main() {}\n```"
+	diagrams := ExtractDiagrams(text)
+	if len(diagrams) != 2 {
+		t.Fatalf("expected 2 diagrams, got %+v", diagrams)
+	}
+	if diagrams[0].Kind != "mermaid" || diagrams[0].Source != "graph TD\n  A --> B" {
+		t.Errorf("first diagram = %+v", diagrams[0])
+	}
+	if diagrams[1].Kind != "plantuml" || !strings.HasPrefix(diagrams[1].Source, "@startuml") {
+		t.Errorf("second diagram = %+v", diagrams[1])
+	}
+}
+
+func TestEncodeInlineImage(t *testing.T) {
+	png := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 5000)...)
+
+	iterm, err := EncodeInlineImage(ImageProtocolITerm2, png, "a.png")
+	if err != nil || !strings.HasPrefix(iterm, "\x1b]1337;File=") || !strings.HasSuffix(iterm, "\a") {
+		t.Errorf("iTerm2 sequence = %.40q, %v", iterm, err)
+	}
+
+	kitty, err := EncodeInlineImage(ImageProtocolKitty, png, "a.png")
+	if err != nil {
+		t.Fatalf("kitty encoding failed: %v", err)
+	}
+	// 5008 字节编码后超过一段的长度，应分段传输
+	if strings.Count(kitty, "\x1b_G") != 2 || !strings.Contains(kitty, "a=T,f=100,m=1;") || !strings.Contains(kitty, "\x1b_Gm=0;") {
+		t.Errorf("unexpected kitty chunks: %d", strings.Count(kitty, "\x1b_G"))
+	}
+
+	if _, err := EncodeInlineImage(ImageProtocolKitty, []byte("GIF89a"), "a.gif"); err == nil {
+		t.Error("expected kitty to reject non-PNG images")
+	}
+	if _, err := EncodeInlineImage(ImageProtocolNone, png, "a.png"); err == nil {
+		t.Error("expected an error without an image protocol")
+	}
+}
+
+func TestWriteDiagramWithoutRenderer(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	dir := t.TempDir()
+	source, png, err := WriteDiagram(context.Background(), Diagram{Kind: "mermaid", Source: "graph TD\nA-->B"}, dir)
+	if !errors.Is(err, ErrDiagramRendererMissing) {
+		t.Fatalf("expected ErrDiagramRendererMissing, got %v", err)
+	}
+	if png != "" || !strings.HasSuffix(source, ".mmd") {
+		t.Errorf("source = %s, png = %s", source, png)
+	}
+	if data, err := os.ReadFile(source); err != nil || string(data) != "graph TD\nA-->B\n" {
+		t.Errorf("source file = %q, %v", data, err)
+	}
+}
@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+)
+
+// 全局出站网络设置，由 ConfigureNetwork 在启动时设置
+var (
+	networkMu sync.RWMutex
+	proxyURL  *url.URL
+	rootCAs   *x509.CertPool
+)
+
+// ConfigureNetwork 设置所有 HTTP 客户端共用的代理和额外 CA 证书
+// proxy 为空时使用 HTTP_PROXY / HTTPS_PROXY / NO_PROXY 环境变量；caFiles 为 PEM 格式证书文件，追加到系统证书池
+func ConfigureNetwork(proxy string, caFiles []string) error {
+	var parsedProxy *url.URL
+	if proxy != "" {
+		u, err := ParseProxyURL(proxy)
+		if err != nil {
+			return err
+		}
+		parsedProxy = u
+	}
+
+	var pool *x509.CertPool
+	if len(caFiles) > 0 {
+		var err error
+		pool, err = x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		for _, file := range caFiles {
+			pem, err := os.ReadFile(file)
+			if err != nil {
+				return fmt.Errorf("failed to read CA bundle %s: %w", file, err)
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return fmt.Errorf("no valid PEM certificates in CA bundle %s", file)
+			}
+		}
+	}
+
+	networkMu.Lock()
+	defer networkMu.Unlock()
+	proxyURL = parsedProxy
+	rootCAs = pool
+	return nil
+}
+
+// ParseProxyURL 解析代理地址，只接受 http、https 和 socks5
+func ParseProxyURL(proxy string) (*url.URL, error) {
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL %q: %w", proxy, err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("invalid proxy URL %q: scheme must be http, https or socks5", proxy)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q: missing host", proxy)
+	}
+	return u, nil
+}
+
+// NewTransport 创建应用了代理和 CA 设置的 Transport，其余参数与 http.DefaultTransport 一致
+func NewTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	networkMu.RLock()
+	defer networkMu.RUnlock()
+
+	if proxyURL != nil {
+		transport.Proxy = http.ProxyURL(proxyURL)
+	} else {
+		transport.Proxy = http.ProxyFromEnvironment
+	}
+	if rootCAs != nil {
+		transport.TLSClientConfig = &tls.Config{
+			RootCAs:    rootCAs,
+			MinVersion: tls.VersionTLS12,
+		}
+	}
+	return transport
+}
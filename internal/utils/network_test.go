@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func resetNetwork(t *testing.T) {
+	t.Cleanup(func() {
+		ConfigureNetwork("", nil)
+	})
+}
+
+func TestConfigureNetwork_Proxy(t *testing.T) {
+	resetNetwork(t)
+
+	if err := ConfigureNetwork("http://proxy.example.com:3128", nil); err != nil {
+		t.Fatalf("ConfigureNetwork failed: %v", err)
+	}
+
+	req, _ := http.NewRequest("GET", "https://api.example.com/v1", nil)
+	proxy, err := NewTransport().Proxy(req)
+	if err != nil {
+		t.Fatalf("Proxy returned error: %v", err)
+	}
+	if proxy == nil || proxy.Host != "proxy.example.com:3128" {
+		t.Errorf("Expected configured proxy, got %v", proxy)
+	}
+}
+
+func TestConfigureNetwork_InvalidProxy(t *testing.T) {
+	resetNetwork(t)
+
+	for _, proxy := range []string{"ftp://proxy.example.com", "http://", "://bad"} {
+		if err := ConfigureNetwork(proxy, nil); err == nil {
+			t.Errorf("Expected error for proxy %q", proxy)
+		}
+	}
+}
+
+func TestConfigureNetwork_CAFiles(t *testing.T) {
+	resetNetwork(t)
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// 未添加证书时应校验失败
+	client := &http.Client{Transport: NewTransport()}
+	if resp, err := client.Get(server.URL); err == nil {
+		resp.Body.Close()
+		t.Fatal("Expected certificate error without custom CA")
+	}
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, certPEM, 0644); err != nil {
+		t.Fatalf("Failed to write CA file: %v", err)
+	}
+
+	if err := ConfigureNetwork("", []string{caFile}); err != nil {
+		t.Fatalf("ConfigureNetwork failed: %v", err)
+	}
+
+	client = &http.Client{Transport: NewTransport()}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Expected request to succeed with custom CA: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestConfigureNetwork_InvalidCAFile(t *testing.T) {
+	resetNetwork(t)
+
+	if err := ConfigureNetwork("", []string{filepath.Join(t.TempDir(), "missing.pem")}); err == nil {
+		t.Error("Expected error for missing CA file")
+	}
+
+	badFile := filepath.Join(t.TempDir(), "bad.pem")
+	os.WriteFile(badFile, []byte("not a certificate"), 0644)
+	if err := ConfigureNetwork("", []string{badFile}); err == nil {
+		t.Error("Expected error for invalid CA file")
+	}
+}
@@ -0,0 +1,117 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// spendDayLayout 每日费用记录的键格式（本地日期）
+	spendDayLayout = "2006-01-02"
+	// spendKeepDays 保留最近多少天的记录
+	spendKeepDays = 31
+)
+
+// spendMu 保护 spend.json 的读改写，同一进程内的多个客户端可能同时记账
+var spendMu sync.Mutex
+
+// DailySpend 返回指定日期累计的估算费用，没有记录时返回 0
+func DailySpend(day time.Time) (float64, error) {
+	spendMu.Lock()
+	defer spendMu.Unlock()
+
+	records, err := loadSpend()
+	if err != nil {
+		return 0, err
+	}
+	return records[day.Format(spendDayLayout)], nil
+}
+
+// AddDailySpend 将费用累加到指定日期，返回当天的累计值
+func AddDailySpend(day time.Time, amount float64) (float64, error) {
+	spendMu.Lock()
+	defer spendMu.Unlock()
+
+	records, err := loadSpend()
+	if err != nil {
+		return 0, err
+	}
+	key := day.Format(spendDayLayout)
+	records[key] += amount
+	total := records[key]
+
+	// 只保留最近的记录，日期格式可以按字符串排序
+	if len(records) > spendKeepDays {
+		days := make([]string, 0, len(records))
+		for d := range records {
+			days = append(days, d)
+		}
+		sort.Strings(days)
+		for _, d := range days[:len(days)-spendKeepDays] {
+			delete(records, d)
+		}
+	}
+
+	if err := saveSpend(records); err != nil {
+		return total, err
+	}
+	return total, nil
+}
+
+func loadSpend() (map[string]float64, error) {
+	spendPath, err := getSpendPath()
+	if err != nil {
+		return nil, err
+	}
+
+	records := map[string]float64{}
+	data, err := os.ReadFile(spendPath)
+	if os.IsNotExist(err) {
+		return records, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取费用记录失败: %w", err)
+	}
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("解析费用记录失败: %w", err)
+	}
+	return records, nil
+}
+
+func saveSpend(records map[string]float64) error {
+	spendPath, err := getSpendPath()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化费用记录失败: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(spendPath), 0755); err != nil {
+		return fmt.Errorf("创建配置目录失败: %w", err)
+	}
+
+	tempFile := spendPath + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0600); err != nil {
+		return fmt.Errorf("写入费用记录失败: %w", err)
+	}
+	if err := os.Rename(tempFile, spendPath); err != nil {
+		os.Remove(tempFile)
+		return fmt.Errorf("写入费用记录失败: %w", err)
+	}
+	return nil
+}
+
+func getSpendPath() (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("获取配置目录失败: %w", err)
+	}
+	return filepath.Join(configDir, "spend.json"), nil
+}
@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestDailySpend(t *testing.T) {
+	t.Setenv("POLYAGENT_CONFIG_HOME", t.TempDir())
+
+	today := time.Date(2025, 3, 10, 15, 0, 0, 0, time.Local)
+	if spent, err := DailySpend(today); err != nil || spent != 0 {
+		t.Fatalf("Expected no spend, got %v, %v", spent, err)
+	}
+
+	if _, err := AddDailySpend(today, 0.25); err != nil {
+		t.Fatalf("AddDailySpend failed: %v", err)
+	}
+	total, err := AddDailySpend(today.Add(-time.Hour), 0.5)
+	if err != nil || math.Abs(total-0.75) > 1e-9 {
+		t.Fatalf("Expected same-day total 0.75, got %v, %v", total, err)
+	}
+	if spent, _ := DailySpend(today.AddDate(0, 0, 1)); spent != 0 {
+		t.Errorf("Expected next day to start at 0, got %v", spent)
+	}
+
+	// 超过保留天数的旧记录被清理
+	for i := 1; i <= spendKeepDays; i++ {
+		if _, err := AddDailySpend(today.AddDate(0, 0, i), 1); err != nil {
+			t.Fatalf("AddDailySpend failed: %v", err)
+		}
+	}
+	if spent, _ := DailySpend(today); spent != 0 {
+		t.Errorf("Expected oldest day to be pruned, got %v", spent)
+	}
+	if spent, _ := DailySpend(today.AddDate(0, 0, spendKeepDays)); spent != 1 {
+		t.Errorf("Expected latest day to be kept, got %v", spent)
+	}
+}
@@ -0,0 +1,122 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rivo/uniseg"
+)
+
+// 编辑器的偏移量和长度按字符（rune）计算，这里的函数负责与字节偏移量和行列位置互相转换，
+// 保证编辑不会把中文、emoji 等多字节字符切开
+
+// RuneToByteOffset 将字符偏移量转换为字节偏移量，offset 可以等于字符数（指向末尾）
+func RuneToByteOffset(content string, offset int) (int, error) {
+	if offset < 0 {
+		return 0, fmt.Errorf("偏移量 %d 不能为负数", offset)
+	}
+	runes := 0
+	for i := range content {
+		if runes == offset {
+			return i, nil
+		}
+		runes++
+	}
+	if runes == offset {
+		return len(content), nil
+	}
+	return 0, fmt.Errorf("偏移量 %d 超出范围 (0-%d)", offset, runes)
+}
+
+// ByteToRuneOffset 将字节偏移量转换为字符偏移量，落在多字节字符中间时按该字符的起始位置计算
+func ByteToRuneOffset(content string, offset int) int {
+	if offset <= 0 {
+		return 0
+	}
+	if offset >= len(content) {
+		return utf8.RuneCountInString(content)
+	}
+	for offset > 0 && !utf8.RuneStart(content[offset]) {
+		offset--
+	}
+	return utf8.RuneCountInString(content[:offset])
+}
+
+// LineColumnToOffset 将行列位置（都从 1 开始，列按字符计算）转换为字符偏移量。
+// 列可以指向行尾换行符的位置；行号为总行数加一时表示文件末尾（用于在末尾追加）
+func LineColumnToOffset(content string, line, column int) (int, error) {
+	if line < 1 || column < 1 {
+		return 0, fmt.Errorf("行列位置 %d:%d 无效，行号和列号都从 1 开始", line, column)
+	}
+	lines := strings.Split(content, "\n")
+	if line > len(lines)+1 || (line == len(lines)+1 && column != 1) {
+		return 0, fmt.Errorf("行号 %d 超出范围 (1-%d)", line, len(lines))
+	}
+
+	offset := 0
+	for i := 0; i < line-1 && i < len(lines); i++ {
+		offset += utf8.RuneCountInString(lines[i]) + 1 // +1 为换行符
+	}
+	if line == len(lines)+1 {
+		// 最后一行之后即文件末尾
+		return utf8.RuneCountInString(content), nil
+	}
+	if width := utf8.RuneCountInString(lines[line-1]); column > width+1 {
+		return 0, fmt.Errorf("第 %d 行只有 %d 个字符，列号 %d 超出范围", line, width, column)
+	}
+	return offset + column - 1, nil
+}
+
+// OffsetToLineColumn 将字符偏移量转换为行列位置（都从 1 开始），超出范围时返回最后的位置
+func OffsetToLineColumn(content string, offset int) (int, int) {
+	line, column := 1, 1
+	runes := 0
+	for _, r := range content {
+		if runes >= offset {
+			break
+		}
+		runes++
+		if r == '\n' {
+			line++
+			column = 1
+		} else {
+			column++
+		}
+	}
+	return line, column
+}
+
+// TruncateText 截取文本开头最多 maxChars 个字符（按用户看到的字符，即字形簇计算），
+// 不会把 emoji 组合、带声调的字母等切开；返回截取后的文本和是否发生截断
+func TruncateText(text string, maxChars int) (string, bool) {
+	if maxChars <= 0 || len(text) <= maxChars {
+		// 字节数不超过上限时字符数一定不超过
+		return text, false
+	}
+	end, count := 0, 0
+	state := -1
+	remaining := text
+	for len(remaining) > 0 {
+		var cluster string
+		cluster, remaining, _, state = uniseg.FirstGraphemeClusterInString(remaining, state)
+		if count == maxChars {
+			return text[:end], true
+		}
+		end += len(cluster)
+		count++
+	}
+	return text, false
+}
+
+// TruncateBytes 截取文本开头最多 maxBytes 个字节，不会切开多字节字符
+func TruncateBytes(text string, maxBytes int) string {
+	if len(text) <= maxBytes {
+		return text
+	}
+	end := maxBytes
+	for end > 0 && !utf8.RuneStart(text[end]) {
+		end--
+	}
+	return text[:end]
+}
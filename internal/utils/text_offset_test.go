@@ -0,0 +1,84 @@
+package utils
+
+import "testing"
+
+func TestRuneAndByteOffsets(t *testing.T) {
+	content := "a你好🙂b"
+	cases := []struct{ runes, bytes int }{{0, 0}, {1, 1}, {2, 4}, {3, 7}, {4, 11}, {5, 12}}
+	for _, c := range cases {
+		got, err := RuneToByteOffset(content, c.runes)
+		if err != nil || got != c.bytes {
+			t.Errorf("RuneToByteOffset(%d) = %d, %v; want %d", c.runes, got, err, c.bytes)
+		}
+		if back := ByteToRuneOffset(content, c.bytes); back != c.runes {
+			t.Errorf("ByteToRuneOffset(%d) = %d, want %d", c.bytes, back, c.runes)
+		}
+	}
+	if _, err := RuneToByteOffset(content, 6); err == nil {
+		t.Error("expected error past the end")
+	}
+	if _, err := RuneToByteOffset(content, -1); err == nil {
+		t.Error("expected error for negative offset")
+	}
+	// 落在多字节字符中间时按字符起点计算
+	if got := ByteToRuneOffset(content, 2); got != 1 {
+		t.Errorf("ByteToRuneOffset inside a rune = %d, want 1", got)
+	}
+}
+
+func TestLineColumnOffsets(t *testing.T) {
+	content := "第一行\nsecond\n🙂x"
+	cases := []struct{ line, column, offset int }{
+		{1, 1, 0},
+		{1, 4, 3}, // 第一行末尾的换行符
+		{2, 1, 4},
+		{3, 2, 12},
+		{3, 3, 13}, // 文件末尾
+		{4, 1, 13},
+	}
+	for _, c := range cases {
+		got, err := LineColumnToOffset(content, c.line, c.column)
+		if err != nil || got != c.offset {
+			t.Errorf("LineColumnToOffset(%d, %d) = %d, %v; want %d", c.line, c.column, got, err, c.offset)
+		}
+		if c.line <= 3 {
+			if line, column := OffsetToLineColumn(content, c.offset); line != c.line || column != c.column {
+				t.Errorf("OffsetToLineColumn(%d) = %d:%d, want %d:%d", c.offset, line, column, c.line, c.column)
+			}
+		}
+	}
+	for _, bad := range [][2]int{{0, 1}, {1, 0}, {1, 5}, {4, 2}, {5, 1}} {
+		if _, err := LineColumnToOffset(content, bad[0], bad[1]); err == nil {
+			t.Errorf("LineColumnToOffset(%d, %d) should fail", bad[0], bad[1])
+		}
+	}
+}
+
+func TestTruncateText(t *testing.T) {
+	cases := []struct {
+		text      string
+		max       int
+		want      string
+		truncated bool
+	}{
+		{"hello", 10, "hello", false},
+		{"hello", 3, "hel", true},
+		{"你好世界", 2, "你好", true},
+		// 带肤色的 emoji 和国旗都是一个字符
+		{"👍🏽👍🏽👍🏽", 2, "👍🏽👍🏽", true},
+		{"🇨🇳🇯🇵", 1, "🇨🇳", true},
+		// 组合声调符号不与字母分开
+		{"éé", 1, "é", true},
+		{"abc", 0, "abc", false},
+	}
+	for _, c := range cases {
+		got, truncated := TruncateText(c.text, c.max)
+		if got != c.want || truncated != c.truncated {
+			t.Errorf("TruncateText(%q, %d) = %q, %v; want %q, %v", c.text, c.max, got, truncated, c.want, c.truncated)
+		}
+	}
+
+	if got := TruncateBytes("你好", 4); got != "你" {
+		t.Errorf("TruncateBytes split a rune: %q", got)
+	}
+}